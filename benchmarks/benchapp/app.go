@@ -0,0 +1,189 @@
+// Package benchapp is a deterministic key-value store ABCI app for benchmarking
+// the consensus and the mempool, with a tunable cost for executing the txs
+// and a tunable size for the state.
+package benchapp
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	abci "github.com/tendermint/abci/types"
+)
+
+// Codes of the responses to invalid txs
+const (
+	CodeTypeEmptyTx uint32 = 1
+)
+
+// Options tune the cost of the txs and the size of the state.
+type Options struct {
+	// CheckCost is the number of sha256 rounds to run in CheckTx
+	CheckCost int
+	// DeliverCost is the number of sha256 rounds to run in DeliverTx
+	DeliverCost int
+	// ValueSize is the size the values are padded to, 0 to store them as they are
+	ValueSize int
+	// MaxKeys bounds the number of keys in the state, 0 for no bound.
+	// Above it, txs overwrite the existing keys.
+	MaxKeys int
+}
+
+// DefaultOptions returns the options of an app that stores the txs as they are.
+func DefaultOptions() Options {
+	return Options{}
+}
+
+// ParseOptions parses options like "deliver_cost=100,value_size=256".
+// The options not given are the DefaultOptions.
+func ParseOptions(s string) (Options, error) {
+	opts := DefaultOptions()
+	if s == "" {
+		return opts, nil
+	}
+	for _, opt := range strings.Split(s, ",") {
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 {
+			return opts, fmt.Errorf("Invalid bench app option %q, expected key=value", opt)
+		}
+		value, err := strconv.Atoi(kv[1])
+		if err != nil || value < 0 {
+			return opts, fmt.Errorf("Invalid value for bench app option %s: %q", kv[0], kv[1])
+		}
+		switch kv[0] {
+		case "check_cost":
+			opts.CheckCost = value
+		case "deliver_cost":
+			opts.DeliverCost = value
+		case "value_size":
+			opts.ValueSize = value
+		case "max_keys":
+			opts.MaxKeys = value
+		default:
+			return opts, fmt.Errorf("Unknown bench app option %s", kv[0])
+		}
+	}
+	return opts, nil
+}
+
+// Application stores the txs in memory, "key=value" or as both key and value.
+// Its app hash is a hash chain of the results of the blocks, so it's
+// deterministic without having to merkleize the state.
+type Application struct {
+	abci.BaseApplication
+
+	opts    Options
+	state   map[string][]byte
+	keys    []string // in the order they were added, for MaxKeys
+	height  int64
+	appHash []byte
+	block   []byte // digest of the txs delivered in the current block
+}
+
+// NewApplication returns a new Application with the given options.
+func NewApplication(opts Options) *Application {
+	return &Application{
+		opts:  opts,
+		state: make(map[string][]byte),
+	}
+}
+
+// Info implements abci.Application.
+func (app *Application) Info(req abci.RequestInfo) abci.ResponseInfo {
+	return abci.ResponseInfo{
+		Data:             fmt.Sprintf("{\"size\":%d}", len(app.state)),
+		LastBlockHeight:  app.height,
+		LastBlockAppHash: app.appHash,
+	}
+}
+
+// CheckTx implements abci.Application.
+func (app *Application) CheckTx(tx []byte) abci.ResponseCheckTx {
+	if len(tx) == 0 {
+		return abci.ResponseCheckTx{Code: CodeTypeEmptyTx, Log: "Empty tx"}
+	}
+	work(tx, app.opts.CheckCost)
+	return abci.ResponseCheckTx{Code: abci.CodeTypeOK}
+}
+
+// DeliverTx implements abci.Application.
+func (app *Application) DeliverTx(tx []byte) abci.ResponseDeliverTx {
+	if len(tx) == 0 {
+		return abci.ResponseDeliverTx{Code: CodeTypeEmptyTx, Log: "Empty tx"}
+	}
+	digest := work(tx, app.opts.DeliverCost)
+
+	key, value := tx, tx
+	if i := strings.IndexByte(string(tx), '='); i > 0 {
+		key, value = tx[:i], tx[i+1:]
+	}
+	app.set(string(key), app.pad(value))
+
+	hasher := sha256.New()
+	hasher.Write(app.block) // nolint: errcheck, gas
+	hasher.Write(digest)    // nolint: errcheck, gas
+	app.block = hasher.Sum(nil)
+	return abci.ResponseDeliverTx{Code: abci.CodeTypeOK}
+}
+
+// Commit implements abci.Application.
+func (app *Application) Commit() abci.ResponseCommit {
+	app.height++
+	hasher := sha256.New()
+	hasher.Write(app.appHash) // nolint: errcheck, gas
+	hasher.Write(app.block)   // nolint: errcheck, gas
+	heightBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(heightBytes, uint64(app.height))
+	hasher.Write(heightBytes) // nolint: errcheck, gas
+	app.appHash = hasher.Sum(nil)
+	app.block = nil
+	return abci.ResponseCommit{Code: abci.CodeTypeOK, Data: app.appHash}
+}
+
+// Query implements abci.Application.
+// It returns the value of the key in req.Data.
+func (app *Application) Query(req abci.RequestQuery) abci.ResponseQuery {
+	value, ok := app.state[string(req.Data)]
+	if !ok {
+		return abci.ResponseQuery{Code: abci.CodeTypeOK, Key: req.Data, Log: "does not exist", Height: app.height}
+	}
+	return abci.ResponseQuery{Code: abci.CodeTypeOK, Key: req.Data, Value: value, Log: "exists", Height: app.height}
+}
+
+// Size returns the number of keys in the state.
+func (app *Application) Size() int {
+	return len(app.state)
+}
+
+func (app *Application) set(key string, value []byte) {
+	if _, ok := app.state[key]; !ok {
+		if app.opts.MaxKeys > 0 && len(app.keys) >= app.opts.MaxKeys {
+			// overwrite the oldest key instead, to keep the state size bounded
+			oldest := app.keys[0]
+			app.keys = app.keys[1:]
+			delete(app.state, oldest)
+		}
+		app.keys = append(app.keys, key)
+	}
+	app.state[key] = value
+}
+
+func (app *Application) pad(value []byte) []byte {
+	if len(value) >= app.opts.ValueSize {
+		return value
+	}
+	padded := make([]byte, app.opts.ValueSize)
+	copy(padded, value)
+	return padded
+}
+
+// work runs rounds of sha256 over the tx, and returns the digest.
+func work(tx []byte, rounds int) []byte {
+	digest := sha256.Sum256(tx)
+	for i := 0; i < rounds; i++ {
+		digest = sha256.Sum256(digest[:])
+	}
+	return digest[:]
+}
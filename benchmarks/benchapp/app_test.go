@@ -0,0 +1,60 @@
+package benchapp
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/abci/types"
+)
+
+func TestParseOptions(t *testing.T) {
+	opts, err := ParseOptions("")
+	require.Nil(t, err)
+	assert.Equal(t, DefaultOptions(), opts)
+
+	opts, err = ParseOptions("check_cost=1,deliver_cost=100,value_size=256,max_keys=10")
+	require.Nil(t, err)
+	assert.Equal(t, Options{CheckCost: 1, DeliverCost: 100, ValueSize: 256, MaxKeys: 10}, opts)
+
+	for _, s := range []string{"deliver_cost", "deliver_cost=-1", "deliver_cost=a", "foo=1"} {
+		_, err = ParseOptions(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestApplicationDeterministic(t *testing.T) {
+	opts := Options{DeliverCost: 10, ValueSize: 16, MaxKeys: 3}
+	run := func() *Application {
+		app := NewApplication(opts)
+		for height := 0; height < 3; height++ {
+			for i := 0; i < 2; i++ {
+				res := app.DeliverTx([]byte(fmt.Sprintf("key%d=value%d", height*2+i, height)))
+				require.Equal(t, abci.CodeTypeOK, res.Code)
+			}
+			app.Commit()
+		}
+		return app
+	}
+	app1, app2 := run(), run()
+	assert.Equal(t, app1.appHash, app2.appHash)
+	assert.EqualValues(t, 3, app1.Info(abci.RequestInfo{}).LastBlockHeight)
+
+	// the oldest keys were dropped, the values padded
+	assert.Equal(t, 3, app1.Size())
+	res := app1.Query(abci.RequestQuery{Data: []byte("key0")})
+	assert.Nil(t, res.Value)
+	res = app1.Query(abci.RequestQuery{Data: []byte("key5")})
+	assert.Equal(t, append([]byte("value2"), make([]byte, 10)...), res.Value)
+
+	// a different tx gives a different app hash
+	app3 := NewApplication(opts)
+	app3.DeliverTx([]byte("foo"))
+	app1.DeliverTx([]byte("bar"))
+	assert.NotEqual(t, app1.Commit().Data, app3.Commit().Data)
+
+	assert.NotEqual(t, abci.CodeTypeOK, app1.CheckTx(nil).Code)
+	assert.NotEqual(t, abci.CodeTypeOK, app1.DeliverTx(nil).Code)
+}
@@ -0,0 +1,206 @@
+package blockchain
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	wire "github.com/tendermint/go-wire"
+
+	"github.com/tendermint/tendermint/proxy"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+)
+
+// ArchiveVersion is the version of the format of the archives of blocks
+// written by ExportBlocks. The archives of other versions are rejected.
+const ArchiveVersion = 1
+
+// archiveMagic starts the archives, once uncompressed.
+var archiveMagic = []byte("TMBLOCKS")
+
+// ErrArchiveTruncated is returned when an archive ends before its last block.
+var ErrArchiveTruncated = errors.New("Archive truncated")
+
+// An archive of blocks is a gzip stream of the archiveMagic, the go-wire
+// encoded ArchiveHeader, then the ArchiveBlock of each height, in order, so
+// the blocks can be kept or copied to other nodes without the DBs.
+
+// ArchiveHeader describes the blocks of an archive.
+type ArchiveHeader struct {
+	Version int    `json:"version"`
+	ChainID string `json:"chain_id"`
+	From    int64  `json:"from"`
+	To      int64  `json:"to"`
+}
+
+// ArchiveBlock is a block of an archive, with the commit and the validators
+// to verify it.
+type ArchiveBlock struct {
+	Block *types.Block `json:"block"`
+	// the +2/3 precommits for the block: the LastCommit of the next block, or
+	// the seen commit for the last block of the store
+	Commit     *types.Commit       `json:"commit"`
+	Validators *types.ValidatorSet `json:"validators"`
+}
+
+// ExportBlocks writes the blocks of the store from the height to the other,
+// with their commits and validators, loaded by loadValidators, to an archive.
+// It returns the number of blocks written.
+func ExportBlocks(w io.Writer, store *BlockStore, chainID string, from, to int64,
+	loadValidators func(height int64) (*types.ValidatorSet, error)) (int64, error) {
+	if from < store.Base() || to > store.Height() || from > to {
+		return 0, fmt.Errorf("Cannot export the blocks %d to %d, the store has the blocks %d to %d",
+			from, to, store.Base(), store.Height())
+	}
+
+	gz := gzip.NewWriter(w)
+	var n int
+	var err error
+	if _, err = gz.Write(archiveMagic); err != nil {
+		return 0, err
+	}
+	wire.WriteBinary(ArchiveHeader{ArchiveVersion, chainID, from, to}, gz, &n, &err)
+	if err != nil {
+		return 0, err
+	}
+
+	for height := from; height <= to; height++ {
+		block := store.LoadBlock(height)
+		if block == nil {
+			return height - from, fmt.Errorf("Block %d not found, pruned meanwhile?", height)
+		}
+		commit := store.LoadBlockCommit(height)
+		if height == store.Height() || commit == nil {
+			commit = store.LoadSeenCommit(height)
+		}
+		if commit == nil {
+			return height - from, fmt.Errorf("Commit for block %d not found", height)
+		}
+		validators, err := loadValidators(height)
+		if err != nil {
+			return height - from, err
+		}
+		wire.WriteBinary(ArchiveBlock{block, commit, validators}, gz, &n, &err)
+		if err != nil {
+			return height - from, err
+		}
+	}
+	return to - from + 1, gz.Close()
+}
+
+// ArchiveReader reads the blocks of an archive, see ExportBlocks.
+type ArchiveReader struct {
+	gz     *gzip.Reader
+	header ArchiveHeader
+	height int64 // the height of the next block
+}
+
+// NewArchiveReader reads the header of the archive. It returns an error if it
+// isn't an archive of blocks of the ArchiveVersion.
+func NewArchiveReader(r io.Reader) (*ArchiveReader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("Not an archive of blocks: %v", err)
+	}
+	magic := make([]byte, len(archiveMagic))
+	if _, err := io.ReadFull(gz, magic); err != nil || !bytes.Equal(magic, archiveMagic) {
+		return nil, errors.New("Not an archive of blocks")
+	}
+
+	var n int
+	header := wire.ReadBinary(&ArchiveHeader{}, gz, 0, &n, &err).(*ArchiveHeader)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading the archive header: %v", err)
+	}
+	if header.Version != ArchiveVersion {
+		return nil, fmt.Errorf("Unsupported archive version %d, expected %d", header.Version, ArchiveVersion)
+	}
+	return &ArchiveReader{gz: gz, header: *header, height: header.From}, nil
+}
+
+// Header returns the header of the archive.
+func (ar *ArchiveReader) Header() ArchiveHeader {
+	return ar.header
+}
+
+// Next returns the next block of the archive, or io.EOF after the last one.
+// It checks that the block is the one of the next height, and that its
+// validators are the ones for it, but not the commit, see ImportBlocks.
+func (ar *ArchiveReader) Next() (*ArchiveBlock, error) {
+	if ar.height > ar.header.To {
+		return nil, io.EOF
+	}
+
+	var n int
+	var err error
+	ab := wire.ReadBinary(&ArchiveBlock{}, ar.gz, 0, &n, &err).(*ArchiveBlock)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, ErrArchiveTruncated
+	} else if err != nil {
+		return nil, fmt.Errorf("Error reading block %d: %v", ar.height, err)
+	}
+
+	switch {
+	case ab.Block == nil || ab.Commit == nil || ab.Validators == nil:
+		return nil, fmt.Errorf("Incomplete block %d", ar.height)
+	case ab.Block.Height != ar.height:
+		return nil, fmt.Errorf("Expected block %d, got %d", ar.height, ab.Block.Height)
+	case ab.Block.ChainID != ar.header.ChainID:
+		return nil, fmt.Errorf("Block %d of chain %s, expected %s", ar.height, ab.Block.ChainID, ar.header.ChainID)
+	case !bytes.Equal(ab.Validators.Hash(), ab.Block.ValidatorsHash):
+		return nil, fmt.Errorf("Validators of block %d don't match its ValidatorsHash", ar.height)
+	}
+	ar.height++
+	return ab, nil
+}
+
+// ImportBlocks applies the blocks of the archive after the last one of the
+// state, like the fast sync: each block is verified with the validators of
+// the state and its commit, saved in the store and applied to the app. The
+// blocks of the archive up to the height of the state are skipped, the store
+// and the state must be at the same height, eg. after the handshake. It
+// returns the number of blocks applied.
+func ImportBlocks(ar *ArchiveReader, state *sm.State, store *BlockStore, proxyAppConn proxy.AppConnConsensus) (int64, error) {
+	if ar.header.ChainID != state.ChainID {
+		return 0, fmt.Errorf("Archive of chain %s, expected %s", ar.header.ChainID, state.ChainID)
+	}
+	if store.Height() != state.LastBlockHeight {
+		return 0, fmt.Errorf("Store height %d doesn't match state height %d", store.Height(), state.LastBlockHeight)
+	}
+	if ar.header.From > state.LastBlockHeight+1 {
+		return 0, fmt.Errorf("Archive starts at %d, the next height is %d", ar.header.From, state.LastBlockHeight+1)
+	}
+
+	imported := int64(0)
+	for {
+		ab, err := ar.Next()
+		if err == io.EOF {
+			return imported, nil
+		} else if err != nil {
+			return imported, err
+		}
+		block := ab.Block
+		if block.Height <= state.LastBlockHeight {
+			continue
+		}
+
+		if !bytes.Equal(ab.Validators.Hash(), state.Validators.Hash()) {
+			return imported, fmt.Errorf("Validators of block %d don't match the ones of the state", block.Height)
+		}
+		parts := block.MakePartSet(state.Params.BlockPartSizeBytes)
+		blockID := types.BlockID{block.Hash(), parts.Header()}
+		if err := state.Validators.VerifyCommit(state.ChainID, blockID, block.Height, ab.Commit); err != nil {
+			return imported, fmt.Errorf("Invalid commit for block %d: %v", block.Height, err)
+		}
+
+		store.SaveBlock(block, parts, ab.Commit)
+		err = state.ApplyBlock(types.NopEventBus{}, proxyAppConn, block, parts.Header(), types.MockMempool{}, types.MockEvidencePool{})
+		if err != nil {
+			return imported, fmt.Errorf("Error applying block %d: %v", block.Height, err)
+		}
+		imported++
+	}
+}
@@ -0,0 +1,77 @@
+package blockchain
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	dbm "github.com/tendermint/tmlibs/db"
+
+	cfg "github.com/tendermint/tendermint/config"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestArchiveExportBlocks(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	config := cfg.ResetTestRoot("blockchain_archive_test")
+	state, _ := sm.GetState(dbm.NewMemDB(), config.GenesisFile())
+	partSize := state.Params.BlockGossipParams.BlockPartSizeBytes
+	chain := makeChain(10, state)
+	store := NewBlockStore(dbm.NewMemDB())
+	for _, block := range chain[:10] {
+		store.SaveBlock(block, block.MakePartSet(partSize), chain[block.Height].LastCommit)
+	}
+	loadValidators := func(height int64) (*types.ValidatorSet, error) {
+		return state.Validators, nil
+	}
+
+	// only the blocks of the store
+	_, err := ExportBlocks(new(bytes.Buffer), store, state.ChainID, 5, 11, loadValidators)
+	assert.NotNil(err)
+
+	buf := new(bytes.Buffer)
+	exported, err := ExportBlocks(buf, store, state.ChainID, 3, 10, loadValidators)
+	require.Nil(err)
+	assert.EqualValues(8, exported)
+	archive := buf.Bytes()
+
+	ar, err := NewArchiveReader(bytes.NewReader(archive))
+	require.Nil(err)
+	assert.Equal(ArchiveHeader{ArchiveVersion, state.ChainID, 3, 10}, ar.Header())
+	for h := int64(3); h <= 10; h++ {
+		ab, err := ar.Next()
+		require.Nil(err, "block %d", h)
+		assert.Equal(chain[h-1].Hash(), ab.Block.Hash())
+		assert.Equal(state.Validators.Hash(), ab.Validators.Hash())
+	}
+	_, err = ar.Next()
+	assert.Equal(io.EOF, err)
+
+	// a truncated archive
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	require.Nil(err)
+	uncompressed := new(bytes.Buffer)
+	_, err = io.Copy(uncompressed, gz)
+	require.Nil(err)
+	truncated := new(bytes.Buffer)
+	gzw := gzip.NewWriter(truncated)
+	_, err = gzw.Write(uncompressed.Bytes()[:uncompressed.Len()/2])
+	require.Nil(err)
+	require.Nil(gzw.Close())
+	ar, err = NewArchiveReader(truncated)
+	require.Nil(err)
+	for err == nil {
+		_, err = ar.Next()
+	}
+	assert.Equal(ErrArchiveTruncated, err)
+
+	// not an archive
+	_, err = NewArchiveReader(bytes.NewReader(uncompressed.Bytes()))
+	assert.NotNil(err)
+}
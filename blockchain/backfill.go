@@ -0,0 +1,146 @@
+package blockchain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/types"
+	cmn "github.com/tendermint/tmlibs/common"
+)
+
+const (
+	// how long to wait for a peer to send a block to backfill
+	backfillRequestTimeout = 10 * time.Second
+	// how long to wait before asking the peers again, once none sent a block
+	backfillRetryInterval = 5 * time.Second
+)
+
+// ErrBackfillAborted is returned by Backfill if the reactor is stopped.
+var ErrBackfillAborted = errors.New("Backfill aborted")
+
+// blockRequest is a block to backfill asked to a peer.
+type blockRequest struct {
+	peerKey string
+	height  int64
+}
+
+// Backfill fetches the blocks before the base of the store from the peers,
+// down to the stop height, eg. after a state sync or once pruned, so the node
+// serves them to the light clients and the explorers. Each block is verified
+// against the header of the next one, see BlockStore.BackfillBlock, the peers
+// sending a block which doesn't match being stopped. It returns once done, or
+// ErrBackfillAborted if the reactor is stopped.
+func (bcR *BlockchainReactor) Backfill(stopHeight int64) error {
+	if stopHeight < 1 {
+		stopHeight = 1
+	}
+	defer bcR.store.Flush()
+
+	backfilled := 0
+	lastFlush := time.Now()
+	for {
+		height := bcR.store.BackfillHeight()
+		if height < stopHeight {
+			bcR.Logger.Info("Backfill done", "base", bcR.store.Base(), "blocks", backfilled)
+			return nil
+		}
+		if !bcR.backfillBlock(height) {
+			select {
+			case <-time.After(backfillRetryInterval):
+				continue
+			case <-bcR.Quit:
+				return ErrBackfillAborted
+			}
+		}
+
+		if bcR.store.Unsynced() >= bcR.batchSize ||
+			(bcR.flushInterval > 0 && time.Since(lastFlush) >= bcR.flushInterval) {
+			bcR.store.Flush()
+			lastFlush = time.Now()
+		}
+		backfilled++
+		if backfilled%100 == 0 {
+			bcR.Logger.Info("Backfilling", "height", height, "stopHeight", stopHeight)
+		}
+	}
+}
+
+// backfillBlock asks the peers for the block of the height in turn, until one
+// sends it, and saves it. It returns false if none did.
+func (bcR *BlockchainReactor) backfillBlock(height int64) bool {
+	partSize, known := bcR.blockPartSize(height)
+	peers := bcR.Switch.Peers().List()
+	for _, i := range cmn.RandPerm(len(peers)) {
+		block := bcR.requestBlock(peers[i], height, backfillRequestTimeout)
+		if block == nil {
+			continue
+		}
+		parts := block.MakePartSet(partSize)
+		if err := bcR.store.BackfillBlock(block, parts); err != nil {
+			bcR.Logger.Error("Peer sent an invalid block to backfill", "peer", peers[i], "height", height, "err", err)
+			if known {
+				bcR.Switch.ReportMisbehavior(peers[i], p2p.SeverityHigh, err)
+			}
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// blockPartSize returns the size of the parts of the block of the height, as
+// of the consensus params of the height. It returns the current one and false
+// if they are not saved, eg. before the snapshot of a state sync, as the block
+// then doesn't match if the size changed since.
+func (bcR *BlockchainReactor) blockPartSize(height int64) (int, bool) {
+	params, err := bcR.state.LoadConsensusParams(height)
+	if err != nil {
+		return bcR.state.Params.BlockPartSizeBytes, false
+	}
+	return params.BlockPartSizeBytes, true
+}
+
+// requestBlock returns the block the peer sent, or nil if it doesn't have it
+// or timed out.
+func (bcR *BlockchainReactor) requestBlock(peer p2p.Peer, height int64, timeout time.Duration) *types.Block {
+	req := blockRequest{peer.Key(), height}
+	blockCh := make(chan *types.Block, 1)
+	bcR.backfillsMtx.Lock()
+	bcR.backfills[req] = blockCh
+	bcR.backfillsMtx.Unlock()
+	defer func() {
+		bcR.backfillsMtx.Lock()
+		delete(bcR.backfills, req)
+		bcR.backfillsMtx.Unlock()
+	}()
+
+	msg := &bcBlockRequestMessage{Height: height}
+	if !peer.TrySend(BlockchainChannel, struct{ BlockchainMessage }{msg}) {
+		return nil
+	}
+	select {
+	case block := <-blockCh:
+		return block
+	case <-time.After(timeout):
+		return nil
+	case <-bcR.Quit:
+		return nil
+	}
+}
+
+// deliverBlock hands the block sent by the peer, nil if it doesn't have it,
+// to the backfill waiting for it, if any. It returns false if none is.
+func (bcR *BlockchainReactor) deliverBlock(src p2p.Peer, height int64, block *types.Block) bool {
+	bcR.backfillsMtx.Lock()
+	defer bcR.backfillsMtx.Unlock()
+	blockCh, ok := bcR.backfills[blockRequest{src.Key(), height}]
+	if !ok {
+		return false
+	}
+	select {
+	case blockCh <- block:
+	default:
+	}
+	return true
+}
@@ -139,6 +139,15 @@ func (pool *BlockPool) GetStatus() (height int64, numPending int32, lenRequester
 	return pool.height, pool.numPending, len(pool.requesters)
 }
 
+// SetHeight sets the height of the next block to request, eg. after a state
+// sync. It must be called before the pool is started.
+func (pool *BlockPool) SetHeight(height int64) {
+	pool.mtx.Lock()
+	defer pool.mtx.Unlock()
+
+	pool.height = height
+}
+
 // TODO: relax conditions, prevent abuse.
 func (pool *BlockPool) IsCaughtUp() bool {
 	pool.mtx.Lock()
@@ -0,0 +1,120 @@
+package blockchain
+
+import (
+	"fmt"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// pcBlock is a block received by the v2 fast sync, from the peer.
+type pcBlock struct {
+	block  *types.Block
+	peerID string
+}
+
+// pcInvalid reports the blocks of the heights which couldn't be verified, and
+// the peers to blame: the one which sent the invalid block, or both peers if
+// ambiguous, as either block can be the invalid one.
+type pcInvalid struct {
+	heights   []int64
+	peerIDs   []string
+	ambiguous bool
+	err       error
+}
+
+// errInvalidBlock is returned by the verify function of the processor when
+// the block of the height is known to be the invalid one.
+type errInvalidBlock struct {
+	height int64
+	err    error
+}
+
+func (e errInvalidBlock) Error() string {
+	return fmt.Sprintf("Invalid block at height %d: %v", e.height, e.err)
+}
+
+// processor applies the blocks of the v2 fast sync in order, as they are
+// received in any order: each block is verified with the LastCommit of the
+// next one before it is applied.
+// NOTE: not thread safe, it is owned by the process routine.
+type processor struct {
+	height int64 // the next height to apply
+	blocks map[int64]pcBlock
+
+	// verify returns the parts of the first block, if it is committed by the
+	// LastCommit of the second one, or an errInvalidBlock if it can tell
+	// which one is invalid
+	verify func(first, second *types.Block) (*types.PartSet, error)
+	// apply saves and applies the first block, of the parts, committed by
+	// the seen commit
+	apply func(first *types.Block, firstParts *types.PartSet, seenCommit *types.Commit)
+}
+
+func newProcessor(height int64,
+	verify func(first, second *types.Block) (*types.PartSet, error),
+	apply func(first *types.Block, firstParts *types.PartSet, seenCommit *types.Commit)) *processor {
+	return &processor{
+		height: height,
+		blocks: make(map[int64]pcBlock),
+		verify: verify,
+		apply:  apply,
+	}
+}
+
+// add adds the block sent by the peer. It returns false if the height is
+// already applied or has a block.
+func (pc *processor) add(peerID string, block *types.Block) bool {
+	if block.Height < pc.height {
+		return false
+	}
+	if _, ok := pc.blocks[block.Height]; ok {
+		return false
+	}
+	pc.blocks[block.Height] = pcBlock{block, peerID}
+	return true
+}
+
+// process applies the blocks it can, up to max of them. It returns the last
+// height applied, 0 if none, and the blocks which couldn't be verified, if
+// any, which are dropped to be fetched again.
+func (pc *processor) process(max int) (int64, *pcInvalid) {
+	processed := int64(0)
+	for i := 0; i < max; i++ {
+		first, ok := pc.blocks[pc.height]
+		if !ok {
+			break
+		}
+		second, ok := pc.blocks[pc.height+1]
+		if !ok {
+			// We need both to apply the first block.
+			break
+		}
+		firstParts, err := pc.verify(first.block, second.block)
+		if err != nil {
+			// both blocks are fetched again, but only the peer of the invalid
+			// one is to blame, if known
+			invalid := &pcInvalid{
+				heights: []int64{pc.height, pc.height + 1},
+				err:     err,
+			}
+			blockErr, ok := err.(errInvalidBlock)
+			switch {
+			case ok && blockErr.height == pc.height:
+				invalid.peerIDs = []string{first.peerID}
+			case ok && blockErr.height == pc.height+1:
+				invalid.peerIDs = []string{second.peerID}
+			default:
+				invalid.peerIDs = []string{first.peerID, second.peerID}
+				invalid.ambiguous = true
+			}
+			delete(pc.blocks, pc.height)
+			delete(pc.blocks, pc.height+1)
+			return processed, invalid
+		}
+		pc.apply(first.block, firstParts, second.block.LastCommit)
+		delete(pc.blocks, pc.height)
+		processed = pc.height
+		pc.height++
+	}
+	return processed, nil
+}
@@ -0,0 +1,77 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestProcessor(t *testing.T) {
+	assert := assert.New(t)
+
+	// the blocks after badHeight don't commit the previous one, and the
+	// invalid one is known if culprit is set
+	badHeight, culprit := int64(0), int64(0)
+	var applied []int64
+	pc := newProcessor(1, func(first, second *types.Block) (*types.PartSet, error) {
+		if badHeight != 0 && second.Height > badHeight {
+			if culprit != 0 {
+				return nil, errInvalidBlock{culprit, errors.New("invalid block")}
+			}
+			return nil, errors.New("invalid commit")
+		}
+		return nil, nil
+	}, func(first *types.Block, firstParts *types.PartSet, seenCommit *types.Commit) {
+		applied = append(applied, first.Height)
+	})
+	block := func(height int64) *types.Block {
+		return &types.Block{Header: &types.Header{Height: height}}
+	}
+
+	// the blocks are applied in order, once the next one is received
+	assert.True(pc.add("a", block(2)))
+	assert.True(pc.add("b", block(3)))
+	processed, invalid := pc.process(10)
+	assert.Zero(processed)
+	assert.Nil(invalid)
+	assert.True(pc.add("a", block(1)))
+	assert.False(pc.add("b", block(1)), "already received")
+	processed, invalid = pc.process(10)
+	assert.EqualValues(2, processed)
+	assert.Nil(invalid)
+	assert.Equal([]int64{1, 2}, applied)
+	assert.False(pc.add("a", block(2)), "already applied")
+
+	// the blocks which can't be verified are dropped
+	badHeight = 3
+	assert.True(pc.add("c", block(4)))
+	processed, invalid = pc.process(10)
+	assert.Zero(processed)
+	if assert.NotNil(invalid) {
+		assert.Equal([]int64{3, 4}, invalid.heights)
+		assert.Equal([]string{"b", "c"}, invalid.peerIDs)
+		assert.True(invalid.ambiguous)
+	}
+
+	// only the peer of the invalid block is to blame, if known
+	culprit = 4
+	assert.True(pc.add("a", block(3)))
+	assert.True(pc.add("c", block(4)))
+	processed, invalid = pc.process(10)
+	assert.Zero(processed)
+	if assert.NotNil(invalid) {
+		assert.Equal([]int64{3, 4}, invalid.heights)
+		assert.Equal([]string{"c"}, invalid.peerIDs)
+		assert.False(invalid.ambiguous)
+	}
+
+	badHeight = 0
+	assert.True(pc.add("a", block(3)))
+	assert.True(pc.add("a", block(4)))
+	processed, invalid = pc.process(10)
+	assert.EqualValues(3, processed)
+	assert.Nil(invalid)
+}
@@ -0,0 +1,129 @@
+package blockchain
+
+import (
+	"fmt"
+	"time"
+
+	abci "github.com/tendermint/abci/types"
+	wire "github.com/tendermint/go-wire"
+	cmn "github.com/tendermint/tmlibs/common"
+
+	"github.com/tendermint/tendermint/proxy"
+)
+
+// QueryPathRetainHeight is the query path of the retain height of the app,
+// see NewAppRetainHeight.
+const QueryPathRetainHeight = "/retain_height"
+
+// RetainHeightFunc returns the height of the first block to retain, 0 for all.
+type RetainHeightFunc func() (int64, error)
+
+// NewAppRetainHeight returns the retain height asked by the app, over its
+// queries as abci has no method for it yet: the app answers the queries of
+// QueryPathRetainHeight with the go-wire encoded height in the Value, empty
+// to retain all the blocks.
+func NewAppRetainHeight(conn proxy.AppConnQuery) RetainHeightFunc {
+	return func() (int64, error) {
+		res, err := conn.QuerySync(abci.RequestQuery{Path: QueryPathRetainHeight})
+		if err != nil {
+			return 0, err
+		}
+		if res.IsErr() {
+			return 0, fmt.Errorf("Error getting the retain height: %v", res.Log)
+		}
+		if len(res.Value) == 0 {
+			return 0, nil
+		}
+		var retainHeight int64
+		if err := wire.ReadBinaryBytes(res.Value, &retainHeight); err != nil {
+			return 0, fmt.Errorf("Error decoding the retain height: %v", err)
+		}
+		return retainHeight, nil
+	}
+}
+
+// BlockPruner deletes the blocks of the store before the retain height, for
+// the nodes which don't need the whole chain. The retain height is the one
+// asked by the app, if any, but the blocks of the last minRetainBlocks
+// heights are kept, if set. The ABCI responses are pruned independently, see
+// state.ABCIResponsesPruner.
+type BlockPruner struct {
+	cmn.BaseService
+
+	store           *BlockStore
+	minRetainBlocks int64
+	appRetainHeight RetainHeightFunc // nil if the app doesn't prune
+	interval        time.Duration
+}
+
+// NewBlockPruner returns a pruner of the blocks of the store, every interval.
+func NewBlockPruner(store *BlockStore, minRetainBlocks int64, appRetainHeight RetainHeightFunc,
+	interval time.Duration) *BlockPruner {
+	pruner := &BlockPruner{
+		store:           store,
+		minRetainBlocks: minRetainBlocks,
+		appRetainHeight: appRetainHeight,
+		interval:        interval,
+	}
+	pruner.BaseService = *cmn.NewBaseService(nil, "BlockPruner", pruner)
+	return pruner
+}
+
+// OnStart implements cmn.Service by starting the pruning routine.
+func (p *BlockPruner) OnStart() error {
+	go p.pruneRoutine()
+	return nil
+}
+
+func (p *BlockPruner) pruneRoutine() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			pruned, err := p.Prune()
+			if err != nil {
+				p.Logger.Error("Error pruning the blocks", "err", err)
+			} else if pruned > 0 {
+				p.Logger.Info("Pruned blocks", "blocks", pruned, "base", p.store.Base())
+			}
+		case <-p.Quit:
+			return
+		}
+	}
+}
+
+// RetainHeight returns the height of the first block to retain, 0 for all:
+// the lowest of the one asked by the app and the one of the last
+// minRetainBlocks heights, of those set.
+func (p *BlockPruner) RetainHeight() (int64, error) {
+	retainHeight := int64(0)
+	if p.appRetainHeight != nil {
+		appRetainHeight, err := p.appRetainHeight()
+		if err != nil {
+			return 0, err
+		}
+		retainHeight = appRetainHeight
+	}
+	if p.minRetainBlocks > 0 {
+		minRetainHeight := cmn.MaxInt64(p.store.Height()-p.minRetainBlocks+1, 1)
+		if retainHeight == 0 || minRetainHeight < retainHeight {
+			retainHeight = minRetainHeight
+		}
+	}
+	return retainHeight, nil
+}
+
+// Prune deletes the blocks before the retain height, but the last one, and
+// returns the number of blocks pruned.
+func (p *BlockPruner) Prune() (int64, error) {
+	retainHeight, err := p.RetainHeight()
+	if err != nil {
+		return 0, err
+	}
+	retainHeight = cmn.MinInt64(retainHeight, p.store.Height())
+	if retainHeight <= p.store.Base() {
+		return 0, nil
+	}
+	return p.store.PruneBlocks(retainHeight)
+}
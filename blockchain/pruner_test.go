@@ -0,0 +1,60 @@
+package blockchain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	dbm "github.com/tendermint/tmlibs/db"
+
+	cfg "github.com/tendermint/tendermint/config"
+	sm "github.com/tendermint/tendermint/state"
+)
+
+func TestBlockPruner(t *testing.T) {
+	assert := assert.New(t)
+
+	config := cfg.ResetTestRoot("blockchain_pruner_test")
+	state, _ := sm.GetState(dbm.NewMemDB(), config.GenesisFile())
+	store := NewBlockStore(dbm.NewMemDB())
+	for height := int64(1); height <= 20; height++ {
+		block := makeBlock(height, state)
+		parts := block.MakePartSet(state.Params.BlockGossipParams.BlockPartSizeBytes)
+		store.SaveBlock(block, parts, makeBlock(height+1, state).LastCommit)
+	}
+
+	// the last minRetainBlocks blocks are kept
+	pruner := NewBlockPruner(store, 15, nil, time.Hour)
+	pruned, err := pruner.Prune()
+	assert.Nil(err)
+	assert.EqualValues(5, pruned)
+	assert.EqualValues(6, store.Base())
+
+	// the app can ask for less, not more
+	appRetainHeight := int64(0)
+	var appErr error
+	pruner = NewBlockPruner(store, 10, func() (int64, error) { return appRetainHeight, appErr }, time.Hour)
+	appRetainHeight = 8
+	pruned, err = pruner.Prune()
+	assert.Nil(err)
+	assert.EqualValues(2, pruned)
+	assert.EqualValues(8, store.Base())
+	appRetainHeight = 18
+	pruned, err = pruner.Prune()
+	assert.Nil(err)
+	assert.EqualValues(3, pruned)
+	assert.EqualValues(11, store.Base(), "the last 10 blocks are kept")
+
+	appErr = errors.New("app down")
+	_, err = pruner.Prune()
+	assert.NotNil(err)
+
+	// without min, the app can prune up to the last block
+	pruner = NewBlockPruner(store, 0, func() (int64, error) { return 30, nil }, time.Hour)
+	pruned, err = pruner.Prune()
+	assert.Nil(err)
+	assert.EqualValues(9, pruned)
+	assert.EqualValues(20, store.Base())
+}
@@ -3,7 +3,9 @@ package blockchain
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"reflect"
+	"sync"
 	"time"
 
 	wire "github.com/tendermint/go-wire"
@@ -49,7 +51,42 @@ type BlockchainReactor struct {
 	requestsCh   chan BlockRequest
 	timeoutsCh   chan string
 
+	// the v2 fast sync, instead of the pool, if set
+	v2 *fastSyncV2
+
+	// the backfilled blocks are synced to disk every batchSize blocks, and
+	// every flushInterval
+	batchSize     int
+	flushInterval time.Duration
+
 	eventBus *types.EventBus
+
+	// tracks the evidence committed in the blocks
+	evpool types.EvidencePool
+
+	// called synchronously once a block is committed, if set
+	onBlockCommitted sm.BlockCommittedFunc
+
+	// the data availability shares of the last block the peers sampled,
+	// see loadShare
+	sharesMtx    sync.Mutex
+	sharesHeight int64
+	shares       *types.PartSet
+
+	// the shares we sampled from the peers, waiting for their response
+	samplesMtx sync.Mutex
+	samples    map[shareRequest]chan *types.Part
+
+	// the blocks to backfill we asked the peers for, see Backfill
+	backfillsMtx sync.Mutex
+	backfills    map[blockRequest]chan *types.Block
+}
+
+// shareRequest is a data availability share asked to a peer.
+type shareRequest struct {
+	peerKey string
+	height  int64
+	index   int
 }
 
 // NewBlockchainReactor returns new reactor instance.
@@ -72,11 +109,42 @@ func NewBlockchainReactor(state *sm.State, proxyAppConn proxy.AppConnConsensus,
 		fastSync:     fastSync,
 		requestsCh:   requestsCh,
 		timeoutsCh:   timeoutsCh,
+		batchSize:    1,
+		evpool:       types.MockEvidencePool{},
+		samples:      make(map[shareRequest]chan *types.Part),
+		backfills:    make(map[blockRequest]chan *types.Block),
 	}
 	bcR.BaseReactor = *p2p.NewBaseReactor("BlockchainReactor", bcR)
 	return bcR
 }
 
+// SetBatching makes the backfill write the blocks to disk without waiting for
+// each one to be synced, and sync them every batchSize blocks and every flushInterval
+// (0 for none). A batchSize of 1 syncs every block.
+// NOTE: fast sync syncs every block, before the state saved with it.
+// NOTE: not thread safe - should only be called once, on startup
+func (bcR *BlockchainReactor) SetBatching(batchSize int, flushInterval time.Duration) {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	bcR.batchSize = batchSize
+	bcR.flushInterval = flushInterval
+}
+
+// SetEvidencePool sets the pool tracking the evidence of byzantine validators,
+// to mark the evidence of the synced blocks as committed.
+// NOTE: not thread safe - should only be called once, on startup
+func (bcR *BlockchainReactor) SetEvidencePool(evpool types.EvidencePool) {
+	bcR.evpool = evpool
+}
+
+// SetBlockCommittedFunc sets the function called once a block is committed by
+// fast sync, from the goroutine of the sync, which waits for it to return.
+// NOTE: not thread safe - should only be called once, on startup
+func (bcR *BlockchainReactor) SetBlockCommittedFunc(f sm.BlockCommittedFunc) {
+	bcR.onBlockCommitted = f
+}
+
 // SetLogger implements cmn.Service by setting the logger on reactor and pool.
 func (bcR *BlockchainReactor) SetLogger(l log.Logger) {
 	bcR.BaseService.Logger = l
@@ -89,15 +157,41 @@ func (bcR *BlockchainReactor) OnStart() error {
 		return err
 	}
 	if bcR.fastSync {
-		err := bcR.pool.Start()
-		if err != nil {
-			return err
-		}
-		go bcR.poolRoutine()
+		return bcR.startFastSync()
+	}
+	return nil
+}
+
+// startFastSync starts the routines of the fast sync of the version set.
+func (bcR *BlockchainReactor) startFastSync() error {
+	if bcR.v2 != nil {
+		bcR.startFastSyncV2()
+		return nil
+	}
+	if err := bcR.pool.Start(); err != nil {
+		return err
 	}
+	go bcR.poolRoutine()
 	return nil
 }
 
+// SwitchToFastSync starts fast syncing from the state, eg. restored from a
+// snapshot by the state sync, once the store is bootstrapped to its height.
+// The reactor must have been started without fast sync.
+func (bcR *BlockchainReactor) SwitchToFastSync(state *sm.State) error {
+	if bcR.fastSync {
+		return errors.New("BlockchainReactor is already fast syncing")
+	}
+	if state.LastBlockHeight != bcR.store.Height() {
+		return fmt.Errorf("state (%v) and store (%v) height mismatch", state.LastBlockHeight, bcR.store.Height())
+	}
+	bcR.Logger.Info("SwitchToFastSync", "height", state.LastBlockHeight)
+	bcR.fastSync = true
+	bcR.state = state
+	bcR.pool.SetHeight(state.LastBlockHeight + 1)
+	return bcR.startFastSync()
+}
+
 // OnStop implements cmn.Service.
 func (bcR *BlockchainReactor) OnStop() {
 	bcR.BaseReactor.OnStop()
@@ -126,6 +220,10 @@ func (bcR *BlockchainReactor) AddPeer(peer p2p.Peer) {
 
 // RemovePeer implements Reactor by removing peer from the pool.
 func (bcR *BlockchainReactor) RemovePeer(peer p2p.Peer, reason interface{}) {
+	if bcR.v2 != nil {
+		bcR.sendEvent(bcPeerRemovedEvent{peer.Key()})
+		return
+	}
 	bcR.pool.RemovePeer(peer.Key())
 }
 
@@ -147,7 +245,115 @@ func (bcR *BlockchainReactor) respondToPeer(msg *bcBlockRequestMessage, src p2p.
 	})
 }
 
-// Receive implements Reactor by handling 4 types of messages (look below).
+// respondToShareRequest sends a data availability share of a block to the
+// requesting peer, if we have it. Otherwise, we'll respond saying we don't
+// have it.
+func (bcR *BlockchainReactor) respondToShareRequest(msg *bcShareRequestMessage, src p2p.Peer) (queued bool) {
+	share := bcR.loadShare(msg.Height, msg.Index)
+	if share != nil {
+		msg := &bcShareResponseMessage{Height: msg.Height, Share: share}
+		return src.TrySend(BlockchainChannel, struct{ BlockchainMessage }{msg})
+	}
+
+	return src.TrySend(BlockchainChannel, struct{ BlockchainMessage }{
+		&bcNoShareResponseMessage{Height: msg.Height, Index: msg.Index},
+	})
+}
+
+// loadShare returns the data availability share at the index of the block at
+// the height, or nil if we don't have the block or the chain doesn't erasure
+// code its txs. The shares of the last block asked for are kept, as the
+// samplers ask for many shares of the same, recent, blocks.
+func (bcR *BlockchainReactor) loadShare(height int64, index int) *types.Part {
+	bcR.sharesMtx.Lock()
+	defer bcR.sharesMtx.Unlock()
+	if bcR.shares == nil || bcR.sharesHeight != height {
+		scheme := bcR.state.Params.DataAvailabilityParams.NewScheme()
+		if scheme == nil {
+			return nil
+		}
+		block := bcR.store.LoadBlock(height)
+		if block == nil || block.DataAvailabilityHeader.IsZero() {
+			return nil
+		}
+		shares, err := block.MakeDataAvailabilityShares(scheme)
+		if err != nil {
+			bcR.Logger.Error("Error erasure coding the txs", "height", height, "err", err)
+			return nil
+		}
+		bcR.sharesHeight, bcR.shares = height, shares
+	}
+	if index < 0 || index >= bcR.shares.Total() {
+		return nil
+	}
+	return bcR.shares.GetPart(index)
+}
+
+// SampleDataAvailability samples the data availability shares of the block at
+// the height with the header, eg. the DataAvailabilityHeader of a header
+// verified by a light node, from the peers (EXPERIMENTAL), see
+// types.SampleDataAvailability. Each share is asked to the peers in turn,
+// waiting up to timeout for each, until one sends it.
+func (bcR *BlockchainReactor) SampleDataAvailability(height int64, header types.PartSetHeader, samples int, timeout time.Duration) error {
+	return types.SampleDataAvailability(header, samples, func(index int) (*types.Part, error) {
+		peers := bcR.Switch.Peers().List()
+		for _, i := range cmn.RandPerm(len(peers)) {
+			share := bcR.requestShare(peers[i], height, index, timeout)
+			if share == nil {
+				continue
+			}
+			if err := types.VerifyDataAvailabilityShare(header, share); err != nil {
+				bcR.Logger.Error("Peer sent an invalid share", "peer", peers[i], "height", height, "err", err)
+				continue
+			}
+			return share, nil
+		}
+		return nil, errors.New("No peer sent it")
+	})
+}
+
+// requestShare returns the share the peer sent, or nil if it doesn't have it
+// or timed out.
+func (bcR *BlockchainReactor) requestShare(peer p2p.Peer, height int64, index int, timeout time.Duration) *types.Part {
+	req := shareRequest{peer.Key(), height, index}
+	shareCh := make(chan *types.Part, 1)
+	bcR.samplesMtx.Lock()
+	bcR.samples[req] = shareCh
+	bcR.samplesMtx.Unlock()
+	defer func() {
+		bcR.samplesMtx.Lock()
+		delete(bcR.samples, req)
+		bcR.samplesMtx.Unlock()
+	}()
+
+	msg := &bcShareRequestMessage{Height: height, Index: index}
+	if !peer.TrySend(BlockchainChannel, struct{ BlockchainMessage }{msg}) {
+		return nil
+	}
+	select {
+	case share := <-shareCh:
+		return share
+	case <-time.After(timeout):
+		return nil
+	case <-bcR.Quit:
+		return nil
+	}
+}
+
+// deliverShare hands the share sent by the peer, nil if it doesn't have it,
+// to the sampler waiting for it, if any.
+func (bcR *BlockchainReactor) deliverShare(src p2p.Peer, height int64, index int, share *types.Part) {
+	bcR.samplesMtx.Lock()
+	defer bcR.samplesMtx.Unlock()
+	if shareCh, ok := bcR.samples[shareRequest{src.Key(), height, index}]; ok {
+		select {
+		case shareCh <- share:
+		default:
+		}
+	}
+}
+
+// Receive implements Reactor by handling 8 types of messages (look below).
 func (bcR *BlockchainReactor) Receive(chID byte, src p2p.Peer, msgBytes []byte) {
 	_, msg, err := DecodeMessage(msgBytes, bcR.maxMsgSize())
 	if err != nil {
@@ -165,7 +371,15 @@ func (bcR *BlockchainReactor) Receive(chID byte, src p2p.Peer, msgBytes []byte)
 		}
 	case *bcBlockResponseMessage:
 		// Got a block.
-		bcR.pool.AddBlock(src.Key(), msg.Block, len(msgBytes))
+		if bcR.deliverBlock(src, msg.Block.Height, msg.Block) {
+			// to backfill, verified by Backfill.
+		} else if bcR.v2 != nil {
+			bcR.sendEvent(bcBlockEvent{src.Key(), msg.Block, len(msgBytes)})
+		} else {
+			bcR.pool.AddBlock(src.Key(), msg.Block, len(msgBytes))
+		}
+	case *bcNoBlockResponseMessage:
+		bcR.deliverBlock(src, msg.Height, nil)
 	case *bcStatusRequestMessage:
 		// Send peer our state.
 		queued := src.TrySend(BlockchainChannel, struct{ BlockchainMessage }{&bcStatusResponseMessage{bcR.store.Height()}})
@@ -174,7 +388,22 @@ func (bcR *BlockchainReactor) Receive(chID byte, src p2p.Peer, msgBytes []byte)
 		}
 	case *bcStatusResponseMessage:
 		// Got a peer status. Unverified.
-		bcR.pool.SetPeerHeight(src.Key(), msg.Height)
+		if bcR.v2 != nil {
+			bcR.sendEvent(bcPeerStatusEvent{src.Key(), msg.Height})
+		} else {
+			bcR.pool.SetPeerHeight(src.Key(), msg.Height)
+		}
+	case *bcShareRequestMessage:
+		if queued := bcR.respondToShareRequest(msg, src); !queued {
+			// the sampler will ask another peer.
+		}
+	case *bcShareResponseMessage:
+		// Got a share. Verified by the sampler.
+		if msg.Share != nil {
+			bcR.deliverShare(src, msg.Height, msg.Share.Index, msg.Share)
+		}
+	case *bcNoShareResponseMessage:
+		bcR.deliverShare(src, msg.Height, msg.Index, nil)
 	default:
 		bcR.Logger.Error(cmn.Fmt("Unknown message type %v", reflect.TypeOf(msg)))
 	}
@@ -197,8 +426,6 @@ func (bcR *BlockchainReactor) poolRoutine() {
 
 	blocksSynced := 0
 
-	chainID := bcR.state.ChainID
-
 	lastHundred := time.Now()
 	lastRate := 0.0
 
@@ -251,14 +478,8 @@ FOR_LOOP:
 					// We need both to sync the first block.
 					break SYNC_LOOP
 				}
-				firstParts := first.MakePartSet(bcR.state.Params.BlockPartSizeBytes)
-				firstPartsHeader := firstParts.Header()
 				// Finally, verify the first block using the second's commit
-				// NOTE: we can probably make this more efficient, but note that calling
-				// first.Hash() doesn't verify the tx contents, so MakePartSet() is
-				// currently necessary.
-				err := bcR.state.Validators.VerifyCommit(
-					chainID, types.BlockID{first.Hash(), firstPartsHeader}, first.Height, second.LastCommit)
+				firstParts, err := bcR.verifyBlock(first, second)
 				if err != nil {
 					bcR.Logger.Error("Error in validation", "err", err)
 					bcR.pool.RedoRequest(first.Height)
@@ -266,17 +487,7 @@ FOR_LOOP:
 				} else {
 					bcR.pool.PopRequest()
 
-					bcR.store.SaveBlock(first, firstParts, second.LastCommit)
-
-					// TODO: should we be firing events? need to fire NewBlock events manually ...
-					// NOTE: we could improve performance if we
-					// didn't make the app commit to disk every block
-					// ... but we would need a way to get the hash without it persisting
-					err := bcR.state.ApplyBlock(bcR.eventBus, bcR.proxyAppConn, first, firstPartsHeader, types.MockMempool{})
-					if err != nil {
-						// TODO This is bad, are we zombie?
-						cmn.PanicQ(cmn.Fmt("Failed to process committed block (%d:%X): %v", first.Height, first.Hash(), err))
-					}
+					bcR.commitBlock(first, firstParts, second.LastCommit)
 					blocksSynced += 1
 
 					if blocksSynced%100 == 0 {
@@ -294,6 +505,52 @@ FOR_LOOP:
 	}
 }
 
+// verifyBlock returns the parts of the first block, if it is committed by the
+// LastCommit of the second one, else an errInvalidBlock of the invalid one.
+func (bcR *BlockchainReactor) verifyBlock(first, second *types.Block) (*types.PartSet, error) {
+	if second.LastCommit == nil {
+		return nil, errInvalidBlock{second.Height, errors.New("No LastCommit")}
+	}
+	firstParts := first.MakePartSet(bcR.state.Params.BlockPartSizeBytes)
+	// NOTE: we can probably make this more efficient, but note that calling
+	// first.Hash() doesn't verify the tx contents, so MakePartSet() is
+	// currently necessary.
+	blockID := types.BlockID{first.Hash(), firstParts.Header()}
+	err := bcR.state.Validators.VerifyCommit(bcR.state.ChainID, blockID, first.Height, second.LastCommit)
+	if err == nil {
+		return firstParts, nil
+	}
+	// a valid commit of another block proves the first one invalid, else the
+	// LastCommit of the second one is
+	commitID := second.LastCommit.BlockID
+	if !commitID.Equals(blockID) && bcR.state.Validators.VerifyCommit(bcR.state.ChainID, commitID, first.Height, second.LastCommit) == nil {
+		return nil, errInvalidBlock{first.Height, err}
+	}
+	return nil, errInvalidBlock{second.Height, err}
+}
+
+// commitBlock saves the verified block, committed by the seen commit, and
+// applies it to the state.
+func (bcR *BlockchainReactor) commitBlock(block *types.Block, blockParts *types.PartSet, seenCommit *types.Commit) {
+	// the block is synced before the state is saved: the handshake can't
+	// recover from a state ahead of the store, after a crash of the machine
+	bcR.store.SaveBlock(block, blockParts, seenCommit)
+
+	// TODO: should we be firing events? need to fire NewBlock events manually ...
+	// NOTE: we could improve performance if we
+	// didn't make the app commit to disk every block
+	// ... but we would need a way to get the hash without it persisting
+	err := bcR.state.ApplyBlock(bcR.eventBus, bcR.proxyAppConn, block, blockParts.Header(), types.MockMempool{}, bcR.evpool)
+	if err != nil {
+		// TODO This is bad, are we zombie?
+		cmn.PanicQ(cmn.Fmt("Failed to process committed block (%d:%X): %v", block.Height, block.Hash(), err))
+	}
+	bcR.evpool.MarkEvidenceAsCommitted(block.Evidence.Evidence)
+	if bcR.onBlockCommitted != nil {
+		bcR.onBlockCommitted(block, bcR.state.Copy())
+	}
+}
+
 // BroadcastStatusRequest broadcasts `BlockStore` height.
 func (bcR *BlockchainReactor) BroadcastStatusRequest() error {
 	bcR.Switch.Broadcast(BlockchainChannel, struct{ BlockchainMessage }{&bcStatusRequestMessage{bcR.store.Height()}})
@@ -314,6 +571,9 @@ const (
 	msgTypeNoBlockResponse = byte(0x12)
 	msgTypeStatusResponse  = byte(0x20)
 	msgTypeStatusRequest   = byte(0x21)
+	msgTypeShareRequest    = byte(0x30)
+	msgTypeShareResponse   = byte(0x31)
+	msgTypeNoShareResponse = byte(0x32)
 )
 
 // BlockchainMessage is a generic message for this reactor.
@@ -326,6 +586,9 @@ var _ = wire.RegisterInterface(
 	wire.ConcreteType{&bcNoBlockResponseMessage{}, msgTypeNoBlockResponse},
 	wire.ConcreteType{&bcStatusResponseMessage{}, msgTypeStatusResponse},
 	wire.ConcreteType{&bcStatusRequestMessage{}, msgTypeStatusRequest},
+	wire.ConcreteType{&bcShareRequestMessage{}, msgTypeShareRequest},
+	wire.ConcreteType{&bcShareResponseMessage{}, msgTypeShareResponse},
+	wire.ConcreteType{&bcNoShareResponseMessage{}, msgTypeNoShareResponse},
 )
 
 // DecodeMessage decodes BlockchainMessage.
@@ -389,3 +652,32 @@ type bcStatusResponseMessage struct {
 func (m *bcStatusResponseMessage) String() string {
 	return cmn.Fmt("[bcStatusResponseMessage %v]", m.Height)
 }
+
+//-------------------------------------
+
+type bcShareRequestMessage struct {
+	Height int64
+	Index  int
+}
+
+func (m *bcShareRequestMessage) String() string {
+	return cmn.Fmt("[bcShareRequestMessage %v/%v]", m.Height, m.Index)
+}
+
+type bcShareResponseMessage struct {
+	Height int64
+	Share  *types.Part
+}
+
+func (m *bcShareResponseMessage) String() string {
+	return cmn.Fmt("[bcShareResponseMessage %v %v]", m.Height, m.Share)
+}
+
+type bcNoShareResponseMessage struct {
+	Height int64
+	Index  int
+}
+
+func (m *bcNoShareResponseMessage) String() string {
+	return cmn.Fmt("[bcNoShareResponseMessage %v/%v]", m.Height, m.Index)
+}
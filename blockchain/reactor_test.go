@@ -2,6 +2,10 @@ package blockchain
 
 import (
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	wire "github.com/tendermint/go-wire"
 	cmn "github.com/tendermint/tmlibs/common"
@@ -90,6 +94,80 @@ func TestNoBlockMessageResponse(t *testing.T) {
 	}
 }
 
+func TestShareMessageResponse(t *testing.T) {
+	bcr := newBlockchainReactor(0)
+	bcr.state.Params.DataAvailabilityParams = types.DataAvailabilityParams{
+		Scheme:         types.DataAvailabilityReedSolomon,
+		ShareSizeBytes: 8,
+	}
+	block := makeBlock(1, bcr.state)
+	shares, err := block.MakeDataAvailabilityShares(bcr.state.Params.DataAvailabilityParams.NewScheme())
+	require.Nil(t, err)
+	block.DataAvailabilityHeader = shares.Header()
+	bcr.store.SaveBlock(block, block.MakePartSet(bcr.state.Params.BlockPartSizeBytes), makeBlock(2, bcr.state).LastCommit)
+	bcr.Start()
+	defer bcr.Stop()
+
+	peer := newbcrTestPeer(cmn.RandStr(12))
+	chID := byte(0x01)
+
+	tests := []struct {
+		height   int64
+		index    int
+		existent bool
+	}{
+		{1, 0, true},
+		{1, shares.Total() - 1, true},
+		{1, shares.Total(), false},
+		{1, -1, false},
+		{2, 0, false},
+	}
+
+	for _, tt := range tests {
+		reqShareBytes := wire.BinaryBytes(struct{ BlockchainMessage }{&bcShareRequestMessage{tt.height, tt.index}})
+		bcr.Receive(chID, peer, reqShareBytes)
+		msg := peer.lastValue().(struct{ BlockchainMessage }).BlockchainMessage
+
+		if tt.existent {
+			shareMsg, ok := msg.(*bcShareResponseMessage)
+			require.True(t, ok, "Expected to receive a share response for %d/%d", tt.height, tt.index)
+			assert.Equal(t, tt.index, shareMsg.Share.Index)
+			assert.Nil(t, types.VerifyDataAvailabilityShare(block.DataAvailabilityHeader, shareMsg.Share))
+		} else {
+			noShareMsg, ok := msg.(*bcNoShareResponseMessage)
+			require.True(t, ok, "Expected to receive a no share response for %d/%d", tt.height, tt.index)
+			assert.Equal(t, tt.height, noShareMsg.Height)
+			assert.Equal(t, tt.index, noShareMsg.Index)
+		}
+	}
+
+	// the response is handed to the sampler waiting for it
+	sampled := make(chan *types.Part)
+	go func() {
+		sampled <- bcr.requestShare(peer, 1, 2, time.Second)
+	}()
+	msg := peer.lastValue().(struct{ BlockchainMessage }).BlockchainMessage
+	reqMsg, ok := msg.(*bcShareRequestMessage)
+	require.True(t, ok)
+	resShareBytes := wire.BinaryBytes(struct{ BlockchainMessage }{&bcShareResponseMessage{reqMsg.Height, shares.GetPart(reqMsg.Index)}})
+	bcr.Receive(chID, peer, resShareBytes)
+	select {
+	case share := <-sampled:
+		require.NotNil(t, share)
+		assert.Equal(t, 2, share.Index)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the sampler to get the share")
+	}
+
+	go func() {
+		sampled <- bcr.requestShare(peer, 1, 3, time.Second)
+	}()
+	peer.lastValue()
+	noShareBytes := wire.BinaryBytes(struct{ BlockchainMessage }{&bcNoShareResponseMessage{1, 3}})
+	bcr.Receive(chID, peer, noShareBytes)
+	assert.Nil(t, <-sampled)
+}
+
 //----------------------------------------------
 // utility funcs
 
@@ -106,7 +184,8 @@ func makeBlock(height int64, state *sm.State) *types.Block {
 	valHash := state.Validators.Hash()
 	prevBlockID := types.BlockID{prevHash, prevParts}
 	block, _ := types.MakeBlock(height, "test_chain", makeTxs(height),
-		new(types.Commit), prevBlockID, valHash, state.AppHash, state.Params.BlockGossipParams.BlockPartSizeBytes)
+		new(types.Commit), prevBlockID, valHash, state.AppHash, state.LastResultsHash,
+		state.Params.BlockGossipParams.BlockPartSizeBytes)
 	return block
 }
 
@@ -145,6 +224,7 @@ func (tp *bcrTestPeer) Send(chID byte, data interface{}) bool { return tp.TrySen
 func (tp *bcrTestPeer) NodeInfo() *p2p.NodeInfo               { return nil }
 func (tp *bcrTestPeer) Status() p2p.ConnectionStatus          { return p2p.ConnectionStatus{} }
 func (tp *bcrTestPeer) Key() string                           { return tp.key }
+func (tp *bcrTestPeer) ID() p2p.ID                            { return p2p.ID(tp.key) }
 func (tp *bcrTestPeer) IsOutbound() bool                      { return false }
 func (tp *bcrTestPeer) IsPersistent() bool                    { return true }
 func (tp *bcrTestPeer) Get(s string) interface{}              { return s }
@@ -0,0 +1,321 @@
+package blockchain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	// the max number of blocks applied by the process routine before it
+	// reports them to the schedule routine
+	processBatchSize = 10
+)
+
+// the events of the peers, sent from Receive to the schedule routine
+type bcPeerStatusEvent struct {
+	peerID string
+	height int64
+}
+
+type bcBlockEvent struct {
+	peerID string
+	block  *types.Block
+	size   int
+}
+
+type bcPeerRemovedEvent struct {
+	peerID string
+}
+
+// the results of the process routine, sent to the schedule routine
+type pcProcessedEvent struct {
+	height int64
+}
+
+type pcInvalidEvent struct {
+	*pcInvalid
+}
+
+// the work of the IO routine, sent by the schedule routine
+type ioStopPeer struct {
+	peerID   string
+	err      error
+	severity p2p.Severity // of the misbehavior of the peer, 0 for a timeout; only reported if SeverityLow
+}
+
+// fastSyncV2 is the pipelined fast sync: the schedule routine runs the
+// scheduler, the IO routine sends the requests to the peers and stops the
+// pruned ones, and the process routine verifies and applies the blocks,
+// all connected by channels, so the blocks of many peers are fetched while
+// the ones received are applied.
+//
+// NOTE: the schedule routine never blocks on the process routine, which
+// blocks on it to report its results: the blocks to process are queued in
+// the schedule routine until the process routine takes them. The peers block
+// on the schedule routine to send it their events, so the IO routine stops
+// the peers in their own routines.
+type fastSyncV2 struct {
+	events     chan interface{} // to the schedule routine
+	processCh  chan pcBlock     // to the process routine
+	ioRequests chan BlockRequest
+	ioStops    chan ioStopPeer
+	started    chan struct{} // closed once the routines are started
+	done       chan struct{} // closed once the schedule routine returns
+}
+
+func newFastSyncV2() *fastSyncV2 {
+	return &fastSyncV2{
+		events:     make(chan interface{}, defaultChannelCapacity),
+		processCh:  make(chan pcBlock, defaultChannelCapacity),
+		ioRequests: make(chan BlockRequest, defaultChannelCapacity),
+		ioStops:    make(chan ioStopPeer, defaultChannelCapacity),
+		started:    make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// SetFastSyncVersion sets the version of the fast sync, v2 for the pipelined
+// one, see fastSyncV2. The default is the v1.
+// NOTE: not thread safe - should only be called once, on startup
+func (bcR *BlockchainReactor) SetFastSyncVersion(v2 bool) {
+	if v2 {
+		bcR.v2 = newFastSyncV2()
+	} else {
+		bcR.v2 = nil
+	}
+}
+
+// startFastSyncV2 starts the routines of the v2 fast sync.
+func (bcR *BlockchainReactor) startFastSyncV2() {
+	go bcR.ioRoutine()
+	go bcR.scheduleRoutine()
+	close(bcR.v2.started)
+}
+
+// sendEvent sends the event to the schedule routine, while it runs. It blocks
+// the peer when the schedule routine is behind, rather than dropping its
+// blocks, so the peers are slowed down to the pace of the fast sync.
+func (bcR *BlockchainReactor) sendEvent(event interface{}) {
+	select {
+	case <-bcR.v2.started:
+	default:
+		// not fast syncing, the peers are asked for their status on start
+		return
+	}
+	select {
+	case bcR.v2.events <- event:
+	case <-bcR.v2.done:
+	case <-bcR.Quit:
+	}
+}
+
+// scheduleRoutine runs the scheduler of the v2 fast sync, until it is caught
+// up, then switches to the consensus.
+func (bcR *BlockchainReactor) scheduleRoutine() {
+	v2 := bcR.v2
+	defer close(v2.done)
+
+	startTime := time.Now()
+	sc := newScheduler(bcR.state.LastBlockHeight+1, peerTimeoutSeconds*time.Second, minRecvRate)
+
+	stopProcessing := make(chan struct{})
+	processResults := make(chan interface{}, defaultChannelCapacity)
+	processDone := make(chan int)
+	go bcR.processRoutine(sc.height, processResults, stopProcessing, processDone)
+	stop := func() int {
+		close(stopProcessing)
+		return <-processDone
+	}
+
+	trySyncTicker := time.NewTicker(trySyncIntervalMS * time.Millisecond)
+	defer trySyncTicker.Stop()
+	statusUpdateTicker := time.NewTicker(statusUpdateIntervalSeconds * time.Second)
+	defer statusUpdateTicker.Stop()
+	switchToConsensusTicker := time.NewTicker(switchToConsensusIntervalSeconds * time.Second)
+	defer switchToConsensusTicker.Stop()
+	go bcR.BroadcastStatusRequest() // nolint: errcheck
+
+	removePeer := func(peerID string, err error, severity p2p.Severity) {
+		sc.removePeer(peerID)
+		v2.ioStops <- ioStopPeer{peerID, err, severity}
+	}
+
+	// the blocks received, until the process routine takes them
+	var toProcess []pcBlock
+
+	for {
+		// only send to the process routine if there's a block to process
+		var processCh chan<- pcBlock
+		var nextBlock pcBlock
+		if len(toProcess) > 0 {
+			processCh = v2.processCh
+			nextBlock = toProcess[0]
+		}
+
+		select {
+		case processCh <- nextBlock:
+			toProcess[0] = pcBlock{}
+			toProcess = toProcess[1:]
+
+		case event := <-v2.events:
+			switch event := event.(type) {
+			case bcPeerStatusEvent:
+				sc.setPeerHeight(event.peerID, event.height, time.Now())
+			case bcPeerRemovedEvent:
+				sc.removePeer(event.peerID)
+			case bcBlockEvent:
+				err := sc.markReceived(event.peerID, event.block.Height, event.size, time.Now())
+				if err != nil {
+					bcR.Logger.Error("Unexpected block", "peer", event.peerID, "err", err)
+					removePeer(event.peerID, err, p2p.SeverityMedium)
+					continue
+				}
+				toProcess = append(toProcess, pcBlock{event.block, event.peerID})
+			}
+
+		case result := <-processResults:
+			switch result := result.(type) {
+			case pcProcessedEvent:
+				sc.markProcessed(result.height)
+			case pcInvalidEvent:
+				bcR.Logger.Error("Error in validation", "heights", result.heights, "peers", result.peerIDs, "err", result.err)
+				for _, height := range result.heights {
+					sc.markInvalid(height)
+				}
+				for _, peerID := range result.peerIDs {
+					if result.ambiguous {
+						// only reported, the peer may have sent a valid block
+						v2.ioStops <- ioStopPeer{peerID, result.err, p2p.SeverityLow}
+					} else {
+						removePeer(peerID, result.err, p2p.SeverityHigh)
+					}
+				}
+			}
+
+		case <-trySyncTicker.C:
+			now := time.Now()
+			for _, peerID := range sc.prunablePeers(now) {
+				bcR.Logger.Info("Pruning peer", "peer", peerID)
+				removePeer(peerID, errors.New("BlockchainReactor Timeout"), 0)
+			}
+			for _, request := range sc.nextRequests(now) {
+				v2.ioRequests <- request
+			}
+
+		case <-statusUpdateTicker.C:
+			// ask for status updates
+			go bcR.BroadcastStatusRequest() // nolint: errcheck
+
+		case <-switchToConsensusTicker.C:
+			// some time for the peers to send their status
+			if sc.caughtUp() && (sc.height > 1 || time.Since(startTime) > 5*time.Second) {
+				bcR.Logger.Info("Time to switch to consensus reactor!", "height", sc.height)
+				blocksSynced := stop()
+
+				conR := bcR.Switch.Reactor("CONSENSUS").(consensusReactor)
+				conR.SwitchToConsensus(bcR.state, blocksSynced)
+				return
+			}
+
+		case <-bcR.Quit:
+			stop()
+			return
+		}
+	}
+}
+
+// processRoutine applies the blocks of the v2 fast sync as they are received,
+// and reports the heights applied and the invalid blocks to the schedule
+// routine, until it is stopped. It sends the number of blocks synced to done
+// on return.
+func (bcR *BlockchainReactor) processRoutine(height int64, results chan<- interface{}, stop <-chan struct{}, done chan<- int) {
+	blocksSynced := 0
+	lastHundred := time.Now()
+	lastRate := 0.0
+
+	pc := newProcessor(height, bcR.verifyBlock, func(block *types.Block, blockParts *types.PartSet, seenCommit *types.Commit) {
+		bcR.commitBlock(block, blockParts, seenCommit)
+		blocksSynced++
+		if blocksSynced%100 == 0 {
+			lastRate = 0.9*lastRate + 0.1*(100/time.Since(lastHundred).Seconds())
+			bcR.Logger.Info("Fast Sync Rate", "height", block.Height, "blocks/s", lastRate)
+			lastHundred = time.Now()
+		}
+	})
+	defer func() {
+		done <- blocksSynced
+	}()
+
+	// report returns false if the routine is stopped
+	report := func(result interface{}) bool {
+		select {
+		case results <- result:
+			return true
+		case <-stop:
+			return false
+		}
+	}
+
+	for {
+		select {
+		case block := <-bcR.v2.processCh:
+			pc.add(block.peerID, block.block)
+			for {
+				processed, invalid := pc.process(processBatchSize)
+				if processed != 0 && !report(pcProcessedEvent{processed}) {
+					return
+				}
+				if invalid != nil && !report(pcInvalidEvent{invalid}) {
+					return
+				}
+				if processed == 0 || invalid != nil {
+					break
+				}
+				select {
+				case <-stop:
+					return
+				default:
+				}
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// ioRoutine sends the requests of the v2 fast sync to the peers, and stops
+// the peers pruned, until the schedule routine returns.
+func (bcR *BlockchainReactor) ioRoutine() {
+	v2 := bcR.v2
+	for {
+		select {
+		case request := <-v2.ioRequests:
+			peer := bcR.Switch.Peers().Get(request.PeerID)
+			if peer == nil {
+				continue // Peer has since been disconnected.
+			}
+			msg := &bcBlockRequestMessage{request.Height}
+			// if the send-queue is full, the scheduler handles the timeout
+			peer.TrySend(BlockchainChannel, struct{ BlockchainMessage }{msg})
+		case stop := <-v2.ioStops:
+			peer := bcR.Switch.Peers().Get(stop.peerID)
+			if peer == nil {
+				continue
+			}
+			// the peer is removed from the reactor, which sends an event to the
+			// schedule routine, maybe blocked on ioStops
+			go func(stop ioStopPeer) {
+				if stop.severity > 0 {
+					bcR.Switch.ReportMisbehavior(peer, stop.severity, stop.err)
+				} else {
+					bcR.Switch.StopPeerForError(peer, stop.err)
+				}
+			}(stop)
+		case <-v2.done:
+			return
+		}
+	}
+}
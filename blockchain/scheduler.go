@@ -0,0 +1,217 @@
+package blockchain
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// the max number of heights requested or received but not processed yet,
+	// past the next height to process
+	scMaxWindow = 600
+	// the max number of requests pending per peer
+	scMaxPendingPerPeer = 20
+	// the weight of the last block in the receive rate of a peer
+	scRateWeight = 0.2
+	// the number of blocks received before a peer can be pruned as slow
+	scMinSamples = 5
+)
+
+// blockState is the state of a height in the window of the scheduler.
+type blockState int
+
+const (
+	blockStateNew blockState = iota
+	blockStatePending
+	blockStateReceived
+)
+
+type scBlock struct {
+	state     blockState
+	peerID    string
+	requested time.Time
+}
+
+// scPeer is a peer of the scheduler, scored by its receive rate: the moving
+// average of the bytes per second of the blocks it sent, from their request.
+type scPeer struct {
+	id          string
+	height      int64
+	pending     int
+	rate        float64
+	samples     int
+	lastTouched time.Time
+}
+
+// score is the rate the peer is expected to send a new request at.
+func (peer *scPeer) score() float64 {
+	return peer.rate / float64(1+peer.pending)
+}
+
+// scheduler schedules the requests of the blocks of the v2 fast sync to the
+// peers: each height of the window past the next one to process is requested
+// to the peer of the best score which has it, and rescheduled if the peer is
+// removed, times out or sent an invalid block. The peers slower than the min
+// receive rate are pruned.
+// NOTE: not thread safe, it is owned by the schedule routine.
+type scheduler struct {
+	height      int64 // the next height to process
+	peerTimeout time.Duration
+	minRecvRate float64 // bytes per second
+
+	peers  map[string]*scPeer
+	blocks map[int64]*scBlock
+}
+
+func newScheduler(height int64, peerTimeout time.Duration, minRecvRate float64) *scheduler {
+	return &scheduler{
+		height:      height,
+		peerTimeout: peerTimeout,
+		minRecvRate: minRecvRate,
+		peers:       make(map[string]*scPeer),
+		blocks:      make(map[int64]*scBlock),
+	}
+}
+
+// setPeerHeight adds the peer, or updates the height it reported.
+func (sc *scheduler) setPeerHeight(peerID string, height int64, now time.Time) {
+	peer, ok := sc.peers[peerID]
+	if !ok {
+		// the new peers get a chance, at a high initial rate, like in v1
+		peer = &scPeer{id: peerID, rate: sc.minRecvRate * math.E, lastTouched: now}
+		sc.peers[peerID] = peer
+	}
+	peer.height = height
+}
+
+// removePeer removes the peer, rescheduling the blocks it was to send. The
+// blocks it sent are kept for the processor, unless invalid.
+func (sc *scheduler) removePeer(peerID string) {
+	if _, ok := sc.peers[peerID]; !ok {
+		return
+	}
+	delete(sc.peers, peerID)
+	for _, block := range sc.blocks {
+		if block.state == blockStatePending && block.peerID == peerID {
+			block.state, block.peerID = blockStateNew, ""
+		}
+	}
+}
+
+// maxPeerHeight returns the highest height reported by a peer.
+func (sc *scheduler) maxPeerHeight() int64 {
+	max := int64(0)
+	for _, peer := range sc.peers {
+		if peer.height > max {
+			max = peer.height
+		}
+	}
+	return max
+}
+
+// nextRequests schedules the heights of the window not pending nor received
+// to the available peers, the best scored first, and returns the requests.
+func (sc *scheduler) nextRequests(now time.Time) []BlockRequest {
+	maxHeight := sc.maxPeerHeight()
+	var requests []BlockRequest
+	for height := sc.height; height < sc.height+scMaxWindow && height <= maxHeight; height++ {
+		block, ok := sc.blocks[height]
+		if !ok {
+			block = &scBlock{state: blockStateNew}
+			sc.blocks[height] = block
+		}
+		if block.state != blockStateNew {
+			continue
+		}
+		peer := sc.bestPeer(height)
+		if peer == nil {
+			continue
+		}
+		block.state, block.peerID, block.requested = blockStatePending, peer.id, now
+		if peer.pending == 0 {
+			peer.lastTouched = now
+		}
+		peer.pending++
+		requests = append(requests, BlockRequest{height, peer.id})
+	}
+	return requests
+}
+
+// bestPeer returns the peer of the best score having the height, with room
+// for a request, or nil if none.
+func (sc *scheduler) bestPeer(height int64) *scPeer {
+	var best *scPeer
+	for _, peer := range sc.peers {
+		if peer.height < height || peer.pending >= scMaxPendingPerPeer {
+			continue
+		}
+		// the ties are broken by the ids, for a deterministic schedule
+		if best == nil || peer.score() > best.score() || (peer.score() == best.score() && peer.id < best.id) {
+			best = peer
+		}
+	}
+	return best
+}
+
+// markReceived marks the block of the height received from the peer, of the
+// size, and updates the rate of the peer. It returns an error if the block
+// wasn't requested to the peer.
+func (sc *scheduler) markReceived(peerID string, height int64, size int, now time.Time) error {
+	peer, ok := sc.peers[peerID]
+	if !ok {
+		return fmt.Errorf("Block %d from unknown peer %s", height, peerID)
+	}
+	block, ok := sc.blocks[height]
+	if !ok || block.state != blockStatePending || block.peerID != peerID {
+		return fmt.Errorf("Block %d not requested to peer %s", height, peerID)
+	}
+	block.state = blockStateReceived
+	peer.pending--
+	peer.lastTouched = now
+
+	elapsed := now.Sub(block.requested).Seconds()
+	if elapsed <= 0 {
+		elapsed = 1e-3
+	}
+	peer.rate = (1-scRateWeight)*peer.rate + scRateWeight*float64(size)/elapsed
+	peer.samples++
+	return nil
+}
+
+// markProcessed forgets the processed heights up to the one given.
+func (sc *scheduler) markProcessed(height int64) {
+	for ; sc.height <= height; sc.height++ {
+		delete(sc.blocks, sc.height)
+	}
+}
+
+// markInvalid reschedules the height, which was received invalid.
+func (sc *scheduler) markInvalid(height int64) {
+	if block, ok := sc.blocks[height]; ok && block.state == blockStateReceived {
+		block.state, block.peerID = blockStateNew, ""
+	}
+}
+
+// prunablePeers returns the peers with requests pending for longer than the
+// peer timeout without sending any block, and the ones slower than the min
+// receive rate, sorted. They must be removed.
+func (sc *scheduler) prunablePeers(now time.Time) []string {
+	var peerIDs []string
+	for _, peer := range sc.peers {
+		timedOut := peer.pending > 0 && now.Sub(peer.lastTouched) > sc.peerTimeout
+		slow := peer.samples >= scMinSamples && peer.rate < sc.minRecvRate
+		if timedOut || slow {
+			peerIDs = append(peerIDs, peer.id)
+		}
+	}
+	sort.Strings(peerIDs)
+	return peerIDs
+}
+
+// caughtUp returns whether all the blocks of the peers but the last one,
+// which can't be verified without the next, are processed.
+func (sc *scheduler) caughtUp() bool {
+	return len(sc.peers) > 0 && sc.height >= sc.maxPeerHeight()
+}
@@ -0,0 +1,79 @@
+package blockchain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedulerSchedule(t *testing.T) {
+	assert := assert.New(t)
+
+	sc := newScheduler(1, 15*time.Second, 1000)
+	now := time.Now()
+	assert.Empty(sc.nextRequests(now), "no peers")
+
+	sc.setPeerHeight("a", 10, now)
+	sc.setPeerHeight("b", 5, now)
+	requests := sc.nextRequests(now)
+	peerOf := make(map[int64]string)
+	for _, request := range requests {
+		peerOf[request.Height] = request.PeerID
+		if request.PeerID == "b" {
+			assert.True(request.Height <= 5, "b doesn't have %d", request.Height)
+		}
+	}
+	assert.Len(requests, 10)
+	assert.Len(peerOf, 10)
+	// the requests are spread over the peers of the same rate
+	assert.Equal("a", peerOf[1])
+	assert.Equal("b", peerOf[2])
+	assert.Empty(sc.nextRequests(now), "all pending")
+
+	// only the peer requested can send the block, once
+	assert.NotNil(sc.markReceived("a", 2, 100, now))
+	assert.NotNil(sc.markReceived("c", 1, 100, now))
+	assert.Nil(sc.markReceived("b", 2, 100, now.Add(time.Second)))
+	assert.NotNil(sc.markReceived("b", 2, 100, now))
+	assert.Nil(sc.markReceived("a", 1, 100, now.Add(time.Second)))
+
+	// the pending heights of a removed peer are rescheduled, not the received ones
+	sc.removePeer("b")
+	assert.Equal([]BlockRequest{{4, "a"}}, sc.nextRequests(now))
+
+	// so are the invalid ones
+	assert.Nil(sc.markReceived("a", 3, 100, now.Add(time.Second)))
+	sc.markInvalid(3)
+	assert.Equal([]BlockRequest{{3, "a"}}, sc.nextRequests(now))
+
+	sc.markProcessed(2)
+	assert.EqualValues(3, sc.height)
+	assert.False(sc.caughtUp())
+	sc.markProcessed(9)
+	assert.True(sc.caughtUp(), "the last block is verified by the next one")
+}
+
+func TestSchedulerPrunePeers(t *testing.T) {
+	assert := assert.New(t)
+
+	sc := newScheduler(1, 15*time.Second, 1000)
+	now := time.Now()
+	for _, peerID := range []string{"fast", "slow", "silent"} {
+		sc.setPeerHeight(peerID, 100, now)
+	}
+	requests := sc.nextRequests(now)
+	assert.Len(requests, 3*scMaxPendingPerPeer)
+	for _, request := range requests {
+		switch request.PeerID {
+		case "fast":
+			assert.Nil(sc.markReceived(request.PeerID, request.Height, 100000, now.Add(time.Second)))
+		case "slow":
+			assert.Nil(sc.markReceived(request.PeerID, request.Height, 10, now.Add(time.Second)))
+		}
+	}
+	assert.True(sc.peers["fast"].score() > sc.peers["slow"].score())
+
+	assert.Equal([]string{"slow"}, sc.prunablePeers(now.Add(2*time.Second)))
+	assert.Equal([]string{"silent", "slow"}, sc.prunablePeers(now.Add(16*time.Second)))
+}
@@ -25,24 +25,43 @@ Currently the precommit signatures are duplicated in the Block parts as
 well as the Commit.  In the future this may change, perhaps by moving
 the Commit data outside the Block.
 
+Each block is written in a single batch. SaveBlock waits for it to be
+synced to disk, while SaveBlockUnsynced leaves it to the next Flush(),
+for the writes not followed by the state saved with the block.
+
+The blocks before the base can be pruned with PruneBlocks, to cap the
+disk usage of the nodes which don't need the whole chain, and the older
+blocks backfilled with BackfillBlock, eg. after a state sync.
+
 // NOTE: BlockStore methods will panic if they encounter errors
 // deserializing loaded data, indicating probable corruption on disk.
 */
 type BlockStore struct {
 	db dbm.DB
 
-	mtx    sync.RWMutex
-	height int64
+	mtx      sync.RWMutex
+	base     int64 // the lowest height not pruned, 0 if empty
+	height   int64
+	unsynced int // blocks saved since the last sync to disk
 }
 
 func NewBlockStore(db dbm.DB) *BlockStore {
 	bsjson := LoadBlockStoreStateJSON(db)
 	return &BlockStore{
+		base:   bsjson.Base,
 		height: bsjson.Height,
 		db:     db,
 	}
 }
 
+// Base returns the lowest height of the blocks in the store, the ones
+// before it being pruned, or 0 if it is empty.
+func (bs *BlockStore) Base() int64 {
+	bs.mtx.RLock()
+	defer bs.mtx.RUnlock()
+	return bs.base
+}
+
 // Height() returns the last known contiguous block height.
 func (bs *BlockStore) Height() int64 {
 	bs.mtx.RLock()
@@ -72,6 +91,10 @@ func (bs *BlockStore) LoadBlock(height int64) *types.Block {
 	bytez := []byte{}
 	for i := 0; i < blockMeta.BlockID.PartsHeader.Total; i++ {
 		part := bs.LoadBlockPart(height, i)
+		if part == nil {
+			// pruned meanwhile
+			return nil
+		}
 		bytez = append(bytez, part.Bytes...)
 	}
 	block := wire.ReadBinary(&types.Block{}, bytes.NewReader(bytez), 0, &n, &err).(*types.Block)
@@ -146,6 +169,36 @@ func (bs *BlockStore) LoadSeenCommit(height int64) *types.Commit {
 //             we need this to reload the precommits to catch-up nodes to the
 //             most recent height.  Otherwise they'd stall at H-1.
 func (bs *BlockStore) SaveBlock(block *types.Block, blockParts *types.PartSet, seenCommit *types.Commit) {
+	bs.saveBlock(block, blockParts, seenCommit)
+	bs.Flush()
+}
+
+// SaveBlockUnsynced is like SaveBlock, but doesn't wait for the block to be
+// synced to disk, which is done by the next Flush() or SaveBlock().
+// NOTE: the block is safe from a crash of the process, not of the machine.
+func (bs *BlockStore) SaveBlockUnsynced(block *types.Block, blockParts *types.PartSet, seenCommit *types.Commit) {
+	bs.saveBlock(block, blockParts, seenCommit)
+}
+
+// Unsynced returns the number of blocks saved since the last sync to disk.
+func (bs *BlockStore) Unsynced() int {
+	bs.mtx.RLock()
+	defer bs.mtx.RUnlock()
+	return bs.unsynced
+}
+
+// Flush syncs the saved blocks to disk.
+func (bs *BlockStore) Flush() {
+	bs.mtx.Lock()
+	defer bs.mtx.Unlock()
+	if bs.unsynced == 0 {
+		return
+	}
+	bs.db.SetSync(nil, nil)
+	bs.unsynced = 0
+}
+
+func (bs *BlockStore) saveBlock(block *types.Block, blockParts *types.PartSet, seenCommit *types.Commit) {
 	height := block.Height
 	if height != bs.Height()+1 {
 		cmn.PanicSanity(cmn.Fmt("BlockStore can only save contiguous blocks. Wanted %v, got %v", bs.Height()+1, height))
@@ -154,43 +207,187 @@ func (bs *BlockStore) SaveBlock(block *types.Block, blockParts *types.PartSet, s
 		cmn.PanicSanity(cmn.Fmt("BlockStore can only save complete block part sets"))
 	}
 
+	batch := bs.db.NewBatch()
+
 	// Save block meta
 	blockMeta := types.NewBlockMeta(block, blockParts)
 	metaBytes := wire.BinaryBytes(blockMeta)
-	bs.db.Set(calcBlockMetaKey(height), metaBytes)
+	batch.Set(calcBlockMetaKey(height), metaBytes)
 
 	// Save block parts
 	for i := 0; i < blockParts.Total(); i++ {
-		bs.saveBlockPart(height, i, blockParts.GetPart(i))
+		bs.saveBlockPart(batch, height, i, blockParts.GetPart(i))
 	}
 
 	// Save block commit (duplicate and separate from the Block)
 	blockCommitBytes := wire.BinaryBytes(block.LastCommit)
-	bs.db.Set(calcBlockCommitKey(height-1), blockCommitBytes)
+	batch.Set(calcBlockCommitKey(height-1), blockCommitBytes)
 
 	// Save seen commit (seen +2/3 precommits for block)
 	// NOTE: we can delete this at a later height
 	seenCommitBytes := wire.BinaryBytes(seenCommit)
-	bs.db.Set(calcSeenCommitKey(height), seenCommitBytes)
+	batch.Set(calcSeenCommitKey(height), seenCommitBytes)
 
-	// Save new BlockStoreStateJSON descriptor
-	BlockStoreStateJSON{Height: height}.Save(bs.db)
+	// Save new BlockStoreStateJSON descriptor, with the base of the pruning
+	bs.mtx.Lock()
+	defer bs.mtx.Unlock()
+	base := bs.base
+	if base == 0 {
+		base = height
+	}
+	batch.Set(blockStoreKey, BlockStoreStateJSON{Base: base, Height: height}.Bytes())
+
+	batch.Write()
 
 	// Done!
+	bs.base = base
+	bs.height = height
+	bs.unsynced++
+}
+
+// Bootstrap sets the height of an empty store to the one of a node restored
+// from a snapshot of the state after the block of the signed header, without
+// the blocks before. Only the meta of the block, without its size, and the
+// commit are saved, for the consensus to resume from.
+func (bs *BlockStore) Bootstrap(signedHeader types.SignedHeader) error {
+	header, commit := signedHeader.Header, signedHeader.Commit
+	if bs.Height() != 0 {
+		return fmt.Errorf("BlockStore can only be bootstrapped empty, it is at height %v", bs.Height())
+	}
+	if header == nil || commit == nil || header.Height <= 0 {
+		return fmt.Errorf("BlockStore can only be bootstrapped from a signed header")
+	}
+	height := header.Height
+
+	batch := bs.db.NewBatch()
+	blockMeta := &types.BlockMeta{BlockID: commit.BlockID, Header: header}
+	batch.Set(calcBlockMetaKey(height), wire.BinaryBytes(blockMeta))
+	batch.Set(calcSeenCommitKey(height), wire.BinaryBytes(commit))
+	batch.Set(blockStoreKey, BlockStoreStateJSON{Base: height, Height: height}.Bytes())
+	batch.Write()
+	bs.db.SetSync(nil, nil)
+
 	bs.mtx.Lock()
+	bs.base = height
 	bs.height = height
 	bs.mtx.Unlock()
+	return nil
+}
 
-	// Flush
-	bs.db.SetSync(nil, nil)
+// PruneBlocks deletes the blocks before the retain height, with their
+// commits, and returns the number of blocks pruned. The last block can't be
+// pruned, it is needed by the consensus.
+func (bs *BlockStore) PruneBlocks(retainHeight int64) (int64, error) {
+	if retainHeight <= 0 {
+		return 0, fmt.Errorf("Retain height must be greater than 0")
+	}
+	bs.mtx.Lock()
+	base, height := bs.base, bs.height
+	if retainHeight > height {
+		bs.mtx.Unlock()
+		return 0, fmt.Errorf("Retain height %v can't be greater than the height %v", retainHeight, height)
+	}
+	if retainHeight <= base {
+		bs.mtx.Unlock()
+		return 0, nil
+	}
+	// the blocks are out of reach before being deleted. A crash meanwhile
+	// only leaves some of them on disk, unreachable.
+	bs.db.SetSync(blockStoreKey, BlockStoreStateJSON{Base: retainHeight, Height: height}.Bytes())
+	bs.base = retainHeight
+	bs.mtx.Unlock()
+
+	for h := base; h < retainHeight; h++ {
+		if meta := bs.LoadBlockMeta(h); meta != nil {
+			for i := 0; i < meta.BlockID.PartsHeader.Total; i++ {
+				bs.db.Delete(calcBlockPartKey(h, i))
+			}
+		}
+		bs.db.Delete(calcBlockMetaKey(h))
+		// the commit saved with the block, its LastCommit
+		bs.db.Delete(calcBlockCommitKey(h - 1))
+		bs.db.Delete(calcSeenCommitKey(h))
+	}
+	return retainHeight - base, nil
 }
 
-func (bs *BlockStore) saveBlockPart(height int64, index int, part *types.Part) {
+// BackfillHeight returns the height of the next block to backfill, see
+// BackfillBlock, or 0 if none.
+func (bs *BlockStore) BackfillHeight() int64 {
+	bs.mtx.RLock()
+	defer bs.mtx.RUnlock()
+	return bs.backfillHeight()
+}
+
+func (bs *BlockStore) backfillHeight() int64 {
+	if bs.base == 0 {
+		return 0
+	}
+	if bs.LoadBlockPart(bs.base, 0) == nil {
+		// only the meta of the block is stored
+		return bs.base
+	}
+	return bs.base - 1
+}
+
+// BackfillBlock saves the block before the base, verified against the
+// LastBlockID of the header at the base, and lowers the base to it. The block
+// at the base itself is saved first if only its meta is stored, as after a
+// Bootstrap, verified against it. The commit of the block is the LastCommit
+// of the next one.
+// NOTE: the block is not synced to disk, until the next Flush().
+func (bs *BlockStore) BackfillBlock(block *types.Block, blockParts *types.PartSet) error {
+	bs.mtx.Lock()
+	defer bs.mtx.Unlock()
+	base, height := bs.base, block.Height
+	if base == 0 {
+		return fmt.Errorf("BlockStore can't backfill an empty store")
+	}
+	if !blockParts.IsComplete() {
+		return fmt.Errorf("BlockStore can only backfill complete block part sets")
+	}
+
+	baseMeta := bs.LoadBlockMeta(base)
+	if baseMeta == nil {
+		return fmt.Errorf("Missing block meta at the base %v", base)
+	}
+	var expected types.BlockID
+	switch next := bs.backfillHeight(); {
+	case height != next:
+		return fmt.Errorf("BlockStore can only backfill the block %v, got %v", next, height)
+	case height == base:
+		expected = baseMeta.BlockID
+	default:
+		expected = baseMeta.Header.LastBlockID
+	}
+	blockID := types.BlockID{block.Hash(), blockParts.Header()}
+	if !blockID.Equals(expected) {
+		return fmt.Errorf("Block %v doesn't match the chain: expected %v, got %v", height, expected, blockID)
+	}
+
+	batch := bs.db.NewBatch()
+	batch.Set(calcBlockMetaKey(height), wire.BinaryBytes(types.NewBlockMeta(block, blockParts)))
+	for i := 0; i < blockParts.Total(); i++ {
+		batch.Set(calcBlockPartKey(height, i), wire.BinaryBytes(blockParts.GetPart(i)))
+	}
+	batch.Set(calcBlockCommitKey(height-1), wire.BinaryBytes(block.LastCommit))
+	if height < base {
+		batch.Set(calcSeenCommitKey(height), wire.BinaryBytes(bs.LoadBlockCommit(height)))
+	}
+	batch.Set(blockStoreKey, BlockStoreStateJSON{Base: height, Height: bs.height}.Bytes())
+	batch.Write()
+
+	bs.base = height
+	bs.unsynced++
+	return nil
+}
+
+func (bs *BlockStore) saveBlockPart(batch dbm.Batch, height int64, index int, part *types.Part) {
 	if height != bs.Height()+1 {
 		cmn.PanicSanity(cmn.Fmt("BlockStore can only save contiguous blocks. Wanted %v, got %v", bs.Height()+1, height))
 	}
 	partBytes := wire.BinaryBytes(part)
-	bs.db.Set(calcBlockPartKey(height, index), partBytes)
+	batch.Set(calcBlockPartKey(height, index), partBytes)
 }
 
 //-----------------------------------------------------------------------------
@@ -216,15 +413,20 @@ func calcSeenCommitKey(height int64) []byte {
 var blockStoreKey = []byte("blockStore")
 
 type BlockStoreStateJSON struct {
+	Base   int64 `json:",omitempty"`
 	Height int64
 }
 
 func (bsj BlockStoreStateJSON) Save(db dbm.DB) {
+	db.SetSync(blockStoreKey, bsj.Bytes())
+}
+
+func (bsj BlockStoreStateJSON) Bytes() []byte {
 	bytes, err := json.Marshal(bsj)
 	if err != nil {
 		cmn.PanicSanity(cmn.Fmt("Could not marshal state bytes: %v", err))
 	}
-	db.SetSync(blockStoreKey, bytes)
+	return bytes
 }
 
 func LoadBlockStoreStateJSON(db dbm.DB) BlockStoreStateJSON {
@@ -239,5 +441,9 @@ func LoadBlockStoreStateJSON(db dbm.DB) BlockStoreStateJSON {
 	if err != nil {
 		cmn.PanicCrisis(cmn.Fmt("Could not unmarshal bytes: %X", bytes))
 	}
+	// saved before the pruning, from the first block
+	if bsj.Base == 0 && bsj.Height > 0 {
+		bsj.Base = 1
+	}
 	return bsj
 }
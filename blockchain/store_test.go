@@ -0,0 +1,194 @@
+package blockchain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	dbm "github.com/tendermint/tmlibs/db"
+
+	cfg "github.com/tendermint/tendermint/config"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestBlockStoreUnsynced(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	config := cfg.ResetTestRoot("blockchain_store_test")
+	state, _ := sm.GetState(dbm.NewMemDB(), config.GenesisFile())
+
+	db := dbm.NewMemDB()
+	store := NewBlockStore(db)
+	saveBlock := func(height int64, synced bool) {
+		block := makeBlock(height, state)
+		parts := block.MakePartSet(state.Params.BlockGossipParams.BlockPartSizeBytes)
+		seenCommit := makeBlock(height+1, state).LastCommit
+		if synced {
+			store.SaveBlock(block, parts, seenCommit)
+		} else {
+			store.SaveBlockUnsynced(block, parts, seenCommit)
+		}
+	}
+
+	for height := int64(1); height <= 3; height++ {
+		saveBlock(height, false)
+	}
+	assert.Equal(3, store.Unsynced())
+	assert.EqualValues(3, store.Height())
+
+	// the blocks can be read before being synced
+	for height := int64(1); height <= 3; height++ {
+		block := store.LoadBlock(height)
+		require.NotNil(block, "block %d", height)
+		assert.Equal(height, block.Height)
+	}
+	assert.EqualValues(3, LoadBlockStoreStateJSON(db).Height)
+
+	store.Flush()
+	assert.Equal(0, store.Unsynced())
+
+	// SaveBlock syncs right away
+	saveBlock(4, false)
+	saveBlock(5, true)
+	assert.Equal(0, store.Unsynced())
+	assert.EqualValues(5, NewBlockStore(db).Height())
+}
+
+func TestBlockStoreBootstrap(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	config := cfg.ResetTestRoot("blockchain_store_test")
+	state, _ := sm.GetState(dbm.NewMemDB(), config.GenesisFile())
+
+	db := dbm.NewMemDB()
+	store := NewBlockStore(db)
+	block, nextBlock := makeBlock(5, state), makeBlock(6, state)
+	assert.NotNil(store.Bootstrap(types.SignedHeader{Header: block.Header}), "no commit")
+	require.Nil(store.Bootstrap(types.SignedHeader{Header: block.Header, Commit: nextBlock.LastCommit}))
+	assert.NotNil(store.Bootstrap(types.SignedHeader{Header: block.Header, Commit: nextBlock.LastCommit}), "not empty")
+
+	store = NewBlockStore(db)
+	assert.EqualValues(5, store.Height())
+	meta := store.LoadBlockMeta(5)
+	require.NotNil(meta)
+	assert.Equal(block.Hash(), meta.Header.Hash())
+	assert.NotNil(store.LoadSeenCommit(5))
+	assert.Nil(store.LoadBlock(5), "the block itself is not saved")
+
+	// the next blocks are saved on top
+	parts := nextBlock.MakePartSet(state.Params.BlockGossipParams.BlockPartSizeBytes)
+	store.SaveBlock(nextBlock, parts, makeBlock(7, state).LastCommit)
+	assert.EqualValues(6, store.Height())
+}
+
+func TestBlockStorePruneBlocks(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	config := cfg.ResetTestRoot("blockchain_store_test")
+	state, _ := sm.GetState(dbm.NewMemDB(), config.GenesisFile())
+
+	db := dbm.NewMemDB()
+	store := NewBlockStore(db)
+	assert.EqualValues(0, store.Base())
+	for height := int64(1); height <= 10; height++ {
+		block := makeBlock(height, state)
+		parts := block.MakePartSet(state.Params.BlockGossipParams.BlockPartSizeBytes)
+		store.SaveBlock(block, parts, makeBlock(height+1, state).LastCommit)
+	}
+	assert.EqualValues(1, store.Base())
+
+	_, err := store.PruneBlocks(0)
+	assert.NotNil(err)
+	_, err = store.PruneBlocks(11)
+	assert.NotNil(err, "the last block is kept")
+
+	pruned, err := store.PruneBlocks(8)
+	require.Nil(err)
+	assert.EqualValues(7, pruned)
+	assert.EqualValues(8, store.Base())
+	assert.EqualValues(10, store.Height())
+	for height := int64(1); height < 8; height++ {
+		assert.Nil(store.LoadBlock(height), "block %d", height)
+		assert.Nil(store.LoadBlockMeta(height), "block meta %d", height)
+		assert.Nil(store.LoadBlockPart(height, 0), "block part %d", height)
+		assert.Nil(store.LoadSeenCommit(height), "seen commit %d", height)
+	}
+	for height := int64(8); height <= 10; height++ {
+		assert.NotNil(store.LoadBlock(height), "block %d", height)
+	}
+
+	// nothing more to prune below the base
+	pruned, err = store.PruneBlocks(5)
+	assert.Nil(err)
+	assert.EqualValues(0, pruned)
+
+	// the base is kept on restart, and as the next blocks are saved
+	store = NewBlockStore(db)
+	assert.EqualValues(8, store.Base())
+	block := makeBlock(11, state)
+	store.SaveBlock(block, block.MakePartSet(state.Params.BlockGossipParams.BlockPartSizeBytes),
+		makeBlock(12, state).LastCommit)
+	assert.EqualValues(8, NewBlockStore(db).Base())
+}
+
+// makeChain returns the blocks of a chain of the height, each one linked to
+// the previous one, and the one after, for the commit of the last one.
+func makeChain(height int64, state *sm.State) []*types.Block {
+	var blocks []*types.Block
+	prevBlockID := state.LastBlockID
+	for h := int64(1); h <= height+1; h++ {
+		block, parts := types.MakeBlock(h, state.ChainID, makeTxs(h), new(types.Commit), prevBlockID,
+			state.Validators.Hash(), state.AppHash, state.LastResultsHash,
+			state.Params.BlockGossipParams.BlockPartSizeBytes)
+		blocks = append(blocks, block)
+		prevBlockID = types.BlockID{block.Hash(), parts.Header()}
+	}
+	return blocks
+}
+
+func TestBlockStoreBackfill(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	config := cfg.ResetTestRoot("blockchain_store_test")
+	state, _ := sm.GetState(dbm.NewMemDB(), config.GenesisFile())
+	partSize := state.Params.BlockGossipParams.BlockPartSizeBytes
+	chain := makeChain(10, state)
+	block := func(height int64) (*types.Block, *types.PartSet) {
+		b := chain[height-1]
+		return b, b.MakePartSet(partSize)
+	}
+
+	// a store bootstrapped at 10, by a state sync
+	db := dbm.NewMemDB()
+	store := NewBlockStore(db)
+	assert.EqualValues(0, store.BackfillHeight())
+	require.Nil(store.Bootstrap(types.SignedHeader{Header: chain[9].Header, Commit: chain[10].LastCommit}))
+
+	// the block at the base first, then the ones before
+	assert.EqualValues(10, store.BackfillHeight())
+	assert.NotNil(store.BackfillBlock(block(9)))
+	require.Nil(store.BackfillBlock(block(10)))
+	assert.EqualValues(9, store.BackfillHeight())
+
+	// the blocks not of the chain are rejected
+	forged := *chain[8]
+	forged.Data = &types.Data{Txs: makeTxs(100)}
+	assert.NotNil(store.BackfillBlock(&forged, forged.MakePartSet(partSize)))
+	for h := int64(9); h >= 5; h-- {
+		require.Nil(store.BackfillBlock(block(h)), "block %d", h)
+	}
+	assert.EqualValues(5, store.Base())
+	assert.EqualValues(4, store.BackfillHeight())
+	assert.EqualValues(10, store.Height())
+
+	store = NewBlockStore(db)
+	assert.EqualValues(5, store.Base())
+	for h := int64(5); h <= 10; h++ {
+		loaded := store.LoadBlock(h)
+		require.NotNil(loaded, "block %d", h)
+		assert.Equal(chain[h-1].Hash(), loaded.Hash())
+		assert.NotNil(store.LoadSeenCommit(h), "seen commit %d", h)
+	}
+}
@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	abci "github.com/tendermint/abci/types"
+
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+	"github.com/tendermint/tendermint/types"
+)
+
+// BenchCmd sends txs to a running node and reports the throughput and latencies.
+var BenchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Send txs to a running node and report the throughput and latencies",
+	Long: `Send txs to a running node over its RPC, from concurrent clients, and report
+the throughput and the latency percentiles of the txs.
+
+With --mode=commit (the default), the latency is the time until the tx is
+committed in a block; with --mode=sync, until it passes CheckTx.
+
+To measure consistently the performance of the consensus and the mempool,
+run the node with the built-in benchmark app, eg.
+
+	tendermint node --proxy_app=bench:deliver_cost=100,value_size=256
+
+where check_cost and deliver_cost are the sha256 rounds per tx in CheckTx
+and DeliverTx, value_size the size the stored values are padded to, and
+max_keys the maximum number of keys in the state.`,
+	RunE:         bench,
+	SilenceUsage: true,
+}
+
+var (
+	benchNode        string
+	benchTxs         int
+	benchConcurrency int
+	benchTxSize      int
+	benchMode        string
+)
+
+func init() {
+	BenchCmd.Flags().StringVar(&benchNode, "node", "tcp://localhost:46657", "RPC address of the node")
+	BenchCmd.Flags().IntVar(&benchTxs, "txs", 1000, "Number of txs to send")
+	BenchCmd.Flags().IntVar(&benchConcurrency, "concurrency", 10, "Number of concurrent clients")
+	BenchCmd.Flags().IntVar(&benchTxSize, "tx-size", 64, "Size of the txs in bytes, at least 20")
+	BenchCmd.Flags().StringVar(&benchMode, "mode", "commit", "Wait for the txs to be committed (commit) or checked (sync)")
+}
+
+// benchResult is the outcome of sending a tx
+type benchResult struct {
+	latency time.Duration
+	err     error
+}
+
+func bench(cmd *cobra.Command, args []string) error {
+	if benchTxs <= 0 || benchConcurrency <= 0 {
+		return fmt.Errorf("--txs and --concurrency must be positive")
+	}
+	if benchTxSize < 20 {
+		return fmt.Errorf("--tx-size must be at least 20 bytes")
+	}
+	var broadcast func(c *rpcclient.HTTP, tx types.Tx) error
+	switch benchMode {
+	case "commit":
+		broadcast = broadcastCommit
+	case "sync":
+		broadcast = broadcastSync
+	default:
+		return fmt.Errorf("Unknown --mode %q, expected commit or sync", benchMode)
+	}
+
+	// a random prefix, so the txs are not in the cache of a previous run
+	prefix := make([]byte, 8)
+	if _, err := rand.Read(prefix); err != nil {
+		return err
+	}
+
+	txs := make(chan types.Tx, benchTxs)
+	for i := 0; i < benchTxs; i++ {
+		txs <- benchTx(prefix, i, benchTxSize)
+	}
+	close(txs)
+
+	results := make(chan benchResult, benchTxs)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < benchConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := rpcclient.NewHTTP(benchNode, "/websocket")
+			for tx := range txs {
+				sent := time.Now()
+				err := broadcast(c, tx)
+				results <- benchResult{time.Since(sent), err}
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	close(results)
+
+	var latencies []time.Duration
+	var lastErr error
+	for res := range results {
+		if res.err != nil {
+			lastErr = res.err
+			continue
+		}
+		latencies = append(latencies, res.latency)
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	fmt.Printf("Sent %d txs of %d bytes in %v, from %d clients (mode %s)\n",
+		benchTxs, benchTxSize, elapsed, benchConcurrency, benchMode)
+	fmt.Printf("Throughput: %.2f txs/s\n", float64(len(latencies))/elapsed.Seconds())
+	if len(latencies) > 0 {
+		fmt.Printf("Latency: p50 %v, p90 %v, p99 %v, max %v\n",
+			percentile(latencies, 50), percentile(latencies, 90), percentile(latencies, 99),
+			latencies[len(latencies)-1])
+	}
+	if failed := benchTxs - len(latencies); failed > 0 {
+		fmt.Printf("Failed: %d txs, eg. %v\n", failed, lastErr)
+	}
+	return nil
+}
+
+// benchTx returns a "key=value" tx of the given size, unique for the prefix and i.
+func benchTx(prefix []byte, i, size int) types.Tx {
+	tx := make([]byte, size)
+	n := copy(tx, fmt.Sprintf("%X%08X=", prefix[:4], i))
+	for j := n; j < size; j++ {
+		tx[j] = 'x'
+	}
+	return tx
+}
+
+func broadcastCommit(c *rpcclient.HTTP, tx types.Tx) error {
+	res, err := c.BroadcastTxCommit(tx)
+	if err != nil {
+		return err
+	}
+	if res.CheckTx.Code != abci.CodeTypeOK {
+		return fmt.Errorf("CheckTx failed: %s", res.CheckTx.Log)
+	}
+	if res.DeliverTx.Code != abci.CodeTypeOK {
+		return fmt.Errorf("DeliverTx failed: %s", res.DeliverTx.Log)
+	}
+	return nil
+}
+
+func broadcastSync(c *rpcclient.HTTP, tx types.Tx) error {
+	res, err := c.BroadcastTxSync(tx)
+	if err != nil {
+		return err
+	}
+	if res.Code != abci.CodeTypeOK {
+		return fmt.Errorf("CheckTx failed: %s", res.Log)
+	}
+	return nil
+}
+
+// percentile returns the p-th percentile of the sorted latencies.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted)*p+99)/100 - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
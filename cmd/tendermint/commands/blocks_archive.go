@@ -0,0 +1,144 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	dbm "github.com/tendermint/tmlibs/db"
+
+	bc "github.com/tendermint/tendermint/blockchain"
+	"github.com/tendermint/tendermint/consensus"
+	"github.com/tendermint/tendermint/proxy"
+	sm "github.com/tendermint/tendermint/state"
+)
+
+// ExportBlocksCmd writes the blocks of the block store to an archive.
+var ExportBlocksCmd = &cobra.Command{
+	Use:     "export_blocks",
+	Aliases: []string{"export-blocks"},
+	Short:   "Export blocks to an archive (the node must be stopped)",
+	Long: `Write the blocks of the block store from --from to --to, with their
+commits and validators, to the compressed archive --output, eg. to keep
+cold archives of the chain or to seed new nodes with import_blocks, without
+copying the DBs.
+
+By default, all the blocks of the store are exported.`,
+	RunE:         exportBlocks,
+	SilenceUsage: true,
+}
+
+// ImportBlocksCmd applies the blocks of an archive to the node.
+var ImportBlocksCmd = &cobra.Command{
+	Use:     "import_blocks",
+	Aliases: []string{"import-blocks"},
+	Short:   "Import blocks from an archive (the node must be stopped)",
+	Long: `Apply the blocks of the archive --input, written by export_blocks, after
+the latest block of the node, like the fast sync but from the archive: each
+block is verified with its commit and the validators of the state, saved in
+the block store and executed by the app, which must be running for a
+remote proxy_app.
+
+The blocks of the archive the node already has are skipped, the archive must
+not start after the next height of the node.`,
+	RunE:         importBlocks,
+	SilenceUsage: true,
+}
+
+var (
+	exportFromHeight int64
+	exportToHeight   int64
+	archiveOutput    string
+	archiveInput     string
+)
+
+func init() {
+	ExportBlocksCmd.Flags().Int64Var(&exportFromHeight, "from", 0, "Height of the first block to export, the base of the store by default")
+	ExportBlocksCmd.Flags().Int64Var(&exportToHeight, "to", 0, "Height of the last block to export, the height of the store by default")
+	ExportBlocksCmd.Flags().StringVar(&archiveOutput, "output", "", "Archive file to write")
+	ImportBlocksCmd.Flags().StringVar(&archiveInput, "input", "", "Archive file to read")
+}
+
+func exportBlocks(cmd *cobra.Command, args []string) error {
+	if archiveOutput == "" {
+		return fmt.Errorf("--output is required")
+	}
+	stateDB := dbm.NewDB("state", config.DBBackend, config.DBDir())
+	state := sm.LoadState(stateDB)
+	if state == nil {
+		return fmt.Errorf("No state found in %s", config.DBDir())
+	}
+	blockStore := bc.NewBlockStore(dbm.NewDB("blockstore", config.DBBackend, config.DBDir()))
+	from, to := exportFromHeight, exportToHeight
+	if from == 0 {
+		from = blockStore.Base()
+	}
+	if to == 0 {
+		to = blockStore.Height()
+	}
+
+	f, err := os.OpenFile(archiveOutput, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close() // nolint: errcheck
+	w := bufio.NewWriter(f)
+	exported, err := bc.ExportBlocks(w, blockStore, state.ChainID, from, to, state.LoadValidators)
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	logger.Info("Exported blocks", "blocks", exported, "from", from, "to", to, "file", archiveOutput)
+	return nil
+}
+
+func importBlocks(cmd *cobra.Command, args []string) error {
+	if archiveInput == "" {
+		return fmt.Errorf("--input is required")
+	}
+	f, err := os.Open(archiveInput)
+	if err != nil {
+		return err
+	}
+	defer f.Close() // nolint: errcheck
+	ar, err := bc.NewArchiveReader(bufio.NewReader(f))
+	if err != nil {
+		return err
+	}
+
+	stateDB := dbm.NewDB("state", config.DBBackend, config.DBDir())
+	state, err := sm.GetState(stateDB, config.GenesisFile())
+	if err != nil {
+		return err
+	}
+	state.SetLogger(logger.With("module", "state"))
+	blockStore := bc.NewBlockStore(dbm.NewDB("blockstore", config.DBBackend, config.DBDir()))
+
+	// sync the app with the store and the state first
+	handshaker := consensus.NewHandshaker(state, blockStore)
+	handshaker.SetLogger(logger.With("module", "consensus"))
+	clientCreator := proxy.DefaultClientCreator(config.ProxyApp, config.ABCI, config.DBDir())
+	proxyApp := proxy.NewAppConns(clientCreator, handshaker)
+	if err := proxyApp.Start(); err != nil {
+		return fmt.Errorf("Error starting proxy app conns: %v", err)
+	}
+	defer proxyApp.Stop() // nolint: errcheck
+
+	header := ar.Header()
+	logger.Info("Importing blocks", "from", header.From, "to", header.To, "height", state.LastBlockHeight)
+	imported, err := bc.ImportBlocks(ar, state, blockStore, proxyApp.Consensus())
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Imported blocks", "blocks", imported, "height", state.LastBlockHeight)
+	return nil
+}
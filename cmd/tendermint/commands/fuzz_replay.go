@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	cfg "github.com/tendermint/tendermint/config"
+	nm "github.com/tendermint/tendermint/node"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/proxy"
+	"github.com/tendermint/tendermint/types"
+)
+
+// FuzzReplayCmd feeds the p2p fuzz corpora recorded by a node back through
+// the reactors, to reproduce the crashes on the messages of a peer.
+var FuzzReplayCmd = &cobra.Command{
+	Use:   "fuzz-replay [corpus files]",
+	Short: "Replay p2p fuzz corpora through the reactors",
+	Long: `Feed the messages recorded in the fuzz corpus files (see the p2p
+fuzz_corpus_dir option) through the reactors of a node, in order, as if a peer
+sent them, and report the first message a reactor crashes on, with its stack trace.
+
+This is a debug command: the node runs a throwaway single validator chain with
+a dummy app, in --dir or in a temporary directory, and the node's own config and
+data are not used. It exits with an error if any corpus crashes a reactor.`,
+	RunE:         fuzzReplay,
+	SilenceUsage: true,
+}
+
+var fuzzReplayDir string
+
+func init() {
+	FuzzReplayCmd.Flags().StringVar(&fuzzReplayDir, "dir", "", "Directory for the chain, a temporary one if empty")
+}
+
+func fuzzReplay(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("No corpus file to replay")
+	}
+	dir := fuzzReplayDir
+	if dir == "" {
+		var err error
+		if dir, err = ioutil.TempDir("", "fuzz_replay"); err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir) // nolint: errcheck
+	}
+
+	n, err := newFuzzReplayNode(dir)
+	if err != nil {
+		return err
+	}
+	if err := n.Start(); err != nil {
+		return err
+	}
+	defer n.Stop() // nolint: errcheck
+
+	crashed := 0
+	for _, path := range args {
+		frames, err := p2p.ReadCorpusFile(path)
+		if err != nil {
+			return fmt.Errorf("Error reading corpus %s: %v", path, err)
+		}
+		if err := p2p.ReplayCorpus(n.Switch(), frames); err != nil {
+			crashed++
+			fmt.Printf("%s: %v\n", path, err)
+			continue
+		}
+		fmt.Printf("%s: replayed %d messages\n", path, len(frames))
+	}
+	if crashed > 0 {
+		return fmt.Errorf("%d of %d corpora crashed a reactor", crashed, len(args))
+	}
+	return nil
+}
+
+// newFuzzReplayNode returns a node running a single validator chain of its own
+// in the dir, with the RPC disabled and listening for peers on localhost only.
+func newFuzzReplayNode(dir string) (*nm.Node, error) {
+	config := cfg.DefaultConfig().SetRoot(dir)
+	config.ChainID = "fuzz-replay"
+	config.ProxyApp = "dummy"
+	config.DBBackend = "memdb"
+	config.RPC.ListenAddress = ""
+	config.P2P.ListenAddress = "tcp://127.0.0.1:0"
+	config.P2P.SkipUPNP = true
+	// replay the pex messages too
+	config.P2P.PexReactor = true
+
+	privVal := types.GenPrivValidatorFS(config.PrivValidatorFile())
+	genDoc := &types.GenesisDoc{
+		ChainID: config.ChainID,
+		Validators: []types.GenesisValidator{{
+			PubKey: privVal.GetPubKey(),
+			Power:  10,
+		}},
+	}
+	return nm.NewNode(config,
+		privVal,
+		proxy.DefaultClientCreator(config.ProxyApp, config.ABCI, config.DBDir()),
+		func() (*types.GenesisDoc, error) { return genDoc, nil },
+		nm.DefaultDBProvider,
+		logger)
+}
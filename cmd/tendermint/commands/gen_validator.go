@@ -3,6 +3,7 @@ package commands
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -14,15 +15,28 @@ import (
 var GenValidatorCmd = &cobra.Command{
 	Use:   "gen_validator",
 	Short: "Generate new validator keypair",
-	Run:   genValidator,
+	RunE:  genValidator,
 }
 
-func genValidator(cmd *cobra.Command, args []string) {
-	privValidator := types.GenPrivValidatorFS("")
+func init() {
+	GenValidatorCmd.Flags().String("key_type", types.KeyTypeEd25519, "Type of the validator key: "+strings.Join(types.KeyTypes(), " | "))
+}
+
+func genValidator(cmd *cobra.Command, args []string) error {
+	keyType, err := cmd.Flags().GetString("key_type")
+	if err != nil {
+		return err
+	}
+	privKey, err := types.GenPrivKey(keyType)
+	if err != nil {
+		return err
+	}
+	privValidator := types.NewPrivValidatorFS(privKey, "")
 	privValidatorJSONBytes, err := json.MarshalIndent(privValidator, "", "\t")
 	if err != nil {
-		panic(err)
+		return err
 	}
 	fmt.Printf(`%v
 `, string(privValidatorJSONBytes))
+	return nil
 }
@@ -2,9 +2,12 @@ package commands
 
 import (
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	cfg "github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/p2p"
 	"github.com/tendermint/tendermint/types"
 	cmn "github.com/tendermint/tmlibs/common"
 )
@@ -13,13 +16,37 @@ import (
 var InitFilesCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize Tendermint",
-	Run:   initFiles,
+	RunE:  initFiles,
 }
 
-func initFiles(cmd *cobra.Command, args []string) {
+func init() {
+	InitFilesCmd.Flags().String("profile", "", "Config preset for the role of the node: "+strings.Join(cfg.Profiles(), " | "))
+	InitFilesCmd.Flags().String("key_type", types.KeyTypeEd25519, "Type of the validator key: "+strings.Join(types.KeyTypes(), " | "))
+}
+
+func initFiles(cmd *cobra.Command, args []string) error {
+	profile, err := cmd.Flags().GetString("profile")
+	if err != nil {
+		return err
+	}
+	if profile != "" {
+		if err := cfg.EnsureProfile(config.RootDir, profile); err != nil {
+			return err
+		}
+		logger.Info("Applied config profile", "profile", profile)
+	}
+
 	privValFile := config.PrivValidatorFile()
 	if _, err := os.Stat(privValFile); os.IsNotExist(err) {
-		privValidator := types.GenPrivValidatorFS(privValFile)
+		keyType, err := cmd.Flags().GetString("key_type")
+		if err != nil {
+			return err
+		}
+		privKey, err := types.GenPrivKey(keyType)
+		if err != nil {
+			return err
+		}
+		privValidator := types.NewPrivValidatorFS(privKey, privValFile)
 		privValidator.Save()
 
 		genFile := config.GenesisFile()
@@ -42,4 +69,11 @@ func initFiles(cmd *cobra.Command, args []string) {
 	} else {
 		logger.Info("Already initialized", "priv_validator", config.PrivValidatorFile())
 	}
+
+	nodeKey, err := p2p.LoadOrGenNodeKey(config.NodeKeyFile())
+	if err != nil {
+		return err
+	}
+	logger.Info("Node key", "ID", nodeKey.ID(), "node_key", config.NodeKeyFile())
+	return nil
 }
@@ -0,0 +1,153 @@
+package commands
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	cmn "github.com/tendermint/tmlibs/common"
+	dbm "github.com/tendermint/tmlibs/db"
+
+	bc "github.com/tendermint/tendermint/blockchain"
+	cstypes "github.com/tendermint/tendermint/consensus/types"
+	rpccore "github.com/tendermint/tendermint/rpc/core"
+	rpcserver "github.com/tendermint/tendermint/rpc/lib/server"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/state/apphash"
+	blockkv "github.com/tendermint/tendermint/state/blockindex/kv"
+	"github.com/tendermint/tendermint/state/signinginfo"
+	"github.com/tendermint/tendermint/state/txindex"
+	"github.com/tendermint/tendermint/state/txindex/kv"
+	"github.com/tendermint/tendermint/state/txindex/null"
+	"github.com/tendermint/tendermint/types"
+)
+
+// InspectCmd serves the RPC over the data of a stopped node.
+var InspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "Run the RPC server over the data of a stopped node, without p2p or consensus",
+	Long: `Serve the RPC endpoints that only read the block store, the state DB and
+the tx indexer of a node, eg. to look into a crashed or halted node.
+The DBs are opened read-only, and the node must be stopped.
+
+The endpoints served are: block, blockchain, commit, genesis, random_beacon,
+signing_info, app_hash, app_hashes, tx, tx_search, block_search and validators.`,
+	RunE:         inspect,
+	SilenceUsage: true,
+}
+
+func init() {
+	InspectCmd.Flags().String("rpc.laddr", config.RPC.ListenAddress, "RPC listen address. Port required")
+}
+
+func inspect(cmd *cobra.Command, args []string) error {
+	// the DBs are opened read-only
+	openDB := func(name string) (dbm.DB, error) {
+		return openReadOnlyDB(name, config.DBBackend, config.DBDir())
+	}
+
+	stateDB, err := openDB("state")
+	if err != nil {
+		return err
+	}
+	state := sm.LoadState(stateDB)
+	if state == nil {
+		return fmt.Errorf("No state found in %s", config.DBDir())
+	}
+	genDoc, err := types.GenesisDocFromFile(config.GenesisFile())
+	if err != nil {
+		return err
+	}
+	blockStoreDB, err := openDB("blockstore")
+	if err != nil {
+		return err
+	}
+
+	var txIndexer txindex.TxIndexer = &null.TxIndex{}
+	if config.TxIndex.Indexer == "kv" {
+		db, err := openDB("tx_index")
+		if err != nil {
+			return err
+		}
+		txIndexer = kv.NewTxIndex(db)
+	}
+
+	rpccore.SetBlockStore(bc.NewBlockStore(blockStoreDB))
+	rpccore.SetGenesisDoc(genDoc)
+	rpccore.SetTxIndexer(txIndexer)
+	if config.TxIndex.IndexBlocks {
+		db, err := openDB("block_index")
+		if err != nil {
+			return err
+		}
+		rpccore.SetBlockIndexer(blockkv.NewBlockIndex(db))
+	}
+	if config.IndexSigningInfo {
+		db, err := openDB("signing_info")
+		if err != nil {
+			return err
+		}
+		rpccore.SetSigningInfoStore(signinginfo.NewStore(db))
+	}
+	if config.IndexAppHashes {
+		db, err := openDB("app_hash")
+		if err != nil {
+			return err
+		}
+		rpccore.SetAppHashStore(apphash.NewStore(db))
+	}
+	rpccore.SetConsensusState(inspectConsensus{state})
+	rpccore.SetHistoryCacheSize(config.RPC.HistoryCacheSize)
+	rpccore.SetLogger(logger.With("module", "rpc"))
+
+	routes := rpccore.InspectRoutes()
+	rpcLogger := logger.With("module", "rpc-server")
+	var listeners []net.Listener
+	for _, listenAddr := range strings.Split(config.RPC.ListenAddress, ",") {
+		mux := http.NewServeMux()
+		rpcserver.RegisterRPCFuncs(mux, routes, rpcLogger)
+		listener, err := rpcserver.StartHTTPServer(listenAddr, mux, rpcLogger)
+		if err != nil {
+			return err
+		}
+		listeners = append(listeners, listener)
+	}
+	logger.Info("Inspecting node", "height", state.LastBlockHeight, "chain_id", state.ChainID)
+
+	cmn.TrapSignal(func() {
+		for _, l := range listeners {
+			if err := l.Close(); err != nil {
+				logger.Error("Error closing listener", "listener", l, "err", err)
+			}
+		}
+	})
+	return nil
+}
+
+// inspectConsensus stands for the consensus of the stopped node,
+// with the state it stopped at.
+type inspectConsensus struct {
+	state *sm.State
+}
+
+func (c inspectConsensus) GetState() *sm.State {
+	return c.state.Copy()
+}
+
+func (c inspectConsensus) GetValidators() (int64, []*types.Validator) {
+	return c.state.LastBlockHeight, c.state.Validators.Copy().Validators
+}
+
+// GetRoundState returns nil, there's no round going on. It's not used by
+// the inspect routes.
+func (c inspectConsensus) GetRoundState() *cstypes.RoundState {
+	return nil
+}
+
+// ScheduleExit returns an error, the node is stopped.
+func (c inspectConsensus) ScheduleExit(height int64) error {
+	return fmt.Errorf("The node is stopped")
+}
@@ -1,10 +1,15 @@
 package commands
 
 import (
+	"encoding/hex"
+	"fmt"
+	"time"
+
 	"github.com/spf13/cobra"
 
 	cmn "github.com/tendermint/tmlibs/common"
 
+	"github.com/tendermint/tendermint/lite"
 	"github.com/tendermint/tendermint/lite/proxy"
 	rpcclient "github.com/tendermint/tendermint/rpc/client"
 )
@@ -16,18 +21,29 @@ var LiteCmd = &cobra.Command{
 	Long: `This node will run a secure proxy to a tendermint rpc server.
 
 All calls that can be tracked back to a block header by a proof
-will be verified before passing them back to the caller. Other that
+will be verified before passing them back to the caller: the abci
+queries, blocks, headers, commits, validators and txs. Other that
 that it will present the same interface as a full tendermint node,
-just with added trust and running locally.`,
+just with added trust and running locally.
+
+The headers are verified from the validator sets trusted in the home
+directory. The first time, give the height and hash of a block obtained
+by other means, eg. from a block explorer or a friend running a full
+node, with --trust-height and --trust-hash.`,
 	RunE:         runProxy,
 	SilenceUsage: true,
 }
 
 var (
-	listenAddr string
-	nodeAddr   string
-	chainID    string
-	home       string
+	listenAddr     string
+	nodeAddr       string
+	chainID        string
+	home           string
+	trustHeight    int64
+	trustHash      string
+	trustLevel     string
+	sequential     bool
+	trustingPeriod time.Duration
 )
 
 func init() {
@@ -35,16 +51,35 @@ func init() {
 	LiteCmd.Flags().StringVar(&nodeAddr, "node", "localhost:46657", "Connect to a Tendermint node at this address")
 	LiteCmd.Flags().StringVar(&chainID, "chain-id", "tendermint", "Specify the Tendermint chain ID")
 	LiteCmd.Flags().StringVar(&home, "home-dir", ".tendermint-lite", "Specify the home directory")
+	LiteCmd.Flags().Int64Var(&trustHeight, "trust-height", 1, "Height of the trusted block, to establish a root of trust")
+	LiteCmd.Flags().StringVar(&trustHash, "trust-hash", "", "Hex hash of the trusted block, to establish a root of trust")
+	LiteCmd.Flags().StringVar(&trustLevel, "trust-level", lite.DefaultTrustLevel.String(), "Fraction of the trusted voting power that must sign a header to skip to it")
+	LiteCmd.Flags().BoolVar(&sequential, "sequential", false, "Verify every header in between rather than skipping")
+	LiteCmd.Flags().DurationVar(&trustingPeriod, "trusting-period", 0, "How long the trusted validators are trusted, less than the unbonding period (0 for ever)")
 }
 
 func runProxy(cmd *cobra.Command, args []string) error {
 	// First, connect a client
 	node := rpcclient.NewHTTP(nodeAddr, "/websocket")
 
-	cert, err := proxy.GetCertifier(chainID, home, nodeAddr)
+	hash, err := hex.DecodeString(trustHash)
+	if err != nil {
+		return fmt.Errorf("Invalid --trust-hash: %v", err)
+	}
+	var level lite.TrustLevel
+	if _, err := fmt.Sscanf(trustLevel, "%d/%d", &level.Numerator, &level.Denominator); err != nil {
+		return fmt.Errorf("Invalid --trust-level %q, expected eg. 1/3", trustLevel)
+	}
+
+	cert, err := proxy.GetCertifier(chainID, home, nodeAddr, trustHeight, hash)
 	if err != nil {
 		return err
 	}
+	if err := cert.SetTrustLevel(level); err != nil {
+		return err
+	}
+	cert.SetSequential(sequential)
+	cert.SetTrustingPeriod(trustingPeriod)
 	sc := proxy.SecureClient(node, cert)
 
 	err = proxy.StartProxy(sc, listenAddr, logger)
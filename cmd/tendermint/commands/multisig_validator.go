@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	crypto "github.com/tendermint/go-crypto"
+	"github.com/tendermint/go-wire/data"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// MultisigValidatorCmd prints the public key of a multisig validator,
+// for the genesis or a validator set update.
+var MultisigValidatorCmd = &cobra.Command{
+	Use:   "multisig_validator [co-signer public keys]",
+	Short: "Show the public key of a multisig validator",
+	Long: `Print the public key of a validator signing with --threshold of the
+co-signers' keys, as printed by show_validator on each of them, eg.
+
+  tendermint multisig_validator --threshold 2 "$(cat key1.json)" "$(cat key2.json)" "$(cat key3.json)"
+
+Run the node with the co-signers' priv_validator_laddr addresses in the same
+order, and the same priv_validator_threshold.`,
+	RunE:         multisigValidator,
+	SilenceUsage: true,
+}
+
+var multisigThreshold int
+
+func init() {
+	MultisigValidatorCmd.Flags().IntVar(&multisigThreshold, "threshold", 0, "Number of co-signers signing for the validator, 0 for all")
+}
+
+func multisigValidator(cmd *cobra.Command, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("At least 2 co-signer public keys are required")
+	}
+	pubKeys := make([]crypto.PubKey, len(args))
+	for i, arg := range args {
+		if err := json.Unmarshal([]byte(arg), &pubKeys[i]); err != nil || pubKeys[i].Empty() {
+			return fmt.Errorf("Invalid public key %q: %v", arg, err)
+		}
+	}
+	threshold := multisigThreshold
+	if threshold == 0 {
+		threshold = len(pubKeys)
+	}
+
+	pubKey, err := types.NewMultisigPubKey(threshold, pubKeys)
+	if err != nil {
+		return err
+	}
+	pubKeyJSONBytes, err := data.ToJSON(pubKey)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(pubKeyJSONBytes))
+	return nil
+}
@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	crypto "github.com/tendermint/go-crypto"
+	cmn "github.com/tendermint/tmlibs/common"
+
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/types"
+	"github.com/tendermint/tendermint/types/privval"
+)
+
+// PrivValServerCmd runs a remote signer for a node with a priv_validator_laddr.
+var PrivValServerCmd = &cobra.Command{
+	Use:   "priv_val_server",
+	Short: "Sign the votes and proposals of a node remotely, with this priv_validator.json",
+	Long: `Connect to the priv_validator_laddr of a node, over an encrypted and
+authenticated connection, and sign its votes, proposals and heartbeats with
+the priv_validator.json of this home directory, which never leaves this machine.
+The priv_validator.json keeps refusing to double sign.
+
+The signer authenticates with the key of its --conn_key_file, generated at the
+first start, which the node must list in its priv_validator_authorized_keys,
+and only signs for the nodes of the --node_keys.
+
+The connection is made again whenever it's lost, eg. when the node restarts.`,
+	RunE:         privValServer,
+	SilenceUsage: true,
+}
+
+var (
+	privValServerAddr        string
+	privValServerConnKeyFile string
+	privValServerNodeKeys    string
+)
+
+func init() {
+	PrivValServerCmd.Flags().StringVar(&privValServerAddr, "addr", "tcp://127.0.0.1:46659", "The priv_validator_laddr of the node")
+	PrivValServerCmd.Flags().StringVar(&privValServerConnKeyFile, "conn_key_file", "priv_val_conn_key.json", "The key file of the signer for the connection, relative to the home directory")
+	PrivValServerCmd.Flags().StringVar(&privValServerNodeKeys, "node_keys", "", "Comma separated hex of the public keys of the nodes to sign for")
+}
+
+func privValServer(cmd *cobra.Command, args []string) error {
+	connKeyFile := privValServerConnKeyFile
+	if !filepath.IsAbs(connKeyFile) {
+		connKeyFile = filepath.Join(config.RootDir, connKeyFile)
+	}
+	connKey, err := loadPrivValConnKey(connKeyFile)
+	if err != nil {
+		return err
+	}
+	nodeKeys, err := privval.ParseConnKeys(privValServerNodeKeys)
+	if err != nil {
+		return err
+	}
+	if len(nodeKeys) == 0 {
+		return fmt.Errorf("--node_keys is required; the connection key of this signer, for the "+
+			"priv_validator_authorized_keys of the nodes, is %v", connKey.PubKey().Unwrap().(crypto.PubKeyEd25519).KeyString())
+	}
+
+	privVal := types.LoadPrivValidatorFS(config.PrivValidatorFile())
+	server := privval.NewPrivValidatorSocketServer(privValServerAddr, connKey, privVal)
+	server.SetLogger(logger.With("module", "privval"))
+	server.SetNodeKeys(nodeKeys)
+	if err := server.Start(); err != nil {
+		return err
+	}
+	logger.Info("Signing for the node", "addr", privValServerAddr, "address", privVal.GetAddress())
+
+	cmn.TrapSignal(func() {
+		server.Stop()
+	})
+	return nil
+}
+
+// loadPrivValConnKey returns the ed25519 key of the file, generated if it
+// doesn't exist yet.
+func loadPrivValConnKey(filePath string) (crypto.PrivKeyEd25519, error) {
+	key, err := p2p.LoadOrGenNodeKey(filePath)
+	if err != nil {
+		return crypto.PrivKeyEd25519{}, err
+	}
+	connKey, ok := key.PrivKey.Unwrap().(crypto.PrivKeyEd25519)
+	if !ok {
+		return crypto.PrivKeyEd25519{}, errors.New("The connection key must be an ed25519 key")
+	}
+	return connKey, nil
+}
@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	cmn "github.com/tendermint/tmlibs/common"
+	dbm "github.com/tendermint/tmlibs/db"
+)
+
+// readOnlyDB is a leveldb DB opened read-only, eg. for inspect not to touch
+// the data of a stopped node. It panics on writes.
+type readOnlyDB struct {
+	db *leveldb.DB
+}
+
+var _ dbm.DB = (*readOnlyDB)(nil)
+
+// openReadOnlyDB opens the DB of the given name in dir read-only. Only the
+// leveldb backends are supported, as the others have no data on disk.
+func openReadOnlyDB(name, backend, dir string) (dbm.DB, error) {
+	switch backend {
+	case "leveldb", "goleveldb", "cleveldb":
+	default:
+		return nil, fmt.Errorf("Cannot open the %s DB read-only with the %q backend", name, backend)
+	}
+	db, err := leveldb.OpenFile(path.Join(dir, name+".db"), &opt.Options{
+		ReadOnly:       true,
+		ErrorIfMissing: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error opening the %s DB read-only: %v", name, err)
+	}
+	return &readOnlyDB{db}, nil
+}
+
+func (db *readOnlyDB) Get(key []byte) []byte {
+	res, err := db.db.Get(key, nil)
+	if err == errors.ErrNotFound {
+		return nil
+	} else if err != nil {
+		cmn.PanicCrisis(err)
+	}
+	return res
+}
+
+func (db *readOnlyDB) Set([]byte, []byte)     { db.panicWrite() }
+func (db *readOnlyDB) SetSync([]byte, []byte) { db.panicWrite() }
+func (db *readOnlyDB) Delete([]byte)          { db.panicWrite() }
+func (db *readOnlyDB) DeleteSync([]byte)      { db.panicWrite() }
+
+func (db *readOnlyDB) NewBatch() dbm.Batch {
+	db.panicWrite()
+	return nil
+}
+
+func (db *readOnlyDB) panicWrite() {
+	cmn.PanicSanity("Cannot write to a read-only DB")
+}
+
+func (db *readOnlyDB) Close() {
+	db.db.Close() // nolint: errcheck
+}
+
+func (db *readOnlyDB) Iterator() dbm.Iterator {
+	return db.db.NewIterator(nil, nil)
+}
+
+func (db *readOnlyDB) IteratorPrefix(prefix []byte) dbm.Iterator {
+	return db.db.NewIterator(util.BytesPrefix(prefix), nil)
+}
+
+func (db *readOnlyDB) Print() {
+	iter := db.db.NewIterator(nil, nil)
+	defer iter.Release()
+	for iter.Next() {
+		fmt.Printf("[%X]:\t[%X]\n", iter.Key(), iter.Value())
+	}
+}
+
+func (db *readOnlyDB) Stats() map[string]string {
+	stats := make(map[string]string)
+	for _, key := range []string{"leveldb.stats", "leveldb.sstables"} {
+		if value, err := db.db.GetProperty(key); err == nil {
+			stats[key] = value
+		}
+	}
+	return stats
+}
@@ -1,14 +1,19 @@
 package commands
 
 import (
+	"bytes"
+	"encoding/json"
 	"os"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	cfg "github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/types"
 	"github.com/tendermint/tmlibs/cli"
 	tmflags "github.com/tendermint/tmlibs/cli/flags"
+	cmn "github.com/tendermint/tmlibs/common"
 	"github.com/tendermint/tmlibs/log"
 )
 
@@ -22,7 +27,8 @@ func init() {
 }
 
 // ParseConfig retrieves the default environment configuration,
-// sets up the Tendermint root and ensures that the root exists
+// sets up the Tendermint root and ensures that the root exists.
+// The settings of the config file are those of the chain, see cfg.ChainsKey.
 func ParseConfig() (*cfg.Config, error) {
 	conf := cfg.DefaultConfig()
 	err := viper.Unmarshal(conf)
@@ -30,10 +36,49 @@ func ParseConfig() (*cfg.Config, error) {
 		return nil, err
 	}
 	conf.SetRoot(conf.RootDir)
+	if file := viper.ConfigFileUsed(); file != "" {
+		if err := readChainSettings(file, conf); err != nil {
+			return nil, err
+		}
+		conf = cfg.DefaultConfig()
+		if err := viper.Unmarshal(conf); err != nil {
+			return nil, err
+		}
+		conf.SetRoot(conf.RootDir)
+	}
 	cfg.EnsureRoot(conf.RootDir)
 	return conf, err
 }
 
+// readChainSettings replaces the settings of the config file in viper by
+// those of the chain, the chain_id of the config or else of the genesis file,
+// so that the flags and the env still override them.
+func readChainSettings(file string, conf *cfg.Config) error {
+	settings, err := cfg.LoadFileSettings(file)
+	if err != nil {
+		return err
+	}
+	chainID := conf.ChainID
+	if chainID == "" && cmn.FileExists(conf.GenesisFile()) {
+		genDoc, err := types.GenesisDocFromFile(conf.GenesisFile())
+		if err != nil {
+			return err
+		}
+		chainID = genDoc.ChainID
+	}
+	settings, err = cfg.ChainSettings(settings, chainID)
+	if err != nil {
+		return errors.Wrap(err, file)
+	}
+	// viper only reads the settings from an encoding
+	settingsJSON, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	viper.SetConfigType("json")
+	return viper.ReadConfig(bytes.NewReader(settingsJSON))
+}
+
 // RootCmd is the root command for Tendermint core.
 var RootCmd = &cobra.Command{
 	Use:   "tendermint",
@@ -1,7 +1,9 @@
 package commands
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 
@@ -62,7 +64,7 @@ func TestRootConfig(t *testing.T) {
 	require.Nil(err)
 
 	defaults := cfg.DefaultConfig()
-	dmax := defaults.P2P.MaxNumPeers
+	dmax := defaults.P2P.MaxNumInboundPeers
 
 	cases := []struct {
 		args     []string
@@ -77,8 +79,8 @@ func TestRootConfig(t *testing.T) {
 		{[]string{"--home", conf}, nil, conf, cvals["moniker"], cfast, dmax},
 		{nil, map[string]string{"TMHOME": conf}, conf, cvals["moniker"], cfast, dmax},
 		// check setting p2p subflags two different ways
-		{[]string{"--p2p.max_num_peers", "420"}, nil, defaultRoot, defaults.Moniker, defaults.FastSync, 420},
-		{nil, map[string]string{"TM_P2P_MAX_NUM_PEERS": "17"}, defaultRoot, defaults.Moniker, defaults.FastSync, 17},
+		{[]string{"--p2p.max_num_inbound_peers", "420"}, nil, defaultRoot, defaults.Moniker, defaults.FastSync, 420},
+		{nil, map[string]string{"TM_P2P_MAX_NUM_INBOUND_PEERS": "17"}, defaultRoot, defaults.Moniker, defaults.FastSync, 17},
 		// try to set env that have no flags attached...
 		{[]string{"--home", conf}, map[string]string{"TM_MONIKER": "funny"}, conf, "funny", cfast, dmax},
 	}
@@ -92,7 +94,7 @@ func TestRootConfig(t *testing.T) {
 				return nil
 			},
 		}
-		noop.Flags().Int("p2p.max_num_peers", defaults.P2P.MaxNumPeers, "")
+		noop.Flags().Int("p2p.max_num_inbound_peers", defaults.P2P.MaxNumInboundPeers, "")
 		cmd := isolate(noop)
 
 		args := append([]string{rootName, noop.Use}, tc.args...)
@@ -104,7 +106,72 @@ func TestRootConfig(t *testing.T) {
 		assert.Equal(tc.root, config.Mempool.RootDir, i)
 		assert.Equal(tc.moniker, config.Moniker, i)
 		assert.Equal(tc.fastSync, config.FastSync, i)
-		assert.Equal(tc.maxPeer, config.P2P.MaxNumPeers, i)
+		assert.Equal(tc.maxPeer, config.P2P.MaxNumInboundPeers, i)
 	}
 
 }
+
+func TestRootChainConfig(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	dir, err := ioutil.TempDir("", "chain_config")
+	require.Nil(err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+	writeConfig := func(contents string) {
+		require.Nil(ioutil.WriteFile(filepath.Join(dir, "config.toml"), []byte(contents), 0644))
+	}
+	run := func(args []string, env map[string]string) error {
+		noop := &cobra.Command{
+			Use: "noop",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return nil
+			},
+		}
+		noop.Flags().Int("p2p.max_num_inbound_peers", cfg.DefaultConfig().P2P.MaxNumInboundPeers, "")
+		return cli.RunWithArgs(isolate(noop), append([]string{rootName, noop.Use, "--home", dir}, args...), env)
+	}
+
+	writeConfig(`
+chain_id = "chain-A"
+moniker = "fleet"
+fast_sync = false
+
+[p2p]
+max_num_inbound_peers = 30
+
+[chains.chain-A]
+moniker = "a"
+
+[chains.chain-A.p2p]
+max_num_inbound_peers = 40
+
+[chains.chain-B]
+moniker = "b"
+`)
+	require.Nil(run(nil, nil))
+	assert.Equal("a", config.Moniker)
+	assert.Equal(40, config.P2P.MaxNumInboundPeers)
+	// not overridden
+	assert.False(config.FastSync)
+
+	// the flags and the env override the chain section
+	require.Nil(run([]string{"--p2p.max_num_inbound_peers", "50"}, map[string]string{"TM_MONIKER": "env"}))
+	assert.Equal("env", config.Moniker)
+	assert.Equal(50, config.P2P.MaxNumInboundPeers)
+
+	// the sections of the other chains are ignored
+	require.Nil(run(nil, map[string]string{"TM_CHAIN_ID": "chain-C"}))
+	assert.Equal("fleet", config.Moniker)
+	assert.Equal(30, config.P2P.MaxNumInboundPeers)
+
+	// unknown keys are errors, in any chain section
+	writeConfig(`
+moniker = "fleet"
+
+[chains.chain-B.p2p]
+max_num_peer = 40
+`)
+	err = run(nil, nil)
+	require.NotNil(err)
+	assert.Contains(err.Error(), "chains.chain-B.p2p.max_num_peer")
+}
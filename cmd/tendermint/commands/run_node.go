@@ -5,7 +5,11 @@ import (
 
 	"github.com/spf13/cobra"
 
+	cfg "github.com/tendermint/tendermint/config"
 	nm "github.com/tendermint/tendermint/node"
+	"github.com/tendermint/tendermint/proxy"
+	"github.com/tendermint/tendermint/statesync/rpcprovider"
+	"github.com/tendermint/tmlibs/log"
 )
 
 // AddNodeFlags exposes some common configuration options on the command-line
@@ -14,6 +18,11 @@ func AddNodeFlags(cmd *cobra.Command) {
 	// bind flags
 	cmd.Flags().String("moniker", config.Moniker, "Node Name")
 
+	// priv val flags
+	cmd.Flags().String("priv_validator_laddr", config.PrivValidatorListenAddr, "Socket address to listen on for a remote signer (priv_val_server), comma separated for a multisig of several")
+	cmd.Flags().Int("priv_validator_threshold", config.PrivValidatorThreshold, "Number of the remote signers signing for a multisig validator, 0 for all")
+	cmd.Flags().String("priv_validator_authorized_keys", config.PrivValidatorAuthorizedKeys, "Comma separated hex of the connection keys of the remote signers")
+
 	// node flags
 	cmd.Flags().Bool("fast_sync", config.FastSync, "Fast blockchain syncing")
 
@@ -28,12 +37,20 @@ func AddNodeFlags(cmd *cobra.Command) {
 
 	// p2p flags
 	cmd.Flags().String("p2p.laddr", config.P2P.ListenAddress, "Node listen address. (0.0.0.0:0 means any interface, any port)")
-	cmd.Flags().String("p2p.seeds", config.P2P.Seeds, "Comma delimited host:port seed nodes")
+	cmd.Flags().String("p2p.seeds", config.P2P.Seeds, "Comma delimited id@host:port seed nodes")
+	cmd.Flags().String("p2p.dns_seeds", config.P2P.DNSSeeds, "Comma delimited DNS names whose SRV/TXT records list seed nodes")
 	cmd.Flags().Bool("p2p.skip_upnp", config.P2P.SkipUPNP, "Skip UPNP configuration")
 	cmd.Flags().Bool("p2p.pex", config.P2P.PexReactor, "Enable Peer-Exchange (dev feature)")
+	cmd.Flags().Bool("p2p.seed_mode", config.P2P.SeedMode, "Run a seed node, only crawling the network and handing out addresses (needs p2p.pex)")
+	cmd.Flags().String("p2p.fuzz_drop_rates", config.P2P.FuzzDropRates, "Comma delimited reactor:percent of the received messages to drop (test networks only)")
+	cmd.Flags().String("p2p.fuzz_corrupt_rates", config.P2P.FuzzCorruptRates, "Comma delimited reactor:percent of the received messages to corrupt (test networks only)")
+	cmd.Flags().String("p2p.fuzz_delay_rates", config.P2P.FuzzDelayRates, "Comma delimited reactor:percent of the received messages to delay (test networks only)")
+	cmd.Flags().Int("p2p.fuzz_max_delay", config.P2P.FuzzMaxDelay, "Max delay of the received messages, in ms")
 
 	// consensus flags
 	cmd.Flags().Bool("consensus.create_empty_blocks", config.Consensus.CreateEmptyBlocks, "Set this to false to only produce blocks when there are txs or when the AppHash changes")
+	cmd.Flags().Int64("consensus.stop_signing_height", config.Consensus.StopSigningHeight, "Height after which the validator stops signing, to leave the validator set (0 for never)")
+	cmd.Flags().Int64("consensus.double_sign_check_height", config.Consensus.DoubleSignCheckHeight, "Number of past blocks to look for the validator's signature in before starting the consensus, to not double sign (0 to disable)")
 }
 
 // NewRunNodeCmd returns the command that allows the CLI to start a
@@ -43,6 +60,10 @@ func NewRunNodeCmd(nodeProvider nm.NodeProvider) *cobra.Command {
 		Use:   "node",
 		Short: "Run the tendermint node",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := config.ValidateProfile(); err != nil {
+				return err
+			}
+
 			// Create & start node
 			n, err := nodeProvider(config, logger)
 			if err != nil {
@@ -65,3 +86,20 @@ func NewRunNodeCmd(nodeProvider nm.NodeProvider) *cobra.Command {
 	AddNodeFlags(cmd)
 	return cmd
 }
+
+// DefaultNewNode returns a Tendermint node with the default settings of
+// nm.DefaultNewNode, state syncing from the RPC servers of the config if
+// enabled. It implements nm.NodeProvider.
+func DefaultNewNode(config *cfg.Config, logger log.Logger) (*nm.Node, error) {
+	privValidator, err := nm.DefaultPrivValidator(config)
+	if err != nil {
+		return nil, err
+	}
+	return nm.NewNode(config,
+		privValidator,
+		proxy.DefaultClientCreator(config.ProxyApp, config.ABCI, config.DBDir()),
+		nm.DefaultGenesisDocProviderFunc(config),
+		nm.DefaultDBProvider,
+		logger,
+		nm.WithStateProvider(rpcprovider.New))
+}
@@ -6,18 +6,23 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/tendermint/go-wire/data"
-	"github.com/tendermint/tendermint/types"
+	nm "github.com/tendermint/tendermint/node"
 )
 
 // ShowValidatorCmd adds capabilities for showing the validator info.
 var ShowValidatorCmd = &cobra.Command{
 	Use:   "show_validator",
 	Short: "Show this node's validator info",
-	Run:   showValidator,
+	RunE:  showValidator,
 }
 
-func showValidator(cmd *cobra.Command, args []string) {
-	privValidator := types.LoadOrGenPrivValidatorFS(config.PrivValidatorFile())
+func showValidator(cmd *cobra.Command, args []string) error {
+	// the key of the HSM if there's one
+	privValidator, err := nm.DefaultPrivValidator(config)
+	if err != nil {
+		return err
+	}
 	pubKeyJSONBytes, _ := data.ToJSON(privValidator.PubKey)
 	fmt.Println(string(pubKeyJSONBytes))
+	return nil
 }
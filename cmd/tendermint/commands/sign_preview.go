@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/tendermint/tendermint/consensus"
+	"github.com/tendermint/tendermint/types"
+	"github.com/tendermint/tendermint/types/privval"
+)
+
+// SignPreviewCmd reconstructs the sign bytes of the votes and proposals of a
+// WAL, and checks them against a signing policy without signing them.
+var SignPreviewCmd = &cobra.Command{
+	Use:   "sign_preview [wal files]",
+	Short: "Preview the sign bytes of the votes and proposals of a WAL, and check them against a signing policy",
+	Long: `Read the votes and proposals the node signed from its consensus WAL files,
+in order, or from the WAL of this home directory, and reconstruct the exact sign
+bytes its priv_validator saw. Each message is checked, in dry-run mode, against
+the signing policy of --policy, a JSON file, eg.
+
+  {"chain_ids": ["test-chain"], "msg_types": ["prevote", "precommit", "proposal"],
+   "min_height": 1, "max_height": 0, "max_round": 20, "max_height_jump": 1000}
+
+and, like a signer would, for height, round and step regressions and conflicting
+messages, so operators can validate the policy of an HSM before pointing a live
+validator at it. Nothing is signed.
+
+It exits with an error if the policy refuses any message.`,
+	RunE:         signPreview,
+	SilenceUsage: true,
+}
+
+var (
+	signPreviewPolicy    string
+	signPreviewChainID   string
+	signPreviewSignBytes bool
+)
+
+func init() {
+	SignPreviewCmd.Flags().StringVar(&signPreviewPolicy, "policy", "", "The JSON signing policy, none to only check for regressions")
+	SignPreviewCmd.Flags().StringVar(&signPreviewChainID, "chain-id", "", "The chain ID, the one of the genesis file if empty")
+	SignPreviewCmd.Flags().BoolVar(&signPreviewSignBytes, "sign-bytes", false, "Print the sign bytes of each message")
+}
+
+func signPreview(cmd *cobra.Command, args []string) error {
+	policy := new(privval.SigningPolicy)
+	if signPreviewPolicy != "" {
+		var err error
+		if policy, err = privval.LoadSigningPolicy(signPreviewPolicy); err != nil {
+			return err
+		}
+	}
+
+	chainID := signPreviewChainID
+	if chainID == "" {
+		genDoc, err := types.GenesisDocFromFile(config.GenesisFile())
+		if err != nil {
+			return err
+		}
+		chainID = genDoc.ChainID
+	}
+
+	files := args
+	if len(files) == 0 {
+		files = []string{config.Consensus.WalFile()}
+	}
+
+	ps := privval.NewPolicySimulator(policy, chainID)
+	checked, refused := 0, 0
+	for _, file := range files {
+		msgs, err := readSignedMessages(file)
+		if err != nil {
+			return err
+		}
+		for _, msg := range msgs {
+			checked++
+			signBytes, err := ps.Check(msg)
+			if err != nil {
+				refused++
+				fmt.Printf("%s: REFUSED: %v\n", privval.DescribeMsg(msg), err)
+			} else {
+				fmt.Printf("%s: OK\n", privval.DescribeMsg(msg))
+			}
+			if signPreviewSignBytes {
+				fmt.Printf("  %s\n", signBytes)
+			}
+		}
+	}
+
+	fmt.Printf("%d messages checked, %d refused\n", checked, refused)
+	if refused > 0 {
+		return fmt.Errorf("The policy refused %d of %d messages", refused, checked)
+	}
+	return nil
+}
+
+func readSignedMessages(file string) ([]types.Signable, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() // nolint: errcheck
+	msgs, err := consensus.ReadSignedMessages(f)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading the WAL %s: %v", file, err)
+	}
+	return msgs, nil
+}
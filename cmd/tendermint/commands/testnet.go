@@ -7,6 +7,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/tendermint/tendermint/p2p"
 	"github.com/tendermint/tendermint/types"
 	cmn "github.com/tendermint/tmlibs/common"
 )
@@ -81,6 +82,11 @@ func initMachCoreDirectory(base, mach string) error {
 
 	// Create priv_validator.json file if not present
 	ensurePrivValidator(path.Join(dir, "priv_validator.json"))
+
+	// Create node_key.json file if not present, the nodes are dialed by its ID
+	if _, err := p2p.LoadOrGenNodeKey(path.Join(dir, "node_key.json")); err != nil {
+		return err
+	}
 	return nil
 
 }
@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	crypto "github.com/tendermint/go-crypto"
+	cmn "github.com/tendermint/tmlibs/common"
+
+	"github.com/tendermint/tendermint/types"
+	"github.com/tendermint/tendermint/types/privval"
+)
+
+// ExportValidatorKeyCmd prints the private key of the priv_validator.json
+// in a standard format.
+var ExportValidatorKeyCmd = &cobra.Command{
+	Use:   "export_validator_key",
+	Short: "Print this node's validator private key as PKCS#8 PEM or hex",
+	Long: `Print the private key of the priv_validator.json, as PKCS#8 PEM
+(--format pem), encrypted with the passphrase of --passphrase_file if any,
+or as the raw hex of the 64 bytes private key (--format hex), eg. for backups
+or to provision an HSM.`,
+	RunE:         exportValidatorKey,
+	SilenceUsage: true,
+}
+
+// ImportValidatorKeyCmd creates the priv_validator.json from a private key
+// in a standard format.
+var ImportValidatorKeyCmd = &cobra.Command{
+	Use:   "import_validator_key [key file]",
+	Short: "Create this node's priv_validator.json from a PKCS#8 PEM or hex private key",
+	Long: `Create the priv_validator.json from the ed25519 private key in the file,
+as PKCS#8 PEM, decrypted with the passphrase of --passphrase_file if it's
+encrypted, or as the raw hex of the 32 bytes seed or the 64 bytes private key.
+
+The priv_validator.json must not exist. The new one has no last signed state,
+so it doesn't know what the key signed on other nodes: stop them before, and
+see consensus.double_sign_check_height.`,
+	RunE:         importValidatorKey,
+	SilenceUsage: true,
+}
+
+var (
+	keyFormat      string
+	passphraseFile string
+)
+
+func init() {
+	ExportValidatorKeyCmd.Flags().StringVar(&keyFormat, "format", privval.KeyFormatPEM, "Key format: pem or hex")
+	ExportValidatorKeyCmd.Flags().StringVar(&passphraseFile, "passphrase_file", "", "File with the passphrase to encrypt the pem key with")
+	ImportValidatorKeyCmd.Flags().StringVar(&passphraseFile, "passphrase_file", "", "File with the passphrase to decrypt the pem key with")
+}
+
+// readPassphrase returns the passphrase in the passphraseFile, if any.
+func readPassphrase() ([]byte, error) {
+	if passphraseFile == "" {
+		return nil, nil
+	}
+	passphrase, err := ioutil.ReadFile(passphraseFile)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(passphrase, "\r\n"), nil
+}
+
+func exportValidatorKey(cmd *cobra.Command, args []string) error {
+	passphrase, err := readPassphrase()
+	if err != nil {
+		return err
+	}
+	privVal := types.LoadPrivValidatorFS(config.PrivValidatorFile())
+	privKey, ok := privVal.PrivKey.Unwrap().(crypto.PrivKeyEd25519)
+	if !ok {
+		return fmt.Errorf("Only ed25519 keys can be exported")
+	}
+	bz, err := privval.ExportPrivKey(privKey, keyFormat, passphrase)
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(bz))
+	return nil
+}
+
+func importValidatorKey(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("Expected the key file")
+	}
+	privValFile := config.PrivValidatorFile()
+	if cmn.FileExists(privValFile) {
+		return fmt.Errorf("%s already exists, move it away first", privValFile)
+	}
+
+	passphrase, err := readPassphrase()
+	if err != nil {
+		return err
+	}
+	bz, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	privKey, err := privval.ImportPrivKey(bz, passphrase)
+	if err != nil {
+		return err
+	}
+
+	if err := cmn.EnsureDir(filepath.Dir(privValFile), 0700); err != nil {
+		return err
+	}
+	privVal := types.NewPrivValidatorFS(privKey.Wrap(), privValFile)
+	privVal.Save()
+	logger.Info("Imported the validator key", "address", fmt.Sprintf("%X", privVal.GetAddress()), "file", privValFile)
+	return nil
+}
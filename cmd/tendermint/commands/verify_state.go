@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	bc "github.com/tendermint/tendermint/blockchain"
+	sm "github.com/tendermint/tendermint/state"
+	dbm "github.com/tendermint/tmlibs/db"
+)
+
+// VerifyStateCmd checks the state DB against the block store.
+var VerifyStateCmd = &cobra.Command{
+	Use:     "verify_state",
+	Aliases: []string{"verify-state"},
+	Short:   "Verify the state DB against the block store (the node must be stopped)",
+	Long: `Recompute the hash of the state after each block from the block store,
+and check it against the one persisted in the state DB, to detect a corrupted
+state DB.
+
+With --height, print the state hash persisted after the block at that height
+instead, to compare it with the one of other nodes.`,
+	RunE:         verifyState,
+	SilenceUsage: true,
+}
+
+var (
+	verifyFromHeight int64
+	stateHashHeight  int64
+)
+
+func init() {
+	VerifyStateCmd.Flags().Int64Var(&verifyFromHeight, "from", 1, "Height to start verifying from")
+	VerifyStateCmd.Flags().Int64Var(&stateHashHeight, "height", 0, "Print the state hash at this height")
+}
+
+func verifyState(cmd *cobra.Command, args []string) error {
+	stateDB := dbm.NewDB("state", config.DBBackend, config.DBDir())
+	state := sm.LoadState(stateDB)
+	if state == nil {
+		return fmt.Errorf("No state found in %s", config.DBDir())
+	}
+
+	if stateHashHeight > 0 {
+		hash := sm.LoadStateHash(stateDB, stateHashHeight)
+		if hash == nil {
+			return fmt.Errorf("No state hash persisted at height %d", stateHashHeight)
+		}
+		fmt.Printf("%X\n", hash)
+		return nil
+	}
+
+	// the latest state must match the hash persisted along with it
+	persisted := sm.LoadStateHash(stateDB, state.LastBlockHeight)
+	if persisted != nil && !bytes.Equal(persisted, state.Hash()) {
+		return sm.ErrStateHashMismatch{Height: state.LastBlockHeight, Persisted: persisted, Computed: state.Hash()}
+	}
+
+	blockStore := bc.NewBlockStore(dbm.NewDB("blockstore", config.DBBackend, config.DBDir()))
+	verified, err := sm.VerifyStateHashes(stateDB, blockStore, state.Params, verifyFromHeight, state.LastBlockHeight)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Verified state", "heights", verified, "height", state.LastBlockHeight, "hash", fmt.Sprintf("%X", state.Hash()))
+	return nil
+}
@@ -6,22 +6,34 @@ import (
 	"github.com/tendermint/tmlibs/cli"
 
 	cmd "github.com/tendermint/tendermint/cmd/tendermint/commands"
-	nm "github.com/tendermint/tendermint/node"
 )
 
 func main() {
 	rootCmd := cmd.RootCmd
 	rootCmd.AddCommand(
+		cmd.BenchCmd,
+		cmd.ExportBlocksCmd,
+		cmd.ExportValidatorKeyCmd,
+		cmd.FuzzReplayCmd,
 		cmd.GenValidatorCmd,
+		cmd.ImportBlocksCmd,
+		cmd.ImportValidatorKeyCmd,
 		cmd.InitFilesCmd,
+		cmd.InspectCmd,
+		cmd.MultisigValidatorCmd,
+		cmd.PrivValServerCmd,
 		cmd.ProbeUpnpCmd,
 		cmd.LiteCmd,
 		cmd.ReplayCmd,
 		cmd.ReplayConsoleCmd,
+		cmd.ReplayFuzzCmd,
 		cmd.ResetAllCmd,
 		cmd.ResetPrivValidatorCmd,
+		cmd.ShowNodeIDCmd,
 		cmd.ShowValidatorCmd,
+		cmd.SignPreviewCmd,
 		cmd.TestnetFilesCmd,
+		cmd.VerifyStateCmd,
 		cmd.VersionCmd)
 
 	// NOTE:
@@ -31,8 +43,9 @@ func main() {
 	//	* Supply a genesis doc file from another source
 	//	* Provide their own DB implementation
 	// can copy this file and use something other than the
-	// DefaultNewNode function
-	nodeFunc := nm.DefaultNewNode
+	// DefaultNewNode function (nm.DefaultNewNode can't state sync, see
+	// nm.WithStateProvider)
+	nodeFunc := cmd.DefaultNewNode
 
 	// Create & start node
 	rootCmd.AddCommand(cmd.NewRunNodeCmd(nodeFunc))
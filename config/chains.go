@@ -0,0 +1,141 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	toml "github.com/pelletier/go-toml"
+)
+
+// ChainsKey is the table of the config file whose sections override the top
+// level settings for the chain of their name, so that one file templates the
+// nodes of several chains:
+//
+//	moniker = "fleet"
+//	[rpc]
+//	laddr = "tcp://0.0.0.0:46657"
+//
+//	[chains.chain-A.rpc]
+//	laddr = "tcp://0.0.0.0:46757"
+const ChainsKey = "chains"
+
+// LoadFileSettings returns the settings of the config file, by key, with the
+// sections as nested maps.
+func LoadFileSettings(file string) (map[string]interface{}, error) {
+	tree, err := toml.LoadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading config file %v: %v", file, err)
+	}
+	return tree.ToMap(), nil
+}
+
+// ChainSettings returns the top level settings overridden by the section of
+// the chain, if any, without the ChainsKey. It returns an error naming the keys
+// of the settings, or of any chain section, which are not those of the Config,
+// eg. misspelt, rather than ignore them.
+func ChainSettings(settings map[string]interface{}, chainID string) (map[string]interface{}, error) {
+	var unknown []string
+	var chainSettings map[string]interface{}
+	if value, ok := settings[ChainsKey]; ok {
+		chains, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s must be a table of chain sections", ChainsKey)
+		}
+		for id, section := range chains {
+			sectionSettings, ok := section.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s.%s must be a table of settings", ChainsKey, id)
+			}
+			unknown = append(unknown, unknownKeys(sectionSettings, configKeys, ChainsKey+"."+id+".")...)
+			if id == chainID {
+				chainSettings = sectionSettings
+			}
+		}
+	}
+	withoutChains := make(map[string]interface{}, len(settings))
+	for key, value := range settings {
+		if key != ChainsKey {
+			withoutChains[key] = value
+		}
+	}
+	unknown = append(unknown, unknownKeys(withoutChains, configKeys, "")...)
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("Unknown config keys: %s", strings.Join(unknown, ", "))
+	}
+	return mergeSettings(withoutChains, chainSettings), nil
+}
+
+// mergeSettings returns the settings overridden by the overrides, section by
+// section.
+func mergeSettings(settings, overrides map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(settings))
+	for key, value := range settings {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		section, isSection := merged[key].(map[string]interface{})
+		overrideSection, isOverrideSection := value.(map[string]interface{})
+		if isSection && isOverrideSection {
+			merged[key] = mergeSettings(section, overrideSection)
+			continue
+		}
+		merged[key] = value
+	}
+	return merged
+}
+
+// unknownKeys returns the keys of the settings, prefixed, which are not in
+// the known ones.
+func unknownKeys(settings map[string]interface{}, known map[string]interface{}, prefix string) []string {
+	var unknown []string
+	for key, value := range settings {
+		knownValue, ok := known[key]
+		if !ok {
+			unknown = append(unknown, prefix+key)
+			continue
+		}
+		section, isSection := knownValue.(map[string]interface{})
+		if !isSection {
+			continue
+		}
+		sectionSettings, ok := value.(map[string]interface{})
+		if !ok {
+			// a setting where a section is expected
+			unknown = append(unknown, prefix+key)
+			continue
+		}
+		unknown = append(unknown, unknownKeys(sectionSettings, section, prefix+key+".")...)
+	}
+	return unknown
+}
+
+// configKeys are the keys of the Config, the sections as nested maps.
+var configKeys = structKeys(reflect.TypeOf(Config{}))
+
+// structKeys returns the mapstructure keys of the fields of the struct, with
+// the squashed structs inlined and the struct pointers as sections.
+func structKeys(t reflect.Type) map[string]interface{} {
+	keys := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("mapstructure")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		if tag == ",squash" {
+			for key, value := range structKeys(field.Type) {
+				keys[key] = value
+			}
+			continue
+		}
+		if field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct {
+			keys[tag] = structKeys(field.Type.Elem())
+			continue
+		}
+		keys[tag] = true
+	}
+	return keys
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"testing"
+
+	toml "github.com/pelletier/go-toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainSettings(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	tree, err := toml.Load(`
+moniker = "fleet"
+fast_sync = false
+
+[rpc]
+laddr = "tcp://0.0.0.0:46657"
+unsafe = true
+
+[chains.chain-A]
+moniker = "a"
+
+[chains.chain-A.rpc]
+laddr = "tcp://0.0.0.0:46757"
+`)
+	require.Nil(err)
+	settings := tree.ToMap()
+
+	chainA, err := ChainSettings(settings, "chain-A")
+	require.Nil(err)
+	assert.Equal("a", chainA["moniker"])
+	assert.Equal(false, chainA["fast_sync"])
+	assert.Equal(map[string]interface{}{"laddr": "tcp://0.0.0.0:46757", "unsafe": true}, chainA["rpc"])
+	assert.NotContains(chainA, ChainsKey)
+
+	// no section for the chain
+	chainB, err := ChainSettings(settings, "chain-B")
+	require.Nil(err)
+	assert.Equal("fleet", chainB["moniker"])
+	assert.Equal("tcp://0.0.0.0:46657", chainB["rpc"].(map[string]interface{})["laddr"])
+
+	// the settings are left untouched
+	assert.Equal("fleet", settings["moniker"])
+
+	// the unknown keys are all named
+	_, err = ChainSettings(map[string]interface{}{
+		"monikr": "x",
+		"rpc":    map[string]interface{}{"ladr": "x"},
+		"p2p":    "x",
+		"chains": map[string]interface{}{
+			"chain-B": map[string]interface{}{"mempool": map[string]interface{}{"recheckk": true}},
+		},
+	}, "chain-A")
+	require.NotNil(err)
+	for _, key := range []string{"monikr", "rpc.ladr", "p2p", "chains.chain-B.mempool.recheckk"} {
+		assert.Contains(err.Error(), key)
+	}
+	_, err = ChainSettings(map[string]interface{}{"chains": "x"}, "chain-A")
+	assert.NotNil(err)
+}
+
+func TestChainSettingsDefaultConfig(t *testing.T) {
+	// the config files written are valid
+	tree, err := toml.Load(defaultConfig("m"))
+	require.Nil(t, err)
+	_, err = ChainSettings(tree.ToMap(), "")
+	assert.Nil(t, err)
+}
@@ -1,7 +1,9 @@
 package config
 
 import (
+	"math"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -16,7 +18,7 @@ func TestDefaultConfig(t *testing.T) {
 	assert.NotNil(cfg.Consensus)
 
 	// check the root dir stuff...
-	cfg.SetRoot("/foo")
+	cfg.RootDir = "/foo"
 	cfg.Genesis = "bar"
 	cfg.DBPath = "/opt/data"
 	cfg.Mempool.WalPath = "wal/mem/"
@@ -26,3 +28,188 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal("/foo/wal/mem", cfg.Mempool.WalDir())
 
 }
+
+func TestConfigProfiles(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, name := range Profiles() {
+		cfg := DefaultConfig()
+		assert.Nil(cfg.ApplyProfile(name), name)
+		assert.Equal(name, cfg.Profile)
+		assert.Nil(cfg.ValidateProfile(), name)
+	}
+
+	cfg := DefaultConfig()
+	assert.NotNil(cfg.ApplyProfile("miner"))
+	assert.Equal("", cfg.Profile)
+	assert.Nil(cfg.ValidateProfile(), "no profile, nothing to validate")
+
+	// changing the config against the profile is caught
+	assert.Nil(cfg.ApplyProfile(ProfileValidator))
+	cfg.P2P.PexReactor = true
+	assert.NotNil(cfg.ValidateProfile())
+
+	assert.Nil(cfg.ApplyProfile(ProfileArchive))
+	cfg.TxIndex.Indexer = "null"
+	assert.NotNil(cfg.ValidateProfile())
+}
+
+func TestConsensusConfigTimeoutEscalation(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := DefaultConsensusConfig()
+	cfg.TimeoutPropose = 3000
+	cfg.TimeoutProposeDelta = 500
+	assert.Nil(cfg.ValidateTimeouts())
+
+	// linear by default
+	ms := func(round int) int { return int(cfg.Propose(round) / time.Millisecond) }
+	assert.Equal([]int{3000, 3500, 4000, 5500}, []int{ms(0), ms(1), ms(2), ms(5)})
+
+	// the delta doubles every round
+	cfg.TimeoutEscalation = TimeoutEscalationExponential
+	assert.Nil(cfg.ValidateTimeouts())
+	assert.Equal([]int{3000, 3500, 4500, 18500}, []int{ms(0), ms(1), ms(2), ms(5)})
+
+	// up to the max, without overflowing
+	cfg.TimeoutMax = 10000
+	assert.Equal([]int{3000, 3500, 4500, 10000, 10000}, []int{ms(0), ms(1), ms(2), ms(5), ms(1000)})
+	cfg.TimeoutMax = 0
+	assert.Equal(math.MaxInt32, ms(1000))
+
+	// but never less than the timeout of the first round
+	cfg.TimeoutEscalation = TimeoutEscalationLinear
+	cfg.TimeoutMax = 1000
+	assert.Equal([]int{3000, 3000}, []int{ms(0), ms(5)})
+
+	cfg.TimeoutEscalation = "quadratic"
+	assert.NotNil(cfg.ValidateTimeouts())
+	cfg.TimeoutEscalation = TimeoutEscalationLinear
+	cfg.TimeoutMax = -1
+	assert.NotNil(cfg.ValidateTimeouts())
+}
+
+func TestRPCConfigValidateSubscriptions(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := DefaultRPCConfig()
+	assert.Nil(cfg.ValidateSubscriptions())
+	cfg.WSSlowSubscriberPolicy = SlowSubscriberClose
+	assert.Nil(cfg.ValidateSubscriptions())
+
+	cfg.WSSlowSubscriberPolicy = "block"
+	assert.NotNil(cfg.ValidateSubscriptions())
+	cfg.WSSlowSubscriberPolicy = SlowSubscriberDrop
+	cfg.WSSubscriptionBuffer = 0
+	assert.NotNil(cfg.ValidateSubscriptions())
+}
+
+func TestRPCConfigValidateRateLimits(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := DefaultRPCConfig()
+	assert.Nil(cfg.ValidateRateLimits())
+	cfg.RateLimit, cfg.MaxConcurrentRequests, cfg.MaxBodyBytes = 0.5, 4, 0
+	assert.Nil(cfg.ValidateRateLimits())
+
+	cfg.RateLimit = -1
+	assert.NotNil(cfg.ValidateRateLimits())
+	cfg.RateLimit, cfg.MaxConcurrentRequests = 0, -1
+	assert.NotNil(cfg.ValidateRateLimits())
+	cfg.MaxConcurrentRequests, cfg.MaxBodyBytes = 0, -1
+	assert.NotNil(cfg.ValidateRateLimits())
+}
+
+func TestRPCConfigValidateTLS(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := DefaultRPCConfig()
+	assert.Nil(cfg.ValidateTLS())
+	assert.False(cfg.IsTLSEnabled())
+	assert.Equal("", cfg.TLSClientCA())
+
+	cfg.RootDir = "/foo"
+	cfg.TLSCertFile = "config/rpc.crt"
+	assert.NotNil(cfg.ValidateTLS())
+	cfg.TLSKeyFile = "/keys/rpc.key"
+	assert.Nil(cfg.ValidateTLS())
+	assert.True(cfg.IsTLSEnabled())
+	assert.Equal("/foo/config/rpc.crt", cfg.TLSCert())
+	assert.Equal("/keys/rpc.key", cfg.TLSKey())
+
+	cfg.TLSClientCAFile = "config/ca.crt"
+	assert.Nil(cfg.ValidateTLS())
+	assert.Equal("/foo/config/ca.crt", cfg.TLSClientCA())
+	cfg.TLSCertFile, cfg.TLSKeyFile = "", ""
+	assert.NotNil(cfg.ValidateTLS())
+}
+
+func TestStateSyncConfigValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := DefaultStateSyncConfig()
+	assert.Nil(cfg.Validate(), "disabled")
+	cfg.Enable = true
+	assert.NotNil(cfg.Validate())
+
+	cfg.RPCServers = " tcp://1.2.3.4:46657, ,tcp://5.6.7.8:46657"
+	cfg.TrustHeight = 10
+	cfg.TrustHash = "96B1D2F2D201BA4BC383EB8224139DB1294944E5"
+	assert.Nil(cfg.Validate())
+	assert.Equal([]string{"tcp://1.2.3.4:46657", "tcp://5.6.7.8:46657"}, cfg.RPCServerList())
+
+	cfg.TrustHash = "not hex"
+	assert.NotNil(cfg.Validate())
+	cfg.TrustHash = "96B1D2F2D201BA4BC383EB8224139DB1294944E5"
+	cfg.ChunkFetchers = 0
+	assert.NotNil(cfg.Validate())
+}
+
+func TestP2PConfigChannelRates(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := DefaultP2PConfig()
+	assert.Nil(cfg.ValidateChannelRates())
+	assert.EqualValues(0, cfg.ChannelSendRate("MEMPOOL"))
+
+	cfg.ChannelSendRates = "mempool:102400, blockchain:256000"
+	cfg.ChannelRecvRates = "MEMPOOL:51200"
+	cfg.ChannelPriorities = "consensus:20,"
+	assert.Nil(cfg.ValidateChannelRates())
+	assert.EqualValues(102400, cfg.ChannelSendRate("MEMPOOL"))
+	assert.EqualValues(256000, cfg.ChannelSendRate("BLOCKCHAIN"))
+	assert.EqualValues(0, cfg.ChannelSendRate("CONSENSUS"))
+	assert.EqualValues(51200, cfg.ChannelRecvRate("MEMPOOL"))
+	assert.Equal(20, cfg.ChannelPriority("CONSENSUS"))
+	assert.Equal(0, cfg.ChannelPriority("MEMPOOL"))
+
+	cfg.ChannelSendRates = "mempool"
+	assert.NotNil(cfg.ValidateChannelRates())
+	cfg.ChannelSendRates = "mempool:-1"
+	assert.NotNil(cfg.ValidateChannelRates())
+	cfg.ChannelSendRates, cfg.ChannelPriorities = "", "consensus:high"
+	assert.NotNil(cfg.ValidateChannelRates())
+}
+
+func TestP2PConfigFuzzRates(t *testing.T) {
+	assert := assert.New(t)
+
+	cfg := DefaultP2PConfig()
+	assert.Nil(cfg.ValidateFuzzRates())
+	drop, corrupt, delay := cfg.ChannelFuzz("CONSENSUS")
+	assert.Zero(drop + corrupt + delay)
+
+	cfg.FuzzDropRates = "consensus:10"
+	cfg.FuzzCorruptRates = "consensus:1,mempool:5"
+	cfg.FuzzDelayRates = "CONSENSUS:50"
+	assert.Nil(cfg.ValidateFuzzRates())
+	drop, corrupt, delay = cfg.ChannelFuzz("CONSENSUS")
+	assert.EqualValues([]int64{10, 1, 50}, []int64{drop, corrupt, delay})
+	drop, corrupt, delay = cfg.ChannelFuzz("MEMPOOL")
+	assert.EqualValues([]int64{0, 5, 0}, []int64{drop, corrupt, delay})
+
+	cfg.FuzzDropRates = "consensus:101"
+	assert.NotNil(cfg.ValidateFuzzRates())
+	cfg.FuzzDropRates = "consensus:0.5"
+	assert.NotNil(cfg.ValidateFuzzRates())
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Profiles are presets of the config for the role a node plays in a network.
+const (
+	// ProfileValidator is a validator hidden behind sentry nodes.
+	ProfileValidator = "validator"
+	// ProfileSentry is a full node shielding validators from the rest of the network.
+	ProfileSentry = "sentry"
+	// ProfileArchive is a full node keeping and indexing the whole history.
+	ProfileArchive = "archive"
+	// ProfileSeed is a node crawling the network to hand out peer addresses.
+	ProfileSeed = "seed"
+)
+
+type profile struct {
+	// apply sets the defaults of the profile
+	apply func(*Config)
+	// validate returns an error if the config contradicts the profile
+	validate func(*Config) error
+}
+
+var profiles = map[string]profile{
+	ProfileValidator: {
+		apply: func(cfg *Config) {
+			cfg.P2P.PexReactor = false
+			cfg.P2P.MaxNumInboundPeers = 10
+			cfg.P2P.MaxNumOutboundPeers = 10
+			cfg.Mempool.Broadcast = true
+			cfg.TxIndex.Indexer = "null"
+		},
+		validate: func(cfg *Config) error {
+			if cfg.P2P.PexReactor {
+				return fmt.Errorf("p2p.pex must be disabled, validators should only dial their sentries (p2p.seeds)")
+			}
+			return nil
+		},
+	},
+	ProfileSentry: {
+		apply: func(cfg *Config) {
+			cfg.P2P.PexReactor = true
+			cfg.P2P.MaxNumInboundPeers = 100
+			cfg.P2P.MaxNumOutboundPeers = 20
+			cfg.P2P.MaxNumPeersPerIP = 4
+			cfg.Mempool.Broadcast = true
+			cfg.TxIndex.Indexer = "null"
+		},
+		validate: func(cfg *Config) error {
+			if !cfg.P2P.PexReactor {
+				return fmt.Errorf("p2p.pex must be enabled for sentries to find peers")
+			}
+			return nil
+		},
+	},
+	ProfileArchive: {
+		apply: func(cfg *Config) {
+			cfg.P2P.PexReactor = true
+			cfg.P2P.MaxNumInboundPeers = 40
+			cfg.P2P.MaxNumOutboundPeers = 10
+			cfg.Mempool.Broadcast = true
+			cfg.TxIndex.Indexer = "kv"
+			cfg.TxIndex.IndexAllTags = true
+		},
+		validate: func(cfg *Config) error {
+			if cfg.TxIndex.Indexer == "null" {
+				return fmt.Errorf("tx_index.indexer must not be null for an archive node")
+			}
+			return nil
+		},
+	},
+	ProfileSeed: {
+		apply: func(cfg *Config) {
+			cfg.P2P.PexReactor = true
+			cfg.P2P.SeedMode = true
+			cfg.P2P.MaxNumInboundPeers = 1000
+			cfg.P2P.MaxNumOutboundPeers = 50
+			cfg.P2P.MaxNumPeersPerIP = 4
+			cfg.Mempool.Broadcast = false
+			cfg.TxIndex.Indexer = "null"
+		},
+		validate: func(cfg *Config) error {
+			if !cfg.P2P.PexReactor {
+				return fmt.Errorf("p2p.pex must be enabled for a seed to exchange addresses")
+			}
+			return nil
+		},
+	},
+}
+
+// Profiles returns the names of all the profiles.
+func Profiles() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyProfile sets the defaults of the named profile and records it in the config.
+// An empty name leaves the config unchanged.
+func (cfg *Config) ApplyProfile(name string) error {
+	if name == "" {
+		return nil
+	}
+	p, ok := profiles[name]
+	if !ok {
+		return fmt.Errorf("Unknown profile %q, must be one of %s", name, strings.Join(Profiles(), ", "))
+	}
+	p.apply(cfg)
+	cfg.Profile = name
+	return nil
+}
+
+// ValidateProfile returns an error if the config contradicts the profile it records.
+// It is a no-op if no profile is recorded.
+func (cfg *Config) ValidateProfile() error {
+	if cfg.Profile == "" {
+		return nil
+	}
+	p, ok := profiles[cfg.Profile]
+	if !ok {
+		return fmt.Errorf("Unknown profile %q, must be one of %s", cfg.Profile, strings.Join(Profiles(), ", "))
+	}
+	if err := p.validate(cfg); err != nil {
+		return fmt.Errorf("Config doesn't match profile %q: %v", cfg.Profile, err)
+	}
+	return nil
+}
@@ -1,10 +1,14 @@
 package config
 
 import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
+	"text/template"
 
 	cmn "github.com/tendermint/tmlibs/common"
 )
@@ -27,14 +31,50 @@ func EnsureRoot(rootDir string) {
 	}
 }
 
-var defaultConfigTmpl = `# This is a TOML config file.
+// EnsureProfile writes the config file in rootDir for the given profile.
+// It returns an error if the config file was already changed from the default,
+// unless it was created for the same profile.
+func EnsureProfile(rootDir, profile string) error {
+	configFilePath := path.Join(rootDir, "config.toml")
+	if cmn.FileExists(configFilePath) {
+		existing, err := ioutil.ReadFile(configFilePath)
+		if err != nil {
+			return err
+		}
+		if string(existing) != defaultConfig(defaultMoniker) {
+			if strings.Contains(string(existing), fmt.Sprintf("profile = %q", profile)) {
+				return nil
+			}
+			return fmt.Errorf("%s was already changed, edit it to use the %q profile", configFilePath, profile)
+		}
+	}
+	return WriteConfigFile(configFilePath, defaultMoniker, profile)
+}
+
+// WriteConfigFile writes a config file for a node playing the role of the given profile.
+// The profile is recorded in the file, so it can be checked with ValidateProfile.
+func WriteConfigFile(configFilePath, moniker, profile string) error {
+	config := DefaultConfig()
+	if err := config.ApplyProfile(profile); err != nil {
+		return err
+	}
+	config.Moniker = moniker
+	return cmn.WriteFile(configFilePath, []byte(renderConfig(config)), 0644)
+}
+
+var configTmpl = template.Must(template.New("config").Parse(`# This is a TOML config file.
 # For more information, see https://github.com/toml-lang/toml
 
 proxy_app = "tcp://127.0.0.1:46658"
-moniker = "__MONIKER__"
+moniker = "{{ .Moniker }}"
 fast_sync = true
 db_backend = "leveldb"
 log_level = "state:info,*:error"
+{{- with .Profile }}
+
+# The role of this node, see "tendermint init --profile"
+profile = "{{ . }}"
+{{- end }}
 
 [rpc]
 laddr = "tcp://0.0.0.0:46657"
@@ -42,10 +82,38 @@ laddr = "tcp://0.0.0.0:46657"
 [p2p]
 laddr = "tcp://0.0.0.0:46656"
 seeds = ""
-`
+{{- if .Profile }}
+pex = {{ .P2P.PexReactor }}
+{{- if .P2P.SeedMode }}
+seed_mode = true
+{{- end }}
+max_num_inbound_peers = {{ .P2P.MaxNumInboundPeers }}
+max_num_outbound_peers = {{ .P2P.MaxNumOutboundPeers }}
+{{- if .P2P.MaxNumPeersPerIP }}
+max_num_peers_per_ip = {{ .P2P.MaxNumPeersPerIP }}
+{{- end }}
+
+[mempool]
+broadcast = {{ .Mempool.Broadcast }}
+
+[tx_index]
+indexer = "{{ .TxIndex.Indexer }}"
+index_all_tags = {{ .TxIndex.IndexAllTags }}
+{{- end }}
+`))
+
+func renderConfig(config *Config) string {
+	var buf bytes.Buffer
+	if err := configTmpl.Execute(&buf, config); err != nil {
+		cmn.PanicSanity(err.Error())
+	}
+	return buf.String()
+}
 
 func defaultConfig(moniker string) string {
-	return strings.Replace(defaultConfigTmpl, "__MONIKER__", moniker, -1)
+	config := DefaultConfig()
+	config.Moniker = moniker
+	return renderConfig(config)
 }
 
 /****** these are for test settings ***********/
@@ -55,3 +55,29 @@ func TestEnsureTestRoot(t *testing.T) {
 
 	ensureFiles(t, rootDir, "data", "genesis.json", "priv_validator.json")
 }
+
+func TestEnsureProfile(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	tmpDir, err := ioutil.TempDir("", "config-test")
+	require.Nil(err)
+	defer os.RemoveAll(tmpDir) // nolint: errcheck
+
+	// the default config can be replaced by a profile
+	EnsureRoot(tmpDir)
+	require.Nil(EnsureProfile(tmpDir, ProfileSentry))
+
+	data, err := ioutil.ReadFile(filepath.Join(tmpDir, "config.toml"))
+	require.Nil(err)
+	assert.Contains(string(data), `profile = "sentry"`)
+	assert.Contains(string(data), "pex = true")
+	assert.Contains(string(data), `indexer = "null"`)
+
+	// init'ing twice with the same profile is fine, but not with another one
+	assert.Nil(EnsureProfile(tmpDir, ProfileSentry))
+	assert.NotNil(EnsureProfile(tmpDir, ProfileValidator))
+
+	// unknown profiles are rejected
+	require.Nil(os.Remove(filepath.Join(tmpDir, "config.toml")))
+	assert.NotNil(EnsureProfile(tmpDir, "miner"))
+}
@@ -347,6 +347,12 @@ func consensusLogger() log.Logger {
 
 func randConsensusNet(nValidators int, testName string, tickerFunc func() TimeoutTicker, appFunc func() abci.Application, configOpts ...func(*cfg.Config)) []*ConsensusState {
 	genDoc, privVals := randGenesisDoc(nValidators, false, 10)
+	return consensusNet(genDoc, privVals, testName, tickerFunc, appFunc, configOpts...)
+}
+
+// consensusNet makes the consensus states of the validators of the genesis
+func consensusNet(genDoc *types.GenesisDoc, privVals []*types.PrivValidatorFS, testName string, tickerFunc func() TimeoutTicker, appFunc func() abci.Application, configOpts ...func(*cfg.Config)) []*ConsensusState {
+	nValidators := len(privVals)
 	css := make([]*ConsensusState, nValidators)
 	logger := consensusLogger()
 	for i := 0; i < nValidators; i++ {
@@ -433,6 +439,30 @@ func randGenesisDoc(numValidators int, randPower bool, minPower int64) (*types.G
 	}, privValidators
 }
 
+// randGenesisDocBLS returns a genesis of validators with BLS12-381 keys,
+// aggregating the signatures of the commits.
+func randGenesisDocBLS(numValidators int) (*types.GenesisDoc, []*types.PrivValidatorFS) {
+	validators := make([]types.GenesisValidator, numValidators)
+	privValidators := make([]*types.PrivValidatorFS, numValidators)
+	for i := 0; i < numValidators; i++ {
+		_, tempFilePath := cmn.Tempfile("priv_validator_")
+		privValidators[i] = types.NewPrivValidatorFS(types.GenPrivKeyBLS12381().Wrap(), tempFilePath)
+		validators[i] = types.GenesisValidator{
+			PubKey: privValidators[i].GetPubKey(),
+			Power:  testMinPower,
+		}
+	}
+	sort.Sort(types.PrivValidatorsByAddress(privValidators))
+	params := types.DefaultConsensusParams()
+	params.CommitParams.AggregateSignatures = true
+	return &types.GenesisDoc{
+		GenesisTime:     time.Now(),
+		ChainID:         config.ChainID,
+		Validators:      validators,
+		ConsensusParams: params,
+	}, privValidators
+}
+
 func randGenesisState(numValidators int, randPower bool, minPower int64) (*sm.State, []*types.PrivValidatorFS) {
 	genDoc, privValidators := randGenesisDoc(numValidators, randPower, minPower)
 	db := dbm.NewMemDB()
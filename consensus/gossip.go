@@ -0,0 +1,173 @@
+package consensus
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"sort"
+	"time"
+
+	cmn "github.com/tendermint/tmlibs/common"
+	"github.com/tendermint/tmlibs/log"
+
+	cstypes "github.com/tendermint/tendermint/consensus/types"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/types"
+)
+
+// With the push-pull vote gossip (see ConsensusConfig.PushPullVoteGossip),
+// the votes of a height are pushed to sqrt(N) of the N peers only, chosen anew
+// at each height. The other peers learn from the HasVoteMessages which votes
+// we have, and pull the ones they miss with a VoteSetPullMessage to a peer
+// that has them. This cuts the redundant vote traffic on networks with
+// hundreds of peers, at the cost of a round trip for the pulled votes.
+
+// pushFanout returns the number of peers to push the votes to, out of n.
+func pushFanout(n int) int {
+	return int(math.Ceil(math.Sqrt(float64(n))))
+}
+
+// pushPeers returns the keys of the peers to push the votes of the height to:
+// the pushFanout(len(keys)) ones with the lowest hash of the seed, the height
+// and their key. The seed is random to each node, so the peers pushed to don't
+// all pick the same ones.
+func pushPeers(seed []byte, height int64, keys []string) map[string]bool {
+	type rankedKey struct {
+		key  string
+		rank []byte
+	}
+	heightBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(heightBytes, uint64(height))
+
+	ranked := make([]rankedKey, len(keys))
+	for i, key := range keys {
+		hasher := sha256.New()
+		hasher.Write(seed)        // nolint: errcheck, gas
+		hasher.Write(heightBytes) // nolint: errcheck, gas
+		hasher.Write([]byte(key)) // nolint: errcheck, gas
+		ranked[i] = rankedKey{key, hasher.Sum(nil)}
+	}
+	sort.Slice(ranked, func(i, j int) bool { return bytes.Compare(ranked[i].rank, ranked[j].rank) < 0 })
+
+	fanout := pushFanout(len(keys))
+	push := make(map[string]bool, fanout)
+	for _, rk := range ranked[:fanout] {
+		push[rk.key] = true
+	}
+	return push
+}
+
+// pushVotesTo returns true if the votes of the height are to be pushed to the peer,
+// always unless the push-pull vote gossip is enabled.
+func (conR *ConsensusReactor) pushVotesTo(peer p2p.Peer, height int64) bool {
+	if !conR.conS.config.PushPullVoteGossip() {
+		return true
+	}
+
+	conR.pushMtx.Lock()
+	defer conR.pushMtx.Unlock()
+	if conR.pushHeight != height || conR.pushNumPeers != conR.Switch.Peers().Size() {
+		peers := conR.Switch.Peers().List()
+		keys := make([]string, len(peers))
+		for i, p := range peers {
+			keys[i] = p.Key()
+		}
+		conR.pushHeight = height
+		conR.pushNumPeers = len(peers)
+		conR.pushPeers = pushPeers(conR.pushSeed, height, keys)
+	}
+	return conR.pushPeers[peer.Key()]
+}
+
+// gossipPulledVotes sends the peer a vote it pulled, if any.
+// Returns true if a vote was sent.
+func (conR *ConsensusReactor) gossipPulledVotes(logger log.Logger, rs *cstypes.RoundState, ps *PeerState) bool {
+	for _, pull := range ps.GetPulls() {
+		if pull.Height == rs.Height {
+			var votes *types.VoteSet
+			switch pull.Type {
+			case types.VoteTypePrevote:
+				votes = rs.Votes.Prevotes(pull.Round)
+			case types.VoteTypePrecommit:
+				votes = rs.Votes.Precommits(pull.Round)
+			}
+			if votes != nil && ps.PickSendVote(votes) {
+				logger.Debug("Picked pulled vote to send", "round", pull.Round, "type", pull.Type)
+				return true
+			}
+		}
+		// nothing left to send for this pull
+		ps.RemovePull(pull)
+	}
+	return false
+}
+
+// pullVotesRoutine periodically pulls the votes of our round that we miss
+// from a peer that has some of them.
+func (conR *ConsensusReactor) pullVotesRoutine() {
+	for {
+		select {
+		case <-time.After(conR.conS.config.PeerGossipSleep()):
+		case <-conR.Quit:
+			return
+		}
+		if conR.FastSync() {
+			continue
+		}
+
+		rs := conR.conS.GetRoundState()
+		if rs.Votes == nil {
+			continue
+		}
+		conR.pullVotes(rs.Votes.Prevotes(rs.Round))
+		conR.pullVotes(rs.Votes.Precommits(rs.Round))
+	}
+}
+
+// pullVotes sends a VoteSetPullMessage for the votes to a random peer
+// that has some of the votes we miss.
+func (conR *ConsensusReactor) pullVotes(votes *types.VoteSet) {
+	if votes == nil {
+		return
+	}
+	ourVotes := votes.BitArray()
+	height, round, type_ := votes.Height(), votes.Round(), votes.Type()
+
+	var peers []p2p.Peer
+	for _, peer := range conR.Switch.Peers().List() {
+		ps, ok := peer.Get(types.PeerStateKey).(*PeerState)
+		if !ok {
+			continue
+		}
+		prs := ps.GetRoundState()
+		if prs.Height != height || prs.Round != round {
+			continue
+		}
+		var theirVotes *cmn.BitArray
+		switch type_ {
+		case types.VoteTypePrevote:
+			theirVotes = prs.Prevotes
+		case types.VoteTypePrecommit:
+			theirVotes = prs.Precommits
+		}
+		if theirVotes == nil {
+			continue
+		}
+		if _, ok := theirVotes.Sub(ourVotes).PickRandom(); ok {
+			peers = append(peers, peer)
+		}
+	}
+	if len(peers) == 0 {
+		return
+	}
+
+	peer := peers[cmn.RandInt()%len(peers)]
+	conR.Logger.Debug("Pulling votes", "peer", peer, "height", height, "round", round, "type", type_)
+	peer.TrySend(StateChannel, struct{ ConsensusMessage }{&VoteSetPullMessage{
+		Height: height,
+		Round:  round,
+		Type:   type_,
+		Votes:  ourVotes,
+	}})
+}
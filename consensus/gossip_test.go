@@ -0,0 +1,42 @@
+package consensus
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPushPeers(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(0, pushFanout(0))
+	assert.Equal(1, pushFanout(1))
+	assert.Equal(3, pushFanout(6))
+	assert.Equal(10, pushFanout(100))
+
+	keys := make([]string, 100)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("peer%d", i)
+	}
+	seed := []byte("seed")
+
+	push := pushPeers(seed, 1, keys)
+	assert.Len(push, 10)
+	for key := range push {
+		assert.Contains(keys, key)
+	}
+
+	// the same for the same seed and height, whatever the order of the peers
+	reversed := make([]string, len(keys))
+	for i, key := range keys {
+		reversed[len(keys)-1-i] = key
+	}
+	assert.Equal(push, pushPeers(seed, 1, reversed))
+
+	// other peers at other heights and for other seeds
+	assert.NotEqual(push, pushPeers(seed, 2, keys))
+	assert.NotEqual(push, pushPeers([]byte("other seed"), 1, keys))
+
+	assert.Empty(pushPeers(seed, 1, nil))
+}
@@ -0,0 +1,128 @@
+package consensus
+
+import (
+	metrics "github.com/rcrowley/go-metrics"
+
+	cstypes "github.com/tendermint/tendermint/consensus/types"
+)
+
+const metricsHistogramSampleSize = 1028
+
+// Metrics exports the progress of the consensus to a go-metrics registry:
+//
+//	consensus.height                 height of the last block committed (gauge)
+//	consensus.rounds                 rounds it took to commit a block (histogram)
+//	consensus.round_duration         how long the rounds lasted (timer)
+//	consensus.step_duration.<step>   how long the steps lasted, eg. step_duration.propose (timer)
+//	consensus.missed_proposals       rounds without a complete proposal before timeout_propose
+//	consensus.byzantine_votes        conflicting votes seen from other validators
+//	consensus.validators             size of the validator set (gauge)
+//	consensus.validators_power       total voting power of the validator set (gauge)
+//	consensus.validators_gini        Gini coefficient of the voting powers (gauge)
+//	consensus.validators_nakamoto    fewest validators with more than 1/3 of the power (gauge)
+//	consensus.validators_supermajority  fewest validators with more than 2/3 of the power (gauge)
+//	consensus.num_txs                txs in the last block committed (gauge)
+//	consensus.total_txs              txs in the blocks committed
+//	consensus.total_blocks           blocks committed
+//	consensus.dropped_stale_msgs.<kind>  stale votes, proposals and block_parts of the peers
+//	                                 dropped while the consensus was behind with them
+type Metrics struct {
+	Height          metrics.Gauge
+	Rounds          metrics.Histogram
+	RoundDuration   metrics.Timer
+	StepDurations   map[cstypes.RoundStepType]metrics.Timer
+	MissedProposals metrics.Counter
+	ByzantineVotes  metrics.Counter
+	Validators      metrics.Gauge
+	ValidatorsPower metrics.Gauge
+	NumTxs          metrics.Gauge
+	TotalTxs        metrics.Counter
+	TotalBlocks     metrics.Counter
+
+	ValidatorsGini          metrics.GaugeFloat64
+	ValidatorsNakamoto      metrics.Gauge
+	ValidatorsSupermajority metrics.Gauge
+
+	DroppedStaleVotes      metrics.Counter
+	DroppedStaleProposals  metrics.Counter
+	DroppedStaleBlockParts metrics.Counter
+}
+
+// names of the steps in the metrics
+var metricsSteps = map[cstypes.RoundStepType]string{
+	cstypes.RoundStepNewHeight:     "new_height",
+	cstypes.RoundStepNewRound:      "new_round",
+	cstypes.RoundStepPropose:       "propose",
+	cstypes.RoundStepPrevote:       "prevote",
+	cstypes.RoundStepPrevoteWait:   "prevote_wait",
+	cstypes.RoundStepPrecommit:     "precommit",
+	cstypes.RoundStepPrecommitWait: "precommit_wait",
+	cstypes.RoundStepCommit:        "commit",
+}
+
+// NewMetrics returns Metrics registered into the given registry.
+// If registry is nil, metrics.DefaultRegistry is used.
+func NewMetrics(registry metrics.Registry) *Metrics {
+	if registry == nil {
+		registry = metrics.DefaultRegistry
+	}
+	stepDurations := make(map[cstypes.RoundStepType]metrics.Timer, len(metricsSteps))
+	for step, name := range metricsSteps {
+		stepDurations[step] = metrics.GetOrRegisterTimer("consensus.step_duration."+name, registry)
+	}
+	return &Metrics{
+		Height: metrics.GetOrRegisterGauge("consensus.height", registry),
+		Rounds: metrics.GetOrRegisterHistogram("consensus.rounds", registry,
+			metrics.NewUniformSample(metricsHistogramSampleSize)),
+		RoundDuration:   metrics.GetOrRegisterTimer("consensus.round_duration", registry),
+		StepDurations:   stepDurations,
+		MissedProposals: metrics.GetOrRegisterCounter("consensus.missed_proposals", registry),
+		ByzantineVotes:  metrics.GetOrRegisterCounter("consensus.byzantine_votes", registry),
+		Validators:      metrics.GetOrRegisterGauge("consensus.validators", registry),
+		ValidatorsPower: metrics.GetOrRegisterGauge("consensus.validators_power", registry),
+		NumTxs:          metrics.GetOrRegisterGauge("consensus.num_txs", registry),
+		TotalTxs:        metrics.GetOrRegisterCounter("consensus.total_txs", registry),
+		TotalBlocks:     metrics.GetOrRegisterCounter("consensus.total_blocks", registry),
+
+		ValidatorsGini:          metrics.GetOrRegisterGaugeFloat64("consensus.validators_gini", registry),
+		ValidatorsNakamoto:      metrics.GetOrRegisterGauge("consensus.validators_nakamoto", registry),
+		ValidatorsSupermajority: metrics.GetOrRegisterGauge("consensus.validators_supermajority", registry),
+
+		DroppedStaleVotes:      metrics.GetOrRegisterCounter("consensus.dropped_stale_msgs.votes", registry),
+		DroppedStaleProposals:  metrics.GetOrRegisterCounter("consensus.dropped_stale_msgs.proposals", registry),
+		DroppedStaleBlockParts: metrics.GetOrRegisterCounter("consensus.dropped_stale_msgs.block_parts", registry),
+	}
+}
+
+// nopMetrics returns Metrics that are not registered anywhere.
+func nopMetrics() *Metrics {
+	return &Metrics{
+		Height:          metrics.NilGauge{},
+		Rounds:          metrics.NilHistogram{},
+		RoundDuration:   metrics.NilTimer{},
+		StepDurations:   map[cstypes.RoundStepType]metrics.Timer{},
+		MissedProposals: metrics.NilCounter{},
+		ByzantineVotes:  metrics.NilCounter{},
+		Validators:      metrics.NilGauge{},
+		ValidatorsPower: metrics.NilGauge{},
+		NumTxs:          metrics.NilGauge{},
+		TotalTxs:        metrics.NilCounter{},
+		TotalBlocks:     metrics.NilCounter{},
+
+		ValidatorsGini:          metrics.NilGaugeFloat64{},
+		ValidatorsNakamoto:      metrics.NilGauge{},
+		ValidatorsSupermajority: metrics.NilGauge{},
+
+		DroppedStaleVotes:      metrics.NilCounter{},
+		DroppedStaleProposals:  metrics.NilCounter{},
+		DroppedStaleBlockParts: metrics.NilCounter{},
+	}
+}
+
+// stepDuration returns the timer of the step.
+func (m *Metrics) stepDuration(step cstypes.RoundStepType) metrics.Timer {
+	if timer, ok := m.StepDurations[step]; ok {
+		return timer
+	}
+	return metrics.NilTimer{}
+}
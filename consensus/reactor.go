@@ -40,6 +40,13 @@ type ConsensusReactor struct {
 	mtx      sync.RWMutex
 	fastSync bool
 	eventBus *types.EventBus
+
+	// peers to push the votes to, with the push-pull vote gossip
+	pushMtx      sync.Mutex
+	pushSeed     []byte
+	pushHeight   int64
+	pushNumPeers int
+	pushPeers    map[string]bool
 }
 
 // NewConsensusReactor returns a new ConsensusReactor with the given consensusState.
@@ -47,11 +54,28 @@ func NewConsensusReactor(consensusState *ConsensusState, fastSync bool) *Consens
 	conR := &ConsensusReactor{
 		conS:     consensusState,
 		fastSync: fastSync,
+		pushSeed: cmn.RandBytes(32),
 	}
 	conR.BaseReactor = *p2p.NewBaseReactor("ConsensusReactor", conR)
+	consensusState.SetBadPeerMsgFunc(conR.reportBadPeerMsg)
 	return conR
 }
 
+// reportBadPeerMsg reports the peer which sent an invalid vote or proposal
+// to the switch, which bans it.
+func (conR *ConsensusReactor) reportBadPeerMsg(peerKey string, err error) {
+	if conR.Switch == nil {
+		return
+	}
+	peer := conR.Switch.Peers().Get(peerKey)
+	if peer == nil {
+		return
+	}
+	// the consensus state is locked, and stopping the peer removes it from
+	// the reactors
+	go conR.Switch.ReportMisbehavior(peer, p2p.SeverityHigh, err)
+}
+
 // OnStart implements BaseService.
 func (conR *ConsensusReactor) OnStart() error {
 	conR.Logger.Info("ConsensusReactor ", "fastSync", conR.FastSync())
@@ -64,6 +88,11 @@ func (conR *ConsensusReactor) OnStart() error {
 		return err
 	}
 
+	if conR.conS.config.PushPullVoteGossip() {
+		conR.Logger.Info("Using the experimental push-pull vote gossip")
+		go conR.pullVotesRoutine()
+	}
+
 	if !conR.FastSync() {
 		err := conR.conS.Start()
 		if err != nil {
@@ -179,7 +208,7 @@ func (conR *ConsensusReactor) Receive(chID byte, src p2p.Peer, msgBytes []byte)
 	_, msg, err := DecodeMessage(msgBytes)
 	if err != nil {
 		conR.Logger.Error("Error decoding message", "src", src, "chId", chID, "msg", msg, "err", err, "bytes", msgBytes)
-		// TODO punish peer?
+		conR.Switch.ReportMisbehavior(src, p2p.SeverityMedium, err)
 		return
 	}
 	conR.Logger.Debug("Receive", "src", src, "chId", chID, "msg", msg)
@@ -225,6 +254,8 @@ func (conR *ConsensusReactor) Receive(chID byte, src p2p.Peer, msgBytes []byte)
 				BlockID: msg.BlockID,
 				Votes:   ourVotes,
 			}})
+		case *VoteSetPullMessage:
+			ps.ApplyVoteSetPullMessage(msg)
 		case *ProposalHeartbeatMessage:
 			hb := msg.Heartbeat
 			conR.Logger.Debug("Received proposal heartbeat message",
@@ -242,11 +273,17 @@ func (conR *ConsensusReactor) Receive(chID byte, src p2p.Peer, msgBytes []byte)
 		switch msg := msg.(type) {
 		case *ProposalMessage:
 			ps.SetHasProposal(msg.Proposal)
+			if conR.dropIfStale(msg) {
+				return
+			}
 			conR.conS.peerMsgQueue <- msgInfo{msg, src.Key()}
 		case *ProposalPOLMessage:
 			ps.ApplyProposalPOLMessage(msg)
 		case *BlockPartMessage:
 			ps.SetHasProposalBlockPart(msg.Height, msg.Round, msg.Part.Index)
+			if conR.dropIfStale(msg) {
+				return
+			}
 			conR.conS.peerMsgQueue <- msgInfo{msg, src.Key()}
 		default:
 			conR.Logger.Error(cmn.Fmt("Unknown message type %v", reflect.TypeOf(msg)))
@@ -267,6 +304,31 @@ func (conR *ConsensusReactor) Receive(chID byte, src p2p.Peer, msgBytes []byte)
 			ps.EnsureVoteBitArrays(height-1, lastCommitSize)
 			ps.SetHasVote(msg.Vote)
 
+			if conR.dropIfStale(msg) {
+				return
+			}
+			cs.peerMsgQueue <- msgInfo{msg, src.Key()}
+
+		case *CommitMessage:
+			if msg.Commit == nil || !msg.Commit.IsAggregated() {
+				conR.Switch.ReportMisbehavior(src, p2p.SeverityMedium, errors.New("CommitMessage without an aggregated commit"))
+				return
+			}
+			if err := msg.Commit.ValidateBasic(); err != nil {
+				conR.Switch.ReportMisbehavior(src, p2p.SeverityMedium, err)
+				return
+			}
+			cs := conR.conS
+			cs.mtx.Lock()
+			height, valSize, lastCommitSize := cs.Height, cs.Validators.Size(), cs.LastCommit.Size()
+			cs.mtx.Unlock()
+			ps.EnsureVoteBitArrays(height, valSize)
+			ps.EnsureVoteBitArrays(height-1, lastCommitSize)
+			ps.SetHasCommit(msg.Commit)
+
+			if conR.dropIfStale(msg) {
+				return
+			}
 			cs.peerMsgQueue <- msgInfo{msg, src.Key()}
 
 		default:
@@ -315,6 +377,52 @@ func (conR *ConsensusReactor) Receive(chID byte, src p2p.Peer, msgBytes []byte)
 	}
 }
 
+// dropIfStale returns true if the msg of a peer is dropped, rather than queued
+// for the consensus: when it is behind with them, eg. CPU-bound, the votes,
+// proposals and block parts of the past heights and rounds, which it would
+// ignore anyway, are dropped so that those of the current round get to it
+// sooner. The precommits of the last height are kept, for the last commit.
+// Nothing is dropped until the queue of the msgs of the peers is half full.
+func (conR *ConsensusReactor) dropIfStale(msg ConsensusMessage) bool {
+	cs := conR.conS
+	if len(cs.peerMsgQueue) < cap(cs.peerMsgQueue)/2 {
+		return false
+	}
+	cs.mtx.Lock()
+	height, round := cs.Height, cs.Round
+	cs.mtx.Unlock()
+
+	switch msg := msg.(type) {
+	case *VoteMessage:
+		vote := msg.Vote
+		if vote.Height < height-1 || (vote.Height == height-1 && vote.Type != types.VoteTypePrecommit) {
+			cs.metrics.DroppedStaleVotes.Inc(1)
+			conR.Logger.Debug("Dropped stale vote", "vote", vote, "height", height)
+			return true
+		}
+	case *ProposalMessage:
+		proposal := msg.Proposal
+		if proposal.Height < height || (proposal.Height == height && proposal.Round < round) {
+			cs.metrics.DroppedStaleProposals.Inc(1)
+			conR.Logger.Debug("Dropped stale proposal", "proposal", proposal, "height", height, "round", round)
+			return true
+		}
+	case *CommitMessage:
+		if msg.Commit.Height() < height-1 {
+			cs.metrics.DroppedStaleVotes.Inc(1)
+			conR.Logger.Debug("Dropped stale commit", "height", msg.Commit.Height(), "csHeight", height)
+			return true
+		}
+	case *BlockPartMessage:
+		if msg.Height < height {
+			cs.metrics.DroppedStaleBlockParts.Inc(1)
+			conR.Logger.Debug("Dropped stale block part", "height", msg.Height, "round", msg.Round, "index", msg.Part.Index)
+			return true
+		}
+	}
+	return false
+}
+
 // SetEventBus sets event bus.
 func (conR *ConsensusReactor) SetEventBus(b *types.EventBus) {
 	conR.eventBus = b
@@ -626,7 +734,11 @@ OUTER_LOOP:
 		// If height matches, then send LastCommit, Prevotes, Precommits.
 		if rs.Height == prs.Height {
 			heightLogger := logger.With("height", prs.Height)
-			if conR.gossipVotesForHeight(heightLogger, rs, prs, ps) {
+			if conR.pushVotesTo(peer, rs.Height) {
+				if conR.gossipVotesForHeight(heightLogger, rs, prs, ps) {
+					continue OUTER_LOOP
+				}
+			} else if conR.gossipPulledVotes(heightLogger, rs, ps) {
 				continue OUTER_LOOP
 			}
 		}
@@ -828,6 +940,15 @@ type PeerState struct {
 
 	mtx sync.Mutex
 	cstypes.PeerRoundState
+
+	pulls []VotePull // votes the peer pulled from us
+}
+
+// VotePull identifies the votes a peer pulled with a VoteSetPullMessage.
+type VotePull struct {
+	Height int64
+	Round  int
+	Type   byte
 }
 
 // NewPeerState returns a new PeerState for the given Peer
@@ -913,7 +1034,16 @@ func (ps *PeerState) SetHasProposalBlockPart(height int64, round int, index int)
 
 // PickSendVote picks a vote and sends it to the peer.
 // Returns true if vote was sent.
+// The precommits of an aggregated commit have no signature of their own,
+// so the whole commit is sent instead, once.
 func (ps *PeerState) PickSendVote(votes types.VoteSetReader) bool {
+	if commit := aggregatedCommit(votes); commit != nil {
+		if ps.pickCommitToSend(commit) {
+			msg := &CommitMessage{commit}
+			return ps.Peer.Send(VoteChannel, struct{ ConsensusMessage }{msg})
+		}
+		return false
+	}
 	if vote, ok := ps.PickVoteToSend(votes); ok {
 		msg := &VoteMessage{vote}
 		return ps.Peer.Send(VoteChannel, struct{ ConsensusMessage }{msg})
@@ -951,6 +1081,40 @@ func (ps *PeerState) PickVoteToSend(votes types.VoteSetReader) (vote *types.Vote
 	return nil, false
 }
 
+// pickCommitToSend returns true if the peer is missing some precommits of the
+// aggregated commit, which it then has once sent.
+func (ps *PeerState) pickCommitToSend(commit *types.Commit) bool {
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
+
+	height, round, size := commit.Height(), commit.Round(), commit.Size()
+	ps.ensureCatchupCommitRound(height, round, size)
+	ps.ensureVoteBitArrays(height, size)
+
+	psVotes := ps.getVoteBitArray(height, round, types.VoteTypePrecommit)
+	if psVotes == nil {
+		return false // Not something worth sending
+	}
+	if _, ok := commit.BitArray().Sub(psVotes).PickRandom(); !ok {
+		return false
+	}
+	ps.setHasCommit(commit)
+	return true
+}
+
+// aggregatedCommit returns the aggregated commit of the votes, if any.
+func aggregatedCommit(votes types.VoteSetReader) *types.Commit {
+	switch votes := votes.(type) {
+	case *types.Commit:
+		if votes != nil && votes.IsAggregated() {
+			return votes
+		}
+	case *types.VoteSet:
+		return votes.AggregatedCommit()
+	}
+	return nil
+}
+
 func (ps *PeerState) getVoteBitArray(height int64, round int, type_ byte) *cmn.BitArray {
 	if !types.IsVoteTypeValid(type_) {
 		return nil
@@ -1059,6 +1223,22 @@ func (ps *PeerState) SetHasVote(vote *types.Vote) {
 	ps.setHasVote(vote.Height, vote.Round, vote.Type, vote.ValidatorIndex)
 }
 
+// SetHasCommit sets the precommits of the aggregated commit as known for the peer.
+func (ps *PeerState) SetHasCommit(commit *types.Commit) {
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
+
+	ps.setHasCommit(commit)
+}
+
+func (ps *PeerState) setHasCommit(commit *types.Commit) {
+	for _, precommit := range commit.Precommits {
+		if precommit != nil {
+			ps.setHasVote(precommit.Height, precommit.Round, precommit.Type, precommit.ValidatorIndex)
+		}
+	}
+}
+
 func (ps *PeerState) setHasVote(height int64, round int, type_ byte, index int) {
 	logger := ps.logger.With("peerH/R", cmn.Fmt("%d/%d", ps.Height, ps.Round), "H/R", cmn.Fmt("%d/%d", height, round))
 	logger.Debug("setHasVote", "type", type_, "index", index)
@@ -1187,6 +1367,50 @@ func (ps *PeerState) ApplyVoteSetBitsMessage(msg *VoteSetBitsMessage, ourVotes *
 	}
 }
 
+// ApplyVoteSetPullMessage updates the peer state for the bit-array of votes
+// it claims to have, and records that it pulls the others.
+func (ps *PeerState) ApplyVoteSetPullMessage(msg *VoteSetPullMessage) {
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
+
+	votes := ps.getVoteBitArray(msg.Height, msg.Round, msg.Type)
+	if votes == nil || msg.Votes == nil || votes.Size() != msg.Votes.Size() {
+		return
+	}
+	votes.Update(msg.Votes)
+
+	pull := VotePull{msg.Height, msg.Round, msg.Type}
+	pulls := ps.pulls[:0]
+	for _, p := range ps.pulls {
+		// drop the pulls of the previous heights, and the duplicate
+		if p.Height == msg.Height && p != pull {
+			pulls = append(pulls, p)
+		}
+	}
+	ps.pulls = append(pulls, pull)
+}
+
+// GetPulls returns a copy of the votes the peer pulled and were not all sent yet.
+func (ps *PeerState) GetPulls() []VotePull {
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
+
+	return append([]VotePull(nil), ps.pulls...)
+}
+
+// RemovePull forgets the pull, once all its votes were sent.
+func (ps *PeerState) RemovePull(pull VotePull) {
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
+
+	for i, p := range ps.pulls {
+		if p == pull {
+			ps.pulls = append(ps.pulls[:i], ps.pulls[i+1:]...)
+			return
+		}
+	}
+}
+
 // String returns a string representation of the PeerState
 func (ps *PeerState) String() string {
 	return ps.StringIndented("")
@@ -1216,6 +1440,8 @@ const (
 	msgTypeHasVote      = byte(0x15)
 	msgTypeVoteSetMaj23 = byte(0x16)
 	msgTypeVoteSetBits  = byte(0x17)
+	msgTypeVoteSetPull  = byte(0x18)
+	msgTypeCommit       = byte(0x19)
 
 	msgTypeProposalHeartbeat = byte(0x20)
 )
@@ -1234,6 +1460,8 @@ var _ = wire.RegisterInterface(
 	wire.ConcreteType{&HasVoteMessage{}, msgTypeHasVote},
 	wire.ConcreteType{&VoteSetMaj23Message{}, msgTypeVoteSetMaj23},
 	wire.ConcreteType{&VoteSetBitsMessage{}, msgTypeVoteSetBits},
+	wire.ConcreteType{&VoteSetPullMessage{}, msgTypeVoteSetPull},
+	wire.ConcreteType{&CommitMessage{}, msgTypeCommit},
 	wire.ConcreteType{&ProposalHeartbeatMessage{}, msgTypeProposalHeartbeat},
 )
 
@@ -1334,6 +1562,19 @@ func (m *VoteMessage) String() string {
 
 //-------------------------------------
 
+// CommitMessage is sent instead of the precommits of an aggregated commit,
+// which have no signature of their own, eg. to catch a peer up.
+type CommitMessage struct {
+	Commit *types.Commit
+}
+
+// String returns a string representation.
+func (m *CommitMessage) String() string {
+	return fmt.Sprintf("[Commit H:%v R:%v %v]", m.Commit.Height(), m.Commit.Round(), m.Commit.BlockID)
+}
+
+//-------------------------------------
+
 // HasVoteMessage is sent to indicate that a particular vote has been received.
 type HasVoteMessage struct {
 	Height int64
@@ -1380,6 +1621,22 @@ func (m *VoteSetBitsMessage) String() string {
 
 //-------------------------------------
 
+// VoteSetPullMessage is sent to request the votes missing from the bit-array,
+// with the push-pull vote gossip.
+type VoteSetPullMessage struct {
+	Height int64
+	Round  int
+	Type   byte
+	Votes  *cmn.BitArray
+}
+
+// String returns a string representation.
+func (m *VoteSetPullMessage) String() string {
+	return fmt.Sprintf("[VSP %v/%02d/%v %v]", m.Height, m.Round, m.Type, m.Votes)
+}
+
+//-------------------------------------
+
 // ProposalHeartbeatMessage is sent to signal that a node is alive and waiting for transactions for a proposal.
 type ProposalHeartbeatMessage struct {
 	Heartbeat *types.Heartbeat
@@ -4,15 +4,24 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path"
 	"runtime/pprof"
 	"sync"
 	"testing"
 	"time"
 
+	metrics "github.com/rcrowley/go-metrics"
 	"github.com/tendermint/abci/example/dummy"
+	abci "github.com/tendermint/abci/types"
+	cmn "github.com/tendermint/tmlibs/common"
+	dbm "github.com/tendermint/tmlibs/db"
+	"github.com/tendermint/tmlibs/log"
 
+	bc "github.com/tendermint/tendermint/blockchain"
 	cfg "github.com/tendermint/tendermint/config"
 	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/proxy"
+	sm "github.com/tendermint/tendermint/state"
 	"github.com/tendermint/tendermint/types"
 
 	"github.com/stretchr/testify/require"
@@ -89,6 +98,105 @@ func TestReactor(t *testing.T) {
 	}, css)
 }
 
+// Ensure a testnet makes blocks with the push-pull vote gossip
+func TestReactorPushPullVoteGossip(t *testing.T) {
+	N := 7 // so the votes are pushed to 3 of the 6 peers
+	css := randConsensusNet(N, "consensus_reactor_test", newMockTickerFunc(true), newCounter,
+		func(c *cfg.Config) {
+			c.Consensus.VoteGossipFanout = "sqrt"
+			c.Consensus.ExperimentalGossip = true
+		})
+	reactors, eventChans, eventBuses := startConsensusNet(t, css, N)
+	defer stopConsensusNet(reactors, eventBuses)
+	// wait till everyone makes the first two blocks
+	timeoutWaitGroup(t, N, func(wg *sync.WaitGroup, j int) {
+		<-eventChans[j]
+		<-eventChans[j]
+		wg.Done()
+	}, css)
+}
+
+// Ensure a node fast synced to blocks with aggregated commits switches to
+// consensus, and catches up with the aggregated commits of its peers
+func TestReactorFastSyncAggregatedCommit(t *testing.T) {
+	N := 4
+	genDoc, privVals := randGenesisDocBLS(N)
+	css := consensusNet(genDoc, privVals, "consensus_reactor_test", newMockTickerFunc(true), newCounter)
+	reactors, eventChans, eventBuses := startConsensusNet(t, css, N)
+	defer stopConsensusNet(reactors, eventBuses)
+	// wait till everyone makes the first four blocks
+	timeoutWaitGroup(t, N, func(wg *sync.WaitGroup, j int) {
+		for i := 0; i < 4; i++ {
+			<-eventChans[j]
+		}
+		wg.Done()
+	}, css)
+	store := css[0].blockStore
+
+	// fast sync the first two blocks, saving the LastCommit of the next
+	// block as the seen commit, like the blockchain reactor
+	stateDB, blockDB := dbm.NewMemDB(), dbm.NewMemDB()
+	state, _ := sm.MakeGenesisState(stateDB, genDoc)
+	state.SetLogger(log.TestingLogger().With("module", "state"))
+	state.Save()
+	app := newCounter()
+	app.InitChain(abci.RequestInitChain{Validators: types.TM2PB.Validators(state.Validators)})
+	proxyApp := proxy.NewAppConns(proxy.NewLocalClientCreator(app), nil)
+	if err := proxyApp.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer proxyApp.Stop()
+	blockStore := bc.NewBlockStore(blockDB)
+	for height := int64(1); height <= 2; height++ {
+		block := store.LoadBlock(height)
+		blockParts := block.MakePartSet(state.Params.BlockPartSizeBytes)
+		seenCommit := store.LoadBlockCommit(height)
+		if !seenCommit.IsAggregated() {
+			t.Fatalf("Expected the commit of the block %d to be aggregated", height)
+		}
+		blockStore.SaveBlock(block, blockParts, seenCommit)
+		err := state.ApplyBlock(types.NopEventBus{}, proxyApp.Consensus(), block, blockParts.Header(), types.MockMempool{}, types.MockEvidencePool{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the LastCommit is made of the aggregated seen commit
+	thisConfig := ResetConfig("consensus_reactor_test_fast_sync")
+	ensureDir(path.Dir(thisConfig.Consensus.WalFile()), 0700) // dir for wal
+	_, tempFilePath := cmn.Tempfile("priv_validator_")
+	cs := newConsensusStateWithConfigAndBlockStore(thisConfig, state, types.GenPrivValidatorFS(tempFilePath), app, blockDB)
+	cs.SetTimeoutTicker(newMockTickerFunc(true)())
+	newBlockCh := make(chan interface{}, 1)
+	err := cs.eventBus.Subscribe(context.Background(), testSubscriber, types.EventQueryNewBlock, newBlockCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reactor := NewConsensusReactor(cs, true)
+	reactor.SetLogger(log.TestingLogger())
+	reactor.SwitchToConsensus(state, 2)
+	defer cs.Stop()
+	if commit := cs.LastCommit.MakeCommit(); !commit.IsAggregated() || commit.Height() != 2 {
+		t.Fatalf("Expected the LastCommit to be the aggregated commit of the block 2, got %v", commit)
+	}
+
+	// the peers send the aggregated commit of the block 3 instead of its precommits
+	commit := store.LoadBlockCommit(3)
+	cs.peerMsgQueue <- msgInfo{&CommitMessage{commit}, "peer"}
+	blockMeta := store.LoadBlockMeta(3)
+	for i := 0; i < blockMeta.BlockID.PartsHeader.Total; i++ {
+		cs.peerMsgQueue <- msgInfo{&BlockPartMessage{3, commit.Round(), store.LoadBlockPart(3, i)}, "peer"}
+	}
+	select {
+	case <-newBlockCh:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for the block 3")
+	}
+	if !cs.blockStore.LoadSeenCommit(3).IsAggregated() {
+		t.Fatal("Expected the seen commit of the block 3 to be aggregated")
+	}
+}
+
 // Ensure a testnet sends proposal heartbeats and makes blocks when there are txs
 func TestReactorProposalHeartbeats(t *testing.T) {
 	N := 4
@@ -389,3 +497,54 @@ func timeoutWaitGroup(t *testing.T, n int, f func(*sync.WaitGroup, int), css []*
 		panic("Timed out waiting for all validators to commit a block")
 	}
 }
+
+func TestReactorDropStaleMsgs(t *testing.T) {
+	cs, _ := randConsensusState(1)
+	cs.Height, cs.Round = 5, 2
+	registry := metrics.NewRegistry()
+	cs.SetMetrics(NewMetrics(registry))
+	conR := NewConsensusReactor(cs, false)
+	conR.SetLogger(log.TestingLogger())
+
+	vote := func(height int64, voteType byte) *VoteMessage {
+		return &VoteMessage{&types.Vote{Height: height, Type: voteType}}
+	}
+	proposal := func(height int64, round int) *ProposalMessage {
+		return &ProposalMessage{&types.Proposal{Height: height, Round: round}}
+	}
+	blockPart := func(height int64) *BlockPartMessage {
+		return &BlockPartMessage{height, 0, &types.Part{}}
+	}
+	msgs := []struct {
+		msg   ConsensusMessage
+		stale bool
+	}{
+		{vote(3, types.VoteTypePrecommit), true},
+		{vote(4, types.VoteTypePrevote), true},
+		{vote(4, types.VoteTypePrecommit), false},
+		{vote(5, types.VoteTypePrevote), false},
+		{vote(6, types.VoteTypePrevote), false},
+		{proposal(4, 3), true},
+		{proposal(5, 1), true},
+		{proposal(5, 2), false},
+		{proposal(5, 3), false},
+		{blockPart(4), true},
+		{blockPart(5), false},
+	}
+
+	// nothing is dropped while the consensus keeps up
+	for _, m := range msgs {
+		require.False(t, conR.dropIfStale(m.msg), "%v", m.msg)
+	}
+
+	for i := 0; i < cap(cs.peerMsgQueue)/2; i++ {
+		cs.peerMsgQueue <- msgInfo{}
+	}
+	for _, m := range msgs {
+		require.Equal(t, m.stale, conR.dropIfStale(m.msg), "%v", m.msg)
+	}
+	counter := func(name string) int64 { return registry.Get(name).(metrics.Counter).Count() }
+	require.EqualValues(t, 2, counter("consensus.dropped_stale_msgs.votes"))
+	require.EqualValues(t, 2, counter("consensus.dropped_stale_msgs.proposals"))
+	require.EqualValues(t, 1, counter("consensus.dropped_stale_msgs.block_parts"))
+}
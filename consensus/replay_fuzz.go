@@ -0,0 +1,281 @@
+package consensus
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/tendermint/abci/example/dummy"
+	auto "github.com/tendermint/tmlibs/autofile"
+	dbm "github.com/tendermint/tmlibs/db"
+	"github.com/tendermint/tmlibs/log"
+
+	bc "github.com/tendermint/tendermint/blockchain"
+	cfg "github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/proxy"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+)
+
+const replayFuzzSubscriber = "replay-fuzz"
+
+// ReplayFuzz checks that a node recovers from a crash at any message boundary
+// of the consensus. It runs a single validator chain up to the given height
+// again and again, crashing it each time before it saves the next message to
+// its WAL, and restarts it from its stores and WAL after each crash: the node
+// must recover to a consistent height, ie. with its state and block store
+// at the same height, and commit a new block.
+// The chain lives in dir, and the app is a dummy app, lost in each crash.
+// It returns the number of crashes the node recovered from.
+func ReplayFuzz(dir string, numBlocks int64, logger log.Logger) (int, error) {
+	if numBlocks < 1 {
+		return 0, errors.New("the number of blocks must be positive")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return 0, err
+	}
+
+	privVal := types.GenPrivValidatorFS(filepath.Join(dir, "priv_validator.json"))
+	genDoc := &types.GenesisDoc{
+		ChainID: "replay-fuzz",
+		Validators: []types.GenesisValidator{{
+			PubKey: privVal.GetPubKey(),
+			Power:  10,
+		}},
+	}
+	csConfig := cfg.TestConsensusConfig()
+	csConfig.SetWalFile(filepath.Join(dir, "cs.wal", "wal"))
+
+	f := &replayFuzzer{
+		genDoc:   genDoc,
+		privVal:  privVal,
+		csConfig: csConfig,
+		logger:   logger,
+	}
+	for crashes := 0; ; crashes++ {
+		reached, err := f.crashAndRecover(crashes+1, numBlocks)
+		if err != nil {
+			return crashes, err
+		}
+		if reached {
+			return crashes, nil
+		}
+	}
+}
+
+type replayFuzzer struct {
+	genDoc   *types.GenesisDoc
+	privVal  *types.PrivValidatorFS
+	csConfig *cfg.ConsensusConfig
+	logger   log.Logger
+}
+
+// crashAndRecover runs the chain from genesis, crashes it before it saves the
+// msgIndex-th message to its WAL, and checks it recovers. It returns true if
+// the chain reached numBlocks before that message.
+func (f *replayFuzzer) crashAndRecover(msgIndex int, numBlocks int64) (bool, error) {
+	logger := f.logger.With("crash", msgIndex)
+
+	// start from a clean slate
+	if err := os.RemoveAll(filepath.Dir(f.csConfig.WalFile())); err != nil {
+		return false, err
+	}
+	f.privVal.Reset()
+	stateDB, blockDB := dbm.NewMemDB(), dbm.NewMemDB()
+
+	cs, stop, err := f.newConsensusState(stateDB, blockDB, logger)
+	if err != nil {
+		return false, err
+	}
+	wal, err := cs.OpenWAL(f.csConfig.WalFile())
+	if err != nil {
+		stop()
+		return false, err
+	}
+	crashCh := make(chan error, 1)
+	cs.wal = &crashingWAL{
+		next:                   wal,
+		panicCh:                crashCh,
+		heightToStop:           numBlocks,
+		msgIndex:               1,
+		lastPanicedForMsgIndex: msgIndex - 1,
+	}
+	if err := cs.Start(); err != nil {
+		stop()
+		return false, err
+	}
+
+	var crashErr error
+	select {
+	case crashErr = <-crashCh:
+	case <-time.After(f.waitTimeout()):
+		crashErr = fmt.Errorf("timed out waiting for message %d", msgIndex)
+	}
+	// the receive routine exited with the crash, so close the WAL for it
+	stop()
+	wal.Stop() // nolint: errcheck
+	if _, ok := crashErr.(ReachedHeightToStopError); ok {
+		return true, nil
+	} else if _, ok := crashErr.(WALWriteError); !ok {
+		return false, crashErr
+	}
+	logger.Info("Crashed", "err", crashErr)
+
+	if err := f.recover(stateDB, blockDB, logger); err != nil {
+		return false, errors.Wrapf(err, "failed to recover from crash %d (%v)", msgIndex, crashErr)
+	}
+	return false, nil
+}
+
+// recover restarts the node from its stores and WAL, and checks it has a
+// consistent height and commits a new block.
+func (f *replayFuzzer) recover(stateDB, blockDB dbm.DB, logger log.Logger) error {
+	cs, stop, err := f.newConsensusState(stateDB, blockDB, logger)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	stateHeight, storeHeight := cs.GetState().LastBlockHeight, cs.blockStore.Height()
+	if stateHeight != storeHeight {
+		return fmt.Errorf("inconsistent height: the state is at %d and the block store at %d", stateHeight, storeHeight)
+	}
+
+	newBlockCh := make(chan interface{}, 1)
+	err = cs.eventBus.Subscribe(context.Background(), replayFuzzSubscriber, types.EventQueryNewBlock, newBlockCh)
+	if err != nil {
+		return err
+	}
+	if err := cs.Start(); err != nil {
+		return err
+	}
+	select {
+	case data := <-newBlockCh:
+		block := data.(types.TMEventData).Unwrap().(types.EventDataNewBlock).Block
+		if block.Height != stateHeight+1 {
+			return fmt.Errorf("committed block %d after height %d", block.Height, stateHeight)
+		}
+		return nil
+	case <-time.After(f.waitTimeout()):
+		return fmt.Errorf("timed out waiting for block %d", stateHeight+1)
+	}
+}
+
+// newConsensusState returns a consensus state for the node with the given
+// stores, handshaked with a new dummy app, and a function to stop it.
+func (f *replayFuzzer) newConsensusState(stateDB, blockDB dbm.DB, logger log.Logger) (*ConsensusState, func(), error) {
+	state := sm.LoadState(stateDB)
+	if state == nil {
+		var err error
+		state, err = sm.MakeGenesisState(stateDB, f.genDoc)
+		if err != nil {
+			return nil, nil, err
+		}
+		state.Save()
+	}
+	state.SetLogger(logger.With("module", "state"))
+	blockStore := bc.NewBlockStore(blockDB)
+
+	handshaker := NewHandshaker(state, blockStore)
+	handshaker.SetLogger(logger.With("module", "consensus"))
+	proxyApp := proxy.NewAppConns(proxy.NewLocalClientCreator(dummy.NewDummyApplication()), handshaker)
+	proxyApp.SetLogger(logger.With("module", "proxy"))
+	if err := proxyApp.Start(); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to start proxy app connections")
+	}
+	eventBus := types.NewEventBus()
+	eventBus.SetLogger(logger.With("module", "events"))
+	if err := eventBus.Start(); err != nil {
+		proxyApp.Stop() // nolint: errcheck
+		return nil, nil, errors.Wrap(err, "failed to start event bus")
+	}
+
+	cs := NewConsensusState(f.csConfig, state.Copy(), proxyApp.Consensus(), blockStore, types.MockMempool{})
+	cs.SetLogger(logger.With("module", "consensus"))
+	cs.SetEventBus(eventBus)
+	cs.SetPrivValidator(f.privVal)
+
+	stop := func() {
+		cs.Stop()                                                           // nolint: errcheck
+		eventBus.UnsubscribeAll(context.Background(), replayFuzzSubscriber) // nolint: errcheck
+		eventBus.Stop()                                                     // nolint: errcheck
+		proxyApp.Stop()                                                     // nolint: errcheck
+	}
+	return cs, stop, nil
+}
+
+// waitTimeout is how long to wait for the next message or block, a few rounds.
+func (f *replayFuzzer) waitTimeout() time.Duration {
+	return 10*time.Second + 3*f.csConfig.Propose(0)
+}
+
+//------------------------------------------------------------------------------------------
+
+// crashingWAL is a WAL which crashes or rather simulates a crash during Save
+// (before and after). It remembers a message for which we last panicked
+// (lastPanicedForMsgIndex), so we don't panic for it in subsequent iterations.
+type crashingWAL struct {
+	next         WAL
+	panicCh      chan error
+	heightToStop int64
+
+	msgIndex               int // current message index
+	lastPanicedForMsgIndex int // last message for which we panicked
+}
+
+// WALWriteError indicates a WAL crash.
+type WALWriteError struct {
+	msg string
+}
+
+func (e WALWriteError) Error() string {
+	return e.msg
+}
+
+// ReachedHeightToStopError indicates we've reached the required consensus
+// height and may exit.
+type ReachedHeightToStopError struct {
+	height int64
+}
+
+func (e ReachedHeightToStopError) Error() string {
+	return fmt.Sprintf("reached height to stop %d", e.height)
+}
+
+// Save simulate WAL's crashing by sending an error to the panicCh and then
+// exiting the cs.receiveRoutine.
+func (w *crashingWAL) Save(m WALMessage) {
+	if endMsg, ok := m.(EndHeightMessage); ok {
+		if endMsg.Height == w.heightToStop {
+			w.panicCh <- ReachedHeightToStopError{endMsg.Height}
+			runtime.Goexit()
+		} else {
+			w.next.Save(m)
+		}
+		return
+	}
+
+	if w.msgIndex > w.lastPanicedForMsgIndex {
+		w.lastPanicedForMsgIndex = w.msgIndex
+		_, file, line, _ := runtime.Caller(1)
+		w.panicCh <- WALWriteError{fmt.Sprintf("failed to write %T to WAL (fileline: %s:%d)", m, file, line)}
+		runtime.Goexit()
+	} else {
+		w.msgIndex++
+		w.next.Save(m)
+	}
+}
+
+func (w *crashingWAL) Group() *auto.Group { return w.next.Group() }
+func (w *crashingWAL) SearchForEndHeight(height int64) (gr *auto.GroupReader, found bool, err error) {
+	return w.next.SearchForEndHeight(height)
+}
+
+func (w *crashingWAL) Start() error { return w.next.Start() }
+func (w *crashingWAL) Stop() error  { return w.next.Stop() }
+func (w *crashingWAL) Wait()        { w.next.Wait() }
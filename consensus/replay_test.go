@@ -9,7 +9,6 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
-	"runtime"
 	"testing"
 	"time"
 
@@ -19,7 +18,6 @@ import (
 	abci "github.com/tendermint/abci/types"
 	crypto "github.com/tendermint/go-crypto"
 	wire "github.com/tendermint/go-wire"
-	auto "github.com/tendermint/tmlibs/autofile"
 	cmn "github.com/tendermint/tmlibs/common"
 	dbm "github.com/tendermint/tmlibs/db"
 
@@ -197,70 +195,17 @@ LOOP:
 	}
 }
 
-// crashingWAL is a WAL which crashes or rather simulates a crash during Save
-// (before and after). It remembers a message for which we last panicked
-// (lastPanicedForMsgIndex), so we don't panic for it in subsequent iterations.
-type crashingWAL struct {
-	next         WAL
-	panicCh      chan error
-	heightToStop int64
-
-	msgIndex               int // current message index
-	lastPanicedForMsgIndex int // last message for which we panicked
-}
-
-// WALWriteError indicates a WAL crash.
-type WALWriteError struct {
-	msg string
-}
-
-func (e WALWriteError) Error() string {
-	return e.msg
-}
-
-// ReachedHeightToStopError indicates we've reached the required consensus
-// height and may exit.
-type ReachedHeightToStopError struct {
-	height int64
-}
-
-func (e ReachedHeightToStopError) Error() string {
-	return fmt.Sprintf("reached height to stop %d", e.height)
-}
-
-// Save simulate WAL's crashing by sending an error to the panicCh and then
-// exiting the cs.receiveRoutine.
-func (w *crashingWAL) Save(m WALMessage) {
-	if endMsg, ok := m.(EndHeightMessage); ok {
-		if endMsg.Height == w.heightToStop {
-			w.panicCh <- ReachedHeightToStopError{endMsg.Height}
-			runtime.Goexit()
-		} else {
-			w.next.Save(m)
-		}
-		return
-	}
-
-	if w.msgIndex > w.lastPanicedForMsgIndex {
-		w.lastPanicedForMsgIndex = w.msgIndex
-		_, file, line, _ := runtime.Caller(1)
-		w.panicCh <- WALWriteError{fmt.Sprintf("failed to write %T to WAL (fileline: %s:%d)", m, file, line)}
-		runtime.Goexit()
-	} else {
-		w.msgIndex++
-		w.next.Save(m)
-	}
-}
+// TestReplayFuzz checks we recover from a crash at every message of a block.
+func TestReplayFuzz(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay_fuzz")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
 
-func (w *crashingWAL) Group() *auto.Group { return w.next.Group() }
-func (w *crashingWAL) SearchForEndHeight(height int64) (gr *auto.GroupReader, found bool, err error) {
-	return w.next.SearchForEndHeight(height)
+	crashes, err := ReplayFuzz(dir, 1, log.TestingLogger())
+	require.NoError(t, err)
+	require.True(t, crashes > 0, "no crash injected")
 }
 
-func (w *crashingWAL) Start() error { return w.next.Start() }
-func (w *crashingWAL) Stop() error  { return w.next.Stop() }
-func (w *crashingWAL) Wait()        { w.next.Wait() }
-
 //------------------------------------------------------------------------------------------
 // Handshake Tests
 
@@ -399,7 +344,7 @@ func testHandshakeReplay(t *testing.T, nBlocks int, mode uint) {
 
 func applyBlock(st *sm.State, blk *types.Block, proxyApp proxy.AppConns) {
 	testPartSize := st.Params.BlockPartSizeBytes
-	err := st.ApplyBlock(types.NopEventBus{}, proxyApp.Consensus(), blk, blk.MakePartSet(testPartSize).Header(), mempool)
+	err := st.ApplyBlock(types.NopEventBus{}, proxyApp.Consensus(), blk, blk.MakePartSet(testPartSize).Header(), mempool, types.MockEvidencePool{})
 	if err != nil {
 		panic(err)
 	}
@@ -593,13 +538,11 @@ func NewMockBlockStore(config *cfg.Config, params types.ConsensusParams) *mockBl
 }
 
 func (bs *mockBlockStore) Height() int64                       { return int64(len(bs.chain)) }
+func (bs *mockBlockStore) Base() int64                         { return cmn.MinInt64(1, bs.Height()) }
 func (bs *mockBlockStore) LoadBlock(height int64) *types.Block { return bs.chain[height-1] }
 func (bs *mockBlockStore) LoadBlockMeta(height int64) *types.BlockMeta {
 	block := bs.chain[height-1]
-	return &types.BlockMeta{
-		BlockID: types.BlockID{block.Hash(), block.MakePartSet(bs.params.BlockPartSizeBytes).Header()},
-		Header:  block.Header,
-	}
+	return types.NewBlockMeta(block, block.MakePartSet(bs.params.BlockPartSizeBytes))
 }
 func (bs *mockBlockStore) LoadBlockPart(height int64, index int) *types.Part { return nil }
 func (bs *mockBlockStore) SaveBlock(block *types.Block, blockParts *types.PartSet, seenCommit *types.Commit) {
@@ -33,10 +33,12 @@ const (
 // Errors
 
 var (
-	ErrInvalidProposalSignature = errors.New("Error invalid proposal signature")
-	ErrInvalidProposalPOLRound  = errors.New("Error invalid proposal POL round")
-	ErrAddingVote               = errors.New("Error adding vote")
-	ErrVoteHeightMismatch       = errors.New("Error vote height mismatch")
+	ErrInvalidProposalSignature   = errors.New("Error invalid proposal signature")
+	ErrInvalidProposalPOLRound    = errors.New("Error invalid proposal POL round")
+	ErrInvalidProposalParts       = errors.New("Error invalid proposal block parts")
+	ErrAddingVote                 = errors.New("Error adding vote")
+	ErrVoteHeightMismatch         = errors.New("Error vote height mismatch")
+	ErrSignatureFoundInPastBlocks = errors.New("Error validator signature found in past blocks, it may be running in another node")
 )
 
 //-----------------------------------------------------------------------------
@@ -78,6 +80,7 @@ type ConsensusState struct {
 	proxyAppConn proxy.AppConnConsensus
 	blockStore   types.BlockStore
 	mempool      types.Mempool
+	evpool       types.EvidencePool
 
 	// internal state
 	mtx sync.Mutex
@@ -100,9 +103,28 @@ type ConsensusState struct {
 	replayMode   bool // so we don't log signing errors during replay
 	doWALCatchup bool // determines if we even try to do the catchup
 
+	// sync every WAL msg to disk, rather than every height
+	strictWALSync bool
+
+	// called synchronously once a block is committed, if set
+	onBlockCommitted sm.BlockCommittedFunc
+
+	// called with the invalid votes and proposals of the peers, if set
+	onBadPeerMsg func(peerKey string, err error)
+
+	// the validator signs nothing of the heights after exitHeight, 0 for never,
+	// and announced it stopped once exitStopped
+	exitHeight  int64
+	exitStopped bool
+
 	// for tests where we want to limit the number of transitions the state makes
 	nSteps int
 
+	// the progress of the consensus, and when the current step and round started
+	metrics    *Metrics
+	stepStart  time.Time
+	roundStart time.Time
+
 	// some functions can be overwritten for testing
 	decideProposal func(height int64, round int)
 	doPrevote      func(height int64, round int)
@@ -119,12 +141,15 @@ func NewConsensusState(config *cfg.ConsensusConfig, state *sm.State, proxyAppCon
 		proxyAppConn:     proxyAppConn,
 		blockStore:       blockStore,
 		mempool:          mempool,
+		evpool:           types.MockEvidencePool{},
 		peerMsgQueue:     make(chan msgInfo, msgQueueSize),
 		internalMsgQueue: make(chan msgInfo, msgQueueSize),
 		timeoutTicker:    NewTimeoutTicker(),
 		done:             make(chan struct{}),
 		doWALCatchup:     true,
 		wal:              nilWAL{},
+		metrics:          nopMetrics(),
+		exitHeight:       config.StopSigningHeight,
 	}
 	// set function defaults (may be overwritten before calling Start)
 	cs.decideProposal = cs.defaultDecideProposal
@@ -148,11 +173,46 @@ func (cs *ConsensusState) SetLogger(l log.Logger) {
 	cs.timeoutTicker.SetLogger(l)
 }
 
+// SetEvidencePool sets the pool tracking the evidence of byzantine validators.
+// NOTE: not thread safe, call before Start.
+func (cs *ConsensusState) SetEvidencePool(evpool types.EvidencePool) {
+	cs.evpool = evpool
+}
+
 // SetEventBus sets event bus.
 func (cs *ConsensusState) SetEventBus(b *types.EventBus) {
 	cs.eventBus = b
 }
 
+// SetStrictWALSync makes the WAL sync every msg to disk, rather than only the
+// EndHeightMessage of each height.
+// NOTE: not thread safe, call before Start.
+func (cs *ConsensusState) SetStrictWALSync(strict bool) {
+	cs.strictWALSync = strict
+}
+
+// SetBlockCommittedFunc sets the function called once a block is committed,
+// from the goroutine of the consensus, with the state locked: it must not call
+// the ConsensusState, and the consensus waits for it to return.
+// NOTE: not thread safe, call before Start.
+func (cs *ConsensusState) SetBlockCommittedFunc(f sm.BlockCommittedFunc) {
+	cs.onBlockCommitted = f
+}
+
+// SetBadPeerMsgFunc sets the function called with the key of a peer which
+// sent an invalid vote or proposal, eg. badly signed, and the error, from the
+// goroutine of the consensus, with the state locked: it must not block.
+// NOTE: not thread safe, call before Start.
+func (cs *ConsensusState) SetBadPeerMsgFunc(f func(peerKey string, err error)) {
+	cs.onBadPeerMsg = f
+}
+
+// SetMetrics sets the metrics of the progress of the consensus.
+// NOTE: not thread safe - should only be called once, on startup
+func (cs *ConsensusState) SetMetrics(metrics *Metrics) {
+	cs.metrics = metrics
+}
+
 // String returns a string.
 func (cs *ConsensusState) String() string {
 	// better not to access shared variables
@@ -199,6 +259,59 @@ func (cs *ConsensusState) SetTimeoutTicker(timeoutTicker TimeoutTicker) {
 	cs.timeoutTicker = timeoutTicker
 }
 
+// ScheduleExit makes the validator stop signing votes and proposals of the
+// heights after the height, or sign them again if 0, so that it can leave the
+// validator set without going dark abruptly. It's announced with an
+// EventDataValidatorExit, and again once the validator stops signing.
+func (cs *ConsensusState) ScheduleExit(height int64) error {
+	cs.mtx.Lock()
+	defer cs.mtx.Unlock()
+	if cs.privValidator == nil {
+		return errors.New("This node is not a validator")
+	}
+	if height < 0 {
+		return fmt.Errorf("Height must be greater than or equal to 0")
+	}
+	if height > 0 && height < cs.Height-1 {
+		return fmt.Errorf("Height must be greater than or equal to the last height committed, %d", cs.Height-1)
+	}
+	cs.exitHeight = height
+	cs.exitStopped = false
+	cs.Logger.Info("Scheduled the exit of the validator", "stopSigningHeight", height)
+	cs.eventBus.PublishEventValidatorExit(types.EventDataValidatorExit{
+		Address:    cs.privValidator.GetAddress(),
+		StopHeight: height,
+	})
+	return nil
+}
+
+// ExitHeight returns the height after which the validator stops signing,
+// 0 for never.
+func (cs *ConsensusState) ExitHeight() int64 {
+	cs.mtx.Lock()
+	defer cs.mtx.Unlock()
+	return cs.exitHeight
+}
+
+// signingStopped returns true if the validator signs nothing of the height,
+// announcing it the first time.
+// CONTRACT: cs.mtx is held
+func (cs *ConsensusState) signingStopped(height int64) bool {
+	if cs.exitHeight == 0 || height <= cs.exitHeight {
+		return false
+	}
+	if !cs.exitStopped && cs.privValidator != nil {
+		cs.exitStopped = true
+		cs.Logger.Info("Stopped signing, as scheduled", "height", height, "stopSigningHeight", cs.exitHeight)
+		cs.eventBus.PublishEventValidatorExit(types.EventDataValidatorExit{
+			Address:    cs.privValidator.GetAddress(),
+			StopHeight: cs.exitHeight,
+			Stopped:    true,
+		})
+	}
+	return true
+}
+
 // LoadCommit loads the commit for a given height.
 func (cs *ConsensusState) LoadCommit(height int64) *types.Commit {
 	cs.mtx.Lock()
@@ -212,6 +325,10 @@ func (cs *ConsensusState) LoadCommit(height int64) *types.Commit {
 // OnStart implements cmn.Service.
 // It loads the latest state via the WAL, and starts the timeout and receive routines.
 func (cs *ConsensusState) OnStart() error {
+	if err := cs.checkDoubleSigningRisk(cs.Height); err != nil {
+		return err
+	}
+
 	// we may set the WAL in testing before calling Start,
 	// so only OpenWAL if its still the nilWAL
 	if _, ok := cs.wal.(nilWAL); ok {
@@ -235,7 +352,10 @@ func (cs *ConsensusState) OnStart() error {
 	}
 
 	// we may have lost some votes if the process crashed
-	// reload from consensus log to catchup
+	// reload from consensus log to catchup.
+	// the mempool is paused first, not to check txs against the app
+	// until it's caught up
+	cs.mempool.Pause()
 	if cs.doWALCatchup {
 		if err := cs.catchupReplay(cs.Height); err != nil {
 			cs.Logger.Error("Error on catchup replay. Proceeding to start ConsensusState anyway", "err", err.Error())
@@ -243,6 +363,9 @@ func (cs *ConsensusState) OnStart() error {
 			// make sure to stop the timeoutTicker
 		}
 	}
+	// we're caught up, whether from the WAL or by fast syncing,
+	// so the mempool replays its own WAL now
+	cs.mempool.Resume()
 
 	// now start the receiveRoutine
 	go cs.receiveRoutine(0)
@@ -254,6 +377,29 @@ func (cs *ConsensusState) OnStart() error {
 	return nil
 }
 
+// checkDoubleSigningRisk returns ErrSignatureFoundInPastBlocks if our validator
+// signed the commit of one of the last DoubleSignCheckHeight blocks before the height.
+func (cs *ConsensusState) checkDoubleSigningRisk(height int64) error {
+	checkHeight := cs.config.DoubleSignCheckHeight
+	if cs.privValidator == nil || checkHeight <= 0 {
+		return nil
+	}
+	address := cs.privValidator.GetAddress()
+	for h := height - 1; h >= 1 && h >= height-checkHeight; h-- {
+		commit := cs.blockStore.LoadSeenCommit(h)
+		if commit == nil {
+			continue
+		}
+		for _, precommit := range commit.Precommits {
+			if precommit != nil && bytes.Equal(precommit.ValidatorAddress, address) {
+				cs.Logger.Error("Found our signature in a past block", "height", h)
+				return ErrSignatureFoundInPastBlocks
+			}
+		}
+	}
+	return nil
+}
+
 // timeoutRoutine: receive requests for timeouts on tickChan and fire timeouts on tockChan
 // receiveRoutine: serializes processing of proposoals, block parts, votes; coordinates state transitions
 func (cs *ConsensusState) startRoutines(maxSteps int) {
@@ -292,6 +438,7 @@ func (cs *ConsensusState) OpenWAL(walFile string) (WAL, error) {
 		return nil, err
 	}
 	wal.SetLogger(cs.Logger.With("wal", walFile))
+	wal.SetStrictSync(cs.strictWALSync)
 	if err := wal.Start(); err != nil {
 		return nil, err
 	}
@@ -365,6 +512,13 @@ func (cs *ConsensusState) updateHeight(height int64) {
 }
 
 func (cs *ConsensusState) updateRoundStep(round int, step cstypes.RoundStepType) {
+	if round != cs.Round || step != cs.Step {
+		now := time.Now()
+		if !cs.stepStart.IsZero() {
+			cs.metrics.stepDuration(cs.Step).Update(now.Sub(cs.stepStart))
+		}
+		cs.stepStart = now
+	}
 	cs.Round = round
 	cs.Step = step
 }
@@ -403,6 +557,14 @@ func (cs *ConsensusState) reconstructLastCommit(state *sm.State) {
 	}
 	seenCommit := cs.blockStore.LoadSeenCommit(state.LastBlockHeight)
 	lastPrecommits := types.NewVoteSet(state.ChainID, state.LastBlockHeight, seenCommit.Round(), types.VoteTypePrecommit, state.LastValidators)
+	// eg. saved by fast sync, with the LastCommit of the next block
+	if seenCommit.IsAggregated() {
+		if err := lastPrecommits.AddAggregatedCommit(seenCommit); err != nil {
+			cmn.PanicCrisis(cmn.Fmt("Failed to reconstruct LastCommit: %v", err))
+		}
+		cs.LastCommit = lastPrecommits
+		return
+	}
 	for _, precommit := range seenCommit.Precommits {
 		if precommit == nil {
 			continue
@@ -452,6 +614,7 @@ func (cs *ConsensusState) updateToState(state *sm.State) {
 
 	// Next desired block height
 	height := state.LastBlockHeight + 1
+	timeouts := timeoutConfig(cs.config, state.Params.TimeoutParams)
 
 	// RoundState fields
 	cs.updateHeight(height)
@@ -462,14 +625,15 @@ func (cs *ConsensusState) updateToState(state *sm.State) {
 		// to be gathered for the first block.
 		// And alternative solution that relies on clocks:
 		//  cs.StartTime = state.LastBlockTime.Add(timeoutCommit)
-		cs.StartTime = cs.config.Commit(time.Now())
+		cs.StartTime = timeouts.Commit(time.Now())
 	} else {
-		cs.StartTime = cs.config.Commit(cs.CommitTime)
+		cs.StartTime = timeouts.Commit(cs.CommitTime)
 	}
 	cs.Validators = validators
 	cs.Proposal = nil
 	cs.ProposalBlock = nil
 	cs.ProposalBlockParts = nil
+	cs.ConflictingProposal = nil
 	cs.LockedRound = 0
 	cs.LockedBlock = nil
 	cs.LockedBlockParts = nil
@@ -494,6 +658,11 @@ func (cs *ConsensusState) newStep() {
 	}
 }
 
+// timeouts returns the config with the timeouts of the current consensus params.
+func (cs *ConsensusState) timeouts() *cfg.ConsensusConfig {
+	return timeoutConfig(cs.config, cs.state.Params.TimeoutParams)
+}
+
 //-----------------------------------------
 // the main go routines
 
@@ -564,6 +733,9 @@ func (cs *ConsensusState) handleMsg(mi msgInfo) {
 		// will not cause transition.
 		// once proposal is set, we can receive block parts
 		err = cs.setProposal(msg.Proposal)
+		if err == ErrInvalidProposalSignature {
+			cs.reportBadPeerMsg(peerKey, err)
+		}
 	case *BlockPartMessage:
 		// if the proposal is complete, we'll enterPrevote or tryFinalizeCommit
 		_, err = cs.addProposalBlockPart(msg.Height, msg.Part, peerKey != "")
@@ -575,7 +747,7 @@ func (cs *ConsensusState) handleMsg(mi msgInfo) {
 		// if the vote gives us a 2/3-any or 2/3-one, we transition
 		err := cs.tryAddVote(msg.Vote, peerKey)
 		if err == ErrAddingVote {
-			// TODO: punish peer
+			cs.reportBadPeerMsg(peerKey, err)
 		}
 
 		// NOTE: the vote is broadcast to peers by the reactor listening
@@ -584,6 +756,12 @@ func (cs *ConsensusState) handleMsg(mi msgInfo) {
 		// TODO: If rs.Height == vote.Height && rs.Round < vote.Round,
 		// the peer is sending us CatchupCommit precommits.
 		// We could make note of this and help filter in broadcastHasVoteMessage().
+	case *CommitMessage:
+		// if the commit is for our height, we commit the block
+		err = cs.addAggregatedCommit(msg.Commit, peerKey)
+		if err != nil {
+			cs.reportBadPeerMsg(peerKey, err)
+		}
 	default:
 		cs.Logger.Error("Unknown msg type", reflect.TypeOf(msg))
 	}
@@ -592,6 +770,13 @@ func (cs *ConsensusState) handleMsg(mi msgInfo) {
 	}
 }
 
+// reportBadPeerMsg reports the invalid message of the peer, if it isn't ours.
+func (cs *ConsensusState) reportBadPeerMsg(peerKey string, err error) {
+	if peerKey != "" && cs.onBadPeerMsg != nil {
+		cs.onBadPeerMsg(peerKey, err)
+	}
+}
+
 func (cs *ConsensusState) handleTimeout(ti timeoutInfo, rs cstypes.RoundState) {
 	cs.Logger.Debug("Received tock", "timeout", ti.Duration, "height", ti.Height, "round", ti.Round, "step", ti.Step)
 
@@ -613,6 +798,9 @@ func (cs *ConsensusState) handleTimeout(ti timeoutInfo, rs cstypes.RoundState) {
 	case cstypes.RoundStepNewRound:
 		cs.enterPropose(ti.Height, 0)
 	case cstypes.RoundStepPropose:
+		if !cs.isProposalComplete() {
+			cs.metrics.MissedProposals.Inc(1)
+		}
 		cs.eventBus.PublishEventTimeoutPropose(cs.RoundStateEvent())
 		cs.enterPrevote(ti.Height, ti.Round)
 	case cstypes.RoundStepPrevoteWait:
@@ -656,6 +844,13 @@ func (cs *ConsensusState) enterNewRound(height int64, round int) {
 
 	cs.Logger.Info(cmn.Fmt("enterNewRound(%v/%v). Current: %v/%v/%v", height, round, cs.Height, cs.Round, cs.Step))
 
+	// the previous round of the height is over
+	now := time.Now()
+	if !cs.roundStart.IsZero() {
+		cs.metrics.RoundDuration.Update(now.Sub(cs.roundStart))
+	}
+	cs.roundStart = now
+
 	// Increment validators if necessary
 	validators := cs.Validators
 	if cs.Round < round {
@@ -676,6 +871,7 @@ func (cs *ConsensusState) enterNewRound(height int64, round int) {
 		cs.Proposal = nil
 		cs.ProposalBlock = nil
 		cs.ProposalBlockParts = nil
+		cs.ConflictingProposal = nil
 	}
 	cs.Votes.SetRound(round + 1) // also track next round (round+1) to allow round-skipping
 
@@ -721,6 +917,13 @@ func (cs *ConsensusState) proposalHeartbeat(height int64, round int) {
 		if rs.Step > cstypes.RoundStepNewRound || rs.Round > round || rs.Height > height {
 			return
 		}
+		// nor once the validator stopped signing
+		cs.mtx.Lock()
+		stopped := cs.signingStopped(rs.Height)
+		cs.mtx.Unlock()
+		if stopped {
+			return
+		}
 		heartbeat := &types.Heartbeat{
 			Height:           rs.Height,
 			Round:            rs.Round,
@@ -759,7 +962,7 @@ func (cs *ConsensusState) enterPropose(height int64, round int) {
 	}()
 
 	// If we don't get the proposal and all block parts quick enough, enterPrevote
-	cs.scheduleTimeout(cs.config.Propose(round), height, round, cstypes.RoundStepPropose)
+	cs.scheduleTimeout(cs.timeouts().Propose(round), height, round, cstypes.RoundStepPropose)
 
 	// Nothing more to do if we're not a validator
 	if cs.privValidator == nil {
@@ -774,6 +977,8 @@ func (cs *ConsensusState) enterPropose(height int64, round int) {
 		} else {
 			cs.Logger.Debug("This node is not a validator")
 		}
+	} else if cs.signingStopped(height) {
+		cs.Logger.Info("enterPropose: Our turn to propose, but we stopped signing", "stopSigningHeight", cs.exitHeight)
 	} else {
 		cs.Logger.Info("enterPropose: Our turn to propose", "proposer", cs.Validators.GetProposer().Address, "privValidator", cs.privValidator)
 		cs.Logger.Debug("This node is a validator")
@@ -855,6 +1060,14 @@ func (cs *ConsensusState) createProposalBlock() (block *types.Block, blockParts
 	} else if cs.LastCommit.HasTwoThirdsMajority() {
 		// Make the commit from LastCommit
 		commit = cs.LastCommit.MakeCommit()
+		if cs.state.Params.CommitParams.AggregateSignatures {
+			// unless all the validators use BLS keys, the precommits keep their signatures
+			if aggregated, err := commit.Aggregate(); err == nil {
+				commit = aggregated
+			} else {
+				cs.Logger.Debug("enterPropose: Not aggregating the commit signatures", "err", err)
+			}
+		}
 	} else {
 		// This shouldn't happen.
 		cs.Logger.Error("enterPropose: Cannot propose anything: No commit for the previous block.")
@@ -863,9 +1076,38 @@ func (cs *ConsensusState) createProposalBlock() (block *types.Block, blockParts
 
 	// Mempool validated transactions
 	txs := cs.mempool.Reap(cs.config.MaxBlockSizeTxs)
-	return types.MakeBlock(cs.Height, cs.state.ChainID, txs, commit,
+	block, blockParts = types.MakeBlock(cs.Height, cs.state.ChainID, txs, commit,
 		cs.state.LastBlockID, cs.state.Validators.Hash(),
-		cs.state.AppHash, cs.state.Params.BlockPartSizeBytes)
+		cs.state.AppHash, cs.state.LastResultsHash, cs.state.Params.BlockPartSizeBytes)
+	// the pending evidence the state can still verify, eg. not the one of an
+	// invalid app hash at a past height, up to the max of the params
+	var evidence []types.Evidence
+	maxEvidence := cs.state.Params.EvidenceParams.MaxNum
+	for _, ev := range cs.evpool.PendingEvidence() {
+		if maxEvidence > 0 && len(evidence) == maxEvidence {
+			break
+		}
+		if err := cs.state.VerifyEvidence(ev); err == nil {
+			evidence = append(evidence, ev)
+		}
+	}
+	block.AddEvidence(evidence)
+	if cs.state.Params.BlockTimeParams.BFTTime {
+		// the time is set by the validators, not by our clock
+		block.Time = cs.state.BlockTime(cs.Height, commit)
+	}
+	if scheme := cs.state.Params.DataAvailabilityParams.NewScheme(); scheme != nil {
+		shares, err := block.MakeDataAvailabilityShares(scheme)
+		if err != nil {
+			cs.Logger.Error("enterPropose: Cannot erasure code the txs", "err", err)
+			return nil, nil
+		}
+		block.DataAvailabilityHeader = shares.Header()
+	}
+	if len(evidence) > 0 || cs.state.Params.BlockTimeParams.BFTTime || !block.DataAvailabilityHeader.IsZero() {
+		blockParts = block.MakePartSet(cs.state.Params.BlockPartSizeBytes)
+	}
+	return block, blockParts
 }
 
 // Enter: `timeoutPropose` after entering Propose.
@@ -911,6 +1153,13 @@ func (cs *ConsensusState) defaultDoPrevote(height int64, round int) {
 		return
 	}
 
+	// If the proposer equivocated, prevote nil, so the round ends without a polka.
+	if cs.ConflictingProposal != nil {
+		logger.Info("enterPrevote: Proposer signed conflicting proposals")
+		cs.signAddVote(types.VoteTypePrevote, nil, types.PartSetHeader{})
+		return
+	}
+
 	// If ProposalBlock is nil, prevote nil.
 	if cs.ProposalBlock == nil {
 		logger.Info("enterPrevote: ProposalBlock is nil")
@@ -919,7 +1168,7 @@ func (cs *ConsensusState) defaultDoPrevote(height int64, round int) {
 	}
 
 	// Validate proposal block
-	err := cs.state.ValidateBlock(cs.ProposalBlock)
+	err := cs.state.ValidateBlock(cs.ProposalBlock, cs.evpool)
 	if err != nil {
 		// ProposalBlock is invalid, prevote nil.
 		logger.Error("enterPrevote: ProposalBlock is invalid", "err", err)
@@ -952,7 +1201,7 @@ func (cs *ConsensusState) enterPrevoteWait(height int64, round int) {
 	}()
 
 	// Wait for some more prevotes; enterPrecommit
-	cs.scheduleTimeout(cs.config.Prevote(round), height, round, cstypes.RoundStepPrevoteWait)
+	cs.scheduleTimeout(cs.timeouts().Prevote(round), height, round, cstypes.RoundStepPrevoteWait)
 }
 
 // Enter: `timeoutPrevote` after any +2/3 prevotes.
@@ -1027,7 +1276,7 @@ func (cs *ConsensusState) enterPrecommit(height int64, round int) {
 	if cs.ProposalBlock.HashesTo(blockID.Hash) {
 		cs.Logger.Info("enterPrecommit: +2/3 prevoted proposal block. Locking", "hash", blockID.Hash)
 		// Validate the block.
-		if err := cs.state.ValidateBlock(cs.ProposalBlock); err != nil {
+		if err := cs.state.ValidateBlock(cs.ProposalBlock, cs.evpool); err != nil {
 			cmn.PanicConsensus(cmn.Fmt("enterPrecommit: +2/3 prevoted for an invalid block: %v", err))
 		}
 		cs.LockedRound = round
@@ -1071,7 +1320,7 @@ func (cs *ConsensusState) enterPrecommitWait(height int64, round int) {
 	}()
 
 	// Wait for some more precommits; enterNewRound
-	cs.scheduleTimeout(cs.config.Precommit(round), height, round, cstypes.RoundStepPrecommitWait)
+	cs.scheduleTimeout(cs.timeouts().Precommit(round), height, round, cstypes.RoundStepPrecommitWait)
 
 }
 
@@ -1162,7 +1411,7 @@ func (cs *ConsensusState) finalizeCommit(height int64) {
 	if !block.HashesTo(blockID.Hash) {
 		cmn.PanicSanity(cmn.Fmt("Cannot finalizeCommit, ProposalBlock does not hash to commit hash"))
 	}
-	if err := cs.state.ValidateBlock(block); err != nil {
+	if err := cs.state.ValidateBlock(block, cs.evpool); err != nil {
 		cmn.PanicConsensus(cmn.Fmt("+2/3 committed an invalid block: %v", err))
 	}
 
@@ -1205,7 +1454,7 @@ func (cs *ConsensusState) finalizeCommit(height int64) {
 	// Execute and commit the block, update and save the state, and update the mempool.
 	// All calls to the proxyAppConn come here.
 	// NOTE: the block.AppHash wont reflect these txs until the next block
-	err := stateCopy.ApplyBlock(txEventBuffer, cs.proxyAppConn, block, blockParts.Header(), cs.mempool)
+	err := stateCopy.ApplyBlock(txEventBuffer, cs.proxyAppConn, block, blockParts.Header(), cs.mempool, cs.evpool)
 	if err != nil {
 		cs.Logger.Error("Error on ApplyBlock. Did the application crash? Please restart tendermint", "err", err)
 		err := cmn.Kill()
@@ -1215,6 +1464,9 @@ func (cs *ConsensusState) finalizeCommit(height int64) {
 		return
 	}
 
+	// the evidence in the block can't be committed again
+	cs.evpool.MarkEvidenceAsCommitted(block.Evidence.Evidence)
+
 	fail.Fail() // XXX
 
 	// Fire event for new block.
@@ -1230,6 +1482,20 @@ func (cs *ConsensusState) finalizeCommit(height int64) {
 	if err != nil {
 		cs.Logger.Error("Failed to flush event buffer", "err", err)
 	}
+	// Fire event for the validator updates, which apply from the next block
+	abciResponses := stateCopy.LoadABCIResponses()
+	if abciResponses != nil && abciResponses.EndBlock != nil && len(abciResponses.EndBlock.ValidatorUpdates) > 0 {
+		cs.eventBus.PublishEventValidatorSetUpdates(types.EventDataValidatorSetUpdates{
+			Height:  block.Height,
+			Updates: abciResponses.EndBlock.ValidatorUpdates,
+		})
+	}
+
+	if cs.onBlockCommitted != nil {
+		cs.onBlockCommitted(block, stateCopy.Copy())
+	}
+
+	cs.recordCommitMetrics(block, stateCopy)
 
 	fail.Fail() // XXX
 
@@ -1248,12 +1514,31 @@ func (cs *ConsensusState) finalizeCommit(height int64) {
 	// * cs.StartTime is set to when we will start round0.
 }
 
+// recordCommitMetrics records the block committed, the round it took, and the new validator set.
+func (cs *ConsensusState) recordCommitMetrics(block *types.Block, state *sm.State) {
+	if !cs.roundStart.IsZero() {
+		cs.metrics.RoundDuration.Update(time.Since(cs.roundStart))
+		cs.roundStart = time.Time{}
+	}
+	cs.metrics.Rounds.Update(int64(cs.CommitRound + 1))
+	cs.metrics.Height.Update(block.Height)
+	cs.metrics.NumTxs.Update(int64(block.NumTxs))
+	cs.metrics.TotalTxs.Inc(int64(block.NumTxs))
+	cs.metrics.TotalBlocks.Inc(1)
+	cs.metrics.Validators.Update(int64(state.Validators.Size()))
+	cs.metrics.ValidatorsPower.Update(state.Validators.TotalVotingPower())
+	dist := types.NewPowerDistribution(block.Height+1, state.Validators)
+	cs.metrics.ValidatorsGini.Update(dist.Gini)
+	cs.metrics.ValidatorsNakamoto.Update(int64(dist.NakamotoCoefficient))
+	cs.metrics.ValidatorsSupermajority.Update(int64(dist.SupermajorityCoefficient))
+}
+
 //-----------------------------------------------------------------------------
 
 func (cs *ConsensusState) defaultSetProposal(proposal *types.Proposal) error {
-	// Already have one
-	// TODO: possibly catch double proposals
+	// Already have one: check the proposer didn't sign another
 	if cs.Proposal != nil {
+		cs.checkConflictingProposal(proposal)
 		return nil
 	}
 
@@ -1273,6 +1558,11 @@ func (cs *ConsensusState) defaultSetProposal(proposal *types.Proposal) error {
 		return ErrInvalidProposalPOLRound
 	}
 
+	// Verify the number of parts, which a block of at most the max size makes
+	if total := proposal.BlockPartsHeader.Total; total <= 0 || total > maxBlockParts(cs.state.Params) {
+		return ErrInvalidProposalParts
+	}
+
 	// Verify signature
 	if !cs.Validators.GetProposer().PubKey.VerifyBytes(types.SignBytes(cs.state.ChainID, proposal), proposal.Signature) {
 		return ErrInvalidProposalSignature
@@ -1283,6 +1573,38 @@ func (cs *ConsensusState) defaultSetProposal(proposal *types.Proposal) error {
 	return nil
 }
 
+// checkConflictingProposal adds the evidence of the proposer equivocating to
+// the evidence pool, if the proposal is another one signed by the proposer
+// for the height and round of the one we have. The evidence is then gossiped
+// along with the pool, and we prevote nil in the round (see defaultDoPrevote):
+// the validators that saw the first or the second proposal only would
+// otherwise split their votes, depending on which one they saw first.
+func (cs *ConsensusState) checkConflictingProposal(proposal *types.Proposal) {
+	if cs.ConflictingProposal != nil ||
+		proposal.Height != cs.Proposal.Height || proposal.Round != cs.Proposal.Round ||
+		cstypes.RoundStepCommit <= cs.Step {
+		return
+	}
+
+	signBytes := types.SignBytes(cs.state.ChainID, proposal)
+	if bytes.Equal(signBytes, types.SignBytes(cs.state.ChainID, cs.Proposal)) {
+		return
+	}
+	proposer := cs.Validators.GetProposer()
+	if !proposer.PubKey.VerifyBytes(signBytes, proposal.Signature) {
+		return
+	}
+
+	cs.Logger.Error("Found conflicting proposals", "height", proposal.Height, "round", proposal.Round,
+		"proposalA", cs.Proposal, "proposalB", proposal)
+	cs.ConflictingProposal = proposal
+	proposerIndex, _ := cs.Validators.GetByAddress(proposer.Address)
+	evidence := types.NewConflictingProposalsEvidence(proposer.PubKey, proposerIndex, cs.Proposal, proposal)
+	if err := cs.evpool.AddEvidence(evidence); err != nil {
+		cs.Logger.Error("Failed to add conflicting proposals evidence", "err", err)
+	}
+}
+
 // NOTE: block is not necessarily valid.
 // Asynchronously triggers either enterPrevote (before we timeout of propose) or tryFinalizeCommit, once we have the full block.
 func (cs *ConsensusState) addProposalBlockPart(height int64, part *types.Part, verify bool) (added bool, err error) {
@@ -1296,6 +1618,9 @@ func (cs *ConsensusState) addProposalBlockPart(height int64, part *types.Part, v
 		return false, nil // TODO: bad peer? Return error?
 	}
 
+	if err := checkBlockPartSize(cs.state.Params, cs.ProposalBlockParts.Header(), part); err != nil {
+		return false, err
+	}
 	added, err = cs.ProposalBlockParts.AddPart(part, verify)
 	if err != nil {
 		return added, err
@@ -1320,9 +1645,30 @@ func (cs *ConsensusState) addProposalBlockPart(height int64, part *types.Part, v
 	return added, nil
 }
 
+// maxBlockParts returns the number of parts of a block of the max size.
+func maxBlockParts(params types.ConsensusParams) int {
+	size := params.BlockPartSizeBytes
+	return (params.BlockSizeParams.MaxBytes + size - 1) / size
+}
+
+// checkBlockPartSize returns an error if the part isn't one of a block chopped
+// in parts of BlockPartSizeBytes: all of them but the last one are of the size.
+// The part set header of a block is then unique, and the votes of a validator
+// for the same block with other ones are AmnesiaEvidence: a proposal of
+// another one never completes, so it isn't voted for.
+func checkBlockPartSize(params types.ConsensusParams, header types.PartSetHeader, part *types.Part) error {
+	size := params.BlockPartSizeBytes
+	if part.Index < header.Total-1 && len(part.Bytes) != size ||
+		part.Index == header.Total-1 && (len(part.Bytes) == 0 || len(part.Bytes) > size) {
+		return fmt.Errorf("%v: part %d of %d has %d bytes, expected parts of %d",
+			ErrInvalidProposalParts, part.Index, header.Total, len(part.Bytes), size)
+	}
+	return nil
+}
+
 // Attempt to add the vote. if its a duplicate signature, dupeout the validator
 func (cs *ConsensusState) tryAddVote(vote *types.Vote, peerKey string) error {
-	_, err := cs.addVote(vote, peerKey)
+	added, err := cs.addVote(vote, peerKey)
 	if err != nil {
 		// If the vote height is off, we'll just ignore it,
 		// But if it's a conflicting sig, broadcast evidence tx for slashing.
@@ -1334,9 +1680,13 @@ func (cs *ConsensusState) tryAddVote(vote *types.Vote, peerKey string) error {
 				cs.Logger.Error("Found conflicting vote from ourselves. Did you unsafe_reset a validator?", "height", vote.Height, "round", vote.Round, "type", vote.Type)
 				return err
 			}
-			cs.Logger.Error("Found conflicting vote. Publish evidence (TODO)", "height", vote.Height, "round", vote.Round, "type", vote.Type, "valAddr", vote.ValidatorAddress, "valIndex", vote.ValidatorIndex)
+			cs.metrics.ByzantineVotes.Inc(1)
+			cs.Logger.Error("Found conflicting vote. Publishing evidence", "height", vote.Height, "round", vote.Round, "type", vote.Type, "valAddr", vote.ValidatorAddress, "valIndex", vote.ValidatorIndex)
 
-			// TODO: track evidence for inclusion in a block
+			// the evidence pool gossips it and includes it in the next blocks we propose
+			if evErr := cs.evpool.AddEvidence(err.(*types.ErrVoteConflictingVotes).DuplicateVoteEvidence); evErr != nil {
+				cs.Logger.Error("Failed to add conflicting vote evidence", "err", evErr)
+			}
 
 			return err
 		} else {
@@ -1345,9 +1695,46 @@ func (cs *ConsensusState) tryAddVote(vote *types.Vote, peerKey string) error {
 			return ErrAddingVote
 		}
 	}
+	if added {
+		cs.checkVoteEvidence(vote)
+	}
 	return nil
 }
 
+// checkVoteEvidence publishes the evidence of misbehaviour the new vote completes:
+// a vote for the same block with other parts (amnesia),
+// or a vote for the proposal block with an invalid header (lunatic validator).
+func (cs *ConsensusState) checkVoteEvidence(vote *types.Vote) {
+	if vote.Height != cs.Height || len(vote.BlockID.Hash) == 0 {
+		return
+	}
+	_, val := cs.Validators.GetByIndex(vote.ValidatorIndex)
+	if val == nil {
+		return
+	}
+
+	var evidence types.Evidence
+	if other := cs.Votes.ConflictingPartsVote(vote); other != nil {
+		cs.Logger.Error("Found vote for conflicting block parts. Publishing evidence", "height", vote.Height, "round", vote.Round, "type", vote.Type, "valAddr", vote.ValidatorAddress, "valIndex", vote.ValidatorIndex)
+		evidence = types.NewAmnesiaEvidence(val.PubKey, other, vote)
+	} else if cs.ProposalBlock != nil && cs.ProposalBlock.HashesTo(vote.BlockID.Hash) {
+		header := *cs.ProposalBlock.Header
+		field := cs.state.InvalidHeaderField(&header)
+		if field == "" {
+			return
+		}
+		cs.Logger.Error("Found vote for a block with an invalid header. Publishing evidence", "height", vote.Height, "round", vote.Round, "type", vote.Type, "valAddr", vote.ValidatorAddress, "valIndex", vote.ValidatorIndex, "field", field)
+		evidence = types.NewLunaticValidatorEvidence(val.PubKey, &header, vote, field)
+	} else {
+		return
+	}
+
+	cs.metrics.ByzantineVotes.Inc(1)
+	if err := cs.evpool.AddEvidence(evidence); err != nil {
+		cs.Logger.Error("Failed to add vote evidence", "err", err)
+	}
+}
+
 //-----------------------------------------------------------------------------
 
 func (cs *ConsensusState) addVote(vote *types.Vote, peerKey string) (added bool, err error) {
@@ -1458,6 +1845,33 @@ func (cs *ConsensusState) addVote(vote *types.Vote, peerKey string) (added bool,
 	return
 }
 
+// addAggregatedCommit adds the precommits of the aggregated commit, and
+// commits its block if it's for our height. It returns an error if the
+// commit doesn't verify.
+func (cs *ConsensusState) addAggregatedCommit(commit *types.Commit, peerKey string) error {
+	height, round := commit.Height(), commit.Round()
+	if height != cs.Height {
+		// we already have a LastCommit, or can't verify it yet
+		cs.Logger.Debug("Ignoring aggregated commit", "commitHeight", height, "csHeight", cs.Height)
+		return nil
+	}
+	if err := cs.Votes.AddAggregatedCommit(commit, peerKey); err != nil {
+		return err
+	}
+	precommits := cs.Votes.Precommits(round)
+	if precommits == nil || !precommits.HasTwoThirdsMajority() {
+		return nil // a round the peer can't make us track
+	}
+	cs.Logger.Info("Added aggregated commit", "commit", commit, "precommits", precommits.StringShort())
+	cs.enterNewRound(height, round)
+	cs.enterPrecommit(height, round)
+	cs.enterCommit(height, round)
+	if cs.config.SkipTimeoutCommit && precommits.HasAll() {
+		cs.enterNewRound(cs.Height, 0)
+	}
+	return nil
+}
+
 func (cs *ConsensusState) signVote(type_ byte, hash []byte, header types.PartSetHeader) (*types.Vote, error) {
 	addr := cs.privValidator.GetAddress()
 	valIndex, _ := cs.Validators.GetByAddress(addr)
@@ -1468,17 +1882,36 @@ func (cs *ConsensusState) signVote(type_ byte, hash []byte, header types.PartSet
 		Round:            cs.Round,
 		Type:             type_,
 		BlockID:          types.BlockID{hash, header},
+		Timestamp:        types.VoteTime(time.Now(), cs.votedBlockTime(hash)),
 	}
 	err := cs.privValidator.SignVote(cs.state.ChainID, vote)
 	return vote, err
 }
 
+// votedBlockTime returns the time the votes for the block of the hash must be
+// after: the time of the block, which may be ahead of our clock, or of the last
+// block for nil. The next block time, the median of the precommit times, is then
+// after it.
+func (cs *ConsensusState) votedBlockTime(hash []byte) time.Time {
+	if cs.ProposalBlock.HashesTo(hash) {
+		return cs.ProposalBlock.Time
+	}
+	if cs.LockedBlock.HashesTo(hash) {
+		return cs.LockedBlock.Time
+	}
+	return cs.state.LastBlockTime
+}
+
 // sign the vote and publish on internalMsgQueue
 func (cs *ConsensusState) signAddVote(type_ byte, hash []byte, header types.PartSetHeader) *types.Vote {
 	// if we don't have a key or we're not in the validator set, do nothing
 	if cs.privValidator == nil || !cs.Validators.HasAddress(cs.privValidator.GetAddress()) {
 		return nil
 	}
+	// nor if it stopped signing to exit
+	if cs.signingStopped(cs.Height) {
+		return nil
+	}
 	vote, err := cs.signVote(type_, hash, header)
 	if err == nil {
 		cs.sendInternalMessage(msgInfo{&VoteMessage{vote}, ""})
@@ -1512,3 +1945,29 @@ func CompareHRS(h1 int64, r1 int, s1 cstypes.RoundStepType, h2 int64, r2 int, s2
 	}
 	return 0
 }
+
+// timeoutConfig returns a copy of the config with the timeouts set by the chain
+// in its consensus params, which take precedence over the ones of the node.
+func timeoutConfig(config *cfg.ConsensusConfig, params types.TimeoutParams) *cfg.ConsensusConfig {
+	if params == (types.TimeoutParams{}) {
+		return config
+	}
+	c := *config
+	override := func(timeout *int, param int) {
+		if param > 0 {
+			*timeout = param
+		}
+	}
+	override(&c.TimeoutPropose, params.Propose)
+	override(&c.TimeoutProposeDelta, params.ProposeDelta)
+	override(&c.TimeoutPrevote, params.Prevote)
+	override(&c.TimeoutPrevoteDelta, params.PrevoteDelta)
+	override(&c.TimeoutPrecommit, params.Precommit)
+	override(&c.TimeoutPrecommitDelta, params.PrecommitDelta)
+	override(&c.TimeoutCommit, params.Commit)
+	override(&c.TimeoutMax, params.Max)
+	if params.Escalation != "" {
+		c.TimeoutEscalation = params.Escalation
+	}
+	return &c
+}
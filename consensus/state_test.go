@@ -7,6 +7,9 @@ import (
 	"testing"
 	"time"
 
+	metrics "github.com/rcrowley/go-metrics"
+
+	cfg "github.com/tendermint/tendermint/config"
 	cstypes "github.com/tendermint/tendermint/consensus/types"
 	"github.com/tendermint/tendermint/types"
 	cmn "github.com/tendermint/tmlibs/common"
@@ -1081,6 +1084,72 @@ func TestHalt1(t *testing.T) {
 	}
 }
 
+func TestStateMetrics(t *testing.T) {
+	cs, _ := randConsensusState(1)
+	height, round := cs.Height, cs.Round
+
+	registry := metrics.NewRegistry()
+	cs.SetMetrics(NewMetrics(registry))
+
+	newBlockCh := subscribe(cs.eventBus, types.EventQueryNewBlock)
+	newRoundCh := subscribe(cs.eventBus, types.EventQueryNewRound)
+
+	startTestRound(cs, height, round)
+	<-newRoundCh
+	<-newBlockCh
+	// the metrics are recorded by the next height,
+	// which may be going on already
+	<-newRoundCh
+
+	gauge := func(name string) int64 { return registry.Get(name).(metrics.Gauge).Value() }
+	if h := gauge("consensus.height"); h < height {
+		t.Fatalf("expected height %d at least, got %d", height, h)
+	}
+	if v := gauge("consensus.validators"); v != 1 {
+		t.Fatalf("expected 1 validator, got %d", v)
+	}
+	if n := registry.Get("consensus.rounds").(metrics.Histogram).Count(); n < 1 {
+		t.Fatalf("expected the rounds of a block, got %d", n)
+	}
+	for _, step := range []string{"propose", "prevote", "precommit", "commit"} {
+		if n := registry.Get("consensus.step_duration." + step).(metrics.Timer).Count(); n < 1 {
+			t.Fatalf("expected a duration for step %s", step)
+		}
+	}
+	if n := registry.Get("consensus.missed_proposals").(metrics.Counter).Count(); n != 0 {
+		t.Fatalf("expected no missed proposals, got %d", n)
+	}
+}
+
+func TestTimeoutConfig(t *testing.T) {
+	nodeConfig := config.Consensus
+
+	// no timeout set by the chain
+	if c := timeoutConfig(nodeConfig, types.TimeoutParams{}); c != nodeConfig {
+		t.Fatal("expected the config of the node")
+	}
+
+	c := timeoutConfig(nodeConfig, types.TimeoutParams{Propose: nodeConfig.TimeoutPropose + 1000, Commit: 1})
+	if c.TimeoutPropose != nodeConfig.TimeoutPropose+1000 || c.TimeoutCommit != 1 {
+		t.Fatalf("expected the timeouts of the chain, got propose %d and commit %d", c.TimeoutPropose, c.TimeoutCommit)
+	}
+	if c.TimeoutPrevote != nodeConfig.TimeoutPrevote || c.SkipTimeoutCommit != nodeConfig.SkipTimeoutCommit {
+		t.Fatal("expected the rest of the config of the node")
+	}
+	if nodeConfig.TimeoutCommit == 1 {
+		t.Fatal("expected the config of the node to be left as it is")
+	}
+
+	// the chain can escalate the timeouts faster after long partitions
+	c = timeoutConfig(nodeConfig, types.TimeoutParams{Escalation: cfg.TimeoutEscalationExponential, Max: 60000})
+	if c.TimeoutEscalation != cfg.TimeoutEscalationExponential || c.TimeoutMax != 60000 {
+		t.Fatalf("expected the escalation of the chain, got %q up to %d", c.TimeoutEscalation, c.TimeoutMax)
+	}
+	if nodeConfig.TimeoutEscalation != cfg.TimeoutEscalationLinear {
+		t.Fatal("expected the config of the node to be left as it is")
+	}
+}
+
 // subscribe subscribes test client to the given query and returns a channel with cap = 1.
 func subscribe(eventBus *types.EventBus, q tmpubsub.Query) <-chan interface{} {
 	out := make(chan interface{}, 1)
@@ -1097,3 +1166,217 @@ func discardFromChan(ch <-chan interface{}, n int) {
 		<-ch
 	}
 }
+
+func TestStateDoubleSignCheck(t *testing.T) {
+	cs1, vss := randConsensusState(2)
+	csConfig := *cs1.config
+	cs1.config = &csConfig
+
+	// our validator signed the commit of block 1
+	block, parts := types.MakeBlock(1, cs1.state.ChainID, nil, &types.Commit{}, types.BlockID{}, nil, nil, nil, 65536)
+	vote := signVote(vss[0], types.VoteTypePrecommit, block.Hash(), parts.Header())
+	seenCommit := &types.Commit{
+		BlockID:    types.BlockID{block.Hash(), parts.Header()},
+		Precommits: []*types.Vote{vote, nil},
+	}
+	cs1.blockStore.SaveBlock(block, parts, seenCommit)
+
+	if err := cs1.checkDoubleSigningRisk(3); err != nil {
+		t.Fatalf("Expected no check by default, got %v", err)
+	}
+	csConfig.DoubleSignCheckHeight = 1
+	if err := cs1.checkDoubleSigningRisk(3); err != nil {
+		t.Fatalf("Expected only block 2 to be checked, got %v", err)
+	}
+	csConfig.DoubleSignCheckHeight = 2
+	if err := cs1.checkDoubleSigningRisk(3); err != ErrSignatureFoundInPastBlocks {
+		t.Fatalf("Expected ErrSignatureFoundInPastBlocks, got %v", err)
+	}
+}
+
+func TestStateScheduleExit(t *testing.T) {
+	cs, _ := randConsensusState(1)
+	exitCh := subscribe(cs.eventBus, types.EventQueryValidatorExit)
+	newBlockCh := subscribe(cs.eventBus, types.EventQueryNewBlock)
+	timeoutCh := subscribe(cs.eventBus, types.EventQueryTimeoutPropose)
+
+	if err := cs.ScheduleExit(-1); err == nil {
+		t.Fatal("Expected an error for a negative height")
+	}
+	if err := cs.ScheduleExit(1); err != nil {
+		t.Fatal(err)
+	}
+	exit := (<-exitCh).(types.TMEventData).Unwrap().(types.EventDataValidatorExit)
+	if exit.StopHeight != 1 || exit.Stopped || !bytes.Equal(exit.Address, cs.privValidator.GetAddress()) {
+		t.Fatalf("Expected the exit after height 1 to be announced, got %v", exit)
+	}
+
+	// the validator signs the block 1, and nothing after it
+	startTestRound(cs, cs.Height, cs.Round)
+	block := (<-newBlockCh).(types.TMEventData).Unwrap().(types.EventDataNewBlock).Block
+	if block.Height != 1 {
+		t.Fatalf("Expected block 1, got %d", block.Height)
+	}
+	exit = (<-exitCh).(types.TMEventData).Unwrap().(types.EventDataValidatorExit)
+	if exit.StopHeight != 1 || !exit.Stopped {
+		t.Fatalf("Expected the validator to announce it stopped, got %v", exit)
+	}
+	<-timeoutCh
+	rs := cs.GetRoundState()
+	if rs.Height != 2 || rs.Proposal != nil {
+		t.Errorf("Expected no proposal at height 2, got %v at height %d", rs.Proposal, rs.Height)
+	}
+	if cs.ExitHeight() != 1 {
+		t.Errorf("Expected the exit height to be 1, got %d", cs.ExitHeight())
+	}
+}
+
+// evidencePoolRecorder records the evidence the ConsensusState adds.
+type evidencePoolRecorder struct {
+	types.MockEvidencePool
+	evidenceCh chan types.Evidence
+}
+
+func (evr *evidencePoolRecorder) AddEvidence(evidence types.Evidence) error {
+	evr.evidenceCh <- evidence
+	return nil
+}
+
+func TestStateConflictingProposals(t *testing.T) {
+	cs1, vss := randConsensusState(2)
+	height, round := cs1.Height, cs1.Round
+	vs2 := vss[1]
+
+	evpool := &evidencePoolRecorder{evidenceCh: make(chan types.Evidence, 1)}
+	cs1.SetEvidencePool(evpool)
+
+	voteCh := subscribe(cs1.eventBus, types.EventQueryVote)
+
+	// make the second validator the proposer by incrementing round
+	round = round + 1
+	incrementRound(vss[1:]...)
+
+	// the proposer signs two proposals for the round
+	proposalA, propBlock := decideProposal(cs1, vs2, vs2.Height, round)
+	propBlockParts := propBlock.MakePartSet(cs1.state.Params.BlockPartSizeBytes)
+	proposalB := types.NewProposal(vs2.Height, round, types.PartSetHeader{1, []byte("otherpartshash")}, -1, types.BlockID{})
+	privKey := vs2.PrivValidator.(*types.PrivValidatorFS).PrivKey
+	proposalB.Signature = privKey.Sign(types.SignBytes(config.ChainID, proposalB))
+
+	// we get the second proposal before the block of the first one is complete
+	if err := cs1.SetProposal(proposalA, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := cs1.SetProposal(proposalB, ""); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < propBlockParts.Total(); i++ {
+		if err := cs1.AddProposalBlockPart(height, round, propBlockParts.GetPart(i), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// start the machine
+	startTestRound(cs1, height, round)
+
+	select {
+	case evidence := <-evpool.evidenceCh:
+		ev, ok := evidence.(*types.ConflictingProposalsEvidence)
+		if !ok {
+			t.Fatalf("Expected ConflictingProposalsEvidence, got %T", evidence)
+		}
+		if err := ev.Verify(config.ChainID); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(ev.Address(), vs2.GetAddress()) || ev.Index() != vs2.Index {
+			t.Fatalf("Expected the evidence against validator %d, got %v", vs2.Index, ev)
+		}
+	case <-time.After(ensureTimeout):
+		t.Fatal("Timed out waiting for the evidence")
+	}
+
+	// we prevote nil even though the block of the first proposal is complete
+	<-voteCh
+	validatePrevote(t, cs1, round, vss[0], nil)
+}
+
+func TestStateBlockPartSize(t *testing.T) {
+	cs1, _ := randConsensusState(1)
+	params := cs1.state.Params
+	params.BlockPartSizeBytes = 100
+
+	if n := maxBlockParts(params); n != (params.MaxBytes+99)/100 {
+		t.Fatalf("Expected %d parts at most, got %d", (params.MaxBytes+99)/100, n)
+	}
+
+	block, _ := cs1.createProposalBlock()
+	if block.MakePartSet(100).Total() < 2 {
+		t.Fatal("Expected a block of several parts")
+	}
+	checkParts := func(partSize int) error {
+		parts := block.MakePartSet(partSize)
+		for i := 0; i < parts.Total(); i++ {
+			if err := checkBlockPartSize(params, parts.Header(), parts.GetPart(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := checkParts(100); err != nil {
+		t.Fatal(err)
+	}
+	// the same block in other parts
+	for _, partSize := range []int{50, 150} {
+		if err := checkParts(partSize); err == nil {
+			t.Fatalf("Expected an error for the parts of %d bytes", partSize)
+		}
+	}
+}
+
+func TestStateVoteTimeClockBehind(t *testing.T) {
+	cs1, vss := randConsensusState(2)
+	height, round := cs1.Height, cs1.Round
+	vs2 := vss[1]
+
+	partSize := cs1.state.Params.BlockPartSizeBytes
+
+	proposalCh := subscribe(cs1.eventBus, types.EventQueryCompleteProposal)
+	voteCh := subscribe(cs1.eventBus, types.EventQueryVote)
+
+	// the clock of the proposer is an hour ahead of ours
+	propBlock, _ := cs1.createProposalBlock()
+	propBlock.Time = time.Now().Add(time.Hour)
+	propBlockParts := propBlock.MakePartSet(partSize)
+
+	// make the second validator the proposer by incrementing round
+	round = round + 1
+	incrementRound(vss[1:]...)
+
+	proposal := types.NewProposal(vs2.Height, round, propBlockParts.Header(), -1, types.BlockID{})
+	if err := vs2.SignProposal(config.ChainID, proposal); err != nil {
+		t.Fatal("failed to sign proposal", err)
+	}
+	if err := cs1.SetProposalAndBlock(proposal, propBlock, propBlockParts, "some peer"); err != nil {
+		t.Fatal(err)
+	}
+
+	startTestRound(cs1, height, round)
+	<-proposalCh
+
+	// our votes are still after the time of the block
+	checkVote := func(voteType byte) {
+		vote := (<-voteCh).(types.TMEventData).Unwrap().(types.EventDataVote).Vote
+		if vote.Type != voteType || !bytes.Equal(vote.BlockID.Hash, propBlock.Hash()) {
+			t.Fatalf("Expected a vote of type %X for the proposal block, got %v", voteType, vote)
+		}
+		if !vote.Timestamp.After(propBlock.Time) {
+			t.Fatalf("Expected a vote after the block time %v, got %v", propBlock.Time, vote.Timestamp)
+		}
+	}
+	checkVote(types.VoteTypePrevote)
+
+	signAddVotes(cs1, types.VoteTypePrevote, propBlock.Hash(), propBlockParts.Header(), vs2)
+	<-voteCh
+
+	checkVote(types.VoteTypePrecommit)
+}
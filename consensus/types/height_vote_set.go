@@ -1,6 +1,7 @@
 package types
 
 import (
+	"bytes"
 	"strings"
 	"sync"
 
@@ -126,6 +127,26 @@ func (hvs *HeightVoteSet) AddVote(vote *types.Vote, peerKey string) (added bool,
 	return
 }
 
+// AddAggregatedCommit adds the precommits of the aggregated commit, see
+// VoteSet.AddAggregatedCommit. Like for the votes, the peer can make us
+// track up to 2 rounds we're not at.
+func (hvs *HeightVoteSet) AddAggregatedCommit(commit *types.Commit, peerKey string) error {
+	hvs.mtx.Lock()
+	defer hvs.mtx.Unlock()
+	round := commit.Round()
+	voteSet := hvs.getVoteSet(round, types.VoteTypePrecommit)
+	if voteSet == nil {
+		if rndz := hvs.peerCatchupRounds[peerKey]; len(rndz) < 2 {
+			hvs.addRound(round)
+			voteSet = hvs.getVoteSet(round, types.VoteTypePrecommit)
+			hvs.peerCatchupRounds[peerKey] = append(rndz, round)
+		} else {
+			return nil
+		}
+	}
+	return voteSet.AddAggregatedCommit(commit)
+}
+
 func (hvs *HeightVoteSet) Prevotes(round int) *types.VoteSet {
 	hvs.mtx.Lock()
 	defer hvs.mtx.Unlock()
@@ -153,6 +174,26 @@ func (hvs *HeightVoteSet) POLInfo() (polRound int, polBlockID types.BlockID) {
 	return -1, types.BlockID{}
 }
 
+// ConflictingPartsVote returns a vote of the validator at the height, in any round,
+// for the same block as the vote but with other part set headers, if any.
+func (hvs *HeightVoteSet) ConflictingPartsVote(vote *types.Vote) *types.Vote {
+	if vote.Height != hvs.height || len(vote.BlockID.Hash) == 0 {
+		return nil
+	}
+	hvs.mtx.Lock()
+	defer hvs.mtx.Unlock()
+	for _, rvs := range hvs.roundVoteSets {
+		for _, voteSet := range []*types.VoteSet{rvs.Prevotes, rvs.Precommits} {
+			other := voteSet.GetByIndex(vote.ValidatorIndex)
+			if other != nil && bytes.Equal(other.BlockID.Hash, vote.BlockID.Hash) &&
+				!other.BlockID.PartsHeader.Equals(vote.BlockID.PartsHeader) {
+				return other
+			}
+		}
+	}
+	return nil
+}
+
 func (hvs *HeightVoteSet) getVoteSet(round int, type_ byte) *types.VoteSet {
 	rvs, ok := hvs.roundVoteSets[round]
 	if !ok {
@@ -47,6 +47,45 @@ func TestPeerCatchupRounds(t *testing.T) {
 
 }
 
+func TestConflictingPartsVote(t *testing.T) {
+	valSet, privVals := types.RandValidatorSet(10, 1)
+
+	hvs := NewHeightVoteSet(config.ChainID, 1, valSet)
+
+	vote0 := makeVoteHR(t, 1, 0, privVals, 0)
+	if _, err := hvs.AddVote(vote0, "peer1"); err != nil {
+		t.Fatal("AddVote error", err)
+	}
+	if other := hvs.ConflictingPartsVote(vote0); other != nil {
+		t.Error("Expected no conflicting parts vote, got", other)
+	}
+
+	// same block, other parts, in the next round
+	vote1 := &types.Vote{
+		ValidatorAddress: privVals[0].GetAddress(),
+		ValidatorIndex:   0,
+		Height:           1,
+		Round:            1,
+		Type:             types.VoteTypePrecommit,
+		BlockID:          types.BlockID{[]byte("fakehash"), types.PartSetHeader{1, []byte("fakeparts")}},
+	}
+	if err := privVals[0].SignVote(config.ChainID, vote1); err != nil {
+		t.Fatal("Error signing vote", err)
+	}
+	hvs.SetRound(1)
+	if _, err := hvs.AddVote(vote1, "peer1"); err != nil {
+		t.Fatal("AddVote error", err)
+	}
+	if other := hvs.ConflictingPartsVote(vote1); other != vote0 {
+		t.Error("Expected the conflicting parts vote of round 0, got", other)
+	}
+
+	// another validator
+	if other := hvs.ConflictingPartsVote(makeVoteHR(t, 1, 1, privVals, 1)); other != nil {
+		t.Error("Expected no conflicting parts vote, got", other)
+	}
+}
+
 func makeVoteHR(t *testing.T, height int64, round int, privVals []*types.PrivValidatorFS, valIndex int) *types.Vote {
 	privVal := privVals[valIndex]
 	vote := &types.Vote{
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/tendermint/go-wire/data"
+
 	"github.com/tendermint/tendermint/types"
 )
 
@@ -58,22 +60,23 @@ func (rs RoundStepType) String() string {
 // NOTE: Not thread safe. Should only be manipulated by functions downstream
 // of the cs.receiveRoutine
 type RoundState struct {
-	Height             int64 // Height we are working on
-	Round              int
-	Step               RoundStepType
-	StartTime          time.Time
-	CommitTime         time.Time // Subjective time when +2/3 precommits for Block at Round were found
-	Validators         *types.ValidatorSet
-	Proposal           *types.Proposal
-	ProposalBlock      *types.Block
-	ProposalBlockParts *types.PartSet
-	LockedRound        int
-	LockedBlock        *types.Block
-	LockedBlockParts   *types.PartSet
-	Votes              *HeightVoteSet
-	CommitRound        int            //
-	LastCommit         *types.VoteSet // Last precommits at Height-1
-	LastValidators     *types.ValidatorSet
+	Height              int64 // Height we are working on
+	Round               int
+	Step                RoundStepType
+	StartTime           time.Time
+	CommitTime          time.Time // Subjective time when +2/3 precommits for Block at Round were found
+	Validators          *types.ValidatorSet
+	Proposal            *types.Proposal
+	ProposalBlock       *types.Block
+	ProposalBlockParts  *types.PartSet
+	ConflictingProposal *types.Proposal // another proposal of the proposer for the round, if it equivocated
+	LockedRound         int
+	LockedBlock         *types.Block
+	LockedBlockParts    *types.PartSet
+	Votes               *HeightVoteSet
+	CommitRound         int            //
+	LastCommit          *types.VoteSet // Last precommits at Height-1
+	LastValidators      *types.ValidatorSet
 }
 
 // RoundStateEvent returns the H/R/S of the RoundState as an event.
@@ -129,3 +132,89 @@ func (rs *RoundState) StringShort() string {
 	return fmt.Sprintf(`RoundState{H:%v R:%v S:%v ST:%v}`,
 		rs.Height, rs.Round, rs.Step, rs.StartTime)
 }
+
+//-----------------------------------------------------------------------------
+
+// RoundStateSummary is what the consensus is at and waiting for, without the
+// blocks and the validators of the RoundState, eg. to debug a stalled height.
+type RoundStateSummary struct {
+	Height            int64               `json:"height"`
+	Round             int                 `json:"round"`
+	Step              string              `json:"step"`
+	StartTime         time.Time           `json:"start_time"`
+	Proposer          data.Bytes          `json:"proposer"`
+	ProposalBlockHash data.Bytes          `json:"proposal_block_hash"`
+	LockedRound       int                 `json:"locked_round"`
+	LockedBlockHash   data.Bytes          `json:"locked_block_hash"`
+	Votes             []RoundVotesSummary `json:"votes"`
+	LastCommit        VoteSetSummary      `json:"last_commit"`
+}
+
+// RoundVotesSummary are the votes seen of a round.
+type RoundVotesSummary struct {
+	Round      int            `json:"round"`
+	Prevotes   VoteSetSummary `json:"prevotes"`
+	Precommits VoteSetSummary `json:"precommits"`
+}
+
+// VoteSetSummary is who voted, how much of the voting power it is, and the
+// block with +2/3 of it, if any.
+type VoteSetSummary struct {
+	BitArray   string     `json:"bit_array"`
+	VotedPower int64      `json:"voted_power"`
+	TotalPower int64      `json:"total_power"`
+	Maj23Hash  data.Bytes `json:"maj23_hash"`
+	Maj23      bool       `json:"maj23"`
+}
+
+// Summary returns the RoundStateSummary of the RoundState, with the votes of
+// the rounds up to the current one.
+func (rs *RoundState) Summary() *RoundStateSummary {
+	summary := &RoundStateSummary{
+		Height:            rs.Height,
+		Round:             rs.Round,
+		Step:              rs.Step.String(),
+		StartTime:         rs.StartTime,
+		ProposalBlockHash: rs.ProposalBlock.Hash(),
+		LockedRound:       rs.LockedRound,
+		LockedBlockHash:   rs.LockedBlock.Hash(),
+		LastCommit:        summarizeVoteSet(rs.LastCommit, rs.LastValidators),
+	}
+	if rs.Validators != nil {
+		if proposer := rs.Validators.GetProposer(); proposer != nil {
+			summary.Proposer = proposer.Address
+		}
+	}
+	if rs.Votes != nil {
+		for round := 0; round <= rs.Votes.Round(); round++ {
+			summary.Votes = append(summary.Votes, RoundVotesSummary{
+				Round:      round,
+				Prevotes:   summarizeVoteSet(rs.Votes.Prevotes(round), rs.Validators),
+				Precommits: summarizeVoteSet(rs.Votes.Precommits(round), rs.Validators),
+			})
+		}
+	}
+	return summary
+}
+
+// summarizeVoteSet returns the VoteSetSummary of the votes of the validators,
+// or an empty one if either is nil.
+func summarizeVoteSet(voteSet *types.VoteSet, valSet *types.ValidatorSet) VoteSetSummary {
+	if voteSet == nil || valSet == nil {
+		return VoteSetSummary{}
+	}
+	summary := VoteSetSummary{
+		BitArray:   voteSet.BitArray().String(),
+		TotalPower: valSet.TotalVotingPower(),
+	}
+	for i, val := range valSet.Validators {
+		if voteSet.GetByIndex(i) != nil {
+			summary.VotedPower += val.VotingPower
+		}
+	}
+	if blockID, ok := voteSet.TwoThirdsMajority(); ok {
+		summary.Maj23Hash = blockID.Hash
+		summary.Maj23 = true
+	}
+	return summary
+}
@@ -0,0 +1,49 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestRoundStateSummary(t *testing.T) {
+	valSet, privVals := types.RandValidatorSet(4, 1)
+	rs := &RoundState{
+		Height:      1,
+		Round:       1,
+		Step:        RoundStepPrecommit,
+		Validators:  valSet,
+		LockedRound: -1,
+		Votes:       NewHeightVoteSet(config.ChainID, 1, valSet),
+	}
+	rs.Votes.SetRound(1)
+	for i := 0; i < 3; i++ {
+		if _, err := rs.Votes.AddVote(makeVoteHR(t, 1, 1, privVals, i), ""); err != nil {
+			t.Fatal("AddVote error", err)
+		}
+	}
+
+	summary := rs.Summary()
+	if summary.Height != 1 || summary.Round != 1 || summary.Step != "RoundStepPrecommit" {
+		t.Errorf("Unexpected H/R/S %v/%v/%v", summary.Height, summary.Round, summary.Step)
+	}
+	if len(summary.Proposer) == 0 || summary.ProposalBlockHash != nil || summary.LockedRound != -1 {
+		t.Errorf("Unexpected proposal %v", summary)
+	}
+	if len(summary.Votes) != 2 {
+		t.Fatalf("Expected the votes of 2 rounds, got %d", len(summary.Votes))
+	}
+	if votes := summary.Votes[0].Precommits; votes.VotedPower != 0 || votes.TotalPower != 4 || votes.Maj23 {
+		t.Errorf("Unexpected precommits of round 0 %v", votes)
+	}
+	votes := summary.Votes[1].Precommits
+	if votes.VotedPower != 3 || votes.TotalPower != 4 || votes.BitArray == "" {
+		t.Errorf("Unexpected precommits of round 1 %v", votes)
+	}
+	if !votes.Maj23 || string(votes.Maj23Hash) != "fakehash" {
+		t.Errorf("Expected +2/3 precommits for fakehash, got %v", votes)
+	}
+	if summary.LastCommit.Maj23 || summary.LastCommit.TotalPower != 0 {
+		t.Errorf("Expected no last commit, got %v", summary.LastCommit)
+	}
+}
@@ -57,13 +57,20 @@ type WAL interface {
 
 // Write ahead logger writes msgs to disk before they are processed.
 // Can be used for crash-recovery and deterministic replay
+//
+// The msgs are flushed to the OS as they are written, and synced to disk with the
+// EndHeightMessage of their height, or one by one with SetStrictSync. Our own votes
+// and proposals are always synced, before they are handled and so sent to the peers.
+// The other msgs of the current height may be lost in a crash of the machine, but
+// not of the process: the peers send them again.
 // TODO: currently the wal is overwritten during replay catchup
 //   give it a mode so it's either reading or appending - must read to end to start appending again
 type baseWAL struct {
 	cmn.BaseService
 
-	group *auto.Group
-	light bool // ignore block parts
+	group      *auto.Group
+	light      bool // ignore block parts
+	strictSync bool // sync every msg
 
 	enc *WALEncoder
 }
@@ -87,6 +94,12 @@ func NewWAL(walFile string, light bool) (*baseWAL, error) {
 	return wal, nil
 }
 
+// SetStrictSync makes the WAL sync every msg to disk, rather than every height.
+// NOTE: not thread safe
+func (wal *baseWAL) SetStrictSync(strict bool) {
+	wal.strictSync = strict
+}
+
 func (wal *baseWAL) Group() *auto.Group {
 	return wal.group
 }
@@ -131,6 +144,32 @@ func (wal *baseWAL) Save(msg WALMessage) {
 	if err := wal.group.Flush(); err != nil {
 		cmn.PanicQ(cmn.Fmt("Error flushing consensus wal buf to file. Error: %v \n", err))
 	}
+
+	// the msgs of a height are synced with its end, but our own
+	if wal.strictSync || isSyncedMsg(msg) {
+		if err := wal.group.Head.Sync(); err != nil {
+			cmn.PanicQ(cmn.Fmt("Error syncing consensus wal file to disk. Error: %v \n", err))
+		}
+	}
+}
+
+// isSyncedMsg returns true if the msg is synced to disk as it is written, even
+// without strict sync: the end of a height, and our own votes and proposals,
+// which must not be forgotten once the peers got them.
+func isSyncedMsg(msg WALMessage) bool {
+	switch m := msg.(type) {
+	case EndHeightMessage:
+		return true
+	case msgInfo:
+		if m.PeerKey != "" {
+			return false
+		}
+		switch m.Msg.(type) {
+		case *VoteMessage, *ProposalMessage:
+			return true
+		}
+	}
+	return false
 }
 
 // SearchForEndHeight searches for the EndHeightMessage with the height and
@@ -272,6 +311,32 @@ func (dec *WALDecoder) Decode() (*TimedWALMessage, error) {
 	return res, err
 }
 
+// ReadSignedMessages decodes the WAL from rd and returns, in order, the votes
+// and proposals the node signed itself, ie. the ones its PrivValidator was
+// asked to sign, eg. to preview their sign bytes. The ones of the peers are skipped.
+func ReadSignedMessages(rd io.Reader) ([]types.Signable, error) {
+	var signed []types.Signable
+	dec := NewWALDecoder(rd)
+	for {
+		msg, err := dec.Decode()
+		if err == io.EOF {
+			return signed, nil
+		} else if err != nil {
+			return signed, err
+		}
+		mi, ok := msg.Msg.(msgInfo)
+		if !ok || mi.PeerKey != "" {
+			continue
+		}
+		switch m := mi.Msg.(type) {
+		case *VoteMessage:
+			signed = append(signed, m.Vote)
+		case *ProposalMessage:
+			signed = append(signed, m.Proposal)
+		}
+	}
+}
+
 type nilWAL struct{}
 
 func (nilWAL) Save(m WALMessage)  {}
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	crypto "github.com/tendermint/go-crypto"
 	wire "github.com/tendermint/go-wire"
 	"github.com/tendermint/tendermint/consensus/types"
 	tmtypes "github.com/tendermint/tendermint/types"
@@ -41,6 +42,57 @@ func TestWALEncoderDecoder(t *testing.T) {
 	}
 }
 
+func TestReadSignedMessages(t *testing.T) {
+	now := time.Now()
+	sig := crypto.GenPrivKeyEd25519().Sign([]byte("sign bytes"))
+	vote := &tmtypes.Vote{Height: 1, Round: 0, Type: tmtypes.VoteTypePrevote, Timestamp: now, Signature: sig}
+	peerVote := &tmtypes.Vote{Height: 1, Round: 0, Type: tmtypes.VoteTypePrecommit, Timestamp: now, Signature: sig}
+	proposal := &tmtypes.Proposal{Height: 1, Round: 1, POLRound: -1, Signature: sig}
+	msgs := []WALMessage{
+		msgInfo{&VoteMessage{vote}, ""},
+		msgInfo{&VoteMessage{peerVote}, "peer"},
+		timeoutInfo{Duration: time.Second, Height: 1, Round: 1, Step: types.RoundStepPropose},
+		msgInfo{&ProposalMessage{proposal}, ""},
+		EndHeightMessage{1},
+	}
+
+	b := new(bytes.Buffer)
+	enc := NewWALEncoder(b)
+	for _, msg := range msgs {
+		require.NoError(t, enc.Encode(&TimedWALMessage{Time: now, Msg: msg}))
+	}
+
+	signed, err := ReadSignedMessages(b)
+	require.NoError(t, err)
+	require.Len(t, signed, 2)
+	if v, ok := signed[0].(*tmtypes.Vote); assert.True(t, ok) {
+		assert.Equal(t, tmtypes.VoteTypePrevote, v.Type)
+	}
+	if p, ok := signed[1].(*tmtypes.Proposal); assert.True(t, ok) {
+		assert.Equal(t, 1, p.Round)
+	}
+}
+
+func TestWALSyncedMessages(t *testing.T) {
+	vote := &tmtypes.Vote{Height: 1, Round: 0, Type: tmtypes.VoteTypePrevote}
+	proposal := &tmtypes.Proposal{Height: 1, Round: 0, POLRound: -1}
+	part := &tmtypes.Part{Index: 0}
+	for _, tc := range []struct {
+		msg    WALMessage
+		synced bool
+	}{
+		{EndHeightMessage{1}, true},
+		{msgInfo{&VoteMessage{vote}, ""}, true},
+		{msgInfo{&ProposalMessage{proposal}, ""}, true},
+		{msgInfo{&BlockPartMessage{1, 0, part}, ""}, false},
+		{msgInfo{&VoteMessage{vote}, "peer"}, false},
+		{msgInfo{&ProposalMessage{proposal}, "peer"}, false},
+		{timeoutInfo{Duration: time.Second, Height: 1, Round: 1, Step: types.RoundStepPropose}, false},
+	} {
+		assert.Equal(t, tc.synced, isSyncedMsg(tc.msg), "%v", tc.msg)
+	}
+}
+
 func TestSearchForEndHeight(t *testing.T) {
 	walBody, err := WALWithNBlocks(6)
 	if err != nil {
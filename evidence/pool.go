@@ -0,0 +1,105 @@
+package evidence
+
+import (
+	dbm "github.com/tendermint/tmlibs/db"
+	"github.com/tendermint/tmlibs/log"
+
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+)
+
+const evidenceChanSize = 100
+
+// EvidencePool maintains a pool of valid evidence
+// in an EvidenceStore.
+type EvidencePool struct {
+	logger log.Logger
+
+	evidenceStore *EvidenceStore
+
+	// the db of the state, to verify the evidence against the latest state
+	stateDB dbm.DB
+
+	// new evidence, never closed
+	evidenceChan chan types.Evidence
+}
+
+// NewEvidencePool returns an EvidencePool for the store,
+// verifying the evidence against the state saved in the stateDB.
+func NewEvidencePool(stateDB dbm.DB, evidenceStore *EvidenceStore) *EvidencePool {
+	evpool := &EvidencePool{
+		logger:        log.NewNopLogger(),
+		evidenceStore: evidenceStore,
+		stateDB:       stateDB,
+		evidenceChan:  make(chan types.Evidence, evidenceChanSize),
+	}
+	return evpool
+}
+
+// SetLogger sets the Logger.
+func (evpool *EvidencePool) SetLogger(l log.Logger) {
+	evpool.logger = l
+}
+
+// EvidenceChan returns a channel on which new evidence can be received.
+// The evidence that doesn't fit in it is only in the PendingEvidence.
+func (evpool *EvidencePool) EvidenceChan() <-chan types.Evidence {
+	return evpool.evidenceChan
+}
+
+// PriorityEvidence returns the priority evidence.
+func (evpool *EvidencePool) PriorityEvidence() []types.Evidence {
+	return evpool.evidenceStore.PriorityEvidence()
+}
+
+// PendingEvidence returns all uncommitted evidence.
+func (evpool *EvidencePool) PendingEvidence() []types.Evidence {
+	return evpool.evidenceStore.PendingEvidence()
+}
+
+// AddEvidence checks the evidence is valid and adds it to the pool.
+func (evpool *EvidencePool) AddEvidence(evidence types.Evidence) (err error) {
+
+	// TODO: check if we already have evidence for this
+	// validator at this height so we dont get spammed
+
+	// the state is saved after each block, so it's never stale
+	state := sm.LoadState(evpool.stateDB)
+	if err := state.VerifyEvidence(evidence); err != nil {
+		return err
+	}
+
+	// fetch the validator and return its voting power as its priority
+	// TODO: something better ?
+	valset, _ := state.LoadValidators(evidence.Height())
+	_, val := valset.GetByAddress(evidence.Address())
+	priority := val.VotingPower
+
+	added := evpool.evidenceStore.AddNewEvidence(evidence, priority)
+	if !added {
+		// evidence already known, just ignore
+		return
+	}
+
+	evpool.logger.Info("Verified new evidence of byzantine behaviour", "evidence", evidence)
+
+	// never closes. always safe to send on, but don't block the consensus on it
+	select {
+	case evpool.evidenceChan <- evidence:
+	default:
+		evpool.logger.Debug("EvidenceChan full, the evidence will be broadcast with the pending evidence")
+	}
+	return nil
+}
+
+// MarkEvidenceAsCommitted marks all the evidence as committed.
+func (evpool *EvidencePool) MarkEvidenceAsCommitted(evidence []types.Evidence) {
+	for _, ev := range evidence {
+		evpool.evidenceStore.MarkEvidenceAsCommitted(ev)
+	}
+}
+
+// IsCommitted returns true if the evidence was committed in a block.
+func (evpool *EvidencePool) IsCommitted(evidence types.Evidence) bool {
+	return evpool.evidenceStore.IsCommitted(evidence)
+}
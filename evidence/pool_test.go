@@ -0,0 +1,115 @@
+package evidence
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	crypto "github.com/tendermint/go-crypto"
+	dbm "github.com/tendermint/tmlibs/db"
+
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+)
+
+// initializeValidatorState saves the genesis state of a chain with the validator
+// of the key, and returns its db.
+func initializeValidatorState(t *testing.T, chainID string, privKey crypto.PrivKey) dbm.DB {
+	stateDB := dbm.NewMemDB()
+	genDoc := &types.GenesisDoc{
+		ChainID: chainID,
+		Validators: []types.GenesisValidator{
+			{PubKey: privKey.PubKey(), Power: 10},
+		},
+	}
+	state, err := sm.MakeGenesisState(stateDB, genDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state.Save()
+	return stateDB
+}
+
+func TestEvidencePool(t *testing.T) {
+	assert := assert.New(t)
+
+	chainID := "mychain"
+	privKey := crypto.GenPrivKeyEd25519().Wrap()
+	stateDB := initializeValidatorState(t, chainID, privKey)
+	pool := NewEvidencePool(stateDB, NewEvidenceStore(dbm.NewMemDB()))
+
+	ev := newDuplicateVoteEvidence(chainID, privKey, 0, 1, 0)
+	assert.Nil(pool.AddEvidence(ev))
+	assert.Equal(1, len(pool.PendingEvidence()))
+
+	// it's sent on the EvidenceChan once
+	select {
+	case evNew := <-pool.EvidenceChan():
+		assert.True(ev.Equal(evNew))
+	default:
+		t.Fatal("Expected the new evidence on the EvidenceChan")
+	}
+	assert.Nil(pool.AddEvidence(ev))
+	assert.Equal(0, len(pool.EvidenceChan()))
+
+	// once committed, it's not pending anymore
+	pool.MarkEvidenceAsCommitted([]types.Evidence{ev})
+	assert.Equal(0, len(pool.PendingEvidence()))
+}
+
+func TestEvidencePoolInvalid(t *testing.T) {
+	assert := assert.New(t)
+
+	chainID := "mychain"
+	privKey := crypto.GenPrivKeyEd25519().Wrap()
+	stateDB := initializeValidatorState(t, chainID, privKey)
+	pool := NewEvidencePool(stateDB, NewEvidenceStore(dbm.NewMemDB()))
+
+	// signed for another chain
+	assert.NotNil(pool.AddEvidence(newDuplicateVoteEvidence("otherchain", privKey, 0, 1, 0)))
+	// from a height after the next block
+	assert.NotNil(pool.AddEvidence(newDuplicateVoteEvidence(chainID, privKey, 0, 5, 0)))
+	// with the wrong validator index
+	assert.NotNil(pool.AddEvidence(newDuplicateVoteEvidence(chainID, privKey, 1, 1, 0)))
+	// from a non-validator
+	otherKey := crypto.GenPrivKeyEd25519().Wrap()
+	assert.NotNil(pool.AddEvidence(newDuplicateVoteEvidence(chainID, otherKey, 0, 1, 0)))
+
+	assert.Equal(0, len(pool.PendingEvidence()))
+}
+
+func TestEvidencePoolLunatic(t *testing.T) {
+	assert := assert.New(t)
+
+	chainID := "mychain"
+	privKey := crypto.GenPrivKeyEd25519().Wrap()
+	stateDB := initializeValidatorState(t, chainID, privKey)
+	pool := NewEvidencePool(stateDB, NewEvidenceStore(dbm.NewMemDB()))
+	state := sm.LoadState(stateDB)
+
+	lunaticEvidence := func(appHash []byte) *types.LunaticValidatorEvidence {
+		header := &types.Header{
+			ChainID:        chainID,
+			Height:         1,
+			ValidatorsHash: state.Validators.Hash(),
+			AppHash:        appHash,
+		}
+		vote := &types.Vote{
+			ValidatorAddress: privKey.PubKey().Address(),
+			ValidatorIndex:   0,
+			Height:           1,
+			Type:             types.VoteTypePrevote,
+			BlockID:          types.BlockID{Hash: header.Hash()},
+		}
+		vote.Signature = privKey.Sign(types.SignBytes(chainID, vote))
+		return types.NewLunaticValidatorEvidence(privKey.PubKey(), header, vote, types.LunaticFieldAppHash)
+	}
+
+	// the app hash of the next block is the one of the state
+	assert.NotNil(pool.AddEvidence(lunaticEvidence(state.AppHash)))
+	assert.Nil(pool.AddEvidence(lunaticEvidence([]byte("invalidapphash"))))
+	assert.Equal(1, len(pool.PendingEvidence()))
+
+	// but the state doesn't know the app hash of the committed blocks
+	state.LastBlockHeight = 1
+	assert.NotNil(state.VerifyEvidence(lunaticEvidence([]byte("invalidapphash"))))
+}
@@ -0,0 +1,176 @@
+package evidence
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	wire "github.com/tendermint/go-wire"
+	"github.com/tendermint/tmlibs/log"
+
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	EvidenceChannel = byte(0x38)
+
+	maxEvidenceMessageSize     = 1048576 // 1MB TODO make it configurable
+	broadcastEvidenceIntervalS = 60      // broadcast uncommitted evidence this often
+)
+
+// EvidenceReactor handles evpool evidence broadcasting amongst peers.
+type EvidenceReactor struct {
+	p2p.BaseReactor
+	evpool *EvidencePool
+}
+
+// NewEvidenceReactor returns a new EvidenceReactor with the given evpool.
+func NewEvidenceReactor(evpool *EvidencePool) *EvidenceReactor {
+	evR := &EvidenceReactor{
+		evpool: evpool,
+	}
+	evR.BaseReactor = *p2p.NewBaseReactor("EvidenceReactor", evR)
+	return evR
+}
+
+// SetLogger sets the Logger on the reactor and the underlying EvidencePool.
+func (evR *EvidenceReactor) SetLogger(l log.Logger) {
+	evR.Logger = l
+	evR.evpool.SetLogger(l)
+}
+
+// OnStart implements BaseService.
+func (evR *EvidenceReactor) OnStart() error {
+	if err := evR.BaseReactor.OnStart(); err != nil {
+		return err
+	}
+	go evR.broadcastRoutine()
+	return nil
+}
+
+// GetChannels implements Reactor.
+// It returns the list of channels for this reactor.
+func (evR *EvidenceReactor) GetChannels() []*p2p.ChannelDescriptor {
+	return []*p2p.ChannelDescriptor{
+		{
+			ID:       EvidenceChannel,
+			Priority: 5,
+		},
+	}
+}
+
+// AddPeer implements Reactor.
+// It sends the pending evidence to the new peer.
+func (evR *EvidenceReactor) AddPeer(peer p2p.Peer) {
+	// the peer won't hear about the evidence we already broadcast
+	evidence := evR.evpool.PendingEvidence()
+	if len(evidence) == 0 {
+		return
+	}
+	msg := &EvidenceListMessage{evidence}
+	// if the send fails, the broadcastRoutine sends it again later
+	peer.Send(EvidenceChannel, struct{ EvidenceMessage }{msg})
+}
+
+// RemovePeer implements Reactor.
+func (evR *EvidenceReactor) RemovePeer(peer p2p.Peer, reason interface{}) {
+	// nothing to do
+}
+
+// Receive implements Reactor.
+// It adds any received evidence to the evpool.
+func (evR *EvidenceReactor) Receive(chID byte, src p2p.Peer, msgBytes []byte) {
+	_, msg, err := DecodeMessage(msgBytes)
+	if err != nil {
+		evR.Logger.Error("Error decoding message", "err", err)
+		evR.Switch.ReportMisbehavior(src, p2p.SeverityMedium, err)
+		return
+	}
+	evR.Logger.Debug("Receive", "src", src, "chId", chID, "msg", msg)
+
+	switch msg := msg.(type) {
+	case *EvidenceListMessage:
+		for _, ev := range msg.Evidence {
+			err := evR.evpool.AddEvidence(ev)
+			if err != nil {
+				evR.Logger.Info("Evidence is not valid", "evidence", ev, "err", err)
+				// only a low severity, as this node may not be able to
+				// verify valid evidence, eg. if it's behind the peer
+				evR.Switch.ReportMisbehavior(src, p2p.SeverityLow, err)
+			}
+		}
+	default:
+		evR.Logger.Error(fmt.Sprintf("Unknown message type %v", reflect.TypeOf(msg)))
+	}
+}
+
+// broadcastRoutine broadcasts the new evidence as it's added to the pool,
+// and periodically all the evidence not committed yet.
+func (evR *EvidenceReactor) broadcastRoutine() {
+	ticker := time.NewTicker(time.Second * broadcastEvidenceIntervalS)
+	defer ticker.Stop()
+	for {
+		select {
+		case evidence := <-evR.evpool.EvidenceChan():
+			// broadcast some new evidence
+			msg := &EvidenceListMessage{[]types.Evidence{evidence}}
+			evR.Switch.Broadcast(EvidenceChannel, struct{ EvidenceMessage }{msg})
+
+			// TODO: Broadcast runs asynchronously, so this should wait on the successChan
+			// in another routine before marking to be proper.
+			evR.evpool.evidenceStore.MarkEvidenceAsBroadcasted(evidence)
+		case <-ticker.C:
+			// broadcast all pending evidence
+			evidence := evR.evpool.PendingEvidence()
+			if len(evidence) == 0 {
+				continue
+			}
+			msg := &EvidenceListMessage{evidence}
+			evR.Switch.Broadcast(EvidenceChannel, struct{ EvidenceMessage }{msg})
+		case <-evR.Quit:
+			return
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+// Messages
+
+const (
+	msgTypeEvidence = byte(0x01)
+)
+
+// EvidenceMessage is a message sent or received by the EvidenceReactor.
+type EvidenceMessage interface{}
+
+var _ = wire.RegisterInterface(
+	struct{ EvidenceMessage }{},
+	wire.ConcreteType{&EvidenceListMessage{}, msgTypeEvidence},
+)
+
+// DecodeMessage decodes a byte-array into a EvidenceMessage.
+func DecodeMessage(bz []byte) (msgType byte, msg EvidenceMessage, err error) {
+	if len(bz) == 0 {
+		return 0, nil, errors.New("Empty evidence message")
+	}
+	msgType = bz[0]
+	n := new(int)
+	r := bytes.NewReader(bz)
+	msg = wire.ReadBinary(struct{ EvidenceMessage }{}, r, maxEvidenceMessageSize, n, &err).(struct{ EvidenceMessage }).EvidenceMessage
+	return
+}
+
+//-------------------------------------
+
+// EvidenceListMessage contains a list of evidence.
+type EvidenceListMessage struct {
+	Evidence []types.Evidence
+}
+
+// String returns a string representation of the EvidenceListMessage.
+func (m *EvidenceListMessage) String() string {
+	return fmt.Sprintf("[EvidenceListMessage %v]", m.Evidence)
+}
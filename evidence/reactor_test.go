@@ -0,0 +1,16 @@
+package evidence
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeEmptyMessage(t *testing.T) {
+	assert.NotPanics(t, func() {
+		_, _, err := DecodeMessage(nil)
+		assert.NotNil(t, err)
+		_, _, err = DecodeMessage([]byte{})
+		assert.NotNil(t, err)
+	})
+}
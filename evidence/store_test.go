@@ -0,0 +1,98 @@
+package evidence
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	crypto "github.com/tendermint/go-crypto"
+	dbm "github.com/tendermint/tmlibs/db"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// newDuplicateVoteEvidence returns the evidence the validator signed
+// two prevotes for different blocks at the height and round.
+func newDuplicateVoteEvidence(chainID string, privKey crypto.PrivKey, valIndex int, height int64, round int) *types.DuplicateVoteEvidence {
+	makeVote := func(blockHash string) *types.Vote {
+		vote := &types.Vote{
+			ValidatorAddress: privKey.PubKey().Address(),
+			ValidatorIndex:   valIndex,
+			Height:           height,
+			Round:            round,
+			Type:             types.VoteTypePrevote,
+			BlockID:          types.BlockID{Hash: []byte(blockHash)},
+		}
+		vote.Signature = privKey.Sign(types.SignBytes(chainID, vote))
+		return vote
+	}
+	return types.NewDuplicateVoteEvidence(privKey.PubKey(), makeVote("blockhash"), makeVote("blockhash2"))
+}
+
+func TestStoreAddDuplicate(t *testing.T) {
+	assert := assert.New(t)
+
+	store := NewEvidenceStore(dbm.NewMemDB())
+	ev := newDuplicateVoteEvidence("mychain", crypto.GenPrivKeyEd25519().Wrap(), 0, 1, 0)
+
+	assert.True(store.AddNewEvidence(ev, 1))
+	assert.False(store.AddNewEvidence(ev, 1))
+
+	// still a duplicate once committed
+	store.MarkEvidenceAsCommitted(ev)
+	assert.False(store.AddNewEvidence(ev, 1))
+}
+
+func TestStoreMark(t *testing.T) {
+	assert := assert.New(t)
+
+	store := NewEvidenceStore(dbm.NewMemDB())
+	assert.Equal(0, len(store.PriorityEvidence()))
+	assert.Equal(0, len(store.PendingEvidence()))
+
+	ev := newDuplicateVoteEvidence("mychain", crypto.GenPrivKeyEd25519().Wrap(), 0, 1, 0)
+	assert.True(store.AddNewEvidence(ev, 1))
+
+	// it's in the outqueue and pending
+	assert.Equal(1, len(store.PriorityEvidence()))
+	assert.Equal(1, len(store.PendingEvidence()))
+	assert.True(ev.Equal(store.PendingEvidence()[0]))
+	ei := store.GetEvidence(ev.Height(), ev.Hash())
+	assert.NotNil(ei)
+	assert.False(ei.Committed)
+	assert.False(store.IsCommitted(ev))
+	assert.Equal(int64(1), ei.Priority)
+
+	// broadcast, it's only pending
+	store.MarkEvidenceAsBroadcasted(ev)
+	assert.Equal(0, len(store.PriorityEvidence()))
+	assert.Equal(1, len(store.PendingEvidence()))
+
+	// committed, it's only in the lookup
+	store.MarkEvidenceAsCommitted(ev)
+	assert.Equal(0, len(store.PriorityEvidence()))
+	assert.Equal(0, len(store.PendingEvidence()))
+	ei = store.GetEvidence(ev.Height(), ev.Hash())
+	assert.NotNil(ei)
+	assert.True(ei.Committed)
+	assert.True(store.IsCommitted(ev))
+}
+
+func TestStorePriority(t *testing.T) {
+	assert := assert.New(t)
+
+	store := NewEvidenceStore(dbm.NewMemDB())
+	privKey := crypto.GenPrivKeyEd25519().Wrap()
+	priorities := []int64{10, 1000, 5}
+	evs := make([]types.Evidence, len(priorities))
+	for i, priority := range priorities {
+		evs[i] = newDuplicateVoteEvidence("mychain", privKey, 0, int64(i+1), 0)
+		assert.True(store.AddNewEvidence(evs[i], priority))
+	}
+
+	// highest priority first
+	priorityEvs := store.PriorityEvidence()
+	assert.Equal(3, len(priorityEvs))
+	for i, j := range []int{1, 0, 2} {
+		assert.True(evs[j].Equal(priorityEvs[i]))
+	}
+}
@@ -54,7 +54,7 @@ func (p *provider) StoreCommit(_ lite.FullCommit) error { return nil }
 // TODO: improve when the rpc interface supports more functionality
 func (p *provider) GetByHash(hash []byte) (lite.FullCommit, error) {
 	var fc lite.FullCommit
-	vals, err := p.node.Validators(nil)
+	vals, err := p.node.Validators(nil, 0, 0, "")
 	// if we get no validators, or a different height, return an error
 	if err != nil {
 		return fc, err
@@ -118,7 +118,7 @@ func (p *provider) seedFromCommit(commit *ctypes.ResultCommit) (fc lite.FullComm
 	fc.Commit = CommitFromResult(commit)
 
 	// now get the proper validators
-	vals, err := p.node.Validators(&commit.Header.Height)
+	vals, err := p.node.Validators(&commit.Header.Height, 0, 0, "")
 	if err != nil {
 		return fc, err
 	}
@@ -38,7 +38,7 @@ follow any changes to the validator set.
 Certifiers
 
 A Certifier validates a new Commit given the currently known
-state. There are three different types of Certifiers exposed,
+state. There are four different types of Certifiers exposed,
 each one building on the last one, with additional complexity.
 
 Static - given the validator set upon initialization. Verifies
@@ -57,6 +57,18 @@ Certify fails as the validator set has changed, then it
 attempts to find a FullCommit and Update to that header.
 To get these FullCommits, it makes use of a Provider.
 
+Verifier - this tracks the trusted validator sets itself, and
+verifies the headers from the closest trusted one below them.
+If their validators changed, it gets the headers in between from
+a Provider and either verifies them all, each signed by more than
+2/3 of the validators of the previous one (sequential), or skips
+to the new header if more than a trust level (1/3 by default) of
+the trusted validators signed it, bisecting the heights in between
+when they didn't. With a files.Provider as trusted provider, a
+Verifier resumes from its trusted state after a restart
+(NewVerifier), after being initialized once from a root FullCommit
+obtained by other means (InitVerifier).
+
 Providers
 
 A Provider allows us to store and retrieve the FullCommits,
@@ -1,12 +1,24 @@
 package proxy
 
 import (
+	"bytes"
+
+	"github.com/pkg/errors"
+
 	"github.com/tendermint/tendermint/lite"
 	certclient "github.com/tendermint/tendermint/lite/client"
+	certerr "github.com/tendermint/tendermint/lite/errors"
 	"github.com/tendermint/tendermint/lite/files"
 )
 
-func GetCertifier(chainID, rootDir, nodeAddr string) (*lite.Inquiring, error) {
+// GetCertifier returns a Verifier of the chain getting the headers from the
+// node at nodeAddr, with the trusted FullCommits stored in rootDir.
+//
+// With no trustHash, it resumes from the FullCommits trusted by a previous run.
+// Otherwise, the root of trust is the block of the node at trustHeight, if its
+// hash is trustHash, which must be obtained by other means (eg. from a block
+// explorer or a friend running a full node), as the node could be lying.
+func GetCertifier(chainID, rootDir, nodeAddr string, trustHeight int64, trustHash []byte) (*lite.Verifier, error) {
 	trust := lite.NewCacheProvider(
 		lite.NewMemStoreProvider(),
 		files.NewProvider(rootDir),
@@ -14,17 +26,23 @@ func GetCertifier(chainID, rootDir, nodeAddr string) (*lite.Inquiring, error) {
 
 	source := certclient.NewHTTPProvider(nodeAddr)
 
-	// XXX: total insecure hack to avoid `init`
-	fc, err := source.LatestCommit()
-	/* XXX
-	// this gets the most recent verified commit
-	fc, err := trust.LatestCommit()
-	if certerr.IsCommitNotFoundErr(err) {
-		return nil, errors.New("Please run init first to establish a root of trust")
-	}*/
+	if len(trustHash) == 0 {
+		cert, err := lite.NewVerifier(chainID, trust, source)
+		if err != nil {
+			return nil, errors.Wrap(err, "Please give the trust height and hash first to establish a root of trust")
+		}
+		return cert, nil
+	}
+
+	fc, err := source.GetByHeight(trustHeight)
 	if err != nil {
 		return nil, err
 	}
-	cert := lite.NewInquiring(chainID, fc, trust, source)
-	return cert, nil
+	if fc.Height() != trustHeight {
+		return nil, certerr.ErrHeightMismatch(trustHeight, fc.Height())
+	}
+	if hash := fc.Header.Hash(); !bytes.Equal(hash, trustHash) {
+		return nil, errors.Errorf("Block %d of the node has hash %X, not the trusted %X", trustHeight, hash, trustHash)
+	}
+	return lite.InitVerifier(chainID, fc, trust, source)
 }
@@ -3,10 +3,12 @@ package proxy
 import (
 	"net/http"
 
+	"github.com/tendermint/go-wire/data"
 	"github.com/tendermint/tmlibs/log"
 
 	rpcclient "github.com/tendermint/tendermint/rpc/client"
 	"github.com/tendermint/tendermint/rpc/core"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 	rpc "github.com/tendermint/tendermint/rpc/lib/server"
 )
 
@@ -40,22 +42,32 @@ func StartProxy(c rpcclient.Client, listenAddr string, logger log.Logger) error
 //
 // if we want security, the client must implement it as a secure client
 func RPCRoutes(c rpcclient.Client) map[string]*rpc.RPCFunc {
+	// abci_query takes the same args as on a fullnode
+	abciQuery := func(path string, data data.Bytes, height int64, trusted bool) (*ctypes.ResultABCIQuery, error) {
+		return c.ABCIQueryWithOptions(path, data, rpcclient.ABCIQueryOptions{Height: height, Trusted: trusted})
+	}
 
 	return map[string]*rpc.RPCFunc{
 		// Subscribe/unsubscribe are reserved for websocket events.
 		// We can just use the core tendermint impl, which uses the
 		// EventSwitch we registered in NewWebsocketManager above
-		"subscribe":   rpc.NewWSRPCFunc(core.Subscribe, "query"),
+		"subscribe":   rpc.NewWSRPCFunc(core.Subscribe, "query,schema_version,from_height"),
 		"unsubscribe": rpc.NewWSRPCFunc(core.Unsubscribe, "query"),
 
 		// info API
-		"status":     rpc.NewRPCFunc(c.Status, ""),
-		"blockchain": rpc.NewRPCFunc(c.BlockchainInfo, "minHeight,maxHeight"),
-		"genesis":    rpc.NewRPCFunc(c.Genesis, ""),
-		"block":      rpc.NewRPCFunc(c.Block, "height"),
-		"commit":     rpc.NewRPCFunc(c.Commit, "height"),
-		"tx":         rpc.NewRPCFunc(c.Tx, "hash,prove"),
-		"validators": rpc.NewRPCFunc(c.Validators, ""),
+		"status":       rpc.NewRPCFunc(c.Status, ""),
+		"blockchain":   rpc.NewRPCFunc(c.BlockchainInfo, "minHeight,maxHeight,page,per_page,order_by"),
+		"headers":      rpc.NewRPCFunc(c.Headers, "from,to"),
+		"genesis":      rpc.NewRPCFunc(c.Genesis, ""),
+		"block":        rpc.NewRPCFunc(c.Block, "height"),
+		"commit":       rpc.NewRPCFunc(c.Commit, "height"),
+		"tx":           rpc.NewRPCFunc(c.Tx, "hash,prove"),
+		"tx_search":    rpc.NewRPCFunc(c.TxSearch, "query,prove,page,per_page,order_by"),
+		"block_search": rpc.NewRPCFunc(c.BlockSearch, "query"),
+		"validators":   rpc.NewRPCFunc(c.Validators, "height,page,per_page,order_by"),
+
+		// verified by the proxy itself, from what the caller supplies
+		"verify_commit": rpc.NewRPCFunc(core.VerifyCommit, "header,commit,validators"),
 
 		// broadcast API
 		"broadcast_tx_commit": rpc.NewRPCFunc(c.BroadcastTxCommit, "tx"),
@@ -63,7 +75,7 @@ func RPCRoutes(c rpcclient.Client) map[string]*rpc.RPCFunc {
 		"broadcast_tx_async":  rpc.NewRPCFunc(c.BroadcastTxAsync, "tx"),
 
 		// abci API
-		"abci_query": rpc.NewRPCFunc(c.ABCIQuery, "path,data,prove"),
+		"abci_query": rpc.NewRPCFunc(abciQuery, "path,data,height,prove"),
 		"abci_info":  rpc.NewRPCFunc(c.ABCIInfo, ""),
 	}
 }
@@ -1,12 +1,18 @@
 package proxy
 
 import (
+	"bytes"
+
+	"github.com/pkg/errors"
+
 	"github.com/tendermint/go-wire/data"
 
 	"github.com/tendermint/tendermint/lite"
 	certclient "github.com/tendermint/tendermint/lite/client"
+	certerr "github.com/tendermint/tendermint/lite/errors"
 	rpcclient "github.com/tendermint/tendermint/rpc/client"
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	"github.com/tendermint/tendermint/types"
 )
 
 var _ rpcclient.Client = Wrapper{}
@@ -15,14 +21,14 @@ var _ rpcclient.Client = Wrapper{}
 // provable before passing it along. Allows you to make any rpcclient fully secure.
 type Wrapper struct {
 	rpcclient.Client
-	cert *lite.Inquiring
+	cert lite.Certifier
 }
 
 // SecureClient uses a given certifier to wrap an connection to an untrusted
 // host and return a cryptographically secure rpc client.
 //
 // If it is wrapping an HTTP rpcclient, it will also wrap the websocket interface
-func SecureClient(c rpcclient.Client, cert *lite.Inquiring) Wrapper {
+func SecureClient(c rpcclient.Client, cert lite.Certifier) Wrapper {
 	wrap := Wrapper{c, cert}
 	// TODO: no longer possible as no more such interface exposed....
 	// if we wrap http client, then we can swap out the event switch to filter
@@ -33,9 +39,15 @@ func SecureClient(c rpcclient.Client, cert *lite.Inquiring) Wrapper {
 	return wrap
 }
 
-// ABCIQueryWithOptions exposes all options for the ABCI query and verifies the returned proof
+// ABCIQueryWithOptions exposes all options for the ABCI query and verifies the returned proof.
+// The proof is always requested, whatever opts.Trusted.
 func (w Wrapper) ABCIQueryWithOptions(path string, data data.Bytes, opts rpcclient.ABCIQueryOptions) (*ctypes.ResultABCIQuery, error) {
+	opts.Trusted = false
 	res, _, err := GetWithProofOptions(path, data, opts, w.Client, w.cert)
+	if res != nil && IsNoDataErr(err) {
+		// the absence of the key is proven
+		return res, nil
+	}
 	return res, err
 }
 
@@ -50,40 +62,100 @@ func (w Wrapper) Tx(hash []byte, prove bool) (*ctypes.ResultTx, error) {
 	if !prove || err != nil {
 		return res, err
 	}
-	h := int64(res.Height)
-	check, err := GetCertifiedCommit(h, w.Client, w.cert)
+	if !bytes.Equal(res.Tx.Hash(), hash) {
+		return nil, errors.Errorf("Tx doesn't have the hash %X", hash)
+	}
+	if err := w.validateTx(res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// TxSearch queries for the txs matching the query and verifies their proofs
+// if they were requested
+func (w Wrapper) TxSearch(query string, prove bool, page, perPage int, orderBy string) (*ctypes.ResultTxSearch, error) {
+	result, err := w.Client.TxSearch(query, prove, page, perPage, orderBy)
+	if !prove || err != nil {
+		return result, err
+	}
+	for _, res := range result.Txs {
+		if err := w.validateTx(res); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// validateTx verifies the proof of the tx against the certified header of its height.
+func (w Wrapper) validateTx(res *ctypes.ResultTx) error {
+	if res.Proof == nil {
+		return errors.New("No proof returned")
+	}
+	if !bytes.Equal(res.Proof.Data, res.Tx) || res.Proof.Index != int(res.Index) {
+		return errors.New("Proof is not of the tx")
+	}
+	check, err := GetCertifiedCommit(res.Height, w.Client, w.cert)
 	if err != nil {
-		return res, err
+		return err
 	}
-	err = res.Proof.Validate(check.Header.DataHash)
-	return res, err
+	return res.Proof.Validate(check.Header.DataHash)
 }
 
 // BlockchainInfo requests a list of headers and verifies them all...
 // Rather expensive.
 //
 // TODO: optimize this if used for anything needing performance
-func (w Wrapper) BlockchainInfo(minHeight, maxHeight int64) (*ctypes.ResultBlockchainInfo, error) {
-	r, err := w.Client.BlockchainInfo(minHeight, maxHeight)
+func (w Wrapper) BlockchainInfo(minHeight, maxHeight int64, page, perPage int, orderBy string) (*ctypes.ResultBlockchainInfo, error) {
+	r, err := w.Client.BlockchainInfo(minHeight, maxHeight, page, perPage, orderBy)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.validateBlockMetas(r.BlockMetas); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Headers requests a range of headers and verifies them all, like BlockchainInfo.
+func (w Wrapper) Headers(from, to int64) (*ctypes.ResultHeaders, error) {
+	r, err := w.Client.Headers(from, to)
 	if err != nil {
 		return nil, err
 	}
+	if err := w.validateBlockMetas(r.BlockMetas); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
 
-	// go and verify every blockmeta in the result....
-	for _, meta := range r.BlockMetas {
+// BlockSearch queries for the blocks matching the query and verifies their
+// headers, like BlockchainInfo.
+func (w Wrapper) BlockSearch(query string) (*ctypes.ResultBlockSearch, error) {
+	r, err := w.Client.BlockSearch(query)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.validateBlockMetas(r.BlockMetas); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// validateBlockMetas verifies every blockmeta against the certified header of its height.
+func (w Wrapper) validateBlockMetas(metas []*types.BlockMeta) error {
+	for _, meta := range metas {
 		// get a checkpoint to verify from
 		c, err := w.Commit(&meta.Header.Height)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		check := certclient.CommitFromResult(c)
 		err = ValidateBlockMeta(meta, check)
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
-
-	return r, nil
+	return nil
 }
 
 // Block returns an entire block and verifies all signatures
@@ -93,7 +165,7 @@ func (w Wrapper) Block(height *int64) (*ctypes.ResultBlock, error) {
 		return nil, err
 	}
 	// get a checkpoint to verify from
-	c, err := w.Commit(height)
+	c, err := w.Commit(&r.Block.Height)
 	if err != nil {
 		return nil, err
 	}
@@ -111,18 +183,52 @@ func (w Wrapper) Block(height *int64) (*ctypes.ResultBlock, error) {
 	return r, nil
 }
 
+// Validators returns the validator set of a height, or of the latest block,
+// and verifies it is the one of the certified header. Only the whole set can
+// be verified, so perPage must be 0.
+func (w Wrapper) Validators(height *int64, page, perPage int, orderBy string) (*ctypes.ResultValidators, error) {
+	if height == nil {
+		// the node returns the validators of the next height, which has no header yet
+		status, err := w.Client.Status()
+		if err != nil {
+			return nil, err
+		}
+		height = &status.LatestBlockHeight
+	}
+	r, err := w.Client.Validators(height, page, perPage, orderBy)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.Validators) != r.Total {
+		return nil, errors.Errorf("Can't verify a page of %d of the %d validators", len(r.Validators), r.Total)
+	}
+	c, err := w.Commit(height)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(types.NewValidatorSet(r.Validators).Hash(), c.Header.ValidatorsHash) {
+		return nil, certerr.ErrValidatorsChanged()
+	}
+	return r, nil
+}
+
 // Commit downloads the Commit and certifies it with the lite.
 //
 // This is the foundation for all other verification in this module
 func (w Wrapper) Commit(height *int64) (*ctypes.ResultCommit, error) {
-	rpcclient.WaitForHeight(w.Client, *height, nil)
+	if height != nil {
+		rpcclient.WaitForHeight(w.Client, *height, nil)
+	}
 	r, err := w.Client.Commit(height)
 	// if we got it, then certify it
 	if err == nil {
 		check := certclient.CommitFromResult(r)
 		err = w.cert.Certify(check)
 	}
-	return r, err
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
 }
 
 // // WrappedSwitch creates a websocket connection that auto-verifies any info
@@ -0,0 +1,56 @@
+package proxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/lite"
+	certclient "github.com/tendermint/tendermint/lite/client"
+	"github.com/tendermint/tendermint/rpc/client"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestWrapperVerifies(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	cl := client.NewLocal(node)
+	client.WaitForHeight(cl, 1, nil)
+	chainID := node.GenesisDoc().ChainID
+
+	source := certclient.NewProvider(cl)
+	root, err := source.GetByHeight(1)
+	require.NoError(err, "%+v", err)
+	cert, err := lite.InitVerifier(chainID, root, lite.NewMemStoreProvider(), source)
+	require.NoError(err, "%+v", err)
+	w := SecureClient(cl, cert)
+
+	tx := dummyTx([]byte("wrapped-key"), []byte("wrapped-value"))
+	br, err := w.BroadcastTxCommit(tx)
+	require.NoError(err, "%+v", err)
+	require.EqualValues(0, br.DeliverTx.Code)
+
+	res, err := w.Tx(types.Tx(tx).Hash(), true)
+	require.NoError(err, "%+v", err)
+	assert.EqualValues(tx, res.Tx)
+
+	block, err := w.Block(nil)
+	require.NoError(err, "%+v", err)
+	assert.True(block.Block.Height >= br.Height)
+
+	headers, err := w.Headers(1, br.Height)
+	require.NoError(err, "%+v", err)
+	assert.Len(headers.BlockMetas, int(br.Height))
+
+	vals, err := w.Validators(nil, 0, 0, "")
+	require.NoError(err, "%+v", err)
+	assert.Len(vals.Validators, 1)
+
+	// the headers of another chain are not certified
+	w = SecureClient(cl, lite.NewStatic("other-chain", root.Validators))
+	_, err = w.Block(&br.Height)
+	assert.Error(err)
+	_, err = w.Tx(types.Tx(tx).Hash(), true)
+	assert.Error(err)
+}
@@ -0,0 +1,321 @@
+package lite
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/tendermint/tendermint/types"
+
+	liteErr "github.com/tendermint/tendermint/lite/errors"
+)
+
+// TrustLevel is the fraction of the voting power of the trusted validators
+// which must have signed a header for the Verifier to skip to it.
+type TrustLevel struct {
+	Numerator   int64 `json:"numerator"`
+	Denominator int64 `json:"denominator"`
+}
+
+// DefaultTrustLevel is more than 1/3 of the trusted power: at least one honest
+// validator signed the header, if less than 1/3 of them are byzantine.
+var DefaultTrustLevel = TrustLevel{1, 3}
+
+// sequentialTrustLevel is the 2/3 of the trusted power of a sequential verification.
+var sequentialTrustLevel = TrustLevel{2, 3}
+
+// Validate returns an error unless the trust level is in [1/3, 1).
+func (tl TrustLevel) Validate() error {
+	if tl.Denominator <= 0 || tl.Numerator*3 < tl.Denominator || tl.Numerator >= tl.Denominator {
+		return errors.Errorf("Trust level must be in [1/3, 1), got %v", tl)
+	}
+	return nil
+}
+
+func (tl TrustLevel) String() string {
+	return fmt.Sprintf("%d/%d", tl.Numerator, tl.Denominator)
+}
+
+var _ Certifier = &Verifier{}
+
+// Verifier certifies the headers of a chain from the validator sets it trusts,
+// starting from a root FullCommit verified by other means.
+//
+// A header signed by a trusted validator set is certified directly. Otherwise,
+// the Verifier gets the FullCommits of the headers in between from the source,
+// and verifies the validator set changes, either:
+//
+//  - sequentially: every header up to the new one, each signed by more than
+//    2/3 of the validators of the previous one
+//  - skipping (by default): straight to the new header if more than the trust
+//    level of the trusted validators signed it, or bisecting the heights in
+//    between until it finds headers they did
+//
+// Every FullCommit verified is stored in the trusted provider, so a Verifier
+// with a files.Provider resumes from its last trusted state after a restart
+// (see NewVerifier).
+//
+// The trusted validators are only trusted for the trusting period, as once
+// unbonded they could sign anything without being punished.
+type Verifier struct {
+	chainID        string
+	trustLevel     TrustLevel
+	sequential     bool
+	trustingPeriod time.Duration
+
+	// These are only properly validated data, from local system
+	trusted Provider
+	// This is a source of new info, like a node rpc, or other import method
+	source Provider
+
+	mtx sync.Mutex // one verification at a time
+}
+
+// InitVerifier returns a Verifier of the chain trusting the root FullCommit,
+// which must be obtained by other means, eg. from the genesis file or a friend.
+// The root is stored in the trusted provider.
+func InitVerifier(chainID string, root FullCommit, trusted, source Provider) (*Verifier, error) {
+	if err := root.ValidateBasic(chainID); err != nil {
+		return nil, err
+	}
+	if root.Validators == nil || !bytes.Equal(root.ValidatorsHash(), root.Validators.Hash()) {
+		return nil, liteErr.ErrValidatorsChanged()
+	}
+	commit := root.Commit.Commit
+	if err := root.Validators.VerifyCommit(chainID, commit.BlockID, root.Height(), commit); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if err := trusted.StoreCommit(root); err != nil {
+		return nil, err
+	}
+	return newVerifier(chainID, trusted, source), nil
+}
+
+// NewVerifier returns a Verifier of the chain resuming from the FullCommits
+// stored in the trusted provider, eg. a files.Provider, by a previous Verifier.
+// It returns an error if there are none: see InitVerifier.
+func NewVerifier(chainID string, trusted, source Provider) (*Verifier, error) {
+	latest, err := trusted.LatestCommit()
+	if err != nil {
+		return nil, errors.Wrap(err, "No trusted commit to resume from")
+	}
+	if latest.Header == nil || latest.Header.ChainID != chainID {
+		return nil, errors.Errorf("Trusted commits are not of chain '%s'", chainID)
+	}
+	return newVerifier(chainID, trusted, source), nil
+}
+
+func newVerifier(chainID string, trusted, source Provider) *Verifier {
+	return &Verifier{
+		chainID:    chainID,
+		trustLevel: DefaultTrustLevel,
+		trusted:    trusted,
+		source:     source,
+	}
+}
+
+// SetTrustLevel sets the fraction of the trusted voting power needed to skip
+// to a header. It must be in [1/3, 1).
+// NOTE: not thread safe
+func (v *Verifier) SetTrustLevel(trustLevel TrustLevel) error {
+	if err := trustLevel.Validate(); err != nil {
+		return err
+	}
+	v.trustLevel = trustLevel
+	return nil
+}
+
+// SetSequential makes the Verifier verify every header up to the new one,
+// rather than skipping.
+// NOTE: not thread safe
+func (v *Verifier) SetSequential(sequential bool) {
+	v.sequential = sequential
+}
+
+// SetTrustingPeriod sets how long after their header the trusted validators
+// are trusted, which must be less than the unbonding period. 0 disables it.
+// NOTE: not thread safe
+func (v *Verifier) SetTrustingPeriod(period time.Duration) {
+	v.trustingPeriod = period
+}
+
+// ChainID returns the chain id.
+func (v *Verifier) ChainID() string {
+	return v.chainID
+}
+
+// LastTrusted returns the latest FullCommit verified.
+func (v *Verifier) LastTrusted() (FullCommit, error) {
+	return v.trusted.LatestCommit()
+}
+
+// Validators returns the validator set of the latest FullCommit verified.
+func (v *Verifier) Validators() (*types.ValidatorSet, error) {
+	latest, err := v.LastTrusted()
+	if err != nil {
+		return nil, err
+	}
+	return latest.Validators, nil
+}
+
+// Certify implements Certifier. It verifies the commit from the closest
+// trusted FullCommit below it, and stores it as trusted.
+func (v *Verifier) Certify(commit Commit) error {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	if err := commit.ValidateBasic(v.chainID); err != nil {
+		return err
+	}
+	h := commit.Height()
+	trusted, err := v.closestTrusted(h)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(commit.ValidatorsHash(), trusted.ValidatorsHash()) {
+		trusted, err = v.verifyTo(trusted, h)
+		if err != nil {
+			return err
+		}
+	}
+	if err := NewStatic(v.chainID, trusted.Validators).Certify(commit); err != nil {
+		return err
+	}
+	return v.trusted.StoreCommit(NewFullCommit(commit, trusted.Validators))
+}
+
+// VerifyHeight gets the FullCommit at height h from the source, verifies it,
+// and stores it as trusted.
+func (v *Verifier) VerifyHeight(h int64) (FullCommit, error) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	trusted, err := v.closestTrusted(h)
+	if err != nil {
+		return FullCommit{}, err
+	}
+	if trusted.Height() == h {
+		return trusted, nil
+	}
+	trusted, err = v.verifyTo(trusted, h)
+	if err != nil {
+		return FullCommit{}, err
+	}
+	if trusted.Height() != h {
+		return FullCommit{}, liteErr.ErrCommitNotFound()
+	}
+	return trusted, nil
+}
+
+// closestTrusted returns the trusted FullCommit with the highest height <= h,
+// unless its trusting period expired.
+func (v *Verifier) closestTrusted(h int64) (FullCommit, error) {
+	trusted, err := v.trusted.GetByHeight(h)
+	if err != nil {
+		return FullCommit{}, err
+	}
+	if v.trustingPeriod > 0 {
+		if expiry := trusted.Header.Time.Add(v.trustingPeriod); time.Now().After(expiry) {
+			return FullCommit{}, errors.Errorf("Trusted header %d expired at %v, verify a new one by other means",
+				trusted.Height(), expiry)
+		}
+	}
+	return trusted, nil
+}
+
+// verifyTo verifies the validator set changes from trusted up to height h,
+// storing the FullCommits verified, and returns the last one.
+func (v *Verifier) verifyTo(trusted FullCommit, h int64) (FullCommit, error) {
+	if !v.sequential {
+		target, err := v.source.GetByHeight(h)
+		if err != nil {
+			return trusted, err
+		}
+		return v.bisect(trusted, target)
+	}
+
+	for height := trusted.Height() + 1; height <= h; height++ {
+		fc, err := v.source.GetByHeight(height)
+		if err != nil {
+			return trusted, err
+		}
+		if fc.Height() != height {
+			// the source must have every header
+			return trusted, liteErr.ErrCommitNotFound()
+		}
+		if err := v.verifyFullCommit(trusted, fc, sequentialTrustLevel); err != nil {
+			return trusted, err
+		}
+		// only the changes of validators and the last one are worth keeping
+		if height == h || !bytes.Equal(fc.ValidatorsHash(), trusted.ValidatorsHash()) {
+			if err := v.trusted.StoreCommit(fc); err != nil {
+				return trusted, err
+			}
+		}
+		trusted = fc
+	}
+	return trusted, nil
+}
+
+// bisect verifies target from trusted, or else the headers in between,
+// recursively, until it finds a path of validator sets signing for the next.
+func (v *Verifier) bisect(trusted, target FullCommit) (FullCommit, error) {
+	err := v.verifyFullCommit(trusted, target, v.trustLevel)
+	if err == nil {
+		return target, v.trusted.StoreCommit(target)
+	}
+	if !liteErr.IsTooMuchChangeErr(err) {
+		return trusted, err
+	}
+
+	mid := (trusted.Height() + target.Height()) / 2
+	if mid <= trusted.Height() {
+		return trusted, liteErr.ErrNoPathFound()
+	}
+	midFC, err := v.source.GetByHeight(mid)
+	if err != nil {
+		return trusted, err
+	}
+	if midFC.Height() <= trusted.Height() {
+		return trusted, liteErr.ErrNoPathFound()
+	}
+	trusted, err = v.bisect(trusted, midFC)
+	if err != nil {
+		return trusted, err
+	}
+	return v.bisect(trusted, target)
+}
+
+// verifyFullCommit verifies fc is signed by more than 2/3 of its validators, and
+// more than the trustLevel of the trusted ones. It returns ErrTooMuchChange for
+// the latter only.
+func (v *Verifier) verifyFullCommit(trusted, fc FullCommit, trustLevel TrustLevel) error {
+	h := fc.Height()
+	if h <= trusted.Height() {
+		return liteErr.ErrPastTime()
+	}
+	if err := fc.ValidateBasic(v.chainID); err != nil {
+		return err
+	}
+	if fc.Validators == nil || !bytes.Equal(fc.ValidatorsHash(), fc.Validators.Hash()) {
+		return liteErr.ErrValidatorsChanged()
+	}
+	commit := fc.Commit.Commit
+	if err := fc.Validators.VerifyCommit(v.chainID, commit.BlockID, h, commit); err != nil {
+		return errors.WithStack(err)
+	}
+	if bytes.Equal(fc.ValidatorsHash(), trusted.ValidatorsHash()) {
+		return nil
+	}
+
+	err := trusted.Validators.VerifyCommitTrusting(fc.Validators, v.chainID, commit.BlockID, h, commit,
+		trustLevel.Numerator, trustLevel.Denominator)
+	if err != nil {
+		return liteErr.ErrTooMuchChange()
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package lite_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/lite"
+	"github.com/tendermint/tendermint/lite/errors"
+	"github.com/tendermint/tendermint/lite/files"
+)
+
+// genRotatingCommits returns the commits of heights 1 to count, one of the 4
+// validators being replaced at each height.
+func genRotatingCommits(chainID string, count int) []lite.FullCommit {
+	keys := lite.GenValKeys(4)
+	commits := make([]lite.FullCommit, count)
+	for i := range commits {
+		h := int64(i + 1)
+		if i > 0 {
+			keys = keys.Change(int(h % 4))
+		}
+		vals := keys.ToValidators(10, 0)
+		appHash := []byte(fmt.Sprintf("h=%d", h))
+		commits[i] = keys.GenFullCommit(chainID, h, nil, vals, appHash, 0, len(keys))
+	}
+	return commits
+}
+
+func TestVerifierSkipping(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	chainID := "verifier-test"
+	commits := genRotatingCommits(chainID, 16)
+	source := lite.NewMemStoreProvider()
+	for _, fc := range commits {
+		require.Nil(source.StoreCommit(fc))
+	}
+
+	trusted := lite.NewMemStoreProvider()
+	verifier, err := lite.InitVerifier(chainID, commits[0], trusted, source)
+	require.Nil(err, "%+v", err)
+
+	// 2 of the 4 validators of height 1 signed height 3
+	require.Nil(verifier.Certify(commits[2].Commit))
+	// but not enough of them signed height 16, reached by bisecting
+	require.Nil(verifier.Certify(commits[15].Commit))
+	last, err := verifier.LastTrusted()
+	require.Nil(err)
+	assert.EqualValues(16, last.Height())
+
+	// the heights in between were verified by the way
+	fc, err := trusted.GetByHeight(15)
+	require.Nil(err)
+	assert.True(fc.Height() > 3 && fc.Height() < 16, "%d", fc.Height())
+
+	// a commit signed by other validators is rejected
+	other := lite.GenValKeys(4)
+	bad := other.GenCommit(chainID, 20, nil, other.ToValidators(10, 0), []byte("bad"), 0, 4)
+	assert.NotNil(verifier.Certify(bad))
+
+	// without the headers in between, there is no way to bisect
+	sparse := lite.NewMemStoreProvider()
+	require.Nil(sparse.StoreCommit(commits[0]))
+	require.Nil(sparse.StoreCommit(commits[15]))
+	verifier, err = lite.InitVerifier(chainID, commits[0], lite.NewMemStoreProvider(), sparse)
+	require.Nil(err)
+	require.Nil(verifier.SetTrustLevel(lite.TrustLevel{2, 3}))
+	err = verifier.Certify(commits[15].Commit)
+	assert.True(errors.IsNoPathFoundErr(err), "%+v", err)
+}
+
+func TestVerifierSequential(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	chainID := "verifier-test"
+	commits := genRotatingCommits(chainID, 10)
+	source := lite.NewMemStoreProvider()
+	for _, fc := range commits[:5] {
+		require.Nil(source.StoreCommit(fc))
+	}
+
+	trusted := lite.NewMemStoreProvider()
+	verifier, err := lite.InitVerifier(chainID, commits[0], trusted, source)
+	require.Nil(err)
+	verifier.SetSequential(true)
+
+	fc, err := verifier.VerifyHeight(5)
+	require.Nil(err, "%+v", err)
+	assert.EqualValues(5, fc.Height())
+
+	// every header up to the new one is needed
+	for _, fc := range commits[6:] {
+		require.Nil(source.StoreCommit(fc))
+	}
+	_, err = verifier.VerifyHeight(10)
+	assert.True(errors.IsCommitNotFoundErr(err), "%+v", err)
+	require.Nil(source.StoreCommit(commits[5]))
+	fc, err = verifier.VerifyHeight(10)
+	require.Nil(err, "%+v", err)
+	assert.EqualValues(10, fc.Height())
+}
+
+func TestVerifierResume(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	dir, err := ioutil.TempDir("", "verifier-test")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+
+	chainID := "verifier-test"
+	commits := genRotatingCommits(chainID, 8)
+	source := lite.NewMemStoreProvider()
+	for _, fc := range commits {
+		require.Nil(source.StoreCommit(fc))
+	}
+
+	// nothing to resume from yet
+	_, err = lite.NewVerifier(chainID, files.NewProvider(dir), source)
+	assert.NotNil(err)
+
+	verifier, err := lite.InitVerifier(chainID, commits[0], files.NewProvider(dir), source)
+	require.Nil(err)
+	_, err = verifier.VerifyHeight(6)
+	require.Nil(err, "%+v", err)
+
+	// the trusted state is read back from disk
+	_, err = lite.NewVerifier("other-chain", files.NewProvider(dir), source)
+	assert.NotNil(err)
+	verifier, err = lite.NewVerifier(chainID, files.NewProvider(dir), source)
+	require.Nil(err)
+	last, err := verifier.LastTrusted()
+	require.Nil(err)
+	assert.EqualValues(6, last.Height())
+	assert.Nil(verifier.Certify(commits[7].Commit))
+
+	// until the trusted headers expire
+	verifier.SetTrustingPeriod(time.Nanosecond)
+	assert.NotNil(verifier.Certify(commits[7].Commit))
+}
+
+func TestTrustLevel(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(lite.DefaultTrustLevel.Validate())
+	assert.Nil(lite.TrustLevel{2, 3}.Validate())
+	assert.NotNil(lite.TrustLevel{1, 1}.Validate())
+	assert.NotNil(lite.TrustLevel{1, 4}.Validate())
+	assert.NotNil(lite.TrustLevel{4, 3}.Validate())
+	assert.NotNil(lite.TrustLevel{1, 0}.Validate())
+}
@@ -0,0 +1,284 @@
+package mempool
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+/*
+
+The cache holds the txs seen recently, so a tx sent again, eg. by another
+peer, is rejected without a CheckTx. Once it holds MempoolConfig.CacheSize txs,
+the oldest one is evicted to make room for a new one:
+with the "fifo" policy it's the one seen first,
+with the "lru" policy it's the one seen last the longest time ago.
+With MempoolConfig.CacheTTL set, txs are also evicted that long after being
+seen (first or last, depending on the policy).
+
+With MempoolConfig.CachePath set, the cache is saved on stop with SaveCache()
+and loaded on restart with LoadCache(), one "<unix nano time> <hex tx>"
+line per tx, from the oldest to the newest.
+
+*/
+
+// Cache policies, see MempoolConfig.CachePolicy
+const (
+	// CachePolicyFIFO evicts the tx seen first
+	CachePolicyFIFO = "fifo"
+	// CachePolicyLRU evicts the tx seen last the longest time ago
+	CachePolicyLRU = "lru"
+)
+
+type cacheEntry struct {
+	tx   types.Tx
+	seen time.Time
+}
+
+// txCache maintains a cache of transactions.
+type txCache struct {
+	mtx  sync.Mutex
+	size int
+	lru  bool
+	ttl  time.Duration // 0 for none
+	map_ map[string]*list.Element
+	list *list.List // of *cacheEntry, to remove oldest tx when cache gets too big
+}
+
+// newTxCache returns a new txCache.
+func newTxCache(cacheSize int, policy string, ttl time.Duration) *txCache {
+	return &txCache{
+		size: cacheSize,
+		lru:  policy == CachePolicyLRU,
+		ttl:  ttl,
+		map_: make(map[string]*list.Element, cacheSize),
+		list: list.New(),
+	}
+}
+
+// Reset resets the txCache to empty.
+func (cache *txCache) Reset() {
+	cache.mtx.Lock()
+	cache.map_ = make(map[string]*list.Element, cache.size)
+	cache.list.Init()
+	cache.mtx.Unlock()
+}
+
+// Size returns the number of txs in the cache, expired or not.
+func (cache *txCache) Size() int {
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+	return cache.list.Len()
+}
+
+// Exists returns true if the given tx is cached.
+func (cache *txCache) Exists(tx types.Tx) bool {
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+	return cache.get(tx, time.Now()) != nil
+}
+
+// Push adds the given tx to the txCache. It returns false if tx is already in the cache.
+// With the "lru" policy, it's then marked as seen last.
+func (cache *txCache) Push(tx types.Tx) bool {
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+
+	now := time.Now()
+	if e := cache.get(tx, now); e != nil {
+		if cache.lru {
+			e.Value.(*cacheEntry).seen = now
+			cache.list.MoveToBack(e)
+		}
+		return false
+	}
+	cache.push(tx, now)
+	return true
+}
+
+// Remove removes the given tx from the cache.
+func (cache *txCache) Remove(tx types.Tx) {
+	cache.mtx.Lock()
+	if e, ok := cache.map_[string(tx)]; ok {
+		cache.remove(e)
+	}
+	cache.mtx.Unlock()
+}
+
+// get returns the element of the tx, or nil if it's not cached or expired.
+// NOTE: unsafe; Lock/Unlock must be managed by caller
+func (cache *txCache) get(tx types.Tx, now time.Time) *list.Element {
+	e, ok := cache.map_[string(tx)]
+	if !ok {
+		return nil
+	}
+	if cache.expired(e.Value.(*cacheEntry), now) {
+		cache.remove(e)
+		return nil
+	}
+	return e
+}
+
+// push adds the tx as seen at the given time, which must not be before
+// the time of the newest tx, and evicts the oldest tx if the cache is full.
+// NOTE: unsafe; Lock/Unlock must be managed by caller
+func (cache *txCache) push(tx types.Tx, seen time.Time) {
+	if cache.size <= 0 {
+		return
+	}
+	// drop the expired txs first, they're the oldest ones
+	for e := cache.list.Front(); e != nil && cache.expired(e.Value.(*cacheEntry), seen); e = cache.list.Front() {
+		cache.remove(e)
+	}
+	if cache.list.Len() >= cache.size {
+		cache.remove(cache.list.Front())
+	}
+	cache.map_[string(tx)] = cache.list.PushBack(&cacheEntry{tx, seen})
+}
+
+// NOTE: unsafe; Lock/Unlock must be managed by caller
+func (cache *txCache) remove(e *list.Element) {
+	delete(cache.map_, string(e.Value.(*cacheEntry).tx))
+	cache.list.Remove(e)
+}
+
+func (cache *txCache) expired(entry *cacheEntry, now time.Time) bool {
+	return cache.ttl > 0 && now.Sub(entry.seen) >= cache.ttl
+}
+
+// save writes the txs of the cache that haven't expired to path.
+func (cache *txCache) save(path string) error {
+	tmpPath := path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+
+	cache.mtx.Lock()
+	now := time.Now()
+	for e := cache.list.Front(); e != nil; e = e.Next() {
+		entry := e.Value.(*cacheEntry)
+		if cache.expired(entry, now) {
+			continue
+		}
+		if _, err = fmt.Fprintf(w, "%d %X\n", entry.seen.UnixNano(), []byte(entry.tx)); err != nil {
+			break
+		}
+	}
+	cache.mtx.Unlock()
+
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// load adds the txs saved at path to the cache, skipping the expired ones
+// and the lines that don't decode. It returns the number of txs loaded.
+func (cache *txCache) load(path string) (loaded, skipped int, err error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	} else if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	cache.mtx.Lock()
+	defer cache.mtx.Unlock()
+
+	now := time.Now()
+	var last time.Time
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxWALLineSize+32)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		entry, derr := decodeCacheEntry(line)
+		// the txs must be in order, and not from the future
+		if derr != nil || entry.seen.Before(last) || entry.seen.After(now) {
+			skipped++
+			continue
+		}
+		last = entry.seen
+		if cache.expired(entry, now) {
+			continue
+		}
+		if e, ok := cache.map_[string(entry.tx)]; ok {
+			cache.remove(e)
+		}
+		cache.push(entry.tx, entry.seen)
+		loaded++
+	}
+	return loaded, skipped, scanner.Err()
+}
+
+func decodeCacheEntry(line []byte) (*cacheEntry, error) {
+	i := bytes.IndexByte(line, ' ')
+	if i < 0 {
+		return nil, errors.New("missing time")
+	}
+	nanos, err := strconv.ParseInt(string(line[:i]), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	tx := make([]byte, hex.DecodedLen(len(line)-i-1))
+	if _, err := hex.Decode(tx, line[i+1:]); err != nil {
+		return nil, err
+	}
+	return &cacheEntry{tx, time.Unix(0, nanos)}, nil
+}
+
+// SaveCache saves the cache to MempoolConfig.CachePath, if set,
+// for LoadCache to restore it on restart. It should be called on stop.
+func (mem *Mempool) SaveCache() error {
+	path := mem.config.CacheFile()
+	if path == "" {
+		return nil
+	}
+	if err := mem.cache.save(path); err != nil {
+		return errors.Wrap(err, "Error saving Mempool cache")
+	}
+	return nil
+}
+
+// LoadCache loads the cache saved at MempoolConfig.CachePath, if set.
+// It should be called once, on startup, before the mempool gets new txs.
+func (mem *Mempool) LoadCache() error {
+	path := mem.config.CacheFile()
+	if path == "" {
+		return nil
+	}
+	loaded, skipped, err := mem.cache.load(path)
+	if err != nil {
+		return errors.Wrap(err, "Error loading Mempool cache")
+	}
+	if skipped > 0 {
+		mem.logger.Error("Skipped invalid lines of the Mempool cache", "skipped", skipped)
+	}
+	mem.logger.Info("Loaded Mempool cache", "txs", loaded)
+	return nil
+}
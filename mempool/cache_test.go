@@ -0,0 +1,145 @@
+package mempool
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/abci/example/counter"
+	"github.com/tendermint/tmlibs/log"
+
+	cfg "github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/proxy"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestTxCachePolicies(t *testing.T) {
+	assert := assert.New(t)
+
+	for policy, kept := range map[string]string{
+		CachePolicyFIFO: "b",
+		CachePolicyLRU:  "a",
+	} {
+		cache := newTxCache(2, policy, 0)
+		assert.True(cache.Push(types.Tx("a")), policy)
+		assert.True(cache.Push(types.Tx("b")), policy)
+		// seen again
+		assert.False(cache.Push(types.Tx("a")), policy)
+
+		assert.True(cache.Push(types.Tx("c")), policy)
+		assert.Equal(2, cache.Size(), policy)
+		assert.True(cache.Exists(types.Tx(kept)), policy)
+		assert.True(cache.Exists(types.Tx("c")), policy)
+
+		cache.Remove(types.Tx("c"))
+		assert.False(cache.Exists(types.Tx("c")), policy)
+		assert.Equal(1, cache.Size(), policy)
+	}
+
+	// a cache of size 0 holds nothing
+	cache := newTxCache(0, CachePolicyFIFO, 0)
+	assert.True(cache.Push(types.Tx("a")))
+	assert.True(cache.Push(types.Tx("a")))
+}
+
+func TestTxCacheTTL(t *testing.T) {
+	assert := assert.New(t)
+
+	cache := newTxCache(10, CachePolicyFIFO, time.Minute)
+	for _, tx := range []string{"a", "b", "c"} {
+		cache.Push(types.Tx(tx))
+	}
+	// a and b were seen more than a minute ago
+	ago := time.Now().Add(-2 * time.Minute)
+	for e := cache.list.Front(); e != nil; e = e.Next() {
+		if entry := e.Value.(*cacheEntry); string(entry.tx) != "c" {
+			entry.seen = ago
+		}
+	}
+	assert.False(cache.Exists(types.Tx("a")))
+	assert.Equal(2, cache.Size())
+	assert.True(cache.Exists(types.Tx("c")))
+
+	// expired txs are dropped on push
+	assert.True(cache.Push(types.Tx("d")))
+	assert.Equal(2, cache.Size())
+	assert.True(cache.Push(types.Tx("b")))
+}
+
+func TestTxCacheSaveLoad(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	dir, err := ioutil.TempDir("", "mempool-cache-test")
+	require.Nil(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cache")
+
+	// nothing saved yet
+	cache := newTxCache(10, CachePolicyLRU, time.Minute)
+	loaded, skipped, err := cache.load(path)
+	require.Nil(err)
+	assert.Equal(0, loaded+skipped)
+
+	for _, tx := range []string{"a", "b", "c", "d"} {
+		cache.Push(types.Tx(tx))
+	}
+	cache.Push(types.Tx("a"))
+	cache.list.Front().Value.(*cacheEntry).seen = time.Now().Add(-2 * time.Minute)
+	require.Nil(cache.save(path))
+
+	// b expired, and the order is kept
+	cache = newTxCache(2, CachePolicyLRU, time.Minute)
+	loaded, skipped, err = cache.load(path)
+	require.Nil(err)
+	assert.Equal(3, loaded)
+	assert.Equal(0, skipped)
+	assert.Equal(2, cache.Size())
+	assert.False(cache.Exists(types.Tx("c")))
+	assert.True(cache.Exists(types.Tx("d")))
+	assert.True(cache.Exists(types.Tx("a")))
+
+	// lines that don't decode are skipped
+	require.Nil(ioutil.WriteFile(path, []byte("1 0A\nfoo\n2 XY\n3 0B\n"), 0600))
+	cache = newTxCache(10, CachePolicyFIFO, 0)
+	loaded, skipped, err = cache.load(path)
+	require.Nil(err)
+	assert.Equal(2, loaded)
+	assert.Equal(2, skipped)
+	assert.True(cache.Exists(types.Tx{0x0B}))
+}
+
+func TestMempoolCacheRestart(t *testing.T) {
+	require := require.New(t)
+
+	config := cfg.ResetTestRoot("mempool_test")
+	config.Mempool.CachePath = "data/mempool.cache"
+	app := counter.NewCounterApplication(false)
+	cc := proxy.NewLocalClientCreator(app)
+	newMempool := func() *Mempool {
+		appConnMem, _ := cc.NewABCIClient()
+		require.Nil(appConnMem.Start())
+		mempool := NewMempool(config.Mempool, appConnMem, 0)
+		mempool.SetLogger(log.TestingLogger())
+		require.Nil(mempool.LoadCache())
+		return mempool
+	}
+
+	mempool := newMempool()
+	require.Nil(mempool.CheckTx(types.Tx("foo"), nil))
+	mempool.Lock()
+	require.Nil(mempool.Update(1, types.Txs{types.Tx("foo")}))
+	mempool.Unlock()
+	require.Nil(mempool.SaveCache())
+	mempool.CloseWAL()
+
+	// the committed tx can't be replayed after a restart
+	mempool = newMempool()
+	defer mempool.CloseWAL()
+	require.NotNil(mempool.CheckTx(types.Tx("foo"), nil))
+	require.Nil(mempool.CheckTx(types.Tx("bar"), nil))
+}
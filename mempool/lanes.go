@@ -0,0 +1,104 @@
+package mempool
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	cmn "github.com/tendermint/tmlibs/common"
+)
+
+// lane groups the txs with a priority of at least minPriority
+// (and less than the minPriority of the previous lane).
+// The lowest lane also gets the txs with a priority below its minPriority.
+type lane struct {
+	minPriority int64
+	quota       int // percentage of the reaped txs reserved for the lane
+}
+
+// parseLanes parses a comma separated list of min_priority:quota,
+// eg. "100:50,1:30,0:20". It returns the lanes sorted from the highest priority.
+// An empty string means a single lane, ie. txs are reaped strictly by priority.
+func parseLanes(s string) ([]lane, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	var lanes []lane
+	total := 0
+	for _, l := range strings.Split(s, ",") {
+		parts := strings.Split(strings.TrimSpace(l), ":")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Lane %q must be min_priority:quota", l)
+		}
+		minPriority, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Lane %q has an invalid min priority: %v", l, err)
+		}
+		quota, err := strconv.Atoi(parts[1])
+		if err != nil || quota < 0 {
+			return nil, fmt.Errorf("Lane %q has an invalid quota, must be a percentage", l)
+		}
+		total += quota
+		lanes = append(lanes, lane{minPriority, quota})
+	}
+	if total > 100 {
+		return nil, fmt.Errorf("The quotas of the lanes add up to %d%%, more than 100%%", total)
+	}
+	sort.Slice(lanes, func(i, j int) bool {
+		return lanes[i].minPriority > lanes[j].minPriority
+	})
+	for i := 1; i < len(lanes); i++ {
+		if lanes[i].minPriority == lanes[i-1].minPriority {
+			return nil, fmt.Errorf("Two lanes have the same min priority %d", lanes[i].minPriority)
+		}
+	}
+	return lanes, nil
+}
+
+// laneIndex returns the index of the lane of the given priority.
+func laneIndex(lanes []lane, priority int64) int {
+	for i, l := range lanes {
+		if priority >= l.minPriority {
+			return i
+		}
+	}
+	return len(lanes) - 1
+}
+
+// reapLanes returns up to maxTxs of the txs, which must be sorted by the given priority.
+// Each lane first gets its quota of maxTxs, then what is left is given to the lanes
+// in order of priority. The result is still sorted by priority.
+func reapLanes(memTxs []*mempoolTx, lanes []lane, maxTxs int, priority func(*mempoolTx) int64) []*mempoolTx {
+	if len(memTxs) <= maxTxs {
+		return memTxs
+	}
+	if len(lanes) == 0 {
+		return memTxs[:maxTxs]
+	}
+
+	byLane := make([][]*mempoolTx, len(lanes))
+	for _, memTx := range memTxs {
+		i := laneIndex(lanes, priority(memTx))
+		byLane[i] = append(byLane[i], memTx)
+	}
+
+	taken, total := make([]int, len(lanes)), 0
+	for i, l := range lanes {
+		taken[i] = cmn.MinInt(len(byLane[i]), maxTxs*l.quota/100)
+		total += taken[i]
+	}
+	// the quotas not used up go to the highest priorities
+	for i := range lanes {
+		extra := cmn.MinInt(len(byLane[i])-taken[i], maxTxs-total)
+		taken[i] += extra
+		total += extra
+	}
+
+	reaped := make([]*mempoolTx, 0, total)
+	for i := range lanes {
+		reaped = append(reaped, byLane[i][:taken[i]]...)
+	}
+	return reaped
+}
@@ -0,0 +1,64 @@
+package mempool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLanes(t *testing.T) {
+	assert := assert.New(t)
+
+	lanes, err := parseLanes("")
+	assert.Nil(err)
+	assert.Nil(lanes)
+
+	lanes, err = parseLanes("0:20, 100:50,1:30")
+	assert.Nil(err)
+	assert.Equal([]lane{{100, 50}, {1, 30}, {0, 20}}, lanes)
+
+	for _, s := range []string{"100", "a:10", "10:a", "10:-1", "10:60,0:60", "1:10,1:20"} {
+		_, err = parseLanes(s)
+		assert.NotNil(err, s)
+	}
+}
+
+func TestReapLanes(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	lanes, err := parseLanes("100:50,1:30,0:20")
+	require.Nil(err)
+
+	// 10 txs with priority 100, 10 with 10, and 10 with 0, sorted
+	var memTxs []*mempoolTx
+	for _, priority := range []int64{100, 10, 0} {
+		for i := 0; i < 10; i++ {
+			memTxs = append(memTxs, &mempoolTx{priority: priority})
+		}
+	}
+	count := func(reaped []*mempoolTx) map[int64]int {
+		counts := make(map[int64]int)
+		for _, memTx := range reaped {
+			counts[memTx.priority]++
+		}
+		return counts
+	}
+
+	// every lane gets its quota
+	reaped := reapLanes(memTxs, lanes, 10, (*mempoolTx).Priority)
+	assert.Equal(map[int64]int{100: 5, 10: 3, 0: 2}, count(reaped))
+	assert.Equal(int64(100), reaped[0].priority)
+	assert.Equal(int64(0), reaped[9].priority)
+
+	// the unused quotas go to the highest priorities
+	reaped = reapLanes(memTxs[10:], lanes, 10, (*mempoolTx).Priority)
+	assert.Equal(map[int64]int{10: 8, 0: 2}, count(reaped))
+
+	// without lanes, strictly by priority
+	reaped = reapLanes(memTxs, nil, 10, (*mempoolTx).Priority)
+	assert.Equal(map[int64]int{100: 10}, count(reaped))
+
+	// everything fits
+	assert.Len(reapLanes(memTxs, lanes, 100, (*mempoolTx).Priority), 30)
+}
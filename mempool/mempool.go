@@ -2,8 +2,8 @@ package mempool
 
 import (
 	"bytes"
-	"container/list"
 	"fmt"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -45,11 +45,44 @@ Garbage collection of old elements from mempool.txs is handlde via
 the DetachPrev() call, which makes old elements not reachable by
 peer broadcastTxRoutine() automatically garbage collected.
 
+Txs are gossiped in the order they were added, but reaped by priority,
+which is the Fee returned by CheckTx. With lanes configured
+(see MempoolConfig.Lanes), each lane of priorities gets a quota of the
+reaped txs, so low priority txs can't be starved completely.
+
+Txs not included in a block before their expiry height (see CheckTxWithExpiry),
+or within the TTL of the mempool (see MempoolConfig.TxTTL and TxTTLBlocks),
+are evicted on Update(), and removed from the cache to be sent again.
+
+Txs seen recently are kept in a cache (see cache.go), to reject the ones
+sent again without a CheckTx.
+
+With MempoolConfig.WalPath set, txs are logged to a WAL (see wal.go) and
+replayed through CheckTx on restart with ReplayWAL().
+
+With MempoolConfig.OrderingKey set, the txs of a sender are reaped in the
+order of their sequence, and only up to the first gap (see ordering.go).
+
+Once the mempool holds MempoolConfig.Size txs, new txs are rejected,
+unless the eviction policy is "priority": then the lowest priority tx
+(the oldest one for equal priorities) is evicted to admit a new tx
+with a higher priority.
+
 TODO: Better handle abci client errors. (make it automatically handle connection errors)
 
 */
 
-const cacheSize = 100000
+// Eviction policies, see MempoolConfig.Eviction
+const (
+	// EvictionNone rejects new txs when the mempool is full
+	EvictionNone = "none"
+	// EvictionPriority evicts the lowest priority tx for a higher priority one
+	EvictionPriority = "priority"
+)
+
+// ErrCatchingUp is returned by CheckTx while the mempool is paused,
+// ie. while the node is fast syncing or replaying the consensus WAL.
+var ErrCatchingUp = errors.New("Mempool is paused while the node is catching up")
 
 // Mempool is an ordered in-memory pool for transactions before they are proposed in a consensus
 // round. Transaction validity is checked using the CheckTx abci message before the transaction is
@@ -62,19 +95,41 @@ type Mempool struct {
 	proxyAppConn         proxy.AppConnMempool
 	txs                  *clist.CList    // concurrent linked-list of good txs
 	counter              int64           // simple incrementing counter
+	txsBytes             int64           // total size of the txs
 	height               int64           // the last block Update()'d to
 	rechecking           int32           // for re-checking filtered txs on Update()
+	paused               int32           // while catching up, see Pause()
+	walReplayPending     int32           // ReplayWAL() was called while paused
 	recheckCursor        *clist.CElement // next expected response
 	recheckEnd           *clist.CElement // re-checking stops here
-	notifiedTxsAvailable bool            // true if fired on txsAvailable for this height
-	txsAvailable         chan int64      // fires the next height once for each height, when the mempool is not empty
+	recheckConns         []proxy.AppConnMempool
+	notifyMtx            sync.Mutex
+	notifiedTxsAvailable bool       // true if fired on txsAvailable for this height
+	txsAvailable         chan int64 // fires the next height once for each height, when the mempool is not empty
 
 	// Keep a cache of already-seen txs.
 	// This reduces the pressure on the proxyApp.
 	cache *txCache
 
+	// The txs waiting for their CheckTx response, in the WAL but not in txs yet
+	pendingMtx sync.Mutex
+	pending    map[string]pendingTx
+	pendingSeq int64
+
+	// Lanes of priorities, highest first
+	lanes []lane
+
+	// Orders the txs of a sender
+	orderingKey OrderingKeyFunc
+
+	metrics *Metrics
+
+	// Publishes the txs accepted
+	eventBus types.MempoolEventPublisher
+
 	// A log of mempool txs
-	wal *auto.AutoFile
+	wal      *auto.AutoFile
+	walLines int // lines written to the WAL since it was compacted
 
 	logger log.Logger
 }
@@ -82,6 +137,29 @@ type Mempool struct {
 // NewMempool returns a new Mempool with the given configuration and connection to an application.
 // TODO: Extract logger into arguments.
 func NewMempool(config *cfg.MempoolConfig, proxyAppConn proxy.AppConnMempool, height int64) *Mempool {
+	lanes, err := parseLanes(config.Lanes)
+	if err != nil {
+		cmn.PanicSanity(errors.Wrap(err, "Error parsing mempool lanes"))
+	}
+	switch config.Eviction {
+	case "", EvictionNone, EvictionPriority:
+	default:
+		cmn.PanicSanity(cmn.Fmt("Unknown mempool eviction policy %q", config.Eviction))
+	}
+	switch config.CachePolicy {
+	case "", CachePolicyFIFO, CachePolicyLRU:
+	default:
+		cmn.PanicSanity(cmn.Fmt("Unknown mempool cache policy %q", config.CachePolicy))
+	}
+	var orderingKey OrderingKeyFunc
+	switch config.OrderingKey {
+	case "", OrderingKeyNone:
+		orderingKey = noOrderingKey
+	case OrderingKeyData:
+		orderingKey = DataOrderingKey
+	default:
+		cmn.PanicSanity(cmn.Fmt("Unknown mempool ordering key %q", config.OrderingKey))
+	}
 	mempool := &Mempool{
 		config:        config,
 		proxyAppConn:  proxyAppConn,
@@ -92,7 +170,12 @@ func NewMempool(config *cfg.MempoolConfig, proxyAppConn proxy.AppConnMempool, he
 		recheckCursor: nil,
 		recheckEnd:    nil,
 		logger:        log.NewNopLogger(),
-		cache:         newTxCache(cacheSize),
+		cache:         newTxCache(config.CacheSize, config.CachePolicy, config.CacheExpiry()),
+		pending:       make(map[string]pendingTx),
+		lanes:         lanes,
+		orderingKey:   orderingKey,
+		metrics:       nopMetrics(),
+		eventBus:      types.NopEventBus{},
 	}
 	mempool.initWAL()
 	proxyAppConn.SetResponseCallback(mempool.resCb)
@@ -106,11 +189,70 @@ func (mem *Mempool) EnableTxsAvailable() {
 	mem.txsAvailable = make(chan int64, 1)
 }
 
+// Pause makes the mempool reject new txs with ErrCatchingUp, and stop gossiping its txs,
+// until Resume is called. It should be called while the node is catching up,
+// as checking txs against a stale app state is a waste and pollutes the cache.
+func (mem *Mempool) Pause() {
+	if atomic.SwapInt32(&mem.paused, 1) == 0 {
+		mem.logger.Info("Paused Mempool while catching up")
+	}
+}
+
+// Resume makes the mempool accept and gossip txs again after Pause,
+// and replays the WAL if ReplayWAL was called while paused.
+func (mem *Mempool) Resume() {
+	if atomic.SwapInt32(&mem.paused, 0) == 1 {
+		mem.logger.Info("Resumed Mempool")
+	}
+	if atomic.SwapInt32(&mem.walReplayPending, 0) == 1 {
+		if err := mem.ReplayWAL(); err != nil {
+			mem.logger.Error("Error replaying Mempool wal", "err", err)
+		}
+	}
+}
+
+// IsPaused returns true if the mempool is paused, see Pause.
+func (mem *Mempool) IsPaused() bool {
+	return atomic.LoadInt32(&mem.paused) == 1
+}
+
 // SetLogger sets the Logger.
 func (mem *Mempool) SetLogger(l log.Logger) {
 	mem.logger = l
 }
 
+// SetMetrics sets the metrics of the txs evicted or rejected when the mempool is full.
+// NOTE: not thread safe - should only be called once, on startup
+func (mem *Mempool) SetMetrics(metrics *Metrics) {
+	mem.metrics = metrics
+}
+
+// SetEventBus sets the event bus the txs accepted into the mempool are published to,
+// as EventDataMempoolTx. Publishing happens in the CheckTx callback, so a
+// slow event bus slows down the mempool.
+// NOTE: not thread safe - should only be called once, on startup
+func (mem *Mempool) SetEventBus(eventBus types.MempoolEventPublisher) {
+	mem.eventBus = eventBus
+}
+
+// SetRecheckConns makes the mempool recheck its txs after each block
+// in parallel over the given connections, rather than in order over its own.
+// NOTE: not thread safe - should only be called once, on startup
+func (mem *Mempool) SetRecheckConns(conns []proxy.AppConnMempool) {
+	for _, conn := range conns {
+		// the responses go to the callback of each request
+		conn.SetResponseCallback(func(*abci.Request, *abci.Response) {})
+	}
+	mem.recheckConns = conns
+}
+
+// SetOrderingKeyFunc sets how the ordering keys of the txs are read from their
+// CheckTx response, overriding MempoolConfig.OrderingKey.
+// NOTE: not thread safe - should only be called once, on startup
+func (mem *Mempool) SetOrderingKeyFunc(orderingKey OrderingKeyFunc) {
+	mem.orderingKey = orderingKey
+}
+
 // CloseWAL closes and discards the underlying WAL file.
 // Any further writes will not be relayed to disk.
 func (mem *Mempool) CloseWAL() bool {
@@ -169,8 +311,7 @@ func (mem *Mempool) Flush() {
 	mem.cache.Reset()
 
 	for e := mem.txs.Front(); e != nil; e = e.Next() {
-		mem.txs.Remove(e)
-		e.DetachPrev()
+		mem.removeTx(e)
 	}
 }
 
@@ -187,32 +328,54 @@ func (mem *Mempool) TxsFrontWait() *clist.CElement {
 //     It gets called from another goroutine.
 // CONTRACT: Either cb will get called, or err returned.
 func (mem *Mempool) CheckTx(tx types.Tx, cb func(*abci.Response)) (err error) {
+	return mem.CheckTxWithExpiry(tx, 0, cb)
+}
+
+// CheckTxWithExpiry is like CheckTx, but the tx is evicted from the mempool
+// if it's not included in a block at or below expiryHeight. 0 means no expiry height.
+// NOTE: the expiry height is not gossiped, peers only apply their own TTL.
+func (mem *Mempool) CheckTxWithExpiry(tx types.Tx, expiryHeight int64, cb func(*abci.Response)) (err error) {
+	if mem.IsPaused() {
+		return ErrCatchingUp
+	}
+
 	mem.proxyMtx.Lock()
 	defer mem.proxyMtx.Unlock()
 
+	if expiryHeight > 0 && expiryHeight <= mem.height {
+		return fmt.Errorf("Tx expired at height %d, the last block is %d", expiryHeight, mem.height)
+	}
+
+	// don't bother the app if the tx can't get in anyway
+	if mem.isFull() && mem.config.Eviction != EvictionPriority {
+		mem.metrics.RejectedTxs.Inc(1)
+		return fmt.Errorf("Mempool is full (%d txs)", mem.config.Size)
+	}
+
 	// CACHE
-	if mem.cache.Exists(tx) {
+	if !mem.cache.Push(tx) {
 		return fmt.Errorf("Tx already exists in cache")
 	}
-	mem.cache.Push(tx)
 	// END CACHE
 
+	mem.pendingMtx.Lock()
+	mem.pendingSeq++
+	mem.pending[string(tx)] = pendingTx{mem.pendingSeq, expiryHeight}
+	mem.pendingMtx.Unlock()
+
 	// WAL
 	if mem.wal != nil {
 		// TODO: Notify administrators when WAL fails
-		_, err := mem.wal.Write([]byte(tx))
-		if err != nil {
-			mem.logger.Error("Error writing to WAL", "err", err)
-		}
-		_, err = mem.wal.Write([]byte("\n"))
-		if err != nil {
+		if err := writeWALTx(mem.wal, tx); err != nil {
 			mem.logger.Error("Error writing to WAL", "err", err)
 		}
+		mem.walLines++
 	}
 	// END WAL
 
 	// NOTE: proxyAppConn may error if tx buffer is full
 	if err = mem.proxyAppConn.Error(); err != nil {
+		mem.takePending(tx)
 		return err
 	}
 	reqRes := mem.proxyAppConn.CheckTxAsync(tx)
@@ -223,6 +386,45 @@ func (mem *Mempool) CheckTx(tx types.Tx, cb func(*abci.Response)) (err error) {
 	return nil
 }
 
+// pendingTx is a tx waiting for its CheckTx response.
+type pendingTx struct {
+	seq          int64 // order of the CheckTx calls
+	expiryHeight int64
+}
+
+// takePending forgets the tx waiting for its CheckTx response, and returns
+// the expiry height given to CheckTxWithExpiry for it.
+func (mem *Mempool) takePending(tx types.Tx) int64 {
+	mem.pendingMtx.Lock()
+	defer mem.pendingMtx.Unlock()
+	pending, ok := mem.pending[string(tx)]
+	if ok {
+		delete(mem.pending, string(tx))
+	}
+	return pending.expiryHeight
+}
+
+// numPending returns the number of txs waiting for their CheckTx response.
+func (mem *Mempool) numPending() int {
+	mem.pendingMtx.Lock()
+	defer mem.pendingMtx.Unlock()
+	return len(mem.pending)
+}
+
+// pendingTxs returns the txs waiting for their CheckTx response, in order.
+func (mem *Mempool) pendingTxs() []types.Tx {
+	mem.pendingMtx.Lock()
+	defer mem.pendingMtx.Unlock()
+	txs := make([]types.Tx, 0, len(mem.pending))
+	for tx := range mem.pending {
+		txs = append(txs, types.Tx(tx))
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		return mem.pending[string(txs[i])].seq < mem.pending[string(txs[j])].seq
+	})
+	return txs
+}
+
 // ABCI callback function
 func (mem *Mempool) resCb(req *abci.Request, res *abci.Response) {
 	if mem.recheckCursor == nil {
@@ -236,16 +438,33 @@ func (mem *Mempool) resCbNormal(req *abci.Request, res *abci.Response) {
 	switch r := res.Value.(type) {
 	case *abci.Response_CheckTx:
 		tx := req.GetCheckTx().Tx
+		expiryHeight := mem.takePending(tx)
 		if r.CheckTx.Code == abci.CodeTypeOK {
+			if !mem.makeRoom(r.CheckTx.Fee) {
+				mem.metrics.RejectedTxs.Inc(1)
+				mem.logger.Info("Rejected transaction, mempool is full", "tx", tx, "priority", r.CheckTx.Fee)
+
+				// remove from cache (it might get in later)
+				mem.cache.Remove(tx)
+				return
+			}
 			mem.counter++
 			memTx := &mempoolTx{
-				counter: mem.counter,
-				height:  mem.height,
-				tx:      tx,
+				counter:      mem.counter,
+				height:       mem.height,
+				priority:     r.CheckTx.Fee,
+				gas:          r.CheckTx.Gas,
+				expiryHeight: expiryHeight,
+				timestamp:    time.Now(),
+				tx:           tx,
 			}
-			mem.txs.PushBack(memTx)
+			if key, ok := mem.orderingKey(tx, r.CheckTx); ok {
+				memTx.key = &key
+			}
+			mem.addTx(memTx)
 			mem.logger.Info("Added good transaction", "tx", tx, "res", r)
 			mem.notifyTxsAvailable()
+			mem.publishTx(memTx)
 		} else {
 			// ignore bad transaction
 			mem.logger.Info("Rejected bad transaction", "tx", tx, "res", r)
@@ -268,36 +487,95 @@ func (mem *Mempool) resCbRecheck(req *abci.Request, res *abci.Response) {
 			cmn.PanicSanity(cmn.Fmt("Unexpected tx response from proxy during recheck\n"+
 				"Expected %X, got %X", r.CheckTx.Data, memTx.tx))
 		}
-		if r.CheckTx.Code == abci.CodeTypeOK {
-			// Good, nothing to do.
-		} else {
-			// Tx became invalidated due to newly committed block.
-			mem.txs.Remove(mem.recheckCursor)
-			mem.recheckCursor.DetachPrev()
-
-			// remove from cache (it might be good later)
-			mem.cache.Remove(req.GetCheckTx().Tx)
-		}
+		mem.rechecked(mem.recheckCursor, r.CheckTx)
 		if mem.recheckCursor == mem.recheckEnd {
 			mem.recheckCursor = nil
 		} else {
 			mem.recheckCursor = mem.recheckCursor.Next()
 		}
 		if mem.recheckCursor == nil {
-			// Done!
-			atomic.StoreInt32(&mem.rechecking, 0)
-			mem.logger.Info("Done rechecking txs")
-
-			// incase the recheck removed all txs
-			if mem.Size() > 0 {
-				mem.notifyTxsAvailable()
-			}
+			mem.doneRechecking()
 		}
 	default:
 		// ignore other messages
 	}
 }
 
+// rechecked updates or removes the tx in e given its new CheckTx response.
+func (mem *Mempool) rechecked(e *clist.CElement, res *abci.ResponseCheckTx) {
+	memTx := e.Value.(*mempoolTx)
+	if res.Code == abci.CodeTypeOK {
+		// Good, the priority may have changed though.
+		atomic.StoreInt64(&memTx.priority, res.Fee)
+		atomic.StoreInt64(&memTx.gas, res.Gas)
+	} else {
+		// Tx became invalidated due to newly committed block.
+		mem.removeTx(e)
+
+		// remove from cache (it might be good later)
+		mem.cache.Remove(memTx.tx)
+	}
+}
+
+func (mem *Mempool) doneRechecking() {
+	// Done!
+	atomic.StoreInt32(&mem.rechecking, 0)
+	mem.logger.Info("Done rechecking txs")
+
+	// incase the recheck removed all txs
+	if mem.Size() > 0 {
+		mem.notifyTxsAvailable()
+	}
+}
+
+// isFull returns true if the mempool holds as many txs as it can.
+func (mem *Mempool) isFull() bool {
+	return mem.config.Size > 0 && mem.Size() >= mem.config.Size
+}
+
+func (mem *Mempool) publishTx(memTx *mempoolTx) {
+	err := mem.eventBus.PublishEventMempoolTx(types.EventDataMempoolTx{
+		Hash:      memTx.tx.Hash(),
+		Size:      len(memTx.tx),
+		Priority:  memTx.priority,
+		GasWanted: memTx.gas,
+		Height:    memTx.height,
+	})
+	if err != nil {
+		mem.logger.Error("Error publishing the mempool tx", "err", err)
+	}
+}
+
+// makeRoom returns true if a tx with the given priority can be added,
+// evicting the lowest priority tx, the oldest one for equal priorities,
+// if the mempool is full and the eviction policy allows it.
+func (mem *Mempool) makeRoom(priority int64) bool {
+	if !mem.isFull() {
+		return true
+	}
+	if mem.config.Eviction != EvictionPriority {
+		return false
+	}
+
+	var lowest *clist.CElement
+	for e := mem.txs.Front(); e != nil; e = e.Next() {
+		if lowest == nil || e.Value.(*mempoolTx).Priority() < lowest.Value.(*mempoolTx).Priority() {
+			lowest = e
+		}
+	}
+	if lowest == nil || lowest.Value.(*mempoolTx).Priority() >= priority {
+		return false
+	}
+
+	memTx := lowest.Value.(*mempoolTx)
+	mem.removeTx(lowest)
+	// remove from cache (it might get in later)
+	mem.cache.Remove(memTx.tx)
+	mem.metrics.EvictedTxs.Inc(1)
+	mem.logger.Info("Evicted lower priority transaction", "tx", memTx.tx, "priority", memTx.Priority(), "for", priority)
+	return true
+}
+
 // TxsAvailable returns a channel which fires once for every height,
 // and only when transactions are available in the mempool.
 // NOTE: the returned channel may be nil if EnableTxsAvailable was not called.
@@ -309,13 +587,17 @@ func (mem *Mempool) notifyTxsAvailable() {
 	if mem.Size() == 0 {
 		panic("notified txs available but mempool is empty!")
 	}
+	// the txs may be rechecked in parallel with new txs
+	mem.notifyMtx.Lock()
+	defer mem.notifyMtx.Unlock()
 	if mem.txsAvailable != nil && !mem.notifiedTxsAvailable {
 		mem.notifiedTxsAvailable = true
 		mem.txsAvailable <- mem.height + 1
 	}
 }
 
-// Reap returns a list of transactions currently in the mempool.
+// Reap returns a list of transactions currently in the mempool,
+// by order of priority, with the lanes getting their quota of maxTxs.
 // If maxTxs is -1, there is no cap on the number of returned transactions.
 func (mem *Mempool) Reap(maxTxs int) types.Txs {
 	mem.proxyMtx.Lock()
@@ -330,6 +612,22 @@ func (mem *Mempool) Reap(maxTxs int) types.Txs {
 	return txs
 }
 
+// Txs returns all the transactions in the mempool, in the order they were added.
+// Unlike Reap, it doesn't wait for Update() or the recheck of the txs.
+func (mem *Mempool) Txs() []types.MempoolTx {
+	txs := make([]types.MempoolTx, 0, mem.txs.Len())
+	for e := mem.txs.Front(); e != nil; e = e.Next() {
+		memTx := e.Value.(*mempoolTx)
+		txs = append(txs, types.MempoolTx{
+			Tx:       memTx.tx,
+			Height:   memTx.Height(),
+			Priority: memTx.Priority(),
+			Gas:      memTx.Gas(),
+		})
+	}
+	return txs
+}
+
 // maxTxs: -1 means uncapped, 0 means none
 func (mem *Mempool) collectTxs(maxTxs int) types.Txs {
 	if maxTxs == 0 {
@@ -337,10 +635,22 @@ func (mem *Mempool) collectTxs(maxTxs int) types.Txs {
 	} else if maxTxs < 0 {
 		maxTxs = mem.txs.Len()
 	}
-	txs := make([]types.Tx, 0, cmn.MinInt(mem.txs.Len(), maxTxs))
-	for e := mem.txs.Front(); e != nil && len(txs) < maxTxs; e = e.Next() {
-		memTx := e.Value.(*mempoolTx)
-		txs = append(txs, memTx.tx)
+	memTxs := make([]*mempoolTx, 0, mem.txs.Len())
+	for e := mem.txs.Front(); e != nil; e = e.Next() {
+		memTxs = append(memTxs, e.Value.(*mempoolTx))
+	}
+	// highest priority first, first come first served for the same priority,
+	// and the txs of a sender by sequence
+	memTxs, order := orderBySender(memTxs)
+	sort.Slice(memTxs, func(i, j int) bool {
+		return order.ranks[memTxs[i]].less(order.ranks[memTxs[j]])
+	})
+	memTxs = reapLanes(memTxs, mem.lanes, maxTxs, order.priority)
+	memTxs = order.dropGaps(memTxs)
+
+	txs := make([]types.Tx, len(memTxs))
+	for i, memTx := range memTxs {
+		txs[i] = memTx.tx
 	}
 	return txs
 }
@@ -360,10 +670,16 @@ func (mem *Mempool) Update(height int64, txs types.Txs) error {
 
 	// Set height
 	mem.height = height
+	mem.notifyMtx.Lock()
 	mem.notifiedTxsAvailable = false
+	mem.notifyMtx.Unlock()
 
-	// Remove transactions that are already in txs.
-	goodTxs := mem.filterTxs(txsMap)
+	// Remove transactions that are already in txs, or expired.
+	goodTxs, removedTxs := mem.filterTxs(txsMap)
+	// and from the WAL
+	if err := mem.appendWALRemovals(removedTxs); err != nil {
+		mem.logger.Error("Error writing to WAL", "err", err)
+	}
 	// Recheck mempool txs if any txs were committed in the block
 	// NOTE/XXX: in some apps a tx could be invalidated due to EndBlock,
 	//	so we really still do need to recheck, but this is for debugging
@@ -377,23 +693,69 @@ func (mem *Mempool) Update(height int64, txs types.Txs) error {
 	return nil
 }
 
-func (mem *Mempool) filterTxs(blockTxsMap map[string]struct{}) []types.Tx {
-	goodTxs := make([]types.Tx, 0, mem.txs.Len())
+// filterTxs removes the txs in the block or expired, and returns the txs left
+// and the ones removed.
+func (mem *Mempool) filterTxs(blockTxsMap map[string]struct{}) (goodTxs, removedTxs []types.Tx) {
+	now := time.Now()
+	goodTxs = make([]types.Tx, 0, mem.txs.Len())
 	for e := mem.txs.Front(); e != nil; e = e.Next() {
 		memTx := e.Value.(*mempoolTx)
 		// Remove the tx if it's alredy in a block.
 		if _, ok := blockTxsMap[string(memTx.tx)]; ok {
 			// remove from clist
-			mem.txs.Remove(e)
-			e.DetachPrev()
+			mem.removeTx(e)
+			removedTxs = append(removedTxs, memTx.tx)
 
 			// NOTE: we don't remove committed txs from the cache.
 			continue
 		}
+		// Remove the tx if it expired.
+		if mem.expired(memTx, now) {
+			mem.removeTx(e)
+			removedTxs = append(removedTxs, memTx.tx)
+			mem.logger.Info("Evicted expired transaction", "tx", memTx.tx, "height", memTx.Height())
+
+			// remove from the cache too, so it can be sent again
+			mem.cache.Remove(memTx.tx)
+			continue
+		}
 		// Good tx!
 		goodTxs = append(goodTxs, memTx.tx)
 	}
-	return goodTxs
+	return goodTxs, removedTxs
+}
+
+// addTx adds the tx to the list of good txs.
+func (mem *Mempool) addTx(memTx *mempoolTx) {
+	mem.txs.PushBack(memTx)
+	mem.updateSizeMetrics(int64(len(memTx.tx)))
+}
+
+// removeTx removes the element of a tx from the list of good txs.
+func (mem *Mempool) removeTx(e *clist.CElement) {
+	mem.txs.Remove(e)
+	e.DetachPrev()
+	mem.updateSizeMetrics(-int64(len(e.Value.(*mempoolTx).tx)))
+}
+
+func (mem *Mempool) updateSizeMetrics(deltaBytes int64) {
+	txsBytes := atomic.AddInt64(&mem.txsBytes, deltaBytes)
+	mem.metrics.Size.Update(int64(mem.txs.Len()))
+	mem.metrics.SizeBytes.Update(txsBytes)
+}
+
+// expired returns true if the tx is past its expiry height or the TTL of the mempool.
+func (mem *Mempool) expired(memTx *mempoolTx, now time.Time) bool {
+	if memTx.expiryHeight > 0 && mem.height >= memTx.expiryHeight {
+		return true
+	}
+	if ttl := mem.config.TxTTLBlocks; ttl > 0 && mem.height-memTx.Height() >= ttl {
+		return true
+	}
+	if ttl := mem.config.TTL(); ttl > 0 && now.Sub(memTx.timestamp) >= ttl {
+		return true
+	}
+	return false
 }
 
 // NOTE: pass in goodTxs because mem.txs can mutate concurrently.
@@ -401,6 +763,10 @@ func (mem *Mempool) recheckTxs(goodTxs []types.Tx) {
 	if len(goodTxs) == 0 {
 		return
 	}
+	if len(mem.recheckConns) > 0 {
+		mem.recheckTxsParallel()
+		return
+	}
 	atomic.StoreInt32(&mem.rechecking, 1)
 	mem.recheckCursor = mem.txs.Front()
 	mem.recheckEnd = mem.txs.Back()
@@ -413,80 +779,101 @@ func (mem *Mempool) recheckTxs(goodTxs []types.Tx) {
 	mem.proxyAppConn.FlushAsync()
 }
 
-//--------------------------------------------------------------------------------
+// recheckTxsParallel splits the txs between the recheck connections,
+// each one rechecking its share in order. The txs of a sender are all
+// rechecked on the same connection, by increasing sequence, as the app
+// would reject them out of order.
+func (mem *Mempool) recheckTxsParallel() {
+	n := len(mem.recheckConns)
+	shares := make([][]*clist.CElement, n)
+	pending := int64(0)
+	for _, group := range recheckGroups(mem.txs) {
+		// to the connection with the fewest txs
+		min := 0
+		for i := range shares {
+			if len(shares[i]) < len(shares[min]) {
+				min = i
+			}
+		}
+		shares[min] = append(shares[min], group...)
+		pending += int64(len(group))
+	}
+	atomic.StoreInt32(&mem.rechecking, 1)
 
-// mempoolTx is a transaction that successfully ran
-type mempoolTx struct {
-	counter int64    // a simple incrementing counter
-	height  int64    // height that this tx had been validated in
-	tx      types.Tx //
+	for i, conn := range mem.recheckConns {
+		share := shares[i]
+		go func(conn proxy.AppConnMempool, share []*clist.CElement) {
+			for _, e := range share {
+				e := e
+				reqRes := conn.CheckTxAsync(e.Value.(*mempoolTx).tx)
+				reqRes.SetCallback(func(res *abci.Response) {
+					if r, ok := res.Value.(*abci.Response_CheckTx); ok {
+						mem.rechecked(e, r.CheckTx)
+					}
+					if atomic.AddInt64(&pending, -1) == 0 {
+						mem.doneRechecking()
+					}
+				})
+			}
+			conn.FlushAsync()
+		}(conn, share)
+	}
 }
 
-// Height returns the height for this transaction
-func (memTx *mempoolTx) Height() int64 {
-	return atomic.LoadInt64(&memTx.height)
+// recheckGroups returns the txs to recheck grouped by sender, each group
+// sorted by sequence. The txs without an ordering key are in groups of
+// their own.
+func recheckGroups(txs *clist.CList) [][]*clist.CElement {
+	var groups [][]*clist.CElement
+	bySender := make(map[string]int) // the index of the group of each sender
+	for e := txs.Front(); e != nil; e = e.Next() {
+		key := e.Value.(*mempoolTx).key
+		if key == nil {
+			groups = append(groups, []*clist.CElement{e})
+			continue
+		}
+		i, ok := bySender[key.Sender]
+		if !ok {
+			i = len(groups)
+			bySender[key.Sender] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], e)
+	}
+	for _, i := range bySender {
+		group := groups[i]
+		sort.SliceStable(group, func(a, b int) bool {
+			return group[a].Value.(*mempoolTx).key.Sequence < group[b].Value.(*mempoolTx).key.Sequence
+		})
+	}
+	return groups
 }
 
 //--------------------------------------------------------------------------------
 
-// txCache maintains a cache of transactions.
-type txCache struct {
-	mtx  sync.Mutex
-	size int
-	map_ map[string]struct{}
-	list *list.List // to remove oldest tx when cache gets too big
-}
-
-// newTxCache returns a new txCache.
-func newTxCache(cacheSize int) *txCache {
-	return &txCache{
-		size: cacheSize,
-		map_: make(map[string]struct{}, cacheSize),
-		list: list.New(),
-	}
-}
-
-// Reset resets the txCache to empty.
-func (cache *txCache) Reset() {
-	cache.mtx.Lock()
-	cache.map_ = make(map[string]struct{}, cacheSize)
-	cache.list.Init()
-	cache.mtx.Unlock()
+// mempoolTx is a transaction that successfully ran
+type mempoolTx struct {
+	counter      int64        // a simple incrementing counter
+	height       int64        // height that this tx had been validated in
+	priority     int64        // the Fee of the last CheckTx
+	gas          int64        // the Gas of the last CheckTx
+	expiryHeight int64        // height after which the tx is evicted, 0 for none
+	timestamp    time.Time    // time the tx was added
+	key          *OrderingKey // nil if the tx is not ordered
+	tx           types.Tx     //
 }
 
-// Exists returns true if the given tx is cached.
-func (cache *txCache) Exists(tx types.Tx) bool {
-	cache.mtx.Lock()
-	_, exists := cache.map_[string(tx)]
-	cache.mtx.Unlock()
-	return exists
+// Height returns the height for this transaction
+func (memTx *mempoolTx) Height() int64 {
+	return atomic.LoadInt64(&memTx.height)
 }
 
-// Push adds the given tx to the txCache. It returns false if tx is already in the cache.
-func (cache *txCache) Push(tx types.Tx) bool {
-	cache.mtx.Lock()
-	defer cache.mtx.Unlock()
-
-	if _, exists := cache.map_[string(tx)]; exists {
-		return false
-	}
-
-	if cache.list.Len() >= cache.size {
-		popped := cache.list.Front()
-		poppedTx := popped.Value.(types.Tx)
-		// NOTE: the tx may have already been removed from the map
-		// but deleting a non-existent element is fine
-		delete(cache.map_, string(poppedTx))
-		cache.list.Remove(popped)
-	}
-	cache.map_[string(tx)] = struct{}{}
-	cache.list.PushBack(tx)
-	return true
+// Priority returns the priority for this transaction
+func (memTx *mempoolTx) Priority() int64 {
+	return atomic.LoadInt64(&memTx.priority)
 }
 
-// Remove removes the given tx from the cache.
-func (cache *txCache) Remove(tx types.Tx) {
-	cache.mtx.Lock()
-	delete(cache.map_, string(tx))
-	cache.mtx.Unlock()
+// Gas returns the gas for this transaction
+func (memTx *mempoolTx) Gas() int64 {
+	return atomic.LoadInt64(&memTx.gas)
 }
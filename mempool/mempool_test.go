@@ -1,6 +1,7 @@
 package mempool
 
 import (
+	"context"
 	"crypto/md5"
 	"crypto/rand"
 	"encoding/binary"
@@ -11,6 +12,8 @@ import (
 	"testing"
 	"time"
 
+	metrics "github.com/rcrowley/go-metrics"
+
 	"github.com/tendermint/abci/example/counter"
 	"github.com/tendermint/abci/example/dummy"
 	abci "github.com/tendermint/abci/types"
@@ -115,6 +118,142 @@ func TestTxsAvailable(t *testing.T) {
 	ensureNoFire(t, mempool.TxsAvailable(), timeoutMS)
 }
 
+// feeApplication accepts all txs, with their first byte as fee
+type feeApplication struct {
+	abci.BaseApplication
+}
+
+func (app *feeApplication) CheckTx(tx []byte) abci.ResponseCheckTx {
+	return abci.ResponseCheckTx{Code: abci.CodeTypeOK, Fee: int64(tx[0])}
+}
+
+func TestReapByPriority(t *testing.T) {
+	cc := proxy.NewLocalClientCreator(&feeApplication{})
+	mempool := newMempoolWithApp(cc)
+
+	txs := types.Txs{{1, 0}, {5, 1}, {1, 2}, {9, 3}, {5, 4}}
+	for _, tx := range txs {
+		require.Nil(t, mempool.CheckTx(tx, nil))
+	}
+
+	// highest fee first, in the order they were received for the same fee
+	reaped := mempool.Reap(-1)
+	require.Equal(t, types.Txs{{9, 3}, {5, 1}, {5, 4}, {1, 0}, {1, 2}}, reaped)
+
+	// reserve 40% of the txs for the low fees
+	mempool.lanes, _ = parseLanes("5:60,0:40")
+	reaped = mempool.Reap(3)
+	require.Equal(t, types.Txs{{9, 3}, {5, 1}, {1, 0}}, reaped)
+}
+
+func TestMempoolEviction(t *testing.T) {
+	cc := proxy.NewLocalClientCreator(&feeApplication{})
+	mempool := newMempoolWithApp(cc)
+	mempool.SetMetrics(NewMetrics(metrics.NewRegistry()))
+	mempool.config.Size = 3
+
+	for _, tx := range (types.Txs{{1, 0}, {2, 1}, {1, 2}}) {
+		require.Nil(t, mempool.CheckTx(tx, nil))
+	}
+	require.NotNil(t, mempool.CheckTx(types.Tx{9, 3}, nil), "mempool is full")
+	require.EqualValues(t, 1, mempool.metrics.RejectedTxs.Count())
+
+	// the oldest of the lowest priority txs makes room for a higher priority one
+	mempool.config.Eviction = EvictionPriority
+	require.Nil(t, mempool.CheckTx(types.Tx{9, 3}, nil))
+	require.Equal(t, types.Txs{{9, 3}, {2, 1}, {1, 2}}, mempool.Reap(-1))
+
+	// but not for one of the same priority
+	require.Nil(t, mempool.CheckTx(types.Tx{1, 4}, nil))
+	require.Equal(t, 3, mempool.Size())
+	require.EqualValues(t, 2, mempool.metrics.RejectedTxs.Count())
+
+	require.Nil(t, mempool.CheckTx(types.Tx{5, 5}, nil))
+	require.Equal(t, types.Txs{{9, 3}, {5, 5}, {2, 1}}, mempool.Reap(-1))
+	require.EqualValues(t, 2, mempool.metrics.EvictedTxs.Count())
+}
+
+func TestMempoolTxExpiry(t *testing.T) {
+	cc := proxy.NewLocalClientCreator(&feeApplication{})
+	mempool := newMempoolWithApp(cc)
+	mempool.config.TxTTLBlocks = 2
+
+	require.Nil(t, mempool.CheckTx(types.Tx{0, 0}, nil))
+	require.Nil(t, mempool.CheckTxWithExpiry(types.Tx{0, 1}, 1, nil))
+	require.Equal(t, 2, mempool.Size())
+
+	// the tx with an expiry height is evicted first
+	require.Nil(t, mempool.Update(1, nil))
+	require.Equal(t, types.Txs{{0, 0}}, mempool.Reap(-1))
+	require.NotNil(t, mempool.CheckTxWithExpiry(types.Tx{0, 2}, 1, nil), "already expired")
+
+	// then the other one, after tx_ttl_blocks
+	require.Nil(t, mempool.Update(2, nil))
+	require.Equal(t, 0, mempool.Size())
+
+	// the expired txs can be sent again
+	require.Nil(t, mempool.CheckTx(types.Tx{0, 1}, nil))
+	require.Equal(t, types.Txs{{0, 1}}, mempool.Reap(-1))
+	require.Nil(t, mempool.Update(4, nil))
+	require.Equal(t, 0, mempool.Size())
+
+	// and txs older than tx_ttl
+	mempool.config.TxTTLBlocks = 0
+	mempool.config.TxTTL = 60
+	require.Nil(t, mempool.CheckTx(types.Tx{0, 3}, nil))
+	require.Nil(t, mempool.CheckTx(types.Tx{0, 4}, nil))
+	mempool.txs.Front().Value.(*mempoolTx).timestamp = time.Now().Add(-time.Minute)
+	require.Nil(t, mempool.Update(5, nil))
+	require.Equal(t, types.Txs{{0, 4}}, mempool.Reap(-1))
+}
+
+func TestMempoolPause(t *testing.T) {
+	cc := proxy.NewLocalClientCreator(&feeApplication{})
+	mempool := newMempoolWithApp(cc)
+
+	mempool.Pause()
+	require.True(t, mempool.IsPaused())
+	require.Equal(t, ErrCatchingUp, mempool.CheckTx(types.Tx{0, 0}, nil))
+	require.Equal(t, 0, mempool.Size())
+
+	// the tx wasn't cached, so it can be sent again once caught up
+	mempool.Resume()
+	require.False(t, mempool.IsPaused())
+	require.Nil(t, mempool.CheckTx(types.Tx{0, 0}, nil))
+	require.Equal(t, 1, mempool.Size())
+}
+
+func TestMempoolTxEvents(t *testing.T) {
+	cc := proxy.NewLocalClientCreator(&feeApplication{})
+	mempool := newMempoolWithApp(cc)
+
+	eventBus := types.NewEventBus()
+	require.Nil(t, eventBus.Start())
+	defer eventBus.Stop()
+	mempool.SetEventBus(eventBus)
+
+	txsCh := make(chan interface{}, 1)
+	require.Nil(t, eventBus.Subscribe(context.Background(), "test", types.EventQueryMempoolTx, txsCh))
+
+	tx := types.Tx{7, 0, 0}
+	require.Nil(t, mempool.CheckTx(tx, nil))
+	select {
+	case msg := <-txsCh:
+		event := msg.(types.TMEventData).Unwrap().(types.EventDataMempoolTx)
+		require.Equal(t, types.EventDataMempoolTx{Hash: tx.Hash(), Size: 3, Priority: 7}, event)
+	case <-time.After(time.Second):
+		t.Fatal("Expected an event for the accepted tx")
+	}
+
+	// nothing for a tx already in the cache
+	require.NotNil(t, mempool.CheckTx(tx, nil))
+	select {
+	case msg := <-txsCh:
+		t.Fatalf("Unexpected event %v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
 func TestSerialReap(t *testing.T) {
 	app := counter.NewCounterApplication(true)
 	app.SetOption(abci.RequestSetOption{"serial", "on"})
@@ -254,7 +393,7 @@ func TestMempoolCloseWAL(t *testing.T) {
 	sum1 := checksumFile(walFilepath, t)
 
 	// 6. Sanity check to ensure that the written TX matches the expectation.
-	require.Equal(t, sum1, checksumIt([]byte("foo\n")), "foo with a newline should be written")
+	require.Equal(t, sum1, checksumIt([]byte("666F6F\n")), "foo hex encoded with a newline should be written")
 
 	// 7. Invoke CloseWAL() and ensure it discards the
 	// WAL thus any other write won't go through.
@@ -272,6 +411,192 @@ func TestMempoolCloseWAL(t *testing.T) {
 	require.Equal(t, 1, len(m3), "expecting the wal match in")
 }
 
+func TestMempoolReplayWAL(t *testing.T) {
+	rootDir, err := ioutil.TempDir("", "mempool-test")
+	require.Nil(t, err, "expecting successful tmpdir creation")
+	defer os.RemoveAll(rootDir)
+
+	wcfg := *(cfg.DefaultMempoolConfig())
+	wcfg.RootDir = rootDir
+	app := counter.NewCounterApplication(true)
+	cc := proxy.NewLocalClientCreator(app)
+	newMempool := func() *Mempool {
+		appConnMem, _ := cc.NewABCIClient()
+		require.Nil(t, appConnMem.Start())
+		mempool := NewMempool(&wcfg, appConnMem, 0)
+		mempool.SetLogger(log.TestingLogger())
+		return mempool
+	}
+	txBytes := func(i int) types.Tx {
+		txBytes := make([]byte, 8)
+		binary.BigEndian.PutUint64(txBytes, uint64(i))
+		return txBytes
+	}
+
+	mempool := newMempool()
+	for i := 0; i < 5; i++ {
+		require.Nil(t, mempool.CheckTx(txBytes(i), nil))
+	}
+	require.Equal(t, 5, mempool.Size())
+
+	// commit the first two txs, the WAL only keeps the others
+	app.DeliverTx(txBytes(0))
+	app.DeliverTx(txBytes(1))
+	app.Commit()
+	mempool.Lock()
+	err = mempool.Update(1, types.Txs{txBytes(0), txBytes(1)})
+	mempool.Unlock()
+	require.Nil(t, err)
+	require.Equal(t, 3, mempool.Size())
+	txs, skipped, err := readWAL(mempool.wal.Path)
+	require.Nil(t, err)
+	require.Equal(t, 0, skipped)
+	require.Equal(t, []types.Tx{txBytes(2), txBytes(3), txBytes(4)}, txs)
+
+	// once compacted, the WAL keeps the txs waiting for their CheckTx response
+	mempool.pending[string(txBytes(6))] = pendingTx{seq: 1}
+	mempool.walLines = minWALCompactLines
+	mempool.Lock()
+	err = mempool.Update(2, types.Txs{})
+	mempool.Unlock()
+	require.Nil(t, err)
+	require.Equal(t, 4, mempool.walLines)
+	txs, _, err = readWAL(mempool.wal.Path)
+	require.Nil(t, err)
+	require.Equal(t, []types.Tx{txBytes(2), txBytes(3), txBytes(4), txBytes(6)}, txs)
+	delete(mempool.pending, string(txBytes(6)))
+
+	// a tx that never got a response is in the WAL too
+	require.Nil(t, mempool.CheckTx(txBytes(5), nil))
+	mempool.CloseWAL()
+
+	// restart
+	mempool = newMempool()
+	require.Equal(t, 0, mempool.Size())
+	require.Nil(t, mempool.ReplayWAL())
+	require.Equal(t, types.Txs{txBytes(2), txBytes(3), txBytes(4), txBytes(6), txBytes(5)}, mempool.Reap(-1))
+	mempool.CloseWAL()
+
+	// restart while catching up, the txs are only replayed once resumed
+	mempool = newMempool()
+	defer mempool.CloseWAL()
+	mempool.Pause()
+	require.Nil(t, mempool.ReplayWAL())
+	require.Equal(t, 0, mempool.Size())
+	mempool.Resume()
+	require.Equal(t, types.Txs{txBytes(2), txBytes(3), txBytes(4), txBytes(6), txBytes(5)}, mempool.Reap(-1))
+
+	// the replayed txs are kept in the WAL
+	txs, skipped, err = readWAL(mempool.wal.Path)
+	require.Nil(t, err)
+	require.Equal(t, 0, skipped)
+	require.Equal(t, []types.Tx{txBytes(2), txBytes(3), txBytes(4), txBytes(6), txBytes(5)}, txs)
+}
+
+func TestMempoolParallelRecheck(t *testing.T) {
+	app := counter.NewCounterApplication(true)
+	cc := proxy.NewLocalClientCreator(app)
+	mempool := newMempoolWithApp(cc)
+	conns, err := proxy.NewAppConnsMempool(cc, 3, log.TestingLogger())
+	require.Nil(t, err)
+	mempool.SetRecheckConns(conns)
+
+	txs := make(types.Txs, 10)
+	for i := range txs {
+		txBytes := make([]byte, 8)
+		binary.BigEndian.PutUint64(txBytes, uint64(i))
+		txs[i] = txBytes
+		require.Nil(t, mempool.CheckTx(txs[i], nil))
+	}
+	require.Equal(t, 10, mempool.Size())
+
+	// commit the first 5 txs, but only tell the mempool about 3 of them
+	for _, tx := range txs[:5] {
+		app.DeliverTx(tx)
+	}
+	app.Commit()
+	mempool.Lock()
+	err = mempool.Update(1, txs[:3])
+	mempool.Unlock()
+	require.Nil(t, err)
+
+	// the recheck drops the 2 others
+	require.Equal(t, txs[5:], mempool.Reap(-1))
+	require.Equal(t, 5, mempool.Size())
+}
+
+// sequenceApplication accepts the txs {sender, sequence} of each sender by
+// increasing sequence, from 1
+type sequenceApplication struct {
+	abci.BaseApplication
+	checked   map[byte]byte // the next sequence of each sender
+	committed map[byte]byte
+}
+
+func nextSequence(sequences map[byte]byte, sender byte) byte {
+	if sequence, ok := sequences[sender]; ok {
+		return sequence
+	}
+	return 1
+}
+
+func (app *sequenceApplication) CheckTx(tx []byte) abci.ResponseCheckTx {
+	sender, sequence := tx[0], tx[1]
+	if sequence != nextSequence(app.checked, sender) {
+		return abci.ResponseCheckTx{Code: 1}
+	}
+	app.checked[sender] = sequence + 1
+	return abci.ResponseCheckTx{Code: abci.CodeTypeOK, Data: []byte(fmt.Sprintf("%d/%d", sender, sequence))}
+}
+
+func (app *sequenceApplication) DeliverTx(tx []byte) abci.ResponseDeliverTx {
+	app.committed[tx[0]] = tx[1] + 1
+	return abci.ResponseDeliverTx{Code: abci.CodeTypeOK}
+}
+
+func (app *sequenceApplication) Commit() abci.ResponseCommit {
+	app.checked = make(map[byte]byte)
+	for sender, sequence := range app.committed {
+		app.checked[sender] = sequence
+	}
+	return abci.ResponseCommit{}
+}
+
+func TestMempoolParallelRecheckSequences(t *testing.T) {
+	app := &sequenceApplication{checked: make(map[byte]byte), committed: make(map[byte]byte)}
+	cc := proxy.NewLocalClientCreator(app)
+	mempool := newMempoolWithApp(cc)
+	mempool.SetOrderingKeyFunc(DataOrderingKey)
+	conns, err := proxy.NewAppConnsMempool(cc, 3, log.TestingLogger())
+	require.Nil(t, err)
+	mempool.SetRecheckConns(conns)
+
+	// the txs of 3 senders, interleaved
+	var txs types.Txs
+	for sequence := byte(1); sequence <= 4; sequence++ {
+		for sender := byte(1); sender <= 3; sender++ {
+			tx := types.Tx{sender, sequence}
+			txs = append(txs, tx)
+			require.Nil(t, mempool.CheckTx(tx, nil))
+		}
+	}
+	require.Equal(t, 12, mempool.Size())
+
+	// commit the first tx of each sender
+	for _, tx := range txs[:3] {
+		app.DeliverTx(tx)
+	}
+	app.Commit()
+	mempool.Lock()
+	err = mempool.Update(1, txs[:3])
+	mempool.Unlock()
+	require.Nil(t, err)
+
+	// the txs of each sender are rechecked in order, so none is dropped
+	require.Len(t, mempool.Reap(-1), 9)
+	require.Equal(t, 9, mempool.Size())
+}
+
 func checksumIt(data []byte) string {
 	h := md5.New()
 	h.Write(data)
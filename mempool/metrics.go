@@ -0,0 +1,43 @@
+package mempool
+
+import (
+	metrics "github.com/rcrowley/go-metrics"
+)
+
+// Metrics exports the size of the mempool, and the admission of txs into
+// a full mempool, to a go-metrics registry:
+//
+//	mempool.size           number of txs in the mempool (gauge)
+//	mempool.size_bytes     total size of the txs in the mempool (gauge)
+//	mempool.evicted_txs    txs evicted to make room for higher priority ones
+//	mempool.rejected_txs   txs rejected because the mempool was full
+type Metrics struct {
+	Size        metrics.Gauge
+	SizeBytes   metrics.Gauge
+	EvictedTxs  metrics.Counter
+	RejectedTxs metrics.Counter
+}
+
+// NewMetrics returns Metrics registered into the given registry.
+// If registry is nil, metrics.DefaultRegistry is used.
+func NewMetrics(registry metrics.Registry) *Metrics {
+	if registry == nil {
+		registry = metrics.DefaultRegistry
+	}
+	return &Metrics{
+		Size:        metrics.GetOrRegisterGauge("mempool.size", registry),
+		SizeBytes:   metrics.GetOrRegisterGauge("mempool.size_bytes", registry),
+		EvictedTxs:  metrics.GetOrRegisterCounter("mempool.evicted_txs", registry),
+		RejectedTxs: metrics.GetOrRegisterCounter("mempool.rejected_txs", registry),
+	}
+}
+
+// nopMetrics returns Metrics that are not registered anywhere.
+func nopMetrics() *Metrics {
+	return &Metrics{
+		Size:        metrics.NilGauge{},
+		SizeBytes:   metrics.NilGauge{},
+		EvictedTxs:  metrics.NilCounter{},
+		RejectedTxs: metrics.NilCounter{},
+	}
+}
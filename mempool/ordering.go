@@ -0,0 +1,157 @@
+package mempool
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+
+	abci "github.com/tendermint/abci/types"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// Ordering keys, see MempoolConfig.OrderingKey
+const (
+	// OrderingKeyNone leaves all txs unordered
+	OrderingKeyNone = "none"
+	// OrderingKeyData reads the ordering key from the Data of the CheckTx response
+	OrderingKeyData = "data"
+)
+
+// OrderingKey orders the txs of a sender, eg. an account and its nonce.
+// The txs of a sender are reaped by increasing sequence, and only up to
+// the first gap in the sequences, as the next txs would fail in DeliverTx.
+type OrderingKey struct {
+	Sender   string
+	Sequence uint64
+}
+
+// OrderingKeyFunc returns the ordering key of a tx from its CheckTx response,
+// or false if the tx is not ordered.
+type OrderingKeyFunc func(tx types.Tx, res *abci.ResponseCheckTx) (OrderingKey, bool)
+
+// DataOrderingKey reads the ordering key from the Data of the CheckTx response,
+// which must be "<sender>/<sequence>", the sequence in decimal.
+// NOTE: abci has no field for it yet, so the app gives up the Data of CheckTx.
+func DataOrderingKey(tx types.Tx, res *abci.ResponseCheckTx) (OrderingKey, bool) {
+	i := bytes.LastIndexByte(res.Data, '/')
+	if i <= 0 {
+		return OrderingKey{}, false
+	}
+	sequence, err := strconv.ParseUint(string(res.Data[i+1:]), 10, 64)
+	if err != nil {
+		return OrderingKey{}, false
+	}
+	return OrderingKey{string(res.Data[:i]), sequence}, true
+}
+
+func noOrderingKey(tx types.Tx, res *abci.ResponseCheckTx) (OrderingKey, bool) {
+	return OrderingKey{}, false
+}
+
+//--------------------------------------------------------------------------------
+
+// txRank is the order in which txs are reaped: highest priority first,
+// first come first served for the same priority.
+// The txs of a sender get the lowest priority and the latest counter of the
+// txs with a lower sequence, so they always rank after them.
+type txRank struct {
+	priority int64
+	counter  int64
+	sequence uint64
+}
+
+func (r txRank) less(other txRank) bool {
+	if r.priority != other.priority {
+		return r.priority > other.priority
+	}
+	if r.counter != other.counter {
+		return r.counter < other.counter
+	}
+	return r.sequence < other.sequence
+}
+
+// senderOrder ranks the txs for reaping.
+type senderOrder struct {
+	ranks map[*mempoolTx]txRank
+	first map[string]uint64 // lowest sequence of each sender
+}
+
+// orderBySender returns the txs that can be reaped, and their ranks.
+// The txs of a sender past the first gap in their sequences, or with the same
+// sequence as an earlier tx of the sender, are left out.
+func orderBySender(memTxs []*mempoolTx) ([]*mempoolTx, *senderOrder) {
+	order := &senderOrder{
+		ranks: make(map[*mempoolTx]txRank, len(memTxs)),
+		first: make(map[string]uint64),
+	}
+	bySender := make(map[string][]*mempoolTx)
+	for _, memTx := range memTxs {
+		if memTx.key == nil {
+			order.ranks[memTx] = txRank{memTx.Priority(), memTx.counter, 0}
+			continue
+		}
+		bySender[memTx.key.Sender] = append(bySender[memTx.key.Sender], memTx)
+	}
+
+	for sender, senderTxs := range bySender {
+		// sorted by counter, so the stable sort keeps the earliest duplicate first
+		sort.SliceStable(senderTxs, func(i, j int) bool {
+			return senderTxs[i].key.Sequence < senderTxs[j].key.Sequence
+		})
+		order.first[sender] = senderTxs[0].key.Sequence
+		var prev txRank
+		for i, memTx := range senderTxs {
+			rank := txRank{memTx.Priority(), memTx.counter, memTx.key.Sequence}
+			if i > 0 {
+				if rank.sequence == prev.sequence {
+					continue
+				}
+				if rank.sequence != prev.sequence+1 {
+					break
+				}
+				if prev.priority < rank.priority {
+					rank.priority = prev.priority
+				}
+				if prev.counter > rank.counter {
+					rank.counter = prev.counter
+				}
+			}
+			order.ranks[memTx] = rank
+			prev = rank
+		}
+	}
+
+	ordered := make([]*mempoolTx, 0, len(order.ranks))
+	for _, memTx := range memTxs {
+		if _, ok := order.ranks[memTx]; ok {
+			ordered = append(ordered, memTx)
+		}
+	}
+	return ordered, order
+}
+
+// priority returns the priority the tx is reaped with.
+func (order *senderOrder) priority(memTx *mempoolTx) int64 {
+	return order.ranks[memTx].priority
+}
+
+// dropGaps drops the txs of a sender following one that wasn't reaped,
+// eg. because the lane of the missing tx was full.
+func (order *senderOrder) dropGaps(reaped []*mempoolTx) []*mempoolTx {
+	next := make(map[string]uint64, len(order.first))
+	for sender, sequence := range order.first {
+		next[sender] = sequence
+	}
+	kept := reaped[:0]
+	for _, memTx := range reaped {
+		if memTx.key != nil {
+			if memTx.key.Sequence != next[memTx.key.Sender] {
+				continue
+			}
+			next[memTx.key.Sender]++
+		}
+		kept = append(kept, memTx)
+	}
+	return kept
+}
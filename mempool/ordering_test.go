@@ -0,0 +1,75 @@
+package mempool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/abci/types"
+	"github.com/tendermint/tmlibs/clist"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestDataOrderingKey(t *testing.T) {
+	assert := assert.New(t)
+
+	for data, key := range map[string]OrderingKey{
+		"acc/12": {"acc", 12},
+		"a/b/3":  {"a/b", 3},
+	} {
+		k, ok := DataOrderingKey(nil, &abci.ResponseCheckTx{Data: []byte(data)})
+		assert.True(ok, data)
+		assert.Equal(key, k, data)
+	}
+	for _, data := range []string{"", "acc", "/3", "acc/", "acc/x", "acc/-1"} {
+		_, ok := DataOrderingKey(nil, &abci.ResponseCheckTx{Data: []byte(data)})
+		assert.False(ok, data)
+	}
+}
+
+func TestReapOrderedTxs(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	newMempool := func(lanesStr string) *Mempool {
+		lanes, err := parseLanes(lanesStr)
+		require.Nil(err)
+		return &Mempool{txs: clist.New(), lanes: lanes}
+	}
+	add := func(mem *Mempool, tx string, priority int64, sender string, sequence uint64) {
+		mem.counter++
+		memTx := &mempoolTx{counter: mem.counter, priority: priority, tx: types.Tx(tx)}
+		if sender != "" {
+			memTx.key = &OrderingKey{sender, sequence}
+		}
+		mem.txs.PushBack(memTx)
+	}
+	txs := func(s ...string) types.Txs {
+		txs := types.Txs{}
+		for _, tx := range s {
+			txs = append(txs, types.Tx(tx))
+		}
+		return txs
+	}
+
+	mem := newMempool("")
+	add(mem, "a2", 1, "a", 2)
+	add(mem, "a1", 5, "a", 1)
+	add(mem, "a4", 9, "a", 4)  // after a gap
+	add(mem, "a2'", 9, "a", 2) // same sequence as a2
+	add(mem, "u", 3, "", 0)
+	add(mem, "b7", 2, "b", 7)
+
+	// a2 has to wait for a1, and gets its lowest priority
+	assert.Equal(txs("a1", "u", "b7", "a2"), mem.collectTxs(-1))
+	assert.Equal(txs("a1", "u"), mem.collectTxs(2))
+
+	// a2 doesn't go without a1, even if its lane has room
+	mem = newMempool("3:50,0:50")
+	add(mem, "a1", 5, "a", 1)
+	add(mem, "u", 9, "", 0)
+	add(mem, "a2", 1, "a", 2)
+	assert.Equal(txs("u"), mem.collectTxs(2))
+	assert.Equal(txs("u", "a1", "a2"), mem.collectTxs(3))
+}
@@ -0,0 +1,116 @@
+package mempool
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	cfg "github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	// spam score added for a tx rejected by CheckTx
+	spamScoreInvalid = 10
+	// spam score added for a tx sent again, over the rate limit, or while throttled
+	spamScoreDropped = 1
+	// the spam score of a peer halves over this period
+	spamScoreHalfLife = time.Minute
+	// number of the last txs received from a peer remembered to detect duplicates
+	peerSeenTxs = 1000
+)
+
+// peerSpam rate limits the txs received from a peer and scores its spam:
+// txs rejected by CheckTx, sent twice, or over the rate limit.
+// Once the score reaches MempoolConfig.PeerSpamThrottle, the txs of the peer
+// are dropped until the score decays, and once it reaches PeerSpamDisconnect,
+// the peer should be disconnected.
+type peerSpam struct {
+	config *cfg.MempoolConfig
+
+	mtx        sync.Mutex
+	score      float64
+	lastDecay  time.Time
+	tokens     float64
+	lastRefill time.Time
+	seen       map[string]struct{}
+	seenRing   []string
+	seenNext   int
+}
+
+func newPeerSpam(config *cfg.MempoolConfig, now time.Time) *peerSpam {
+	return &peerSpam{
+		config:     config,
+		lastDecay:  now,
+		tokens:     float64(config.PeerMaxTxsPerSecond),
+		lastRefill: now,
+		seen:       make(map[string]struct{}),
+		seenRing:   make([]string, peerSeenTxs),
+	}
+}
+
+// admit returns true if the tx should be checked, or the reason it is dropped.
+func (ps *peerSpam) admit(tx types.Tx, now time.Time) (bool, string) {
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
+	ps.decay(now)
+
+	if throttle := ps.config.PeerSpamThrottle; throttle > 0 && ps.score >= float64(throttle) {
+		ps.score += spamScoreDropped
+		return false, "throttled"
+	}
+
+	key := string(tx.Hash())
+	if _, ok := ps.seen[key]; ok {
+		ps.score += spamScoreDropped
+		return false, "duplicate"
+	}
+
+	if rate := float64(ps.config.PeerMaxTxsPerSecond); rate > 0 {
+		ps.tokens = math.Min(rate, ps.tokens+rate*now.Sub(ps.lastRefill).Seconds())
+		ps.lastRefill = now
+		if ps.tokens < 1 {
+			ps.score += spamScoreDropped
+			return false, "rate limited"
+		}
+		ps.tokens--
+	}
+
+	delete(ps.seen, ps.seenRing[ps.seenNext])
+	ps.seenRing[ps.seenNext] = key
+	ps.seenNext = (ps.seenNext + 1) % len(ps.seenRing)
+	ps.seen[key] = struct{}{}
+	return true, ""
+}
+
+// invalid scores a tx of the peer rejected by CheckTx.
+func (ps *peerSpam) invalid(now time.Time) {
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
+	ps.decay(now)
+	ps.score += spamScoreInvalid
+}
+
+// shouldDisconnect returns true if the peer has spammed enough to be disconnected.
+func (ps *peerSpam) shouldDisconnect() bool {
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
+	disconnect := ps.config.PeerSpamDisconnect
+	return disconnect > 0 && ps.score >= float64(disconnect)
+}
+
+// Score returns the current spam score of the peer.
+func (ps *peerSpam) Score() float64 {
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
+	return ps.score
+}
+
+func (ps *peerSpam) decay(now time.Time) {
+	elapsed := now.Sub(ps.lastDecay)
+	if elapsed <= 0 {
+		return
+	}
+	ps.score *= math.Pow(0.5, float64(elapsed)/float64(spamScoreHalfLife))
+	ps.lastDecay = now
+}
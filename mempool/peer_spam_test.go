@@ -0,0 +1,56 @@
+package mempool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	cfg "github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestPeerSpam(t *testing.T) {
+	assert := assert.New(t)
+
+	config := cfg.DefaultMempoolConfig()
+	config.PeerMaxTxsPerSecond = 2
+	config.PeerSpamThrottle = 20
+	config.PeerSpamDisconnect = 30
+	now := time.Now()
+	spam := newPeerSpam(config, now)
+
+	ok, _ := spam.admit(types.Tx("a"), now)
+	assert.True(ok)
+	ok, reason := spam.admit(types.Tx("a"), now)
+	assert.False(ok)
+	assert.Equal("duplicate", reason)
+	ok, _ = spam.admit(types.Tx("b"), now)
+	assert.True(ok)
+	ok, reason = spam.admit(types.Tx("c"), now)
+	assert.False(ok)
+	assert.Equal("rate limited", reason)
+	assert.EqualValues(2, spam.Score())
+
+	// the tokens refill over time
+	now = now.Add(time.Second)
+	ok, _ = spam.admit(types.Tx("c"), now)
+	assert.True(ok)
+
+	// invalid txs get the peer throttled, then disconnected
+	spam.invalid(now)
+	spam.invalid(now)
+	assert.False(spam.shouldDisconnect())
+	ok, reason = spam.admit(types.Tx("d"), now)
+	assert.False(ok)
+	assert.Equal("throttled", reason)
+	for i := 0; i < 10; i++ {
+		spam.admit(types.Tx("d"), now)
+	}
+	assert.True(spam.shouldDisconnect())
+
+	// the score decays
+	score := spam.Score()
+	spam.invalid(now.Add(spamScoreHalfLife))
+	assert.InDelta(score/2+spamScoreInvalid, spam.Score(), 0.001)
+}
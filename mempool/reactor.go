@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"reflect"
+	"sync"
 	"time"
 
 	abci "github.com/tendermint/abci/types"
@@ -28,6 +29,9 @@ type MempoolReactor struct {
 	p2p.BaseReactor
 	config  *cfg.MempoolConfig
 	Mempool *Mempool
+
+	mtx   sync.Mutex
+	spams map[string]*peerSpam // spam scores by peer key
 }
 
 // NewMempoolReactor returns a new MempoolReactor with the given config and mempool.
@@ -35,6 +39,7 @@ func NewMempoolReactor(config *cfg.MempoolConfig, mempool *Mempool) *MempoolReac
 	memR := &MempoolReactor{
 		config:  config,
 		Mempool: mempool,
+		spams:   make(map[string]*peerSpam),
 	}
 	memR.BaseReactor = *p2p.NewBaseReactor("MempoolReactor", memR)
 	return memR
@@ -60,14 +65,36 @@ func (memR *MempoolReactor) GetChannels() []*p2p.ChannelDescriptor {
 // AddPeer implements Reactor.
 // It starts a broadcast routine ensuring all txs are forwarded to the given peer.
 func (memR *MempoolReactor) AddPeer(peer p2p.Peer) {
+	memR.mtx.Lock()
+	memR.spams[peer.Key()] = newPeerSpam(memR.config, time.Now())
+	memR.mtx.Unlock()
+
 	go memR.broadcastTxRoutine(peer)
 }
 
 // RemovePeer implements Reactor.
 func (memR *MempoolReactor) RemovePeer(peer p2p.Peer, reason interface{}) {
+	memR.mtx.Lock()
+	delete(memR.spams, peer.Key())
+	memR.mtx.Unlock()
 	// broadcast routine checks if peer is gone and returns
 }
 
+// peerSpam returns the spam score of the peer, nil if it was removed.
+func (memR *MempoolReactor) peerSpam(peer p2p.Peer) *peerSpam {
+	memR.mtx.Lock()
+	defer memR.mtx.Unlock()
+	return memR.spams[peer.Key()]
+}
+
+// checkSpam disconnects the peer if it has spammed too much, which gets it
+// banned if it keeps doing it.
+func (memR *MempoolReactor) checkSpam(peer p2p.Peer, spam *peerSpam) {
+	if spam.shouldDisconnect() && memR.Switch != nil {
+		memR.Switch.ReportMisbehavior(peer, p2p.SeverityMedium, fmt.Errorf("Mempool spam score %.0f", spam.Score()))
+	}
+}
+
 // Receive implements Reactor.
 // It adds any received transactions to the mempool.
 func (memR *MempoolReactor) Receive(chID byte, src p2p.Peer, msgBytes []byte) {
@@ -80,10 +107,31 @@ func (memR *MempoolReactor) Receive(chID byte, src p2p.Peer, msgBytes []byte) {
 
 	switch msg := msg.(type) {
 	case *TxMessage:
-		err := memR.Mempool.CheckTx(msg.Tx, nil)
+		if memR.Mempool.IsPaused() {
+			// not the peer's fault, we're catching up
+			return
+		}
+		spam := memR.peerSpam(src)
+		if spam == nil {
+			return
+		}
+		if ok, reason := spam.admit(msg.Tx, time.Now()); !ok {
+			memR.Logger.Debug("Dropped tx", "src", src, "reason", reason, "spamScore", spam.Score())
+			memR.checkSpam(src, spam)
+			return
+		}
+		// NOTE: the callback only scores the peer: it may run with the mempool
+		// locked, so the peer is disconnected after CheckTx returns, or on its
+		// next tx if the response comes later
+		err := memR.Mempool.CheckTx(msg.Tx, func(res *abci.Response) {
+			if r := res.GetCheckTx(); r != nil && r.Code != abci.CodeTypeOK {
+				spam.invalid(time.Now())
+			}
+		})
 		if err != nil {
 			memR.Logger.Info("Could not check tx", "tx", msg.Tx, "err", err)
 		}
+		memR.checkSpam(src, spam)
 		// broadcasting happens from go routines per peer
 	default:
 		memR.Logger.Error(fmt.Sprintf("Unknown message type %v", reflect.TypeOf(msg)))
@@ -126,6 +174,11 @@ func (memR *MempoolReactor) broadcastTxRoutine(peer Peer) {
 			// Go ahead and start from the beginning.
 			next = memR.Mempool.TxsFrontWait() // Wait until a tx is available
 		}
+		// don't gossip while catching up
+		if memR.Mempool.IsPaused() {
+			time.Sleep(peerCatchupSleepIntervalMS * time.Millisecond)
+			continue
+		}
 		memTx := next.Value.(*mempoolTx)
 		// make sure the peer is up to date
 		height := memTx.Height()
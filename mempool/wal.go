@@ -0,0 +1,240 @@
+package mempool
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+
+	auto "github.com/tendermint/tmlibs/autofile"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+/*
+
+The mempool WAL holds one hex encoded tx per line.
+Txs are appended as they come in, before CheckTx, and the hex encoded hashes
+of the txs committed or expired are appended after each Update(), prefixed
+with "-", so on restart ReplayWAL() only has to run CheckTx on the txs that
+weren't removed. Once the WAL has more than twice as many lines as the mempool
+has txs, it's compacted: rewritten with the txs of the mempool and the ones
+still waiting for their CheckTx response.
+
+NOTE: txs committed in a block right before a crash, ie. before their removal
+was appended, are replayed too. It's up to the app to reject them in CheckTx,
+as it must for any tx sent twice. So are the txs rejected by the recheck
+since the last compaction.
+
+*/
+
+const (
+	// maxWALLineSize is the max size of a line of the WAL, ie. a 4MB tx, hex encoded
+	maxWALLineSize = 8 * 1024 * 1024
+
+	// minWALCompactLines is the number of lines below which the WAL isn't compacted
+	minWALCompactLines = 1000
+)
+
+// writeWALTx writes the tx as a line of the WAL.
+func writeWALTx(w io.Writer, tx types.Tx) error {
+	_, err := fmt.Fprintf(w, "%X\n", []byte(tx))
+	return err
+}
+
+// writeWALRemoval writes the removal of the tx as a line of the WAL.
+func writeWALRemoval(w io.Writer, tx types.Tx) error {
+	_, err := fmt.Fprintf(w, "-%X\n", tx.Hash())
+	return err
+}
+
+// readWAL returns the txs in the WAL at path, in order, without the removed ones.
+// Lines that don't decode, eg. from an older WAL format, are skipped.
+func readWAL(path string) (txs []types.Tx, skipped int, err error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	} else if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	// the indexes of the txs not removed yet, by hash
+	indexes := make(map[string][]int)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxWALLineSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		removal := line[0] == '-'
+		if removal {
+			line = line[1:]
+		}
+		bz := make([]byte, hex.DecodedLen(len(line)))
+		if _, err := hex.Decode(bz, line); err != nil {
+			skipped++
+			continue
+		}
+		if removal {
+			// the first tx with the hash is removed, if any
+			if idx := indexes[string(bz)]; len(idx) > 0 {
+				txs[idx[0]] = nil
+				indexes[string(bz)] = idx[1:]
+			}
+			continue
+		}
+		tx := types.Tx(bz)
+		indexes[string(tx.Hash())] = append(indexes[string(tx.Hash())], len(txs))
+		txs = append(txs, tx)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, skipped, err
+	}
+
+	left := txs[:0]
+	for _, tx := range txs {
+		if tx != nil {
+			left = append(left, tx)
+		}
+	}
+	return left, skipped, nil
+}
+
+// appendWALRemovals appends the removal of the txs to the WAL, and compacts it
+// if it got too long.
+// NOTE: unsafe; Lock/Unlock must be managed by caller
+func (mem *Mempool) appendWALRemovals(txs []types.Tx) error {
+	if mem.wal == nil {
+		return nil
+	}
+	w := bufio.NewWriter(mem.wal)
+	for _, tx := range txs {
+		if err := writeWALRemoval(w, tx); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	mem.walLines += len(txs)
+
+	if mem.walLines < minWALCompactLines || mem.walLines <= 2*(mem.txs.Len()+mem.numPending()) {
+		return nil
+	}
+	return mem.rewriteWAL()
+}
+
+// rewriteWAL replaces the WAL with the txs in the mempool, followed by the
+// ones waiting for their CheckTx response.
+// NOTE: unsafe; Lock/Unlock must be managed by caller
+func (mem *Mempool) rewriteWAL() error {
+	if mem.wal == nil {
+		return nil
+	}
+	path := mem.wal.Path
+	tmpPath := path + ".tmp"
+
+	txs := make([]types.Tx, 0, mem.txs.Len())
+	for e := mem.txs.Front(); e != nil; e = e.Next() {
+		txs = append(txs, e.Value.(*mempoolTx).tx)
+	}
+	txs = append(txs, mem.pendingTxs()...)
+
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, tx := range txs {
+		if err = writeWALTx(w, tx); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	mem.walLines = len(txs)
+	return mem.swapWAL(func() error {
+		return os.Rename(tmpPath, path)
+	})
+}
+
+// swapWAL closes the WAL file, runs fn and reopens it.
+// NOTE: unsafe; Lock/Unlock must be managed by caller
+func (mem *Mempool) swapWAL(fn func() error) error {
+	path := mem.wal.Path
+	if err := mem.wal.Close(); err != nil {
+		return err
+	}
+	err := fn()
+	af, oerr := auto.OpenAutoFile(path)
+	if oerr != nil {
+		// Don't write to a closed file, the WAL is off until restart
+		mem.wal = nil
+		return errors.Wrap(oerr, "Error reopening Mempool wal file")
+	}
+	mem.wal = af
+	return err
+}
+
+// ReplayWAL runs CheckTx on the txs in the WAL, ie. the txs that were in
+// the mempool when the node stopped. Txs that pass are added back.
+// It should be called once, on startup, before the mempool gets new txs.
+// If the mempool is paused, the txs are only replayed on Resume, once the
+// app is caught up.
+func (mem *Mempool) ReplayWAL() error {
+	mem.proxyMtx.Lock()
+	if mem.wal == nil {
+		mem.proxyMtx.Unlock()
+		return nil
+	}
+	path := mem.wal.Path
+	txs, skipped, err := readWAL(path)
+	if err == nil && mem.IsPaused() {
+		mem.proxyMtx.Unlock()
+		atomic.StoreInt32(&mem.walReplayPending, 1)
+		mem.logger.Info("Replaying Mempool wal once resumed", "txs", len(txs))
+		return nil
+	}
+	if err == nil {
+		// CheckTx writes the txs back
+		err = mem.swapWAL(func() error {
+			return os.Truncate(path, 0)
+		})
+		mem.walLines = 0
+	}
+	mem.proxyMtx.Unlock()
+	if err != nil {
+		return errors.Wrap(err, "Error replaying Mempool wal")
+	}
+	if skipped > 0 {
+		mem.logger.Error("Skipped undecodable lines of the Mempool wal", "skipped", skipped)
+	}
+
+	mem.logger.Info("Replaying Mempool wal", "txs", len(txs))
+	for _, tx := range txs {
+		// the tx was seen already, and may be in the loaded cache
+		mem.cache.Remove(tx)
+		if err := mem.CheckTx(tx, nil); err != nil {
+			mem.logger.Info("Error replaying tx", "tx", tx, "err", err)
+		}
+	}
+	return nil
+}
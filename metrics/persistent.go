@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"strings"
+	"time"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	wire "github.com/tendermint/go-wire"
+	cmn "github.com/tendermint/tmlibs/common"
+	dbm "github.com/tendermint/tmlibs/db"
+)
+
+const persistedCounterPrefix = "counter:"
+
+// PersistentCounters saves the counts of some counters of a registry in a db,
+// and adds them back on start, so the totals they count, eg. the txs committed,
+// keep growing across restarts instead of starting from zero, which Prometheus
+// would see as a reset. The counters are selected by the prefixes of their names.
+//
+// The counts are saved every interval and on stop, so a crash loses at most
+// what was counted during an interval.
+type PersistentCounters struct {
+	cmn.BaseService
+
+	registry gometrics.Registry
+	db       dbm.DB
+	prefixes []string
+	interval time.Duration
+}
+
+// NewPersistentCounters returns a service persisting the counters of the registry
+// whose names start with one of the prefixes in the db, every interval.
+// If registry is nil, metrics.DefaultRegistry is used.
+func NewPersistentCounters(registry gometrics.Registry, db dbm.DB, interval time.Duration,
+	prefixes ...string) *PersistentCounters {
+	if registry == nil {
+		registry = gometrics.DefaultRegistry
+	}
+	pc := &PersistentCounters{
+		registry: registry,
+		db:       db,
+		prefixes: prefixes,
+		interval: interval,
+	}
+	pc.BaseService = *cmn.NewBaseService(nil, "PersistentCounters", pc)
+	return pc
+}
+
+// OnStart implements cmn.Service by restoring the counts saved,
+// and starting the routine saving them.
+func (pc *PersistentCounters) OnStart() error {
+	pc.Restore()
+	go pc.saveRoutine()
+	return nil
+}
+
+// OnStop implements cmn.Service by saving the counts.
+func (pc *PersistentCounters) OnStop() {
+	pc.Save()
+}
+
+func (pc *PersistentCounters) saveRoutine() {
+	ticker := time.NewTicker(pc.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			pc.Save()
+		case <-pc.Quit:
+			return
+		}
+	}
+}
+
+// Restore adds the counts saved to the counters, registering the ones not
+// registered yet. It must be called once, before Save.
+func (pc *PersistentCounters) Restore() {
+	it := pc.db.IteratorPrefix([]byte(persistedCounterPrefix))
+	defer it.Release()
+	for it.Next() {
+		name := strings.TrimPrefix(string(it.Key()), persistedCounterPrefix)
+		var count int64
+		if err := wire.ReadBinaryBytes(it.Value(), &count); err != nil {
+			pc.Logger.Error("Failed to restore a counter", "name", name, "err", err)
+			continue
+		}
+		gometrics.GetOrRegisterCounter(name, pc.registry).Inc(count)
+	}
+}
+
+// Save saves the counts of the counters persisted.
+func (pc *PersistentCounters) Save() {
+	batch := pc.db.NewBatch()
+	pc.registry.Each(func(name string, metric interface{}) {
+		counter, ok := metric.(gometrics.Counter)
+		if !ok || !pc.persisted(name) {
+			return
+		}
+		batch.Set([]byte(persistedCounterPrefix+name), wire.BinaryBytes(counter.Count()))
+	})
+	batch.Write()
+}
+
+func (pc *PersistentCounters) persisted(name string) bool {
+	for _, prefix := range pc.prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	gometrics "github.com/rcrowley/go-metrics"
+	dbm "github.com/tendermint/tmlibs/db"
+)
+
+func TestPersistentCounters(t *testing.T) {
+	assert := assert.New(t)
+	db := dbm.NewMemDB()
+
+	registry := gometrics.NewRegistry()
+	gometrics.GetOrRegisterCounter("consensus.total_txs", registry).Inc(12)
+	gometrics.GetOrRegisterCounter("p2p.channel.20.send_bytes", registry).Inc(300)
+	gometrics.GetOrRegisterCounter("mempool.evicted_txs", registry).Inc(3)
+	gometrics.GetOrRegisterGauge("consensus.total_blocks", registry).Update(5)
+	pc := NewPersistentCounters(registry, db, 0, "consensus.", "p2p.channel.")
+	pc.Save()
+
+	// after a restart, the counters registered before or after the restore
+	// continue from the counts saved
+	registry = gometrics.NewRegistry()
+	totalTxs := gometrics.GetOrRegisterCounter("consensus.total_txs", registry)
+	totalTxs.Inc(1)
+	pc = NewPersistentCounters(registry, db, 0, "consensus.", "p2p.channel.")
+	pc.Restore()
+	assert.EqualValues(13, totalTxs.Count())
+	assert.EqualValues(300, gometrics.GetOrRegisterCounter("p2p.channel.20.send_bytes", registry).Count())
+	assert.EqualValues(0, gometrics.GetOrRegisterCounter("mempool.evicted_txs", registry).Count())
+	assert.Nil(registry.Get("consensus.total_blocks"))
+
+	totalTxs.Inc(2)
+	pc.Save()
+	registry = gometrics.NewRegistry()
+	NewPersistentCounters(registry, db, 0, "consensus.").Restore()
+	assert.EqualValues(15, gometrics.GetOrRegisterCounter("consensus.total_txs", registry).Count())
+}
@@ -0,0 +1,110 @@
+// Package metrics exposes the metrics of a node, registered in a go-metrics
+// registry by the consensus, the mempool and the p2p switch, to Prometheus.
+package metrics
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+// Quantiles reported for the histograms and timers
+var quantiles = []float64{0.5, 0.75, 0.95, 0.99}
+
+// PrometheusHandler serves the metrics of the registry for Prometheus.
+func PrometheusHandler(registry gometrics.Registry, namespace string) http.Handler {
+	promRegistry := prometheus.NewRegistry()
+	promRegistry.MustRegister(NewPrometheusCollector(registry, namespace))
+	return promhttp.HandlerFor(promRegistry, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+// PrometheusCollector collects the metrics of a go-metrics registry for
+// Prometheus. The names are prefixed with the namespace, and the characters
+// Prometheus doesn't allow, eg. the dots, are replaced with underscores:
+// p2p.peers becomes <namespace>_p2p_peers.
+//
+// Counters and meters are exported as counters, gauges as gauges, and
+// histograms and timers as summaries. Timers are in seconds, with a _seconds suffix.
+type PrometheusCollector struct {
+	registry  gometrics.Registry
+	namespace string
+}
+
+var _ prometheus.Collector = (*PrometheusCollector)(nil)
+
+// NewPrometheusCollector returns a collector of the metrics of the registry.
+func NewPrometheusCollector(registry gometrics.Registry, namespace string) *PrometheusCollector {
+	return &PrometheusCollector{
+		registry:  registry,
+		namespace: namespace,
+	}
+}
+
+// Describe implements prometheus.Collector. It describes no metrics, as the
+// registry gets new ones over time, eg. for each peer, so the collector is
+// unchecked.
+func (c *PrometheusCollector) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (c *PrometheusCollector) Collect(ch chan<- prometheus.Metric) {
+	c.registry.Each(func(name string, metric interface{}) {
+		if m := collectMetric(prometheusName(c.namespace, name), name, metric); m != nil {
+			ch <- m
+		}
+	})
+}
+
+// collectMetric returns the Prometheus metric of the go-metrics one, nil if
+// its type isn't supported.
+func collectMetric(name, help string, metric interface{}) prometheus.Metric {
+	switch m := metric.(type) {
+	case gometrics.Counter:
+		return constMetric(name, help, prometheus.CounterValue, float64(m.Count()))
+	case gometrics.Meter:
+		return constMetric(name, help, prometheus.CounterValue, float64(m.Count()))
+	case gometrics.Gauge:
+		return constMetric(name, help, prometheus.GaugeValue, float64(m.Value()))
+	case gometrics.GaugeFloat64:
+		return constMetric(name, help, prometheus.GaugeValue, m.Value())
+	case gometrics.Histogram:
+		h := m.Snapshot()
+		return constSummary(name, help, h.Percentiles(quantiles), float64(h.Sum()), h.Count(), 1)
+	case gometrics.Timer:
+		t := m.Snapshot()
+		return constSummary(name+"_seconds", help, t.Percentiles(quantiles), float64(t.Sum()), t.Count(), 1e9)
+	}
+	return nil
+}
+
+func constMetric(name, help string, typ prometheus.ValueType, value float64) prometheus.Metric {
+	desc := prometheus.NewDesc(name, help, nil, nil)
+	return prometheus.MustNewConstMetric(desc, typ, value)
+}
+
+// constSummary returns a summary, with the values divided by unit.
+func constSummary(name, help string, values []float64, sum float64, count int64, unit float64) prometheus.Metric {
+	desc := prometheus.NewDesc(name, help, nil, nil)
+	qs := make(map[float64]float64, len(quantiles))
+	for i, q := range quantiles {
+		qs[q] = values[i] / unit
+	}
+	return prometheus.MustNewConstSummary(desc, uint64(count), sum/unit, qs)
+}
+
+// prometheusName returns the name of the metric in Prometheus.
+func prometheusName(namespace, name string) string {
+	if namespace != "" {
+		name = namespace + "_" + name
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == ':' {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	gometrics "github.com/rcrowley/go-metrics"
+)
+
+func TestPrometheusHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	registry := gometrics.NewRegistry()
+	gometrics.GetOrRegisterCounter("mempool.evicted_txs", registry).Inc(3)
+	gometrics.GetOrRegisterGauge("p2p.peers", registry).Update(7)
+	gometrics.GetOrRegisterHistogram("consensus.rounds", registry, gometrics.NewUniformSample(100)).Update(1)
+	gometrics.GetOrRegisterTimer("consensus.round_duration", registry).Update(2 * time.Second)
+
+	rec := httptest.NewRecorder()
+	PrometheusHandler(registry, "tendermint").ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(200, rec.Code)
+	assert.Equal(`# HELP tendermint_consensus_round_duration_seconds consensus.round_duration
+# TYPE tendermint_consensus_round_duration_seconds summary
+tendermint_consensus_round_duration_seconds{quantile="0.5"} 2
+tendermint_consensus_round_duration_seconds{quantile="0.75"} 2
+tendermint_consensus_round_duration_seconds{quantile="0.95"} 2
+tendermint_consensus_round_duration_seconds{quantile="0.99"} 2
+tendermint_consensus_round_duration_seconds_sum 2
+tendermint_consensus_round_duration_seconds_count 1
+# HELP tendermint_consensus_rounds consensus.rounds
+# TYPE tendermint_consensus_rounds summary
+tendermint_consensus_rounds{quantile="0.5"} 1
+tendermint_consensus_rounds{quantile="0.75"} 1
+tendermint_consensus_rounds{quantile="0.95"} 1
+tendermint_consensus_rounds{quantile="0.99"} 1
+tendermint_consensus_rounds_sum 1
+tendermint_consensus_rounds_count 1
+# HELP tendermint_mempool_evicted_txs mempool.evicted_txs
+# TYPE tendermint_mempool_evicted_txs counter
+tendermint_mempool_evicted_txs 3
+# HELP tendermint_p2p_peers p2p.peers
+# TYPE tendermint_p2p_peers gauge
+tendermint_p2p_peers 7
+`, rec.Body.String())
+
+	// the metrics registered later are collected too
+	gometrics.GetOrRegisterGauge("p2p.peer.AB12.channel.01.send_bytes", registry).Update(1)
+	rec = httptest.NewRecorder()
+	PrometheusHandler(registry, "tendermint").ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Contains(rec.Body.String(), "\ntendermint_p2p_peer_AB12_channel_01_send_bytes 1\n")
+}
+
+func TestPrometheusName(t *testing.T) {
+	assert.Equal(t, "tm_p2p_peer_AB12_channel_01_send_bytes", prometheusName("tm", "p2p.peer.AB12.channel.01.send_bytes"))
+	assert.Equal(t, "mempool_size", prometheusName("", "mempool.size"))
+}
@@ -0,0 +1,86 @@
+package node
+
+import (
+	"github.com/tendermint/tendermint/p2p"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+)
+
+// Hooks are the functions called by a node as it runs, for the Go apps
+// embedding it to react to its events synchronously, without subscribing to
+// the EventBus. All of them are optional.
+//
+// They are called from the goroutines of the node, which wait for them to
+// return, so they should be quick: a slow hook slows down the node.
+type Hooks struct {
+	// OnBlockCommitted is called once a block is committed, by the consensus or
+	// by fast sync, with a copy of the state after it. It's called for each block
+	// in order, never concurrently. It's called with the consensus state locked:
+	// it must not call the ConsensusState, or the ConsensusReader, of the node.
+	// NOTE: the blocks replayed by the handshake with the app on start are not
+	// reported.
+	OnBlockCommitted func(block *types.Block, state *sm.State)
+
+	// OnValidatorSetChanged is called after OnBlockCommitted, with the same
+	// guarantees, if the block changed the validator set. The validators are
+	// the ones of the next block.
+	OnValidatorSetChanged func(height int64, validators *types.ValidatorSet)
+
+	// OnPeerAdded and OnPeerRemoved are called from the goroutine adding or
+	// removing the peer, so they may be called concurrently, for different peers.
+	// The reason a peer is removed is nil if it was stopped gracefully.
+	OnPeerAdded   func(peer p2p.Peer)
+	OnPeerRemoved func(peer p2p.Peer, reason interface{})
+}
+
+// blockCommittedFunc returns the function calling the block hooks,
+// or nil if there are none.
+func (h Hooks) blockCommittedFunc() sm.BlockCommittedFunc {
+	if h.OnBlockCommitted == nil && h.OnValidatorSetChanged == nil {
+		return nil
+	}
+	return func(block *types.Block, state *sm.State) {
+		if h.OnBlockCommitted != nil {
+			h.OnBlockCommitted(block, state)
+		}
+		// the validators updated by a block apply from the next one
+		if h.OnValidatorSetChanged != nil && state.LastHeightValidatorsChanged == block.Height+1 {
+			h.OnValidatorSetChanged(block.Height, state.Validators)
+		}
+	}
+}
+
+// hasPeerHooks returns true if any of the peer hooks is set.
+func (h Hooks) hasPeerHooks() bool {
+	return h.OnPeerAdded != nil || h.OnPeerRemoved != nil
+}
+
+//------------------------------------------------------------------------------
+
+// hooksReactor calls the peer hooks as the switch adds and removes peers.
+// It has no channels, so it receives no messages.
+type hooksReactor struct {
+	p2p.BaseReactor
+
+	hooks Hooks
+}
+
+func newHooksReactor(hooks Hooks) *hooksReactor {
+	hR := &hooksReactor{hooks: hooks}
+	hR.BaseReactor = *p2p.NewBaseReactor("HooksReactor", hR)
+	return hR
+}
+
+// AddPeer implements p2p.Reactor.
+func (hR *hooksReactor) AddPeer(peer p2p.Peer) {
+	if hR.hooks.OnPeerAdded != nil {
+		hR.hooks.OnPeerAdded(peer)
+	}
+}
+
+// RemovePeer implements p2p.Reactor.
+func (hR *hooksReactor) RemovePeer(peer p2p.Peer, reason interface{}) {
+	if hR.hooks.OnPeerRemoved != nil {
+		hR.hooks.OnPeerRemoved(peer, reason)
+	}
+}
@@ -2,13 +2,19 @@ package node
 
 import (
 	"bytes"
+	"crypto/tls"
+	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"os"
 	"strings"
 
+	metrics "github.com/rcrowley/go-metrics"
+
 	abci "github.com/tendermint/abci/types"
 	crypto "github.com/tendermint/go-crypto"
 	wire "github.com/tendermint/go-wire"
@@ -19,7 +25,9 @@ import (
 	bc "github.com/tendermint/tendermint/blockchain"
 	cfg "github.com/tendermint/tendermint/config"
 	"github.com/tendermint/tendermint/consensus"
+	"github.com/tendermint/tendermint/evidence"
 	mempl "github.com/tendermint/tendermint/mempool"
+	tmmetrics "github.com/tendermint/tendermint/metrics"
 	"github.com/tendermint/tendermint/p2p"
 	"github.com/tendermint/tendermint/p2p/trust"
 	"github.com/tendermint/tendermint/proxy"
@@ -28,10 +36,17 @@ import (
 	rpc "github.com/tendermint/tendermint/rpc/lib"
 	rpcserver "github.com/tendermint/tendermint/rpc/lib/server"
 	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/state/apphash"
+	"github.com/tendermint/tendermint/state/blockindex"
+	blockkv "github.com/tendermint/tendermint/state/blockindex/kv"
+	"github.com/tendermint/tendermint/state/signinginfo"
 	"github.com/tendermint/tendermint/state/txindex"
 	"github.com/tendermint/tendermint/state/txindex/kv"
 	"github.com/tendermint/tendermint/state/txindex/null"
+	"github.com/tendermint/tendermint/state/txindex/psql"
+	"github.com/tendermint/tendermint/statesync"
 	"github.com/tendermint/tendermint/types"
+	"github.com/tendermint/tendermint/types/privval"
 	"github.com/tendermint/tendermint/version"
 
 	_ "net/http/pprof"
@@ -72,16 +87,94 @@ type NodeProvider func(*cfg.Config, log.Logger) (*Node, error)
 
 // DefaultNewNode returns a Tendermint node with default settings for the
 // PrivValidator, ClientCreator, GenesisDoc, and DBProvider.
-// It implements NodeProvider.
+// It implements NodeProvider. It can't state sync, see WithStateProvider.
 func DefaultNewNode(config *cfg.Config, logger log.Logger) (*Node, error) {
+	privValidator, err := DefaultPrivValidator(config)
+	if err != nil {
+		return nil, err
+	}
 	return NewNode(config,
-		types.LoadOrGenPrivValidatorFS(config.PrivValidatorFile()),
+		privValidator,
 		proxy.DefaultClientCreator(config.ProxyApp, config.ABCI, config.DBDir()),
 		DefaultGenesisDocProviderFunc(config),
 		DefaultDBProvider,
 		logger)
 }
 
+// DefaultPrivValidator returns the PrivValidatorFS of the priv_validator_file,
+// signing with the key of the HSM if it's configured, see config.HSMConfig.
+func DefaultPrivValidator(config *cfg.Config) (*types.PrivValidatorFS, error) {
+	if !config.HSM.Enabled() {
+		return types.LoadOrGenPrivValidatorFS(config.PrivValidatorFile()), nil
+	}
+	// NOTE: the session with the HSM lasts as long as the process
+	signer, err := privval.NewPKCS11Signer(config.HSM.Module, config.HSM.TokenLabel, config.HSM.KeyLabel, config.HSM.PIN)
+	if err != nil {
+		return nil, err
+	}
+	return types.LoadOrGenPrivValidatorFSWithSigner(config.PrivValidatorFile(), signer, signer.PubKey())
+}
+
+// startRemoteSigners waits for the remote signers to connect to the priv_validator_laddr,
+// and returns the PrivValidator signing with them: the remote signer, or with several
+// addresses, the multisig of priv_validator_threshold of them. The connections are
+// authenticated with the node key, and the priv_validator_authorized_keys of the signers.
+func startRemoteSigners(config *cfg.Config, logger log.Logger) (types.PrivValidator, []*privval.PrivValidatorSocketClient, error) {
+	nodeKey, err := p2p.LoadOrGenNodeKey(config.NodeKeyFile())
+	if err != nil {
+		return nil, nil, err
+	}
+	connKey, ok := nodeKey.PrivKey.Unwrap().(crypto.PrivKeyEd25519)
+	if !ok {
+		return nil, nil, errors.New("The remote signer connections need an ed25519 node key")
+	}
+	authorizedKeys, err := privval.ParseConnKeys(config.PrivValidatorAuthorizedKeys)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(authorizedKeys) == 0 {
+		return nil, nil, fmt.Errorf("priv_validator_laddr needs the connection keys of the remote signers in "+
+			"priv_validator_authorized_keys; the one of this node, for their --node_keys, is %v",
+			connKey.PubKey().Unwrap().(crypto.PubKeyEd25519).KeyString())
+	}
+
+	addrs := strings.Split(config.PrivValidatorListenAddr, ",")
+	privValSockets := make([]*privval.PrivValidatorSocketClient, 0, len(addrs))
+	signers := make([]types.PrivValidator, 0, len(addrs))
+	stopAll := func() {
+		for _, privValSocket := range privValSockets {
+			privValSocket.Stop()
+		}
+	}
+	for _, addr := range addrs {
+		privValSocket := privval.NewPrivValidatorSocketClient(addr, connKey)
+		privValSocket.SetLogger(logger.With("addr", addr))
+		privValSocket.SetAuthorizedKeys(authorizedKeys)
+		if err := privValSocket.Start(); err != nil {
+			stopAll()
+			return nil, nil, fmt.Errorf("Error starting the remote signer connection: %v", err)
+		}
+		privValSockets = append(privValSockets, privValSocket)
+		signers = append(signers, privValSocket)
+	}
+	if len(signers) == 1 {
+		return signers[0], privValSockets, nil
+	}
+
+	threshold := config.PrivValidatorThreshold
+	if threshold == 0 {
+		threshold = len(signers)
+	}
+	multisig, err := privval.NewPrivValidatorMultisig(threshold, signers)
+	if err != nil {
+		stopAll()
+		return nil, nil, err
+	}
+	logger.Info("Signing with a multisig of the remote signers", "threshold", threshold, "signers", len(signers),
+		"address", fmt.Sprintf("%X", multisig.GetAddress()))
+	return multisig, privValSockets, nil
+}
+
 //------------------------------------------------------------------------------
 
 // Node is the highest level interface to a full Tendermint node.
@@ -90,43 +183,124 @@ type Node struct {
 	cmn.BaseService
 
 	// config
-	config        *cfg.Config
-	genesisDoc    *types.GenesisDoc   // initial validator set
-	privValidator types.PrivValidator // local node's validator key
+	config         *cfg.Config
+	genesisDoc     *types.GenesisDoc                    // initial validator set
+	privValidator  types.PrivValidator                  // local node's validator key
+	privValSockets []*privval.PrivValidatorSocketClient // connections to the remote signers, if any
 
 	// network
 	privKey          crypto.PrivKeyEd25519   // local node's p2p key
 	sw               *p2p.Switch             // p2p connections
 	addrBook         *p2p.AddrBook           // known peers
 	trustMetricStore *trust.TrustMetricStore // trust metrics for all peers
+	dnsSeeder        *p2p.DNSSeeder          // discovers the seeds through DNS, if any
 
 	// services
 	eventBus         *types.EventBus             // pub/sub for services
 	blockStore       *bc.BlockStore              // store the blockchain to disk
 	bcReactor        *bc.BlockchainReactor       // for fast-syncing
+	stateSyncReactor *statesync.Reactor          // for serving and restoring the snapshots of the app
+	stateSyncGenesis *sm.State                   // the state to build the one of the snapshot upon, if state syncing
+	stateProvider    StateProviderFunc           // of the states to state sync from, if state syncing
 	mempoolReactor   *mempl.MempoolReactor       // for gossipping transactions
+	evidencePool     *evidence.EvidencePool      // tracking evidence
 	consensusState   *consensus.ConsensusState   // latest consensus state
 	consensusReactor *consensus.ConsensusReactor // for participating in the consensus
 	proxyApp         proxy.AppConns              // connection to the application
 	rpcListeners     []net.Listener              // rpc servers
+	rpcAccessLog     *os.File                    // where the rpc servers log the requests, if enabled
 	txIndexer        txindex.TxIndexer
 	indexerService   *txindex.IndexerService
+	abciRespPruner   *sm.ABCIResponsesPruner // prunes the ABCI responses not retained, if any
+	blockPruner      *bc.BlockPruner         // prunes the blocks not retained, if any
+	integrityChecker *sm.IntegrityChecker    // cross-checks the blocks and the state db, if enabled
+	signingInfoStore *signinginfo.Store      // which validators signed the commits, if indexed
+	signingIndexer   *signinginfo.IndexerService
+	appHashStore     *apphash.Store // the app hashes of the headers, if indexed
+	appHashIndexer   *apphash.IndexerService
+	blockIndexer     blockindex.BlockIndexer // the tags of the blocks, if indexed
+	blockIndexerSvc  *blockindex.IndexerService
+	metricsRegistry  metrics.Registry // metrics of the services
+	metricsListener  net.Listener     // metrics server
+
+	persistentCounters *tmmetrics.PersistentCounters // saves the totals counted by the metrics, if enabled
+	systemdNotifier    *systemdNotifier              // notifies systemd of the state of the node, if enabled and run by it
 }
 
+// persistedCounters are the prefixes of the names of the metrics counting totals
+// saved across restarts: the txs and blocks committed, and the p2p traffic of
+// each channel, summed over the peers.
+var persistedCounters = []string{"consensus.total_txs", "consensus.total_blocks", "p2p.channel."}
+
 // NewNode returns a new, ready to go, Tendermint Node.
+// The options override the components it builds by default.
 func NewNode(config *cfg.Config,
 	privValidator types.PrivValidator,
 	clientCreator proxy.ClientCreator,
 	genesisDocProvider GenesisDocProvider,
 	dbProvider DBProvider,
-	logger log.Logger) (*Node, error) {
+	logger log.Logger,
+	options ...Option) (*Node, error) {
 
-	// Get BlockStore
-	blockStoreDB, err := dbProvider(&DBContext{"blockstore", config})
+	opts := &nodeOptions{
+		mempoolProvider: DefaultMempoolProvider,
+	}
+	for _, option := range options {
+		option(opts)
+	}
+
+	// Sign with the remote signers, if any
+	var privValSockets []*privval.PrivValidatorSocketClient
+	if config.PrivValidatorListenAddr != "" {
+		var err error
+		privValidator, privValSockets, err = startRemoteSigners(config, logger.With("module", "privval"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	strictFsync, err := config.StrictFsync()
+	if err != nil {
+		return nil, err
+	}
+	fastSyncV2, err := config.UseFastSyncV2()
 	if err != nil {
 		return nil, err
 	}
-	blockStore := bc.NewBlockStore(blockStoreDB)
+	if err := config.Consensus.ValidateTimeouts(); err != nil {
+		return nil, err
+	}
+	if err := config.RPC.ValidateSubscriptions(); err != nil {
+		return nil, err
+	}
+	if err := config.RPC.ValidateTLS(); err != nil {
+		return nil, err
+	}
+	if err := config.RPC.ValidateRateLimits(); err != nil {
+		return nil, err
+	}
+	if err := config.StateSync.Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.P2P.ValidateSeedMode(); err != nil {
+		return nil, err
+	}
+	if err := config.P2P.ValidateChannelRates(); err != nil {
+		return nil, err
+	}
+	if err := config.P2P.ValidateFuzzRates(); err != nil {
+		return nil, err
+	}
+
+	// Get BlockStore
+	blockStore := opts.blockStore
+	if blockStore == nil {
+		blockStoreDB, err := dbProvider(&DBContext{"blockstore", config})
+		if err != nil {
+			return nil, err
+		}
+		blockStore = bc.NewBlockStore(blockStoreDB)
+	}
 
 	consensusLogger := logger.With("module", "consensus")
 	stateLogger := logger.With("module", "state")
@@ -159,10 +333,26 @@ func NewNode(config *cfg.Config,
 	}
 	state.SetLogger(stateLogger)
 
+	// State sync a new node, from a snapshot of the app of the peers,
+	// instead of replaying the whole chain
+	// A seed doesn't sync
+	stateSync := config.StateSync.Enable && state.LastBlockHeight == 0 && !config.P2P.SeedMode
+	if config.StateSync.Enable && state.LastBlockHeight > 0 {
+		logger.Info("Not state syncing, the node has blocks", "height", state.LastBlockHeight)
+	}
+	if stateSync && opts.stateProvider == nil {
+		return nil, errors.New("State sync needs a state provider, see WithStateProvider")
+	}
+
 	// Create the proxyApp, which manages connections (consensus, mempool, query)
-	// and sync tendermint and the app by replaying any necessary blocks
-	handshaker := consensus.NewHandshaker(state, blockStore)
-	handshaker.SetLogger(consensusLogger)
+	// and sync tendermint and the app by replaying any necessary blocks.
+	// The app restoring a snapshot doesn't start from the genesis.
+	var handshaker proxy.Handshaker
+	if !stateSync {
+		h := consensus.NewHandshaker(state, blockStore)
+		h.SetLogger(consensusLogger)
+		handshaker = h
+	}
 	proxyApp := proxy.NewAppConns(clientCreator, handshaker)
 	proxyApp.SetLogger(logger.With("module", "proxy"))
 	if err := proxyApp.Start(); err != nil {
@@ -173,8 +363,13 @@ func NewNode(config *cfg.Config,
 	state = sm.LoadState(stateDB)
 	state.SetLogger(stateLogger)
 
-	// Generate node PrivKey
-	privKey := crypto.GenPrivKeyEd25519()
+	// Load or generate the node key, which the ID of the node is derived from
+	nodeKey, err := p2p.LoadOrGenNodeKey(config.NodeKeyFile())
+	if err != nil {
+		return nil, err
+	}
+	privKey := nodeKey.PrivKey.Unwrap().(crypto.PrivKeyEd25519)
+	logger.Info("P2P node ID", "ID", nodeKey.ID(), "file", config.NodeKeyFile())
 
 	// Decide whether to fast-sync or not
 	// We don't fast-sync when the only validator is us.
@@ -194,36 +389,111 @@ func NewNode(config *cfg.Config,
 	}
 
 	// Make BlockchainReactor
-	bcReactor := bc.NewBlockchainReactor(state.Copy(), proxyApp.Consensus(), blockStore, fastSync)
+	// When state syncing, it fast syncs from the snapshot once restored
+	bcReactor := bc.NewBlockchainReactor(state.Copy(), proxyApp.Consensus(), blockStore, fastSync && !stateSync)
 	bcReactor.SetLogger(logger.With("module", "blockchain"))
+	if strictFsync {
+		bcReactor.SetBatching(1, 0)
+	} else {
+		bcReactor.SetBatching(config.FastSyncBatchSize, config.FastSyncFlush())
+	}
+	bcReactor.SetFastSyncVersion(fastSyncV2)
 
 	// Make MempoolReactor
 	mempoolLogger := logger.With("module", "mempool")
-	mempool := mempl.NewMempool(config.Mempool, proxyApp.Mempool(), state.LastBlockHeight)
+	mempool := opts.mempoolProvider(config.Mempool, proxyApp.Mempool(), state.LastBlockHeight)
 	mempool.SetLogger(mempoolLogger)
+	mempool.SetMetrics(mempl.NewMetrics(opts.metricsRegistry))
+	if n := config.Mempool.RecheckConns; n > 0 {
+		recheckConns, err := proxy.NewAppConnsMempool(clientCreator, n, logger.With("module", "proxy"))
+		if err != nil {
+			return nil, err
+		}
+		mempool.SetRecheckConns(recheckConns)
+	}
 	mempoolReactor := mempl.NewMempoolReactor(config.Mempool, mempool)
 	mempoolReactor.SetLogger(mempoolLogger)
 
 	if config.Consensus.WaitForTxs() {
 		mempool.EnableTxsAvailable()
 	}
+	// The app is behind until fast sync and the replay of the consensus WAL
+	// are over, the ConsensusState resumes the mempool when it starts
+	mempool.Pause()
+	// Get back the txs we had seen, and the ones we had, before stopping.
+	// The WAL is replayed once the mempool is resumed
+	if err := mempool.LoadCache(); err != nil {
+		return nil, err
+	}
+	if err := mempool.ReplayWAL(); err != nil {
+		return nil, err
+	}
+
+	// Make EvidenceReactor
+	evidenceDB, err := dbProvider(&DBContext{"evidence", config})
+	if err != nil {
+		return nil, err
+	}
+	evidenceLogger := logger.With("module", "evidence")
+	evidenceStore := evidence.NewEvidenceStore(evidenceDB)
+	evidencePool := evidence.NewEvidencePool(stateDB, evidenceStore)
+	evidenceReactor := evidence.NewEvidenceReactor(evidencePool)
+	evidenceReactor.SetLogger(evidenceLogger)
+	bcReactor.SetEvidencePool(evidencePool)
 
 	// Make ConsensusReactor
 	consensusState := consensus.NewConsensusState(config.Consensus, state.Copy(), proxyApp.Consensus(), blockStore, mempool)
 	consensusState.SetLogger(consensusLogger)
+	consensusState.SetEvidencePool(evidencePool)
+	consensusState.SetMetrics(consensus.NewMetrics(opts.metricsRegistry))
+	consensusState.SetStrictWALSync(strictFsync)
+	if onBlockCommitted := opts.hooks.blockCommittedFunc(); onBlockCommitted != nil {
+		consensusState.SetBlockCommittedFunc(onBlockCommitted)
+		bcReactor.SetBlockCommittedFunc(onBlockCommitted)
+	}
 	if privValidator != nil {
 		consensusState.SetPrivValidator(privValidator)
 	}
-	consensusReactor := consensus.NewConsensusReactor(consensusState, fastSync)
+	consensusReactor := consensus.NewConsensusReactor(consensusState, fastSync || stateSync)
 	consensusReactor.SetLogger(consensusLogger)
 
 	p2pLogger := logger.With("module", "p2p")
 
 	sw := p2p.NewSwitch(config.P2P)
 	sw.SetLogger(p2pLogger)
-	sw.AddReactor("MEMPOOL", mempoolReactor)
-	sw.AddReactor("BLOCKCHAIN", bcReactor)
-	sw.AddReactor("CONSENSUS", consensusReactor)
+	if config.P2P.FuzzDropRates != "" || config.P2P.FuzzCorruptRates != "" || config.P2P.FuzzDelayRates != "" {
+		p2pLogger.Error("Fuzzing the messages of the peers, for test networks only",
+			"drop", config.P2P.FuzzDropRates, "corrupt", config.P2P.FuzzCorruptRates, "delay", config.P2P.FuzzDelayRates)
+	}
+	// the protocol of the listen address selects the transport, eg. quic://
+	protocol, _ := cmn.ProtocolAndAddress(config.P2P.ListenAddress)
+	transport, err := p2p.NewTransport(protocol)
+	if err != nil {
+		return nil, err
+	}
+	sw.SetTransport(transport)
+	sw.SetMetrics(p2p.NewMetrics(opts.metricsRegistry, config.P2P.MetricsMaxPeers))
+	banList, err := p2p.NewBanList(config.P2P.BanListFile())
+	if err != nil {
+		return nil, err
+	}
+	sw.SetBanList(banList)
+	// Make StateSyncReactor
+	// NOTE: abci has no snapshot methods yet, see statesync.NewQueryApp
+	stateSyncReactor := statesync.NewReactor(config.StateSync, statesync.NewQueryApp(proxyApp.Query()), proxyApp.Query())
+	stateSyncReactor.SetLogger(logger.With("module", "statesync"))
+	// A seed only crawls the network with the pex reactor, it takes no part
+	// in the consensus nor gossips blocks, txs or evidence
+	if !config.P2P.SeedMode {
+		sw.AddReactor("MEMPOOL", mempoolReactor)
+		sw.AddReactor("BLOCKCHAIN", bcReactor)
+		sw.AddReactor("CONSENSUS", consensusReactor)
+		sw.AddReactor("EVIDENCE", evidenceReactor)
+		sw.AddReactor("STATESYNC", stateSyncReactor)
+	}
+	if opts.hooks.hasPeerHooks() {
+		sw.AddReactor("HOOKS", newHooksReactor(opts.hooks))
+	}
 
 	// Optionally, start the pex reactor
 	var addrBook *p2p.AddrBook
@@ -231,6 +501,7 @@ func NewNode(config *cfg.Config,
 	if config.P2P.PexReactor {
 		addrBook = p2p.NewAddrBook(config.P2P.AddrBookFile(), config.P2P.AddrBookStrict)
 		addrBook.SetLogger(p2pLogger.With("book", config.P2P.AddrBookFile()))
+		addrBook.AddPrivateIDs(strings.Split(config.P2P.PrivatePeerIDs, ","))
 
 		// Get the trust metric history data
 		trustHistoryDB, err := dbProvider(&DBContext{"trusthistory", config})
@@ -242,9 +513,18 @@ func NewNode(config *cfg.Config,
 
 		pexReactor := p2p.NewPEXReactor(addrBook)
 		pexReactor.SetLogger(p2pLogger)
+		pexReactor.SetSeedMode(config.P2P.SeedMode)
+		pexReactor.SetDetectExternalAddr(config.P2P.DetectExternalAddress)
 		sw.AddReactor("PEX", pexReactor)
 	}
 
+	// Optionally, discover seeds through DNS
+	var dnsSeeder *p2p.DNSSeeder
+	if config.P2P.DNSSeeds != "" {
+		dnsSeeder = p2p.NewDNSSeeder(sw, addrBook, strings.Split(config.P2P.DNSSeeds, ","), config.P2P.DNSSeedsResolve())
+		dnsSeeder.SetLogger(p2pLogger)
+	}
+
 	// Filter peers by addr or pubkey with an ABCI query.
 	// If the query return code is OK, add peer.
 	// XXX: Query format subject to change
@@ -278,6 +558,7 @@ func NewNode(config *cfg.Config,
 	// services which will be publishing and/or subscribing for messages (events)
 	bcReactor.SetEventBus(eventBus)
 	consensusReactor.SetEventBus(eventBus)
+	mempool.SetEventBus(eventBus)
 
 	// Transaction indexing
 	var txIndexer txindex.TxIndexer
@@ -294,12 +575,104 @@ func NewNode(config *cfg.Config,
 		} else {
 			txIndexer = kv.NewTxIndex(store)
 		}
+	case "psql":
+		store, err := sql.Open(psql.DriverName, config.TxIndex.PsqlConn)
+		if err != nil {
+			return nil, err
+		}
+		if config.TxIndex.IndexTags != "" {
+			txIndexer, err = psql.NewTxIndex(store, psql.IndexTags(strings.Split(config.TxIndex.IndexTags, ",")))
+		} else if config.TxIndex.IndexAllTags {
+			txIndexer, err = psql.NewTxIndex(store, psql.IndexAllTags())
+		} else {
+			txIndexer, err = psql.NewTxIndex(store)
+		}
+		if err != nil {
+			return nil, err
+		}
 	default:
 		txIndexer = &null.TxIndex{}
 	}
 
 	indexerService := txindex.NewIndexerService(txIndexer, eventBus)
 
+	// Signing info indexing
+	var signingInfoStore *signinginfo.Store
+	var signingIndexer *signinginfo.IndexerService
+	if config.IndexSigningInfo {
+		store, err := dbProvider(&DBContext{"signing_info", config})
+		if err != nil {
+			return nil, err
+		}
+		signingInfoStore = signinginfo.NewStore(store)
+		signingIndexer = signinginfo.NewIndexerService(signingInfoStore, blockStore, state.LoadValidators, eventBus)
+		signingIndexer.SetLogger(logger.With("module", "signing_info"))
+	}
+
+	// App hash indexing
+	var appHashStore *apphash.Store
+	var appHashIndexer *apphash.IndexerService
+	if config.IndexAppHashes {
+		store, err := dbProvider(&DBContext{"app_hash", config})
+		if err != nil {
+			return nil, err
+		}
+		appHashStore = apphash.NewStore(store)
+		appHashIndexer = apphash.NewIndexerService(appHashStore, blockStore, eventBus)
+		appHashIndexer.SetLogger(logger.With("module", "app_hash"))
+	}
+
+	// Block indexing
+	var blockIndexer blockindex.BlockIndexer
+	var blockIndexerSvc *blockindex.IndexerService
+	if config.TxIndex.IndexBlocks {
+		store, err := dbProvider(&DBContext{"block_index", config})
+		if err != nil {
+			return nil, err
+		}
+		blockIndexer = blockkv.NewBlockIndex(store)
+		blockIndexerSvc = blockindex.NewIndexerService(blockIndexer, state.LoadABCIResponsesAt, eventBus)
+		blockIndexerSvc.SetLogger(logger.With("module", "block_index"))
+	}
+
+	// prune the ABCI responses in the state db, independently of the blocks
+	var abciRespPruner *sm.ABCIResponsesPruner
+	if config.ABCIResponsesRetainBlocks > 0 {
+		abciRespPruner = sm.NewABCIResponsesPruner(stateDB, config.ABCIResponsesRetainBlocks, config.ABCIResponsesPrune())
+		abciRespPruner.SetLogger(stateLogger)
+	}
+
+	// prune the blocks, for the nodes which don't need the whole chain
+	var blockPruner *bc.BlockPruner
+	if config.MinRetainBlocks > 0 || config.AppPrunesBlocks {
+		var appRetainHeight bc.RetainHeightFunc
+		if config.AppPrunesBlocks {
+			appRetainHeight = bc.NewAppRetainHeight(proxyApp.Query())
+		}
+		blockPruner = bc.NewBlockPruner(blockStore, config.MinRetainBlocks, appRetainHeight, config.BlockPrune())
+		blockPruner.SetLogger(logger.With("module", "blockchain"))
+	}
+
+	// cross-check the blocks against the state db, eg. after a partial restore
+	var integrityChecker *sm.IntegrityChecker
+	if config.IntegrityCheckInterval > 0 {
+		integrityChecker = sm.NewIntegrityChecker(stateDB, blockStore, config.IntegrityCheck())
+		integrityChecker.SetLogger(stateLogger)
+		integrityChecker.SetMetrics(sm.NewIntegrityMetrics(opts.metricsRegistry))
+	}
+
+	// save the totals counted by the metrics, to continue from them on restart
+	var persistentCounters *tmmetrics.PersistentCounters
+	if config.MetricsPersistInterval > 0 {
+		store, err := dbProvider(&DBContext{"metrics", config})
+		if err != nil {
+			return nil, err
+		}
+		persistentCounters = tmmetrics.NewPersistentCounters(opts.metricsRegistry, store,
+			config.MetricsPersist(), persistedCounters...)
+		persistentCounters.SetLogger(logger.With("module", "metrics"))
+	}
+
 	// run the profile server
 	profileHost := config.ProfListenAddress
 	if profileHost != "" {
@@ -308,25 +681,57 @@ func NewNode(config *cfg.Config,
 		}()
 	}
 
+	var notifier *systemdNotifier
+	if config.SystemdNotify {
+		notifier = newSystemdNotifier(consensusReactor.FastSync,
+			func() { consensusState.GetRoundState() }, blockStore.Height)
+		if notifier != nil {
+			notifier.SetLogger(logger.With("module", "systemd"))
+		} else {
+			logger.Info("Not run by systemd, ignoring systemd_notify")
+		}
+	}
+
 	node := &Node{
-		config:        config,
-		genesisDoc:    genDoc,
-		privValidator: privValidator,
+		config:         config,
+		genesisDoc:     genDoc,
+		privValidator:  privValidator,
+		privValSockets: privValSockets,
 
 		privKey:          privKey,
 		sw:               sw,
 		addrBook:         addrBook,
 		trustMetricStore: trustMetricStore,
+		dnsSeeder:        dnsSeeder,
 
 		blockStore:       blockStore,
 		bcReactor:        bcReactor,
+		stateSyncReactor: stateSyncReactor,
 		mempoolReactor:   mempoolReactor,
+		evidencePool:     evidencePool,
 		consensusState:   consensusState,
 		consensusReactor: consensusReactor,
 		proxyApp:         proxyApp,
 		txIndexer:        txIndexer,
 		indexerService:   indexerService,
+		abciRespPruner:   abciRespPruner,
+		blockPruner:      blockPruner,
+		integrityChecker: integrityChecker,
+		signingInfoStore: signingInfoStore,
+		signingIndexer:   signingIndexer,
+		appHashStore:     appHashStore,
+		appHashIndexer:   appHashIndexer,
+		blockIndexer:     blockIndexer,
+		blockIndexerSvc:  blockIndexerSvc,
 		eventBus:         eventBus,
+		metricsRegistry:  opts.metricsRegistry,
+
+		persistentCounters: persistentCounters,
+		systemdNotifier:    notifier,
+	}
+	if stateSync {
+		node.stateSyncGenesis = state.Copy()
+		node.stateProvider = opts.stateProvider
 	}
 	node.BaseService = *cmn.NewBaseService(logger, "Node", node)
 	return node, nil
@@ -339,6 +744,13 @@ func (n *Node) OnStart() error {
 		return err
 	}
 
+	// restore the totals before anything counts
+	if n.persistentCounters != nil {
+		if err := n.persistentCounters.Start(); err != nil {
+			return err
+		}
+	}
+
 	// Run the RPC server first
 	// so we can eg. receive txs for the first block
 	if n.config.RPC.ListenAddress != "" {
@@ -349,9 +761,17 @@ func (n *Node) OnStart() error {
 		n.rpcListeners = listeners
 	}
 
+	if n.config.MetricsListenAddress != "" {
+		listener, err := n.startMetrics()
+		if err != nil {
+			return err
+		}
+		n.metricsListener = listener
+	}
+
 	// Create & add listener
-	protocol, address := cmn.ProtocolAndAddress(n.config.P2P.ListenAddress)
-	l := p2p.NewDefaultListener(protocol, address, n.config.P2P.SkipUPNP, n.Logger.With("module", "p2p"))
+	_, address := cmn.ProtocolAndAddress(n.config.P2P.ListenAddress)
+	l := p2p.NewTransportListener(n.sw.Transport(), address, n.config.P2P.SkipUPNP, n.Logger.With("module", "p2p"))
 	n.sw.AddListener(l)
 
 	// Start the switch
@@ -371,8 +791,110 @@ func (n *Node) OnStart() error {
 		}
 	}
 
+	if n.dnsSeeder != nil {
+		if err := n.dnsSeeder.Start(); err != nil {
+			return err
+		}
+	}
+
+	if n.stateSyncGenesis != nil {
+		go n.stateSync()
+	} else if n.config.BackfillHeight > 0 && !n.config.P2P.SeedMode {
+		go n.backfill()
+	}
+
+	if n.abciRespPruner != nil {
+		if err := n.abciRespPruner.Start(); err != nil {
+			return err
+		}
+	}
+
+	if n.blockPruner != nil {
+		if err := n.blockPruner.Start(); err != nil {
+			return err
+		}
+	}
+
+	if n.integrityChecker != nil {
+		if err := n.integrityChecker.Start(); err != nil {
+			return err
+		}
+	}
+
+	if n.signingIndexer != nil {
+		if err := n.signingIndexer.Start(); err != nil {
+			return err
+		}
+	}
+
+	if n.appHashIndexer != nil {
+		if err := n.appHashIndexer.Start(); err != nil {
+			return err
+		}
+	}
+
+	if n.blockIndexerSvc != nil {
+		if err := n.blockIndexerSvc.Start(); err != nil {
+			return err
+		}
+	}
+
 	// start tx indexer
-	return n.indexerService.Start()
+	if err := n.indexerService.Start(); err != nil {
+		return err
+	}
+
+	if n.systemdNotifier != nil {
+		return n.systemdNotifier.Start()
+	}
+	return nil
+}
+
+// stateSync restores the app from a snapshot of the peers, verified against
+// the RPC servers of the config, and then fast syncs the blocks after it.
+// The node stays idle if it fails, as the app may be partly restored.
+func (n *Node) stateSync() {
+	config := n.config.StateSync
+	logger := n.Logger.With("module", "statesync")
+
+	trustHash, _ := hex.DecodeString(config.TrustHash) // validated by NewNode
+	stateProvider, err := n.stateProvider(n.stateSyncGenesis, config.RPCServerList(), config.TrustHeight, trustHash)
+	if err != nil {
+		logger.Error("Failed to state sync, restart the node with a new app", "err", err)
+		return
+	}
+	state, signedHeader, err := n.stateSyncReactor.Sync(stateProvider, config.Discovery())
+	if err == statesync.ErrAborted {
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to state sync, restart the node with a new app", "err", err)
+		return
+	}
+	if err := n.blockStore.Bootstrap(signedHeader); err != nil {
+		logger.Error("Failed to bootstrap the block store", "err", err)
+		return
+	}
+	state.Save()
+	logger.Info("State synced", "height", state.LastBlockHeight, "appHash", state.AppHash)
+
+	if err := n.bcReactor.SwitchToFastSync(state); err != nil {
+		logger.Error("Failed to switch to fast sync", "err", err)
+		return
+	}
+
+	if n.config.BackfillHeight > 0 {
+		n.backfill()
+	}
+}
+
+// backfill fetches the blocks before the base of the block store from the
+// peers, down to the backfill height.
+func (n *Node) backfill() {
+	err := n.bcReactor.Backfill(n.config.BackfillHeight)
+	if err != nil && err != bc.ErrBackfillAborted {
+		n.Logger.Error("Failed to backfill the blocks", "err", err)
+	}
 }
 
 // OnStop stops the Node. It implements cmn.Service.
@@ -380,6 +902,13 @@ func (n *Node) OnStop() {
 	n.BaseService.OnStop()
 
 	n.Logger.Info("Stopping Node")
+	if n.systemdNotifier != nil {
+		n.systemdNotifier.Stop()
+	}
+	if n.dnsSeeder != nil {
+		n.dnsSeeder.Stop()
+	}
+
 	// TODO: gracefully disconnect from peers.
 	n.sw.Stop()
 
@@ -390,9 +919,58 @@ func (n *Node) OnStop() {
 		}
 	}
 
+	if n.rpcAccessLog != nil {
+		if err := n.rpcAccessLog.Close(); err != nil {
+			n.Logger.Error("Error closing rpc access log", "err", err)
+		}
+	}
+
+	if n.metricsListener != nil {
+		if err := n.metricsListener.Close(); err != nil {
+			n.Logger.Error("Error closing metrics listener", "err", err)
+		}
+	}
+
 	n.eventBus.Stop()
 
 	n.indexerService.Stop()
+
+	if n.abciRespPruner != nil {
+		n.abciRespPruner.Stop()
+	}
+
+	if n.blockPruner != nil {
+		n.blockPruner.Stop()
+	}
+
+	if n.integrityChecker != nil {
+		n.integrityChecker.Stop()
+	}
+
+	if n.signingIndexer != nil {
+		n.signingIndexer.Stop()
+	}
+
+	if n.appHashIndexer != nil {
+		n.appHashIndexer.Stop()
+	}
+
+	if n.blockIndexerSvc != nil {
+		n.blockIndexerSvc.Stop()
+	}
+
+	// once the consensus and the switch stopped counting
+	if n.persistentCounters != nil {
+		n.persistentCounters.Stop()
+	}
+
+	if err := n.mempoolReactor.Mempool.SaveCache(); err != nil {
+		n.Logger.Error("Error saving mempool cache", "err", err)
+	}
+
+	for _, privValSocket := range n.privValSockets {
+		privValSocket.Stop()
+	}
 }
 
 // RunForever waits for an interrupt signal and stops the node.
@@ -422,8 +1000,17 @@ func (n *Node) ConfigureRPC() {
 	rpccore.SetAddrBook(n.addrBook)
 	rpccore.SetProxyAppQuery(n.proxyApp.Query())
 	rpccore.SetTxIndexer(n.txIndexer)
+	rpccore.SetBlockIndexer(n.blockIndexer)
+	rpccore.SetSigningInfoStore(n.signingInfoStore)
+	rpccore.SetAppHashStore(n.appHashStore)
+	rpccore.SetIntegrityChecker(n.integrityChecker)
 	rpccore.SetConsensusReactor(n.consensusReactor)
 	rpccore.SetEventBus(n.eventBus)
+	rpccore.SetABCIQueryLimits(n.config.RPC.ABCIQueryTimeout(), n.config.RPC.MaxABCIQueries)
+	rpccore.SetAppRetainHeights(n.config.AppRetainHeights)
+	rpccore.SetHistoryCacheSize(n.config.RPC.HistoryCacheSize)
+	rpccore.SetSubscriptionLimits(n.config.RPC.WSSubscriptionBuffer,
+		n.config.RPC.WSSlowSubscriberPolicy == cfg.SlowSubscriberClose)
 	rpccore.SetLogger(n.Logger.With("module", "rpc"))
 }
 
@@ -431,8 +1018,56 @@ func (n *Node) startRPC() ([]net.Listener, error) {
 	n.ConfigureRPC()
 	listenAddrs := strings.Split(n.config.RPC.ListenAddress, ",")
 
+	// the unsafe methods are only served to the clients authorized, if any
+	var unsafeAuth *rpcserver.UnsafeAuth
 	if n.config.RPC.Unsafe {
-		rpccore.AddUnsafeRoutes()
+		var methods []string
+		for _, method := range strings.Split(n.config.RPC.UnsafeMethods, ",") {
+			if method = strings.TrimSpace(method); method != "" {
+				methods = append(methods, method)
+			}
+		}
+		if err := rpccore.AddUnsafeRoutes(methods...); err != nil {
+			return nil, err
+		}
+		var tokens map[string][]string
+		if file := n.config.RPC.UnsafeAuthTokens(); file != "" {
+			var err error
+			if tokens, err = rpcserver.LoadAuthTokenFile(file); err != nil {
+				return nil, err
+			}
+		}
+		unsafeAuth = rpcserver.NewUnsafeAuth(rpccore.UnsafeMethods(), n.config.RPC.UnsafeLocalOnly, tokens)
+	}
+
+	// all the servers share the access log, if any
+	var accessLog *rpcserver.AccessLog
+	if file := n.config.RPC.AccessLogFile(); file != "" {
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			return nil, err
+		}
+		n.rpcAccessLog = f
+		accessLog = rpcserver.NewAccessLog(f, n.config.RPC.AccessLogSampleRate, strings.Split(n.config.RPC.AccessLogRedact, ","))
+	}
+
+	// all the servers share the limits of the clients, if any
+	methodRateLimits, err := rpcserver.ParseMethodRateLimits(n.config.RPC.MethodRateLimits)
+	if err != nil {
+		return nil, err
+	}
+	rateLimiter := rpcserver.NewRateLimiter(rpcserver.RateLimit{
+		Rate:          n.config.RPC.RateLimit,
+		MaxConcurrent: n.config.RPC.MaxConcurrentRequests,
+	}, methodRateLimits)
+
+	// the unix sockets are local, they are served in the clear
+	var tlsConfig *tls.Config
+	if n.config.RPC.IsTLSEnabled() {
+		tlsConfig, err = rpcserver.NewServerTLSConfig(n.config.RPC.TLSCert(), n.config.RPC.TLSKey(), n.config.RPC.TLSClientCA())
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// we may expose the rpc over both a unix and tcp socket
@@ -440,11 +1075,29 @@ func (n *Node) startRPC() ([]net.Listener, error) {
 	for i, listenAddr := range listenAddrs {
 		mux := http.NewServeMux()
 		rpcLogger := n.Logger.With("module", "rpc-server")
-		wm := rpcserver.NewWebsocketManager(rpccore.Routes, rpcserver.EventSubscriber(n.eventBus))
+		wm := rpcserver.NewWebsocketManager(rpccore.Routes,
+			rpcserver.EventSubscriber(n.eventBus),
+			rpcserver.RateLimits(rateLimiter),
+			rpcserver.ReadLimit(n.config.RPC.MaxBodyBytes),
+			rpcserver.Authorization(unsafeAuth))
 		wm.SetLogger(rpcLogger.With("protocol", "websocket"))
 		mux.HandleFunc("/websocket", wm.WebsocketHandler)
 		rpcserver.RegisterRPCFuncs(mux, rpccore.Routes, rpcLogger)
-		listener, err := rpcserver.StartHTTPServer(listenAddr, mux, rpcLogger)
+		// the requests over the limits are logged, the bodies too big are not read,
+		// and guessing the auth tokens is rate limited
+		handler := rpcserver.UnsafeAuthHandler(mux, unsafeAuth)
+		handler = rpcserver.RateLimitHandler(handler, rateLimiter)
+		if accessLog != nil {
+			handler = rpcserver.AccessLogHandler(handler, rpccore.Routes, accessLog)
+		}
+		handler = rpcserver.MaxBytesHandler(handler, n.config.RPC.MaxBodyBytes)
+		var listener net.Listener
+		var err error
+		if protocol, _ := cmn.ProtocolAndAddress(listenAddr); tlsConfig != nil && protocol != "unix" {
+			listener, err = rpcserver.StartHTTPAndTLSServer(listenAddr, handler, tlsConfig, rpcLogger)
+		} else {
+			listener, err = rpcserver.StartHTTPServer(listenAddr, handler, rpcLogger)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -464,6 +1117,26 @@ func (n *Node) startRPC() ([]net.Listener, error) {
 	return listeners, nil
 }
 
+// startMetrics serves the metrics for Prometheus on MetricsListenAddress.
+func (n *Node) startMetrics() (net.Listener, error) {
+	registry := n.metricsRegistry
+	if registry == nil {
+		registry = metrics.DefaultRegistry
+	}
+	listener, err := net.Listen("tcp", n.config.MetricsListenAddress)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", tmmetrics.PrometheusHandler(registry, "tendermint"))
+	go func() {
+		err := http.Serve(listener, mux)
+		n.Logger.Info("Metrics server stopped", "err", err)
+	}()
+	n.Logger.Info("Serving metrics", "addr", listener.Addr())
+	return listener, nil
+}
+
 // Switch returns the Node's Switch.
 func (n *Node) Switch() *p2p.Switch {
 	return n.sw
@@ -489,6 +1162,11 @@ func (n *Node) MempoolReactor() *mempl.MempoolReactor {
 	return n.mempoolReactor
 }
 
+// EvidencePool returns the Node's EvidencePool.
+func (n *Node) EvidencePool() *evidence.EvidencePool {
+	return n.evidencePool
+}
+
 // EventBus returns the Node's EventBus.
 func (n *Node) EventBus() *types.EventBus {
 	return n.eventBus
@@ -523,9 +1201,11 @@ func (n *Node) makeNodeInfo() *p2p.NodeInfo {
 		Other: []string{
 			cmn.Fmt("wire_version=%v", wire.Version),
 			cmn.Fmt("p2p_version=%v", p2p.Version),
+			cmn.Fmt("%v=%v", p2p.PexVersionKey, p2p.PexVersion),
 			cmn.Fmt("consensus_version=%v", consensus.Version),
 			cmn.Fmt("rpc_version=%v/%v", rpc.Version, rpccore.Version),
 			cmn.Fmt("tx_index=%v", txIndexerStatus),
+			cmn.Fmt("%v=%X", p2p.GenesisHashKey, n.genesisDoc.Hash()),
 		},
 	}
 
@@ -5,11 +5,18 @@ import (
 	"testing"
 	"time"
 
+	metrics "github.com/rcrowley/go-metrics"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	dbm "github.com/tendermint/tmlibs/db"
 	"github.com/tendermint/tmlibs/log"
 
+	bc "github.com/tendermint/tendermint/blockchain"
 	cfg "github.com/tendermint/tendermint/config"
+	mempl "github.com/tendermint/tendermint/mempool"
+	"github.com/tendermint/tendermint/proxy"
+	sm "github.com/tendermint/tendermint/state"
 	"github.com/tendermint/tendermint/types"
 )
 
@@ -46,3 +53,90 @@ func TestNodeStartStop(t *testing.T) {
 		t.Fatal("timed out waiting for shutdown")
 	}
 }
+
+func TestNodeOptions(t *testing.T) {
+	config := cfg.ResetTestRoot("node_options_test")
+
+	blockStore := bc.NewBlockStore(dbm.NewMemDB())
+	privValidator := types.GenPrivValidatorFS(config.PrivValidatorFile())
+	registry := metrics.NewRegistry()
+	var mempool *mempl.Mempool
+
+	n, err := NewNode(config,
+		privValidator,
+		proxy.DefaultClientCreator(config.ProxyApp, config.ABCI, config.DBDir()),
+		DefaultGenesisDocProviderFunc(config),
+		DefaultDBProvider,
+		log.TestingLogger(),
+		WithBlockStore(blockStore),
+		WithMetrics(registry),
+		WithMempool(func(config *cfg.MempoolConfig, appConn proxy.AppConnMempool, height int64) *mempl.Mempool {
+			mempool = DefaultMempoolProvider(config, appConn, height)
+			return mempool
+		}),
+	)
+	require.NoError(t, err)
+	defer n.ProxyApp().Stop()
+
+	assert.Equal(t, blockStore, n.BlockStore())
+	assert.Equal(t, privValidator, n.PrivValidator())
+	assert.Equal(t, mempool, n.MempoolReactor().Mempool)
+	assert.NotNil(t, registry.Get("mempool.evicted_txs"), "metrics are registered into the registry")
+
+	// the read interfaces are backed by the same components
+	assert.Equal(t, int64(0), n.BlockReader().Height())
+	assert.Equal(t, 0, n.MempoolReader().Size())
+	assert.Equal(t, n.GenesisDoc().ChainID, n.ConsensusReader().GetState().ChainID)
+}
+
+func TestNodeHooks(t *testing.T) {
+	config := cfg.ResetTestRoot("node_hooks_test")
+
+	committed := make(chan int64, 10)
+	n, err := NewNode(config,
+		types.LoadOrGenPrivValidatorFS(config.PrivValidatorFile()),
+		proxy.DefaultClientCreator(config.ProxyApp, config.ABCI, config.DBDir()),
+		DefaultGenesisDocProviderFunc(config),
+		DefaultDBProvider,
+		log.TestingLogger(),
+		WithHooks(Hooks{
+			OnBlockCommitted: func(block *types.Block, state *sm.State) {
+				// the state is the one after the block
+				assert.Equal(t, block.Height, state.LastBlockHeight)
+				committed <- block.Height
+			},
+		}),
+	)
+	require.NoError(t, err)
+	require.NoError(t, n.Start())
+	defer n.Stop()
+
+	// the hook is called for each block, in order
+	for height := int64(1); height <= 2; height++ {
+		select {
+		case h := <-committed:
+			assert.Equal(t, height, h)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the node to commit a block")
+		}
+	}
+}
+
+func TestHooksValidatorSetChanged(t *testing.T) {
+	var changes []int64
+	onBlockCommitted := Hooks{
+		OnValidatorSetChanged: func(height int64, validators *types.ValidatorSet) {
+			changes = append(changes, height)
+		},
+	}.blockCommittedFunc()
+	require.NotNil(t, onBlockCommitted)
+
+	block := &types.Block{Header: &types.Header{Height: 5}}
+	onBlockCommitted(block, &sm.State{LastHeightValidatorsChanged: 1})
+	assert.Empty(t, changes)
+	onBlockCommitted(block, &sm.State{LastHeightValidatorsChanged: 6})
+	assert.Equal(t, []int64{5}, changes)
+
+	// no hooks, nothing to call
+	assert.Nil(t, Hooks{}.blockCommittedFunc())
+}
@@ -0,0 +1,121 @@
+package node
+
+import (
+	metrics "github.com/rcrowley/go-metrics"
+
+	bc "github.com/tendermint/tendermint/blockchain"
+	cfg "github.com/tendermint/tendermint/config"
+	cstypes "github.com/tendermint/tendermint/consensus/types"
+	mempl "github.com/tendermint/tendermint/mempool"
+	"github.com/tendermint/tendermint/proxy"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/statesync"
+	"github.com/tendermint/tendermint/types"
+)
+
+// Option overrides one of the components NewNode builds by default.
+type Option func(*nodeOptions)
+
+type nodeOptions struct {
+	mempoolProvider MempoolProvider
+	blockStore      *bc.BlockStore
+	metricsRegistry metrics.Registry
+	hooks           Hooks
+	stateProvider   StateProviderFunc
+}
+
+// StateProviderFunc returns the provider of the verified states to state sync
+// from, built upon the initial state of the genesis, from the RPC servers and
+// the trusted header of the StateSyncConfig, see rpcprovider.New.
+type StateProviderFunc func(initial *sm.State, servers []string, trustHeight int64, trustHash []byte) (statesync.StateProvider, error)
+
+// MempoolProvider returns the mempool of the node, connected to the app through appConn
+// and starting at the given height.
+type MempoolProvider func(config *cfg.MempoolConfig, appConn proxy.AppConnMempool, height int64) *mempl.Mempool
+
+// DefaultMempoolProvider returns a Mempool as configured.
+func DefaultMempoolProvider(config *cfg.MempoolConfig, appConn proxy.AppConnMempool, height int64) *mempl.Mempool {
+	return mempl.NewMempool(config, appConn, height)
+}
+
+// WithMempool makes the node use the mempool returned by the given provider.
+func WithMempool(provider MempoolProvider) Option {
+	return func(opts *nodeOptions) {
+		opts.mempoolProvider = provider
+	}
+}
+
+// WithBlockStore makes the node use the given block store,
+// instead of one in the "blockstore" DB of the DBProvider.
+func WithBlockStore(blockStore *bc.BlockStore) Option {
+	return func(opts *nodeOptions) {
+		opts.blockStore = blockStore
+	}
+}
+
+// WithMetrics makes the node register its metrics into the given registry,
+// instead of metrics.DefaultRegistry.
+func WithMetrics(registry metrics.Registry) Option {
+	return func(opts *nodeOptions) {
+		opts.metricsRegistry = registry
+	}
+}
+
+// WithStateProvider makes the node state sync from the states of the given
+// provider. It's required to state sync, as the providers verifying the
+// states against RPC servers can't be imported by the node.
+func WithStateProvider(provider StateProviderFunc) Option {
+	return func(opts *nodeOptions) {
+		opts.stateProvider = provider
+	}
+}
+
+// WithHooks makes the node call the given hooks as it runs (see Hooks).
+func WithHooks(hooks Hooks) Option {
+	return func(opts *nodeOptions) {
+		opts.hooks = hooks
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// The read interfaces below are the stable API to the components of a running node.
+// Unlike the concrete types returned by Node.BlockStore(), Node.ConsensusState()
+// and Node.MempoolReactor(), they only change when the node's behaviour does.
+
+// BlockReader reads the blocks committed by the node.
+type BlockReader interface {
+	Height() int64
+	LoadBlockMeta(height int64) *types.BlockMeta
+	LoadBlock(height int64) *types.Block
+	LoadBlockCommit(height int64) *types.Commit
+	LoadSeenCommit(height int64) *types.Commit
+}
+
+// MempoolReader reads the txs waiting in the mempool of the node.
+type MempoolReader interface {
+	Size() int
+	Reap(maxTxs int) types.Txs
+}
+
+// ConsensusReader reads the consensus state of the node.
+type ConsensusReader interface {
+	GetState() *sm.State
+	GetRoundState() *cstypes.RoundState
+	GetValidators() (int64, []*types.Validator)
+}
+
+// BlockReader returns a read-only view of the Node's BlockStore.
+func (n *Node) BlockReader() BlockReader {
+	return n.blockStore
+}
+
+// MempoolReader returns a read-only view of the Node's Mempool.
+func (n *Node) MempoolReader() MempoolReader {
+	return n.mempoolReactor.Mempool
+}
+
+// ConsensusReader returns a read-only view of the Node's ConsensusState.
+func (n *Node) ConsensusReader() ConsensusReader {
+	return n.consensusState
+}
@@ -0,0 +1,142 @@
+package node
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	cmn "github.com/tendermint/tmlibs/common"
+)
+
+// systemdReadyPoll is how often the notifier checks if the node caught up,
+// until it did.
+const systemdReadyPoll = time.Second
+
+// systemdNotifier notifies systemd, for a unit of Type=notify, of the state of
+// the node, per sd_notify(3): READY=1 once it caught up, ie. fast sync is done,
+// WATCHDOG=1 every half of the WatchdogSec of the unit while the consensus loop
+// is responsive, and STOPPING=1 once stopping. So systemd restarts a wedged
+// node, without a health check script.
+type systemdNotifier struct {
+	cmn.BaseService
+
+	socket           string        // $NOTIFY_SOCKET
+	watchdogInterval time.Duration // 0 if the unit has no watchdog for the node
+
+	fastSyncing    func() bool  // true until caught up
+	probeConsensus func()       // returns once the consensus loop answers
+	height         func() int64 // of the last block, for the status
+}
+
+// newSystemdNotifier returns a notifier of the systemd of the environment,
+// or nil if the node is not run by systemd.
+func newSystemdNotifier(fastSyncing func() bool, probeConsensus func(), height func() int64) *systemdNotifier {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	n := &systemdNotifier{
+		socket:           socket,
+		watchdogInterval: systemdWatchdogInterval(),
+		fastSyncing:      fastSyncing,
+		probeConsensus:   probeConsensus,
+		height:           height,
+	}
+	n.BaseService = *cmn.NewBaseService(nil, "SystemdNotifier", n)
+	return n
+}
+
+// systemdWatchdogInterval returns the WatchdogSec of the unit, from the
+// environment, or 0 if it has none or it's for another process.
+func systemdWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// OnStart implements cmn.Service by starting the notifying routine.
+func (n *systemdNotifier) OnStart() error {
+	go n.notifyRoutine()
+	return nil
+}
+
+// OnStop implements cmn.Service by notifying systemd that the node stops.
+func (n *systemdNotifier) OnStop() {
+	n.BaseService.OnStop()
+	n.notify("STOPPING=1")
+}
+
+func (n *systemdNotifier) notifyRoutine() {
+	ready := time.NewTicker(systemdReadyPoll)
+	defer ready.Stop()
+	for n.fastSyncing() {
+		// keep systemd from timing out the start-up while fast syncing
+		n.notify(fmt.Sprintf("EXTEND_TIMEOUT_USEC=%d\nSTATUS=Fast syncing, at height %d",
+			3*systemdReadyPoll/time.Microsecond, n.height()))
+		select {
+		case <-ready.C:
+		case <-n.Quit:
+			return
+		}
+	}
+	n.notify(fmt.Sprintf("READY=1\nSTATUS=Caught up at height %d", n.height()))
+	if n.watchdogInterval <= 0 {
+		return
+	}
+
+	watchdog := time.NewTicker(n.watchdogInterval / 2)
+	defer watchdog.Stop()
+	answered := n.probe()
+	for {
+		select {
+		case <-watchdog.C:
+		case <-n.Quit:
+			return
+		}
+		select {
+		case <-answered:
+			n.notify("WATCHDOG=1")
+			answered = n.probe()
+		default:
+			// systemd restarts the node if it stays wedged past the WatchdogSec
+			n.Logger.Error("The consensus loop is not responding, not pinging the systemd watchdog")
+		}
+	}
+}
+
+// probe returns a channel closed once the consensus loop answers.
+func (n *systemdNotifier) probe() <-chan struct{} {
+	answered := make(chan struct{})
+	go func() {
+		n.probeConsensus()
+		close(answered)
+	}()
+	return answered
+}
+
+// notify sends the state to systemd, logging the errors.
+func (n *systemdNotifier) notify(state string) {
+	if err := sdNotify(n.socket, state); err != nil {
+		n.Logger.Error("Failed to notify systemd", "state", state, "err", err)
+	}
+}
+
+// sdNotify sends the state, newline separated VAR=value assignments, to the
+// unix datagram socket of systemd, per sd_notify(3). A socket starting with @
+// is in the abstract namespace.
+func sdNotify(socket, state string) error {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close() // nolint: errcheck
+	_, err = conn.Write([]byte(state))
+	return err
+}
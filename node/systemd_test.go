@@ -0,0 +1,85 @@
+package node
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tmlibs/log"
+)
+
+func TestSystemdNotifier(t *testing.T) {
+	dir, err := ioutil.TempDir("", "systemd")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+	socket := filepath.Join(dir, "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	require.Nil(t, err)
+	defer conn.Close() // nolint: errcheck
+
+	// not run by systemd
+	os.Unsetenv("NOTIFY_SOCKET")
+	assert.Nil(t, newSystemdNotifier(nil, nil, nil))
+
+	os.Setenv("NOTIFY_SOCKET", socket)
+	os.Setenv("WATCHDOG_USEC", "200000")
+	defer os.Unsetenv("NOTIFY_SOCKET")
+	defer os.Unsetenv("WATCHDOG_USEC")
+
+	var fastSyncing int32 = 1
+	wedged := make(chan struct{})
+	var isWedged int32
+	n := newSystemdNotifier(
+		func() bool { return atomic.LoadInt32(&fastSyncing) == 1 },
+		func() {
+			if atomic.LoadInt32(&isWedged) == 1 {
+				<-wedged
+			}
+		},
+		func() int64 { return 7 })
+	require.NotNil(t, n)
+	assert.Equal(t, 200*time.Millisecond, n.watchdogInterval)
+	n.SetLogger(log.TestingLogger())
+	require.Nil(t, n.Start())
+
+	read := func(timeout time.Duration) string {
+		buf := make([]byte, 1024)
+		require.Nil(t, conn.SetReadDeadline(time.Now().Add(timeout)))
+		size, err := conn.Read(buf)
+		if err != nil {
+			return ""
+		}
+		return string(buf[:size])
+	}
+
+	// the start-up is extended until caught up
+	assert.Contains(t, read(time.Second), "EXTEND_TIMEOUT_USEC=")
+	atomic.StoreInt32(&fastSyncing, 0)
+	for state := read(2 * time.Second); !strings.HasPrefix(state, "READY=1"); state = read(2 * time.Second) {
+		require.NotEmpty(t, state, "not ready")
+	}
+
+	// the watchdog is pinged while the consensus answers
+	assert.Equal(t, "WATCHDOG=1", read(time.Second))
+	assert.Equal(t, "WATCHDOG=1", read(time.Second))
+
+	// but no longer once wedged
+	atomic.StoreInt32(&isWedged, 1)
+	read(time.Second) // a probe may have answered already
+	assert.Equal(t, "", read(500*time.Millisecond))
+	close(wedged)
+	assert.Equal(t, "WATCHDOG=1", read(time.Second))
+
+	n.Stop()
+	for state := read(time.Second); state != "STOPPING=1"; state = read(time.Second) {
+		require.NotEmpty(t, state, "not stopping")
+	}
+}
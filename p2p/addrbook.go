@@ -12,6 +12,8 @@ import (
 	"math/rand"
 	"net"
 	"os"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -60,6 +62,14 @@ const (
 	// days since the last success before we will consider evicting an address.
 	minBadDays = 7
 
+	// backoff after a failed attempt to connect to an address, doubled with
+	// each consecutive one, up to maxAttemptBackoff.
+	minAttemptBackoff = 30 * time.Second
+	maxAttemptBackoff = 1 * time.Hour
+
+	// random addresses PickAddress returns the best scored of.
+	pickAddressCandidates = 5
+
 	// % of total addresses known returned by GetSelection.
 	getSelectionPercent = 23
 
@@ -89,6 +99,7 @@ type AddrBook struct {
 	mtx        sync.Mutex
 	rand       *rand.Rand
 	ourAddrs   map[string]*NetAddress
+	privateIDs map[ID]struct{}          // never added nor shared
 	addrLookup map[string]*knownAddress // new & old
 	bucketsOld []map[string]*knownAddress
 	bucketsNew []map[string]*knownAddress
@@ -104,6 +115,7 @@ func NewAddrBook(filePath string, routabilityStrict bool) *AddrBook {
 	am := &AddrBook{
 		rand:              rand.New(rand.NewSource(time.Now().UnixNano())),
 		ourAddrs:          make(map[string]*NetAddress),
+		privateIDs:        make(map[ID]struct{}),
 		addrLookup:        make(map[string]*knownAddress),
 		filePath:          filePath,
 		routabilityStrict: routabilityStrict,
@@ -157,7 +169,7 @@ func (a *AddrBook) AddOurAddress(addr *NetAddress) {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
 	a.Logger.Info("Add our address to book", "addr", addr)
-	a.ourAddrs[addr.String()] = addr
+	a.ourAddrs[addr.DialString()] = addr
 }
 
 // OurAddresses returns a list of our addresses.
@@ -169,6 +181,19 @@ func (a *AddrBook) OurAddresses() []*NetAddress {
 	return addrs
 }
 
+// AddPrivateIDs adds the IDs of private peers, eg. the validator behind a
+// sentry, whose addresses are never added to the book, so never shared with
+// the other peers.
+func (a *AddrBook) AddPrivateIDs(ids []string) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	for _, id := range ids {
+		if id = strings.TrimSpace(id); id != "" {
+			a.privateIDs[ID(strings.ToLower(id))] = struct{}{}
+		}
+	}
+}
+
 // AddAddress adds the given address as received from the given source.
 // NOTE: addr must not be nil
 func (a *AddrBook) AddAddress(addr *NetAddress, src *NetAddress) error {
@@ -194,11 +219,13 @@ func (a *AddrBook) size() int {
 }
 
 // PickAddress picks an address to connect to.
-// The address is picked randomly from an old or new bucket according
+// The address is picked from old or new buckets according
 // to the newBias argument, which must be between [0, 100] (or else is truncated to that range)
 // and determines how biased we are to pick an address from a new bucket.
-// PickAddress returns nil if the AddrBook is empty or if we try to pick
-// from an empty bucket.
+// It is the best scored of a few random addresses of the buckets, skipping
+// the ones backing off after failed attempts, see knownAddress.score.
+// PickAddress returns nil if the AddrBook is empty, if we try to pick
+// from an empty bucket, or if the addresses picked are backing off.
 func (a *AddrBook) PickAddress(newBias int) *NetAddress {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
@@ -217,13 +244,33 @@ func (a *AddrBook) PickAddress(newBias int) *NetAddress {
 	oldCorrelation := math.Sqrt(float64(a.nOld)) * (100.0 - float64(newBias))
 	newCorrelation := math.Sqrt(float64(a.nNew)) * float64(newBias)
 
-	// pick a random peer from a random bucket
-	var bucket map[string]*knownAddress
 	pickFromOldBucket := (newCorrelation+oldCorrelation)*a.rand.Float64() < oldCorrelation
 	if (pickFromOldBucket && a.nOld == 0) ||
 		(!pickFromOldBucket && a.nNew == 0) {
 		return nil
 	}
+
+	now := time.Now()
+	var best *knownAddress
+	for i := 0; i < pickAddressCandidates; i++ {
+		ka := a.pickRandom(pickFromOldBucket)
+		if ka == nil || ka.isBackingOff(now) {
+			continue
+		}
+		if best == nil || ka.score() > best.score() {
+			best = ka
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return best.Addr
+}
+
+// pickRandom picks a random address from a random old or new bucket.
+// NOTE: the buckets must not be all empty.
+func (a *AddrBook) pickRandom(pickFromOldBucket bool) *knownAddress {
+	var bucket map[string]*knownAddress
 	// loop until we pick a random non-empty bucket
 	for len(bucket) == 0 {
 		if pickFromOldBucket {
@@ -236,7 +283,7 @@ func (a *AddrBook) PickAddress(newBias int) *NetAddress {
 	randIndex := a.rand.Intn(len(bucket))
 	for _, ka := range bucket {
 		if randIndex == 0 {
-			return ka.Addr
+			return ka
 		}
 		randIndex--
 	}
@@ -244,7 +291,6 @@ func (a *AddrBook) PickAddress(newBias int) *NetAddress {
 }
 
 // MarkGood marks the peer as good and moves it into an "old" bucket.
-// XXX: we never call this!
 func (a *AddrBook) MarkGood(addr *NetAddress) {
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
@@ -269,6 +315,30 @@ func (a *AddrBook) MarkAttempt(addr *NetAddress) {
 	ka.markAttempt()
 }
 
+// MarkConnected marks that we are connected to the address, to count the
+// uptime of the connection, see MarkDisconnected.
+func (a *AddrBook) MarkConnected(addr *NetAddress) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	ka := a.addrLookup[addr.String()]
+	if ka == nil {
+		return
+	}
+	ka.markConnected(time.Now())
+}
+
+// MarkDisconnected adds the uptime of the connection to the address to its
+// score.
+func (a *AddrBook) MarkDisconnected(addr *NetAddress) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	ka := a.addrLookup[addr.String()]
+	if ka == nil {
+		return
+	}
+	ka.markDisconnected(time.Now())
+}
+
 // MarkBad currently just ejects the address. In the future, consider
 // blacklisting.
 func (a *AddrBook) MarkBad(addr *NetAddress) {
@@ -298,11 +368,13 @@ func (a *AddrBook) GetSelection() []*NetAddress {
 		return nil
 	}
 
-	allAddr := make([]*NetAddress, a.size())
-	i := 0
+	allAddr := make([]*NetAddress, 0, a.size())
 	for _, v := range a.addrLookup {
-		allAddr[i] = v.Addr
-		i++
+		// loaded from the file before the IDs were private
+		if _, private := a.privateIDs[v.Addr.ID]; private {
+			continue
+		}
+		allAddr = append(allAddr, v.Addr)
 	}
 
 	numAddresses := cmn.MaxInt(
@@ -323,6 +395,33 @@ func (a *AddrBook) GetSelection() []*NetAddress {
 	return allAddr[:numAddresses]
 }
 
+// crawlAddrs returns up to max addresses for a seed to crawl, the least
+// recently attempted first, skipping the ones attempted or connected to
+// within the interval.
+func (a *AddrBook) crawlAddrs(max int, interval time.Duration) []*NetAddress {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	now := time.Now()
+	var kas []*knownAddress
+	for _, ka := range a.addrLookup {
+		if ka.Attempts > 0 && now.Sub(ka.LastAttempt) < interval {
+			continue
+		}
+		if now.Sub(ka.LastSuccess) < interval {
+			continue
+		}
+		kas = append(kas, ka)
+	}
+	sort.Slice(kas, func(i, j int) bool { return kas[i].LastAttempt.Before(kas[j].LastAttempt) })
+
+	addrs := make([]*NetAddress, 0, cmn.MinInt(max, len(kas)))
+	for i := 0; i < len(kas) && i < max; i++ {
+		addrs = append(addrs, kas[i].Addr)
+	}
+	return addrs
+}
+
 /* Loading & Saving */
 
 type addrBookJSON struct {
@@ -335,9 +434,11 @@ func (a *AddrBook) saveToFile(filePath string) {
 
 	a.mtx.Lock()
 	defer a.mtx.Unlock()
-	// Compile Addrs
+	// Compile Addrs, with the uptime of the connections in progress
+	now := time.Now()
 	addrs := []*knownAddress{}
 	for _, ka := range a.addrLookup {
+		ka.countUptime(now)
 		addrs = append(addrs, ka)
 	}
 
@@ -539,25 +640,31 @@ func (a *AddrBook) removeFromAllBuckets(ka *knownAddress) {
 	delete(a.addrLookup, ka.Addr.String())
 }
 
-func (a *AddrBook) pickOldest(bucketType byte, bucketIdx int) *knownAddress {
+// pickWorst returns the lowest scored address of the bucket, the least
+// recently attempted of them.
+func (a *AddrBook) pickWorst(bucketType byte, bucketIdx int) *knownAddress {
 	bucket := a.getBucket(bucketType, bucketIdx)
-	var oldest *knownAddress
+	var worst *knownAddress
 	for _, ka := range bucket {
-		if oldest == nil || ka.LastAttempt.Before(oldest.LastAttempt) {
-			oldest = ka
+		if worst == nil || ka.score() < worst.score() ||
+			(ka.score() == worst.score() && ka.LastAttempt.Before(worst.LastAttempt)) {
+			worst = ka
 		}
 	}
-	return oldest
+	return worst
 }
 
 func (a *AddrBook) addAddress(addr, src *NetAddress) error {
 	if a.routabilityStrict && !addr.Routable() {
 		return fmt.Errorf("Cannot add non-routable address %v", addr)
 	}
-	if _, ok := a.ourAddrs[addr.String()]; ok {
+	if _, ok := a.ourAddrs[addr.DialString()]; ok {
 		// Ignore our own listener address.
 		return fmt.Errorf("Cannot add ourselves with address %v", addr)
 	}
+	if _, ok := a.privateIDs[addr.ID]; ok {
+		return fmt.Errorf("Cannot add private peer with address %v", addr)
+	}
 
 	ka := a.addrLookup[addr.String()]
 
@@ -598,14 +705,14 @@ func (a *AddrBook) expireNew(bucketIdx int) {
 		}
 	}
 
-	// If we haven't thrown out a bad entry, throw out the oldest entry
-	oldest := a.pickOldest(bucketTypeNew, bucketIdx)
-	a.removeFromBucket(oldest, bucketTypeNew, bucketIdx)
+	// If we haven't thrown out a bad entry, throw out the worst entry
+	worst := a.pickWorst(bucketTypeNew, bucketIdx)
+	a.removeFromBucket(worst, bucketTypeNew, bucketIdx)
 }
 
 // Promotes an address from new to old.
 // TODO: Move to old probabilistically.
-// The better a node is, the less likely it should be evicted from an old bucket.
+// The worst scored address of the old bucket is evicted, if full.
 func (a *AddrBook) moveToOld(ka *knownAddress) {
 	// Sanity check
 	if ka.isOld() {
@@ -629,16 +736,16 @@ func (a *AddrBook) moveToOld(ka *knownAddress) {
 	added := a.addToOldBucket(ka, oldBucketIdx)
 	if !added {
 		// No room, must evict something
-		oldest := a.pickOldest(bucketTypeOld, oldBucketIdx)
-		a.removeFromBucket(oldest, bucketTypeOld, oldBucketIdx)
-		// Find new bucket to put oldest in
-		newBucketIdx := a.calcNewBucket(oldest.Addr, oldest.Src)
-		added := a.addToNewBucket(oldest, newBucketIdx)
+		worst := a.pickWorst(bucketTypeOld, oldBucketIdx)
+		a.removeFromBucket(worst, bucketTypeOld, oldBucketIdx)
+		// Find new bucket to put worst in
+		newBucketIdx := a.calcNewBucket(worst.Addr, worst.Src)
+		added := a.addToNewBucket(worst, newBucketIdx)
 		// No space in newBucket either, just put it in freedBucket from above.
 		if !added {
-			added := a.addToNewBucket(oldest, freedBucket)
+			added := a.addToNewBucket(worst, freedBucket)
 			if !added {
-				a.Logger.Error(cmn.Fmt("Could not migrate oldest %v to freedBucket %v", oldest, freedBucket))
+				a.Logger.Error(cmn.Fmt("Could not migrate worst %v to freedBucket %v", worst, freedBucket))
 			}
 		}
 		// Finally, add to bucket again.
@@ -750,11 +857,16 @@ func (a *AddrBook) groupKey(na *NetAddress) string {
 type knownAddress struct {
 	Addr        *NetAddress
 	Src         *NetAddress
-	Attempts    int32
+	Attempts    int32 // since the last success, for the backoff
 	LastAttempt time.Time
 	LastSuccess time.Time
+	Tries       int32 // with Successes and Uptime, for the score
+	Successes   int32
+	Uptime      time.Duration
 	BucketType  byte
 	Buckets     []int
+
+	connectedAt time.Time // zero unless connected
 }
 
 func newKnownAddress(addr *NetAddress, src *NetAddress) *knownAddress {
@@ -780,6 +892,7 @@ func (ka *knownAddress) markAttempt() {
 	now := time.Now()
 	ka.LastAttempt = now
 	ka.Attempts += 1
+	ka.Tries += 1
 }
 
 func (ka *knownAddress) markGood() {
@@ -787,6 +900,48 @@ func (ka *knownAddress) markGood() {
 	ka.LastAttempt = now
 	ka.Attempts = 0
 	ka.LastSuccess = now
+	ka.Successes += 1
+}
+
+func (ka *knownAddress) markConnected(now time.Time) {
+	ka.countUptime(now)
+	ka.connectedAt = now
+}
+
+func (ka *knownAddress) markDisconnected(now time.Time) {
+	ka.countUptime(now)
+	ka.connectedAt = time.Time{}
+}
+
+// countUptime adds the uptime of the connection in progress, if any.
+func (ka *knownAddress) countUptime(now time.Time) {
+	if !ka.connectedAt.IsZero() {
+		ka.Uptime += now.Sub(ka.connectedAt)
+		ka.connectedAt = now
+	}
+}
+
+// score rates the address from the ratio of its attempts which succeeded,
+// and how long we stayed connected to it: the ratio is weighted by the log
+// of the uptime, in hours.
+func (ka *knownAddress) score() float64 {
+	// 1 success out of 2 tries a priori, so the addresses never tried get 0.5
+	ratio := math.Min(1, float64(ka.Successes+1)/float64(ka.Tries+2))
+	return ratio * (1 + math.Log1p(ka.Uptime.Hours()))
+}
+
+// isBackingOff returns true if the last attempts to connect to the address
+// failed and the backoff, doubled by each of them, hasn't elapsed since the
+// last one.
+func (ka *knownAddress) isBackingOff(now time.Time) bool {
+	if ka.Attempts == 0 {
+		return false
+	}
+	backoff := minAttemptBackoff << uint(cmn.MinInt(int(ka.Attempts-1), 16))
+	if backoff > maxAttemptBackoff {
+		backoff = maxAttemptBackoff
+	}
+	return now.Before(ka.LastAttempt.Add(backoff))
 }
 
 func (ka *knownAddress) addBucketRef(bucketIdx int) int {
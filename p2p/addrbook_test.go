@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"math/rand"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	crypto "github.com/tendermint/go-crypto"
 	"github.com/tendermint/tmlibs/log"
 )
 
@@ -211,3 +214,122 @@ func TestAddrBookRemoveAddress(t *testing.T) {
 	book.RemoveAddress(nonExistingAddr)
 	assert.Equal(t, 0, book.Size())
 }
+
+func TestAddrBookCrawlAddrs(t *testing.T) {
+	assert := assert.New(t)
+	fname := createTempFileName("addrbook_test")
+	book := NewAddrBook(fname, true)
+	book.SetLogger(log.TestingLogger())
+
+	randAddrs := randNetAddressPairs(t, 10)
+	for _, addrSrc := range randAddrs {
+		book.AddAddress(addrSrc.addr, addrSrc.src)
+	}
+
+	// all the new addresses, up to max
+	assert.Len(book.crawlAddrs(100, time.Minute), 10)
+	assert.Len(book.crawlAddrs(4, time.Minute), 4)
+
+	// not the ones crawled within the interval
+	book.MarkAttempt(randAddrs[0].addr)
+	book.MarkGood(randAddrs[1].addr)
+	addrs := book.crawlAddrs(100, time.Minute)
+	assert.Len(addrs, 8)
+	for _, addr := range addrs {
+		assert.False(addr.Equals(randAddrs[0].addr) || addr.Equals(randAddrs[1].addr))
+	}
+	assert.Len(book.crawlAddrs(100, 0), 10)
+}
+
+func TestAddrBookPrivateIDs(t *testing.T) {
+	assert := assert.New(t)
+	fname := createTempFileName("addrbook_test")
+	book := NewAddrBook(fname, true)
+	book.SetLogger(log.TestingLogger())
+
+	addrs := randNetAddressPairs(t, 2)
+	for _, addrSrc := range addrs {
+		addrSrc.addr.ID = PubKeyToID(crypto.GenPrivKeyEd25519().PubKey())
+	}
+	// the first address is known before its ID is private
+	assert.Nil(book.AddAddress(addrs[0].addr, addrs[0].src))
+	book.AddPrivateIDs([]string{string(addrs[0].addr.ID), strings.ToUpper(string(addrs[1].addr.ID)), ""})
+	assert.NotNil(book.AddAddress(addrs[1].addr, addrs[1].src))
+	assert.Equal(1, book.Size())
+
+	// the private addresses are never shared, the ones without ID aren't private
+	noID := randIPv4Address(t)
+	assert.Nil(book.AddAddress(noID, noID))
+	assert.Equal([]*NetAddress{noID}, book.GetSelection())
+}
+
+func TestAddrBookBackoff(t *testing.T) {
+	assert := assert.New(t)
+	fname := createTempFileName("addrbook_test")
+	book := NewAddrBook(fname, true)
+	book.SetLogger(log.TestingLogger())
+
+	addrSrc := randNetAddressPairs(t, 1)[0]
+	book.AddAddress(addrSrc.addr, addrSrc.src)
+	ka := book.addrLookup[addrSrc.addr.String()]
+	now := time.Now()
+	assert.False(ka.isBackingOff(now))
+
+	// each failed attempt doubles the backoff, up to the max
+	book.MarkAttempt(addrSrc.addr)
+	assert.True(ka.isBackingOff(ka.LastAttempt.Add(minAttemptBackoff - time.Second)))
+	assert.False(ka.isBackingOff(ka.LastAttempt.Add(minAttemptBackoff)))
+	book.MarkAttempt(addrSrc.addr)
+	assert.True(ka.isBackingOff(ka.LastAttempt.Add(2*minAttemptBackoff - time.Second)))
+	assert.False(ka.isBackingOff(ka.LastAttempt.Add(2 * minAttemptBackoff)))
+	for i := 0; i < 20; i++ {
+		book.MarkAttempt(addrSrc.addr)
+	}
+	assert.False(ka.isBackingOff(ka.LastAttempt.Add(maxAttemptBackoff)))
+
+	// the address isn't picked while backing off
+	assert.Nil(book.PickAddress(100))
+
+	// until it succeeds
+	book.MarkGood(addrSrc.addr)
+	assert.False(ka.isBackingOff(time.Now()))
+	assert.NotNil(book.PickAddress(0))
+}
+
+func TestAddrBookScore(t *testing.T) {
+	assert := assert.New(t)
+	fname := createTempFileName("addrbook_test")
+	book := NewAddrBook(fname, true)
+	book.SetLogger(log.TestingLogger())
+
+	randAddrs := randNetAddressPairs(t, 3)
+	for _, addrSrc := range randAddrs {
+		book.AddAddress(addrSrc.addr, addrSrc.src)
+	}
+	good, bad, up := randAddrs[0].addr, randAddrs[1].addr, randAddrs[2].addr
+	for i := 0; i < 3; i++ {
+		book.MarkAttempt(good)
+		book.MarkGood(good)
+		book.MarkAttempt(bad)
+	}
+	book.MarkAttempt(up)
+	book.MarkGood(up)
+	book.MarkConnected(up)
+	ka := book.addrLookup[up.String()]
+	ka.connectedAt = ka.connectedAt.Add(-2 * time.Hour)
+	book.MarkDisconnected(up)
+
+	score := func(book *AddrBook, addr *NetAddress) float64 {
+		return book.addrLookup[addr.String()].score()
+	}
+	assert.True(score(book, good) > score(book, bad))
+	assert.True(score(book, up) > score(book, good))
+
+	// the scores are saved with the book
+	scores := []float64{score(book, good), score(book, bad), score(book, up)}
+	book.saveToFile(fname)
+	book = NewAddrBook(fname, true)
+	book.SetLogger(log.TestingLogger())
+	book.loadFromFile(fname)
+	assert.Equal(scores, []float64{score(book, good), score(book, bad), score(book, up)})
+}
@@ -0,0 +1,238 @@
+package p2p
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	cmn "github.com/tendermint/tmlibs/common"
+)
+
+// Severity is the severity of a misbehavior of a peer, the points it adds
+// towards a ban of the peer, see BanList.Report.
+type Severity int
+
+const (
+	// SeverityLow is eg. spam or useless messages.
+	SeverityLow Severity = 10
+	// SeverityMedium is eg. undecodable or malformed messages.
+	SeverityMedium Severity = 40
+	// SeverityHigh is eg. an invalid block or vote: the peer is banned right away.
+	SeverityHigh Severity = 100
+)
+
+const (
+	// points of misbehavior which get a peer banned
+	banThreshold = 100
+	// time for the points of a peer to decay from banThreshold to 0
+	misbehaviorDecay = time.Hour
+	// duration of the first ban of a peer, doubled by each new ban
+	minBanDuration = 10 * time.Minute
+	maxBanDuration = 7 * 24 * time.Hour
+)
+
+// Ban is the ban of a peer, by its ID or its IP.
+type Ban struct {
+	Target string    `json:"target"` // the ID or the IP of the peer
+	Until  time.Time `json:"until"`
+	Reason string    `json:"reason"`
+	Count  int       `json:"count"` // number of times the target was banned
+}
+
+// IsActive returns true if the ban isn't over at the time.
+func (b *Ban) IsActive(now time.Time) bool {
+	return now.Before(b.Until)
+}
+
+// misbehavior is the decaying points of misbehavior of a peer.
+type misbehavior struct {
+	points int
+	at     time.Time
+}
+
+// BanList bans the misbehaving peers for a while: the reactors report the
+// misbehaviors of the peers, weighted by their severity, and a peer whose
+// points reach banThreshold is banned, by its ID, for minBanDuration, doubled
+// by each new ban within maxBanDuration of the last one. The points decay
+// with time, so only repeated offenses get a peer banned, but for the high
+// severity ones.
+//
+// The IPs are only banned manually, as many peers may share one, eg. behind
+// a NAT.
+//
+// The bans are saved to a file, to outlive restarts. The points aren't.
+type BanList struct {
+	mtx          sync.Mutex
+	filePath     string // empty for no file
+	bans         map[string]*Ban
+	misbehaviors map[ID]*misbehavior
+}
+
+// NewBanList returns the ban list saved to the file, empty if the file
+// doesn't exist yet. An empty path keeps the bans in memory only.
+func NewBanList(filePath string) (*BanList, error) {
+	bl := &BanList{
+		filePath:     filePath,
+		bans:         make(map[string]*Ban),
+		misbehaviors: make(map[ID]*misbehavior),
+	}
+	if filePath == "" || !cmn.FileExists(filePath) {
+		return bl, nil
+	}
+	jsonBytes, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var bans []*Ban
+	if err := json.Unmarshal(jsonBytes, &bans); err != nil {
+		return nil, fmt.Errorf("Error reading the ban list from %v: %v", filePath, err)
+	}
+	for _, ban := range bans {
+		bl.bans[ban.Target] = ban
+	}
+	return bl, nil
+}
+
+// Report adds the misbehavior of the peer to its points, and bans the peer
+// once they reach banThreshold. It returns the new ban of the peer, if any,
+// and the error saving it.
+func (bl *BanList) Report(id ID, severity Severity, reason error) (*Ban, error) {
+	bl.mtx.Lock()
+	defer bl.mtx.Unlock()
+
+	now := time.Now()
+	m := bl.misbehaviors[id]
+	if m == nil {
+		m = &misbehavior{}
+		bl.misbehaviors[id] = m
+	}
+	decayed := int(int64(banThreshold) * int64(now.Sub(m.at)) / int64(misbehaviorDecay))
+	m.points = cmn.MaxInt(0, m.points-decayed) + int(severity)
+	m.at = now
+	if m.points < banThreshold {
+		return nil, nil
+	}
+
+	delete(bl.misbehaviors, id)
+	ban := *bl.ban(string(id), 0, reason.Error(), now)
+	return &ban, bl.save(now)
+}
+
+// Ban bans the target, the ID or the IP of a peer, for the duration, or for
+// minBanDuration doubled by each of its previous bans if 0.
+func (bl *BanList) Ban(target string, duration time.Duration, reason string) (*Ban, error) {
+	if err := validateBanTarget(target); err != nil {
+		return nil, err
+	}
+	if duration < 0 {
+		return nil, fmt.Errorf("Negative ban duration %v", duration)
+	}
+	bl.mtx.Lock()
+	defer bl.mtx.Unlock()
+
+	now := time.Now()
+	ban := *bl.ban(target, duration, reason, now)
+	return &ban, bl.save(now)
+}
+
+// ban bans the target, see Ban.
+func (bl *BanList) ban(target string, duration time.Duration, reason string, now time.Time) *Ban {
+	ban, ok := bl.bans[target]
+	if !ok || now.Sub(ban.Until) > maxBanDuration {
+		ban = &Ban{Target: target}
+		bl.bans[target] = ban
+	}
+	if duration == 0 {
+		duration = maxBanDuration
+		if ban.Count < 10 {
+			duration = time.Duration(cmn.MinInt64(int64(minBanDuration)<<uint(ban.Count), int64(maxBanDuration)))
+		}
+	}
+	ban.Count++
+	ban.Until = now.Add(duration)
+	ban.Reason = reason
+	return ban
+}
+
+// Unban lifts the ban of the target, the ID or the IP of a peer, and forgets
+// its misbehaviors. It returns an error if the target isn't banned.
+func (bl *BanList) Unban(target string) error {
+	bl.mtx.Lock()
+	defer bl.mtx.Unlock()
+
+	now := time.Now()
+	ban, ok := bl.bans[target]
+	if !ok || !ban.IsActive(now) {
+		return fmt.Errorf("%v is not banned", target)
+	}
+	delete(bl.bans, target)
+	delete(bl.misbehaviors, ID(target))
+	return bl.save(now)
+}
+
+// IsBanned returns the active ban of the target, the ID or the IP of a peer,
+// nil if it isn't banned.
+func (bl *BanList) IsBanned(target string) *Ban {
+	bl.mtx.Lock()
+	defer bl.mtx.Unlock()
+	ban, ok := bl.bans[target]
+	if !ok || !ban.IsActive(time.Now()) {
+		return nil
+	}
+	banCopy := *ban
+	return &banCopy
+}
+
+// List returns the active bans, the ones ending first first.
+func (bl *BanList) List() []Ban {
+	bl.mtx.Lock()
+	defer bl.mtx.Unlock()
+
+	now := time.Now()
+	bans := make([]Ban, 0, len(bl.bans))
+	for _, ban := range bl.bans {
+		if ban.IsActive(now) {
+			bans = append(bans, *ban)
+		}
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].Until.Before(bans[j].Until) })
+	return bans
+}
+
+// save forgets the bans over for more than maxBanDuration, whose count
+// doesn't matter anymore, and saves the others to the file.
+func (bl *BanList) save(now time.Time) error {
+	bans := make([]*Ban, 0, len(bl.bans))
+	for target, ban := range bl.bans {
+		if now.Sub(ban.Until) > maxBanDuration {
+			delete(bl.bans, target)
+			continue
+		}
+		bans = append(bans, ban)
+	}
+	if bl.filePath == "" {
+		return nil
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].Target < bans[j].Target })
+	jsonBytes, err := json.MarshalIndent(bans, "", "\t")
+	if err != nil {
+		return err
+	}
+	return cmn.WriteFileAtomic(bl.filePath, jsonBytes, 0644)
+}
+
+// validateBanTarget returns an error if the target isn't the ID or the IP of
+// a peer.
+func validateBanTarget(target string) error {
+	if net.ParseIP(target) != nil {
+		return nil
+	}
+	if err := validateID(ID(target)); err != nil {
+		return fmt.Errorf("Invalid ban target %q, expected the ID or the IP of a peer", target)
+	}
+	return nil
+}
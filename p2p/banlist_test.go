@@ -0,0 +1,94 @@
+package p2p
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	crypto "github.com/tendermint/go-crypto"
+)
+
+func TestBanListReport(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	bl, err := NewBanList("")
+	require.Nil(err)
+	id := PubKeyToID(crypto.GenPrivKeyEd25519().PubKey())
+	reason := errors.New("Invalid message")
+
+	// the misbehaviors add up to a ban
+	for i := 0; i < 2; i++ {
+		ban, err := bl.Report(id, SeverityMedium, reason)
+		require.Nil(err)
+		assert.Nil(ban)
+	}
+	ban, err := bl.Report(id, SeverityMedium, reason)
+	require.Nil(err)
+	require.NotNil(ban)
+	assert.Equal(string(id), ban.Target)
+	assert.Equal(reason.Error(), ban.Reason)
+	assert.WithinDuration(time.Now().Add(minBanDuration), ban.Until, time.Second)
+	assert.NotNil(bl.IsBanned(string(id)))
+
+	// but decay with time
+	other := PubKeyToID(crypto.GenPrivKeyEd25519().PubKey())
+	_, err = bl.Report(other, SeverityMedium, reason)
+	require.Nil(err)
+	bl.misbehaviors[other].at = time.Now().Add(-misbehaviorDecay / 2)
+	for i := 0; i < 2; i++ {
+		ban, err = bl.Report(other, SeverityMedium, reason)
+		require.Nil(err)
+		assert.Nil(ban)
+	}
+
+	// the high severity ones get the peer banned right away, for longer
+	// each time
+	ban, err = bl.Report(id, SeverityHigh, reason)
+	require.Nil(err)
+	require.NotNil(ban)
+	assert.Equal(2, ban.Count)
+	assert.WithinDuration(time.Now().Add(2*minBanDuration), ban.Until, time.Second)
+}
+
+func TestBanListBan(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	filePath := createTempFileName("banlist")
+	require.Nil(os.Remove(filePath))
+	defer os.Remove(filePath) // nolint: errcheck
+	bl, err := NewBanList(filePath)
+	require.Nil(err)
+
+	_, err = bl.Ban("not an ID", 0, "")
+	assert.NotNil(err)
+	_, err = bl.Ban("127.0.0.1", -time.Second, "")
+	assert.NotNil(err)
+
+	id := string(PubKeyToID(crypto.GenPrivKeyEd25519().PubKey()))
+	_, err = bl.Ban(id, time.Hour, "manual")
+	require.Nil(err)
+	_, err = bl.Ban("127.0.0.1", 0, "manual")
+	require.Nil(err)
+	assert.Len(bl.List(), 2)
+
+	// the bans outlive restarts
+	bl, err = NewBanList(filePath)
+	require.Nil(err)
+	bans := bl.List()
+	require.Len(bans, 2)
+	assert.Equal("127.0.0.1", bans[0].Target)
+	assert.Equal(id, bans[1].Target)
+	assert.Equal("manual", bans[1].Reason)
+
+	require.Nil(bl.Unban(id))
+	assert.Nil(bl.IsBanned(id))
+	assert.NotNil(bl.Unban(id))
+
+	// the expired bans are over
+	bl.bans["127.0.0.1"].Until = time.Now().Add(-time.Second)
+	assert.Nil(bl.IsBanned("127.0.0.1"))
+	assert.Empty(bl.List())
+}
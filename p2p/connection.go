@@ -25,6 +25,9 @@ const (
 	updateState        = 2 * time.Second
 	pingTimeout        = 40 * time.Second
 
+	// how long to wait before sending again the channels over their send rate
+	channelThrottleRetry = 10 * time.Millisecond
+
 	// some of these defaults are written in the user config
 	// flushThrottle, sendRate, recvRate
 	// TODO: remove values present in config
@@ -85,6 +88,7 @@ type MConnection struct {
 
 	quit         chan struct{}
 	flushTimer   *cmn.ThrottleTimer // flush writes as necessary but throttled.
+	retryTimer   *cmn.ThrottleTimer // send the channels over their send rate again.
 	pingTimer    *cmn.RepeatTimer   // send pings periodically
 	chStatsTimer *cmn.RepeatTimer   // update channel stats periodically
 
@@ -168,6 +172,7 @@ func (c *MConnection) OnStart() error {
 	}
 	c.quit = make(chan struct{})
 	c.flushTimer = cmn.NewThrottleTimer("flush", c.config.flushThrottle)
+	c.retryTimer = cmn.NewThrottleTimer("retry", channelThrottleRetry)
 	c.pingTimer = cmn.NewRepeatTimer("ping", pingTimeout)
 	c.chStatsTimer = cmn.NewRepeatTimer("chStats", updateState)
 	go c.sendRoutine()
@@ -179,6 +184,7 @@ func (c *MConnection) OnStart() error {
 func (c *MConnection) OnStop() {
 	c.BaseService.OnStop()
 	c.flushTimer.Stop()
+	c.retryTimer.Stop()
 	c.pingTimer.Stop()
 	c.chStatsTimer.Stop()
 	if c.quit != nil {
@@ -192,6 +198,14 @@ func (c *MConnection) OnStop() {
 	// close(c.pong)
 }
 
+// setChannelMetrics sets the metrics recording the traffic of the channels.
+// NOTE: must be called before the connection is started.
+func (c *MConnection) setChannelMetrics(chMetrics map[byte]*channelMetrics) {
+	for _, channel := range c.channels {
+		channel.metrics = chMetrics[channel.desc.ID]
+	}
+}
+
 func (c *MConnection) String() string {
 	return fmt.Sprintf("MConn{%v}", c.conn.RemoteAddr())
 }
@@ -306,6 +320,11 @@ FOR_LOOP:
 			// NOTE: flushTimer.Set() must be called every time
 			// something is written to .bufWriter.
 			c.flush()
+		case <-c.retryTimer.Ch:
+			select {
+			case c.send <- struct{}{}:
+			default:
+			}
 		case <-c.chStatsTimer.Ch:
 			for _, channel := range c.channels {
 				channel.updateStats()
@@ -367,14 +386,21 @@ func (c *MConnection) sendSomeMsgPackets() bool {
 // Returns true if messages from channels were exhausted.
 func (c *MConnection) sendMsgPacket() bool {
 	// Choose a channel to create a msgPacket from.
-	// The chosen channel will be the one whose recentlySent/priority is the least.
+	// The chosen channel will be the one whose recentlySent/priority is the least,
+	// among the ones within their send rate.
 	var leastRatio float32 = math.MaxFloat32
 	var leastChannel *Channel
+	throttled := false
 	for _, channel := range c.channels {
 		// If nothing to send, skip this channel
 		if !channel.isSendPending() {
 			continue
 		}
+		// If over its send rate, skip this channel and retry it later
+		if channel.isThrottled() {
+			throttled = true
+			continue
+		}
 		// Get ratio, and keep track of lowest ratio.
 		ratio := float32(channel.recentlySent) / float32(channel.desc.Priority)
 		if ratio < leastRatio {
@@ -385,6 +411,9 @@ func (c *MConnection) sendMsgPacket() bool {
 
 	// Nothing to send?
 	if leastChannel == nil {
+		if throttled {
+			c.retryTimer.Set()
+		}
 		return true
 	} else {
 		// c.Logger.Info("Found a msgPacket to send")
@@ -476,6 +505,21 @@ FOR_LOOP:
 				}
 				break FOR_LOOP
 			}
+			channel.metrics.received(n, msgBytes != nil)
+			// Block until the channel is within its receive rate.
+			// It slows the whole connection down, but only the peer flooding the channel.
+			channel.recvMonitor.Update(n)
+			if channel.desc.RecvRate > 0 {
+				channel.recvMonitor.Limit(c.config.maxMsgPacketTotalSize(), channel.desc.RecvRate, true)
+			}
+			if msgBytes != nil && channel.desc.Fuzz != nil {
+				var delay time.Duration
+				msgBytes, delay = channel.desc.Fuzz.fuzzMsg(msgBytes)
+				if delay > 0 {
+					c.receiveAfter(delay, pkt.ChannelID, msgBytes)
+					msgBytes = nil
+				}
+			}
 			if msgBytes != nil {
 				c.Logger.Debug("Received bytes", "chID", pkt.ChannelID, "msgBytes", msgBytes)
 				// NOTE: This means the reactor.Receive runs in the same thread as the p2p recv routine
@@ -511,6 +555,8 @@ type ChannelStatus struct {
 	SendQueueSize     int
 	Priority          int
 	RecentlySent      int64
+	SendRate          int64
+	RecvRate          int64
 }
 
 func (c *MConnection) Status() ConnectionStatus {
@@ -525,11 +571,26 @@ func (c *MConnection) Status() ConnectionStatus {
 			SendQueueSize:     int(channel.sendQueueSize), // TODO use atomic
 			Priority:          channel.desc.Priority,
 			RecentlySent:      channel.recentlySent,
+			SendRate:          channel.sendMonitor.Status().CurRate,
+			RecvRate:          channel.recvMonitor.Status().CurRate,
 		}
 	}
 	return status
 }
 
+// receiveAfter pushes the message to onReceive after the delay, if the
+// connection is still running, eg. to reorder the fuzzed messages.
+func (c *MConnection) receiveAfter(delay time.Duration, chID byte, msgBytes []byte) {
+	// the bytes of the channel are reused for the next message
+	msgCopy := make([]byte, len(msgBytes))
+	copy(msgCopy, msgBytes)
+	time.AfterFunc(delay, func() {
+		if c.IsRunning() {
+			c.onReceive(chID, msgCopy)
+		}
+	})
+}
+
 //-----------------------------------------------------------------------------
 
 type ChannelDescriptor struct {
@@ -538,6 +599,14 @@ type ChannelDescriptor struct {
 	SendQueueCapacity   int
 	RecvBufferCapacity  int
 	RecvMessageCapacity int
+
+	// Rates of the channel, in bytes/second, within the ones of the
+	// connection. 0 for no limit but the connection's.
+	SendRate int64
+	RecvRate int64
+
+	// Chaos of the received messages, for testing only. nil for none.
+	Fuzz *FuzzMsgConfig
 }
 
 func (chDesc ChannelDescriptor) FillDefaults() (filled ChannelDescriptor) {
@@ -564,6 +633,9 @@ type Channel struct {
 	recving       []byte
 	sending       []byte
 	recentlySent  int64 // exponential moving average
+	metrics       *channelMetrics
+	sendMonitor   *flow.Monitor
+	recvMonitor   *flow.Monitor
 
 	maxMsgPacketPayloadSize int
 }
@@ -578,6 +650,8 @@ func newChannel(conn *MConnection, desc ChannelDescriptor) *Channel {
 		desc:                    desc,
 		sendQueue:               make(chan []byte, desc.SendQueueCapacity),
 		recving:                 make([]byte, 0, desc.RecvBufferCapacity),
+		sendMonitor:             flow.New(0, 0),
+		recvMonitor:             flow.New(0, 0),
 		maxMsgPacketPayloadSize: conn.config.maxMsgPacketPayloadSize,
 	}
 }
@@ -586,11 +660,13 @@ func newChannel(conn *MConnection, desc ChannelDescriptor) *Channel {
 // Goroutine-safe
 // Times out (and returns false) after defaultSendTimeout
 func (ch *Channel) sendBytes(bytes []byte) bool {
+	ch.metrics.queued(len(ch.sendQueue), cap(ch.sendQueue))
 	select {
 	case ch.sendQueue <- bytes:
 		atomic.AddInt32(&ch.sendQueueSize, 1)
 		return true
 	case <-time.After(defaultSendTimeout):
+		ch.metrics.dropped()
 		return false
 	}
 }
@@ -599,11 +675,13 @@ func (ch *Channel) sendBytes(bytes []byte) bool {
 // Nonblocking, returns true if successful.
 // Goroutine-safe
 func (ch *Channel) trySendBytes(bytes []byte) bool {
+	ch.metrics.queued(len(ch.sendQueue), cap(ch.sendQueue))
 	select {
 	case ch.sendQueue <- bytes:
 		atomic.AddInt32(&ch.sendQueueSize, 1)
 		return true
 	default:
+		ch.metrics.dropped()
 		return false
 	}
 }
@@ -632,6 +710,15 @@ func (ch *Channel) isSendPending() bool {
 	return true
 }
 
+// Returns true if the channel is over its send rate.
+// Not goroutine-safe
+func (ch *Channel) isThrottled() bool {
+	if ch.desc.SendRate <= 0 {
+		return false
+	}
+	return ch.sendMonitor.Limit(ch.maxMsgPacketPayloadSize+maxMsgPacketOverheadSize, ch.desc.SendRate, false) == 0
+}
+
 // Creates a new msgPacket to send.
 // Not goroutine-safe
 func (ch *Channel) nextMsgPacket() msgPacket {
@@ -658,6 +745,8 @@ func (ch *Channel) writeMsgPacketTo(w io.Writer) (n int, err error) {
 	writeMsgPacketTo(packet, w, &n, &err)
 	if err == nil {
 		ch.recentlySent += int64(n)
+		ch.sendMonitor.Update(n)
+		ch.metrics.sent(n, packet.EOF == byte(0x01))
 	}
 	return
 }
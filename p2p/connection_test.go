@@ -313,3 +313,109 @@ func TestMConnectionTrySend(t *testing.T) {
 	server.Read(make([]byte, len(msg)))
 	assert.Equal("Send", <-resultCh) // Order constrained by parallel blocking above
 }
+
+func TestMConnectionChannelSendRate(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	server, client := netPipe()
+	defer server.Close() // nolint: errcheck
+	defer client.Close() // nolint: errcheck
+
+	// a channel sending a packet per sample of its monitor at most, and an unlimited one
+	chDescs := []*ChannelDescriptor{
+		{ID: 0x01, Priority: 1, SendQueueCapacity: 10, SendRate: 1},
+		{ID: 0x02, Priority: 1, SendQueueCapacity: 10},
+	}
+	mconn1 := NewMConnection(client, chDescs, func(chID byte, msgBytes []byte) {}, func(r interface{}) {})
+	mconn1.SetLogger(log.TestingLogger())
+	require.Nil(mconn1.Start())
+	defer mconn1.Stop()
+
+	receivedCh := make(chan byte, 10)
+	onReceive := func(chID byte, msgBytes []byte) {
+		receivedCh <- chID
+	}
+	mconn2 := NewMConnection(server, chDescs, onReceive, func(r interface{}) {})
+	mconn2.SetLogger(log.TestingLogger())
+	require.Nil(mconn2.Start())
+	defer mconn2.Stop()
+
+	for i := 0; i < 5; i++ {
+		assert.True(mconn1.TrySend(0x01, "Quicksilver"))
+	}
+	assert.True(mconn1.TrySend(0x02, "Flash"))
+
+	// the unlimited channel isn't starved by the throttled one, which still sends all its messages
+	received := make(map[byte]int)
+	timeout := time.After(5 * time.Second)
+	for received[0x01] < 5 || received[0x02] < 1 {
+		select {
+		case chID := <-receivedCh:
+			if chID == 0x02 {
+				assert.True(received[0x01] < 5, "Throttled channel sent all its messages first")
+			}
+			received[chID]++
+		case <-timeout:
+			t.Fatalf("Did not receive all the messages in 5s, got %v", received)
+		}
+	}
+}
+
+func TestMConnectionFuzzMsgs(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	server, client := netPipe()
+	defer server.Close() // nolint: errcheck
+	defer client.Close() // nolint: errcheck
+
+	chDescs := []*ChannelDescriptor{
+		{ID: 0x01, Priority: 1, Fuzz: &FuzzMsgConfig{ProbDrop: 1}},
+		{ID: 0x02, Priority: 1, Fuzz: &FuzzMsgConfig{ProbCorrupt: 1}},
+		{ID: 0x03, Priority: 1, Fuzz: &FuzzMsgConfig{ProbDelay: 1, MaxDelay: 100 * time.Millisecond}},
+	}
+	mconn1 := NewMConnection(client, chDescs, func(chID byte, msgBytes []byte) {}, func(r interface{}) {})
+	mconn1.SetLogger(log.TestingLogger())
+	require.Nil(mconn1.Start())
+	defer mconn1.Stop()
+
+	type received struct {
+		chID     byte
+		msgBytes []byte
+	}
+	receivedCh := make(chan received, 10)
+	onReceive := func(chID byte, msgBytes []byte) {
+		receivedCh <- received{chID, msgBytes}
+	}
+	mconn2 := NewMConnection(server, chDescs, onReceive, func(r interface{}) {})
+	mconn2.SetLogger(log.TestingLogger())
+	require.Nil(mconn2.Start())
+	defer mconn2.Stop()
+
+	msg := "Ant-Man"
+	msgBytes := wire.BinaryBytes(msg)
+	for _, chID := range []byte{0x01, 0x02, 0x03} {
+		assert.True(mconn1.Send(chID, msg))
+	}
+
+	// the corrupted and the delayed messages arrive, in any order
+	got := make(map[byte][]byte)
+	for len(got) < 2 {
+		select {
+		case r := <-receivedCh:
+			got[r.chID] = r.msgBytes
+		case <-time.After(time.Second):
+			t.Fatalf("Did not receive the messages, got %v", got)
+		}
+	}
+	require.Contains(got, byte(0x02))
+	assert.NotEqual(msgBytes, got[0x02])
+	assert.Len(got[0x02], len(msgBytes))
+	assert.Equal(msgBytes, got[0x03])
+
+	// the dropped one never does
+	select {
+	case r := <-receivedCh:
+		t.Fatalf("Received the dropped message of channel %X", r.chID)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
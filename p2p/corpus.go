@@ -0,0 +1,183 @@
+package p2p
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	cmn "github.com/tendermint/tmlibs/common"
+)
+
+// A fuzz corpus is the sequence of the messages a peer sent us, as they were
+// handed to the reactors. Each frame is the channel ID (1 byte), the length of
+// the message (4 bytes, big endian) and the raw message bytes.
+// The corpora are recorded for a sample of the peers (see P2PConfig.FuzzCorpusDir),
+// and fed back through the reactors with ReplayCorpus, eg. by `tendermint fuzz-replay`,
+// to reproduce the decoding crashes seen in production.
+
+const (
+	// CorpusFileExt is the extension of the corpus files.
+	CorpusFileExt = ".corpus"
+
+	maxCorpusFrameSize = 1 << 26 // 64MB, larger than any p2p message
+)
+
+// CorpusFrame is a message received from a peer on a channel.
+type CorpusFrame struct {
+	ChID byte
+	Msg  []byte
+}
+
+// corpusRecorder appends the messages received from a peer to its corpus file.
+type corpusRecorder struct {
+	mtx  sync.Mutex
+	file *os.File
+}
+
+// newCorpusRecorder creates the corpus file of the peer in the dir.
+func newCorpusRecorder(dir string, peerKey string) (*corpusRecorder, error) {
+	if err := cmn.EnsureDir(dir, 0700); err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("%s-%d%s", peerKey, time.Now().UnixNano(), CorpusFileExt)
+	file, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &corpusRecorder{file: file}, nil
+}
+
+// Record writes the frame to the corpus file.
+// It isn't buffered, so the frame that crashes the node is on disk.
+func (cr *corpusRecorder) Record(chID byte, msgBytes []byte) error {
+	buf := make([]byte, 5+len(msgBytes))
+	buf[0] = chID
+	binary.BigEndian.PutUint32(buf[1:5], uint32(len(msgBytes)))
+	copy(buf[5:], msgBytes)
+
+	cr.mtx.Lock()
+	defer cr.mtx.Unlock()
+	_, err := cr.file.Write(buf)
+	return err
+}
+
+// Close closes the corpus file.
+func (cr *corpusRecorder) Close() error {
+	cr.mtx.Lock()
+	defer cr.mtx.Unlock()
+	return cr.file.Close()
+}
+
+// ReadCorpus reads the frames of a corpus.
+// A corpus truncated in its last frame, as when the node crashed while
+// recording it, returns the complete frames only.
+func ReadCorpus(r io.Reader) ([]CorpusFrame, error) {
+	br := bufio.NewReader(r)
+	frames := []CorpusFrame{}
+	header := make([]byte, 5)
+	for {
+		if _, err := io.ReadFull(br, header); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return frames, nil
+			}
+			return nil, err
+		}
+		size := binary.BigEndian.Uint32(header[1:5])
+		if size > maxCorpusFrameSize {
+			return nil, fmt.Errorf("Corpus frame %d is too big: %d bytes", len(frames), size)
+		}
+		msg := make([]byte, size)
+		if _, err := io.ReadFull(br, msg); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return frames, nil
+			}
+			return nil, err
+		}
+		frames = append(frames, CorpusFrame{ChID: header[0], Msg: msg})
+	}
+}
+
+// ReadCorpusFile reads the frames of the corpus file.
+func ReadCorpusFile(path string) ([]CorpusFrame, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close() // nolint: errcheck
+	return ReadCorpus(file)
+}
+
+// ReplayCorpus feeds the frames of a corpus through the reactors of the switch,
+// as if a peer had sent them. The messages the reactors send back are dropped.
+// It returns an error with the stack trace on the first frame a reactor panics on.
+// Frames on channels no reactor handles are skipped, as the connection rejects them.
+func ReplayCorpus(sw *Switch, frames []CorpusFrame) error {
+	peer := newReplayPeer()
+	if err := peer.Start(); err != nil {
+		return err
+	}
+	defer peer.Stop() // nolint: errcheck
+
+	for _, reactor := range sw.reactors {
+		reactor.AddPeer(peer)
+	}
+	defer func() {
+		for _, reactor := range sw.reactors {
+			reactor.RemovePeer(peer, "corpus replayed")
+		}
+	}()
+
+	for i, frame := range frames {
+		reactor := sw.reactorsByCh[frame.ChID]
+		if reactor == nil {
+			continue
+		}
+		if err := replayFrame(reactor, peer, frame); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("Frame %d on channel %X", i, frame.ChID))
+		}
+	}
+	return nil
+}
+
+func replayFrame(reactor Reactor, peer Peer, frame CorpusFrame) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Reactor panicked: %v\n%s", r, debug.Stack())
+		}
+	}()
+	reactor.Receive(frame.ChID, peer, frame.Msg)
+	return nil
+}
+
+// replayPeer is the peer the corpus frames are replayed from.
+// It drops all the messages sent to it.
+type replayPeer struct {
+	cmn.BaseService
+	data *cmn.CMap
+}
+
+func newReplayPeer() *replayPeer {
+	p := &replayPeer{data: cmn.NewCMap()}
+	p.BaseService = *cmn.NewBaseService(nil, "ReplayPeer", p)
+	return p
+}
+
+func (p *replayPeer) Key() string                       { return "corpus-replay" }
+func (p *replayPeer) ID() ID                            { return "corpus-replay" }
+func (p *replayPeer) IsOutbound() bool                  { return false }
+func (p *replayPeer) IsPersistent() bool                { return false }
+func (p *replayPeer) NodeInfo() *NodeInfo               { return &NodeInfo{Moniker: "corpus-replay"} }
+func (p *replayPeer) Status() ConnectionStatus          { return ConnectionStatus{} }
+func (p *replayPeer) Send(byte, interface{}) bool       { return true }
+func (p *replayPeer) TrySend(byte, interface{}) bool    { return true }
+func (p *replayPeer) Set(key string, value interface{}) { p.data.Set(key, value) }
+func (p *replayPeer) Get(key string) interface{}        { return p.data.Get(key) }
+func (p *replayPeer) String() string                    { return "Peer{corpus-replay}" }
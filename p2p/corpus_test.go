@@ -0,0 +1,77 @@
+package p2p
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type panicReactor struct {
+	BaseReactor
+}
+
+func (pr *panicReactor) GetChannels() []*ChannelDescriptor {
+	return []*ChannelDescriptor{{ID: byte(0x04), Priority: 10}}
+}
+
+func (pr *panicReactor) Receive(chID byte, peer Peer, msgBytes []byte) {
+	panic("bad message")
+}
+
+func TestCorpusRecordAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "corpus")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	cr, err := newCorpusRecorder(dir, "peerkey")
+	require.Nil(t, err)
+	require.Nil(t, cr.Record(0x00, []byte("foo")))
+	require.Nil(t, cr.Record(0x02, []byte("bar")))
+	require.Nil(t, cr.Record(0x05, []byte("no reactor")))
+	require.Nil(t, cr.Record(0x01, []byte{}))
+	require.Nil(t, cr.Close())
+
+	paths, err := filepath.Glob(filepath.Join(dir, "peerkey-*"+CorpusFileExt))
+	require.Nil(t, err)
+	require.Len(t, paths, 1)
+
+	// a frame cut by a crash is dropped
+	f, err := os.OpenFile(paths[0], os.O_WRONLY|os.O_APPEND, 0600)
+	require.Nil(t, err)
+	_, err = f.Write([]byte{0x03, 0x00, 0x00, 0x00, 0x10, 'b'})
+	require.Nil(t, err)
+	require.Nil(t, f.Close())
+
+	frames, err := ReadCorpusFile(paths[0])
+	require.Nil(t, err)
+	assert.Equal(t, []CorpusFrame{
+		{0x00, []byte("foo")},
+		{0x02, []byte("bar")},
+		{0x05, []byte("no reactor")},
+		{0x01, []byte{}},
+	}, frames)
+
+	sw := initSwitchFunc(0, NewSwitch(config))
+	require.Nil(t, ReplayCorpus(sw, frames))
+	foo := sw.Reactor("foo").(*TestReactor)
+	bar := sw.Reactor("bar").(*TestReactor)
+	assert.Equal(t, []byte("foo"), foo.getMsgs(0x00)[0].Bytes)
+	assert.Len(t, foo.getMsgs(0x01), 1)
+	assert.Equal(t, []byte("bar"), bar.getMsgs(0x02)[0].Bytes)
+	assert.Len(t, foo.peersAdded, 1)
+	assert.Len(t, foo.peersRemoved, 1)
+
+	// the frame a reactor panics on is reported
+	pr := &panicReactor{}
+	pr.BaseReactor = *NewBaseReactor("panicReactor", pr)
+	sw.AddReactor("panic", pr)
+	frames = append(frames, CorpusFrame{0x04, []byte("boom")})
+	err = ReplayCorpus(sw, frames)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Frame 4 on channel 04")
+	assert.Contains(t, err.Error(), "bad message")
+}
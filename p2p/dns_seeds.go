@@ -0,0 +1,178 @@
+package p2p
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cmn "github.com/tendermint/tmlibs/common"
+)
+
+// DNSSeeder discovers seed nodes through DNS, so the operators of a network can
+// rotate its seeds by editing DNS records instead of the config of every node.
+//
+// Each DNS name is resolved for its SRV records, each pointing to a seed by its
+// host and port, and for its TXT records, each listing seeds as "[id@]host:port",
+// separated by commas or spaces. The names are re-resolved every interval, if
+// not zero, and the seeds not found by the previous resolution are added to the
+// address book and dialed. Unlike the seeds of the config, they're not
+// persistent peers, so the seeds rotated out aren't reconnected to.
+// NOTE: the peers are dialed by their ID, which SRV records can't tell, so the
+// seeds of the SRV records, and the ones listed without ID, are ignored.
+type DNSSeeder struct {
+	cmn.BaseService
+
+	sw       *Switch
+	addrBook *AddrBook
+	names    []string
+	interval time.Duration
+
+	// resolvers, replaced in tests
+	lookupSRV func(service, proto, name string) (string, []*net.SRV, error)
+	lookupTXT func(name string) ([]string, error)
+
+	seeds map[string]bool // the seeds found by the last resolution
+}
+
+// NewDNSSeeder returns a DNSSeeder resolving the names every interval, and
+// dialing the seeds found with the switch, adding them to the addrBook if not nil.
+func NewDNSSeeder(sw *Switch, addrBook *AddrBook, names []string, interval time.Duration) *DNSSeeder {
+	ds := &DNSSeeder{
+		sw:        sw,
+		addrBook:  addrBook,
+		names:     names,
+		interval:  interval,
+		lookupSRV: net.LookupSRV,
+		lookupTXT: net.LookupTXT,
+		seeds:     make(map[string]bool),
+	}
+	ds.BaseService = *cmn.NewBaseService(nil, "DNSSeeder", ds)
+	return ds
+}
+
+// OnStart implements cmn.Service by starting the routine resolving the names.
+func (ds *DNSSeeder) OnStart() error {
+	go ds.resolveRoutine()
+	return nil
+}
+
+func (ds *DNSSeeder) resolveRoutine() {
+	if ds.interval <= 0 {
+		ds.dialNewSeeds()
+		return
+	}
+	ticker := time.NewTicker(ds.interval)
+	defer ticker.Stop()
+	for {
+		ds.dialNewSeeds()
+		select {
+		case <-ticker.C:
+		case <-ds.Quit:
+			return
+		}
+	}
+}
+
+// dialNewSeeds resolves the names, and dials the seeds found since the last time.
+// If no name resolves, the seeds of the last resolution are kept.
+func (ds *DNSSeeder) dialNewSeeds() {
+	seeds := make(map[string]bool)
+	for _, name := range ds.names {
+		addrs, err := ds.Resolve(name)
+		if err != nil {
+			ds.Logger.Error("Failed to resolve DNS seed", "name", name, "err", err)
+			continue
+		}
+		for _, addr := range addrs {
+			seeds[addr] = true
+		}
+	}
+	if len(seeds) == 0 {
+		return
+	}
+
+	var newSeeds []string
+	for addr := range seeds {
+		if ds.seeds[addr] {
+			continue
+		}
+		if !strings.Contains(addr, "@") {
+			ds.Logger.Info("Ignoring DNS seed without ID", "seed", addr)
+			continue
+		}
+		newSeeds = append(newSeeds, addr)
+	}
+	ds.seeds = seeds
+	if len(newSeeds) == 0 {
+		return
+	}
+	sort.Strings(newSeeds)
+	ds.Logger.Info("Dialing new DNS seeds", "seeds", newSeeds)
+	if err := ds.sw.dialSeeds(ds.addrBook, newSeeds, false); err != nil {
+		ds.Logger.Error("Failed to dial DNS seeds", "err", err)
+	}
+}
+
+// Resolve returns the addresses, as "[id@]host:port", of the seeds listed by
+// the SRV and TXT records of the name, sorted and without duplicates.
+// It only fails if neither kind of record could be resolved.
+func (ds *DNSSeeder) Resolve(name string) ([]string, error) {
+	found := make(map[string]bool)
+
+	_, srvs, srvErr := ds.lookupSRV("", "", name)
+	for _, srv := range srvs {
+		host := strings.TrimSuffix(srv.Target, ".")
+		found[net.JoinHostPort(host, strconv.Itoa(int(srv.Port)))] = true
+	}
+
+	txts, txtErr := ds.lookupTXT(name)
+	for _, txt := range txts {
+		for _, entry := range strings.FieldsFunc(txt, func(r rune) bool { return r == ',' || r == ' ' }) {
+			addr, err := parseDNSSeed(entry)
+			if err != nil {
+				ds.Logger.Error("Invalid DNS seed", "name", name, "entry", entry, "err", err)
+				continue
+			}
+			found[addr] = true
+		}
+	}
+
+	if srvErr != nil && txtErr != nil {
+		return nil, fmt.Errorf("SRV: %v, TXT: %v", srvErr, txtErr)
+	}
+	addrs := make([]string, 0, len(found))
+	for addr := range found {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	return addrs, nil
+}
+
+// parseDNSSeed returns the "[id@]host:port" of an entry of a TXT record.
+func parseDNSSeed(entry string) (string, error) {
+	var id ID
+	if i := strings.Index(entry, "@"); i >= 0 {
+		id = ID(strings.ToLower(entry[:i]))
+		if err := validateID(id); err != nil {
+			return "", err
+		}
+		entry = entry[i+1:]
+	}
+	host, port, err := net.SplitHostPort(entry)
+	if err != nil {
+		return "", err
+	}
+	if host == "" {
+		return "", fmt.Errorf("No host")
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return "", fmt.Errorf("Invalid port %q", port)
+	}
+	if id != "" {
+		return string(id) + "@" + net.JoinHostPort(host, port), nil
+	}
+	return net.JoinHostPort(host, port), nil
+}
@@ -0,0 +1,72 @@
+package p2p
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tendermint/tmlibs/log"
+)
+
+const testID = "3e2a5b7c9d0f1e2a3b4c5d6e7f8091a2b3c4d5e6"
+
+func TestDNSSeederResolve(t *testing.T) {
+	ds := NewDNSSeeder(nil, nil, []string{"seeds.example.com"}, 0)
+	ds.SetLogger(log.TestingLogger())
+	ds.lookupSRV = func(service, proto, name string) (string, []*net.SRV, error) {
+		return "", []*net.SRV{
+			{Target: "seed1.example.com.", Port: 46656},
+			{Target: "seed2.example.com.", Port: 46666},
+		}, nil
+	}
+	ds.lookupTXT = func(name string) ([]string, error) {
+		return []string{
+			testID + "@seed2.example.com:46666, 10.0.0.1:46656",
+			"[::1]:46656 not-an-address seed3.example.com:99999",
+		}, nil
+	}
+
+	addrs, err := ds.Resolve("seeds.example.com")
+	require.Nil(t, err)
+	assert.Equal(t, []string{
+		testID + "@seed2.example.com:46666",
+		"10.0.0.1:46656",
+		"[::1]:46656",
+		"seed1.example.com:46656",
+		"seed2.example.com:46666",
+	}, addrs)
+
+	// one kind of record is enough
+	ds.lookupSRV = func(service, proto, name string) (string, []*net.SRV, error) {
+		return "", nil, errors.New("no such host")
+	}
+	addrs, err = ds.Resolve("seeds.example.com")
+	require.Nil(t, err)
+	assert.Len(t, addrs, 3)
+
+	ds.lookupTXT = func(name string) ([]string, error) {
+		return nil, errors.New("no such host")
+	}
+	_, err = ds.Resolve("seeds.example.com")
+	assert.NotNil(t, err)
+}
+
+func TestParseDNSSeed(t *testing.T) {
+	for entry, addr := range map[string]string{
+		"seed.example.com:46656":                                 "seed.example.com:46656",
+		testID + "@seed.example.com:46656":                       testID + "@seed.example.com:46656",
+		"3E2A5B7C9D0F1E2A3B4C5D6E7F8091A2B3C4D5E6@1.2.3.4:46656": testID + "@1.2.3.4:46656",
+		"1.2.3.4:46656":                                          "1.2.3.4:46656",
+	} {
+		parsed, err := parseDNSSeed(entry)
+		assert.Nil(t, err, entry)
+		assert.Equal(t, addr, parsed, entry)
+	}
+	for _, entry := range []string{"seed.example.com", ":46656", "seed.example.com:port", "seed.example.com:70000",
+		"ABCDEF@seed.example.com:46656"} {
+		_, err := parseDNSSeed(entry)
+		assert.NotNil(t, err, entry)
+	}
+}
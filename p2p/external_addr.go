@@ -0,0 +1,82 @@
+package p2p
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// number of outbound peers which must see us at the same IP for it to be
+	// probed as our external address
+	minExternalAddrObservations = 3
+	// min interval between two probes of the same IP
+	externalAddrProbeInterval = 10 * time.Minute
+	// time for a peer to dial us back at a probed address
+	dialBackTimeout = 10 * time.Second
+)
+
+// externalAddrDetector finds our external address, like STUN: the outbound
+// peers tell the PEX reactor the IP they see us connect from, and once
+// enough of them agree on an IP, a peer is asked to dial us back at it, with
+// our listen port, to check we're dialable there, eg. behind a NAT with a
+// forwarded port but without UPnP.
+type externalAddrDetector struct {
+	mtx      sync.Mutex
+	observed map[ID]string            // the IP each peer sees us at
+	probes   map[string]externalProbe // the last probe of each IP
+}
+
+// externalProbe is the probe of an IP, by a peer asked to dial us back.
+type externalProbe struct {
+	peer ID
+	at   time.Time
+}
+
+func newExternalAddrDetector() *externalAddrDetector {
+	return &externalAddrDetector{
+		observed: make(map[ID]string),
+		probes:   make(map[string]externalProbe),
+	}
+}
+
+// observe records the IP the peer sees us at. It returns true if the peer
+// is to probe the IP: enough peers see us at it, and it wasn't probed
+// recently.
+func (d *externalAddrDetector) observe(id ID, ip net.IP) bool {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	ipStr := ip.String()
+	d.observed[id] = ipStr
+	count := 0
+	for _, observed := range d.observed {
+		if observed == ipStr {
+			count++
+		}
+	}
+	if count < minExternalAddrObservations {
+		return false
+	}
+	if probe, ok := d.probes[ipStr]; ok && time.Since(probe.at) < externalAddrProbeInterval {
+		return false
+	}
+	d.probes[ipStr] = externalProbe{id, time.Now()}
+	return true
+}
+
+// isProbing returns true if the peer is probing the IP, so its answer is
+// expected.
+func (d *externalAddrDetector) isProbing(id ID, ip net.IP) bool {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	probe, ok := d.probes[ip.String()]
+	return ok && probe.peer == id && time.Since(probe.at) < 2*dialBackTimeout
+}
+
+// removePeer forgets the IP the peer sees us at.
+func (d *externalAddrDetector) removePeer(id ID) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	delete(d.observed, id)
+}
@@ -0,0 +1,31 @@
+package p2p
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExternalAddrDetector(t *testing.T) {
+	assert := assert.New(t)
+
+	d := newExternalAddrDetector()
+	ip, otherIP := net.ParseIP("8.8.8.8"), net.ParseIP("8.8.4.4")
+	ids := []ID{"a", "b", "c", "d"}
+
+	// enough distinct peers must see us at the IP
+	assert.False(d.observe(ids[0], ip))
+	assert.False(d.observe(ids[0], ip))
+	assert.False(d.observe(ids[1], otherIP))
+	assert.False(d.observe(ids[1], ip))
+	d.removePeer(ids[1])
+	assert.False(d.observe(ids[2], ip))
+	assert.True(d.observe(ids[1], ip))
+	assert.True(d.isProbing(ids[1], ip))
+	assert.False(d.isProbing(ids[0], ip), "only the peer asked probes")
+	assert.False(d.isProbing(ids[1], otherIP))
+
+	// not probed again right away
+	assert.False(d.observe(ids[3], ip))
+}
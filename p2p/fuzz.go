@@ -155,6 +155,37 @@ func (fc *FuzzedConnection) fuzz() bool {
 	return false
 }
 
+// FuzzMsgConfig is the chaos of the messages received on a channel, to test
+// the liveness of the consensus under adverse network conditions: the
+// messages are dropped, corrupted or delayed, and so reordered, at random.
+// Unlike a FuzzedConnection, it works on the messages of the reactors, above
+// the secret connection, which would reject corrupted bytes.
+type FuzzMsgConfig struct {
+	ProbDrop    float64
+	ProbCorrupt float64 // a random byte of the message is flipped
+	ProbDelay   float64
+	MaxDelay    time.Duration
+}
+
+// fuzzMsg returns the message, maybe corrupted, and how long to delay it, or
+// nil if it's dropped.
+func (fc *FuzzMsgConfig) fuzzMsg(msgBytes []byte) ([]byte, time.Duration) {
+	if rand.Float64() < fc.ProbDrop { // nolint: gas
+		return nil, 0
+	}
+	if len(msgBytes) > 0 && rand.Float64() < fc.ProbCorrupt { // nolint: gas
+		corrupted := make([]byte, len(msgBytes))
+		copy(corrupted, msgBytes)
+		corrupted[rand.Intn(len(corrupted))] ^= byte(1 + rand.Intn(255)) // nolint: gas
+		msgBytes = corrupted
+	}
+	var delay time.Duration
+	if fc.MaxDelay > 0 && rand.Float64() < fc.ProbDelay { // nolint: gas
+		delay = time.Duration(rand.Int63n(int64(fc.MaxDelay))) // nolint: gas
+	}
+	return msgBytes, delay
+}
+
 func (fc *FuzzedConnection) shouldFuzz() bool {
 	if fc.active {
 		return true
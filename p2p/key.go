@@ -0,0 +1,85 @@
+package p2p
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	crypto "github.com/tendermint/go-crypto"
+	cmn "github.com/tendermint/tmlibs/common"
+)
+
+// ID is the hex encoded address of the public key of a node, which
+// authenticates it: a peer dialed at "id@host:port" must prove it holds the
+// private key of the ID in the secret connection handshake.
+type ID string
+
+// IDByteLength is the length of the address of an ID, in bytes.
+const IDByteLength = 20
+
+// NodeKey is the persistent key of a node, which its ID is derived from.
+// It is distinct from the key of the validator, if any.
+type NodeKey struct {
+	PrivKey crypto.PrivKey `json:"priv_key"`
+}
+
+// ID returns the ID of the node.
+func (nodeKey *NodeKey) ID() ID {
+	return PubKeyToID(nodeKey.PubKey())
+}
+
+// PubKey returns the public key of the node.
+func (nodeKey *NodeKey) PubKey() crypto.PubKey {
+	return nodeKey.PrivKey.PubKey()
+}
+
+// PubKeyToID returns the ID of the public key.
+func PubKeyToID(pubKey crypto.PubKey) ID {
+	return ID(hex.EncodeToString(pubKey.Address()))
+}
+
+// validateID returns an error if the ID isn't the hex encoded address of a key.
+func validateID(id ID) error {
+	bz, err := hex.DecodeString(string(id))
+	if err != nil {
+		return fmt.Errorf("Invalid peer ID %q: %v", id, err)
+	}
+	if len(bz) != IDByteLength {
+		return fmt.Errorf("Invalid peer ID %q: %d bytes, expected %d", id, len(bz), IDByteLength)
+	}
+	return nil
+}
+
+// LoadOrGenNodeKey loads the node key from the file, or generates a new one
+// and saves it there if the file doesn't exist.
+func LoadOrGenNodeKey(filePath string) (*NodeKey, error) {
+	if cmn.FileExists(filePath) {
+		return LoadNodeKey(filePath)
+	}
+	nodeKey := &NodeKey{PrivKey: crypto.GenPrivKeyEd25519().Wrap()}
+	jsonBytes, err := json.Marshal(nodeKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := cmn.WriteFileAtomic(filePath, jsonBytes, 0600); err != nil {
+		return nil, err
+	}
+	return nodeKey, nil
+}
+
+// LoadNodeKey loads the node key from the file.
+func LoadNodeKey(filePath string) (*NodeKey, error) {
+	jsonBytes, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	nodeKey := new(NodeKey)
+	if err := json.Unmarshal(jsonBytes, nodeKey); err != nil {
+		return nil, fmt.Errorf("Error reading the node key from %v: %v", filePath, err)
+	}
+	if _, ok := nodeKey.PrivKey.Unwrap().(crypto.PrivKeyEd25519); !ok {
+		return nil, fmt.Errorf("The node key in %v must be ed25519", filePath)
+	}
+	return nodeKey, nil
+}
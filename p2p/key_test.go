@@ -0,0 +1,34 @@
+package p2p
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOrGenNodeKey(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	dir, err := ioutil.TempDir("", "node_key")
+	require.Nil(err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+	filePath := filepath.Join(dir, "node_key.json")
+
+	nodeKey, err := LoadOrGenNodeKey(filePath)
+	require.Nil(err)
+	assert.Nil(validateID(nodeKey.ID()))
+	assert.Equal(PubKeyToID(nodeKey.PubKey()), nodeKey.ID())
+
+	// the key is persistent
+	loaded, err := LoadOrGenNodeKey(filePath)
+	require.Nil(err)
+	assert.Equal(nodeKey.ID(), loaded.ID())
+
+	require.Nil(ioutil.WriteFile(filePath, []byte("not a key"), 0600))
+	_, err = LoadNodeKey(filePath)
+	assert.NotNil(err)
+}
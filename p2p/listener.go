@@ -26,9 +26,18 @@ type DefaultListener struct {
 	listener    net.Listener
 	intAddr     *NetAddress
 	extAddr     *NetAddress
+	portMapping *portMapping // the UPnP mapping of the port, if any
 	connections chan net.Conn
 }
 
+// portMapping is a port of the router forwarded to the listener with UPnP.
+type portMapping struct {
+	nat          upnp.NAT
+	protocol     string
+	externalPort int
+	internalPort int
+}
+
 const (
 	numBufferedConnections = 10
 	defaultExternalPort    = 8770
@@ -47,8 +56,15 @@ func splitHostPort(addr string) (host string, port int) {
 	return host, port
 }
 
+// NewDefaultListener listens with the TCP transport of the protocol.
 // skipUPNP: If true, does not try getUPNPExternalAddress()
 func NewDefaultListener(protocol string, lAddr string, skipUPNP bool, logger log.Logger) Listener {
+	return NewTransportListener(NewTCPTransport(protocol), lAddr, skipUPNP, logger)
+}
+
+// NewTransportListener listens with the transport, see NewTransport.
+// skipUPNP: If true, does not try getUPNPExternalAddress()
+func NewTransportListener(transport Transport, lAddr string, skipUPNP bool, logger log.Logger) Listener {
 	// Local listen IP & port
 	lAddrIP, lAddrPort := splitHostPort(lAddr)
 
@@ -56,7 +72,7 @@ func NewDefaultListener(protocol string, lAddr string, skipUPNP bool, logger log
 	var listener net.Listener
 	var err error
 	for i := 0; i < tryListenSeconds; i++ {
-		listener, err = net.Listen(protocol, lAddr)
+		listener, err = transport.Listen(lAddr)
 		if err == nil {
 			break
 		} else if i < tryListenSeconds-1 {
@@ -79,10 +95,11 @@ func NewDefaultListener(protocol string, lAddr string, skipUPNP bool, logger log
 
 	// Determine external address...
 	var extAddr *NetAddress
+	var mapping *portMapping
 	if !skipUPNP {
 		// If the lAddrIP is INADDR_ANY, try UPnP
 		if lAddrIP == "" || lAddrIP == "0.0.0.0" {
-			extAddr = getUPNPExternalAddress(lAddrPort, listenerPort, logger)
+			extAddr, mapping = getUPNPExternalAddress(transport.Network(), lAddrPort, listenerPort, logger)
 		}
 	}
 	// Otherwise just use the local address...
@@ -97,6 +114,7 @@ func NewDefaultListener(protocol string, lAddr string, skipUPNP bool, logger log
 		listener:    listener,
 		intAddr:     intAddr,
 		extAddr:     extAddr,
+		portMapping: mapping,
 		connections: make(chan net.Conn, numBufferedConnections),
 	}
 	dl.BaseService = *cmn.NewBaseService(logger, "DefaultListener", dl)
@@ -118,6 +136,11 @@ func (l *DefaultListener) OnStart() error {
 func (l *DefaultListener) OnStop() {
 	l.BaseService.OnStop()
 	l.listener.Close() // nolint: errcheck
+	if m := l.portMapping; m != nil {
+		if err := m.nat.DeletePortMapping(m.protocol, m.externalPort, m.internalPort); err != nil {
+			l.Logger.Info("Could not delete UPNP port mapping", "err", err)
+		}
+	}
 }
 
 // Accept connections and pass on the channel
@@ -171,19 +194,25 @@ func (l *DefaultListener) String() string {
 
 /* external address helpers */
 
-// UPNP external address discovery & port mapping
-func getUPNPExternalAddress(externalPort, internalPort int, logger log.Logger) *NetAddress {
+// UPNP external address discovery & port mapping.
+// The mapping is deleted when the listener stops.
+func getUPNPExternalAddress(protocol string, externalPort, internalPort int, logger log.Logger) (*NetAddress, *portMapping) {
 	logger.Info("Getting UPNP external address")
 	nat, err := upnp.Discover()
 	if err != nil {
 		logger.Info("Could not perform UPNP discover", "err", err)
-		return nil
+		return nil, nil
 	}
 
 	ext, err := nat.GetExternalAddress()
 	if err != nil {
 		logger.Info("Could not get UPNP external address", "err", err)
-		return nil
+		return nil, nil
+	}
+	// eg. behind another NAT, the address may still be detected by the peers
+	if !NewNetAddressIPPort(ext, 0).Routable() {
+		logger.Info("UPNP external address is not routable", "address", ext)
+		return nil, nil
 	}
 
 	// UPnP can't seem to get the external port, so let's just be explicit.
@@ -191,14 +220,14 @@ func getUPNPExternalAddress(externalPort, internalPort int, logger log.Logger) *
 		externalPort = defaultExternalPort
 	}
 
-	externalPort, err = nat.AddPortMapping("tcp", externalPort, internalPort, "tendermint", 0)
+	externalPort, err = nat.AddPortMapping(protocol, externalPort, internalPort, "tendermint", 0)
 	if err != nil {
 		logger.Info("Could not add UPNP port mapping", "err", err)
-		return nil
+		return nil, nil
 	}
 
-	logger.Info("Got UPNP external address", "address", ext)
-	return NewNetAddressIPPort(ext, uint16(externalPort))
+	logger.Info("Got UPNP external address", "address", ext, "port", externalPort)
+	return NewNetAddressIPPort(ext, uint16(externalPort)), &portMapping{nat, protocol, externalPort, internalPort}
 }
 
 // TODO: use syscalls: see issue #712
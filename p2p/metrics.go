@@ -0,0 +1,197 @@
+package p2p
+
+import (
+	"fmt"
+	"sync"
+
+	metrics "github.com/rcrowley/go-metrics"
+)
+
+const (
+	// peers beyond maxPeers are aggregated under this key
+	metricsOtherPeers = "other"
+
+	metricsHistogramSampleSize = 1028
+)
+
+// Metrics exports per-channel p2p traffic to a go-metrics registry,
+// so bandwidth can be attributed to the reactors using it
+// (eg. mempool vs consensus vs blockchain) and to the peers on the other end.
+//
+// For every channel, these are registered:
+//
+//	p2p.channel.<chID>.<metric>                  summed over all peers
+//	p2p.peer.<peerKey>.channel.<chID>.<metric>   for a single peer
+//
+// where <metric> is one of send_bytes, recv_bytes, send_msgs, recv_msgs,
+// dropped_msgs (counters) or queue_saturation (a histogram of how full, in percent,
+// the send queue was whenever a message was queued).
+//
+// To cap the cardinality, only the first maxPeers peers get their own metrics,
+// the others are reported together as peer "other".
+// The metrics of a peer are unregistered when it disconnects.
+//
+// The number of connected peers is reported as the gauge p2p.peers.
+type Metrics struct {
+	registry metrics.Registry
+	maxPeers int
+	numPeers metrics.Gauge
+
+	mtx    sync.Mutex
+	peers  map[string]string // peer key -> key its metrics are reported under
+	counts map[string]int    // number of peers reported under a key
+}
+
+// NewMetrics returns Metrics registering into the given registry.
+// If registry is nil, metrics.DefaultRegistry is used.
+func NewMetrics(registry metrics.Registry, maxPeers int) *Metrics {
+	if registry == nil {
+		registry = metrics.DefaultRegistry
+	}
+	return &Metrics{
+		registry: registry,
+		maxPeers: maxPeers,
+		numPeers: metrics.GetOrRegisterGauge("p2p.peers", registry),
+		peers:    make(map[string]string),
+		counts:   make(map[string]int),
+	}
+}
+
+// peerChannelMetrics returns the metrics to use for the channels of the given
+// peer, and the key they are reported under, to give addPeer once the peer is
+// added to the switch.
+func (m *Metrics) peerChannelMetrics(peerKey string, chIDs []byte) (string, map[byte]*channelMetrics) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	key, ok := m.peers[peerKey]
+	if !ok {
+		key = peerKey
+		if len(m.peers)-m.counts[metricsOtherPeers] >= m.maxPeers {
+			key = metricsOtherPeers
+		}
+	}
+
+	chMetrics := make(map[byte]*channelMetrics, len(chIDs))
+	for _, chID := range chIDs {
+		chMetrics[chID] = &channelMetrics{
+			total: m.channelCounters(fmt.Sprintf("p2p.channel.%X", chID)),
+			peer:  m.channelCounters(fmt.Sprintf("p2p.peer.%s.channel.%X", key, chID)),
+		}
+	}
+	return key, chMetrics
+}
+
+// addPeer counts the given peer as connected, its metrics reported under key.
+func (m *Metrics) addPeer(peerKey, key string) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if _, ok := m.peers[peerKey]; ok {
+		return
+	}
+	m.peers[peerKey] = key
+	m.counts[key]++
+	m.numPeers.Update(int64(len(m.peers)))
+}
+
+// removePeer unregisters the metrics of the given peer,
+// once no other peer is reported under the same key.
+// It is a no-op if the peer was already removed.
+func (m *Metrics) removePeer(peerKey string, chIDs []byte) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	key, ok := m.peers[peerKey]
+	if !ok {
+		return
+	}
+	delete(m.peers, peerKey)
+	m.numPeers.Update(int64(len(m.peers)))
+	m.counts[key]--
+	if m.counts[key] > 0 {
+		return
+	}
+	delete(m.counts, key)
+	for _, chID := range chIDs {
+		prefix := fmt.Sprintf("p2p.peer.%s.channel.%X", key, chID)
+		for _, name := range channelMetricNames {
+			m.registry.Unregister(prefix + "." + name)
+		}
+	}
+}
+
+func (m *Metrics) channelCounters(prefix string) *channelCounters {
+	return &channelCounters{
+		sendBytes:   metrics.GetOrRegisterCounter(prefix+".send_bytes", m.registry),
+		recvBytes:   metrics.GetOrRegisterCounter(prefix+".recv_bytes", m.registry),
+		sendMsgs:    metrics.GetOrRegisterCounter(prefix+".send_msgs", m.registry),
+		recvMsgs:    metrics.GetOrRegisterCounter(prefix+".recv_msgs", m.registry),
+		droppedMsgs: metrics.GetOrRegisterCounter(prefix+".dropped_msgs", m.registry),
+		queueSaturation: metrics.GetOrRegisterHistogram(prefix+".queue_saturation", m.registry,
+			metrics.NewUniformSample(metricsHistogramSampleSize)),
+	}
+}
+
+var channelMetricNames = []string{
+	"send_bytes", "recv_bytes", "send_msgs", "recv_msgs", "dropped_msgs", "queue_saturation",
+}
+
+//-----------------------------------------------------------------------------
+
+type channelCounters struct {
+	sendBytes       metrics.Counter
+	recvBytes       metrics.Counter
+	sendMsgs        metrics.Counter
+	recvMsgs        metrics.Counter
+	droppedMsgs     metrics.Counter
+	queueSaturation metrics.Histogram
+}
+
+// channelMetrics updates both the totals of a channel and the ones of its peer.
+// A nil *channelMetrics is valid and records nothing.
+type channelMetrics struct {
+	total *channelCounters
+	peer  *channelCounters
+}
+
+func (cm *channelMetrics) queued(queueSize, queueCapacity int) {
+	if cm == nil {
+		return
+	}
+	saturation := int64(100 * queueSize / queueCapacity)
+	cm.total.queueSaturation.Update(saturation)
+	cm.peer.queueSaturation.Update(saturation)
+}
+
+func (cm *channelMetrics) dropped() {
+	if cm == nil {
+		return
+	}
+	cm.total.droppedMsgs.Inc(1)
+	cm.peer.droppedMsgs.Inc(1)
+}
+
+func (cm *channelMetrics) sent(n int, eof bool) {
+	if cm == nil {
+		return
+	}
+	cm.total.sendBytes.Inc(int64(n))
+	cm.peer.sendBytes.Inc(int64(n))
+	if eof {
+		cm.total.sendMsgs.Inc(1)
+		cm.peer.sendMsgs.Inc(1)
+	}
+}
+
+func (cm *channelMetrics) received(n int, eof bool) {
+	if cm == nil {
+		return
+	}
+	cm.total.recvBytes.Inc(int64(n))
+	cm.peer.recvBytes.Inc(int64(n))
+	if eof {
+		cm.total.recvMsgs.Inc(1)
+		cm.peer.recvMsgs.Inc(1)
+	}
+}
@@ -0,0 +1,104 @@
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	metrics "github.com/rcrowley/go-metrics"
+)
+
+// addMetricsPeer adds the peer to the metrics, as the switch does.
+func addMetricsPeer(m *Metrics, peerKey string, chIDs []byte) map[byte]*channelMetrics {
+	key, chMetrics := m.peerChannelMetrics(peerKey, chIDs)
+	m.addPeer(peerKey, key)
+	return chMetrics
+}
+
+func TestMetricsMaxPeers(t *testing.T) {
+	assert := assert.New(t)
+
+	registry := metrics.NewRegistry()
+	m := NewMetrics(registry, 2)
+	chIDs := []byte{0x01, 0x02}
+
+	addMetricsPeer(m, "a", chIDs)
+	addMetricsPeer(m, "b", chIDs)
+	addMetricsPeer(m, "c", chIDs)
+	addMetricsPeer(m, "d", chIDs)
+	assert.NotNil(registry.Get("p2p.peer.a.channel.01.send_bytes"))
+	assert.NotNil(registry.Get("p2p.peer.b.channel.02.recv_msgs"))
+	assert.Nil(registry.Get("p2p.peer.c.channel.01.send_bytes"))
+	assert.NotNil(registry.Get("p2p.peer.other.channel.01.send_bytes"))
+	assert.NotNil(registry.Get("p2p.channel.02.queue_saturation"))
+
+	// peers are unregistered when they disconnect, more than once is fine
+	m.removePeer("a", chIDs)
+	m.removePeer("a", chIDs)
+	assert.Nil(registry.Get("p2p.peer.a.channel.01.send_bytes"))
+
+	// "other" stays until all its peers are gone
+	m.removePeer("c", chIDs)
+	assert.NotNil(registry.Get("p2p.peer.other.channel.01.send_bytes"))
+	m.removePeer("d", chIDs)
+	assert.Nil(registry.Get("p2p.peer.other.channel.01.send_bytes"))
+
+	// a freed slot can be used by a new peer
+	addMetricsPeer(m, "e", chIDs)
+	assert.NotNil(registry.Get("p2p.peer.e.channel.01.send_bytes"))
+
+	// the peers only count once added to the switch
+	m.peerChannelMetrics("f", chIDs)
+	assert.EqualValues(2, registry.Get("p2p.peers").(metrics.Gauge).Value())
+}
+
+func TestMetricsChannelTraffic(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	server, client := netPipe()
+	defer server.Close() // nolint: errcheck
+	defer client.Close() // nolint: errcheck
+
+	registry := metrics.NewRegistry()
+	m := NewMetrics(registry, 10)
+
+	receivedCh := make(chan []byte)
+	onReceive := func(chID byte, msgBytes []byte) {
+		receivedCh <- msgBytes
+	}
+	onError := func(r interface{}) {}
+
+	mconn1 := createMConnectionWithCallbacks(client, onReceive, onError)
+	mconn1.setChannelMetrics(addMetricsPeer(m, "server", []byte{0x01}))
+	require.Nil(mconn1.Start())
+	defer mconn1.Stop()
+
+	mconn2 := createMConnectionWithCallbacks(server, func(byte, []byte) {}, onError)
+	mconn2.setChannelMetrics(addMetricsPeer(m, "client", []byte{0x01}))
+	require.Nil(mconn2.Start())
+	defer mconn2.Stop()
+
+	msg := "Cyclops"
+	assert.True(mconn2.Send(0x01, msg))
+
+	select {
+	case <-receivedCh:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Did not receive the message in 500ms")
+	}
+
+	counter := func(name string) int64 {
+		return registry.Get(name).(metrics.Counter).Count()
+	}
+	assert.EqualValues(1, counter("p2p.peer.client.channel.01.send_msgs"))
+	assert.EqualValues(1, counter("p2p.peer.server.channel.01.recv_msgs"))
+	assert.EqualValues(1, counter("p2p.channel.01.send_msgs"))
+	assert.EqualValues(1, counter("p2p.channel.01.recv_msgs"))
+	assert.True(counter("p2p.peer.client.channel.01.send_bytes") > int64(len(msg)))
+	assert.True(counter("p2p.peer.server.channel.01.recv_bytes") > int64(len(msg)))
+
+	saturation := registry.Get("p2p.peer.client.channel.01.queue_saturation").(metrics.Histogram)
+	assert.EqualValues(1, saturation.Count())
+}
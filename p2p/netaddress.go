@@ -9,14 +9,16 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"strings"
 	"time"
 
 	cmn "github.com/tendermint/tmlibs/common"
 )
 
 // NetAddress defines information about a peer on the network
-// including its IP address, and port.
+// including its ID, IP address, and port.
 type NetAddress struct {
+	ID   ID // empty if unknown, eg. for our listeners
 	IP   net.IP
 	Port uint16
 	str  string
@@ -42,9 +44,18 @@ func NewNetAddress(addr net.Addr) *NetAddress {
 }
 
 // NewNetAddressString returns a new NetAddress using the provided
-// address in the form of "IP:Port". Also resolves the host if host
+// address in the form of "[ID@]IP:Port". Also resolves the host if host
 // is not an IP.
 func NewNetAddressString(addr string) (*NetAddress, error) {
+	var id ID
+	if i := strings.Index(addr, "@"); i >= 0 {
+		id = ID(strings.ToLower(addr[:i]))
+		if err := validateID(id); err != nil {
+			return nil, err
+		}
+		addr = addr[i+1:]
+	}
+
 	host, portStr, err := net.SplitHostPort(addr)
 	if err != nil {
 		return nil, err
@@ -67,6 +78,7 @@ func NewNetAddressString(addr string) (*NetAddress, error) {
 	}
 
 	na := NewNetAddressIPPort(ip, uint16(port))
+	na.ID = id
 	return na, nil
 }
 
@@ -118,8 +130,16 @@ func (na *NetAddress) Less(other interface{}) bool {
 	return false
 }
 
-// String representation.
+// String representation, "ID@IP:Port", or "IP:Port" if the ID is unknown.
 func (na *NetAddress) String() string {
+	if na.ID != "" {
+		return string(na.ID) + "@" + na.DialString()
+	}
+	return na.DialString()
+}
+
+// DialString returns the "IP:Port" to dial.
+func (na *NetAddress) DialString() string {
 	if na.str == "" {
 		na.str = net.JoinHostPort(
 			na.IP.String(),
@@ -131,7 +151,7 @@ func (na *NetAddress) String() string {
 
 // Dial calls net.Dial on the address.
 func (na *NetAddress) Dial() (net.Conn, error) {
-	conn, err := net.Dial("tcp", na.String())
+	conn, err := net.Dial("tcp", na.DialString())
 	if err != nil {
 		return nil, err
 	}
@@ -140,7 +160,7 @@ func (na *NetAddress) Dial() (net.Conn, error) {
 
 // DialTimeout calls net.DialTimeout on the address.
 func (na *NetAddress) DialTimeout(timeout time.Duration) (net.Conn, error) {
-	conn, err := net.DialTimeout("tcp", na.String(), timeout)
+	conn, err := net.DialTimeout("tcp", na.DialString(), timeout)
 	if err != nil {
 		return nil, err
 	}
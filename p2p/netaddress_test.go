@@ -36,6 +36,10 @@ func TestNewNetAddressString(t *testing.T) {
 		{"notahost:8080", false},
 		{"8082", false},
 		{"127.0.0:8080000", false},
+		{"deadbeefdeadbeefdeadbeefdeadbeefdeadbeef@127.0.0.1:8080", true},
+		{"deadbeef@127.0.0.1:8080", false},
+		{"notanid@127.0.0.1:8080", false},
+		{"deadbeefdeadbeefdeadbeefdeadbeefdeadbeef@notahost", false},
 	}
 
 	for _, t := range tests {
@@ -50,6 +54,21 @@ func TestNewNetAddressString(t *testing.T) {
 	}
 }
 
+func TestNetAddressID(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	addr, err := NewNetAddressString("DEADBEEFDEADBEEFDEADBEEFDEADBEEFDEADBEEF@127.0.0.1:8080")
+	require.Nil(err)
+	assert.Equal(ID("deadbeefdeadbeefdeadbeefdeadbeefdeadbeef"), addr.ID)
+	assert.Equal("deadbeefdeadbeefdeadbeefdeadbeefdeadbeef@127.0.0.1:8080", addr.String())
+	assert.Equal("127.0.0.1:8080", addr.DialString())
+
+	noID, err := NewNetAddressString("127.0.0.1:8080")
+	require.Nil(err)
+	assert.False(addr.Equals(noID))
+	assert.Equal(addr.DialString(), noID.DialString())
+}
+
 func TestNewNetAddressStrings(t *testing.T) {
 	addrs, errs := NewNetAddressStrings([]string{"127.0.0.1:8080", "127.0.0.2:8080"})
 	assert.Len(t, errs, 0)
@@ -3,6 +3,7 @@ package p2p
 import (
 	"fmt"
 	"io"
+	"math/rand"
 	"net"
 	"time"
 
@@ -19,6 +20,7 @@ type Peer interface {
 	cmn.Service
 
 	Key() string
+	ID() ID // the ID authenticated by the secret connection
 	IsOutbound() bool
 	IsPersistent() bool
 	NodeInfo() *NodeInfo
@@ -49,7 +51,11 @@ type peer struct {
 
 	nodeInfo *NodeInfo
 	key      string
+	id       ID
 	Data     *cmn.CMap // User data.
+
+	recordCorpus bool
+	corpus       *corpusRecorder // nil unless the peer is sampled for the fuzz corpus
 }
 
 // PeerConfig is a Peer configuration.
@@ -62,8 +68,15 @@ type PeerConfig struct {
 
 	MConfig *MConnConfig `mapstructure:"connection"`
 
+	// dials the peers, see Switch.SetTransport
+	Transport Transport `mapstructure:"-"`
+
 	Fuzz       bool            `mapstructure:"fuzz"` // fuzz connection (for testing)
 	FuzzConfig *FuzzConnConfig `mapstructure:"fuzz_config"`
+
+	// record the messages of a sample of the peers in fuzz corpora (for debugging)
+	CorpusDir        string  `mapstructure:"corpus_dir"`
+	CorpusSampleRate float64 `mapstructure:"corpus_sample_rate"`
 }
 
 // DefaultPeerConfig returns the default config.
@@ -73,6 +86,7 @@ func DefaultPeerConfig() *PeerConfig {
 		HandshakeTimeout: 20, // * time.Second,
 		DialTimeout:      3,  // * time.Second,
 		MConfig:          DefaultMConnConfig(),
+		Transport:        NewTCPTransport("tcp"),
 		Fuzz:             false,
 		FuzzConfig:       DefaultFuzzConnConfig(),
 	}
@@ -132,6 +146,8 @@ func newPeerFromConnAndConfig(rawConn net.Conn, outbound bool, reactorsByCh map[
 		conn:     conn,
 		config:   config,
 		Data:     cmn.NewCMap(),
+
+		recordCorpus: config.CorpusDir != "" && rand.Float64() < config.CorpusSampleRate,
 	}
 
 	p.mconn = createMConnection(conn, p, reactorsByCh, chDescs, onPeerError, config.MConfig)
@@ -210,6 +226,7 @@ func (p *peer) HandshakeTimeout(ourNodeInfo *NodeInfo, timeout time.Duration) er
 
 	p.nodeInfo = peerNodeInfo
 	p.key = peerNodeInfo.PubKey.KeyString()
+	p.id = peerNodeInfo.ID()
 
 	return nil
 }
@@ -235,6 +252,15 @@ func (p *peer) OnStart() error {
 	if err := p.BaseService.OnStart(); err != nil {
 		return err
 	}
+	if p.recordCorpus {
+		// the key is only known after the handshake
+		corpus, err := newCorpusRecorder(p.config.CorpusDir, p.key)
+		if err != nil {
+			return errors.Wrap(err, "Error creating fuzz corpus")
+		}
+		p.corpus = corpus
+		p.Logger.Info("Recording the messages of the peer in a fuzz corpus", "dir", p.config.CorpusDir)
+	}
 	err := p.mconn.Start()
 	return err
 }
@@ -243,6 +269,11 @@ func (p *peer) OnStart() error {
 func (p *peer) OnStop() {
 	p.BaseService.OnStop()
 	p.mconn.Stop()
+	if p.corpus != nil {
+		if err := p.corpus.Close(); err != nil {
+			p.Logger.Error("Error closing fuzz corpus", "err", err)
+		}
+	}
 }
 
 // Connection returns underlying MConnection.
@@ -320,6 +351,11 @@ func (p *peer) Key() string {
 	return p.key
 }
 
+// ID returns the peer's ID, derived from its public key.
+func (p *peer) ID() ID {
+	return p.id
+}
+
 // NodeInfo returns a copy of the peer's NodeInfo.
 func (p *peer) NodeInfo() *NodeInfo {
 	if p.nodeInfo == nil {
@@ -335,7 +371,7 @@ func (p *peer) Status() ConnectionStatus {
 }
 
 func dial(addr *NetAddress, config *PeerConfig) (net.Conn, error) {
-	conn, err := addr.DialTimeout(config.DialTimeout * time.Second)
+	conn, err := config.Transport.Dial(addr, config.DialTimeout*time.Second)
 	if err != nil {
 		return nil, err
 	}
@@ -350,6 +386,11 @@ func createMConnection(conn net.Conn, p *peer, reactorsByCh map[byte]Reactor, ch
 		if reactor == nil {
 			cmn.PanicSanity(cmn.Fmt("Unknown channel %X", chID))
 		}
+		if p.corpus != nil {
+			if err := p.corpus.Record(chID, msgBytes); err != nil {
+				p.Logger.Error("Error recording fuzz corpus", "err", err)
+			}
+		}
 		reactor.Receive(chID, p, msgBytes)
 	}
 
@@ -2,9 +2,12 @@ package p2p
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"math/rand"
+	"net"
 	"reflect"
+	"strconv"
 	"time"
 
 	wire "github.com/tendermint/go-wire"
@@ -17,14 +20,31 @@ const (
 
 	// period to ensure peers connected
 	defaultEnsurePeersPeriod = 30 * time.Second
-	minNumOutboundPeers      = 10
 	maxPexMessageSize        = 1048576 // 1MB
 
 	// maximum pex messages one peer can send to us during `msgCountByPeerFlushInterval`
 	defaultMaxMsgCountByPeer    = 1000
 	msgCountByPeerFlushInterval = 1 * time.Hour
+
+	// in seed mode, the max number of addresses dialed per crawl, and the
+	// min interval between two crawls of an address
+	maxCrawlPeers     = 50
+	crawlPeerInterval = 2 * time.Minute
+	// in seed mode, time for the addresses sent to a peer to be flushed
+	// before disconnecting it, and the max time a peer stays connected
+	seedDisconnectDelay      = 1 * time.Second
+	seedDisconnectWaitPeriod = 30 * time.Second
 )
 
+// PexVersionKey is the key of the version of the PEX messages of a node in
+// NodeInfo.Other. The nodes without it only know pexAddrsV1Message and
+// pexRequestMessage.
+const PexVersionKey = "pex_version"
+
+// PexVersion is the version of the PEX messages of this node: since 2, the
+// addresses have the IDs of the peers, and the external address is detected.
+const PexVersion = "2"
+
 // PEXReactor handles PEX (peer exchange) and ensures that an
 // adequate number of peers are connected to the switch.
 //
@@ -36,6 +56,22 @@ const (
 // `defaultMaxMsgCountByPeer` messages per `msgCountByPeerFlushInterval` (1000
 // msg/hour).
 //
+// ## Seed mode
+//
+// In seed mode, the reactor crawls the network instead of keeping enough
+// peers connected: it dials the addresses of the book in turn, asks them
+// for their addresses and disconnects, to fill the book. The inbound peers
+// are sent a selection of the book and disconnected right away. See
+// SetSeedMode.
+//
+// ## External address
+//
+// Each peer is told the IP we see it connect from. With
+// SetDetectExternalAddr, once enough outbound peers see us at the same IP,
+// which isn't the one of our ListenAddr, one of them is asked to dial us
+// back there, and if it connects, the address is advertised as ours, to the
+// peers connecting next and to the connected ones, see externalAddrDetector.
+//
 // NOTE [2017-01-17]:
 //   Limiting is fine for now. Maybe down the road we want to keep track of the
 //   quality of peer messages so if peerA keeps telling us about peers we can't
@@ -50,6 +86,15 @@ type PEXReactor struct {
 	// tracks message count by peer, so we can prevent abuse
 	msgCountByPeer    *cmn.CMap
 	maxMsgCountByPeer uint16
+
+	seedMode bool
+	// in seed mode, the time each peer connected at, by key
+	peerConnectedAt *cmn.CMap
+
+	detectExternalAddr bool
+	addrDetector       *externalAddrDetector
+	// the peers being dialed back, by key
+	dialingBack *cmn.CMap
 }
 
 // NewPEXReactor creates new PEX reactor.
@@ -59,6 +104,9 @@ func NewPEXReactor(b *AddrBook) *PEXReactor {
 		ensurePeersPeriod: defaultEnsurePeersPeriod,
 		msgCountByPeer:    cmn.NewCMap(),
 		maxMsgCountByPeer: defaultMaxMsgCountByPeer,
+		peerConnectedAt:   cmn.NewCMap(),
+		addrDetector:      newExternalAddrDetector(),
+		dialingBack:       cmn.NewCMap(),
 	}
 	r.BaseReactor = *NewBaseReactor("PEXReactor", r)
 	return r
@@ -73,7 +121,11 @@ func (r *PEXReactor) OnStart() error {
 	if err != nil && err != cmn.ErrAlreadyStarted {
 		return err
 	}
-	go r.ensurePeersRoutine()
+	if r.seedMode {
+		go r.crawlPeersRoutine()
+	} else {
+		go r.ensurePeersRoutine()
+	}
 	go r.flushMsgCountByPeer()
 	return nil
 }
@@ -96,30 +148,54 @@ func (r *PEXReactor) GetChannels() []*ChannelDescriptor {
 }
 
 // AddPeer implements Reactor by adding peer to the address book (if inbound)
-// or by requesting more addresses (if outbound).
+// or by requesting more addresses (if outbound). In seed mode, the inbound
+// peers are sent addresses and disconnected, and the outbound ones, crawled,
+// are always asked for addresses.
 func (r *PEXReactor) AddPeer(p Peer) {
+	r.sendObservedAddr(p)
+	if r.seedMode {
+		r.peerConnectedAt.Set(p.Key(), time.Now())
+	}
 	if p.IsOutbound() {
+		if r.seedMode {
+			if addr, err := p.NodeInfo().NetAddress(); err == nil {
+				r.book.MarkGood(addr)
+			}
+			r.RequestPEX(p)
+			return
+		}
 		// For outbound peers, the address is already in the books.
 		// Either it was added in DialSeeds or when we
 		// received the peer's address in r.Receive
+		if addr, err := p.NodeInfo().NetAddress(); err == nil {
+			r.book.MarkConnected(addr)
+		}
 		if r.book.NeedMoreAddrs() {
 			r.RequestPEX(p)
 		}
 	} else { // For inbound connections, the peer is its own source
-		addr, err := NewNetAddressString(p.NodeInfo().ListenAddr)
+		addr, err := p.NodeInfo().NetAddress()
 		if err != nil {
 			// peer gave us a bad ListenAddr. TODO: punish
 			r.Logger.Error("Error in AddPeer: invalid peer address", "addr", p.NodeInfo().ListenAddr, "err", err)
 			return
 		}
 		r.book.AddAddress(addr, addr)
+		if r.seedMode {
+			r.serveAddrs(p)
+		}
 	}
 }
 
 // RemovePeer implements Reactor.
 func (r *PEXReactor) RemovePeer(p Peer, reason interface{}) {
-	// If we aren't keeping track of local temp data for each peer here, then we
-	// don't have to do anything.
+	if p.IsOutbound() {
+		if addr, err := p.NodeInfo().NetAddress(); err == nil {
+			r.book.MarkDisconnected(addr)
+		}
+	}
+	r.peerConnectedAt.Delete(p.Key())
+	r.addrDetector.removePeer(p.ID())
 }
 
 // Receive implements Reactor by handling incoming PEX messages.
@@ -131,17 +207,19 @@ func (r *PEXReactor) Receive(chID byte, src Peer, msgBytes []byte) {
 		r.Logger.Error("Error in Receive: invalid peer address", "addr", srcAddrStr, "err", err)
 		return
 	}
+	srcAddr.ID = src.ID()
 
 	r.IncrementMsgCountForPeer(srcAddrStr)
 	if r.ReachedMaxMsgCountForPeer(srcAddrStr) {
 		r.Logger.Error("Maximum number of messages reached for peer", "peer", srcAddrStr)
-		// TODO remove src from peers?
+		r.Switch.ReportMisbehavior(src, SeverityLow, errors.New("Maximum number of PEX messages reached"))
 		return
 	}
 
 	_, msg, err := DecodeMessage(msgBytes)
 	if err != nil {
 		r.Logger.Error("Error decoding message", "err", err)
+		r.Switch.ReportMisbehavior(src, SeverityMedium, err)
 		return
 	}
 	r.Logger.Info("Received message", "msg", msg)
@@ -154,16 +232,46 @@ func (r *PEXReactor) Receive(chID byte, src Peer, msgBytes []byte) {
 	case *pexAddrsMessage:
 		// We received some peer addresses from src.
 		// TODO: (We don't want to get spammed with bad peers)
-		for _, addr := range msg.Addrs {
-			if addr != nil {
-				r.book.AddAddress(addr, srcAddr)
-			}
+		r.addAddrs(src, srcAddr, msg.Addrs)
+	case *pexAddrsV1Message:
+		// the addresses of an older peer, without their IDs, can't be dialed
+		if hasPexV2(src) {
+			r.Switch.ReportMisbehavior(src, SeverityLow, errors.New("PEX addresses without ID from a peer of PEX version 2"))
+			return
+		}
+		r.Logger.Debug("Ignoring the addresses without ID of an older peer", "peer", src, "num", len(msg.Addrs))
+		r.addAddrs(src, srcAddr, nil)
+	case *pexObservedAddrMessage:
+		// src sees us at the IP of the address
+		if r.detectExternalAddr && src.IsOutbound() && msg.Addr != nil {
+			r.observeExternalAddr(src, msg.Addr.IP)
+		}
+	case *pexDialBackRequestMessage:
+		// src probes its external address
+		r.dialBack(src, msg.Port)
+	case *pexDialBackResponseMessage:
+		// src dialed us back at the address
+		if r.detectExternalAddr && msg.Reachable && msg.Addr != nil && r.addrDetector.isProbing(src.ID(), msg.Addr.IP) {
+			r.setExternalAddr(msg.Addr)
 		}
 	default:
 		r.Logger.Error(fmt.Sprintf("Unknown message type %v", reflect.TypeOf(msg)))
 	}
 }
 
+// addAddrs adds the addresses received from src to the book.
+func (r *PEXReactor) addAddrs(src Peer, srcAddr *NetAddress, addrs []*NetAddress) {
+	for _, addr := range addrs {
+		if addr != nil {
+			r.book.AddAddress(addr, srcAddr)
+		}
+	}
+	if r.seedMode && src.IsOutbound() {
+		// done crawling src
+		r.Switch.StopPeerGracefully(src)
+	}
+}
+
 // RequestPEX asks peer for more addresses.
 func (r *PEXReactor) RequestPEX(p Peer) {
 	p.Send(PexChannel, struct{ PexMessage }{&pexRequestMessage{}})
@@ -171,7 +279,30 @@ func (r *PEXReactor) RequestPEX(p Peer) {
 
 // SendAddrs sends addrs to the peer.
 func (r *PEXReactor) SendAddrs(p Peer, addrs []*NetAddress) {
-	p.Send(PexChannel, struct{ PexMessage }{&pexAddrsMessage{Addrs: addrs}})
+	p.Send(PexChannel, struct{ PexMessage }{newPexAddrsMessage(p, addrs)})
+}
+
+// hasPexV2 returns true if the peer knows the messages of PexVersion 2.
+func hasPexV2(p Peer) bool {
+	nodeInfo := p.NodeInfo()
+	if nodeInfo == nil {
+		return false
+	}
+	version, err := strconv.Atoi(nodeInfo.otherValue(PexVersionKey))
+	return err == nil && version >= 2
+}
+
+// newPexAddrsMessage returns the message with the addresses for the peer,
+// without their IDs if it's older than PexVersion 2.
+func newPexAddrsMessage(p Peer, addrs []*NetAddress) PexMessage {
+	if hasPexV2(p) {
+		return &pexAddrsMessage{Addrs: addrs}
+	}
+	addrsV1 := make([]*netAddressV1, len(addrs))
+	for i, addr := range addrs {
+		addrsV1[i] = &netAddressV1{IP: addr.IP, Port: addr.Port}
+	}
+	return &pexAddrsV1Message{Addrs: addrsV1}
 }
 
 // SetEnsurePeersPeriod sets period to ensure peers connected.
@@ -179,6 +310,20 @@ func (r *PEXReactor) SetEnsurePeersPeriod(d time.Duration) {
 	r.ensurePeersPeriod = d
 }
 
+// SetSeedMode sets the reactor in seed mode, to crawl the network and
+// serve addresses rather than keep peers connected.
+// NOTE: not thread safe - should only be called once, before starting
+func (r *PEXReactor) SetSeedMode(seedMode bool) {
+	r.seedMode = seedMode
+}
+
+// SetDetectExternalAddr sets whether to detect our external address from
+// the IPs the outbound peers see us at.
+// NOTE: not thread safe - should only be called once, before starting
+func (r *PEXReactor) SetDetectExternalAddr(detect bool) {
+	r.detectExternalAddr = detect
+}
+
 // SetMaxMsgCountByPeer sets maximum messages one peer can send to us during 'msgCountByPeerFlushInterval'.
 func (r *PEXReactor) SetMaxMsgCountByPeer(v uint16) {
 	r.maxMsgCountByPeer = v
@@ -239,7 +384,7 @@ func (r *PEXReactor) ensurePeersRoutine() {
 // upon a single successful connection.
 func (r *PEXReactor) ensurePeers() {
 	numOutPeers, _, numDialing := r.Switch.NumPeers()
-	numToDial := minNumOutboundPeers - (numOutPeers + numDialing)
+	numToDial := r.Switch.config.MaxNumOutboundPeers - (numOutPeers + numDialing)
 	r.Logger.Info("Ensure peers", "numOutPeers", numOutPeers, "numDialing", numDialing, "numToDial", numToDial)
 	if numToDial <= 0 {
 		return
@@ -258,6 +403,10 @@ func (r *PEXReactor) ensurePeers() {
 		if try == nil {
 			continue
 		}
+		if try.ID == "" {
+			// from an address book older than the IDs
+			continue
+		}
 		if _, selected := toDial[try.IP.String()]; selected {
 			continue
 		}
@@ -271,12 +420,12 @@ func (r *PEXReactor) ensurePeers() {
 		toDial[try.IP.String()] = try
 	}
 
-	// Dial picked addresses
+	// Dial picked addresses, backing off the ones failing, see AddrBook.PickAddress
 	for _, item := range toDial {
+		r.book.MarkAttempt(item)
 		go func(picked *NetAddress) {
-			_, err := r.Switch.DialPeerWithAddress(picked, false)
-			if err != nil {
-				r.book.MarkAttempt(picked)
+			if _, err := r.Switch.DialPeerWithAddress(picked, false); err == nil {
+				r.book.MarkGood(picked)
 			}
 		}(item)
 	}
@@ -292,6 +441,141 @@ func (r *PEXReactor) ensurePeers() {
 	}
 }
 
+// serveAddrs sends a selection of the address book to the peer, and
+// disconnects it once they are flushed, in seed mode.
+func (r *PEXReactor) serveAddrs(p Peer) {
+	r.SendAddrs(p, r.book.GetSelection())
+	go func() {
+		select {
+		case <-time.After(seedDisconnectDelay):
+			if r.Switch.Peers().Has(p.Key()) {
+				r.Switch.StopPeerGracefully(p)
+			}
+		case <-r.Quit:
+		}
+	}()
+}
+
+// Crawls the peers of the address book, in seed mode. (continuous)
+func (r *PEXReactor) crawlPeersRoutine() {
+	// fire once immediately.
+	r.crawlPeers()
+
+	// fire periodically
+	ticker := time.NewTicker(r.ensurePeersPeriod)
+
+	for {
+		select {
+		case <-ticker.C:
+			r.disconnectPeers()
+			r.crawlPeers()
+		case <-r.Quit:
+			ticker.Stop()
+			return
+		}
+	}
+}
+
+// crawlPeers dials the addresses not crawled recently, once. The peers are
+// asked for their addresses on connection, see AddPeer, and disconnected
+// once they sent them.
+func (r *PEXReactor) crawlPeers() {
+	addrs := r.book.crawlAddrs(maxCrawlPeers, crawlPeerInterval)
+	r.Logger.Info("Crawl peers", "numToDial", len(addrs), "bookSize", r.book.Size())
+	for _, addr := range addrs {
+		if r.Switch.IsDialing(addr) || r.Switch.Peers().Has(addr.IP.String()) {
+			continue
+		}
+		r.book.MarkAttempt(addr)
+		go func(addr *NetAddress) {
+			_, err := r.Switch.DialPeerWithAddress(addr, false)
+			if err != nil {
+				r.Logger.Debug("Error crawling peer", "addr", addr, "err", err)
+			}
+		}(addr)
+	}
+}
+
+// disconnectPeers disconnects the peers connected for longer than
+// seedDisconnectWaitPeriod, eg. the crawled ones which never sent their
+// addresses, in seed mode.
+func (r *PEXReactor) disconnectPeers() {
+	for _, p := range r.Switch.Peers().List() {
+		connectedAt, ok := r.peerConnectedAt.Get(p.Key()).(time.Time)
+		if ok && time.Since(connectedAt) > seedDisconnectWaitPeriod {
+			r.Logger.Info("Disconnecting peer", "peer", p)
+			r.Switch.StopPeerGracefully(p)
+		}
+	}
+}
+
+// sendObservedAddr tells the peer the IP we see it connect from, with its
+// listen port.
+func (r *PEXReactor) sendObservedAddr(p Peer) {
+	if !hasPexV2(p) {
+		return
+	}
+	ip := net.ParseIP(addrIP(p.NodeInfo().RemoteAddr))
+	_, portStr, err := net.SplitHostPort(p.NodeInfo().ListenAddr)
+	if ip == nil || err != nil {
+		return
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return
+	}
+	addr := NewNetAddressIPPort(ip, uint16(port))
+	p.TrySend(PexChannel, struct{ PexMessage }{&pexObservedAddrMessage{Addr: addr}})
+}
+
+// observeExternalAddr records the IP the outbound peer sees us at, and asks
+// it to dial us back there, at our listen port, once enough peers agree on
+// it, unless it's already the one of our address.
+func (r *PEXReactor) observeExternalAddr(src Peer, ip net.IP) {
+	ourAddr, err := r.Switch.NodeInfo().NetAddress()
+	if err != nil || ourAddr.IP.Equal(ip) || !NewNetAddressIPPort(ip, ourAddr.Port).Routable() {
+		return
+	}
+	if !r.addrDetector.observe(src.ID(), ip) {
+		return
+	}
+	r.Logger.Info("Probing external address", "ip", ip, "port", ourAddr.Port, "peer", src)
+	src.TrySend(PexChannel, struct{ PexMessage }{&pexDialBackRequestMessage{Port: ourAddr.Port}})
+}
+
+// dialBack dials the peer at the port of the IP it connects from, and tells
+// it whether it connected. Only one dial back per peer runs at a time.
+func (r *PEXReactor) dialBack(src Peer, port uint16) {
+	ip := net.ParseIP(addrIP(src.NodeInfo().RemoteAddr))
+	if ip == nil || r.dialingBack.Has(src.Key()) {
+		return
+	}
+	r.dialingBack.Set(src.Key(), struct{}{})
+	go func() {
+		defer r.dialingBack.Delete(src.Key())
+		addr := NewNetAddressIPPort(ip, port)
+		conn, err := r.Switch.Transport().Dial(addr, dialBackTimeout)
+		if err == nil {
+			conn.Close() // nolint: errcheck
+		}
+		src.TrySend(PexChannel, struct{ PexMessage }{&pexDialBackResponseMessage{Addr: addr, Reachable: err == nil}})
+	}()
+}
+
+// setExternalAddr advertises the address a peer dialed us back at as ours:
+// to the peers connecting next, in the handshake, and to the connected ones,
+// so it spreads through the address books.
+func (r *PEXReactor) setExternalAddr(addr *NetAddress) {
+	ourAddr := NewNetAddressIPPort(addr.IP, addr.Port)
+	ourAddr.ID = r.Switch.NodeInfo().ID()
+	r.Logger.Info("Detected external address", "addr", ourAddr)
+	r.Switch.SetExternalAddress(ourAddr)
+	r.book.AddOurAddress(ourAddr)
+	for _, p := range r.Switch.Peers().List() {
+		p.TrySend(PexChannel, struct{ PexMessage }{newPexAddrsMessage(p, []*NetAddress{ourAddr})})
+	}
+}
+
 func (r *PEXReactor) flushMsgCountByPeer() {
 	ticker := time.NewTicker(msgCountByPeerFlushInterval)
 
@@ -309,19 +593,29 @@ func (r *PEXReactor) flushMsgCountByPeer() {
 //-----------------------------------------------------------------------------
 // Messages
 
+// NOTE: the messages after msgTypeAddrsV1 are only sent to the peers of PexVersion 2
 const (
-	msgTypeRequest = byte(0x01)
-	msgTypeAddrs   = byte(0x02)
+	msgTypeRequest         = byte(0x01)
+	msgTypeAddrsV1         = byte(0x02)
+	msgTypeObservedAddr    = byte(0x03)
+	msgTypeDialBackRequest = byte(0x04)
+	msgTypeDialBack        = byte(0x05)
+	msgTypeAddrs           = byte(0x06)
 )
 
 // PexMessage is a primary type for PEX messages. Underneath, it could contain
-// either pexRequestMessage, or pexAddrsMessage messages.
+// either pexRequestMessage, pexAddrsMessage, pexAddrsV1Message, pexObservedAddrMessage,
+// pexDialBackRequestMessage or pexDialBackResponseMessage messages.
 type PexMessage interface{}
 
 var _ = wire.RegisterInterface(
 	struct{ PexMessage }{},
 	wire.ConcreteType{&pexRequestMessage{}, msgTypeRequest},
+	wire.ConcreteType{&pexAddrsV1Message{}, msgTypeAddrsV1},
 	wire.ConcreteType{&pexAddrsMessage{}, msgTypeAddrs},
+	wire.ConcreteType{&pexObservedAddrMessage{}, msgTypeObservedAddr},
+	wire.ConcreteType{&pexDialBackRequestMessage{}, msgTypeDialBackRequest},
+	wire.ConcreteType{&pexDialBackResponseMessage{}, msgTypeDialBack},
 )
 
 // DecodeMessage implements interface registered above.
@@ -353,3 +647,57 @@ type pexAddrsMessage struct {
 func (m *pexAddrsMessage) String() string {
 	return fmt.Sprintf("[pexAddrs %v]", m.Addrs)
 }
+
+/*
+A message with announced peer addresses, without their IDs, as sent by and to
+the peers older than PexVersion 2.
+*/
+type pexAddrsV1Message struct {
+	Addrs []*netAddressV1
+}
+
+func (m *pexAddrsV1Message) String() string {
+	return fmt.Sprintf("[pexAddrsV1 %v]", m.Addrs)
+}
+
+// netAddressV1 is the wire encoding of a NetAddress before PexVersion 2.
+type netAddressV1 struct {
+	IP   net.IP
+	Port uint16
+}
+
+/*
+A message with the address a peer sees the node at: the IP it connects
+from, and its listen port.
+*/
+type pexObservedAddrMessage struct {
+	Addr *NetAddress
+}
+
+func (m *pexObservedAddrMessage) String() string {
+	return fmt.Sprintf("[pexObservedAddr %v]", m.Addr)
+}
+
+/*
+A pexDialBackRequestMessage asks the peer to dial the node back at the port
+of the IP it connects from.
+*/
+type pexDialBackRequestMessage struct {
+	Port uint16
+}
+
+func (m *pexDialBackRequestMessage) String() string {
+	return fmt.Sprintf("[pexDialBackRequest %v]", m.Port)
+}
+
+/*
+A message with whether the peer could dial the node back at the address.
+*/
+type pexDialBackResponseMessage struct {
+	Addr      *NetAddress
+	Reachable bool
+}
+
+func (m *pexDialBackResponseMessage) String() string {
+	return fmt.Sprintf("[pexDialBackResponse %v %v]", m.Addr, m.Reachable)
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	crypto "github.com/tendermint/go-crypto"
 	wire "github.com/tendermint/go-wire"
 	cmn "github.com/tendermint/tmlibs/common"
 	"github.com/tendermint/tmlibs/log"
@@ -86,7 +87,7 @@ func TestPEXReactorRunning(t *testing.T) {
 
 	// fill the address book and add listeners
 	for _, s := range switches {
-		addr, _ := NewNetAddressString(s.NodeInfo().ListenAddr)
+		addr, _ := s.NodeInfo().NetAddress()
 		book.AddAddress(addr, addr)
 		s.AddListener(NewDefaultListener("tcp", s.NodeInfo().ListenAddr, true, log.TestingLogger()))
 	}
@@ -143,20 +144,91 @@ func TestPEXReactorReceive(t *testing.T) {
 
 	r := NewPEXReactor(book)
 	r.SetLogger(log.TestingLogger())
+	makeSwitch(config, 0, "testing", "123.123.123", func(i int, sw *Switch) *Switch {
+		sw.SetLogger(log.TestingLogger())
+		sw.AddReactor("pex", r)
+		return sw
+	})
 
 	peer := createRandomPeer(false)
 
 	size := book.Size()
-	netAddr, _ := NewNetAddressString(peer.NodeInfo().ListenAddr)
+	netAddr, _ := peer.NodeInfo().NetAddress()
 	addrs := []*NetAddress{netAddr}
 	msg := wire.BinaryBytes(struct{ PexMessage }{&pexAddrsMessage{Addrs: addrs}})
 	r.Receive(PexChannel, peer, msg)
 	assert.Equal(size+1, book.Size())
 
+	// the addresses of the older peers, without ID, can't be dialed
+	_, noIDAddr := createRoutableAddr()
+	msg = wire.BinaryBytes(struct{ PexMessage }{&pexAddrsV1Message{Addrs: []*netAddressV1{{IP: noIDAddr.IP, Port: noIDAddr.Port}}}})
+	r.Receive(PexChannel, peer, msg)
+	assert.Equal(size+1, book.Size())
+
+	// nor are they sent by the newer ones
+	peer.nodeInfo.Other = []string{PexVersionKey + "=" + PexVersion}
+	r.Receive(PexChannel, peer, msg)
+	assert.Equal(size+1, book.Size())
+
 	msg = wire.BinaryBytes(struct{ PexMessage }{&pexRequestMessage{}})
 	r.Receive(PexChannel, peer, msg)
 }
 
+func TestPEXReactorAddrsVersions(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	peer := createRandomPeer(false)
+	netAddr, _ := peer.NodeInfo().NetAddress()
+	addrs := []*NetAddress{netAddr}
+
+	// the older peers get the addresses without their IDs, as they encoded them
+	old := createRandomPeer(false)
+	msg, ok := newPexAddrsMessage(old, addrs).(*pexAddrsV1Message)
+	require.True(ok)
+	require.Len(msg.Addrs, 1)
+	assert.Equal(netAddr.DialString(), NewNetAddressIPPort(msg.Addrs[0].IP, msg.Addrs[0].Port).DialString())
+	assert.Equal(msgTypeAddrsV1, wire.BinaryBytes(struct{ PexMessage }{msg})[0])
+
+	peer.nodeInfo.Other = []string{PexVersionKey + "=" + PexVersion}
+	assert.Equal(&pexAddrsMessage{Addrs: addrs}, newPexAddrsMessage(peer, addrs))
+}
+
+func TestPEXReactorDialBack(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	dir, err := ioutil.TempDir("", "pex_reactor")
+	require.Nil(err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+	book := NewAddrBook(dir+"addrbook.json", false)
+	book.SetLogger(log.TestingLogger())
+
+	// a switch of a pex reactor, and a listener on the loopback
+	sw := makeSwitch(config, 0, "testing", "123.123.123", func(i int, sw *Switch) *Switch {
+		r := NewPEXReactor(book)
+		r.SetLogger(log.TestingLogger())
+		sw.AddReactor("pex", r)
+		return sw
+	})
+	sw.SetLogger(log.TestingLogger())
+	r := sw.Reactor("pex").(*PEXReactor)
+	l := NewDefaultListener("tcp", "127.0.0.1:0", true, log.TestingLogger())
+	defer l.Stop() // nolint: errcheck
+
+	// a peer connecting from the loopback, probing its address
+	peer := createRandomPeer(false)
+	peer.nodeInfo.RemoteAddr = "127.0.0.1:12345"
+	msg := wire.BinaryBytes(struct{ PexMessage }{&pexDialBackRequestMessage{Port: l.ExternalAddress().Port}})
+	r.Receive(PexChannel, peer, msg)
+
+	select {
+	case conn := <-l.Connections():
+		assert.Equal("127.0.0.1", addrIP(conn.RemoteAddr().String()))
+		conn.Close() // nolint: errcheck
+	case <-time.After(dialBackTimeout):
+		t.Fatal("The peer was not dialed back")
+	}
+}
+
 func TestPEXReactorAbuseFromPeer(t *testing.T) {
 	assert, require := assert.New(t), require.New(t)
 
@@ -169,6 +241,14 @@ func TestPEXReactorAbuseFromPeer(t *testing.T) {
 	r := NewPEXReactor(book)
 	r.SetLogger(log.TestingLogger())
 	r.SetMaxMsgCountByPeer(5)
+	banList, err := NewBanList("")
+	require.Nil(err)
+	makeSwitch(config, 0, "testing", "123.123.123", func(i int, sw *Switch) *Switch {
+		sw.SetLogger(log.TestingLogger())
+		sw.SetBanList(banList)
+		sw.AddReactor("pex", r)
+		return sw
+	})
 
 	peer := createRandomPeer(false)
 
@@ -178,6 +258,76 @@ func TestPEXReactorAbuseFromPeer(t *testing.T) {
 	}
 
 	assert.True(r.ReachedMaxMsgCountForPeer(peer.NodeInfo().ListenAddr))
+	assert.Nil(banList.IsBanned(string(peer.ID())), "a few messages too many don't get the peer banned")
+
+	// the spam goes on
+	for i := 0; i < 10; i++ {
+		r.Receive(PexChannel, peer, msg)
+	}
+	assert.NotNil(banList.IsBanned(string(peer.ID())))
+}
+
+func TestPEXReactorSeedMode(t *testing.T) {
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "pex_reactor")
+	require.Nil(err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	// a seed, a peer it crawls, and one connecting to it
+	books := make([]*AddrBook, 3)
+	switches := make([]*Switch, 3)
+	for i := range switches {
+		books[i] = NewAddrBook(fmt.Sprintf("%s/addrbook%d.json", dir, i), false)
+		books[i].SetLogger(log.TestingLogger())
+		switches[i] = makeSwitch(config, i, "127.0.0.1", "123.123.123", func(i int, sw *Switch) *Switch {
+			sw.SetLogger(log.TestingLogger().With("switch", i))
+
+			r := NewPEXReactor(books[i])
+			r.SetLogger(log.TestingLogger())
+			r.SetEnsurePeersPeriod(250 * time.Millisecond)
+			r.SetSeedMode(i == 0)
+			sw.AddReactor("pex", r)
+			return sw
+		})
+	}
+	addrs := make([]*NetAddress, 3)
+	for i, s := range switches {
+		addrs[i], _ = s.NodeInfo().NetAddress()
+		s.AddListener(NewDefaultListener("tcp", s.NodeInfo().ListenAddr, true, log.TestingLogger()))
+	}
+	books[0].AddAddress(addrs[1], addrs[1])
+	books[2].AddAddress(addrs[0], addrs[0])
+
+	for _, s := range switches {
+		require.Nil(s.Start())
+		defer s.Stop()
+	}
+
+	// the seed crawled the first peer, handed its address out to the second,
+	// and learnt the address of the second
+	knownAddr := func(book *AddrBook, addr *NetAddress) *knownAddress {
+		book.mtx.Lock()
+		defer book.mtx.Unlock()
+		if ka, ok := book.addrLookup[addr.String()]; ok {
+			kaCopy := *ka
+			return &kaCopy
+		}
+		return nil
+	}
+	timeout := time.After(10 * time.Second)
+	for {
+		crawled := knownAddr(books[0], addrs[1])
+		if crawled != nil && !crawled.LastSuccess.IsZero() &&
+			knownAddr(books[2], addrs[1]) != nil && knownAddr(books[0], addrs[2]) != nil {
+			return
+		}
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-timeout:
+			t.Fatal("expected the seed to crawl the peers and hand their addresses out")
+		}
+	}
 }
 
 func createRoutableAddr() (addr string, netAddr *NetAddress) {
@@ -193,9 +343,12 @@ func createRoutableAddr() (addr string, netAddr *NetAddress) {
 
 func createRandomPeer(outbound bool) *peer {
 	addr, netAddr := createRoutableAddr()
+	pubKey := crypto.GenPrivKeyEd25519().PubKey().Unwrap().(crypto.PubKeyEd25519)
 	p := &peer{
 		key: cmn.RandStr(12),
+		id:  PubKeyToID(pubKey.Wrap()),
 		nodeInfo: &NodeInfo{
+			PubKey:     pubKey,
 			ListenAddr: addr,
 			RemoteAddr: netAddr.String(),
 		},
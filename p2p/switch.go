@@ -5,6 +5,7 @@ import (
 	"math"
 	"math/rand"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -81,8 +82,12 @@ type Switch struct {
 	reactorsByCh map[byte]Reactor
 	peers        *PeerSet
 	dialing      *cmn.CMap
+	persistent   *cmn.CMap             // the *NetAddress of the persistent peers, by ID
+	nodeInfoMtx  sync.RWMutex          // guards nodeInfo
 	nodeInfo     *NodeInfo             // our node info
 	nodePrivKey  crypto.PrivKeyEd25519 // our node privkey
+	metrics      *Metrics              // optional, records per-channel traffic
+	banList      *BanList              // optional, bans the misbehaving peers
 
 	filterConnByAddr   func(net.Addr) error
 	filterConnByPubKey func(crypto.PubKeyEd25519) error
@@ -91,9 +96,33 @@ type Switch struct {
 }
 
 var (
-	ErrSwitchDuplicatePeer = errors.New("Duplicate peer")
+	ErrSwitchDuplicatePeer    = errors.New("Duplicate peer")
+	ErrSwitchMaxInboundPeers  = errors.New("Maximum number of inbound peers reached")
+	ErrSwitchMaxOutboundPeers = errors.New("Maximum number of outbound peers reached")
+	ErrSwitchMaxPeersPerIP    = errors.New("Maximum number of peers per IP reached")
+	ErrSwitchNoBanList        = errors.New("No ban list")
 )
 
+// ErrSwitchBannedPeer is returned when connecting to a banned peer.
+type ErrSwitchBannedPeer struct {
+	Ban Ban
+}
+
+func (e ErrSwitchBannedPeer) Error() string {
+	return fmt.Sprintf("Peer %v is banned until %v: %v", e.Ban.Target, e.Ban.Until, e.Ban.Reason)
+}
+
+// ErrSwitchAuthenticationFailure is returned when the peer dialed at an
+// address doesn't prove it holds the key of the ID of the address.
+type ErrSwitchAuthenticationFailure struct {
+	Dialed *NetAddress
+	Got    ID
+}
+
+func (e ErrSwitchAuthenticationFailure) Error() string {
+	return fmt.Sprintf("Failed to authenticate peer. Dialed %v, but got peer with ID %s", e.Dialed, e.Got)
+}
+
 func NewSwitch(config *cfg.P2PConfig) *Switch {
 	sw := &Switch{
 		config:       config,
@@ -103,6 +132,7 @@ func NewSwitch(config *cfg.P2PConfig) *Switch {
 		reactorsByCh: make(map[byte]Reactor),
 		peers:        NewPeerSet(),
 		dialing:      cmn.NewCMap(),
+		persistent:   cmn.NewCMap(),
 		nodeInfo:     nil,
 	}
 
@@ -115,12 +145,16 @@ func NewSwitch(config *cfg.P2PConfig) *Switch {
 	sw.peerConfig.MConfig.SendRate = config.SendRate
 	sw.peerConfig.MConfig.RecvRate = config.RecvRate
 	sw.peerConfig.MConfig.maxMsgPacketPayloadSize = config.MaxMsgPacketPayloadSize
+	sw.peerConfig.CorpusDir = config.FuzzCorpusDirPath()
+	sw.peerConfig.CorpusSampleRate = config.FuzzCorpusSampleRate
 
 	sw.BaseService = *cmn.NewBaseService(nil, "P2P Switch", sw)
 	return sw
 }
 
 // AddReactor adds the given reactor to the switch.
+// The channels of the reactor get the per-channel rates and priority of the
+// config for its name, if any.
 // NOTE: Not goroutine safe.
 func (sw *Switch) AddReactor(name string, reactor Reactor) Reactor {
 	// Validate the reactor.
@@ -131,6 +165,7 @@ func (sw *Switch) AddReactor(name string, reactor Reactor) Reactor {
 		if sw.reactorsByCh[chID] != nil {
 			cmn.PanicSanity(fmt.Sprintf("Channel %X has multiple reactors %v & %v", chID, sw.reactorsByCh[chID], reactor))
 		}
+		chDesc = sw.configureChannel(name, chDesc)
 		sw.chDescs = append(sw.chDescs, chDesc)
 		sw.reactorsByCh[chID] = reactor
 	}
@@ -139,6 +174,43 @@ func (sw *Switch) AddReactor(name string, reactor Reactor) Reactor {
 	return reactor
 }
 
+// configureChannel returns a copy of the channel of the reactor with the
+// rates, priority and fuzzing of the config.
+func (sw *Switch) configureChannel(name string, chDesc *ChannelDescriptor) *ChannelDescriptor {
+	desc := *chDesc
+	if rate := sw.config.ChannelSendRate(name); rate > 0 {
+		desc.SendRate = rate
+	}
+	if rate := sw.config.ChannelRecvRate(name); rate > 0 {
+		desc.RecvRate = rate
+	}
+	if priority := sw.config.ChannelPriority(name); priority > 0 {
+		desc.Priority = priority
+	}
+	if drop, corrupt, delay := sw.config.ChannelFuzz(name); drop+corrupt+delay > 0 {
+		desc.Fuzz = &FuzzMsgConfig{
+			ProbDrop:    float64(drop) / 100,
+			ProbCorrupt: float64(corrupt) / 100,
+			ProbDelay:   float64(delay) / 100,
+			MaxDelay:    sw.config.FuzzMaxDelayDuration(),
+		}
+	}
+	return &desc
+}
+
+// SetTransport sets the transport to dial the peers with, TCP by default.
+// It must be the one of the listeners.
+// NOTE: Not goroutine safe.
+func (sw *Switch) SetTransport(transport Transport) {
+	sw.peerConfig.Transport = transport
+}
+
+// Transport returns the transport the switch dials the peers with.
+// NOTE: Not goroutine safe.
+func (sw *Switch) Transport() Transport {
+	return sw.peerConfig.Transport
+}
+
 // Reactors returns a map of reactors registered on the switch.
 // NOTE: Not goroutine safe.
 func (sw *Switch) Reactors() map[string]Reactor {
@@ -172,15 +244,29 @@ func (sw *Switch) IsListening() bool {
 // SetNodeInfo sets the switch's NodeInfo for checking compatibility and handshaking with other nodes.
 // NOTE: Not goroutine safe.
 func (sw *Switch) SetNodeInfo(nodeInfo *NodeInfo) {
+	sw.nodeInfoMtx.Lock()
+	defer sw.nodeInfoMtx.Unlock()
 	sw.nodeInfo = nodeInfo
 }
 
 // NodeInfo returns the switch's NodeInfo.
-// NOTE: Not goroutine safe.
 func (sw *Switch) NodeInfo() *NodeInfo {
+	sw.nodeInfoMtx.RLock()
+	defer sw.nodeInfoMtx.RUnlock()
 	return sw.nodeInfo
 }
 
+// SetExternalAddress sets the address our node is dialable at, eg. once
+// detected by the PEX reactor, as the ListenAddr of our NodeInfo, which the
+// peers get in the handshake from then on.
+func (sw *Switch) SetExternalAddress(addr *NetAddress) {
+	sw.nodeInfoMtx.Lock()
+	defer sw.nodeInfoMtx.Unlock()
+	nodeInfo := *sw.nodeInfo // copy, the peers handshaking may read it
+	nodeInfo.ListenAddr = addr.DialString()
+	sw.nodeInfo = &nodeInfo
+}
+
 // SetNodePrivKey sets the switch's private key for authenticated encryption.
 // NOTE: Overwrites sw.nodeInfo.PubKey.
 // NOTE: Not goroutine safe.
@@ -191,6 +277,18 @@ func (sw *Switch) SetNodePrivKey(nodePrivKey crypto.PrivKeyEd25519) {
 	}
 }
 
+// SetMetrics sets the metrics recording the per-channel traffic of the peers.
+// NOTE: Not goroutine safe.
+func (sw *Switch) SetMetrics(metrics *Metrics) {
+	sw.metrics = metrics
+}
+
+// SetBanList sets the ban list of the misbehaving peers, see ReportMisbehavior.
+// NOTE: Not goroutine safe.
+func (sw *Switch) SetBanList(banList *BanList) {
+	sw.banList = banList
+}
+
 // OnStart implements BaseService. It starts all the reactors, peers, and listeners.
 func (sw *Switch) OnStart() error {
 	// Start reactors
@@ -218,6 +316,7 @@ func (sw *Switch) OnStop() {
 	for _, peer := range sw.peers.List() {
 		peer.Stop()
 		sw.peers.Remove(peer)
+		sw.removePeerMetrics(peer)
 	}
 	// Stop reactors
 	for _, reactor := range sw.reactors {
@@ -239,17 +338,22 @@ func (sw *Switch) addPeer(peer *peer) error {
 		return err
 	}
 
-	if err := peer.HandshakeTimeout(sw.nodeInfo, time.Duration(sw.peerConfig.HandshakeTimeout*time.Second)); err != nil {
+	if err := sw.checkBanned(peer.ID(), addrIP(peer.Addr().String())); err != nil {
+		return err
+	}
+
+	nodeInfo := sw.NodeInfo()
+	if err := peer.HandshakeTimeout(nodeInfo, time.Duration(sw.peerConfig.HandshakeTimeout*time.Second)); err != nil {
 		return err
 	}
 
 	// Avoid self
-	if sw.nodeInfo.PubKey.Equals(peer.PubKey().Wrap()) {
+	if nodeInfo.PubKey.Equals(peer.PubKey().Wrap()) {
 		return errors.New("Ignoring connection from self")
 	}
 
 	// Check version, chain id
-	if err := sw.nodeInfo.CompatibleWith(peer.NodeInfo()); err != nil {
+	if err := nodeInfo.CompatibleWith(peer.NodeInfo()); err != nil {
 		return err
 	}
 
@@ -259,6 +363,19 @@ func (sw *Switch) addPeer(peer *peer) error {
 
 	}
 
+	if err := sw.checkPeerLimits(peer); err != nil {
+		return err
+	}
+
+	// the channels record their traffic from the start, but the peer is only
+	// counted once added
+	metricsKey := ""
+	if sw.metrics != nil {
+		var chMetrics map[byte]*channelMetrics
+		metricsKey, chMetrics = sw.metrics.peerChannelMetrics(peer.Key(), sw.channelIDs())
+		peer.mconn.setChannelMetrics(chMetrics)
+	}
+
 	// Start peer
 	if sw.IsRunning() {
 		sw.startInitPeer(peer)
@@ -270,11 +387,87 @@ func (sw *Switch) addPeer(peer *peer) error {
 	if err := sw.peers.Add(peer); err != nil {
 		return err
 	}
+	if sw.metrics != nil {
+		sw.metrics.addPeer(peer.Key(), metricsKey)
+	}
 
 	sw.Logger.Info("Added peer", "peer", peer)
 	return nil
 }
 
+// checkPeerLimits returns an error if adding the peer would exceed the
+// maximum number of inbound or outbound peers, or of peers per IP. The
+// persistent peers are always accepted.
+func (sw *Switch) checkPeerLimits(peer *peer) error {
+	if peer.IsPersistent() || sw.persistent.Has(string(peer.ID())) {
+		return nil
+	}
+	outbound, inbound, _ := sw.NumPeers()
+	if peer.IsOutbound() && outbound >= sw.config.MaxNumOutboundPeers {
+		return ErrSwitchMaxOutboundPeers
+	}
+	if !peer.IsOutbound() && inbound >= sw.config.MaxNumInboundPeers {
+		return ErrSwitchMaxInboundPeers
+	}
+	if ip := addrIP(peer.Addr().String()); ip != "" && sw.maxPeersWithIP(ip) {
+		return ErrSwitchMaxPeersPerIP
+	}
+	return nil
+}
+
+// maxPeersWithIP returns whether the maximum number of peers with the IP is
+// reached.
+func (sw *Switch) maxPeersWithIP(ip string) bool {
+	if sw.config.MaxNumPeersPerIP <= 0 {
+		return false
+	}
+	n := 0
+	for _, peer := range sw.peers.List() {
+		if addrIP(peer.NodeInfo().RemoteAddr) == ip {
+			n++
+		}
+	}
+	return n >= sw.config.MaxNumPeersPerIP
+}
+
+// isPersistentIP returns whether a persistent peer has the IP, so its inbound
+// connections are handshaked whatever the limits, to check its ID.
+func (sw *Switch) isPersistentIP(ip string) bool {
+	for _, addr := range sw.persistent.Values() {
+		if addr.(*NetAddress).IP.String() == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// addrIP returns the IP of the "IP:Port" address, empty if it has none, eg.
+// for a pipe.
+func addrIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
+// checkBanned returns an ErrSwitchBannedPeer if the ID or the IP, if any, of
+// the peer is banned.
+func (sw *Switch) checkBanned(id ID, ip string) error {
+	if sw.banList == nil {
+		return nil
+	}
+	for _, target := range []string{string(id), ip} {
+		if target == "" {
+			continue
+		}
+		if ban := sw.banList.IsBanned(target); ban != nil {
+			return ErrSwitchBannedPeer{*ban}
+		}
+	}
+	return nil
+}
+
 // FilterConnByAddr returns an error if connecting to the given address is forbidden.
 func (sw *Switch) FilterConnByAddr(addr net.Addr) error {
 	if sw.filterConnByAddr != nil {
@@ -314,20 +507,38 @@ func (sw *Switch) startInitPeer(peer *peer) {
 	}
 }
 
-// DialSeeds dials a list of seeds asynchronously in random order.
+// DialSeeds dials a list of seeds, as "id@host:port", asynchronously in random order.
+// The seeds are persistent peers: they are reconnected to if the connection drops.
 func (sw *Switch) DialSeeds(addrBook *AddrBook, seeds []string) error {
+	return sw.dialSeeds(addrBook, seeds, true)
+}
+
+// dialSeeds adds the seeds to the addrBook, if any, and dials them asynchronously
+// in random order, as persistent peers or not.
+func (sw *Switch) dialSeeds(addrBook *AddrBook, seeds []string, persistent bool) error {
 	netAddrs, errs := NewNetAddressStrings(seeds)
 	for _, err := range errs {
 		sw.Logger.Error("Error in seed's address", "err", err)
 	}
+	// the seeds are only dialed by their ID
+	for _, netAddr := range netAddrs {
+		if netAddr.ID == "" {
+			return fmt.Errorf("Seed %v has no ID, expected id@host:port", netAddr)
+		}
+	}
+	// the persistent peers are accepted beyond the limits, see checkPeerLimits
+	if persistent {
+		for _, netAddr := range netAddrs {
+			sw.persistent.Set(string(netAddr.ID), netAddr)
+		}
+	}
 
 	if addrBook != nil {
 		// add seeds to `addrBook`
-		ourAddrS := sw.nodeInfo.ListenAddr
-		ourAddr, _ := NewNetAddressString(ourAddrS)
+		ourAddr, _ := sw.NodeInfo().NetAddress()
 		for _, netAddr := range netAddrs {
 			// do not add ourselves
-			if netAddr.Equals(ourAddr) {
+			if ourAddr != nil && netAddr.DialString() == ourAddr.DialString() {
 				continue
 			}
 			addrBook.AddAddress(netAddr, ourAddr)
@@ -341,7 +552,7 @@ func (sw *Switch) DialSeeds(addrBook *AddrBook, seeds []string) error {
 		go func(i int) {
 			sw.randomSleep(0)
 			j := perm[i]
-			sw.dialSeed(netAddrs[j])
+			sw.dialSeed(netAddrs[j], persistent)
 		}(i)
 	}
 	return nil
@@ -353,8 +564,8 @@ func (sw *Switch) randomSleep(interval time.Duration) {
 	time.Sleep(r + interval)
 }
 
-func (sw *Switch) dialSeed(addr *NetAddress) {
-	peer, err := sw.DialPeerWithAddress(addr, true)
+func (sw *Switch) dialSeed(addr *NetAddress, persistent bool) {
+	peer, err := sw.DialPeerWithAddress(addr, persistent)
 	if err != nil {
 		sw.Logger.Error("Error dialing seed", "err", err)
 	} else {
@@ -363,8 +574,16 @@ func (sw *Switch) dialSeed(addr *NetAddress) {
 }
 
 // DialPeerWithAddress dials the given peer and runs sw.addPeer if it connects successfully.
+// The address must have the ID of the peer, which the peer must prove it holds the key of
+// in the secret connection handshake, see ErrSwitchAuthenticationFailure.
 // If `persistent == true`, the switch will always try to reconnect to this peer if the connection ever fails.
 func (sw *Switch) DialPeerWithAddress(addr *NetAddress, persistent bool) (Peer, error) {
+	if addr.ID == "" {
+		return nil, fmt.Errorf("Cannot dial %v without the ID of the peer", addr)
+	}
+	if err := sw.checkBanned(addr.ID, addr.IP.String()); err != nil {
+		return nil, err
+	}
 	sw.dialing.Set(addr.IP.String(), addr)
 	defer sw.dialing.Delete(addr.IP.String())
 
@@ -374,6 +593,15 @@ func (sw *Switch) DialPeerWithAddress(addr *NetAddress, persistent bool) (Peer,
 		sw.Logger.Error("Failed to dial peer", "address", addr, "err", err)
 		return nil, err
 	}
+	// the remote key is only authenticated by the secret connection
+	if sw.peerConfig.AuthEnc {
+		if id := PubKeyToID(peer.PubKey().Wrap()); id != addr.ID {
+			err = ErrSwitchAuthenticationFailure{addr, id}
+			sw.Logger.Error("Failed to dial peer", "address", addr, "err", err)
+			peer.CloseConn()
+			return nil, err
+		}
+	}
 	peer.SetLogger(sw.Logger.With("peer", addr))
 	if persistent {
 		peer.makePersistent()
@@ -441,12 +669,72 @@ func (sw *Switch) StopPeerForError(peer Peer, reason interface{}) {
 	}
 }
 
+// ReportMisbehavior reports the misbehavior of the peer, eg. an invalid block
+// or vote received from it, to the ban list. The peer is stopped for the
+// reason if the misbehavior is at least of SeverityMedium, and stopped
+// without reconnecting to it if it gets banned. The persistent peers are
+// never banned for their misbehaviors, only stopped.
+func (sw *Switch) ReportMisbehavior(peer Peer, severity Severity, reason error) {
+	if sw.banList != nil && !peer.IsPersistent() {
+		ban, err := sw.banList.Report(peer.ID(), severity, reason)
+		if err != nil {
+			sw.Logger.Error("Error saving the ban list", "err", err)
+		}
+		if ban != nil {
+			sw.Logger.Error("Banning peer", "peer", peer, "until", ban.Until, "err", reason)
+			sw.stopAndRemovePeer(peer, reason)
+			return
+		}
+	}
+	if severity >= SeverityMedium {
+		sw.StopPeerForError(peer, reason)
+		return
+	}
+	sw.Logger.Info("Peer misbehaved", "peer", peer, "severity", severity, "err", reason)
+}
+
+// BanPeer bans the ID or the IP of a peer for the duration, or for longer
+// and longer durations if 0, see BanList.Ban, and stops the peers it bans.
+func (sw *Switch) BanPeer(target string, duration time.Duration, reason string) (*Ban, error) {
+	if sw.banList == nil {
+		return nil, ErrSwitchNoBanList
+	}
+	ban, err := sw.banList.Ban(target, duration, reason)
+	if err != nil {
+		return nil, err
+	}
+	for _, peer := range sw.peers.List() {
+		if string(peer.ID()) == target || addrIP(peer.NodeInfo().RemoteAddr) == target {
+			sw.Logger.Info("Stopping banned peer", "peer", peer, "until", ban.Until)
+			sw.stopAndRemovePeer(peer, ErrSwitchBannedPeer{*ban})
+		}
+	}
+	return ban, nil
+}
+
+// UnbanPeer lifts the ban of the ID or the IP of a peer.
+func (sw *Switch) UnbanPeer(target string) error {
+	if sw.banList == nil {
+		return ErrSwitchNoBanList
+	}
+	return sw.banList.Unban(target)
+}
+
+// BannedPeers returns the active bans of the peers.
+func (sw *Switch) BannedPeers() []Ban {
+	if sw.banList == nil {
+		return nil
+	}
+	return sw.banList.List()
+}
+
 // reconnectToPeer tries to reconnect to the peer, first repeatedly
 // with a fixed interval, then with exponential backoff.
 // If no success after all that, it stops trying, and leaves it
 // to the PEX/Addrbook to find the peer again
 func (sw *Switch) reconnectToPeer(peer Peer) {
 	addr, _ := NewNetAddressString(peer.NodeInfo().RemoteAddr)
+	addr.ID = peer.ID()
 	start := time.Now()
 	sw.Logger.Info("Reconnecting to peer", "peer", peer)
 	for i := 0; i < reconnectAttempts; i++ {
@@ -498,11 +786,26 @@ func (sw *Switch) StopPeerGracefully(peer Peer) {
 func (sw *Switch) stopAndRemovePeer(peer Peer, reason interface{}) {
 	sw.peers.Remove(peer)
 	peer.Stop()
+	sw.removePeerMetrics(peer)
 	for _, reactor := range sw.reactors {
 		reactor.RemovePeer(peer, reason)
 	}
 }
 
+func (sw *Switch) channelIDs() []byte {
+	chIDs := make([]byte, len(sw.chDescs))
+	for i, chDesc := range sw.chDescs {
+		chIDs[i] = chDesc.ID
+	}
+	return chIDs
+}
+
+func (sw *Switch) removePeerMetrics(peer Peer) {
+	if sw.metrics != nil {
+		sw.metrics.removePeer(peer.Key(), sw.channelIDs())
+	}
+}
+
 func (sw *Switch) listenerRoutine(l Listener) {
 	for {
 		inConn, ok := <-l.Connections()
@@ -510,12 +813,28 @@ func (sw *Switch) listenerRoutine(l Listener) {
 			break
 		}
 
-		// ignore connection if we already have enough
-		maxPeers := sw.config.MaxNumPeers
-		if maxPeers <= sw.peers.Size() {
-			sw.Logger.Info("Ignoring inbound connection: already have enough peers", "address", inConn.RemoteAddr().String(), "numPeers", sw.peers.Size(), "max", maxPeers)
+		// ignore connection if we already have enough, before the handshake,
+		// unless it may be a persistent peer: the limits are checked again
+		// once its ID is known, see checkPeerLimits
+		ip := addrIP(inConn.RemoteAddr().String())
+		if err := sw.checkBanned("", ip); err != nil {
+			sw.Logger.Info("Ignoring inbound connection: banned IP", "address", inConn.RemoteAddr().String(), "err", err)
+			inConn.Close() // nolint: errcheck
 			continue
 		}
+		if !sw.isPersistentIP(ip) {
+			_, inbound, _ := sw.NumPeers()
+			if inbound >= sw.config.MaxNumInboundPeers {
+				sw.Logger.Info("Ignoring inbound connection: already have enough inbound peers", "address", inConn.RemoteAddr().String(), "numPeers", inbound, "max", sw.config.MaxNumInboundPeers)
+				inConn.Close() // nolint: errcheck
+				continue
+			}
+			if ip != "" && sw.maxPeersWithIP(ip) {
+				sw.Logger.Info("Ignoring inbound connection: already have enough peers with its IP", "address", inConn.RemoteAddr().String(), "max", sw.config.MaxNumPeersPerIP)
+				inConn.Close() // nolint: errcheck
+				continue
+			}
+		}
 
 		// New inbound connection!
 		err := sw.addPeerWithConnectionAndConfig(inConn, sw.peerConfig)
@@ -608,6 +927,7 @@ func makeSwitch(cfg *cfg.P2PConfig, i int, network, version string, initSwitch f
 		Version:    version,
 		RemoteAddr: cmn.Fmt("%v:%v", network, rand.Intn(64512)+1023),
 		ListenAddr: cmn.Fmt("%v:%v", network, rand.Intn(64512)+1023),
+		Other:      []string{cmn.Fmt("%v=%v", PexVersionKey, PexVersion)},
 	})
 	s.SetNodePrivKey(privKey)
 	return s
@@ -221,6 +221,161 @@ func TestConnPubKeyFilter(t *testing.T) {
 	assertNoPeersAfterTimeout(t, s2, 400*time.Millisecond)
 }
 
+func TestSwitchAuthenticatesDialedPeer(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	s1 := makeSwitch(config, 1, "127.0.0.1", "123.123.123", initSwitchFunc)
+	s2 := makeSwitch(config, 2, "127.0.0.1", "123.123.123", initSwitchFunc)
+	s2.AddListener(NewDefaultListener("tcp", s2.NodeInfo().ListenAddr, true, log.TestingLogger()))
+	for _, s := range []*Switch{s1, s2} {
+		require.Nil(s.Start())
+		defer s.Stop()
+	}
+	addr, err := s2.NodeInfo().NetAddress()
+	require.Nil(err)
+
+	// the ID is required
+	noID := *addr
+	noID.ID = ""
+	_, err = s1.DialPeerWithAddress(&noID, false)
+	assert.NotNil(err)
+
+	// the peer must hold the key of the ID
+	otherID := *addr
+	otherID.ID = PubKeyToID(crypto.GenPrivKeyEd25519().PubKey())
+	_, err = s1.DialPeerWithAddress(&otherID, false)
+	assert.Equal(ErrSwitchAuthenticationFailure{&otherID, s2.NodeInfo().ID()}, err)
+	assert.Zero(s1.Peers().Size())
+
+	peer, err := s1.DialPeerWithAddress(addr, false)
+	require.Nil(err)
+	assert.Equal(s2.NodeInfo().ID(), peer.ID())
+}
+
+func TestSwitchMaxInboundPeers(t *testing.T) {
+	assert := assert.New(t)
+
+	conf := *config
+	conf.MaxNumInboundPeers = 1
+	s0 := makeSwitch(&conf, 0, "testing", "123.123.123", initSwitchFunc)
+	s1 := makeSwitch(config, 1, "testing", "123.123.123", initSwitchFunc)
+	s2 := makeSwitch(config, 2, "testing", "123.123.123", initSwitchFunc)
+	s3 := makeSwitch(config, 3, "testing", "123.123.123", initSwitchFunc)
+	for _, s := range []*Switch{s0, s1, s2, s3} {
+		defer s.Stop()
+	}
+	connect := func(s *Switch) error {
+		c0, c := netPipe()
+		errCh := make(chan error)
+		go func() {
+			errCh <- s0.addPeerWithConnection(c0)
+		}()
+		go s.addPeerWithConnection(c) // nolint: errcheck
+		return <-errCh
+	}
+
+	assert.Nil(connect(s1))
+	assert.Equal(ErrSwitchMaxInboundPeers, connect(s2))
+	assert.Equal(1, s0.Peers().Size())
+
+	// the persistent peers are always accepted
+	s3ID := s3.NodeInfo().ID()
+	s0.persistent.Set(string(s3ID), &NetAddress{ID: s3ID})
+	assert.Nil(connect(s3))
+	assert.Equal(2, s0.Peers().Size())
+}
+
+func TestSwitchMaxPeersPerIP(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	conf := *config
+	conf.MaxNumPeersPerIP = 1
+	s0 := makeSwitch(&conf, 0, "127.0.0.1", "123.123.123", initSwitchFunc)
+	s0.AddListener(NewDefaultListener("tcp", s0.NodeInfo().ListenAddr, true, log.TestingLogger()))
+	s1 := makeSwitch(config, 1, "127.0.0.1", "123.123.123", initSwitchFunc)
+	s2 := makeSwitch(config, 2, "127.0.0.1", "123.123.123", initSwitchFunc)
+	for _, s := range []*Switch{s0, s1, s2} {
+		require.Nil(s.Start())
+		defer s.Stop()
+	}
+	addr, err := s0.NodeInfo().NetAddress()
+	require.Nil(err)
+
+	_, err = s1.DialPeerWithAddress(addr, false)
+	require.Nil(err)
+	for i := 0; i < 100 && s0.Peers().Size() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Equal(1, s0.Peers().Size())
+
+	// s2 connects from the same IP
+	_, err = s2.DialPeerWithAddress(addr, false)
+	assert.NotNil(err)
+	assert.Equal(1, s0.Peers().Size())
+}
+
+func TestSwitchBansMisbehavingPeer(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	s0 := makeSwitch(config, 0, "127.0.0.1", "123.123.123", initSwitchFunc)
+	s0.AddListener(NewDefaultListener("tcp", s0.NodeInfo().ListenAddr, true, log.TestingLogger()))
+	s1 := makeSwitch(config, 1, "127.0.0.1", "123.123.123", initSwitchFunc)
+	banList, err := NewBanList("")
+	require.Nil(err)
+	s1.SetBanList(banList)
+	for _, s := range []*Switch{s0, s1} {
+		require.Nil(s.Start())
+		defer s.Stop()
+	}
+	addr, err := s0.NodeInfo().NetAddress()
+	require.Nil(err)
+
+	peer, err := s1.DialPeerWithAddress(addr, false)
+	require.Nil(err)
+
+	// a low severity misbehavior doesn't stop the peer
+	s1.ReportMisbehavior(peer, SeverityLow, fmt.Errorf("Spam"))
+	assert.Equal(1, s1.Peers().Size())
+
+	s1.ReportMisbehavior(peer, SeverityHigh, fmt.Errorf("Invalid block"))
+	assert.Zero(s1.Peers().Size())
+	_, err = s1.DialPeerWithAddress(addr, false)
+	assert.IsType(ErrSwitchBannedPeer{}, err)
+	require.Len(s1.BannedPeers(), 1)
+
+	require.Nil(s1.UnbanPeer(string(addr.ID)))
+	peer, err = s1.DialPeerWithAddress(addr, false)
+	require.Nil(err)
+
+	// the manual bans stop the peers right away
+	_, err = s1.BanPeer(addr.IP.String(), time.Minute, "manual")
+	require.Nil(err)
+	assert.Zero(s1.Peers().Size())
+	assert.False(peer.IsRunning())
+}
+
+func TestSwitchConfiguresChannels(t *testing.T) {
+	assert := assert.New(t)
+
+	p2pConfig := *config
+	p2pConfig.ChannelSendRates = "foo:1000"
+	p2pConfig.ChannelRecvRates = "foo:2000"
+	p2pConfig.ChannelPriorities = "bar:20"
+	sw := initSwitchFunc(0, NewSwitch(&p2pConfig))
+
+	descs := make(map[byte]*ChannelDescriptor)
+	for _, desc := range sw.chDescs {
+		descs[desc.ID] = desc
+	}
+	assert.EqualValues(1000, descs[0x00].SendRate)
+	assert.EqualValues(2000, descs[0x01].RecvRate)
+	assert.Equal(10, descs[0x01].Priority)
+	assert.EqualValues(0, descs[0x02].SendRate)
+	assert.Equal(20, descs[0x03].Priority)
+	// the descriptors of the reactor are left untouched
+	assert.EqualValues(0, sw.Reactor("foo").GetChannels()[0].SendRate)
+}
+
 func TestSwitchStopsNonPersistentPeerOnError(t *testing.T) {
 	assert, require := assert.New(t), require.New(t)
 
@@ -0,0 +1,62 @@
+package p2p
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// Transport dials and accepts the raw connections to the peers, which are
+// then authenticated and encrypted by the secret connection, and multiplexed
+// by the MConnection, whatever the transport.
+type Transport interface {
+	// Network is the network of the transport, "tcp" or "udp", eg. to map the
+	// port of the listener with UPnP.
+	Network() string
+
+	// Dial connects to the peer at the address, within the timeout.
+	Dial(addr *NetAddress, timeout time.Duration) (net.Conn, error)
+
+	// Listen accepts the connections of the peers at the local address.
+	Listen(lAddr string) (net.Listener, error)
+}
+
+// NewTransport returns the transport of the protocol of the p2p.laddr of the
+// node: "tcp" (or "tcp4", "tcp6") or "quic". The peers of a node must use the
+// same transport.
+func NewTransport(protocol string) (Transport, error) {
+	switch protocol {
+	case "tcp", "tcp4", "tcp6":
+		return NewTCPTransport(protocol), nil
+	case "quic":
+		return NewQUICTransport()
+	default:
+		return nil, fmt.Errorf("Unknown p2p transport %q, expected tcp or quic", protocol)
+	}
+}
+
+// TCPTransport is the default transport, a TCP connection per peer.
+type TCPTransport struct {
+	network string
+}
+
+// NewTCPTransport returns a transport over the TCP network, "tcp", "tcp4"
+// or "tcp6".
+func NewTCPTransport(network string) *TCPTransport {
+	return &TCPTransport{network: network}
+}
+
+// Network implements Transport.
+func (t *TCPTransport) Network() string {
+	return "tcp"
+}
+
+// Dial implements Transport.
+func (t *TCPTransport) Dial(addr *NetAddress, timeout time.Duration) (net.Conn, error) {
+	return net.DialTimeout(t.network, addr.DialString(), timeout)
+}
+
+// Listen implements Transport.
+func (t *TCPTransport) Listen(lAddr string) (net.Listener, error) {
+	return net.Listen(t.network, lAddr)
+}
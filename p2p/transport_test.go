@@ -0,0 +1,70 @@
+package p2p
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTransport(t *testing.T) {
+	assert := assert.New(t)
+
+	for protocol, network := range map[string]string{"tcp": "tcp", "tcp4": "tcp", "quic": "udp"} {
+		transport, err := NewTransport(protocol)
+		if assert.Nil(err, protocol) {
+			assert.Equal(network, transport.Network(), protocol)
+		}
+	}
+	_, err := NewTransport("unix")
+	assert.NotNil(err)
+}
+
+func TestTransportDialListen(t *testing.T) {
+	for _, protocol := range []string{"tcp", "quic"} {
+		testTransportDialListen(t, protocol)
+	}
+}
+
+func testTransportDialListen(t *testing.T, protocol string) {
+	assert, require := assert.New(t), require.New(t)
+
+	transport, err := NewTransport(protocol)
+	require.Nil(err, protocol)
+	listener, err := transport.Listen("127.0.0.1:0")
+	require.Nil(err, protocol)
+	defer listener.Close() // nolint: errcheck
+	addr, err := NewNetAddressString(listener.Addr().String())
+	require.Nil(err, protocol)
+
+	// the dialer writes first, like the secret connection handshake
+	go func() {
+		conn, err := transport.Dial(addr, time.Second)
+		if err != nil {
+			t.Error(protocol, err)
+			return
+		}
+		defer conn.Close() // nolint: errcheck
+		_, err = conn.Write([]byte("ping"))
+		assert.Nil(err, protocol)
+		buf := make([]byte, 4)
+		_, err = io.ReadFull(conn, buf)
+		assert.Nil(err, protocol)
+		assert.Equal("pong", string(buf), protocol)
+	}()
+
+	conn, err := listener.Accept()
+	require.Nil(err, protocol)
+	defer conn.Close() // nolint: errcheck
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	require.Nil(err, protocol)
+	assert.Equal("ping", string(buf), protocol)
+	_, err = conn.Write([]byte("pong"))
+	assert.Nil(err, protocol)
+	// wait for the dialer to read the pong before closing
+	conn.SetReadDeadline(time.Now().Add(time.Second)) // nolint: errcheck
+	conn.Read(buf)                                    // nolint: errcheck
+}
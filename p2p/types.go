@@ -11,6 +11,9 @@ import (
 
 const maxNodeInfoSize = 10240 // 10Kb
 
+// GenesisHashKey is the key of the hash of the genesis doc in NodeInfo.Other
+const GenesisHashKey = "genesis_hash"
+
 type NodeInfo struct {
 	PubKey     crypto.PubKeyEd25519 `json:"pub_key"`
 	Moniker    string               `json:"moniker"`
@@ -51,9 +54,41 @@ func (info *NodeInfo) CompatibleWith(other *NodeInfo) error {
 		return fmt.Errorf("Peer is on a different network. Got %v, expected %v", other.Network, info.Network)
 	}
 
+	// nodes must have the same genesis, if both tell its hash (older nodes don't)
+	iGenesis, oGenesis := info.otherValue(GenesisHashKey), other.otherValue(GenesisHashKey)
+	if iGenesis != "" && oGenesis != "" && iGenesis != oGenesis {
+		return fmt.Errorf("Peer has a different genesis for network %v. Got hash %v, expected %v", info.Network, oGenesis, iGenesis)
+	}
+
 	return nil
 }
 
+// otherValue returns the value of key in Other, formatted as "key=value", or "" if it's not there.
+func (info *NodeInfo) otherValue(key string) string {
+	prefix := key + "="
+	for _, s := range info.Other {
+		if strings.HasPrefix(s, prefix) {
+			return s[len(prefix):]
+		}
+	}
+	return ""
+}
+
+// ID returns the ID of the node, derived from its public key.
+func (info *NodeInfo) ID() ID {
+	return PubKeyToID(info.PubKey.Wrap())
+}
+
+// NetAddress returns the address the node listens at, with its ID.
+func (info *NodeInfo) NetAddress() (*NetAddress, error) {
+	addr, err := NewNetAddressString(info.ListenAddr)
+	if err != nil {
+		return nil, err
+	}
+	addr.ID = info.ID()
+	return addr, nil
+}
+
 func (info *NodeInfo) ListenHost() string {
 	host, _, _ := net.SplitHostPort(info.ListenAddr) // nolint: errcheck, gas
 	return host
@@ -0,0 +1,25 @@
+package p2p
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeInfoCompatibleGenesis(t *testing.T) {
+	assert := assert.New(t)
+
+	nodeInfo := func(other ...string) *NodeInfo {
+		return &NodeInfo{Network: "testing", Version: "1.2.3", Other: other}
+	}
+	info := nodeInfo("tx_index=on", "genesis_hash=AB")
+
+	assert.Nil(info.CompatibleWith(nodeInfo("genesis_hash=AB")))
+	assert.NotNil(info.CompatibleWith(nodeInfo("genesis_hash=CD")))
+	// older nodes don't tell
+	assert.Nil(info.CompatibleWith(nodeInfo()))
+	assert.Nil(nodeInfo().CompatibleWith(info))
+
+	assert.Equal("on", info.otherValue("tx_index"))
+	assert.Equal("", info.otherValue("rpc_addr"))
+}
@@ -1,6 +1,7 @@
 package proxy
 
 import (
+	"strings"
 	"sync"
 
 	"github.com/pkg/errors"
@@ -8,6 +9,8 @@ import (
 	abcicli "github.com/tendermint/abci/client"
 	"github.com/tendermint/abci/example/dummy"
 	"github.com/tendermint/abci/types"
+
+	"github.com/tendermint/tendermint/benchmarks/benchapp"
 )
 
 // NewABCIClient returns newly connected client
@@ -59,10 +62,30 @@ func (r *remoteClientCreator) NewABCIClient() (abcicli.Client, error) {
 	return remoteApp, nil
 }
 
+// errClientCreator fails to create clients, eg. for an invalid app config
+type errClientCreator struct {
+	err error
+}
+
+func (e errClientCreator) NewABCIClient() (abcicli.Client, error) {
+	return nil, e.err
+}
+
 //-----------------------------------------------------------------
 // default
 
+// DefaultClientCreator returns the ClientCreator of the in-proc app named addr:
+// "dummy", "persistent_dummy", "nilapp", or "bench" with its options after a colon,
+// eg. "bench:deliver_cost=100", or else of the app listening at addr.
 func DefaultClientCreator(addr, transport, dbDir string) ClientCreator {
+	if addr == "bench" || strings.HasPrefix(addr, "bench:") {
+		opts, err := benchapp.ParseOptions(strings.TrimPrefix(strings.TrimPrefix(addr, "bench"), ":"))
+		if err != nil {
+			return errClientCreator{err}
+		}
+		return NewLocalClientCreator(benchapp.NewApplication(opts))
+	}
+
 	switch addr {
 	case "dummy":
 		return NewLocalClientCreator(dummy.NewDummyApplication())
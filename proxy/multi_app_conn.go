@@ -1,9 +1,12 @@
 package proxy
 
 import (
+	"fmt"
+
 	"github.com/pkg/errors"
 
 	cmn "github.com/tendermint/tmlibs/common"
+	"github.com/tendermint/tmlibs/log"
 )
 
 //-----------------------------
@@ -21,6 +24,24 @@ func NewAppConns(clientCreator ClientCreator, handshaker Handshaker) AppConns {
 	return NewMultiAppConn(clientCreator, handshaker)
 }
 
+// NewAppConnsMempool starts n more mempool connections to the app,
+// eg. to recheck the txs of the mempool in parallel.
+func NewAppConnsMempool(clientCreator ClientCreator, n int, logger log.Logger) ([]AppConnMempool, error) {
+	conns := make([]AppConnMempool, n)
+	for i := range conns {
+		cli, err := clientCreator.NewABCIClient()
+		if err != nil {
+			return nil, errors.Wrap(err, "Error creating ABCI client (mempool connection)")
+		}
+		cli.SetLogger(logger.With("module", "abci-client", "connection", fmt.Sprintf("mempool-%d", i+1)))
+		if err := cli.Start(); err != nil {
+			return nil, errors.Wrap(err, "Error starting ABCI client (mempool connection)")
+		}
+		conns[i] = NewAppConnMempool(cli)
+	}
+	return conns, nil
+}
+
 //-----------------------------
 // multiAppConn implements AppConns
 
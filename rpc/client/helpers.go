@@ -1,7 +1,9 @@
 package client
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"time"
 
 	"github.com/pkg/errors"
@@ -78,3 +80,26 @@ func WaitForOneEvent(c EventsClient, evtTyp string, timeout time.Duration) (type
 		return types.TMEventData{}, errors.New("timed out waiting for event")
 	}
 }
+
+// FetchGenesis returns the genesis doc of the node, reassembled from the
+// chunks of GenesisChunked, for the ones too large for Genesis.
+func FetchGenesis(c HistoryClient) (*types.GenesisDoc, error) {
+	var buf bytes.Buffer
+	for chunk, total := 0, 1; chunk < total; chunk++ {
+		result, err := c.GenesisChunked(chunk)
+		if err != nil {
+			return nil, err
+		}
+		if result.ChunkNumber != chunk || (chunk > 0 && result.TotalChunks != total) {
+			return nil, errors.Errorf("Got chunk %d of %d, expected chunk %d of %d",
+				result.ChunkNumber, result.TotalChunks, chunk, total)
+		}
+		total = result.TotalChunks
+		data, err := base64.StdEncoding.DecodeString(result.Data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error decoding chunk %d", chunk)
+		}
+		buf.Write(data)
+	}
+	return types.GenesisDocFromJSON(buf.Bytes())
+}
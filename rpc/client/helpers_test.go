@@ -1,15 +1,19 @@
 package client_test
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	crypto "github.com/tendermint/go-crypto"
 	"github.com/tendermint/tendermint/rpc/client"
 	"github.com/tendermint/tendermint/rpc/client/mock"
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	"github.com/tendermint/tendermint/types"
 )
 
 func TestWaitForHeight(t *testing.T) {
@@ -74,3 +78,45 @@ func TestWaitForHeight(t *testing.T) {
 	require.True(ok)
 	assert.Equal(int64(15), postr.LatestBlockHeight)
 }
+
+// genesisChunks serves the chunks of a genesis doc.
+type genesisChunks struct {
+	client.HistoryClient
+	chunks []string
+}
+
+func (g genesisChunks) GenesisChunked(chunk int) (*ctypes.ResultGenesisChunk, error) {
+	if chunk < 0 || chunk >= len(g.chunks) {
+		return nil, errors.New("no such chunk")
+	}
+	return &ctypes.ResultGenesisChunk{chunk, len(g.chunks), g.chunks[chunk]}, nil
+}
+
+func TestFetchGenesis(t *testing.T) {
+	doc := &types.GenesisDoc{
+		ChainID:    "test-chain",
+		Validators: []types.GenesisValidator{{crypto.GenPrivKeyEd25519().PubKey(), 10, "myval"}},
+		AppOptions: map[string]interface{}{"accounts": strings.Repeat("a", 100)},
+	}
+	b, err := json.Marshal(doc)
+	require.Nil(t, err)
+	third := len(b) / 3
+	chunks := []string{
+		base64.StdEncoding.EncodeToString(b[:third]),
+		base64.StdEncoding.EncodeToString(b[third : 2*third]),
+		base64.StdEncoding.EncodeToString(b[2*third:]),
+	}
+
+	fetched, err := client.FetchGenesis(genesisChunks{chunks: chunks})
+	require.Nil(t, err)
+	assert.Equal(t, doc.ChainID, fetched.ChainID)
+	assert.Equal(t, doc.Validators, fetched.Validators)
+	assert.Equal(t, doc.AppOptions, fetched.AppOptions)
+
+	// a chunk is missing
+	_, err = client.FetchGenesis(genesisChunks{chunks: chunks[:2]})
+	assert.NotNil(t, err)
+	// or corrupted
+	_, err = client.FetchGenesis(genesisChunks{chunks: []string{chunks[0], "!", chunks[2]}})
+	assert.NotNil(t, err)
+}
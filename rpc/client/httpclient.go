@@ -2,8 +2,10 @@ package client
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -26,17 +28,24 @@ out the server for test code (mock).
 */
 type HTTP struct {
 	remote string
-	rpc    *rpcclient.JSONRPCClient
+	rpc    rpcclient.HTTPClient
 	*WSEvents
 }
 
 // New takes a remote endpoint in the form tcp://<host>:<port>
 // and the websocket path (which always seems to be "/websocket")
 func NewHTTP(remote, wsEndpoint string) *HTTP {
+	return NewHTTPWithTLS(remote, wsEndpoint, nil)
+}
+
+// NewHTTPWithTLS is like NewHTTP, but connects over TLS (https:// and wss://)
+// with the tlsConfig, eg. from rpcclient.NewClientTLSConfig, or with the
+// system CAs if nil and the remote is https://.
+func NewHTTPWithTLS(remote, wsEndpoint string, tlsConfig *tls.Config) *HTTP {
 	return &HTTP{
-		rpc:      rpcclient.NewJSONRPCClient(remote),
+		rpc:      rpcclient.NewJSONRPCClientWithTLS(remote, tlsConfig),
 		remote:   remote,
-		WSEvents: newWSEvents(remote, wsEndpoint),
+		WSEvents: newWSEvents(remote, wsEndpoint, tlsConfig),
 	}
 }
 
@@ -46,6 +55,41 @@ var (
 	_ EventsClient  = (*HTTP)(nil)
 )
 
+// BatchHTTP is an HTTP client whose calls are queued, to be sent in one HTTP
+// request with Send. The results the calls return are filled in by Send.
+// The events are not batched.
+type BatchHTTP struct {
+	*HTTP
+	batch *rpcclient.JSONRPCRequestBatch
+}
+
+// NewBatch returns a client queueing its calls, to send them at once, eg. to
+// get many blocks in one round-trip.
+func (c *HTTP) NewBatch() *BatchHTTP {
+	batch := rpcclient.NewJSONRPCClient(c.remote).NewRequestBatch()
+	return &BatchHTTP{
+		HTTP:  &HTTP{remote: c.remote, rpc: batch, WSEvents: c.WSEvents},
+		batch: batch,
+	}
+}
+
+// Send sends the calls queued in one HTTP request, fills in their results, and
+// returns them in the order of the calls. The error is the one of the first
+// call which failed, if any.
+func (b *BatchHTTP) Send() ([]interface{}, error) {
+	return b.batch.Send()
+}
+
+// Count returns the number of calls queued.
+func (b *BatchHTTP) Count() int {
+	return b.batch.Count()
+}
+
+// Clear removes the calls queued, and returns how many there were.
+func (b *BatchHTTP) Clear() int {
+	return b.batch.Clear()
+}
+
 func (c *HTTP) Status() (*ctypes.ResultStatus, error) {
 	result := new(ctypes.ResultStatus)
 	_, err := c.rpc.Call("status", map[string]interface{}{}, result)
@@ -70,12 +114,18 @@ func (c *HTTP) ABCIQuery(path string, data data.Bytes) (*ctypes.ResultABCIQuery,
 
 func (c *HTTP) ABCIQueryWithOptions(path string, data data.Bytes, opts ABCIQueryOptions) (*ctypes.ResultABCIQuery, error) {
 	result := new(ctypes.ResultABCIQuery)
+	trusted := opts.Trusted && !opts.Prove
 	_, err := c.rpc.Call("abci_query",
-		map[string]interface{}{"path": path, "data": data, "height": opts.Height, "trusted": opts.Trusted},
+		map[string]interface{}{"path": path, "data": data, "height": opts.Height, "trusted": trusted},
 		result)
 	if err != nil {
 		return nil, errors.Wrap(err, "ABCIQuery")
 	}
+	if opts.Prove {
+		if err := verifyABCIQuery(c, path, result); err != nil {
+			return nil, err
+		}
+	}
 	return result, nil
 }
 
@@ -114,6 +164,68 @@ func (c *HTTP) NetInfo() (*ctypes.ResultNetInfo, error) {
 	return result, nil
 }
 
+func (c *HTTP) IntegrityReport() (*ctypes.ResultIntegrityReport, error) {
+	result := new(ctypes.ResultIntegrityReport)
+	_, err := c.rpc.Call("integrity_report", map[string]interface{}{}, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "IntegrityReport")
+	}
+	return result, nil
+}
+
+func (c *HTTP) CheckIntegrity(from, to int64) (*ctypes.ResultIntegrityReport, error) {
+	result := new(ctypes.ResultIntegrityReport)
+	_, err := c.rpc.Call("check_integrity", map[string]interface{}{"from": from, "to": to}, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "CheckIntegrity")
+	}
+	return result, nil
+}
+
+// ScheduleExit makes the validator of the node stop signing after the height,
+// or sign again if 0. It needs the unsafe routes.
+func (c *HTTP) ScheduleExit(height int64) (*ctypes.ResultScheduleExit, error) {
+	result := new(ctypes.ResultScheduleExit)
+	_, err := c.rpc.Call("unsafe_schedule_exit", map[string]interface{}{"height": height}, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "ScheduleExit")
+	}
+	return result, nil
+}
+
+// BannedPeers returns the active bans of the peers.
+func (c *HTTP) BannedPeers() (*ctypes.ResultBannedPeers, error) {
+	result := new(ctypes.ResultBannedPeers)
+	_, err := c.rpc.Call("banned_peers", map[string]interface{}{}, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "BannedPeers")
+	}
+	return result, nil
+}
+
+// BanPeer bans the ID or the IP of a peer for the duration, rounded to the
+// second. It needs the unsafe routes.
+func (c *HTTP) BanPeer(target string, duration time.Duration, reason string) (*ctypes.ResultBanPeer, error) {
+	result := new(ctypes.ResultBanPeer)
+	params := map[string]interface{}{"target": target, "duration": int64(duration / time.Second), "reason": reason}
+	_, err := c.rpc.Call("unsafe_ban_peer", params, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "BanPeer")
+	}
+	return result, nil
+}
+
+// UnbanPeer lifts the ban of the ID or the IP of a peer. It needs the unsafe
+// routes.
+func (c *HTTP) UnbanPeer(target string) (*ctypes.ResultUnbanPeer, error) {
+	result := new(ctypes.ResultUnbanPeer)
+	_, err := c.rpc.Call("unsafe_unban_peer", map[string]interface{}{"target": target}, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "UnbanPeer")
+	}
+	return result, nil
+}
+
 func (c *HTTP) DumpConsensusState() (*ctypes.ResultDumpConsensusState, error) {
 	result := new(ctypes.ResultDumpConsensusState)
 	_, err := c.rpc.Call("dump_consensus_state", map[string]interface{}{}, result)
@@ -123,17 +235,76 @@ func (c *HTTP) DumpConsensusState() (*ctypes.ResultDumpConsensusState, error) {
 	return result, nil
 }
 
-func (c *HTTP) BlockchainInfo(minHeight, maxHeight int64) (*ctypes.ResultBlockchainInfo, error) {
+func (c *HTTP) ConsensusState() (*ctypes.ResultConsensusState, error) {
+	result := new(ctypes.ResultConsensusState)
+	_, err := c.rpc.Call("consensus_state", map[string]interface{}{}, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "ConsensusState")
+	}
+	return result, nil
+}
+
+func (c *HTTP) BlockchainInfo(minHeight, maxHeight int64, page, perPage int, orderBy string) (*ctypes.ResultBlockchainInfo, error) {
 	result := new(ctypes.ResultBlockchainInfo)
-	_, err := c.rpc.Call("blockchain",
-		map[string]interface{}{"minHeight": minHeight, "maxHeight": maxHeight},
-		result)
+	params := map[string]interface{}{
+		"minHeight": minHeight,
+		"maxHeight": maxHeight,
+		"page":      page,
+		"per_page":  perPage,
+		"order_by":  orderBy,
+	}
+	_, err := c.rpc.Call("blockchain", params, result)
 	if err != nil {
 		return nil, errors.Wrap(err, "BlockchainInfo")
 	}
 	return result, nil
 }
 
+func (c *HTTP) Headers(from, to int64) (*ctypes.ResultHeaders, error) {
+	result := new(ctypes.ResultHeaders)
+	_, err := c.rpc.Call("headers", map[string]interface{}{"from": from, "to": to}, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "Headers")
+	}
+	return result, nil
+}
+
+func (c *HTTP) AppHash(height *int64) (*ctypes.ResultAppHash, error) {
+	result := new(ctypes.ResultAppHash)
+	_, err := c.rpc.Call("app_hash", map[string]interface{}{"height": height}, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "AppHash")
+	}
+	return result, nil
+}
+
+func (c *HTTP) AppHashes(from, to int64) (*ctypes.ResultAppHashes, error) {
+	result := new(ctypes.ResultAppHashes)
+	_, err := c.rpc.Call("app_hashes", map[string]interface{}{"from": from, "to": to}, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "AppHashes")
+	}
+	return result, nil
+}
+
+func (c *HTTP) BlockSearch(query string) (*ctypes.ResultBlockSearch, error) {
+	result := new(ctypes.ResultBlockSearch)
+	_, err := c.rpc.Call("block_search", map[string]interface{}{"query": query}, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "BlockSearch")
+	}
+	return result, nil
+}
+
+func (c *HTTP) ValidatorDistribution(from, to int64) (*ctypes.ResultValidatorDistribution, error) {
+	result := new(ctypes.ResultValidatorDistribution)
+	_, err := c.rpc.Call("validator_distribution", map[string]interface{}{"from": from, "to": to}, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "ValidatorDistribution")
+	}
+	return result, nil
+}
+
 func (c *HTTP) Genesis() (*ctypes.ResultGenesis, error) {
 	result := new(ctypes.ResultGenesis)
 	_, err := c.rpc.Call("genesis", map[string]interface{}{}, result)
@@ -143,6 +314,15 @@ func (c *HTTP) Genesis() (*ctypes.ResultGenesis, error) {
 	return result, nil
 }
 
+func (c *HTTP) GenesisChunked(chunk int) (*ctypes.ResultGenesisChunk, error) {
+	result := new(ctypes.ResultGenesisChunk)
+	_, err := c.rpc.Call("genesis_chunked", map[string]interface{}{"chunk": chunk}, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "GenesisChunked")
+	}
+	return result, nil
+}
+
 func (c *HTTP) Block(height *int64) (*ctypes.ResultBlock, error) {
 	result := new(ctypes.ResultBlock)
 	_, err := c.rpc.Call("block", map[string]interface{}{"height": height}, result)
@@ -161,6 +341,47 @@ func (c *HTTP) Commit(height *int64) (*ctypes.ResultCommit, error) {
 	return result, nil
 }
 
+func (c *HTTP) BlockResults(height *int64) (*ctypes.ResultBlockResults, error) {
+	result := new(ctypes.ResultBlockResults)
+	_, err := c.rpc.Call("block_results", map[string]interface{}{"height": height}, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "BlockResults")
+	}
+	return result, nil
+}
+
+func (c *HTTP) VerifyCommit(header *types.Header, commit *types.Commit, validators []*types.Validator) (*ctypes.ResultVerifyCommit, error) {
+	result := new(ctypes.ResultVerifyCommit)
+	_, err := c.rpc.Call("verify_commit", map[string]interface{}{"header": header, "commit": commit, "validators": validators}, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "VerifyCommit")
+	}
+	return result, nil
+}
+
+func (c *HTTP) RandomBeacon(height *int64) (*ctypes.ResultRandomBeacon, error) {
+	result := new(ctypes.ResultRandomBeacon)
+	_, err := c.rpc.Call("random_beacon", map[string]interface{}{"height": height}, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "RandomBeacon")
+	}
+	return result, nil
+}
+
+func (c *HTTP) SigningInfo(address []byte, from, to int64) (*ctypes.ResultSigningInfo, error) {
+	result := new(ctypes.ResultSigningInfo)
+	params := map[string]interface{}{
+		"address": address,
+		"from":    from,
+		"to":      to,
+	}
+	_, err := c.rpc.Call("signing_info", params, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "SigningInfo")
+	}
+	return result, nil
+}
+
 func (c *HTTP) Tx(hash []byte, prove bool) (*ctypes.ResultTx, error) {
 	result := new(ctypes.ResultTx)
 	params := map[string]interface{}{
@@ -174,44 +395,64 @@ func (c *HTTP) Tx(hash []byte, prove bool) (*ctypes.ResultTx, error) {
 	return result, nil
 }
 
-func (c *HTTP) TxSearch(query string, prove bool) ([]*ctypes.ResultTx, error) {
-	results := new([]*ctypes.ResultTx)
+func (c *HTTP) TxSearch(query string, prove bool, page, perPage int, orderBy string) (*ctypes.ResultTxSearch, error) {
+	result := new(ctypes.ResultTxSearch)
 	params := map[string]interface{}{
-		"query": query,
-		"prove": prove,
+		"query":    query,
+		"prove":    prove,
+		"page":     page,
+		"per_page": perPage,
+		"order_by": orderBy,
 	}
-	_, err := c.rpc.Call("tx_search", params, results)
+	_, err := c.rpc.Call("tx_search", params, result)
 	if err != nil {
 		return nil, errors.Wrap(err, "TxSearch")
 	}
-	return *results, nil
+	return result, nil
 }
 
-func (c *HTTP) Validators(height *int64) (*ctypes.ResultValidators, error) {
+func (c *HTTP) Validators(height *int64, page, perPage int, orderBy string) (*ctypes.ResultValidators, error) {
 	result := new(ctypes.ResultValidators)
-	_, err := c.rpc.Call("validators", map[string]interface{}{"height": height}, result)
+	params := map[string]interface{}{
+		"height":   height,
+		"page":     page,
+		"per_page": perPage,
+		"order_by": orderBy,
+	}
+	_, err := c.rpc.Call("validators", params, result)
 	if err != nil {
 		return nil, errors.Wrap(err, "Validators")
 	}
 	return result, nil
 }
 
+func (c *HTTP) ConsensusParams(height *int64) (*ctypes.ResultConsensusParams, error) {
+	result := new(ctypes.ResultConsensusParams)
+	_, err := c.rpc.Call("consensus_params", map[string]interface{}{"height": height}, result)
+	if err != nil {
+		return nil, errors.Wrap(err, "ConsensusParams")
+	}
+	return result, nil
+}
+
 /** websocket event stuff here... **/
 
 type WSEvents struct {
 	cmn.BaseService
-	remote   string
-	endpoint string
-	ws       *rpcclient.WSClient
+	remote    string
+	endpoint  string
+	tlsConfig *tls.Config // nil for the system CAs, if over TLS
+	ws        *rpcclient.WSClient
 
 	mtx           sync.RWMutex
 	subscriptions map[string]chan<- interface{}
 }
 
-func newWSEvents(remote, endpoint string) *WSEvents {
+func newWSEvents(remote, endpoint string, tlsConfig *tls.Config) *WSEvents {
 	wsEvents := &WSEvents{
 		endpoint:      endpoint,
 		remote:        remote,
+		tlsConfig:     tlsConfig,
 		subscriptions: make(map[string]chan<- interface{}),
 	}
 
@@ -220,9 +461,13 @@ func newWSEvents(remote, endpoint string) *WSEvents {
 }
 
 func (w *WSEvents) OnStart() error {
-	w.ws = rpcclient.NewWSClient(w.remote, w.endpoint, rpcclient.OnReconnect(func() {
+	options := []func(*rpcclient.WSClient){rpcclient.OnReconnect(func() {
 		w.redoSubscriptions()
-	}))
+	})}
+	if w.tlsConfig != nil {
+		options = append(options, rpcclient.TLSConfig(w.tlsConfig))
+	}
+	w.ws = rpcclient.NewWSClient(w.remote, w.endpoint, options...)
 	err := w.ws.Start()
 	if err != nil {
 		return err
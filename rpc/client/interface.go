@@ -46,15 +46,26 @@ type ABCIClient interface {
 type SignClient interface {
 	Block(height *int64) (*ctypes.ResultBlock, error)
 	Commit(height *int64) (*ctypes.ResultCommit, error)
-	Validators(height *int64) (*ctypes.ResultValidators, error)
+	BlockResults(height *int64) (*ctypes.ResultBlockResults, error)
+	VerifyCommit(header *types.Header, commit *types.Commit, validators []*types.Validator) (*ctypes.ResultVerifyCommit, error)
+	RandomBeacon(height *int64) (*ctypes.ResultRandomBeacon, error)
+	Validators(height *int64, page, perPage int, orderBy string) (*ctypes.ResultValidators, error)
+	ConsensusParams(height *int64) (*ctypes.ResultConsensusParams, error)
+	SigningInfo(address []byte, from, to int64) (*ctypes.ResultSigningInfo, error)
 	Tx(hash []byte, prove bool) (*ctypes.ResultTx, error)
-	TxSearch(query string, prove bool) ([]*ctypes.ResultTx, error)
+	TxSearch(query string, prove bool, page, perPage int, orderBy string) (*ctypes.ResultTxSearch, error)
 }
 
 // HistoryClient shows us data from genesis to now in large chunks.
 type HistoryClient interface {
 	Genesis() (*ctypes.ResultGenesis, error)
-	BlockchainInfo(minHeight, maxHeight int64) (*ctypes.ResultBlockchainInfo, error)
+	GenesisChunked(chunk int) (*ctypes.ResultGenesisChunk, error)
+	BlockchainInfo(minHeight, maxHeight int64, page, perPage int, orderBy string) (*ctypes.ResultBlockchainInfo, error)
+	Headers(from, to int64) (*ctypes.ResultHeaders, error)
+	BlockSearch(query string) (*ctypes.ResultBlockSearch, error)
+	ValidatorDistribution(from, to int64) (*ctypes.ResultValidatorDistribution, error)
+	AppHash(height *int64) (*ctypes.ResultAppHash, error)
+	AppHashes(from, to int64) (*ctypes.ResultAppHashes, error)
 }
 
 type StatusClient interface {
@@ -82,6 +93,9 @@ type Client interface {
 type NetworkClient interface {
 	NetInfo() (*ctypes.ResultNetInfo, error)
 	DumpConsensusState() (*ctypes.ResultDumpConsensusState, error)
+	ConsensusState() (*ctypes.ResultConsensusState, error)
+	IntegrityReport() (*ctypes.ResultIntegrityReport, error)
+	CheckIntegrity(from, to int64) (*ctypes.ResultIntegrityReport, error)
 }
 
 // EventsClient is reactive, you can subscribe to any message, given the proper
@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"time"
 
 	data "github.com/tendermint/go-wire/data"
 	nm "github.com/tendermint/tendermint/node"
@@ -60,8 +61,15 @@ func (c *Local) ABCIQuery(path string, data data.Bytes) (*ctypes.ResultABCIQuery
 	return c.ABCIQueryWithOptions(path, data, DefaultABCIQueryOptions)
 }
 
-func (Local) ABCIQueryWithOptions(path string, data data.Bytes, opts ABCIQueryOptions) (*ctypes.ResultABCIQuery, error) {
-	return core.ABCIQuery(path, data, opts.Height, opts.Trusted)
+func (c Local) ABCIQueryWithOptions(path string, data data.Bytes, opts ABCIQueryOptions) (*ctypes.ResultABCIQuery, error) {
+	result, err := core.ABCIQuery(context.Background(), path, data, opts.Height, opts.Trusted && !opts.Prove)
+	if err != nil || !opts.Prove {
+		return result, err
+	}
+	if err := verifyABCIQuery(c, path, result); err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
 func (Local) BroadcastTxCommit(tx types.Tx) (*ctypes.ResultBroadcastTxCommit, error) {
@@ -69,11 +77,11 @@ func (Local) BroadcastTxCommit(tx types.Tx) (*ctypes.ResultBroadcastTxCommit, er
 }
 
 func (Local) BroadcastTxAsync(tx types.Tx) (*ctypes.ResultBroadcastTx, error) {
-	return core.BroadcastTxAsync(tx)
+	return core.BroadcastTxAsync(tx, 0)
 }
 
 func (Local) BroadcastTxSync(tx types.Tx) (*ctypes.ResultBroadcastTx, error) {
-	return core.BroadcastTxSync(tx)
+	return core.BroadcastTxSync(tx, 0)
 }
 
 func (Local) NetInfo() (*ctypes.ResultNetInfo, error) {
@@ -84,18 +92,70 @@ func (Local) DumpConsensusState() (*ctypes.ResultDumpConsensusState, error) {
 	return core.DumpConsensusState()
 }
 
+func (Local) ConsensusState() (*ctypes.ResultConsensusState, error) {
+	return core.ConsensusState()
+}
+
+func (Local) IntegrityReport() (*ctypes.ResultIntegrityReport, error) {
+	return core.IntegrityReport()
+}
+
+func (Local) CheckIntegrity(from, to int64) (*ctypes.ResultIntegrityReport, error) {
+	return core.CheckIntegrity(from, to)
+}
+
+func (Local) ScheduleExit(height int64) (*ctypes.ResultScheduleExit, error) {
+	return core.UnsafeScheduleExit(height)
+}
+
 func (Local) DialSeeds(seeds []string) (*ctypes.ResultDialSeeds, error) {
 	return core.UnsafeDialSeeds(seeds)
 }
 
-func (Local) BlockchainInfo(minHeight, maxHeight int64) (*ctypes.ResultBlockchainInfo, error) {
-	return core.BlockchainInfo(minHeight, maxHeight)
+func (Local) BannedPeers() (*ctypes.ResultBannedPeers, error) {
+	return core.BannedPeers()
+}
+
+func (Local) BanPeer(target string, duration time.Duration, reason string) (*ctypes.ResultBanPeer, error) {
+	return core.UnsafeBanPeer(target, int64(duration/time.Second), reason)
+}
+
+func (Local) UnbanPeer(target string) (*ctypes.ResultUnbanPeer, error) {
+	return core.UnsafeUnbanPeer(target)
+}
+
+func (Local) BlockchainInfo(minHeight, maxHeight int64, page, perPage int, orderBy string) (*ctypes.ResultBlockchainInfo, error) {
+	return core.BlockchainInfo(minHeight, maxHeight, page, perPage, orderBy)
+}
+
+func (Local) Headers(from, to int64) (*ctypes.ResultHeaders, error) {
+	return core.Headers(from, to)
+}
+
+func (Local) AppHash(height *int64) (*ctypes.ResultAppHash, error) {
+	return core.AppHash(height)
+}
+
+func (Local) AppHashes(from, to int64) (*ctypes.ResultAppHashes, error) {
+	return core.AppHashes(from, to)
+}
+
+func (Local) BlockSearch(query string) (*ctypes.ResultBlockSearch, error) {
+	return core.BlockSearch(query)
+}
+
+func (Local) ValidatorDistribution(from, to int64) (*ctypes.ResultValidatorDistribution, error) {
+	return core.ValidatorDistribution(from, to)
 }
 
 func (Local) Genesis() (*ctypes.ResultGenesis, error) {
 	return core.Genesis()
 }
 
+func (Local) GenesisChunked(chunk int) (*ctypes.ResultGenesisChunk, error) {
+	return core.GenesisChunked(chunk)
+}
+
 func (Local) Block(height *int64) (*ctypes.ResultBlock, error) {
 	return core.Block(height)
 }
@@ -104,16 +164,36 @@ func (Local) Commit(height *int64) (*ctypes.ResultCommit, error) {
 	return core.Commit(height)
 }
 
-func (Local) Validators(height *int64) (*ctypes.ResultValidators, error) {
-	return core.Validators(height)
+func (Local) BlockResults(height *int64) (*ctypes.ResultBlockResults, error) {
+	return core.BlockResults(height)
+}
+
+func (Local) VerifyCommit(header *types.Header, commit *types.Commit, validators []*types.Validator) (*ctypes.ResultVerifyCommit, error) {
+	return core.VerifyCommit(header, commit, validators)
+}
+
+func (Local) RandomBeacon(height *int64) (*ctypes.ResultRandomBeacon, error) {
+	return core.RandomBeacon(height)
+}
+
+func (Local) Validators(height *int64, page, perPage int, orderBy string) (*ctypes.ResultValidators, error) {
+	return core.Validators(height, page, perPage, orderBy)
+}
+
+func (Local) ConsensusParams(height *int64) (*ctypes.ResultConsensusParams, error) {
+	return core.ConsensusParams(height)
+}
+
+func (Local) SigningInfo(address []byte, from, to int64) (*ctypes.ResultSigningInfo, error) {
+	return core.SigningInfo(address, from, to)
 }
 
 func (Local) Tx(hash []byte, prove bool) (*ctypes.ResultTx, error) {
 	return core.Tx(hash, prove)
 }
 
-func (Local) TxSearch(query string, prove bool) ([]*ctypes.ResultTx, error) {
-	return core.TxSearch(query, prove)
+func (Local) TxSearch(query string, prove bool, page, perPage int, orderBy string) (*ctypes.ResultTxSearch, error) {
+	return core.TxSearch(query, prove, page, perPage, orderBy)
 }
 
 func (c *Local) Subscribe(ctx context.Context, subscriber string, query tmpubsub.Query, out chan<- interface{}) error {
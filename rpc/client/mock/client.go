@@ -14,6 +14,8 @@ want to directly call a tendermint node in process, you can use the
 package mock
 
 import (
+	"context"
+
 	"reflect"
 
 	data "github.com/tendermint/go-wire/data"
@@ -88,7 +90,7 @@ func (c Client) ABCIQuery(path string, data data.Bytes) (*ctypes.ResultABCIQuery
 }
 
 func (c Client) ABCIQueryWithOptions(path string, data data.Bytes, opts client.ABCIQueryOptions) (*ctypes.ResultABCIQuery, error) {
-	return core.ABCIQuery(path, data, opts.Height, opts.Trusted)
+	return core.ABCIQuery(context.Background(), path, data, opts.Height, opts.Trusted)
 }
 
 func (c Client) BroadcastTxCommit(tx types.Tx) (*ctypes.ResultBroadcastTxCommit, error) {
@@ -96,11 +98,11 @@ func (c Client) BroadcastTxCommit(tx types.Tx) (*ctypes.ResultBroadcastTxCommit,
 }
 
 func (c Client) BroadcastTxAsync(tx types.Tx) (*ctypes.ResultBroadcastTx, error) {
-	return core.BroadcastTxAsync(tx)
+	return core.BroadcastTxAsync(tx, 0)
 }
 
 func (c Client) BroadcastTxSync(tx types.Tx) (*ctypes.ResultBroadcastTx, error) {
-	return core.BroadcastTxSync(tx)
+	return core.BroadcastTxSync(tx, 0)
 }
 
 func (c Client) NetInfo() (*ctypes.ResultNetInfo, error) {
@@ -111,14 +113,38 @@ func (c Client) DialSeeds(seeds []string) (*ctypes.ResultDialSeeds, error) {
 	return core.UnsafeDialSeeds(seeds)
 }
 
-func (c Client) BlockchainInfo(minHeight, maxHeight int64) (*ctypes.ResultBlockchainInfo, error) {
-	return core.BlockchainInfo(minHeight, maxHeight)
+func (c Client) BlockchainInfo(minHeight, maxHeight int64, page, perPage int, orderBy string) (*ctypes.ResultBlockchainInfo, error) {
+	return core.BlockchainInfo(minHeight, maxHeight, page, perPage, orderBy)
+}
+
+func (c Client) Headers(from, to int64) (*ctypes.ResultHeaders, error) {
+	return core.Headers(from, to)
+}
+
+func (c Client) AppHash(height *int64) (*ctypes.ResultAppHash, error) {
+	return core.AppHash(height)
+}
+
+func (c Client) AppHashes(from, to int64) (*ctypes.ResultAppHashes, error) {
+	return core.AppHashes(from, to)
+}
+
+func (c Client) BlockSearch(query string) (*ctypes.ResultBlockSearch, error) {
+	return core.BlockSearch(query)
+}
+
+func (c Client) ValidatorDistribution(from, to int64) (*ctypes.ResultValidatorDistribution, error) {
+	return core.ValidatorDistribution(from, to)
 }
 
 func (c Client) Genesis() (*ctypes.ResultGenesis, error) {
 	return core.Genesis()
 }
 
+func (c Client) GenesisChunked(chunk int) (*ctypes.ResultGenesisChunk, error) {
+	return core.GenesisChunked(chunk)
+}
+
 func (c Client) Block(height *int64) (*ctypes.ResultBlock, error) {
 	return core.Block(height)
 }
@@ -127,6 +153,26 @@ func (c Client) Commit(height *int64) (*ctypes.ResultCommit, error) {
 	return core.Commit(height)
 }
 
-func (c Client) Validators(height *int64) (*ctypes.ResultValidators, error) {
-	return core.Validators(height)
+func (c Client) BlockResults(height *int64) (*ctypes.ResultBlockResults, error) {
+	return core.BlockResults(height)
+}
+
+func (c Client) VerifyCommit(header *types.Header, commit *types.Commit, validators []*types.Validator) (*ctypes.ResultVerifyCommit, error) {
+	return core.VerifyCommit(header, commit, validators)
+}
+
+func (c Client) RandomBeacon(height *int64) (*ctypes.ResultRandomBeacon, error) {
+	return core.RandomBeacon(height)
+}
+
+func (c Client) Validators(height *int64, page, perPage int, orderBy string) (*ctypes.ResultValidators, error) {
+	return core.Validators(height, page, perPage, orderBy)
+}
+
+func (c Client) ConsensusParams(height *int64) (*ctypes.ResultConsensusParams, error) {
+	return core.ConsensusParams(height)
+}
+
+func (c Client) SigningInfo(address []byte, from, to int64) (*ctypes.ResultSigningInfo, error) {
+	return core.SigningInfo(address, from, to)
 }
@@ -0,0 +1,262 @@
+package client
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	abci "github.com/tendermint/abci/types"
+	wire "github.com/tendermint/go-wire"
+	"github.com/tendermint/go-wire/data"
+	"github.com/tendermint/iavl"
+
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+/*
+The proof of a query is a list of operations, each proving a key: eg. for an app
+with several stores, the key in the IAVL tree of a store, up to the root of the
+store, then the name of the store in the multi-store, up to the AppHash. Apps
+supporting such proofs encode their ProofOps in ResponseQuery.Proof, and the
+ones with a single IAVL tree the IAVL proof of the key, as the dummy app does.
+
+A ProofRuntime decodes the ProofOps with the decoders registered for their types,
+and verifies them against the AppHash, for the key path of the query.
+*/
+
+// ProofOp is an operation of a proof, decoded by the ProofOpDecoder registered
+// for its Type in a ProofRuntime.
+type ProofOp struct {
+	Type string     `json:"type"`
+	Key  data.Bytes `json:"key"`
+	Data data.Bytes `json:"data"`
+}
+
+// ProofOps are the operations of a proof, from the innermost key to the outermost.
+type ProofOps []ProofOp
+
+// Bytes returns the encoding of the ProofOps, for ResponseQuery.Proof.
+func (poz ProofOps) Bytes() []byte {
+	return wire.BinaryBytes(poz)
+}
+
+// ProofOpsFromBytes decodes the ProofOps encoded by ProofOps.Bytes.
+func ProofOpsFromBytes(bz []byte) (ProofOps, error) {
+	var poz ProofOps
+	err := wire.ReadBinaryBytes(bz, &poz)
+	return poz, err
+}
+
+// ProofOperator is a decoded ProofOp.
+type ProofOperator interface {
+	// Run verifies the args, eg. the value of the key, and returns the hashes they
+	// lead to, eg. the root of the tree, which are the args of the next operation.
+	Run(args [][]byte) ([][]byte, error)
+	// GetKey returns the key proven, or nil if the operation proves none.
+	GetKey() []byte
+	// ProofOp encodes the operation.
+	ProofOp() ProofOp
+}
+
+// ProofOperators are decoded ProofOps.
+type ProofOperators []ProofOperator
+
+// Verify runs the operations from args, and checks the keys of the key path are
+// the ones proven, from the last one, and the last operation leads to root.
+func (poz ProofOperators) Verify(root []byte, keypath string, args [][]byte) error {
+	keys, err := KeyPathToKeys(keypath)
+	if err != nil {
+		return err
+	}
+
+	for i, op := range poz {
+		key := op.GetKey()
+		if len(key) != 0 {
+			if len(keys) == 0 {
+				return errors.Errorf("Key path has no key left for %X", key)
+			}
+			lastKey := keys[len(keys)-1]
+			if !bytes.Equal(lastKey, key) {
+				return errors.Errorf("Key mismatch on operation #%d: expected %X but got %X", i, lastKey, key)
+			}
+			keys = keys[:len(keys)-1]
+		}
+		args, err = op.Run(args)
+		if err != nil {
+			return err
+		}
+	}
+	if len(keys) != 0 {
+		return errors.Errorf("Keys of the key path not proven: %d", len(keys))
+	}
+	if len(args) != 1 || !bytes.Equal(root, args[0]) {
+		return errors.Errorf("Calculated root hash is invalid: expected %X", root)
+	}
+	return nil
+}
+
+// ProofOpDecoder decodes a ProofOp of a given type.
+type ProofOpDecoder func(ProofOp) (ProofOperator, error)
+
+// ProofRuntime decodes and verifies the ProofOps.
+type ProofRuntime struct {
+	decoders map[string]ProofOpDecoder
+}
+
+// NewProofRuntime returns a ProofRuntime with no decoders.
+func NewProofRuntime() *ProofRuntime {
+	return &ProofRuntime{
+		decoders: make(map[string]ProofOpDecoder),
+	}
+}
+
+// DefaultProofRuntime returns a ProofRuntime decoding the IAVL proofs of the
+// keys of a store, and the simple merkle proofs of the stores of a multi-store.
+func DefaultProofRuntime() *ProofRuntime {
+	prt := NewProofRuntime()
+	prt.RegisterOpDecoder(ProofOpIAVLValue, IAVLValueOpDecoder)
+	prt.RegisterOpDecoder(ProofOpIAVLAbsence, IAVLAbsenceOpDecoder)
+	prt.RegisterOpDecoder(ProofOpSimpleValue, SimpleValueOpDecoder)
+	return prt
+}
+
+// RegisterOpDecoder registers the decoder of the ProofOps of type typ.
+// It panics if one is already registered.
+func (prt *ProofRuntime) RegisterOpDecoder(typ string, dec ProofOpDecoder) {
+	if _, ok := prt.decoders[typ]; ok {
+		panic("Already registered for type " + typ)
+	}
+	prt.decoders[typ] = dec
+}
+
+// Decode decodes a ProofOp with the decoder registered for its type.
+func (prt *ProofRuntime) Decode(pop ProofOp) (ProofOperator, error) {
+	decoder := prt.decoders[pop.Type]
+	if decoder == nil {
+		return nil, errors.Errorf("Unrecognized proof type %v", pop.Type)
+	}
+	return decoder(pop)
+}
+
+// DecodeProof decodes all the ProofOps.
+func (prt *ProofRuntime) DecodeProof(proof ProofOps) (ProofOperators, error) {
+	poz := make(ProofOperators, 0, len(proof))
+	for _, pop := range proof {
+		operator, err := prt.Decode(pop)
+		if err != nil {
+			return nil, errors.Wrap(err, "Decoding a proof operator")
+		}
+		poz = append(poz, operator)
+	}
+	return poz, nil
+}
+
+// VerifyValue verifies the proof the key path has the value, under root.
+func (prt *ProofRuntime) VerifyValue(proof ProofOps, root []byte, keypath string, value []byte) error {
+	return prt.Verify(proof, root, keypath, [][]byte{value})
+}
+
+// VerifyAbsence verifies the proof the key path has no value, under root.
+func (prt *ProofRuntime) VerifyAbsence(proof ProofOps, root []byte, keypath string) error {
+	return prt.Verify(proof, root, keypath, nil)
+}
+
+// Verify decodes the proof and verifies it from args, under root.
+func (prt *ProofRuntime) Verify(proof ProofOps, root []byte, keypath string, args [][]byte) error {
+	poz, err := prt.DecodeProof(proof)
+	if err != nil {
+		return err
+	}
+	return poz.Verify(root, keypath, args)
+}
+
+// decodeQueryProof decodes the proof of the response: its ProofOps, or else
+// the IAVL proof of its key.
+func (prt *ProofRuntime) decodeQueryProof(res abci.ResponseQuery) (ProofOperators, error) {
+	if proof, err := ProofOpsFromBytes(res.Proof); err == nil && len(proof) > 0 {
+		if poz, err := prt.DecodeProof(proof); err == nil {
+			return poz, nil
+		}
+	}
+
+	if len(res.Value) > 0 {
+		proof, err := iavl.ReadKeyExistsProof(res.Proof)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error reading proof")
+		}
+		return ProofOperators{NewIAVLValueOp(res.Key, proof)}, nil
+	}
+	proof, err := iavl.ReadKeyAbsentProof(res.Proof)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error reading proof")
+	}
+	return ProofOperators{NewIAVLAbsenceOp(res.Key, proof)}, nil
+}
+
+// VerifyABCIQuery verifies the proof of the response to the query of path,
+// against the AppHash of the header of the height after the response's one.
+// The key path proven is the key of the response, after the name of the store
+// if path is "/store/<name>/key", as for the apps with a multi-store.
+func VerifyABCIQuery(prt *ProofRuntime, path string, res abci.ResponseQuery, appHash []byte) error {
+	if len(res.Proof) == 0 {
+		return errors.New("No proof returned")
+	}
+	poz, err := prt.decodeQueryProof(res)
+	if err != nil {
+		return err
+	}
+
+	keyPath := KeyPath{}
+	if storeName, ok := queryStoreName(path); ok {
+		keyPath = keyPath.AppendKey([]byte(storeName), KeyEncodingURL)
+	}
+	keyPath = keyPath.AppendKey(res.Key, KeyEncodingURL)
+
+	var args [][]byte
+	if len(res.Value) > 0 {
+		args = [][]byte{res.Value}
+	}
+	if err := poz.Verify(appHash, keyPath.String(), args); err != nil {
+		return errors.Wrap(err, "Couldn't verify proof")
+	}
+	return nil
+}
+
+// queryStoreName returns the name of the store of a "/store/<name>/key" path.
+func queryStoreName(path string) (string, bool) {
+	if !strings.HasPrefix(path, "/store/") || !strings.HasSuffix(path, "/key") {
+		return "", false
+	}
+	name := strings.TrimSuffix(strings.TrimPrefix(path, "/store/"), "/key")
+	if name == "" || strings.Contains(name, "/") {
+		return "", false
+	}
+	return name, true
+}
+
+// signStatusClient gets the headers, waiting for them.
+type signStatusClient interface {
+	SignClient
+	StatusClient
+}
+
+// verifyABCIQuery verifies the proof of the response against the AppHash of the
+// header of the next height, as returned by the node, once it's committed.
+func verifyABCIQuery(c signStatusClient, path string, res *ctypes.ResultABCIQuery) error {
+	resp := res.Response
+	if resp.IsErr() {
+		// nothing to prove
+		return nil
+	}
+	// the AppHash of height H is in the header of H+1
+	h := resp.Height + 1
+	if err := WaitForHeight(c, h, nil); err != nil {
+		return err
+	}
+	commit, err := c.Commit(&h)
+	if err != nil {
+		return errors.Wrap(err, "Getting the header with the AppHash")
+	}
+	return VerifyABCIQuery(DefaultProofRuntime(), path, resp, commit.Header.AppHash)
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+/*
+A key path is the keys proven by the operations of a proof, from the outermost
+to the innermost, eg. "/bank/alice" for the key "alice" of the store "bank".
+Each key is escaped for a URL path, or written in hex after "x:" for binary keys,
+eg. "/bank/x:00FF".
+*/
+
+type keyEncoding int
+
+const (
+	KeyEncodingURL keyEncoding = iota
+	KeyEncodingHex
+)
+
+type key struct {
+	name []byte
+	enc  keyEncoding
+}
+
+// KeyPath builds the key path of a proof, see KeyPathToKeys.
+type KeyPath []key
+
+// AppendKey returns the key path with the key appended, written with the encoding.
+func (pth KeyPath) AppendKey(name []byte, enc keyEncoding) KeyPath {
+	return append(pth, key{name, enc})
+}
+
+func (pth KeyPath) String() string {
+	res := ""
+	for _, k := range pth {
+		switch k.enc {
+		case KeyEncodingURL:
+			res += "/" + url.PathEscape(string(k.name))
+		case KeyEncodingHex:
+			res += "/x:" + fmt.Sprintf("%X", k.name)
+		default:
+			panic("unexpected key encoding type")
+		}
+	}
+	return res
+}
+
+// KeyPathToKeys decodes the keys of a key path.
+func KeyPathToKeys(path string) ([][]byte, error) {
+	if path == "" || path[0] != '/' {
+		return nil, errors.New("Key path string must start with a forward slash '/'")
+	}
+	parts := strings.Split(path[1:], "/")
+	keys := make([][]byte, len(parts))
+	for i, part := range parts {
+		if strings.HasPrefix(part, "x:") {
+			hexPart := part[2:]
+			k, err := hex.DecodeString(hexPart)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Decoding hex-encoded part #%d: /%s", i, part)
+			}
+			keys[i] = k
+		} else {
+			k, err := url.PathUnescape(part)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Decoding url-encoded part #%d: /%s", i, part)
+			}
+			keys[i] = []byte(k)
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,204 @@
+package client
+
+import (
+	"github.com/pkg/errors"
+
+	wire "github.com/tendermint/go-wire"
+	"github.com/tendermint/iavl"
+	"github.com/tendermint/tmlibs/merkle"
+)
+
+const (
+	// ProofOpIAVLValue is the type of the IAVL proofs of the value of a key
+	ProofOpIAVLValue = "iavl:v"
+	// ProofOpIAVLAbsence is the type of the IAVL proofs of the absence of a key
+	ProofOpIAVLAbsence = "iavl:a"
+	// ProofOpSimpleValue is the type of the simple merkle proofs of a KVLeaf
+	ProofOpSimpleValue = "simple:v"
+)
+
+//----------------------------------------
+
+// IAVLValueOp proves the value of a key in an IAVL tree, leading to its root.
+type IAVLValueOp struct {
+	key   []byte
+	Proof *iavl.KeyExistsProof
+}
+
+var _ ProofOperator = IAVLValueOp{}
+
+// NewIAVLValueOp returns the IAVLValueOp of the key.
+func NewIAVLValueOp(key []byte, proof *iavl.KeyExistsProof) IAVLValueOp {
+	return IAVLValueOp{key: key, Proof: proof}
+}
+
+// IAVLValueOpDecoder decodes a ProofOpIAVLValue.
+func IAVLValueOpDecoder(pop ProofOp) (ProofOperator, error) {
+	if pop.Type != ProofOpIAVLValue {
+		return nil, errors.Errorf("Unexpected ProofOp.Type; got %v, want %v", pop.Type, ProofOpIAVLValue)
+	}
+	proof, err := iavl.ReadKeyExistsProof(pop.Data)
+	if err != nil {
+		return nil, errors.Wrap(err, "Decoding ProofOp.Data into IAVLValueOp")
+	}
+	return NewIAVLValueOp(pop.Key, proof), nil
+}
+
+// Run implements ProofOperator.
+func (op IAVLValueOp) Run(args [][]byte) ([][]byte, error) {
+	if len(args) != 1 {
+		return nil, errors.Errorf("Expected 1 arg, got %v", len(args))
+	}
+	if err := op.Proof.Verify(op.key, args[0], op.Proof.RootHash); err != nil {
+		return nil, errors.Wrap(err, "Verifying value")
+	}
+	return [][]byte{op.Proof.RootHash}, nil
+}
+
+// GetKey implements ProofOperator.
+func (op IAVLValueOp) GetKey() []byte {
+	return op.key
+}
+
+// ProofOp implements ProofOperator.
+func (op IAVLValueOp) ProofOp() ProofOp {
+	return ProofOp{
+		Type: ProofOpIAVLValue,
+		Key:  op.key,
+		Data: wire.BinaryBytes(op.Proof),
+	}
+}
+
+//----------------------------------------
+
+// IAVLAbsenceOp proves a key has no value in an IAVL tree, leading to its root.
+type IAVLAbsenceOp struct {
+	key   []byte
+	Proof *iavl.KeyAbsentProof
+}
+
+var _ ProofOperator = IAVLAbsenceOp{}
+
+// NewIAVLAbsenceOp returns the IAVLAbsenceOp of the key.
+func NewIAVLAbsenceOp(key []byte, proof *iavl.KeyAbsentProof) IAVLAbsenceOp {
+	return IAVLAbsenceOp{key: key, Proof: proof}
+}
+
+// IAVLAbsenceOpDecoder decodes a ProofOpIAVLAbsence.
+func IAVLAbsenceOpDecoder(pop ProofOp) (ProofOperator, error) {
+	if pop.Type != ProofOpIAVLAbsence {
+		return nil, errors.Errorf("Unexpected ProofOp.Type; got %v, want %v", pop.Type, ProofOpIAVLAbsence)
+	}
+	proof, err := iavl.ReadKeyAbsentProof(pop.Data)
+	if err != nil {
+		return nil, errors.Wrap(err, "Decoding ProofOp.Data into IAVLAbsenceOp")
+	}
+	return NewIAVLAbsenceOp(pop.Key, proof), nil
+}
+
+// Run implements ProofOperator.
+func (op IAVLAbsenceOp) Run(args [][]byte) ([][]byte, error) {
+	if len(args) != 0 {
+		return nil, errors.Errorf("Expected 0 args, got %v", len(args))
+	}
+	if err := op.Proof.Verify(op.key, nil, op.Proof.RootHash); err != nil {
+		return nil, errors.Wrap(err, "Verifying absence")
+	}
+	return [][]byte{op.Proof.RootHash}, nil
+}
+
+// GetKey implements ProofOperator.
+func (op IAVLAbsenceOp) GetKey() []byte {
+	return op.key
+}
+
+// ProofOp implements ProofOperator.
+func (op IAVLAbsenceOp) ProofOp() ProofOp {
+	return ProofOp{
+		Type: ProofOpIAVLAbsence,
+		Key:  op.key,
+		Data: wire.BinaryBytes(op.Proof),
+	}
+}
+
+//----------------------------------------
+
+// KVLeaf is a key-value pair hashed as a leaf of a simple merkle tree, eg. the
+// name and root hash of a store, the multi-store of an app being the simple
+// merkle tree of the KVLeafs of its stores, sorted by name.
+type KVLeaf struct {
+	Key   []byte
+	Value []byte
+}
+
+// Hash implements merkle.Hashable.
+func (kv KVLeaf) Hash() []byte {
+	return merkle.SimpleHashFromBinary(kv)
+}
+
+// SimpleValueOp proves the value of a key is in a simple merkle tree of KVLeafs,
+// leading to its root, eg. the root hash of a store in a multi-store.
+type SimpleValueOp struct {
+	key []byte
+
+	Index    int                 `json:"index"`
+	Total    int                 `json:"total"`
+	RootHash []byte              `json:"root_hash"`
+	Proof    *merkle.SimpleProof `json:"proof"`
+}
+
+var _ ProofOperator = SimpleValueOp{}
+
+// NewSimpleValueOp returns the SimpleValueOp of the key, the leaf index of total
+// under rootHash.
+func NewSimpleValueOp(key []byte, index, total int, rootHash []byte, proof *merkle.SimpleProof) SimpleValueOp {
+	return SimpleValueOp{
+		key:      key,
+		Index:    index,
+		Total:    total,
+		RootHash: rootHash,
+		Proof:    proof,
+	}
+}
+
+// SimpleValueOpDecoder decodes a ProofOpSimpleValue.
+func SimpleValueOpDecoder(pop ProofOp) (ProofOperator, error) {
+	if pop.Type != ProofOpSimpleValue {
+		return nil, errors.Errorf("Unexpected ProofOp.Type; got %v, want %v", pop.Type, ProofOpSimpleValue)
+	}
+	var op SimpleValueOp
+	if err := wire.ReadBinaryBytes(pop.Data, &op); err != nil {
+		return nil, errors.Wrap(err, "Decoding ProofOp.Data into SimpleValueOp")
+	}
+	op.key = pop.Key
+	if op.Proof == nil {
+		return nil, errors.New("SimpleValueOp has no proof")
+	}
+	return op, nil
+}
+
+// Run implements ProofOperator.
+func (op SimpleValueOp) Run(args [][]byte) ([][]byte, error) {
+	if len(args) != 1 {
+		return nil, errors.Errorf("Expected 1 arg, got %v", len(args))
+	}
+	leafHash := KVLeaf{op.key, args[0]}.Hash()
+	if !op.Proof.Verify(op.Index, op.Total, leafHash, op.RootHash) {
+		return nil, errors.Errorf("Leaf %X is not in the tree of root %X", op.key, op.RootHash)
+	}
+	return [][]byte{op.RootHash}, nil
+}
+
+// GetKey implements ProofOperator.
+func (op SimpleValueOp) GetKey() []byte {
+	return op.key
+}
+
+// ProofOp implements ProofOperator.
+func (op SimpleValueOp) ProofOp() ProofOp {
+	return ProofOp{
+		Type: ProofOpSimpleValue,
+		Key:  op.key,
+		Data: wire.BinaryBytes(op),
+	}
+}
@@ -0,0 +1,100 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/abci/types"
+	"github.com/tendermint/tmlibs/merkle"
+
+	"github.com/tendermint/tendermint/rpc/client"
+)
+
+// hashValueOp proves a value by its hash, the root of a store of one value.
+type hashValueOp struct {
+	key []byte
+}
+
+func (op hashValueOp) Run(args [][]byte) ([][]byte, error) {
+	if len(args) != 1 {
+		return nil, errors.New("Expected the value")
+	}
+	return [][]byte{merkle.SimpleHashFromBinary(args[0])}, nil
+}
+
+func (op hashValueOp) GetKey() []byte {
+	return op.key
+}
+
+func (op hashValueOp) ProofOp() client.ProofOp {
+	return client.ProofOp{Type: "test:hash", Key: op.key}
+}
+
+func TestProofRuntime(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	prt := client.DefaultProofRuntime()
+	prt.RegisterOpDecoder("test:hash", func(pop client.ProofOp) (client.ProofOperator, error) {
+		return hashValueOp{pop.Key}, nil
+	})
+
+	// a multi-store of 3 stores, "bank" holding the value of "alice"
+	value := []byte("100")
+	leaves := []merkle.Hashable{
+		client.KVLeaf{[]byte("acc"), []byte("acc-root")},
+		client.KVLeaf{[]byte("bank"), merkle.SimpleHashFromBinary(value)},
+		client.KVLeaf{[]byte("gov"), []byte("gov-root")},
+	}
+	appHash, proofs := merkle.SimpleProofsFromHashables(leaves)
+	proof := client.ProofOps{
+		hashValueOp{[]byte("alice")}.ProofOp(),
+		client.NewSimpleValueOp([]byte("bank"), 1, 3, appHash, proofs[1]).ProofOp(),
+	}
+	keyPath := client.KeyPath{}.
+		AppendKey([]byte("bank"), client.KeyEncodingURL).
+		AppendKey([]byte("alice"), client.KeyEncodingURL)
+
+	assert.Nil(prt.VerifyValue(proof, appHash, keyPath.String(), value))
+	assert.NotNil(prt.VerifyValue(proof, appHash, keyPath.String(), []byte("1000")))
+	assert.NotNil(prt.VerifyValue(proof, []byte("other-app-hash"), keyPath.String(), value))
+	assert.NotNil(prt.VerifyValue(proof, appHash, "/gov/alice", value))
+	assert.NotNil(prt.VerifyValue(proof, appHash, "/alice", value))
+	assert.NotNil(prt.VerifyAbsence(proof, appHash, keyPath.String()))
+
+	// without the decoder of an op
+	err := client.DefaultProofRuntime().VerifyValue(proof, appHash, keyPath.String(), value)
+	assert.NotNil(err)
+
+	// as returned by a query of the store
+	res := abci.ResponseQuery{Key: []byte("alice"), Value: value, Proof: proof.Bytes(), Height: 10}
+	decoded, err := client.ProofOpsFromBytes(res.Proof)
+	require.Nil(err)
+	assert.Equal(proof, decoded)
+	assert.Nil(client.VerifyABCIQuery(prt, "/store/bank/key", res, appHash))
+	assert.NotNil(client.VerifyABCIQuery(prt, "/store/gov/key", res, appHash))
+	res.Proof = nil
+	assert.NotNil(client.VerifyABCIQuery(prt, "/store/bank/key", res, appHash))
+}
+
+func TestKeyPath(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	keys := [][]byte{[]byte("bank"), []byte("a/b c"), {0x00, 0xff}}
+	path := client.KeyPath{}.
+		AppendKey(keys[0], client.KeyEncodingURL).
+		AppendKey(keys[1], client.KeyEncodingURL).
+		AppendKey(keys[2], client.KeyEncodingHex)
+	assert.Equal("/bank/a%2Fb%20c/x:00FF", path.String())
+
+	parsed, err := client.KeyPathToKeys(path.String())
+	require.Nil(err)
+	assert.Equal(keys, parsed)
+
+	_, err = client.KeyPathToKeys("bank")
+	assert.NotNil(err)
+	_, err = client.KeyPathToKeys("/x:0g")
+	assert.NotNil(err)
+}
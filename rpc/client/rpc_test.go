@@ -1,9 +1,14 @@
 package client_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -12,6 +17,8 @@ import (
 	"github.com/tendermint/iavl"
 
 	"github.com/tendermint/tendermint/rpc/client"
+	rpcclient "github.com/tendermint/tendermint/rpc/lib/client"
+	rpctypes "github.com/tendermint/tendermint/rpc/lib/types"
 	rpctest "github.com/tendermint/tendermint/rpc/test"
 	"github.com/tendermint/tendermint/types"
 )
@@ -76,6 +83,12 @@ func TestDumpConsensusState(t *testing.T) {
 		require.Nil(t, err, "%d: %+v", i, err)
 		assert.NotEmpty(t, cons.RoundState)
 		assert.Empty(t, cons.PeerRoundStates)
+
+		summary, err := nc.ConsensusState()
+		require.Nil(t, err, "%d: %+v", i, err)
+		require.NotNil(t, summary.RoundState)
+		assert.Equal(t, cons.RoundState.Validators.GetProposer().Address, summary.RoundState.Proposer)
+		assert.NotEmpty(t, summary.RoundState.Votes)
 	}
 }
 
@@ -85,22 +98,69 @@ func TestGenesisAndValidators(t *testing.T) {
 		// make sure this is the right genesis file
 		gen, err := c.Genesis()
 		require.Nil(t, err, "%d: %+v", i, err)
+		// which is in one chunk
+		fetched, err := client.FetchGenesis(c)
+		require.Nil(t, err, "%d: %+v", i, err)
+		assert.Equal(t, gen.Genesis.Hash(), fetched.Hash())
+
 		// get the genesis validator
 		require.Equal(t, 1, len(gen.Genesis.Validators))
 		gval := gen.Genesis.Validators[0]
 
 		// get the current validators
-		vals, err := c.Validators(nil)
+		vals, err := c.Validators(nil, 0, 0, "")
 		require.Nil(t, err, "%d: %+v", i, err)
 		require.Equal(t, 1, len(vals.Validators))
+		require.Equal(t, 1, vals.Total)
 		val := vals.Validators[0]
 
 		// make sure the current set is also the genesis set
 		assert.Equal(t, gval.Power, val.VotingPower)
 		assert.Equal(t, gval.PubKey, val.PubKey)
+
+		// which has all the voting power since the first block
+		dist, err := c.ValidatorDistribution(0, 0)
+		require.Nil(t, err, "%d: %+v", i, err)
+		if assert.Equal(t, 1, len(dist.Distributions)) {
+			assert.EqualValues(t, 1, dist.Distributions[0].Height)
+			assert.Equal(t, gval.Power, dist.Distributions[0].TotalVotingPower)
+			assert.Equal(t, 1, dist.Distributions[0].NakamotoCoefficient)
+		}
+
+		// and the params of the genesis are still in effect
+		params, err := c.ConsensusParams(nil)
+		require.Nil(t, err, "%d: %+v", i, err)
+		assert.Equal(t, *gen.Genesis.ConsensusParams, params.ConsensusParams)
+		h := int64(1)
+		params, err = c.ConsensusParams(&h)
+		require.Nil(t, err, "%d: %+v", i, err)
+		assert.EqualValues(t, 1, params.BlockHeight)
+		assert.Equal(t, *gen.Genesis.ConsensusParams, params.ConsensusParams)
 	}
 }
 
+func TestBatchedCalls(t *testing.T) {
+	batch := getHTTPClient().NewBatch()
+
+	// the results are filled in once sent
+	status, err := batch.Status()
+	require.Nil(t, err)
+	gen, err := batch.Genesis()
+	require.Nil(t, err)
+	vals, err := batch.Validators(nil, 0, 0, "")
+	require.Nil(t, err)
+	require.Equal(t, 3, batch.Count())
+
+	results, err := batch.Send()
+	require.Nil(t, err)
+	require.Equal(t, 3, len(results))
+	assert.Equal(t, 0, batch.Count())
+	assert.Equal(t, status, results[0])
+	assert.Equal(t, gen.Genesis.ChainID, status.NodeInfo.Network)
+	require.Equal(t, 1, len(vals.Validators))
+	assert.Equal(t, gen.Genesis.Validators[0].PubKey, vals.Validators[0].PubKey)
+}
+
 func TestABCIQuery(t *testing.T) {
 	for i, c := range GetClients() {
 		// write something
@@ -161,6 +221,15 @@ func TestAppCalls(t *testing.T) {
 		assert.EqualValues(txh, ptx.Height)
 		assert.EqualValues(tx, ptx.Tx)
 
+		// and get the result of its execution
+		results, err := c.BlockResults(&txh)
+		require.Nil(err, "%d: %+v", i, err)
+		assert.EqualValues(txh, results.Height)
+		if assert.Equal(1, len(results.DeliverTx)) {
+			assert.True(results.DeliverTx[0].IsOK())
+		}
+		assert.Nil(results.ValidatorUpdates)
+
 		// and we can even check the block is added
 		block, err := c.Block(&apph)
 		require.Nil(err, "%d: %+v", i, err)
@@ -169,11 +238,10 @@ func TestAppCalls(t *testing.T) {
 		assert.EqualValues(apph, block.BlockMeta.Header.Height)
 
 		// check blockchain info, now that we know there is info
-		// TODO: is this commented somewhere that they are returned
-		// in order of descending height???
-		info, err := c.BlockchainInfo(apph, apph)
+		info, err := c.BlockchainInfo(apph, apph, 0, 0, "")
 		require.Nil(err, "%d: %+v", i, err)
 		assert.True(info.LastHeight >= apph)
+		assert.Equal(1, info.Total)
 		if assert.Equal(1, len(info.BlockMetas)) {
 			lastMeta := info.BlockMetas[0]
 			assert.EqualValues(apph, lastMeta.Header.Height)
@@ -182,6 +250,31 @@ func TestAppCalls(t *testing.T) {
 			assert.Equal(bMeta.BlockID, lastMeta.BlockID)
 		}
 
+		// the block metas are in decreasing height by default, a page at a time
+		info, err = c.BlockchainInfo(apph-1, apph, 2, 1, "")
+		require.Nil(err, "%d: %+v", i, err)
+		assert.Equal(2, info.Total)
+		if assert.Equal(1, len(info.BlockMetas)) {
+			assert.EqualValues(apph-1, info.BlockMetas[0].Header.Height)
+		}
+		info, err = c.BlockchainInfo(apph-1, apph, 1, 1, "asc")
+		require.Nil(err, "%d: %+v", i, err)
+		if assert.Equal(1, len(info.BlockMetas)) {
+			assert.EqualValues(apph-1, info.BlockMetas[0].Header.Height)
+		}
+		_, err = c.BlockchainInfo(apph-1, apph, 3, 1, "")
+		assert.NotNil(err, "%d: page past the last one", i)
+
+		// the headers are the same block metas, in increasing height
+		headers, err := c.Headers(apph-1, apph)
+		require.Nil(err, "%d: %+v", i, err)
+		assert.True(headers.LastHeight >= apph)
+		if assert.Equal(2, len(headers.BlockMetas)) {
+			assert.EqualValues(apph-1, headers.BlockMetas[0].Header.Height)
+			assert.Equal(block.BlockMeta, headers.BlockMetas[1])
+			assert.True(headers.BlockMetas[1].BlockSize > 0)
+		}
+
 		// and get the corresponding commit with the same apphash
 		commit, err := c.Commit(&apph)
 		require.Nil(err, "%d: %+v", i, err)
@@ -195,6 +288,33 @@ func TestAppCalls(t *testing.T) {
 		require.Nil(err, "%d: %+v", i, err)
 		assert.Equal(block.Block.LastCommit, commit2.Commit)
 
+		// which verifies for its header and validators, supplied by the client
+		vals, err := c.Validators(&apph, 0, 0, "")
+		require.Nil(err, "%d: %+v", i, err)
+		vres, err := c.VerifyCommit(commit.Header, commit.Commit, vals.Validators)
+		require.Nil(err, "%d: %+v", i, err)
+		assert.True(vres.Valid, "%d: %v", i, vres.Failures)
+		vres, err = c.VerifyCommit(commit2.Header, commit.Commit, vals.Validators)
+		require.Nil(err, "%d: %+v", i, err)
+		assert.False(vres.Valid)
+		assert.NotEmpty(vres.Failures)
+
+		// the beacon is derived from the last commit
+		beacon, err := c.RandomBeacon(&apph)
+		require.Nil(err, "%d: %+v", i, err)
+		assert.EqualValues(commit2.Commit.RandomBeacon(), beacon.RandomBeacon)
+		assert.EqualValues(block.BlockMeta.Header.RandomBeacon, beacon.RandomBeacon)
+
+		// the app hashes of the headers are indexed
+		ahres, err := c.AppHash(&apph)
+		require.Nil(err, "%d: %+v", i, err)
+		assert.EqualValues(appHash, ahres.AppHash)
+		appHashes, err := c.AppHashes(apph-1, apph)
+		require.Nil(err, "%d: %+v", i, err)
+		if assert.Equal(2, len(appHashes.AppHashes)) {
+			assert.EqualValues(block.BlockMeta.Header.AppHash, appHashes.AppHashes[1])
+		}
+
 		// and we got a proof that works!
 		_pres, err := c.ABCIQueryWithOptions("/key", k, client.ABCIQueryOptions{Trusted: false})
 		pres := _pres.Response
@@ -208,6 +328,12 @@ func TestAppCalls(t *testing.T) {
 				assert.Nil(valid)
 			}
 		}
+
+		// which the client can verify itself
+		_pres, err = c.ABCIQueryWithOptions("/key", k, client.ABCIQueryOptions{Prove: true})
+		if assert.Nil(err, "%d: %+v", i, err) {
+			assert.EqualValues(v, _pres.Response.Value)
+		}
 	}
 }
 
@@ -293,8 +419,13 @@ func TestTx(t *testing.T) {
 
 				// time to verify the proof
 				proof := ptx.Proof
-				if tc.prove && assert.EqualValues(tx, proof.Data) {
+				if !tc.prove {
+					assert.Nil(proof)
+				} else if assert.NotNil(proof) && assert.EqualValues(tx, proof.Data) {
 					assert.True(proof.Proof.Verify(proof.Index, proof.Total, txHash, proof.RootHash))
+					block, err := c.Block(&txHeight)
+					require.Nil(err, "%+v", err)
+					assert.Nil(proof.Validate(block.Block.DataHash))
 				}
 			}
 		}
@@ -318,11 +449,12 @@ func TestTxSearch(t *testing.T) {
 
 		// now we query for the tx.
 		// since there's only one tx, we know index=0.
-		results, err := c.TxSearch(fmt.Sprintf("tx.hash='%v'", txHash), true)
+		result, err := c.TxSearch(fmt.Sprintf("tx.hash='%v'", txHash), true, 0, 0, "")
 		require.Nil(t, err, "%+v", err)
-		require.Len(t, results, 1)
+		require.Len(t, result.Txs, 1)
+		assert.Equal(t, 1, result.Total)
 
-		ptx := results[0]
+		ptx := result.Txs[0]
 		assert.EqualValues(t, txHeight, ptx.Height)
 		assert.EqualValues(t, tx, ptx.Tx)
 		assert.Zero(t, ptx.Index)
@@ -330,20 +462,116 @@ func TestTxSearch(t *testing.T) {
 
 		// time to verify the proof
 		proof := ptx.Proof
-		if assert.EqualValues(t, tx, proof.Data) {
+		if assert.NotNil(t, proof) && assert.EqualValues(t, tx, proof.Data) {
 			assert.True(t, proof.Proof.Verify(proof.Index, proof.Total, txHash, proof.RootHash))
+			block, err := c.Block(&txHeight)
+			require.Nil(t, err, "%+v", err)
+			assert.Nil(t, proof.Validate(block.Block.DataHash))
 		}
 
 		// we query for non existing tx
-		results, err = c.TxSearch(fmt.Sprintf("tx.hash='%X'", anotherTxHash), false)
+		result, err = c.TxSearch(fmt.Sprintf("tx.hash='%X'", anotherTxHash), false, 0, 0, "")
 		require.Nil(t, err, "%+v", err)
-		require.Len(t, results, 0)
+		require.Len(t, result.Txs, 0)
+		assert.Equal(t, 0, result.Total)
 
 		// we query using a tag (see dummy application)
-		results, err = c.TxSearch("app.creator='jae'", false)
+		result, err = c.TxSearch("app.creator='jae'", false, 0, 0, "")
 		require.Nil(t, err, "%+v", err)
-		if len(results) == 0 {
+		if len(result.Txs) == 0 {
 			t.Fatal("expected a lot of transactions")
 		}
+
+		// the pages of the txs, in decreasing height
+		first, err := c.TxSearch("app.creator='jae'", false, 1, 1, "desc")
+		require.Nil(t, err, "%+v", err)
+		require.Len(t, first.Txs, 1)
+		assert.Equal(t, result.Total, first.Total)
+		assert.Equal(t, result.Txs[len(result.Txs)-1].Height, first.Txs[0].Height)
+	}
+}
+
+func TestBlockSearch(t *testing.T) {
+	// commit a block with a tx
+	c := getHTTPClient()
+	_, _, tx := MakeTxKV()
+	bres, err := c.BroadcastTxCommit(tx)
+	require.Nil(t, err, "%+v", err)
+	txHeight := bres.Height
+	// the blocks are indexed asynchronously, as they are committed
+	require.Nil(t, client.WaitForHeight(c, txHeight+1, nil))
+
+	for i, c := range GetClients() {
+		t.Logf("client %d", i)
+
+		res, err := c.BlockSearch(fmt.Sprintf("block.height = %d", txHeight))
+		require.Nil(t, err, "%+v", err)
+		require.Len(t, res.BlockMetas, 1)
+		assert.EqualValues(t, txHeight, res.BlockMetas[0].Header.Height)
+		assert.EqualValues(t, 1, res.BlockMetas[0].Header.NumTxs)
+
+		res, err = c.BlockSearch(fmt.Sprintf("block.num_txs > 0 AND block.height <= %d", txHeight))
+		require.Nil(t, err, "%+v", err)
+		require.NotEmpty(t, res.BlockMetas)
+		for _, meta := range res.BlockMetas {
+			assert.True(t, meta.Header.NumTxs > 0)
+			assert.True(t, meta.Header.Height <= txHeight)
+		}
+
+		_, err = c.BlockSearch("block.height >")
+		assert.NotNil(t, err)
+	}
+}
+
+// callPositional calls the method over HTTP with positional params, as the
+// clients written before its last params were added do.
+func callPositional(t *testing.T, method string, params ...interface{}) *rpctypes.RPCResponse {
+	request, err := rpctypes.ArrayToRequest("positional", method, params)
+	require.Nil(t, err)
+	body, err := json.Marshal(request)
+	require.Nil(t, err)
+	addr := strings.Replace(rpctest.GetConfig().RPC.ListenAddress, "tcp://", "http://", 1)
+	res, err := http.Post(addr, "application/json", bytes.NewReader(body))
+	require.Nil(t, err)
+	defer res.Body.Close() // nolint: errcheck
+
+	response := new(rpctypes.RPCResponse)
+	require.Nil(t, json.NewDecoder(res.Body).Decode(response))
+	return response
+}
+
+// The params added to the routes are optional for the positional callers.
+func TestPositionalParams(t *testing.T) {
+	for _, call := range []struct {
+		method string
+		params []interface{}
+	}{
+		{"blockchain", []interface{}{1, 1}},
+		{"tx_search", []interface{}{"app.creator='jae'", false}},
+		{"validators", []interface{}{1}},
+		{"unconfirmed_txs", []interface{}{}},
+	} {
+		res := callPositional(t, call.method, call.params...)
+		assert.Nil(t, res.Error, "%s %v", call.method, call.params)
+		assert.NotEmpty(t, res.Result, "%s %v", call.method, call.params)
+	}
+}
+
+// The params added to subscribe are optional for the positional callers.
+func TestPositionalSubscribe(t *testing.T) {
+	for _, params := range [][]interface{}{
+		{"tm.event='Unlock'"},
+		{"tm.event='Unlock'", 0},
+	} {
+		ws := rpcclient.NewWSClient(rpctest.GetConfig().RPC.ListenAddress, "/websocket")
+		require.Nil(t, ws.Start())
+		require.Nil(t, ws.CallWithArrayParams(context.Background(), "subscribe", params))
+		select {
+		case res := <-ws.ResponsesCh:
+			assert.Nil(t, res.Error, "%v", params)
+		case <-time.After(5 * time.Second):
+			t.Errorf("No response to subscribe %v", params)
+		}
+		ws.Stop()
 	}
 }
@@ -5,6 +5,10 @@ package client
 type ABCIQueryOptions struct {
 	Height  int64
 	Trusted bool
+	// Prove requests a proof, whatever Trusted, and verifies it against the AppHash
+	// of the header of the next height, waiting for it. The header is the node's:
+	// certify it with the lite client to trust the result (see lite/proxy).
+	Prove bool
 }
 
 // DefaultABCIQueryOptions are latest height (0) and trusted equal to false
@@ -1,8 +1,16 @@
 package core
 
 import (
+	"context"
+	"fmt"
+	pathpkg "path"
+	"strings"
+
+	"github.com/pkg/errors"
+
 	abci "github.com/tendermint/abci/types"
 	data "github.com/tendermint/go-wire/data"
+	bc "github.com/tendermint/tendermint/blockchain"
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 	"github.com/tendermint/tendermint/version"
 )
@@ -47,8 +55,27 @@ import (
 // | data      | []byte | false   | true     | Data                                           |
 // | height    | int64 | 0       | false    | Height (0 means latest)                        |
 // | trusted   | bool   | false   | false    | Does not include a proof of the data inclusion |
-func ABCIQuery(path string, data data.Bytes, height int64, trusted bool) (*ctypes.ResultABCIQuery, error) {
-	resQuery, err := proxyAppQuery.QuerySync(abci.RequestQuery{
+//
+// The query is abandoned if the client disconnects or if the app doesn't answer
+// within `rpc.timeout_abci_query`.
+//
+// A query at a height is answered by the app with its state at that height, so
+// it fails without asking the app if the height is not yet committed, or before
+// the last `app_retain_heights` ones the app keeps, with the error
+// `height X pruned, earliest available is Y`; and if the app answers with the
+// state at another height.
+//
+// The paths the node itself queries the app with are rejected, as some of them
+// change the state of the app: `/snapshots/...` to restore a snapshot with state
+// sync, and `/retain_height`.
+func ABCIQuery(ctx context.Context, path string, data data.Bytes, height int64, trusted bool) (*ctypes.ResultABCIQuery, error) {
+	if isReservedQueryPath(path) {
+		return nil, fmt.Errorf("Query path %q is reserved to the node", path)
+	}
+	if err := validateQueryHeight(height); err != nil {
+		return nil, err
+	}
+	resQuery, err := abciQuery(ctx, abci.RequestQuery{
 		Path:   path,
 		Data:   data,
 		Height: height,
@@ -57,10 +84,100 @@ func ABCIQuery(path string, data data.Bytes, height int64, trusted bool) (*ctype
 	if err != nil {
 		return nil, err
 	}
+	if height > 0 && resQuery.Height != 0 && resQuery.Height != height {
+		return nil, fmt.Errorf("The app answered the query at height %d with its state at height %d", height, resQuery.Height)
+	}
 	logger.Info("ABCIQuery", "path", path, "data", data, "result", resQuery)
 	return &ctypes.ResultABCIQuery{*resQuery}, nil
 }
 
+// ErrHeightPruned is the error of a query at a height whose state the app no
+// longer keeps.
+type ErrHeightPruned struct {
+	Height   int64
+	Earliest int64
+}
+
+func (e ErrHeightPruned) Error() string {
+	return fmt.Sprintf("height %d pruned, earliest available is %d", e.Height, e.Earliest)
+}
+
+// queryHeights returns the earliest and the latest heights the app keeps the
+// state of, or a latest of 0 if none is committed yet.
+func queryHeights() (earliest, latest int64) {
+	latest = blockStore.Height()
+	earliest = 1
+	if appRetainHeights > 0 && latest-appRetainHeights+1 > earliest {
+		earliest = latest - appRetainHeights + 1
+	}
+	return earliest, latest
+}
+
+// isReservedQueryPath returns true if the path is one of the statesync or
+// blockchain ones, only queried by the node.
+func isReservedQueryPath(path string) bool {
+	path = pathpkg.Clean("/" + path)
+	return path == "/snapshots" || strings.HasPrefix(path, "/snapshots/") || path == bc.QueryPathRetainHeight
+}
+
+// validateQueryHeight returns an error if the height, unless 0 for the latest,
+// is not one the app keeps the state of.
+func validateQueryHeight(height int64) error {
+	if height == 0 {
+		return nil
+	}
+	if height < 0 {
+		return fmt.Errorf("Height must be greater than or equal to 0")
+	}
+	earliest, latest := queryHeights()
+	if height > latest {
+		return fmt.Errorf("Height must be less than or equal to the current blockchain height %d", latest)
+	}
+	if height < earliest {
+		return ErrHeightPruned{Height: height, Earliest: earliest}
+	}
+	return nil
+}
+
+// abciQuery runs the query on the query connection, unless ctx is done first.
+// An abandoned query still holds its slot until the app answers, so that no more
+// than the max number of queries ever pile up on the connection.
+func abciQuery(ctx context.Context, req abci.RequestQuery) (*abci.ResponseQuery, error) {
+	if abciQueryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, abciQueryTimeout)
+		defer cancel()
+	}
+
+	if abciQuerySlots != nil {
+		select {
+		case abciQuerySlots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), "Gave up waiting for a free query connection slot")
+		}
+	}
+
+	type result struct {
+		res *abci.ResponseQuery
+		err error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		if abciQuerySlots != nil {
+			defer func() { <-abciQuerySlots }()
+		}
+		res, err := proxyAppQuery.QuerySync(req)
+		resCh <- result{res, err}
+	}()
+
+	select {
+	case r := <-resCh:
+		return r.res, r.err
+	case <-ctx.Done():
+		return nil, errors.Wrap(ctx.Err(), "Gave up waiting for the app to answer the query")
+	}
+}
+
 // Get some info about the application.
 //
 // ```shell
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// heightStore is a block store of which only the height is known.
+type heightStore struct {
+	types.BlockStore
+	height int64
+}
+
+func (s heightStore) Height() int64 { return s.height }
+
+func TestValidateQueryHeight(t *testing.T) {
+	defer func(bs types.BlockStore, retain int64) {
+		blockStore, appRetainHeights = bs, retain
+	}(blockStore, appRetainHeights)
+	blockStore = heightStore{height: 10}
+
+	SetAppRetainHeights(0)
+	for _, height := range []int64{0, 1, 10} {
+		assert.Nil(t, validateQueryHeight(height), "height %d", height)
+	}
+	assert.NotNil(t, validateQueryHeight(-1))
+	assert.NotNil(t, validateQueryHeight(11), "not committed yet")
+
+	SetAppRetainHeights(3)
+	for _, height := range []int64{0, 8, 10} {
+		assert.Nil(t, validateQueryHeight(height), "height %d", height)
+	}
+	err := validateQueryHeight(7)
+	assert.Equal(t, ErrHeightPruned{Height: 7, Earliest: 8}, err)
+	assert.EqualError(t, err, "height 7 pruned, earliest available is 8")
+
+	// more retained than committed
+	SetAppRetainHeights(100)
+	assert.Nil(t, validateQueryHeight(1))
+}
+
+func TestIsReservedQueryPath(t *testing.T) {
+	for _, path := range []string{"/snapshots/offer", "/snapshots/apply_chunk", "snapshots/list",
+		"/store/../snapshots/offer", "//snapshots/apply_chunk", "/retain_height"} {
+		assert.True(t, isReservedQueryPath(path), path)
+	}
+	for _, path := range []string{"", "/store", "/snapshotsx", "/retain_heights"} {
+		assert.False(t, isReservedQueryPath(path), path)
+	}
+}
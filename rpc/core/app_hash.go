@@ -0,0 +1,126 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/tendermint/go-wire/data"
+
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+// maxAppHashHeights is the max number of heights of an /app_hashes query.
+const maxAppHashHeights = 10000
+
+// AppHash returns the AppHash of the header at a height, from the app hash
+// index, without loading the header. The AppHash of the header at height H is
+// the one of the state of the app after the block H-1, and the commit of the
+// height, from `/commit`, proves it. If no height is provided, it returns the
+// one of the last height indexed.
+//
+// ```shell
+// curl 'localhost:46657/app_hash?height=10'
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:46657", "/websocket")
+// info, err := client.AppHash(10)
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+// 	"error": "",
+// 	"result": {
+// 		"height": 10,
+// 		"app_hash": "0A2C4F1B9E0D6F3E8C7B5A4D3C2B1A0F9E8D7C6B"
+// 	},
+// 	"id": "",
+// 	"jsonrpc": "2.0"
+// }
+// ```
+func AppHash(heightPtr *int64) (*ctypes.ResultAppHash, error) {
+	if appHashStore == nil {
+		return nil, fmt.Errorf("The app hashes are not indexed (see index_app_hashes)")
+	}
+	lastHeight := appHashStore.LastHeight()
+	height := lastHeight
+	if heightPtr != nil {
+		height = *heightPtr
+		if height <= 0 {
+			return nil, fmt.Errorf("Height must be greater than 0")
+		}
+		if height > lastHeight {
+			return nil, fmt.Errorf("Height must be less than or equal to the last height indexed, %d", lastHeight)
+		}
+	}
+	if height == 0 {
+		return nil, fmt.Errorf("No app hash indexed yet")
+	}
+	return &ctypes.ResultAppHash{height, appHashStore.AppHash(height)}, nil
+}
+
+// AppHashes returns the app hashes of the headers of a range of heights, from
+// the app hash index, for the bridges and the audit tools which only need the
+// app hashes.
+//
+// ```shell
+// curl 'localhost:46657/app_hashes?from=1&to=3'
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:46657", "/websocket")
+// info, err := client.AppHashes(1, 3)
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+// 	"error": "",
+// 	"result": {
+// 		"from": 1,
+// 		"to": 3,
+// 		"app_hashes": [
+// 			"",
+// 			"0A2C4F1B9E0D6F3E8C7B5A4D3C2B1A0F9E8D7C6B",
+// 			"5E1F0B2A3C4D6E7F8091A2B3C4D5E6F708192A3B"
+// 		],
+// 		"last_height": 5240
+// 	},
+// 	"id": "",
+// 	"jsonrpc": "2.0"
+// }
+// ```
+//
+// ### Query Parameters
+//
+// | Parameter | Type  | Default     | Required | Description                          |
+// |-----------+-------+-------------+----------+--------------------------------------|
+// | from      | int64 | 1           | false    | The first height                     |
+// | to        | int64 | last height | false    | The last height, at most 10000 after |
+//
+// The app hashes are in increasing height, from `from` to `to` included.
+func AppHashes(from, to int64) (*ctypes.ResultAppHashes, error) {
+	if appHashStore == nil {
+		return nil, fmt.Errorf("The app hashes are not indexed (see index_app_hashes)")
+	}
+
+	lastHeight := appHashStore.LastHeight()
+	if from <= 0 {
+		from = 1
+	}
+	if to <= 0 || to > lastHeight {
+		to = lastHeight
+	}
+	if to-from >= maxAppHashHeights {
+		return nil, fmt.Errorf("At most %d heights can be queried at once", maxAppHashHeights)
+	}
+
+	hashes := appHashStore.AppHashes(from, to)
+	appHashes := make([]data.Bytes, len(hashes))
+	for i, hash := range hashes {
+		appHashes[i] = hash
+	}
+	return &ctypes.ResultAppHashes{from, to, appHashes, lastHeight}, nil
+}
@@ -3,20 +3,29 @@ package core
 import (
 	"fmt"
 
+	abci "github.com/tendermint/abci/types"
+
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 	"github.com/tendermint/tendermint/types"
+	tmquery "github.com/tendermint/tendermint/types/query"
 	cmn "github.com/tendermint/tmlibs/common"
 )
 
-// Get block headers for minHeight <= height <= maxHeight.
+// defaultBlockchainPerPage is the default number of block metas per page of BlockchainInfo.
+const defaultBlockchainPerPage = 20
+
+// Get block headers for minHeight <= height <= maxHeight, a page at a time, in
+// decreasing height by default, and the number of blocks in the range.
+// If maxHeight is 0 or past the last block, it's the last block. If minHeight
+// is 0, it's 1, and below the base of the pruned blocks, the base.
 //
 // ```shell
-// curl 'localhost:46657/blockchain?minHeight=10&maxHeight=10'
+// curl 'localhost:46657/blockchain?minHeight=10&maxHeight=100&page=2&per_page=20&order_by="asc"'
 // ```
 //
 // ```go
 // client := client.NewHTTP("tcp://0.0.0.0:46657", "/websocket")
-// info, err := client.BlockchainInfo(10, 10)
+// info, err := client.BlockchainInfo(10, 100, 2, 20, "asc")
 // ```
 //
 // > The above command returns JSON structured like this:
@@ -53,15 +62,31 @@ import (
 // 				}
 // 			}
 // 		],
-// 		"last_height": 5493
+// 		"last_height": 5493,
+// 		"total": 91
 // 	},
 // 	"id": "",
 // 	"jsonrpc": "2.0"
 // }
 // ```
 //
-// <aside class="notice">Returns at most 20 items.</aside>
-func BlockchainInfo(minHeight, maxHeight int64) (*ctypes.ResultBlockchainInfo, error) {
+// ### Query Parameters
+//
+// | Parameter | Type   | Default | Required | Description                                 |
+// |-----------+--------+---------+----------+---------------------------------------------|
+// | minHeight | int64  | 1       | false    | Lowest height of the range                  |
+// | maxHeight | int64  | 0       | false    | Highest height of the range, 0 for the last |
+// | page      | int    | 1       | false    | Page of the blocks, from 1                  |
+// | per_page  | int    | 20      | false    | Number of blocks per page, at most 100      |
+// | order_by  | string | "desc"  | false    | Order of the heights, "asc" or "desc"       |
+func BlockchainInfo(minHeight, maxHeight int64, page, perPage int, orderBy string) (*ctypes.ResultBlockchainInfo, error) {
+	orderBy, err := validateOrder(orderBy, orderDesc, orderAsc, orderDesc)
+	if err != nil {
+		return nil, err
+	}
+
+	// the blocks before the base are pruned
+	minHeight = cmn.MaxInt64(minHeight, blockStore.Base())
 	if minHeight == 0 {
 		minHeight = 1
 	}
@@ -72,23 +97,112 @@ func BlockchainInfo(minHeight, maxHeight int64) (*ctypes.ResultBlockchainInfo, e
 		maxHeight = cmn.MinInt64(blockStore.Height(), maxHeight)
 	}
 
-	// maximum 20 block metas
-	const limit int64 = 20
-	minHeight = cmn.MaxInt64(minHeight, maxHeight-limit)
-
 	logger.Debug("BlockchainInfoHandler", "maxHeight", maxHeight, "minHeight", minHeight)
 
 	if minHeight > maxHeight {
 		return nil, fmt.Errorf("min height %d can't be greater than max height %d", minHeight, maxHeight)
 	}
 
+	total := int(maxHeight - minHeight + 1)
+	start, end, err := paginate(page, perPage, defaultBlockchainPerPage, total)
+	if err != nil {
+		return nil, err
+	}
+
 	blockMetas := []*types.BlockMeta{}
-	for height := maxHeight; height >= minHeight; height-- {
-		blockMeta := blockStore.LoadBlockMeta(height)
-		blockMetas = append(blockMetas, blockMeta)
+	for i := start; i < end; i++ {
+		height := maxHeight - int64(i)
+		if orderBy == orderAsc {
+			height = minHeight + int64(i)
+		}
+		blockMetas = append(blockMetas, blockStore.LoadBlockMeta(height))
 	}
 
-	return &ctypes.ResultBlockchainInfo{blockStore.Height(), blockMetas}, nil
+	return &ctypes.ResultBlockchainInfo{blockStore.Height(), blockMetas, total}, nil
+}
+
+// maxHeaders is the max number of block metas returned by Headers.
+const maxHeaders = 100
+
+// Get the block metas for from <= height <= to, in increasing height: the
+// header, block ID and size of the blocks, without loading the blocks,
+// eg. for explorers listing the blocks.
+// If to is 0 or past the last block, it's the last block. If from is 0,
+// the last 100 block metas up to to are returned.
+//
+// ```shell
+// curl 'localhost:46657/headers?from=10&to=11'
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:46657", "/websocket")
+// info, err := client.Headers(10, 11)
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+// 	"error": "",
+// 	"result": {
+// 		"block_metas": [
+// 			{
+// 				"header": {
+// 					"app_hash": "",
+// 					"chain_id": "test-chain-6UTNIN",
+// 					"height": 10,
+// 					"time": "2017-05-29T15:05:53.877Z",
+// 					"num_txs": 0,
+// 					"last_block_id": {
+// 						"parts": {
+// 							"hash": "3C78F00658E06744A88F24FF97A0A5011139F34A",
+// 							"total": 1
+// 						},
+// 						"hash": "F70588DAB36BDA5A953D548A16F7D48C6C2DFD78"
+// 					},
+// 					"last_commit_hash": "F31CC4282E50B3F2A58D763D233D76F26D26CABE",
+// 					"data_hash": "",
+// 					"validators_hash": "9365FC80F234C967BD233F5A3E2AB2F1E4B0E5AA"
+// 				},
+// 				"block_id": {
+// 					"parts": {
+// 						"hash": "277A4DBEF91483A18B85F2F5677ABF9694DFA40F",
+// 						"total": 1
+// 					},
+// 					"hash": "96B1D2F2D201BA4BC383EB8224139DB1294944E5"
+// 				},
+// 				"block_size": 573
+// 			},
+// 			...
+// 		],
+// 		"last_height": 5493
+// 	},
+// 	"id": "",
+// 	"jsonrpc": "2.0"
+// }
+// ```
+//
+// <aside class="notice">Returns at most 100 items.</aside>
+func Headers(from, to int64) (*ctypes.ResultHeaders, error) {
+	height := blockStore.Height()
+	if to <= 0 || to > height {
+		to = height
+	}
+	if from <= 0 {
+		from = cmn.MaxInt64(1, to-maxHeaders+1)
+	}
+	// the blocks before the base are pruned
+	from = cmn.MaxInt64(from, blockStore.Base())
+	if from > to {
+		return nil, fmt.Errorf("from %d can't be greater than to %d", from, to)
+	}
+	to = cmn.MinInt64(to, from+maxHeaders-1)
+
+	blockMetas := make([]*types.BlockMeta, 0, to-from+1)
+	for h := from; h <= to; h++ {
+		blockMetas = append(blockMetas, blockStore.LoadBlockMeta(h))
+	}
+	return &ctypes.ResultHeaders{height, blockMetas}, nil
 }
 
 // Get block at a given height.
@@ -206,10 +320,20 @@ func Block(heightPtr *int64) (*ctypes.ResultBlock, error) {
 	if height > blockStore.Height() {
 		return nil, fmt.Errorf("Height must be less than the current blockchain height")
 	}
+	if err := checkPruned(height); err != nil {
+		return nil, err
+	}
+	if cached, ok := history.Get("block", height).(*ctypes.ResultBlock); ok {
+		return cached, nil
+	}
 
 	blockMeta := blockStore.LoadBlockMeta(height)
 	block := blockStore.LoadBlock(height)
-	return &ctypes.ResultBlock{blockMeta, block}, nil
+	result := &ctypes.ResultBlock{blockMeta, block}
+	if isPastHeight(heightPtr) {
+		history.Set("block", height, result)
+	}
+	return result, nil
 }
 
 // Get block commit at a given height.
@@ -298,6 +422,13 @@ func Commit(heightPtr *int64) (*ctypes.ResultCommit, error) {
 	if height > storeHeight {
 		return nil, fmt.Errorf("Height must be less than or equal to the current blockchain height")
 	}
+	if err := checkPruned(height); err != nil {
+		return nil, err
+	}
+
+	if cached, ok := history.Get("commit", height).(*ctypes.ResultCommit); ok {
+		return cached, nil
+	}
 
 	header := blockStore.LoadBlockMeta(height).Header
 
@@ -310,5 +441,267 @@ func Commit(heightPtr *int64) (*ctypes.ResultCommit, error) {
 
 	// Return the canonical commit (comes from the block at height+1)
 	commit := blockStore.LoadBlockCommit(height)
-	return ctypes.NewResultCommit(header, commit, true), nil
+	result := ctypes.NewResultCommit(header, commit, true)
+	history.Set("commit", height, result)
+	return result, nil
+}
+
+// checkPruned returns an error if the block of the height is pruned.
+func checkPruned(height int64) error {
+	if base := blockStore.Base(); height < base {
+		return fmt.Errorf("Height %d is pruned, the lowest height stored is %d", height, base)
+	}
+	return nil
+}
+
+// Verify a commit for a header, by a validator set, all supplied by the caller
+// rather than read from the chain of the node, eg. of another chain, for the
+// relayers and bridges which verify headers. The commit must be for the header,
+// made by the validator set of the header, and signed by more than 2/3 of its
+// voting power. All the reasons it fails are returned.
+//
+// ```shell
+// curl 'localhost:46657/verify_commit?header=_&commit=_&validators=_'
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:46657", "/websocket")
+// res, err := client.VerifyCommit(header, commit, validators)
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+//   "error": "",
+//   "result": {
+//     "valid": false,
+//     "failures": [
+//       {
+//         "reason": "invalid_signature",
+//         "index": 2,
+//         "message": "Invalid signature of E89A51D60F68385E09E716D353373B11F8FACD62"
+//       },
+//       {
+//         "reason": "insufficient_voting_power",
+//         "index": -1,
+//         "message": "Got 20 of the voting power, needed 27"
+//       }
+//     ],
+//     "signed_voting_power": 20,
+//     "needed_voting_power": 27,
+//     "total_voting_power": 40
+//   },
+//   "id": "",
+//   "jsonrpc": "2.0"
+// }
+// ```
+//
+// ### Query Parameters
+//
+// | Parameter  | Type        | Default | Required | Description                                   |
+// |------------+-------------+---------+----------+-----------------------------------------------|
+// | header     | Header      | nil     | true     | The header                                    |
+// | commit     | Commit      | nil     | true     | The commit for the header                     |
+// | validators | []Validator | nil     | true     | The validator set of the header, in any order |
+//
+// The reasons are in types.CommitFailure: `missing`, `validators_hash_mismatch`,
+// `block_id_mismatch`, `wrong_set_size`, `wrong_height`, `wrong_round`,
+// `not_precommit`, `wrong_validator`, `invalid_signature`,
+// `invalid_aggregated_signature` and `insufficient_voting_power`, with the
+// index of the precommit at fault, or -1.
+func VerifyCommit(header *types.Header, commit *types.Commit, validators []*types.Validator) (*ctypes.ResultVerifyCommit, error) {
+	var valSet *types.ValidatorSet
+	if len(validators) > 0 {
+		valSet = types.NewValidatorSet(validators)
+	}
+	return &ctypes.ResultVerifyCommit{*types.VerifyCommitForHeader(header, commit, valSet)}, nil
+}
+
+// Get the random beacon committed in the header at a given height.
+// The beacon at height H is derived from the precommit signatures of block H-1,
+// so it cannot be known before block H-1 is committed.
+// If no height is provided, it will fetch the beacon of the latest block.
+//
+// ```shell
+// curl 'localhost:46657/random_beacon?height=10'
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:46657", "/websocket")
+// info, err := client.RandomBeacon(10)
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+//   "error": "",
+//   "result": {
+//     "height": 10,
+//     "random_beacon": "2CB2D0F4D9E8E9A1F5C7E1B29F7F08A6D4A0F34E"
+//   },
+//   "id": "",
+//   "jsonrpc": "2.0"
+// }
+// ```
+func RandomBeacon(heightPtr *int64) (*ctypes.ResultRandomBeacon, error) {
+	height := blockStore.Height()
+	if heightPtr != nil {
+		height = *heightPtr
+		if height <= 0 {
+			return nil, fmt.Errorf("Height must be greater than 0")
+		}
+		if height > blockStore.Height() {
+			return nil, fmt.Errorf("Height must be less than or equal to the current blockchain height")
+		}
+	}
+
+	header := blockStore.LoadBlockMeta(height).Header
+	return &ctypes.ResultRandomBeacon{height, header.RandomBeacon}, nil
+}
+
+// BlockSearch allows you to query for the blocks by their tags: `block.height`,
+// `block.num_txs` and `block.validator_updates`, the number of validator
+// updates returned by EndBlock, if any.
+//
+// ```shell
+// curl "localhost:46657/block_search?query=\"block.validator_updates>0\""
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:46657", "/websocket")
+// result, err := client.BlockSearch("block.validator_updates > 0")
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+// 	"error": "",
+// 	"result": {
+// 		"block_metas": [
+// 			{
+// 				"header": {
+// 					"chain_id": "test-chain-6UTNIN",
+// 					"height": 12,
+// 					"num_txs": 1,
+// 					...
+// 				},
+// 				"block_id": {
+// 					"parts": {
+// 						"hash": "277A4DBEF91483A18B85F2F5677ABF9694DFA40F",
+// 						"total": 1
+// 					},
+// 					"hash": "96B1D2F2D201BA4BC383EB8224139DB1294944E5"
+// 				}
+// 			}
+// 		]
+// 	},
+// 	"id": "",
+// 	"jsonrpc": "2.0"
+// }
+// ```
+//
+// Returns the metas of the blocks matching the given query, by ascending height.
+// The blocks are only indexed if `tx_index.index_blocks` is set.
+//
+// ### Query Parameters
+//
+// | Parameter | Type   | Default | Required | Description |
+// |-----------+--------+---------+----------+-------------|
+// | query     | string | ""      | true     | Query       |
+func BlockSearch(query string) (*ctypes.ResultBlockSearch, error) {
+	if blockIndexer == nil {
+		return nil, fmt.Errorf("The blocks are not indexed (see tx_index.index_blocks)")
+	}
+	q, err := tmquery.New(query)
+	if err != nil {
+		return nil, err
+	}
+
+	heights, err := blockIndexer.Search(q)
+	if err != nil {
+		return nil, err
+	}
+
+	// TODO: we may want to consider putting a maximum on this length and somehow
+	// informing the user that things were truncated.
+	blockMetas := make([]*types.BlockMeta, 0, len(heights))
+	for _, height := range heights {
+		// the blocks may have been indexed before a restore of the block store
+		if blockMeta := blockStore.LoadBlockMeta(height); blockMeta != nil {
+			blockMetas = append(blockMetas, blockMeta)
+		}
+	}
+	return &ctypes.ResultBlockSearch{BlockMetas: blockMetas}, nil
+}
+
+// BlockResults returns the results of executing the block at a height, as
+// saved by the node: the DeliverTx responses of its txs, and the validator
+// and consensus params updates of EndBlock. If no height is provided, it
+// returns the ones of the last block executed. The results of the heights
+// before the last `abci_responses_retain_blocks` ones are pruned.
+//
+// ```shell
+// curl 'localhost:46657/block_results?height=10'
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:46657", "/websocket")
+// info, err := client.BlockResults(10)
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+// 	"error": "",
+// 	"result": {
+// 		"height": 10,
+// 		"deliver_tx": [
+// 			{
+// 				"code": 0,
+// 				"data": "",
+// 				"log": "",
+// 				"tags": []
+// 			}
+// 		],
+// 		"validator_updates": null,
+// 		"consensus_params_update": null
+// 	},
+// 	"id": "",
+// 	"jsonrpc": "2.0"
+// }
+// ```
+func BlockResults(heightPtr *int64) (*ctypes.ResultBlockResults, error) {
+	state := consensusState.GetState()
+	height := state.LastBlockHeight
+	if heightPtr != nil {
+		if *heightPtr <= 0 {
+			return nil, fmt.Errorf("Height must be greater than 0")
+		}
+		if *heightPtr > height {
+			return nil, fmt.Errorf("Height must be less than or equal to the height of the last block executed, %d", height)
+		}
+		height = *heightPtr
+	}
+	if height == 0 {
+		return nil, fmt.Errorf("No block executed yet")
+	}
+
+	abciResponses, err := state.LoadABCIResponsesAt(height)
+	if err != nil {
+		return nil, err
+	}
+	var validatorUpdates []*abci.Validator
+	if abciResponses.EndBlock != nil && len(abciResponses.EndBlock.ValidatorUpdates) > 0 {
+		validatorUpdates = abciResponses.EndBlock.ValidatorUpdates
+	}
+	return &ctypes.ResultBlockResults{
+		Height:                height,
+		DeliverTx:             abciResponses.DeliverTx,
+		ValidatorUpdates:      validatorUpdates,
+		ConsensusParamsUpdate: abciResponses.ParamsUpdate,
+	}, nil
 }
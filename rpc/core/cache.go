@@ -0,0 +1,104 @@
+package core
+
+import (
+	"container/list"
+	"sync"
+)
+
+// historyCache is an LRU of the results at the past heights, which never
+// change, so that the explorers going through the blocks don't load them from
+// the db over and over.
+type historyCache struct {
+	mtx     sync.Mutex
+	size    int
+	entries *list.List // of *historyEntry, the most recently used first
+	byKey   map[historyKey]*list.Element
+}
+
+// historyKey is the endpoint and the height of a result.
+type historyKey struct {
+	endpoint string
+	height   int64
+}
+
+type historyEntry struct {
+	key    historyKey
+	result interface{}
+}
+
+// newHistoryCache returns a historyCache of at most size results, or nil if
+// size is 0, which caches nothing.
+func newHistoryCache(size int) *historyCache {
+	if size <= 0 {
+		return nil
+	}
+	return &historyCache{
+		size:    size,
+		entries: list.New(),
+		byKey:   make(map[historyKey]*list.Element, size),
+	}
+}
+
+// Get returns the result of the endpoint at the height, or nil.
+func (c *historyCache) Get(endpoint string, height int64) interface{} {
+	if c == nil {
+		return nil
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	e, ok := c.byKey[historyKey{endpoint, height}]
+	if !ok {
+		return nil
+	}
+	c.entries.MoveToFront(e)
+	return e.Value.(*historyEntry).result
+}
+
+// Set caches the result of the endpoint at the height, evicting the least
+// recently used one if full.
+func (c *historyCache) Set(endpoint string, height int64, result interface{}) {
+	if c == nil {
+		return
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	key := historyKey{endpoint, height}
+	if e, ok := c.byKey[key]; ok {
+		e.Value.(*historyEntry).result = result
+		c.entries.MoveToFront(e)
+		return
+	}
+	c.byKey[key] = c.entries.PushFront(&historyEntry{key, result})
+	if c.entries.Len() > c.size {
+		oldest := c.entries.Back()
+		c.entries.Remove(oldest)
+		delete(c.byKey, oldest.Value.(*historyEntry).key)
+	}
+}
+
+// Len returns the number of results cached.
+func (c *historyCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.entries.Len()
+}
+
+// pastHeight returns true if the first of the args is the height of a block
+// before the last one, whose block, commit and validators never change: the
+// HTTP responses for it are cacheable, see rpcserver.RPCFunc.Cacheable.
+func pastHeight(args []interface{}) bool {
+	if len(args) == 0 {
+		return false
+	}
+	height, ok := args[0].(*int64)
+	return ok && isPastHeight(height)
+}
+
+// isPastHeight returns true if the height is given, and of a block before the
+// last one.
+func isPastHeight(height *int64) bool {
+	return height != nil && *height > 0 && *height < blockStore.Height()
+}
@@ -0,0 +1,29 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryCache(t *testing.T) {
+	cache := newHistoryCache(2)
+	cache.Set("block", 1, "block 1")
+	cache.Set("commit", 1, "commit 1")
+	assert.Equal(t, "block 1", cache.Get("block", 1))
+	assert.Nil(t, cache.Get("block", 2))
+
+	// the least recently used is evicted
+	cache.Set("block", 2, "block 2")
+	assert.Equal(t, 2, cache.Len())
+	assert.Nil(t, cache.Get("commit", 1))
+	assert.Equal(t, "block 1", cache.Get("block", 1))
+	assert.Equal(t, "block 2", cache.Get("block", 2))
+
+	// a nil cache caches nothing
+	cache = newHistoryCache(0)
+	assert.Nil(t, cache)
+	cache.Set("block", 1, "block 1")
+	assert.Nil(t, cache.Get("block", 1))
+	assert.Equal(t, 0, cache.Len())
+}
@@ -1,6 +1,9 @@
 package core
 
 import (
+	"fmt"
+	"sort"
+
 	cm "github.com/tendermint/tendermint/consensus"
 	cstypes "github.com/tendermint/tendermint/consensus/types"
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
@@ -9,14 +12,16 @@ import (
 
 // Get the validator set at the given block height.
 // If no height is provided, it will fetch the current validator set.
+// The validators are ordered by address, like in the set, or by decreasing
+// voting power, and are all returned unless per_page is set.
 //
 // ```shell
-// curl 'localhost:46657/validators'
+// curl 'localhost:46657/validators?page=1&per_page=10&order_by="voting_power"'
 // ```
 //
 // ```go
 // client := client.NewHTTP("tcp://0.0.0.0:46657", "/websocket")
-// state, err := client.Validators()
+// state, err := client.Validators(nil, 1, 10, "voting_power")
 // ```
 //
 // > The above command returns JSON structured like this:
@@ -36,25 +41,144 @@ import (
 // 				"address": "E89A51D60F68385E09E716D353373B11F8FACD62"
 // 			}
 // 		],
-// 		"block_height": 5241
+// 		"block_height": 5241,
+// 		"total": 1
 // 	},
 // 	"id": "",
 // 	"jsonrpc": "2.0"
 // }
 // ```
-func Validators(heightPtr *int64) (*ctypes.ResultValidators, error) {
+//
+// ### Query Parameters
+//
+// | Parameter | Type   | Default   | Required | Description                                  |
+// |-----------+--------+-----------+----------+----------------------------------------------|
+// | height    | int64  | 0         | false    | Height of the validator set, 0 for the next  |
+// | page      | int    | 1         | false    | Page of the validators, from 1               |
+// | per_page  | int    | 0         | false    | Number of validators per page, 0 for all     |
+// | order_by  | string | "address" | false    | Order, "address" or "voting_power"           |
+func Validators(heightPtr *int64, page, perPage int, orderBy string) (*ctypes.ResultValidators, error) {
+	orderBy, err := validateOrder(orderBy, orderAddress, orderAddress, orderVotingPower)
+	if err != nil {
+		return nil, err
+	}
+
+	var height int64
+	var validators []*types.Validator
 	if heightPtr == nil {
-		blockHeight, validators := consensusState.GetValidators()
-		return &ctypes.ResultValidators{blockHeight, validators}, nil
+		height, validators = consensusState.GetValidators()
+	} else {
+		height = *heightPtr
+		valSet, ok := history.Get("validators", height).(*types.ValidatorSet)
+		if !ok {
+			state := consensusState.GetState()
+			if valSet, err = state.LoadValidators(height); err != nil {
+				return nil, err
+			}
+			if isPastHeight(heightPtr) {
+				history.Set("validators", height, valSet)
+			}
+		}
+		validators = valSet.Validators
+	}
+
+	start, end, err := paginate(page, perPage, 0, len(validators))
+	if err != nil {
+		return nil, err
+	}
+	if orderBy == orderVotingPower {
+		sorted := make([]*types.Validator, len(validators))
+		copy(sorted, validators)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].VotingPower > sorted[j].VotingPower
+		})
+		validators = sorted
+	}
+	return &ctypes.ResultValidators{height, validators[start:end], len(validators)}, nil
+}
+
+// maxValidatorDistributions is the max number of distributions returned by ValidatorDistribution.
+const maxValidatorDistributions = 100
+
+// Get the distribution of the voting power of the validators for from <= height <= to:
+// their Gini coefficient, and the fewest of them with more than 1/3 (the Nakamoto
+// coefficient) and 2/3 of the power, at from and at each height the set changed at,
+// in increasing height, eg. for decentralization dashboards.
+// If to is 0 or past the last block, it's the last block. If from is 0, it's 1.
+// With more than 100 changes, the last 100 are returned.
+//
+// ```shell
+// curl 'localhost:46657/validator_distribution?from=1&to=100'
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:46657", "/websocket")
+// dist, err := client.ValidatorDistribution(1, 100)
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+// 	"error": "",
+// 	"result": {
+// 		"distributions": [
+// 			{
+// 				"height": 1,
+// 				"num_validators": 4,
+// 				"total_voting_power": 40,
+// 				"max_voting_power": 10,
+// 				"gini": 0,
+// 				"nakamoto_coefficient": 2,
+// 				"supermajority_coefficient": 3
+// 			},
+// 			{
+// 				"height": 57,
+// 				"num_validators": 5,
+// 				"total_voting_power": 100,
+// 				"max_voting_power": 60,
+// 				"gini": 0.4,
+// 				"nakamoto_coefficient": 1,
+// 				"supermajority_coefficient": 2
+// 			}
+// 		],
+// 		"last_height": 100
+// 	},
+// 	"id": "",
+// 	"jsonrpc": "2.0"
+// }
+// ```
+//
+// <aside class="notice">Returns at most 100 items.</aside>
+func ValidatorDistribution(from, to int64) (*ctypes.ResultValidatorDistribution, error) {
+	height := blockStore.Height()
+	if to <= 0 || to > height {
+		to = height
+	}
+	if from <= 0 {
+		from = 1
+	}
+	if from > to {
+		return nil, fmt.Errorf("from %d can't be greater than to %d", from, to)
 	}
 
-	height := *heightPtr
 	state := consensusState.GetState()
-	validators, err := state.LoadValidators(height)
+	heights, err := state.ValidatorsChangeHeights(from, to, maxValidatorDistributions)
 	if err != nil {
 		return nil, err
 	}
-	return &ctypes.ResultValidators{height, validators.Validators}, nil
+	if len(heights) < maxValidatorDistributions {
+		heights = append([]int64{from}, heights...)
+	}
+	distributions := make([]types.PowerDistribution, 0, len(heights))
+	for _, h := range heights {
+		valSet, err := state.LoadValidators(h)
+		if err != nil {
+			return nil, err
+		}
+		distributions = append(distributions, types.NewPowerDistribution(h, valSet))
+	}
+	return &ctypes.ResultValidatorDistribution{height, distributions}, nil
 }
 
 // Dump consensus state.
@@ -90,3 +214,149 @@ func DumpConsensusState() (*ctypes.ResultDumpConsensusState, error) {
 	}
 	return &ctypes.ResultDumpConsensusState{consensusState.GetRoundState(), peerRoundStates}, nil
 }
+
+// Get a summary of the consensus state: the height, round and step, the
+// proposal and the locked block, and who voted and how much voting power it
+// is of each round of the height, and of the last commit, eg. to see what a
+// stalled height is waiting for.
+//
+// ```shell
+// curl 'localhost:46657/consensus_state'
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:46657", "/websocket")
+// state, err := client.ConsensusState()
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+// 	"error": "",
+// 	"result": {
+// 		"round_state": {
+// 			"height": 3537,
+// 			"round": 1,
+// 			"step": "RoundStepPrevoteWait",
+// 			"start_time": "2017-05-31T12:32:31.178653883Z",
+// 			"proposer": "E89A51D60F68385E09E716D353373B11F8FACD62",
+// 			"proposal_block_hash": "",
+// 			"locked_round": 0,
+// 			"locked_block_hash": "",
+// 			"votes": [
+// 				{
+// 					"round": 0,
+// 					"prevotes": {"bit_array": "BA{4:xx__}", "voted_power": 20, "total_power": 40, "maj23_hash": "", "maj23": false},
+// 					"precommits": {"bit_array": "BA{4:x___}", "voted_power": 10, "total_power": 40, "maj23_hash": "", "maj23": false}
+// 				},
+// 				{
+// 					"round": 1,
+// 					"prevotes": {"bit_array": "BA{4:xx_x}", "voted_power": 30, "total_power": 40, "maj23_hash": "", "maj23": false},
+// 					"precommits": {"bit_array": "BA{4:____}", "voted_power": 0, "total_power": 40, "maj23_hash": "", "maj23": false}
+// 				}
+// 			],
+// 			"last_commit": {"bit_array": "BA{4:xxxx}", "voted_power": 40, "total_power": 40, "maj23_hash": "B7F988FBCDC68F9320E346EECAA76E32F6054654", "maj23": true}
+// 		}
+// 	},
+// 	"id": "",
+// 	"jsonrpc": "2.0"
+// }
+// ```
+func ConsensusState() (*ctypes.ResultConsensusState, error) {
+	return &ctypes.ResultConsensusState{consensusState.GetRoundState().Summary()}, nil
+}
+
+// Get the consensus params in effect at the given block height.
+// If no height is provided, it will fetch the ones of the next block.
+//
+// ```shell
+// curl 'localhost:46657/consensus_params?height=10'
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:46657", "/websocket")
+// params, err := client.ConsensusParams(10)
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+// 	"error": "",
+// 	"result": {
+// 		"block_height": 10,
+// 		"consensus_params": {
+// 			"block_size_params": {"max_bytes": 22020096, "max_txs": 100000, "max_gas": -1},
+// 			"tx_size_params": {"max_bytes": 10240, "max_gas": -1},
+// 			"block_gossip_params": {"block_part_size_bytes": 65536},
+// 			...
+// 		}
+// 	},
+// 	"id": "",
+// 	"jsonrpc": "2.0"
+// }
+// ```
+//
+// ### Query Parameters
+//
+// | Parameter | Type  | Default | Required | Description                              |
+// |-----------+-------+---------+----------+------------------------------------------|
+// | height    | int64 | 0       | false    | Height of the block, 0 for the next one  |
+func ConsensusParams(heightPtr *int64) (*ctypes.ResultConsensusParams, error) {
+	state := consensusState.GetState()
+	// the params are saved along with the state, for the next block
+	nextHeight := state.LastBlockHeight + 1
+	height := nextHeight
+	if heightPtr != nil {
+		height = *heightPtr
+		if height <= 0 {
+			return nil, fmt.Errorf("Height must be greater than 0")
+		}
+		if height > nextHeight {
+			return nil, fmt.Errorf("Height must be less than or equal to the next height %d", nextHeight)
+		}
+	}
+	params, err := state.LoadConsensusParams(height)
+	if err != nil {
+		return nil, err
+	}
+	return &ctypes.ResultConsensusParams{height, params}, nil
+}
+
+// UnsafeScheduleExit makes the validator of the node stop signing votes and
+// proposals of the heights after the height, or sign them again if 0, so that
+// it can leave the validator set along with the unbonding in the app rather
+// than go dark and be slashed for downtime. It's announced with a
+// `ValidatorExit` event, and again once the validator stops signing. It
+// doesn't survive a restart of the node: set `consensus.stop_signing_height`
+// too.
+//
+// ```shell
+// curl 'localhost:46657/unsafe_schedule_exit?height=1000'
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:46657", "/websocket")
+// result, err := client.ScheduleExit(1000)
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+// 	"error": "",
+// 	"result": {
+// 		"address": "E89A51D60F68385E09E716D353373B11F8FACD62",
+// 		"stop_height": 1000
+// 	},
+// 	"id": "",
+// 	"jsonrpc": "2.0"
+// }
+// ```
+func UnsafeScheduleExit(height int64) (*ctypes.ResultScheduleExit, error) {
+	if err := consensusState.ScheduleExit(height); err != nil {
+		return nil, err
+	}
+	return &ctypes.ResultScheduleExit{pubKey.Address(), height}, nil
+}
@@ -81,7 +81,6 @@ Available endpoints:
 /net_info
 /num_unconfirmed_txs
 /status
-/unconfirmed_txs
 /unsafe_flush_mempool
 /unsafe_stop_cpu_profiler
 /validators
@@ -97,6 +96,7 @@ Endpoints that require arguments:
 /dial_seeds?seeds=_
 /subscribe?event=_
 /tx?hash=_&prove=_
+/unconfirmed_txs?limit=_&offset=_&hash_prefix=_
 /unsafe_start_cpu_profiler?filename=_
 /unsafe_write_heap_profile?filename=_
 /unsubscribe?event=_
@@ -8,11 +8,35 @@ import (
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 	rpctypes "github.com/tendermint/tendermint/rpc/lib/types"
 	tmtypes "github.com/tendermint/tendermint/types"
-	tmquery "github.com/tendermint/tmlibs/pubsub/query"
+	tmquery "github.com/tendermint/tendermint/types/query"
 )
 
 // Subscribe for events via WebSocket.
 //
+// With a schema_version, the new_block, new_block_header, tx, vote,
+// validator_set_updates and mempool_tx events are sent in that version of the event schema
+// (see types.EventMessage), which is stable across releases, and the other
+// events are not sent. Without it, the events are sent as the internal types.
+//
+// The txs accepted into the mempool of the node are followed with the query
+// "tm.event='MempoolTx'", and a given one with "tm.event='MempoolTx' AND tx.hash='<HASH>'".
+//
+// The query language supports AND, OR, parentheses, EXISTS and comparisons of
+// numbers, times and dates, eg. "tm.event='Tx' AND (tx.height > 5 OR app.fee EXISTS)".
+// It is evaluated by the node, so only the matching events are sent.
+//
+// Up to rpc.ws_subscription_buffer events are buffered per subscription for
+// a client slower to read them than they happen. Past that, the events are
+// dropped, or with rpc.ws_slow_subscriber_policy "close", the subscription is
+// closed and an error sent with the id "<ID>#event", so one slow client
+// doesn't hold up the event bus.
+//
+// With a from_height, the NewBlock, NewBlockHeader, Tx and ValidatorSetUpdates
+// events matching the query of the blocks from that height on are first
+// replayed from the block store, then the live events sent, none missed or
+// twice, so a client can catch up after downtime. The replayed events are
+// never dropped. The blocks and their ABCI responses must not be pruned.
+//
 // ```go
 // import "github.com/tendermint/tendermint/types"
 //
@@ -33,34 +57,47 @@ import (
 //
 // ### Query Parameters
 //
-// | Parameter | Type   | Default | Required | Description |
-// |-----------+--------+---------+----------+-------------|
-// | event     | string | ""      | true     | Event name  |
+// | Parameter      | Type   | Default | Required | Description                      |
+// |----------------+--------+---------+----------+----------------------------------|
+// | query          | string | ""      | true     | Query for the events             |
+// | schema_version | int    | 0       | false    | Version of the event schema      |
+// | from_height    | int64  | 0       | false    | Height to replay the events from |
 //
 // <aside class="notice">WebSocket only</aside>
-func Subscribe(wsCtx rpctypes.WSRPCContext, query string) (*ctypes.ResultSubscribe, error) {
+func Subscribe(wsCtx rpctypes.WSRPCContext, query string, schemaVersion int, fromHeight int64) (*ctypes.ResultSubscribe, error) {
 	addr := wsCtx.GetRemoteAddr()
-	logger.Info("Subscribe to query", "remote", addr, "query", query)
+	logger.Info("Subscribe to query", "remote", addr, "query", query, "schema_version", schemaVersion, "from_height", fromHeight)
 
 	q, err := tmquery.New(query)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to parse query")
 	}
+	if schemaVersion < 0 || schemaVersion > tmtypes.EventSchemaVersion {
+		return nil, errors.Errorf("unknown schema_version %d, the latest is %d", schemaVersion, tmtypes.EventSchemaVersion)
+	}
+
+	if fromHeight < 0 {
+		return nil, errors.Errorf("from_height must not be negative, got %d", fromHeight)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), subscribeTimeout)
 	defer cancel()
 	ch := make(chan interface{})
-	err = eventBusFor(wsCtx).Subscribe(ctx, addr, q, ch)
+	var replayedHeight int64
+	if fromHeight == 0 {
+		err = eventBusFor(wsCtx).Subscribe(ctx, addr, q, ch)
+	} else {
+		eb, ok := eventBusFor(wsCtx).(*tmtypes.EventBus)
+		if !ok || consensusState == nil {
+			return nil, errors.New("from_height is not supported by this node")
+		}
+		replayedHeight, err = eb.SubscribeFrom(ctx, addr, q, fromHeight, storeReplayer{}, ch)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	go func() {
-		for event := range ch {
-			tmResult := &ctypes.ResultEvent{query, event.(tmtypes.TMEventData)}
-			wsCtx.TryWriteRPCResponse(rpctypes.NewRPCSuccessResponse(wsCtx.Request.ID+"#event", tmResult))
-		}
-	}()
+	go forwardEvents(wsCtx, query, q, schemaVersion, replayedHeight, ch)
 
 	return &ctypes.ResultSubscribe{}, nil
 }
@@ -116,6 +153,135 @@ func UnsubscribeAll(wsCtx rpctypes.WSRPCContext) (*ctypes.ResultUnsubscribe, err
 	return &ctypes.ResultUnsubscribe{}, nil
 }
 
+// forwardEvents sends the events of the subscription to the client. The events
+// are buffered, not to block the event bus while the client reads them, and
+// dropped or the subscription closed once subscriptionBufferSize are waiting.
+// The past events, of the heights up to replayedHeight, are waited for instead,
+// as they are replayed at the pace of the client.
+func forwardEvents(wsCtx rpctypes.WSRPCContext, query string, q *tmquery.Query, schemaVersion int, replayedHeight int64, ch <-chan interface{}) {
+	addr := wsCtx.GetRemoteAddr()
+	buf := make(chan rpctypes.RPCResponse, subscriptionBufferSize)
+	go func() {
+		for resp := range buf {
+			// returns once the connection is closed
+			wsCtx.WriteRPCResponse(resp)
+		}
+	}()
+	defer close(buf)
+
+	dropped, closing := 0, false
+	// read ch until the subscription is gone, to never block the event bus
+	for event := range ch {
+		if closing {
+			continue
+		}
+		var tmResult interface{} = &ctypes.ResultEvent{query, event.(tmtypes.TMEventData)}
+		if schemaVersion > 0 {
+			msg, err := tmtypes.NewEventMessage(event.(tmtypes.TMEventData), schemaVersion)
+			if err != nil {
+				// not in the schema
+				continue
+			}
+			tmResult = &ctypes.ResultEventMessage{query, msg}
+		}
+		resp := rpctypes.NewRPCSuccessResponse(wsCtx.Request.ID+"#event", tmResult)
+		if height, ok := tmtypes.EventHeight(event.(tmtypes.TMEventData)); ok && height <= replayedHeight {
+			buf <- resp
+			continue
+		}
+		select {
+		case buf <- resp:
+			continue
+		default:
+		}
+
+		// the client is too slow
+		if !closeSlowSubscriptions {
+			dropped++
+			logger.Debug("Dropped event for slow subscriber", "remote", addr, "query", query, "dropped", dropped)
+			continue
+		}
+		logger.Info("Closing subscription of slow subscriber", "remote", addr, "query", query)
+		closing = true
+		// not from this goroutine, the event bus may be waiting on ch
+		go func() {
+			if err := eventBusFor(wsCtx).Unsubscribe(context.Background(), addr, q); err != nil {
+				logger.Error("Failed to unsubscribe slow subscriber", "remote", addr, "query", query, "err", err)
+			}
+		}()
+	}
+
+	if closing {
+		err := errors.Errorf("subscription to %q closed, the client did not keep up with its events", query)
+		buf <- rpctypes.RPCInternalError(wsCtx.Request.ID+"#event", err)
+	}
+}
+
+// SubscribeEvents subscribes the subscriber to the events matching the query,
+// sending them to out until the ctx is done, for the servers other than the
+// websockets, eg. gRPC. out is closed once unsubscribed, and must be read until
+// then, not to block the event bus.
+func SubscribeEvents(ctx context.Context, subscriber, query string, out chan<- interface{}) error {
+	q, err := tmquery.New(query)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse query")
+	}
+	subCtx, cancel := context.WithTimeout(ctx, subscribeTimeout)
+	defer cancel()
+	if err := eventBus.Subscribe(subCtx, subscriber, q, out); err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		if err := eventBus.Unsubscribe(context.Background(), subscriber, q); err != nil {
+			logger.Error("Failed to unsubscribe", "subscriber", subscriber, "query", query, "err", err)
+		}
+	}()
+	return nil
+}
+
+// storeReplayer replays the events of the blocks in the block store, with
+// their ABCI responses, for the subscriptions from a past height.
+type storeReplayer struct{}
+
+func (storeReplayer) LatestHeight() int64 {
+	return consensusState.GetState().LastBlockHeight
+}
+
+func (storeReplayer) BlockEvents(height int64) ([]tmtypes.TMEventData, error) {
+	block := blockStore.LoadBlock(height)
+	if block == nil {
+		return nil, errors.Errorf("no block at height %d", height)
+	}
+	abciResponses, err := consensusState.GetState().LoadABCIResponsesAt(height)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []tmtypes.TMEventData{
+		{tmtypes.EventDataNewBlock{block}},
+		{tmtypes.EventDataNewBlockHeader{block.Header}},
+	}
+	for i, tx := range block.Data.Txs {
+		if i >= len(abciResponses.DeliverTx) || abciResponses.DeliverTx[i] == nil {
+			break
+		}
+		events = append(events, tmtypes.TMEventData{tmtypes.EventDataTx{tmtypes.TxResult{
+			Height: height,
+			Index:  uint32(i),
+			Tx:     tx,
+			Result: *abciResponses.DeliverTx[i],
+		}}})
+	}
+	if abciResponses.EndBlock != nil && len(abciResponses.EndBlock.ValidatorUpdates) > 0 {
+		events = append(events, tmtypes.TMEventData{tmtypes.EventDataValidatorSetUpdates{
+			Height:  height,
+			Updates: abciResponses.EndBlock.ValidatorUpdates,
+		}})
+	}
+	return events, nil
+}
+
 func eventBusFor(wsCtx rpctypes.WSRPCContext) tmtypes.EventBusSubscriber {
 	es := wsCtx.GetEventSubscriber()
 	if es == nil {
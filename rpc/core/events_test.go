@@ -0,0 +1,104 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	rpctypes "github.com/tendermint/tendermint/rpc/lib/types"
+	"github.com/tendermint/tendermint/types"
+	"github.com/tendermint/tmlibs/log"
+)
+
+// slowConn is a websocket connection whose client reads the responses only
+// when the test does.
+type slowConn struct {
+	responses chan rpctypes.RPCResponse
+	eventBus  *types.EventBus
+}
+
+func (c *slowConn) GetRemoteAddr() string                        { return "slow" }
+func (c *slowConn) WriteRPCResponse(resp rpctypes.RPCResponse)   { c.responses <- resp }
+func (c *slowConn) GetEventSubscriber() rpctypes.EventSubscriber { return c.eventBus }
+func (c *slowConn) TryWriteRPCResponse(resp rpctypes.RPCResponse) bool {
+	select {
+	case c.responses <- resp:
+		return true
+	default:
+		return false
+	}
+}
+
+// subscribeSlow subscribes a slowConn to the headers, and publishes n of them.
+func subscribeSlow(t *testing.T, n int) (*slowConn, func()) {
+	eventBus := types.NewEventBus()
+	require.Nil(t, eventBus.Start())
+	conn := &slowConn{make(chan rpctypes.RPCResponse), eventBus}
+	wsCtx := rpctypes.WSRPCContext{rpctypes.RPCRequest{ID: "1"}, conn}
+	_, err := Subscribe(wsCtx, "tm.event='NewBlockHeader'", 0, 0)
+	require.Nil(t, err)
+
+	for i := 1; i <= n; i++ {
+		// never held up by the client
+		require.Nil(t, eventBus.PublishEventNewBlockHeader(types.EventDataNewBlockHeader{&types.Header{Height: int64(i)}}))
+	}
+	time.Sleep(100 * time.Millisecond)
+	return conn, func() { eventBus.Stop() }
+}
+
+// readAll returns the responses the client gets until there are no more.
+func readAll(conn *slowConn) []rpctypes.RPCResponse {
+	var responses []rpctypes.RPCResponse
+	for {
+		select {
+		case resp := <-conn.responses:
+			responses = append(responses, resp)
+		case <-time.After(100 * time.Millisecond):
+			return responses
+		}
+	}
+}
+
+func TestSubscribeDropSlow(t *testing.T) {
+	SetLogger(log.NewNopLogger())
+	SetSubscriptionLimits(2, false)
+	defer SetSubscriptionLimits(100, false)
+
+	conn, stop := subscribeSlow(t, 10)
+	defer stop()
+
+	// the one being written, and the buffered ones
+	responses := readAll(conn)
+	assert.True(t, len(responses) >= 2 && len(responses) <= 3, "got %d events", len(responses))
+	for _, resp := range responses {
+		assert.Nil(t, resp.Error)
+		assert.Equal(t, "1#event", resp.ID)
+	}
+
+	// still subscribed
+	require.Nil(t, conn.eventBus.PublishEventNewBlockHeader(types.EventDataNewBlockHeader{&types.Header{Height: 11}}))
+	assert.Equal(t, 1, len(readAll(conn)))
+}
+
+func TestSubscribeCloseSlow(t *testing.T) {
+	SetLogger(log.NewNopLogger())
+	SetSubscriptionLimits(2, true)
+	defer SetSubscriptionLimits(100, false)
+
+	conn, stop := subscribeSlow(t, 10)
+	defer stop()
+
+	// the events buffered, then the error
+	responses := readAll(conn)
+	require.True(t, len(responses) >= 3 && len(responses) <= 4, "got %d responses", len(responses))
+	last := responses[len(responses)-1]
+	require.NotNil(t, last.Error)
+	assert.Contains(t, last.Error.Data, "did not keep up")
+	assert.Equal(t, "1#event", last.ID)
+
+	// no longer subscribed
+	require.Nil(t, conn.eventBus.PublishEventNewBlockHeader(types.EventDataNewBlockHeader{&types.Header{Height: 11}}))
+	assert.Equal(t, 0, len(readAll(conn)))
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"fmt"
+
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+// maxIntegrityCheckHeights is the max number of heights of a /check_integrity query.
+const maxIntegrityCheckHeights = 10000
+
+// IntegrityReport returns the report of the background cross-check of the blocks
+// against the records of the state db (see `integrity_check_interval`): the heights
+// checked since the node started, and the discrepancies found, the first 100 of them.
+//
+// ```shell
+// curl 'localhost:46657/integrity_report'
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:46657", "/websocket")
+// report, err := client.IntegrityReport()
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+// 	"error": "",
+// 	"result": {
+// 		"report": {
+// 			"from": 1,
+// 			"to": 5240,
+// 			"checked_heights": 5240,
+// 			"num_discrepancies": 1,
+// 			"discrepancies": [
+// 				{
+// 					"height": 5100,
+// 					"field": "ValidatorsHash",
+// 					"error": "Could not find validator set for height #5100"
+// 				}
+// 			],
+// 			"time": "2017-12-07T18:44:39.581051458Z"
+// 		}
+// 	},
+// 	"id": "",
+// 	"jsonrpc": "2.0"
+// }
+// ```
+func IntegrityReport() (*ctypes.ResultIntegrityReport, error) {
+	if integrityChecker == nil {
+		return nil, fmt.Errorf("The integrity check is disabled (see integrity_check_interval)")
+	}
+	return &ctypes.ResultIntegrityReport{integrityChecker.Report()}, nil
+}
+
+// CheckIntegrity cross-checks the blocks of a range of heights against the records
+// of the state db, and returns the report: the validator sets against the
+// ValidatorsHash of the blocks, the consensus params against their parts, and the
+// ABCI responses, unless they were pruned, against the LastResultsHash of the next blocks.
+//
+// ```shell
+// curl 'localhost:46657/check_integrity?from=1&to=100'
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:46657", "/websocket")
+// report, err := client.CheckIntegrity(1, 100)
+// ```
+//
+// The result is structured like the one of `/integrity_report`.
+//
+// ### Query Parameters
+//
+// | Parameter | Type  | Default     | Required | Description                          |
+// |-----------+-------+-------------+----------+--------------------------------------|
+// | from      | int64 | 1           | false    | The first height                     |
+// | to        | int64 | last height | false    | The last height, at most 10000 after |
+func CheckIntegrity(from, to int64) (*ctypes.ResultIntegrityReport, error) {
+	lastHeight := blockStore.Height()
+	if from <= 0 {
+		from = 1
+	}
+	if to <= 0 || to > lastHeight {
+		to = lastHeight
+	}
+	if to-from >= maxIntegrityCheckHeights {
+		return nil, fmt.Errorf("At most %d heights can be checked at once", maxIntegrityCheckHeights)
+	}
+
+	report := consensusState.GetState().CheckIntegrity(blockStore, from, to)
+	return &ctypes.ResultIntegrityReport{report}, nil
+}
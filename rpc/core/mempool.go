@@ -3,6 +3,7 @@ package core
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -45,11 +46,12 @@ import (
 //
 // ### Query Parameters
 //
-// | Parameter | Type | Default | Required | Description     |
-// |-----------+------+---------+----------+-----------------|
-// | tx        | Tx   | nil     | true     | The transaction |
-func BroadcastTxAsync(tx types.Tx) (*ctypes.ResultBroadcastTx, error) {
-	err := mempool.CheckTx(tx, nil)
+// | Parameter     | Type  | Default | Required | Description                                      |
+// |---------------+-------+---------+----------+--------------------------------------------------|
+// | tx            | Tx    | nil     | true     | The transaction                                  |
+// | expiry_height | int64 | 0       | false    | Evict the tx if not in a block by then (0: none) |
+func BroadcastTxAsync(tx types.Tx, expiryHeight int64) (*ctypes.ResultBroadcastTx, error) {
+	err := mempool.CheckTxWithExpiry(tx, expiryHeight, nil)
 	if err != nil {
 		return nil, fmt.Errorf("Error broadcasting transaction: %v", err)
 	}
@@ -85,12 +87,13 @@ func BroadcastTxAsync(tx types.Tx) (*ctypes.ResultBroadcastTx, error) {
 //
 // ### Query Parameters
 //
-// | Parameter | Type | Default | Required | Description     |
-// |-----------+------+---------+----------+-----------------|
-// | tx        | Tx   | nil     | true     | The transaction |
-func BroadcastTxSync(tx types.Tx) (*ctypes.ResultBroadcastTx, error) {
+// | Parameter     | Type  | Default | Required | Description                                      |
+// |---------------+-------+---------+----------+--------------------------------------------------|
+// | tx            | Tx    | nil     | true     | The transaction                                  |
+// | expiry_height | int64 | 0       | false    | Evict the tx if not in a block by then (0: none) |
+func BroadcastTxSync(tx types.Tx, expiryHeight int64) (*ctypes.ResultBroadcastTx, error) {
 	resCh := make(chan *abci.Response, 1)
-	err := mempool.CheckTx(tx, func(res *abci.Response) {
+	err := mempool.CheckTxWithExpiry(tx, expiryHeight, func(res *abci.Response) {
 		resCh <- res
 	})
 	if err != nil {
@@ -209,10 +212,11 @@ func BroadcastTxCommit(tx types.Tx) (*ctypes.ResultBroadcastTxCommit, error) {
 	}
 }
 
-// Get unconfirmed transactions including their number.
+// Get unconfirmed transactions, in the order they were added to the mempool,
+// and the number, total size and total gas of the ones matching the filter.
 //
 // ```shell
-// curl 'localhost:46657/unconfirmed_txs'
+// curl 'localhost:46657/unconfirmed_txs?limit=10&offset=20&hash_prefix="4A2B"'
 // ```
 //
 // ```go
@@ -227,15 +231,46 @@ func BroadcastTxCommit(tx types.Tx) (*ctypes.ResultBroadcastTxCommit, error) {
 //   "error": "",
 //   "result": {
 //     "txs": [],
-//     "n_txs": 0
+//     "n_txs": 0,
+//     "total": 25,
+//     "total_bytes": 4096,
+//     "total_gas": 2500
 //   },
 //   "id": "",
 //   "jsonrpc": "2.0"
 // }
 // ```
-func UnconfirmedTxs() (*ctypes.ResultUnconfirmedTxs, error) {
-	txs := mempool.Reap(-1)
-	return &ctypes.ResultUnconfirmedTxs{len(txs), txs}, nil
+//
+// ### Query Parameters
+//
+// | Parameter   | Type   | Default | Required | Description                                     |
+// |-------------+--------+---------+----------+-------------------------------------------------|
+// | limit       | int    | 0       | false    | Max number of txs to return, 0 for no limit     |
+// | offset      | int    | 0       | false    | Number of matching txs to skip                  |
+// | hash_prefix | string | ""      | false    | Only the txs with a hash starting with this hex |
+func UnconfirmedTxs(limit, offset int, hashPrefix string) (*ctypes.ResultUnconfirmedTxs, error) {
+	if limit < 0 || offset < 0 {
+		return nil, fmt.Errorf("limit and offset can't be negative")
+	}
+	hashPrefix = strings.ToUpper(hashPrefix)
+	if strings.Trim(hashPrefix, "0123456789ABCDEF") != "" {
+		return nil, fmt.Errorf("hash_prefix must be hex, got %q", hashPrefix)
+	}
+
+	res := &ctypes.ResultUnconfirmedTxs{Txs: []types.Tx{}}
+	for _, memTx := range mempool.Txs() {
+		if hashPrefix != "" && !strings.HasPrefix(fmt.Sprintf("%X", memTx.Tx.Hash()), hashPrefix) {
+			continue
+		}
+		if res.Total >= offset && (limit == 0 || len(res.Txs) < limit) {
+			res.Txs = append(res.Txs, memTx.Tx)
+		}
+		res.Total++
+		res.TotalBytes += int64(len(memTx.Tx))
+		res.TotalGas += memTx.Gas
+	}
+	res.N = len(res.Txs)
+	return res, nil
 }
 
 // Get number of unconfirmed transactions.
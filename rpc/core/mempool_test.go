@@ -0,0 +1,62 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+type txsMempool struct {
+	types.MockMempool
+	txs []types.MempoolTx
+}
+
+func (m txsMempool) Txs() []types.MempoolTx { return m.txs }
+
+func TestUnconfirmedTxs(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	var memTxs []types.MempoolTx
+	for i := 0; i < 10; i++ {
+		memTxs = append(memTxs, types.MempoolTx{Tx: types.Tx(fmt.Sprintf("tx-%d", i)), Gas: int64(i)})
+	}
+	SetMempool(txsMempool{txs: memTxs})
+	defer SetMempool(nil)
+
+	// no limit
+	res, err := UnconfirmedTxs(0, 0, "")
+	require.Nil(err)
+	assert.Equal(10, res.N)
+	assert.Equal(memTxs[0].Tx, res.Txs[0])
+	assert.Equal(10, res.Total)
+	assert.EqualValues(10*len("tx-0"), res.TotalBytes)
+	assert.EqualValues(45, res.TotalGas)
+
+	// the stats cover all the txs, not just the page
+	res, err = UnconfirmedTxs(3, 8, "")
+	require.Nil(err)
+	assert.Equal(types.Txs{memTxs[8].Tx, memTxs[9].Tx}, types.Txs(res.Txs))
+	assert.Equal(10, res.Total)
+
+	res, err = UnconfirmedTxs(3, 20, "")
+	require.Nil(err)
+	assert.Equal(0, res.N)
+	assert.Equal([]types.Tx{}, res.Txs)
+
+	// filter by hash prefix, in any case
+	hash := fmt.Sprintf("%x", memTxs[4].Tx.Hash())
+	res, err = UnconfirmedTxs(0, 0, hash[:10])
+	require.Nil(err)
+	assert.Equal(types.Txs{memTxs[4].Tx}, types.Txs(res.Txs))
+	assert.Equal(1, res.Total)
+	assert.EqualValues(4, res.TotalGas)
+
+	for _, args := range [][]interface{}{{-1, 0, ""}, {0, -1, ""}, {0, 0, "xyz"}} {
+		_, err = UnconfirmedTxs(args[0].(int), args[1].(int), args[2].(string))
+		assert.NotNil(err, "%v", args)
+	}
+}
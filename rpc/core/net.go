@@ -1,9 +1,13 @@
 package core
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+	"github.com/tendermint/tendermint/types"
 )
 
 // Get network info.
@@ -68,6 +72,82 @@ func UnsafeDialSeeds(seeds []string) (*ctypes.ResultDialSeeds, error) {
 	return &ctypes.ResultDialSeeds{"Dialing seeds in progress. See /net_info for details"}, nil
 }
 
+// Get the peers banned for misbehaving, or manually, by ID or IP.
+//
+// ```shell
+// curl 'localhost:46657/banned_peers'
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:46657", "/websocket")
+// result, err := client.BannedPeers()
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+// 	"error": "",
+// 	"result": {
+// 		"bans": [
+// 			{
+// 				"target": "4f8ac2bd6e0fd52bbcd2a8ab1a6a1b4c2d1e7a90",
+// 				"until": "2018-01-10T12:10:00.000Z",
+// 				"reason": "Error adding vote",
+// 				"count": 1
+// 			}
+// 		]
+// 	},
+// 	"id": "",
+// 	"jsonrpc": "2.0"
+// }
+// ```
+func BannedPeers() (*ctypes.ResultBannedPeers, error) {
+	return &ctypes.ResultBannedPeers{p2pSwitch.BannedPeers()}, nil
+}
+
+// UnsafeBanPeer bans the ID or the IP of a peer for the duration, in seconds,
+// or for longer and longer durations if 0 (10 minutes, doubled by each ban),
+// and disconnects the peers it bans.
+//
+// ```shell
+// curl 'localhost:46657/unsafe_ban_peer?target="127.0.0.1"&duration=3600&reason="spam"'
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:46657", "/websocket")
+// result, err := client.BanPeer("127.0.0.1", time.Hour, "spam")
+// ```
+//
+// ### Query Parameters
+//
+// | Parameter | Type   | Default | Required | Description                          |
+// |-----------+--------+---------+----------+--------------------------------------|
+// | target    | string | ""      | true     | ID or IP of the peer                 |
+// | duration  | int64  | 0       | false    | Duration of the ban, in seconds      |
+// | reason    | string | ""      | false    | Reason of the ban, listed with it    |
+func UnsafeBanPeer(target string, duration int64, reason string) (*ctypes.ResultBanPeer, error) {
+	ban, err := p2pSwitch.BanPeer(target, time.Duration(duration)*time.Second, reason)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Banned peer", "target", target, "until", ban.Until)
+	return &ctypes.ResultBanPeer{ban}, nil
+}
+
+// UnsafeUnbanPeer lifts the ban of the ID or the IP of a peer.
+//
+// ```shell
+// curl 'localhost:46657/unsafe_unban_peer?target="127.0.0.1"'
+// ```
+func UnsafeUnbanPeer(target string) (*ctypes.ResultUnbanPeer, error) {
+	if err := p2pSwitch.UnbanPeer(target); err != nil {
+		return nil, err
+	}
+	logger.Info("Unbanned peer", "target", target)
+	return &ctypes.ResultUnbanPeer{}, nil
+}
+
 // Get genesis file.
 //
 // ```shell
@@ -105,6 +185,84 @@ func UnsafeDialSeeds(seeds []string) (*ctypes.ResultDialSeeds, error) {
 // 	"jsonrpc": "2.0"
 // }
 // ```
+//
+// <aside class="notice">Fails if the genesis doc is larger than a chunk of
+// `/genesis_chunked`, which must be used instead.</aside>
 func Genesis() (*ctypes.ResultGenesis, error) {
+	if len(genChunks) > 1 {
+		return nil, fmt.Errorf("The genesis doc is in %d chunks, use /genesis_chunked", len(genChunks))
+	}
 	return &ctypes.ResultGenesis{genDoc}, nil
 }
+
+// genesisChunkSize is the max size of the chunks of the JSON genesis doc
+// served by GenesisChunked, before their base64 encoding.
+var genesisChunkSize = 16 * 1024 * 1024
+
+// Get a chunk of the JSON genesis doc, base64 encoded, and the number of
+// chunks, from 0, for the genesis docs too large for `/genesis`, eg. with a
+// big app state. The chunks are 16MB but the last one. The client helper
+// `FetchGenesis` reassembles them.
+//
+// ```shell
+// curl 'localhost:46657/genesis_chunked?chunk=0'
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:46657", "/websocket")
+// chunk, err := client.GenesisChunked(0)
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+// 	"error": "",
+// 	"result": {
+// 		"chunk": 0,
+// 		"total": 1,
+// 		"data": "eyJnZW5lc2lzX3RpbWUiOiIyMDE3LTA1LTI5VDE1OjA1OjQxLjY3MVoiLCJjaGFpbl9pZCI6InRlc3QtY2hhaW4tNlVUTklOIiwidmFsaWRhdG9ycyI6W119"
+// 	},
+// 	"id": "",
+// 	"jsonrpc": "2.0"
+// }
+// ```
+//
+// ### Query Parameters
+//
+// | Parameter | Type | Default | Required | Description             |
+// |-----------+------+---------+----------+-------------------------|
+// | chunk     | int  | 0       | false    | Number of the chunk     |
+func GenesisChunked(chunk int) (*ctypes.ResultGenesisChunk, error) {
+	if genChunksErr != nil {
+		return nil, genChunksErr
+	}
+	if len(genChunks) == 0 {
+		return nil, fmt.Errorf("No genesis doc")
+	}
+	if chunk < 0 || chunk >= len(genChunks) {
+		return nil, fmt.Errorf("Chunk must be between 0 and %d", len(genChunks)-1)
+	}
+	return &ctypes.ResultGenesisChunk{chunk, len(genChunks), genChunks[chunk]}, nil
+}
+
+// chunkGenesisDoc returns the chunks of the JSON genesis doc, of size bytes
+// but the last one, base64 encoded.
+func chunkGenesisDoc(doc *types.GenesisDoc, size int) ([]string, error) {
+	if doc == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("Error encoding the genesis doc: %v", err)
+	}
+	chunks := make([]string, 0, (len(b)+size-1)/size)
+	for start := 0; start < len(b); start += size {
+		end := start + size
+		if end > len(b) {
+			end = len(b)
+		}
+		chunks = append(chunks, base64.StdEncoding.EncodeToString(b[start:end]))
+	}
+	return chunks, nil
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestGenesisChunked(t *testing.T) {
+	defer func(doc *types.GenesisDoc, size int) {
+		genesisChunkSize = size
+		SetGenesisDoc(doc)
+	}(genDoc, genesisChunkSize)
+
+	doc := &types.GenesisDoc{
+		ChainID:    "test-chain",
+		AppOptions: map[string]string{"accounts": strings.Repeat("a", 100)},
+	}
+	b, err := json.Marshal(doc)
+	require.Nil(t, err)
+
+	// in one chunk
+	SetGenesisDoc(doc)
+	_, err = Genesis()
+	assert.Nil(t, err)
+	chunk, err := GenesisChunked(0)
+	require.Nil(t, err)
+	assert.Equal(t, 1, chunk.TotalChunks)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(b), chunk.Data)
+
+	// too large for /genesis
+	genesisChunkSize = 50
+	SetGenesisDoc(doc)
+	_, err = Genesis()
+	assert.NotNil(t, err)
+	var buf bytes.Buffer
+	total := (len(b) + 49) / 50
+	for i := 0; i < total; i++ {
+		chunk, err := GenesisChunked(i)
+		require.Nil(t, err, "chunk %d", i)
+		assert.Equal(t, i, chunk.ChunkNumber)
+		assert.Equal(t, total, chunk.TotalChunks)
+		data, err := base64.StdEncoding.DecodeString(chunk.Data)
+		require.Nil(t, err)
+		assert.True(t, len(data) <= 50)
+		buf.Write(data)
+	}
+	assert.Equal(t, b, buf.Bytes())
+
+	for _, i := range []int{-1, total} {
+		_, err = GenesisChunked(i)
+		assert.NotNil(t, err, "chunk %d", i)
+	}
+
+	SetGenesisDoc(nil)
+	_, err = GenesisChunked(0)
+	assert.NotNil(t, err)
+}
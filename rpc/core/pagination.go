@@ -0,0 +1,57 @@
+package core
+
+import (
+	"fmt"
+
+	cmn "github.com/tendermint/tmlibs/common"
+)
+
+// maxPerPage is the max number of items per page of the paginated endpoints.
+const maxPerPage = 100
+
+// The values of the order_by parameter.
+const (
+	orderAsc         = "asc"
+	orderDesc        = "desc"
+	orderAddress     = "address"
+	orderVotingPower = "voting_power"
+)
+
+// paginate returns the bounds, [start, end), of a page of the total items,
+// from page 1, with perPage items, or defaultPerPage if 0. A defaultPerPage
+// of 0 puts all the items in one page. A page of 0 is the first one.
+func paginate(page, perPage, defaultPerPage, total int) (start, end int, err error) {
+	if perPage == 0 {
+		perPage = defaultPerPage
+	} else if perPage < 0 || perPage > maxPerPage {
+		return 0, 0, fmt.Errorf("per_page must be between 1 and %d, got %d", maxPerPage, perPage)
+	}
+	if perPage == 0 {
+		perPage = cmn.MaxInt(total, 1)
+	}
+
+	pages := cmn.MaxInt((total+perPage-1)/perPage, 1)
+	if page == 0 {
+		page = 1
+	}
+	if page < 0 || page > pages {
+		return 0, 0, fmt.Errorf("page must be between 1 and %d, got %d", pages, page)
+	}
+	start = (page - 1) * perPage
+	end = cmn.MinInt(start+perPage, total)
+	return start, end, nil
+}
+
+// validateOrder returns the orderBy, or the defaultOrder if empty, or an error
+// if it's not one of the orders.
+func validateOrder(orderBy, defaultOrder string, orders ...string) (string, error) {
+	if orderBy == "" {
+		return defaultOrder, nil
+	}
+	for _, order := range orders {
+		if orderBy == order {
+			return orderBy, nil
+		}
+	}
+	return "", fmt.Errorf("order_by must be one of %v, got %q", orders, orderBy)
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaginate(t *testing.T) {
+	cases := []struct {
+		page, perPage, defaultPerPage, total int
+		start, end                           int
+		ok                                   bool
+	}{
+		// the default page and per_page
+		{0, 0, 30, 100, 0, 30, true},
+		{0, 0, 30, 10, 0, 10, true},
+		{0, 0, 30, 0, 0, 0, true},
+		// all the items if no default
+		{0, 0, 0, 150, 0, 150, true},
+		{1, 0, 0, 0, 0, 0, true},
+		// the pages
+		{2, 30, 30, 100, 30, 60, true},
+		{4, 30, 30, 100, 90, 100, true},
+		{5, 30, 30, 100, 0, 0, false},
+		{2, 10, 30, 10, 0, 0, false},
+		{-1, 10, 30, 100, 0, 0, false},
+		// the per_page
+		{1, 100, 30, 150, 0, 100, true},
+		{1, 101, 30, 150, 0, 0, false},
+		{1, -1, 30, 150, 0, 0, false},
+	}
+	for _, c := range cases {
+		start, end, err := paginate(c.page, c.perPage, c.defaultPerPage, c.total)
+		if !c.ok {
+			assert.NotNil(t, err, "%+v", c)
+			continue
+		}
+		if assert.Nil(t, err, "%+v", c) {
+			assert.Equal(t, c.start, start, "%+v", c)
+			assert.Equal(t, c.end, end, "%+v", c)
+		}
+	}
+}
+
+func TestValidateOrder(t *testing.T) {
+	order, err := validateOrder("", orderDesc, orderAsc, orderDesc)
+	assert.Nil(t, err)
+	assert.Equal(t, orderDesc, order)
+
+	order, err = validateOrder(orderAsc, orderDesc, orderAsc, orderDesc)
+	assert.Nil(t, err)
+	assert.Equal(t, orderAsc, order)
+
+	_, err = validateOrder(orderVotingPower, orderDesc, orderAsc, orderDesc)
+	assert.NotNil(t, err)
+}
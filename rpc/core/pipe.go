@@ -9,6 +9,9 @@ import (
 	p2p "github.com/tendermint/tendermint/p2p"
 	"github.com/tendermint/tendermint/proxy"
 	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/state/apphash"
+	"github.com/tendermint/tendermint/state/blockindex"
+	"github.com/tendermint/tendermint/state/signinginfo"
 	"github.com/tendermint/tendermint/state/txindex"
 	"github.com/tendermint/tendermint/types"
 	"github.com/tendermint/tmlibs/log"
@@ -16,6 +19,13 @@ import (
 
 var subscribeTimeout = 5 * time.Second
 
+// limits on the events waiting to be sent per websocket subscription, see
+// SetSubscriptionLimits
+var (
+	subscriptionBufferSize = 100
+	closeSlowSubscriptions = false
+)
+
 //----------------------------------------------
 // These interfaces are used by RPC and must be thread safe
 
@@ -23,6 +33,7 @@ type Consensus interface {
 	GetState() *sm.State
 	GetValidators() (int64, []*types.Validator)
 	GetRoundState() *cstypes.RoundState
+	ScheduleExit(height int64) error
 }
 
 type P2P interface {
@@ -32,6 +43,9 @@ type P2P interface {
 	NodeInfo() *p2p.NodeInfo
 	IsListening() bool
 	DialSeeds(*p2p.AddrBook, []string) error
+	BanPeer(target string, duration time.Duration, reason string) (*p2p.Ban, error)
+	UnbanPeer(target string) error
+	BannedPeers() []p2p.Ban
 }
 
 //----------------------------------------------
@@ -51,12 +65,27 @@ var (
 	// objects
 	pubKey           crypto.PubKey
 	genDoc           *types.GenesisDoc // cache the genesis structure
+	genChunks        []string          // of genDoc, see GenesisChunked
+	genChunksErr     error
 	addrBook         *p2p.AddrBook
 	txIndexer        txindex.TxIndexer
+	blockIndexer     blockindex.BlockIndexer // nil if not indexed
+	signingInfoStore *signinginfo.Store      // nil if not indexed
+	appHashStore     *apphash.Store          // nil if not indexed
+	integrityChecker *sm.IntegrityChecker    // nil if disabled
 	consensusReactor *consensus.ConsensusReactor
 	eventBus         *types.EventBus // thread safe
+	history          *historyCache   // nil if disabled
 
 	logger log.Logger
+
+	// limits on the queries to the app, see SetABCIQueryLimits
+	abciQueryTimeout time.Duration
+	abciQuerySlots   chan struct{}
+
+	// number of the last heights the app keeps the state of, 0 for all,
+	// see SetAppRetainHeights
+	appRetainHeights int64
 )
 
 func SetBlockStore(bs types.BlockStore) {
@@ -81,6 +110,7 @@ func SetPubKey(pk crypto.PubKey) {
 
 func SetGenesisDoc(doc *types.GenesisDoc) {
 	genDoc = doc
+	genChunks, genChunksErr = chunkGenesisDoc(doc, genesisChunkSize)
 }
 
 func SetAddrBook(book *p2p.AddrBook) {
@@ -95,6 +125,22 @@ func SetTxIndexer(indexer txindex.TxIndexer) {
 	txIndexer = indexer
 }
 
+func SetBlockIndexer(indexer blockindex.BlockIndexer) {
+	blockIndexer = indexer
+}
+
+func SetSigningInfoStore(store *signinginfo.Store) {
+	signingInfoStore = store
+}
+
+func SetAppHashStore(store *apphash.Store) {
+	appHashStore = store
+}
+
+func SetIntegrityChecker(checker *sm.IntegrityChecker) {
+	integrityChecker = checker
+}
+
 func SetConsensusReactor(conR *consensus.ConsensusReactor) {
 	consensusReactor = conR
 }
@@ -106,3 +152,33 @@ func SetLogger(l log.Logger) {
 func SetEventBus(b *types.EventBus) {
 	eventBus = b
 }
+
+// SetABCIQueryLimits sets how long to wait for the app to answer a query
+// and how many queries can be running at once. Zero means no limit.
+func SetABCIQueryLimits(timeout time.Duration, maxInFlight int) {
+	abciQueryTimeout = timeout
+	abciQuerySlots = nil
+	if maxInFlight > 0 {
+		abciQuerySlots = make(chan struct{}, maxInFlight)
+	}
+}
+
+// SetAppRetainHeights sets the number of the last heights the app keeps the
+// state of, for the queries at a height. Zero means all of them.
+func SetAppRetainHeights(retainHeights int64) {
+	appRetainHeights = retainHeights
+}
+
+// SetHistoryCacheSize sets how many blocks, commits and validator sets of the
+// past heights are kept in memory. Zero disables the cache.
+func SetHistoryCacheSize(size int) {
+	history = newHistoryCache(size)
+}
+
+// SetSubscriptionLimits sets how many events can wait to be sent per websocket
+// subscription, and whether to close the subscription rather than drop the
+// events past that.
+func SetSubscriptionLimits(bufferSize int, closeSlow bool) {
+	subscriptionBufferSize = bufferSize
+	closeSlowSubscriptions = closeSlow
+}
@@ -1,47 +1,108 @@
 package core
 
 import (
+	"fmt"
+
 	rpc "github.com/tendermint/tendermint/rpc/lib/server"
 )
 
 // TODO: better system than "unsafe" prefix
 var Routes = map[string]*rpc.RPCFunc{
 	// subscribe/unsubscribe are reserved for websocket events.
-	"subscribe":       rpc.NewWSRPCFunc(Subscribe, "query"),
+	"subscribe":       rpc.NewWSRPCFunc(Subscribe, "query,schema_version,from_height"),
 	"unsubscribe":     rpc.NewWSRPCFunc(Unsubscribe, "query"),
 	"unsubscribe_all": rpc.NewWSRPCFunc(UnsubscribeAll, ""),
 
 	// info API
-	"status":               rpc.NewRPCFunc(Status, ""),
-	"net_info":             rpc.NewRPCFunc(NetInfo, ""),
-	"blockchain":           rpc.NewRPCFunc(BlockchainInfo, "minHeight,maxHeight"),
-	"genesis":              rpc.NewRPCFunc(Genesis, ""),
-	"block":                rpc.NewRPCFunc(Block, "height"),
-	"commit":               rpc.NewRPCFunc(Commit, "height"),
-	"tx":                   rpc.NewRPCFunc(Tx, "hash,prove"),
-	"tx_search":            rpc.NewRPCFunc(TxSearch, "query,prove"),
-	"validators":           rpc.NewRPCFunc(Validators, "height"),
-	"dump_consensus_state": rpc.NewRPCFunc(DumpConsensusState, ""),
-	"unconfirmed_txs":      rpc.NewRPCFunc(UnconfirmedTxs, ""),
-	"num_unconfirmed_txs":  rpc.NewRPCFunc(NumUnconfirmedTxs, ""),
+	"status":                 rpc.NewRPCFunc(Status, ""),
+	"net_info":               rpc.NewRPCFunc(NetInfo, ""),
+	"banned_peers":           rpc.NewRPCFunc(BannedPeers, ""),
+	"blockchain":             rpc.NewRPCFunc(BlockchainInfo, "minHeight,maxHeight,page,per_page,order_by"),
+	"genesis":                rpc.NewRPCFunc(Genesis, ""),
+	"genesis_chunked":        rpc.NewRPCFunc(GenesisChunked, "chunk"),
+	"headers":                rpc.NewRPCFunc(Headers, "from,to"),
+	"block":                  rpc.NewRPCFunc(Block, "height").Cacheable(pastHeight),
+	"commit":                 rpc.NewRPCFunc(Commit, "height").Cacheable(pastHeight),
+	"block_results":          rpc.NewRPCFunc(BlockResults, "height").Cacheable(pastHeight),
+	"verify_commit":          rpc.NewRPCFunc(VerifyCommit, "header,commit,validators"),
+	"random_beacon":          rpc.NewRPCFunc(RandomBeacon, "height"),
+	"tx":                     rpc.NewRPCFunc(Tx, "hash,prove"),
+	"tx_search":              rpc.NewRPCFunc(TxSearch, "query,prove,page,per_page,order_by"),
+	"block_search":           rpc.NewRPCFunc(BlockSearch, "query"),
+	"validators":             rpc.NewRPCFunc(Validators, "height,page,per_page,order_by").Cacheable(pastHeight),
+	"validator_distribution": rpc.NewRPCFunc(ValidatorDistribution, "from,to"),
+	"signing_info":           rpc.NewRPCFunc(SigningInfo, "address,from,to"),
+	"app_hash":               rpc.NewRPCFunc(AppHash, "height"),
+	"app_hashes":             rpc.NewRPCFunc(AppHashes, "from,to"),
+	"integrity_report":       rpc.NewRPCFunc(IntegrityReport, ""),
+	"check_integrity":        rpc.NewRPCFunc(CheckIntegrity, "from,to"),
+	"dump_consensus_state":   rpc.NewRPCFunc(DumpConsensusState, ""),
+	"consensus_state":        rpc.NewRPCFunc(ConsensusState, ""),
+	"consensus_params":       rpc.NewRPCFunc(ConsensusParams, "height").Cacheable(pastHeight),
+	"unconfirmed_txs":        rpc.NewRPCFunc(UnconfirmedTxs, "limit,offset,hash_prefix"),
+	"num_unconfirmed_txs":    rpc.NewRPCFunc(NumUnconfirmedTxs, ""),
 
 	// broadcast API
 	"broadcast_tx_commit": rpc.NewRPCFunc(BroadcastTxCommit, "tx"),
-	"broadcast_tx_sync":   rpc.NewRPCFunc(BroadcastTxSync, "tx"),
-	"broadcast_tx_async":  rpc.NewRPCFunc(BroadcastTxAsync, "tx"),
+	"broadcast_tx_sync":   rpc.NewRPCFunc(BroadcastTxSync, "tx,expiry_height"),
+	"broadcast_tx_async":  rpc.NewRPCFunc(BroadcastTxAsync, "tx,expiry_height"),
 
 	// abci API
 	"abci_query": rpc.NewRPCFunc(ABCIQuery, "path,data,height,prove"),
 	"abci_info":  rpc.NewRPCFunc(ABCIInfo, ""),
 }
 
-func AddUnsafeRoutes() {
+// InspectRoutes returns the routes that only read the block store, the state
+// and the tx indexer, which is all `tendermint inspect` serves of a stopped node.
+func InspectRoutes() map[string]*rpc.RPCFunc {
+	routes := make(map[string]*rpc.RPCFunc)
+	for _, name := range []string{
+		"blockchain", "headers", "genesis", "genesis_chunked", "block", "commit", "block_results", "verify_commit", "random_beacon", "tx", "tx_search", "block_search",
+		"validators", "validator_distribution", "consensus_params", "signing_info", "app_hash", "app_hashes", "check_integrity",
+	} {
+		routes[name] = Routes[name]
+	}
+	return routes
+}
+
+// UnsafeRoutes are the routes of the unsafe methods, served with
+// `rpc.unsafe`, see AddUnsafeRoutes.
+var UnsafeRoutes = map[string]*rpc.RPCFunc{
 	// control API
-	Routes["dial_seeds"] = rpc.NewRPCFunc(UnsafeDialSeeds, "seeds")
-	Routes["unsafe_flush_mempool"] = rpc.NewRPCFunc(UnsafeFlushMempool, "")
+	"dial_seeds":           rpc.NewRPCFunc(UnsafeDialSeeds, "seeds"),
+	"unsafe_ban_peer":      rpc.NewRPCFunc(UnsafeBanPeer, "target,duration,reason"),
+	"unsafe_unban_peer":    rpc.NewRPCFunc(UnsafeUnbanPeer, "target"),
+	"unsafe_flush_mempool": rpc.NewRPCFunc(UnsafeFlushMempool, ""),
+	"unsafe_schedule_exit": rpc.NewRPCFunc(UnsafeScheduleExit, "height"),
 
 	// profiler API
-	Routes["unsafe_start_cpu_profiler"] = rpc.NewRPCFunc(UnsafeStartCPUProfiler, "filename")
-	Routes["unsafe_stop_cpu_profiler"] = rpc.NewRPCFunc(UnsafeStopCPUProfiler, "")
-	Routes["unsafe_write_heap_profile"] = rpc.NewRPCFunc(UnsafeWriteHeapProfile, "filename")
+	"unsafe_start_cpu_profiler": rpc.NewRPCFunc(UnsafeStartCPUProfiler, "filename"),
+	"unsafe_stop_cpu_profiler":  rpc.NewRPCFunc(UnsafeStopCPUProfiler, ""),
+	"unsafe_write_heap_profile": rpc.NewRPCFunc(UnsafeWriteHeapProfile, "filename"),
+}
+
+// UnsafeMethods returns the names of the UnsafeRoutes.
+func UnsafeMethods() []string {
+	methods := make([]string, 0, len(UnsafeRoutes))
+	for method := range UnsafeRoutes {
+		methods = append(methods, method)
+	}
+	return methods
+}
+
+// AddUnsafeRoutes adds the unsafe routes of the methods, or all of them if
+// none, to the Routes, or returns an error if one is unknown.
+func AddUnsafeRoutes(methods ...string) error {
+	if len(methods) == 0 {
+		methods = UnsafeMethods()
+	}
+	for _, method := range methods {
+		if UnsafeRoutes[method] == nil {
+			return fmt.Errorf("Unknown unsafe RPC method %q", method)
+		}
+	}
+	for _, method := range methods {
+		Routes[method] = UnsafeRoutes[method]
+	}
+	return nil
 }
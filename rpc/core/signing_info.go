@@ -0,0 +1,77 @@
+package core
+
+import (
+	"fmt"
+
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+// maxSigningInfoHeights is the max number of heights of a /signing_info query.
+const maxSigningInfoHeights = 100000
+
+// SigningInfo returns how many of the commits of a range of heights the
+// validator signed, among the ones it was in the validator set at, and
+// the heights it missed. The commits of the heights, from `/commit`, prove it.
+//
+// ```shell
+// curl 'localhost:46657/signing_info?address=0xE89A51D60F68385E09E716D353373B11F8FACD62&from=1&to=100'
+// ```
+//
+// ```go
+// client := client.NewHTTP("tcp://0.0.0.0:46657", "/websocket")
+// info, err := client.SigningInfo(address, 1, 100)
+// ```
+//
+// > The above command returns JSON structured like this:
+//
+// ```json
+// {
+// 	"error": "",
+// 	"result": {
+// 		"signing_info": {
+// 			"address": "E89A51D60F68385E09E716D353373B11F8FACD62",
+// 			"from": 1,
+// 			"to": 100,
+// 			"active": 100,
+// 			"signed": 98,
+// 			"missed_heights": [12, 13]
+// 		},
+// 		"last_height": 5240
+// 	},
+// 	"id": "",
+// 	"jsonrpc": "2.0"
+// }
+// ```
+//
+// ### Query Parameters
+//
+// | Parameter | Type   | Default     | Required | Description                           |
+// |-----------+--------+-------------+----------+---------------------------------------|
+// | address   | []byte | nil         | true     | The address of the validator          |
+// | from      | int64  | 1           | false    | The first height                      |
+// | to        | int64  | last height | false    | The last height, at most 100000 after |
+//
+// The heights are the ones of the commits, the commit of a height being in the
+// block of the next height, so the last height indexed is one behind the chain.
+func SigningInfo(address []byte, from, to int64) (*ctypes.ResultSigningInfo, error) {
+	if signingInfoStore == nil {
+		return nil, fmt.Errorf("The signing info is not indexed (see index_signing_info)")
+	}
+	if len(address) == 0 {
+		return nil, fmt.Errorf("Address is required")
+	}
+
+	lastHeight := signingInfoStore.LastHeight()
+	if from <= 0 {
+		from = 1
+	}
+	if to <= 0 || to > lastHeight {
+		to = lastHeight
+	}
+	if to-from >= maxSigningInfoHeights {
+		return nil, fmt.Errorf("At most %d heights can be queried at once", maxSigningInfoHeights)
+	}
+
+	info := signingInfoStore.SigningInfo(address, from, to)
+	return &ctypes.ResultSigningInfo{info, lastHeight}, nil
+}
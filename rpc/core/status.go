@@ -28,6 +28,7 @@ import (
 // 		"syncing": false,
 // 		"latest_block_time": "2017-12-07T18:19:47.617Z",
 // 		"latest_block_height": 6,
+// 		"earliest_block_height": 1,
 // 		"latest_app_hash": "",
 // 		"latest_block_hash": "A63D0C3307DEDCCFCC82ED411AE9108B70B29E02",
 // 		"pub_key": {
@@ -79,5 +80,7 @@ func Status() (*ctypes.ResultStatus, error) {
 		LatestAppHash:     latestAppHash,
 		LatestBlockHeight: latestHeight,
 		LatestBlockTime:   latestBlockTime,
-		Syncing:           consensusReactor.FastSync()}, nil
+		Syncing:           consensusReactor.FastSync(),
+
+		EarliestBlockHeight: blockStore.Base()}, nil
 }
@@ -2,11 +2,12 @@ package core
 
 import (
 	"fmt"
+	"sort"
 
 	ctypes "github.com/tendermint/tendermint/rpc/core/types"
 	"github.com/tendermint/tendermint/state/txindex/null"
 	"github.com/tendermint/tendermint/types"
-	tmquery "github.com/tendermint/tmlibs/pubsub/query"
+	tmquery "github.com/tendermint/tendermint/types/query"
 )
 
 // Tx allows you to query the transaction results. `nil` could mean the
@@ -14,7 +15,7 @@ import (
 // place.
 //
 // ```shell
-// curl "localhost:46657/tx?hash=0x2B8EC32BA2579B3B8606E42C06DE2F7AFA2556EF"
+// curl "localhost:46657/tx?hash=0x2B8EC32BA2579B3B8606E42C06DE2F7AFA2556EF&prove=true"
 // ```
 //
 // ```go
@@ -29,13 +30,13 @@ import (
 // 	"error": "",
 // 	"result": {
 // 		"proof": {
-// 			"Proof": {
+// 			"proof": {
 // 				"aunts": []
 // 			},
-// 			"Data": "YWJjZA==",
-// 			"RootHash": "2B8EC32BA2579B3B8606E42C06DE2F7AFA2556EF",
-// 			"Total": 1,
-// 			"Index": 0
+// 			"data": "YWJjZA==",
+// 			"root_hash": "2B8EC32BA2579B3B8606E42C06DE2F7AFA2556EF",
+// 			"total": 1,
+// 			"index": 0
 // 		},
 // 		"tx": "YWJjZA==",
 // 		"tx_result": {
@@ -62,7 +63,8 @@ import (
 //
 // ### Returns
 //
-// - `proof`: the `types.TxProof` object
+// - `proof`: the `types.TxProof` object, if `prove`, to verify with its `Validate(dataHash)`
+//   against the `DataHash` of the header of `height`
 // - `tx`: `[]byte` - the transaction
 // - `tx_result`: the `abci.Result` object
 // - `index`: `int` - index of the transaction
@@ -86,10 +88,11 @@ func Tx(hash []byte, prove bool) (*ctypes.ResultTx, error) {
 	height := r.Height
 	index := r.Index
 
-	var proof types.TxProof
+	var proof *types.TxProof
 	if prove {
 		block := blockStore.LoadBlock(height)
-		proof = block.Data.Txs.Proof(int(index)) // XXX: overflow on 32-bit machines
+		txProof := block.Data.Txs.Proof(int(index)) // XXX: overflow on 32-bit machines
+		proof = &txProof
 	}
 
 	return &ctypes.ResultTx{
@@ -101,26 +104,29 @@ func Tx(hash []byte, prove bool) (*ctypes.ResultTx, error) {
 	}, nil
 }
 
+// defaultTxSearchPerPage is the default number of txs per page of TxSearch.
+const defaultTxSearchPerPage = 30
+
 // TxSearch allows you to query for multiple transactions results.
 //
 // ```shell
-// curl "localhost:46657/tx_search?query=\"account.owner='Ivan'\"&prove=true"
+// curl "localhost:46657/tx_search?query=\"account.owner='Ivan'\"&prove=true&page=1&per_page=30&order_by=\"desc\""
 // ```
 //
 // ```go
 // client := client.NewHTTP("tcp://0.0.0.0:46657", "/websocket")
-// q, err := tmquery.New("account.owner='Ivan'")
-// tx, err := client.TxSearch(q, true)
+// result, err := client.TxSearch("account.owner='Ivan'", true, 1, 30, "desc")
 // ```
 //
 // > The above command returns JSON structured like this:
 //
 // ```json
 // {
-//   "result": [
+//   "result": {
+//     "txs": [
 //     {
 //       "proof": {
-//         "Proof": {
+//         "proof": {
 //           "aunts": [
 //             "J3LHbizt806uKnABNLwG4l7gXCA=",
 //             "iblMO/M1TnNtlAefJyNCeVhjAb0=",
@@ -129,23 +135,29 @@ func Tx(hash []byte, prove bool) (*ctypes.ResultTx, error) {
 //             "afhsNxFnLlZgFDoyPpdQSe0bR8g="
 //           ]
 //         },
-//         "Data": "mvZHHa7HhZ4aRT0xMDA=",
-//         "RootHash": "F6541223AA46E428CB1070E9840D2C3DF3B6D776",
-//         "Total": 32,
-//         "Index": 31
+//         "data": "mvZHHa7HhZ4aRT0xMDA=",
+//         "root_hash": "F6541223AA46E428CB1070E9840D2C3DF3B6D776",
+//         "total": 32,
+//         "index": 31
 //       },
 //       "tx": "mvZHHa7HhZ4aRT0xMDA=",
 //       "tx_result": {},
 //       "index": 31,
 //       "height": 12
 //     }
-//   ],
+//     ],
+//     "total": 1
+//   },
 //   "id": "",
 //   "jsonrpc": "2.0"
 // }
 // ```
 //
-// Returns transactions matching the given query.
+// Returns transactions matching the given query, a page at a time, ordered by
+// height and index, and the number of them. The conditions on the tags
+// can be combined with AND and OR, grouped with parentheses, and EXISTS matches
+// the txs with a tag whatever its value, eg.
+// "tx.height > 5 AND (account.owner='Ivan' OR account.refund EXISTS)".
 //
 // ### Query Parameters
 //
@@ -153,20 +165,31 @@ func Tx(hash []byte, prove bool) (*ctypes.ResultTx, error) {
 // |-----------+--------+---------+----------+-----------------------------------------------------------|
 // | query     | string | ""      | true     | Query                                                     |
 // | prove     | bool   | false   | false    | Include proofs of the transactions inclusion in the block |
+// | page      | int    | 1       | false    | Page of the transactions, from 1                          |
+// | per_page  | int    | 30      | false    | Number of transactions per page, at most 100              |
+// | order_by  | string | "asc"   | false    | Order of the transactions, "asc" or "desc"                |
 //
 // ### Returns
 //
-// - `proof`: the `types.TxProof` object
-// - `tx`: `[]byte` - the transaction
-// - `tx_result`: the `abci.Result` object
-// - `index`: `int` - index of the transaction
-// - `height`: `int` - height of the block where this transaction was in
-func TxSearch(query string, prove bool) ([]*ctypes.ResultTx, error) {
+// - `txs`: the transactions of the page, each with:
+//   - `proof`: the `types.TxProof` object, if `prove`, to verify with its `Validate(dataHash)`
+//     against the `DataHash` of the header of `height`
+//   - `tx`: `[]byte` - the transaction
+//   - `tx_result`: the `abci.Result` object
+//   - `index`: `int` - index of the transaction
+//   - `height`: `int` - height of the block where this transaction was in
+// - `total`: `int` - number of transactions matching the query
+func TxSearch(query string, prove bool, page, perPage int, orderBy string) (*ctypes.ResultTxSearch, error) {
 	// if index is disabled, return error
 	if _, ok := txIndexer.(*null.TxIndex); ok {
 		return nil, fmt.Errorf("Transaction indexing is disabled.")
 	}
 
+	orderBy, err := validateOrder(orderBy, orderAsc, orderAsc, orderDesc)
+	if err != nil {
+		return nil, err
+	}
+
 	q, err := tmquery.New(query)
 	if err != nil {
 		return nil, err
@@ -177,27 +200,40 @@ func TxSearch(query string, prove bool) ([]*ctypes.ResultTx, error) {
 		return nil, err
 	}
 
-	// TODO: we may want to consider putting a maximum on this length and somehow
-	// informing the user that things were truncated.
-	apiResults := make([]*ctypes.ResultTx, len(results))
-	var proof types.TxProof
-	for i, r := range results {
+	// the indexers may return the results in any order
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if orderBy == orderDesc {
+			a, b = b, a
+		}
+		return a.Height < b.Height || (a.Height == b.Height && a.Index < b.Index)
+	})
+
+	start, end, err := paginate(page, perPage, defaultTxSearchPerPage, len(results))
+	if err != nil {
+		return nil, err
+	}
+
+	apiResults := make([]*ctypes.ResultTx, 0, end-start)
+	for _, r := range results[start:end] {
 		height := r.Height
 		index := r.Index
 
+		var proof *types.TxProof
 		if prove {
 			block := blockStore.LoadBlock(height)
-			proof = block.Data.Txs.Proof(int(index)) // XXX: overflow on 32-bit machines
+			txProof := block.Data.Txs.Proof(int(index)) // XXX: overflow on 32-bit machines
+			proof = &txProof
 		}
 
-		apiResults[i] = &ctypes.ResultTx{
+		apiResults = append(apiResults, &ctypes.ResultTx{
 			Height:   height,
 			Index:    index,
 			TxResult: r.Result,
 			Tx:       r.Tx,
 			Proof:    proof,
-		}
+		})
 	}
 
-	return apiResults, nil
+	return &ctypes.ResultTxSearch{apiResults, len(results)}, nil
 }
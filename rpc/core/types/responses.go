@@ -9,18 +9,36 @@ import (
 	"github.com/tendermint/go-wire/data"
 	cstypes "github.com/tendermint/tendermint/consensus/types"
 	"github.com/tendermint/tendermint/p2p"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/state/signinginfo"
 	"github.com/tendermint/tendermint/types"
 )
 
 type ResultBlockchainInfo struct {
 	LastHeight int64              `json:"last_height"`
 	BlockMetas []*types.BlockMeta `json:"block_metas"`
+
+	// the blocks in the range, before pagination
+	Total int `json:"total"`
+}
+
+// Headers of a range of blocks, in increasing height
+type ResultHeaders struct {
+	LastHeight int64              `json:"last_height"`
+	BlockMetas []*types.BlockMeta `json:"block_metas"`
 }
 
 type ResultGenesis struct {
 	Genesis *types.GenesisDoc `json:"genesis"`
 }
 
+// A chunk of the JSON genesis doc, base64 encoded
+type ResultGenesisChunk struct {
+	ChunkNumber int    `json:"chunk"`
+	TotalChunks int    `json:"total"`
+	Data        string `json:"data"`
+}
+
 type ResultBlock struct {
 	BlockMeta *types.BlockMeta `json:"block_meta"`
 	Block     *types.Block     `json:"block"`
@@ -33,6 +51,12 @@ type ResultCommit struct {
 	CanonicalCommit bool `json:"canonical"`
 }
 
+// ResultVerifyCommit is the verification of a commit, embedded so we only have
+// one level in the json output
+type ResultVerifyCommit struct {
+	types.CommitVerification
+}
+
 // NewResultCommit is a helper to initialize the ResultCommit with
 // the embedded struct
 func NewResultCommit(header *types.Header, commit *types.Commit,
@@ -47,6 +71,41 @@ func NewResultCommit(header *types.Header, commit *types.Commit,
 	}
 }
 
+// Results of executing the block at a height
+type ResultBlockResults struct {
+	Height                int64                        `json:"height"`
+	DeliverTx             []*abci.ResponseDeliverTx    `json:"deliver_tx"`
+	ValidatorUpdates      []*abci.Validator            `json:"validator_updates"`
+	ConsensusParamsUpdate *types.ConsensusParamsUpdate `json:"consensus_params_update"`
+}
+
+type ResultRandomBeacon struct {
+	Height       int64      `json:"height"`
+	RandomBeacon data.Bytes `json:"random_beacon"`
+}
+
+type ResultSigningInfo struct {
+	SigningInfo *signinginfo.SigningInfo `json:"signing_info"`
+	LastHeight  int64                    `json:"last_height"` // the last height indexed
+}
+
+type ResultAppHash struct {
+	Height  int64      `json:"height"`
+	AppHash data.Bytes `json:"app_hash"`
+}
+
+// App hashes of the headers of a range of heights, in increasing height
+type ResultAppHashes struct {
+	From       int64        `json:"from"`
+	To         int64        `json:"to"`
+	AppHashes  []data.Bytes `json:"app_hashes"`
+	LastHeight int64        `json:"last_height"` // the last height indexed
+}
+
+type ResultIntegrityReport struct {
+	Report *sm.IntegrityReport `json:"report"`
+}
+
 type ResultStatus struct {
 	NodeInfo          *p2p.NodeInfo `json:"node_info"`
 	PubKey            crypto.PubKey `json:"pub_key"`
@@ -55,6 +114,9 @@ type ResultStatus struct {
 	LatestBlockHeight int64         `json:"latest_block_height"`
 	LatestBlockTime   time.Time     `json:"latest_block_time"`
 	Syncing           bool          `json:"syncing"`
+
+	// the lowest height stored, the blocks before being pruned
+	EarliestBlockHeight int64 `json:"earliest_block_height"`
 }
 
 func (s *ResultStatus) TxIndexEnabled() bool {
@@ -80,6 +142,16 @@ type ResultDialSeeds struct {
 	Log string `json:"log"`
 }
 
+type ResultBannedPeers struct {
+	Bans []p2p.Ban `json:"bans"`
+}
+
+type ResultBanPeer struct {
+	Ban *p2p.Ban `json:"ban"`
+}
+
+type ResultUnbanPeer struct{}
+
 type Peer struct {
 	p2p.NodeInfo     `json:"node_info"`
 	IsOutbound       bool                 `json:"is_outbound"`
@@ -89,6 +161,16 @@ type Peer struct {
 type ResultValidators struct {
 	BlockHeight int64              `json:"block_height"`
 	Validators  []*types.Validator `json:"validators"`
+
+	// the validators of the set, before pagination
+	Total int `json:"total"`
+}
+
+// Distributions of the voting power of the validator sets of a range of heights,
+// in increasing height
+type ResultValidatorDistribution struct {
+	LastHeight    int64                     `json:"last_height"`
+	Distributions []types.PowerDistribution `json:"distributions"`
 }
 
 type ResultDumpConsensusState struct {
@@ -96,6 +178,15 @@ type ResultDumpConsensusState struct {
 	PeerRoundStates map[string]*cstypes.PeerRoundState `json:"peer_round_states"`
 }
 
+type ResultConsensusState struct {
+	RoundState *cstypes.RoundStateSummary `json:"round_state"`
+}
+
+type ResultConsensusParams struct {
+	BlockHeight     int64                 `json:"block_height"`
+	ConsensusParams types.ConsensusParams `json:"consensus_params"`
+}
+
 type ResultBroadcastTx struct {
 	Code uint32     `json:"code"`
 	Data data.Bytes `json:"data"`
@@ -116,12 +207,28 @@ type ResultTx struct {
 	Index    uint32                 `json:"index"`
 	TxResult abci.ResponseDeliverTx `json:"tx_result"`
 	Tx       types.Tx               `json:"tx"`
-	Proof    types.TxProof          `json:"proof,omitempty"`
+	Proof    *types.TxProof         `json:"proof,omitempty"` // only if proven
+}
+
+type ResultTxSearch struct {
+	Txs []*ResultTx `json:"txs"`
+
+	// the txs matching the query, before pagination
+	Total int `json:"total"`
+}
+
+type ResultBlockSearch struct {
+	BlockMetas []*types.BlockMeta `json:"block_metas"`
 }
 
 type ResultUnconfirmedTxs struct {
 	N   int        `json:"n_txs"`
 	Txs []types.Tx `json:"txs"`
+
+	// the txs matching the filter, before pagination
+	Total      int   `json:"total"`
+	TotalBytes int64 `json:"total_bytes"`
+	TotalGas   int64 `json:"total_gas"`
 }
 
 type ResultABCIInfo struct {
@@ -132,6 +239,11 @@ type ResultABCIQuery struct {
 	Response abci.ResponseQuery `json:"response"`
 }
 
+type ResultScheduleExit struct {
+	Address    data.Bytes `json:"address"`
+	StopHeight int64      `json:"stop_height"`
+}
+
 type ResultUnsafeFlushMempool struct{}
 
 type ResultUnsafeProfile struct{}
@@ -144,3 +256,10 @@ type ResultEvent struct {
 	Query string            `json:"query"`
 	Data  types.TMEventData `json:"data"`
 }
+
+// ResultEventMessage is an event in a version of the event schema,
+// see types.EventMessage
+type ResultEventMessage struct {
+	Query string              `json:"query"`
+	Event *types.EventMessage `json:"event"`
+}
@@ -2,9 +2,13 @@ package core_grpc
 
 import (
 	"context"
+	"fmt"
 
 	abci "github.com/tendermint/abci/types"
+	cmn "github.com/tendermint/tmlibs/common"
+
 	core "github.com/tendermint/tendermint/rpc/core"
+	tmtypes "github.com/tendermint/tendermint/types"
 )
 
 type broadcastAPI struct {
@@ -34,3 +38,92 @@ func (bapi *broadcastAPI) BroadcastTx(ctx context.Context, req *RequestBroadcast
 		},
 	}, nil
 }
+
+//----------------------------------------
+
+// coreAPI serves the RPC of rpc/core over gRPC.
+type coreAPI struct {
+}
+
+func (capi *coreAPI) Status(ctx context.Context, req *RequestStatus) (*ResponseStatus, error) {
+	res, err := core.Status()
+	if err != nil {
+		return nil, err
+	}
+	return &ResponseStatus{
+		NodeInfo:          nodeInfoToProto(res.NodeInfo),
+		PubKey:            res.PubKey.Bytes(),
+		LatestBlockHash:   res.LatestBlockHash,
+		LatestAppHash:     res.LatestAppHash,
+		LatestBlockHeight: res.LatestBlockHeight,
+		LatestBlockTime:   timeToProto(res.LatestBlockTime),
+		Syncing:           res.Syncing,
+	}, nil
+}
+
+func (capi *coreAPI) Block(ctx context.Context, req *RequestBlock) (*ResponseBlock, error) {
+	var heightPtr *int64
+	if req.Height != 0 {
+		heightPtr = &req.Height
+	}
+	res, err := core.Block(heightPtr)
+	if err != nil {
+		return nil, err
+	}
+	if res.BlockMeta == nil || res.Block == nil {
+		return nil, fmt.Errorf("No block yet")
+	}
+	return &ResponseBlock{
+		BlockId: tmtypes.TM2PB.BlockID(res.BlockMeta.BlockID),
+		Block:   blockToProto(res.Block),
+	}, nil
+}
+
+func (capi *coreAPI) Tx(ctx context.Context, req *RequestTx) (*ResponseTx, error) {
+	res, err := core.Tx(req.Hash, req.Prove)
+	if err != nil {
+		return nil, err
+	}
+	return txToProto(res.Height, res.Index, res.Tx, res.TxResult, res.Proof), nil
+}
+
+func (capi *coreAPI) ABCIQuery(ctx context.Context, req *RequestABCIQuery) (*ResponseABCIQuery, error) {
+	res, err := core.ABCIQuery(ctx, req.Path, req.Data, req.Height, req.Trusted)
+	if err != nil {
+		return nil, err
+	}
+	return &ResponseABCIQuery{Response: &res.Response}, nil
+}
+
+// Subscribe streams the events matching the query until the client goes away.
+func (capi *coreAPI) Subscribe(req *RequestSubscribe, stream CoreAPI_SubscribeServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	subscriber := fmt.Sprintf("grpc-%s", cmn.RandStr(12))
+	ch := make(chan interface{})
+	if err := core.SubscribeEvents(ctx, subscriber, req.Query, ch); err != nil {
+		return err
+	}
+
+	// ch is closed once unsubscribed, when the ctx is done, and is read until
+	// then so as not to block the event bus
+	var sendErr error
+	for event := range ch {
+		if sendErr != nil {
+			continue
+		}
+		res, err := eventToProto(req.Query, event.(tmtypes.TMEventData))
+		if err == nil {
+			err = stream.Send(res)
+		}
+		if err != nil {
+			sendErr = err
+			cancel()
+		}
+	}
+	if sendErr != nil {
+		return sendErr
+	}
+	return ctx.Err()
+}
@@ -25,6 +25,7 @@ func StartGRPCServer(protoAddr string) (net.Listener, error) {
 
 	grpcServer := grpc.NewServer()
 	RegisterBroadcastAPIServer(grpcServer, &broadcastAPI{})
+	RegisterCoreAPIServer(grpcServer, &coreAPI{})
 	go grpcServer.Serve(ln) // nolint: errcheck
 
 	return ln, nil
@@ -32,11 +33,20 @@ func StartGRPCServer(protoAddr string) (net.Listener, error) {
 
 // Start the client by dialing the server
 func StartGRPCClient(protoAddr string) BroadcastAPIClient {
+	return NewBroadcastAPIClient(dialGRPC(protoAddr))
+}
+
+// Start the client of the CoreAPI by dialing the server
+func StartGRPCCoreClient(protoAddr string) CoreAPIClient {
+	return NewCoreAPIClient(dialGRPC(protoAddr))
+}
+
+func dialGRPC(protoAddr string) *grpc.ClientConn {
 	conn, err := grpc.Dial(protoAddr, grpc.WithInsecure(), grpc.WithDialer(dialerFunc))
 	if err != nil {
 		panic(err)
 	}
-	return NewBroadcastAPIClient(conn)
+	return conn
 }
 
 func dialerFunc(addr string, timeout time.Duration) (net.Conn, error) {
@@ -0,0 +1,164 @@
+package core_grpc
+
+import (
+	"encoding/json"
+	"time"
+
+	abci "github.com/tendermint/abci/types"
+	wire "github.com/tendermint/go-wire"
+
+	"github.com/tendermint/tendermint/p2p"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// timeToProto returns the time in unix nanoseconds, or 0 for the zero time.
+func timeToProto(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func nodeInfoToProto(ni *p2p.NodeInfo) *NodeInfo {
+	if ni == nil {
+		return nil
+	}
+	return &NodeInfo{
+		PubKey:     ni.PubKey.Bytes(),
+		Moniker:    ni.Moniker,
+		Network:    ni.Network,
+		RemoteAddr: ni.RemoteAddr,
+		ListenAddr: ni.ListenAddr,
+		Version:    ni.Version,
+		Other:      ni.Other,
+	}
+}
+
+func headerToProto(h *tmtypes.Header) *Header {
+	if h == nil {
+		return nil
+	}
+	return &Header{
+		ChainId:         h.ChainID,
+		Height:          h.Height,
+		Time:            timeToProto(h.Time),
+		NumTxs:          int64(h.NumTxs),
+		LastBlockId:     tmtypes.TM2PB.BlockID(h.LastBlockID),
+		LastCommitHash:  h.LastCommitHash,
+		DataHash:        h.DataHash,
+		ValidatorsHash:  h.ValidatorsHash,
+		AppHash:         h.AppHash,
+		LastResultsHash: h.LastResultsHash,
+		RandomBeacon:    h.RandomBeacon,
+		EvidenceHash:    h.EvidenceHash,
+	}
+}
+
+func voteToProto(v *tmtypes.Vote) *Vote {
+	if v == nil {
+		return nil
+	}
+	vote := &Vote{
+		ValidatorAddress: v.ValidatorAddress,
+		ValidatorIndex:   int32(v.ValidatorIndex),
+		Height:           v.Height,
+		Round:            int32(v.Round),
+		Type:             int32(v.Type),
+		BlockId:          tmtypes.TM2PB.BlockID(v.BlockID),
+		Timestamp:        timeToProto(v.Timestamp),
+	}
+	if !v.Signature.Empty() {
+		vote.Signature = v.Signature.Bytes()
+	}
+	return vote
+}
+
+func commitToProto(c *tmtypes.Commit) *Commit {
+	if c == nil {
+		return nil
+	}
+	commit := &Commit{
+		BlockId:    tmtypes.TM2PB.BlockID(c.BlockID),
+		Precommits: make([]*Vote, len(c.Precommits)),
+	}
+	for i, precommit := range c.Precommits {
+		// nil if absent, an empty vote in the repeated field
+		commit.Precommits[i] = voteToProto(precommit)
+		if commit.Precommits[i] == nil {
+			commit.Precommits[i] = &Vote{}
+		}
+	}
+	if !c.AggregatedSignature.Empty() {
+		commit.AggregatedSignature = c.AggregatedSignature.Bytes()
+	}
+	return commit
+}
+
+func blockToProto(b *tmtypes.Block) *Block {
+	if b == nil {
+		return nil
+	}
+	block := &Block{
+		Header:     headerToProto(b.Header),
+		LastCommit: commitToProto(b.LastCommit),
+	}
+	if b.Data != nil {
+		block.Txs = make([][]byte, len(b.Data.Txs))
+		for i, tx := range b.Data.Txs {
+			block.Txs[i] = tx
+		}
+	}
+	block.Evidence = make([][]byte, len(b.Evidence.Evidence))
+	for i, ev := range b.Evidence.Evidence {
+		block.Evidence[i] = wire.BinaryBytes(struct{ tmtypes.Evidence }{ev})
+	}
+	return block
+}
+
+func txToProto(height int64, index uint32, tx tmtypes.Tx, result abci.ResponseDeliverTx, proof *tmtypes.TxProof) *ResponseTx {
+	res := &ResponseTx{
+		Hash:     tx.Hash(),
+		Height:   height,
+		Index:    index,
+		TxResult: &result,
+		Tx:       tx,
+	}
+	if proof != nil {
+		res.Proof = &TxProof{
+			Index:    int32(proof.Index),
+			Total:    int32(proof.Total),
+			RootHash: proof.RootHash,
+			Data:     proof.Data,
+			Aunts:    proof.Proof.Aunts,
+		}
+	}
+	return res
+}
+
+// eventToProto returns the event of a subscription to the query. The data of
+// the events other than the blocks, headers and txs is their JSON.
+func eventToProto(query string, event tmtypes.TMEventData) (*ResponseEvent, error) {
+	res := &ResponseEvent{Query: query}
+	switch data := event.Unwrap().(type) {
+	case tmtypes.EventDataNewBlock:
+		res.Type, res.Block = tmtypes.EventDataNameNewBlock, blockToProto(data.Block)
+	case tmtypes.EventDataNewBlockHeader:
+		res.Type, res.Header = tmtypes.EventDataNameNewBlockHeader, headerToProto(data.Header)
+	case tmtypes.EventDataTx:
+		res.Type, res.Tx = tmtypes.EventDataNameTx, txToProto(data.Height, data.Index, data.Tx, data.Result, nil)
+	default:
+		bz, err := json.Marshal(event)
+		if err != nil {
+			return nil, err
+		}
+		var typed struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(bz, &typed); err != nil {
+			return nil, err
+		}
+		res.Type, res.Data = typed.Type, typed.Data
+	}
+	return res, nil
+}
@@ -31,3 +31,51 @@ func TestBroadcastTx(t *testing.T) {
 	require.EqualValues(0, res.CheckTx.Code)
 	require.EqualValues(0, res.DeliverTx.Code)
 }
+
+func TestCoreAPI(t *testing.T) {
+	require := require.New(t)
+	client := rpctest.GetGRPCCoreClient()
+	ctx := context.Background()
+
+	// the txs are streamed once subscribed
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := client.Subscribe(subCtx, &core_grpc.RequestSubscribe{"tm.event = 'Tx'"})
+	require.Nil(err, "%+v", err)
+
+	tx := []byte("grpc=core")
+	bres, err := rpctest.GetGRPCClient().BroadcastTx(ctx, &core_grpc.RequestBroadcastTx{tx})
+	require.Nil(err, "%+v", err)
+	require.EqualValues(0, bres.DeliverTx.Code)
+
+	event, err := stream.Recv()
+	require.Nil(err, "%+v", err)
+	require.Equal("tx", event.Type)
+	require.Equal(tx, event.Tx.Tx)
+
+	status, err := client.Status(ctx, &core_grpc.RequestStatus{})
+	require.Nil(err, "%+v", err)
+	require.True(status.LatestBlockHeight >= event.Tx.Height)
+	require.NotNil(status.NodeInfo)
+
+	block, err := client.Block(ctx, &core_grpc.RequestBlock{event.Tx.Height})
+	require.Nil(err, "%+v", err)
+	require.EqualValues(event.Tx.Height, block.Block.Header.Height)
+	require.Contains(block.Block.Txs, tx)
+
+	txRes, err := client.Tx(ctx, &core_grpc.RequestTx{event.Tx.Hash, true})
+	require.Nil(err, "%+v", err)
+	require.Equal(tx, txRes.Tx)
+	require.NotNil(txRes.Proof)
+	require.EqualValues(tx, txRes.Proof.Data)
+
+	query, err := client.ABCIQuery(ctx, &core_grpc.RequestABCIQuery{Path: "/key", Data: []byte("grpc")})
+	require.Nil(err, "%+v", err)
+	require.EqualValues("core", query.Response.Value)
+
+	// the errors of a stream are received
+	stream, err = client.Subscribe(ctx, &core_grpc.RequestSubscribe{"tm.event = "})
+	require.Nil(err, "%+v", err)
+	_, err = stream.Recv()
+	require.NotNil(err)
+}
@@ -9,10 +9,26 @@ It is generated from these files:
 	types.proto
 
 It has these top-level messages:
+	NodeInfo
+	Header
+	Vote
+	Commit
+	Block
+	TxProof
 	RequestPing
 	RequestBroadcastTx
+	RequestStatus
+	RequestBlock
+	RequestTx
+	RequestABCIQuery
+	RequestSubscribe
 	ResponsePing
 	ResponseBroadcastTx
+	ResponseStatus
+	ResponseBlock
+	ResponseTx
+	ResponseABCIQuery
+	ResponseEvent
 */
 package core_grpc
 
@@ -38,13 +54,373 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+type NodeInfo struct {
+	PubKey     []byte   `protobuf:"bytes,1,opt,name=pub_key,json=pubKey,proto3" json:"pub_key,omitempty"`
+	Moniker    string   `protobuf:"bytes,2,opt,name=moniker" json:"moniker,omitempty"`
+	Network    string   `protobuf:"bytes,3,opt,name=network" json:"network,omitempty"`
+	RemoteAddr string   `protobuf:"bytes,4,opt,name=remote_addr,json=remoteAddr" json:"remote_addr,omitempty"`
+	ListenAddr string   `protobuf:"bytes,5,opt,name=listen_addr,json=listenAddr" json:"listen_addr,omitempty"`
+	Version    string   `protobuf:"bytes,6,opt,name=version" json:"version,omitempty"`
+	Other      []string `protobuf:"bytes,7,rep,name=other" json:"other,omitempty"`
+}
+
+func (m *NodeInfo) Reset()                    { *m = NodeInfo{} }
+func (m *NodeInfo) String() string            { return proto.CompactTextString(m) }
+func (*NodeInfo) ProtoMessage()               {}
+func (*NodeInfo) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+func (m *NodeInfo) GetPubKey() []byte {
+	if m != nil {
+		return m.PubKey
+	}
+	return nil
+}
+
+func (m *NodeInfo) GetMoniker() string {
+	if m != nil {
+		return m.Moniker
+	}
+	return ""
+}
+
+func (m *NodeInfo) GetNetwork() string {
+	if m != nil {
+		return m.Network
+	}
+	return ""
+}
+
+func (m *NodeInfo) GetRemoteAddr() string {
+	if m != nil {
+		return m.RemoteAddr
+	}
+	return ""
+}
+
+func (m *NodeInfo) GetListenAddr() string {
+	if m != nil {
+		return m.ListenAddr
+	}
+	return ""
+}
+
+func (m *NodeInfo) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+func (m *NodeInfo) GetOther() []string {
+	if m != nil {
+		return m.Other
+	}
+	return nil
+}
+
+type Header struct {
+	ChainId         string         `protobuf:"bytes,1,opt,name=chain_id,json=chainId" json:"chain_id,omitempty"`
+	Height          int64          `protobuf:"varint,2,opt,name=height" json:"height,omitempty"`
+	Time            int64          `protobuf:"varint,3,opt,name=time" json:"time,omitempty"`
+	NumTxs          int64          `protobuf:"varint,4,opt,name=num_txs,json=numTxs" json:"num_txs,omitempty"`
+	LastBlockId     *types.BlockID `protobuf:"bytes,5,opt,name=last_block_id,json=lastBlockId" json:"last_block_id,omitempty"`
+	LastCommitHash  []byte         `protobuf:"bytes,6,opt,name=last_commit_hash,json=lastCommitHash,proto3" json:"last_commit_hash,omitempty"`
+	DataHash        []byte         `protobuf:"bytes,7,opt,name=data_hash,json=dataHash,proto3" json:"data_hash,omitempty"`
+	ValidatorsHash  []byte         `protobuf:"bytes,8,opt,name=validators_hash,json=validatorsHash,proto3" json:"validators_hash,omitempty"`
+	AppHash         []byte         `protobuf:"bytes,9,opt,name=app_hash,json=appHash,proto3" json:"app_hash,omitempty"`
+	LastResultsHash []byte         `protobuf:"bytes,10,opt,name=last_results_hash,json=lastResultsHash,proto3" json:"last_results_hash,omitempty"`
+	RandomBeacon    []byte         `protobuf:"bytes,11,opt,name=random_beacon,json=randomBeacon,proto3" json:"random_beacon,omitempty"`
+	EvidenceHash    []byte         `protobuf:"bytes,12,opt,name=evidence_hash,json=evidenceHash,proto3" json:"evidence_hash,omitempty"`
+}
+
+func (m *Header) Reset()                    { *m = Header{} }
+func (m *Header) String() string            { return proto.CompactTextString(m) }
+func (*Header) ProtoMessage()               {}
+func (*Header) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *Header) GetChainId() string {
+	if m != nil {
+		return m.ChainId
+	}
+	return ""
+}
+
+func (m *Header) GetHeight() int64 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *Header) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *Header) GetNumTxs() int64 {
+	if m != nil {
+		return m.NumTxs
+	}
+	return 0
+}
+
+func (m *Header) GetLastBlockId() *types.BlockID {
+	if m != nil {
+		return m.LastBlockId
+	}
+	return nil
+}
+
+func (m *Header) GetLastCommitHash() []byte {
+	if m != nil {
+		return m.LastCommitHash
+	}
+	return nil
+}
+
+func (m *Header) GetDataHash() []byte {
+	if m != nil {
+		return m.DataHash
+	}
+	return nil
+}
+
+func (m *Header) GetValidatorsHash() []byte {
+	if m != nil {
+		return m.ValidatorsHash
+	}
+	return nil
+}
+
+func (m *Header) GetAppHash() []byte {
+	if m != nil {
+		return m.AppHash
+	}
+	return nil
+}
+
+func (m *Header) GetLastResultsHash() []byte {
+	if m != nil {
+		return m.LastResultsHash
+	}
+	return nil
+}
+
+func (m *Header) GetRandomBeacon() []byte {
+	if m != nil {
+		return m.RandomBeacon
+	}
+	return nil
+}
+
+func (m *Header) GetEvidenceHash() []byte {
+	if m != nil {
+		return m.EvidenceHash
+	}
+	return nil
+}
+
+type Vote struct {
+	ValidatorAddress []byte         `protobuf:"bytes,1,opt,name=validator_address,json=validatorAddress,proto3" json:"validator_address,omitempty"`
+	ValidatorIndex   int32          `protobuf:"varint,2,opt,name=validator_index,json=validatorIndex" json:"validator_index,omitempty"`
+	Height           int64          `protobuf:"varint,3,opt,name=height" json:"height,omitempty"`
+	Round            int32          `protobuf:"varint,4,opt,name=round" json:"round,omitempty"`
+	Type             int32          `protobuf:"varint,5,opt,name=type" json:"type,omitempty"`
+	BlockId          *types.BlockID `protobuf:"bytes,6,opt,name=block_id,json=blockId" json:"block_id,omitempty"`
+	Timestamp        int64          `protobuf:"varint,7,opt,name=timestamp" json:"timestamp,omitempty"`
+	Signature        []byte         `protobuf:"bytes,8,opt,name=signature,proto3" json:"signature,omitempty"`
+}
+
+func (m *Vote) Reset()                    { *m = Vote{} }
+func (m *Vote) String() string            { return proto.CompactTextString(m) }
+func (*Vote) ProtoMessage()               {}
+func (*Vote) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *Vote) GetValidatorAddress() []byte {
+	if m != nil {
+		return m.ValidatorAddress
+	}
+	return nil
+}
+
+func (m *Vote) GetValidatorIndex() int32 {
+	if m != nil {
+		return m.ValidatorIndex
+	}
+	return 0
+}
+
+func (m *Vote) GetHeight() int64 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *Vote) GetRound() int32 {
+	if m != nil {
+		return m.Round
+	}
+	return 0
+}
+
+func (m *Vote) GetType() int32 {
+	if m != nil {
+		return m.Type
+	}
+	return 0
+}
+
+func (m *Vote) GetBlockId() *types.BlockID {
+	if m != nil {
+		return m.BlockId
+	}
+	return nil
+}
+
+func (m *Vote) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *Vote) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+type Commit struct {
+	BlockId             *types.BlockID `protobuf:"bytes,1,opt,name=block_id,json=blockId" json:"block_id,omitempty"`
+	Precommits          []*Vote        `protobuf:"bytes,2,rep,name=precommits" json:"precommits,omitempty"`
+	AggregatedSignature []byte         `protobuf:"bytes,3,opt,name=aggregated_signature,json=aggregatedSignature,proto3" json:"aggregated_signature,omitempty"`
+}
+
+func (m *Commit) Reset()                    { *m = Commit{} }
+func (m *Commit) String() string            { return proto.CompactTextString(m) }
+func (*Commit) ProtoMessage()               {}
+func (*Commit) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *Commit) GetBlockId() *types.BlockID {
+	if m != nil {
+		return m.BlockId
+	}
+	return nil
+}
+
+func (m *Commit) GetPrecommits() []*Vote {
+	if m != nil {
+		return m.Precommits
+	}
+	return nil
+}
+
+func (m *Commit) GetAggregatedSignature() []byte {
+	if m != nil {
+		return m.AggregatedSignature
+	}
+	return nil
+}
+
+type Block struct {
+	Header     *Header  `protobuf:"bytes,1,opt,name=header" json:"header,omitempty"`
+	Txs        [][]byte `protobuf:"bytes,2,rep,name=txs,proto3" json:"txs,omitempty"`
+	Evidence   [][]byte `protobuf:"bytes,3,rep,name=evidence,proto3" json:"evidence,omitempty"`
+	LastCommit *Commit  `protobuf:"bytes,4,opt,name=last_commit,json=lastCommit" json:"last_commit,omitempty"`
+}
+
+func (m *Block) Reset()                    { *m = Block{} }
+func (m *Block) String() string            { return proto.CompactTextString(m) }
+func (*Block) ProtoMessage()               {}
+func (*Block) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *Block) GetHeader() *Header {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *Block) GetTxs() [][]byte {
+	if m != nil {
+		return m.Txs
+	}
+	return nil
+}
+
+func (m *Block) GetEvidence() [][]byte {
+	if m != nil {
+		return m.Evidence
+	}
+	return nil
+}
+
+func (m *Block) GetLastCommit() *Commit {
+	if m != nil {
+		return m.LastCommit
+	}
+	return nil
+}
+
+type TxProof struct {
+	Index    int32    `protobuf:"varint,1,opt,name=index" json:"index,omitempty"`
+	Total    int32    `protobuf:"varint,2,opt,name=total" json:"total,omitempty"`
+	RootHash []byte   `protobuf:"bytes,3,opt,name=root_hash,json=rootHash,proto3" json:"root_hash,omitempty"`
+	Data     []byte   `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	Aunts    [][]byte `protobuf:"bytes,5,rep,name=aunts,proto3" json:"aunts,omitempty"`
+}
+
+func (m *TxProof) Reset()                    { *m = TxProof{} }
+func (m *TxProof) String() string            { return proto.CompactTextString(m) }
+func (*TxProof) ProtoMessage()               {}
+func (*TxProof) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *TxProof) GetIndex() int32 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *TxProof) GetTotal() int32 {
+	if m != nil {
+		return m.Total
+	}
+	return 0
+}
+
+func (m *TxProof) GetRootHash() []byte {
+	if m != nil {
+		return m.RootHash
+	}
+	return nil
+}
+
+func (m *TxProof) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *TxProof) GetAunts() [][]byte {
+	if m != nil {
+		return m.Aunts
+	}
+	return nil
+}
+
 type RequestPing struct {
 }
 
 func (m *RequestPing) Reset()                    { *m = RequestPing{} }
 func (m *RequestPing) String() string            { return proto.CompactTextString(m) }
 func (*RequestPing) ProtoMessage()               {}
-func (*RequestPing) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+func (*RequestPing) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
 
 type RequestBroadcastTx struct {
 	Tx []byte `protobuf:"bytes,1,opt,name=tx,proto3" json:"tx,omitempty"`
@@ -53,7 +429,7 @@ type RequestBroadcastTx struct {
 func (m *RequestBroadcastTx) Reset()                    { *m = RequestBroadcastTx{} }
 func (m *RequestBroadcastTx) String() string            { return proto.CompactTextString(m) }
 func (*RequestBroadcastTx) ProtoMessage()               {}
-func (*RequestBroadcastTx) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+func (*RequestBroadcastTx) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
 
 func (m *RequestBroadcastTx) GetTx() []byte {
 	if m != nil {
@@ -62,13 +438,117 @@ func (m *RequestBroadcastTx) GetTx() []byte {
 	return nil
 }
 
+type RequestStatus struct {
+}
+
+func (m *RequestStatus) Reset()                    { *m = RequestStatus{} }
+func (m *RequestStatus) String() string            { return proto.CompactTextString(m) }
+func (*RequestStatus) ProtoMessage()               {}
+func (*RequestStatus) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+type RequestBlock struct {
+	Height int64 `protobuf:"varint,1,opt,name=height" json:"height,omitempty"`
+}
+
+func (m *RequestBlock) Reset()                    { *m = RequestBlock{} }
+func (m *RequestBlock) String() string            { return proto.CompactTextString(m) }
+func (*RequestBlock) ProtoMessage()               {}
+func (*RequestBlock) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *RequestBlock) GetHeight() int64 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+type RequestTx struct {
+	Hash  []byte `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	Prove bool   `protobuf:"varint,2,opt,name=prove" json:"prove,omitempty"`
+}
+
+func (m *RequestTx) Reset()                    { *m = RequestTx{} }
+func (m *RequestTx) String() string            { return proto.CompactTextString(m) }
+func (*RequestTx) ProtoMessage()               {}
+func (*RequestTx) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *RequestTx) GetHash() []byte {
+	if m != nil {
+		return m.Hash
+	}
+	return nil
+}
+
+func (m *RequestTx) GetProve() bool {
+	if m != nil {
+		return m.Prove
+	}
+	return false
+}
+
+type RequestABCIQuery struct {
+	Path    string `protobuf:"bytes,1,opt,name=path" json:"path,omitempty"`
+	Data    []byte `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	Height  int64  `protobuf:"varint,3,opt,name=height" json:"height,omitempty"`
+	Trusted bool   `protobuf:"varint,4,opt,name=trusted" json:"trusted,omitempty"`
+}
+
+func (m *RequestABCIQuery) Reset()                    { *m = RequestABCIQuery{} }
+func (m *RequestABCIQuery) String() string            { return proto.CompactTextString(m) }
+func (*RequestABCIQuery) ProtoMessage()               {}
+func (*RequestABCIQuery) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *RequestABCIQuery) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *RequestABCIQuery) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (m *RequestABCIQuery) GetHeight() int64 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *RequestABCIQuery) GetTrusted() bool {
+	if m != nil {
+		return m.Trusted
+	}
+	return false
+}
+
+type RequestSubscribe struct {
+	Query string `protobuf:"bytes,1,opt,name=query" json:"query,omitempty"`
+}
+
+func (m *RequestSubscribe) Reset()                    { *m = RequestSubscribe{} }
+func (m *RequestSubscribe) String() string            { return proto.CompactTextString(m) }
+func (*RequestSubscribe) ProtoMessage()               {}
+func (*RequestSubscribe) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *RequestSubscribe) GetQuery() string {
+	if m != nil {
+		return m.Query
+	}
+	return ""
+}
+
 type ResponsePing struct {
 }
 
 func (m *ResponsePing) Reset()                    { *m = ResponsePing{} }
 func (m *ResponsePing) String() string            { return proto.CompactTextString(m) }
 func (*ResponsePing) ProtoMessage()               {}
-func (*ResponsePing) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (*ResponsePing) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
 
 type ResponseBroadcastTx struct {
 	CheckTx   *types.ResponseCheckTx   `protobuf:"bytes,1,opt,name=check_tx,json=checkTx" json:"check_tx,omitempty"`
@@ -78,7 +558,7 @@ type ResponseBroadcastTx struct {
 func (m *ResponseBroadcastTx) Reset()                    { *m = ResponseBroadcastTx{} }
 func (m *ResponseBroadcastTx) String() string            { return proto.CompactTextString(m) }
 func (*ResponseBroadcastTx) ProtoMessage()               {}
-func (*ResponseBroadcastTx) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+func (*ResponseBroadcastTx) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
 
 func (m *ResponseBroadcastTx) GetCheckTx() *types.ResponseCheckTx {
 	if m != nil {
@@ -94,11 +574,243 @@ func (m *ResponseBroadcastTx) GetDeliverTx() *types.ResponseDeliverTx {
 	return nil
 }
 
+type ResponseStatus struct {
+	NodeInfo          *NodeInfo `protobuf:"bytes,1,opt,name=node_info,json=nodeInfo" json:"node_info,omitempty"`
+	PubKey            []byte    `protobuf:"bytes,2,opt,name=pub_key,json=pubKey,proto3" json:"pub_key,omitempty"`
+	LatestBlockHash   []byte    `protobuf:"bytes,3,opt,name=latest_block_hash,json=latestBlockHash,proto3" json:"latest_block_hash,omitempty"`
+	LatestAppHash     []byte    `protobuf:"bytes,4,opt,name=latest_app_hash,json=latestAppHash,proto3" json:"latest_app_hash,omitempty"`
+	LatestBlockHeight int64     `protobuf:"varint,5,opt,name=latest_block_height,json=latestBlockHeight" json:"latest_block_height,omitempty"`
+	LatestBlockTime   int64     `protobuf:"varint,6,opt,name=latest_block_time,json=latestBlockTime" json:"latest_block_time,omitempty"`
+	Syncing           bool      `protobuf:"varint,7,opt,name=syncing" json:"syncing,omitempty"`
+}
+
+func (m *ResponseStatus) Reset()                    { *m = ResponseStatus{} }
+func (m *ResponseStatus) String() string            { return proto.CompactTextString(m) }
+func (*ResponseStatus) ProtoMessage()               {}
+func (*ResponseStatus) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+func (m *ResponseStatus) GetNodeInfo() *NodeInfo {
+	if m != nil {
+		return m.NodeInfo
+	}
+	return nil
+}
+
+func (m *ResponseStatus) GetPubKey() []byte {
+	if m != nil {
+		return m.PubKey
+	}
+	return nil
+}
+
+func (m *ResponseStatus) GetLatestBlockHash() []byte {
+	if m != nil {
+		return m.LatestBlockHash
+	}
+	return nil
+}
+
+func (m *ResponseStatus) GetLatestAppHash() []byte {
+	if m != nil {
+		return m.LatestAppHash
+	}
+	return nil
+}
+
+func (m *ResponseStatus) GetLatestBlockHeight() int64 {
+	if m != nil {
+		return m.LatestBlockHeight
+	}
+	return 0
+}
+
+func (m *ResponseStatus) GetLatestBlockTime() int64 {
+	if m != nil {
+		return m.LatestBlockTime
+	}
+	return 0
+}
+
+func (m *ResponseStatus) GetSyncing() bool {
+	if m != nil {
+		return m.Syncing
+	}
+	return false
+}
+
+type ResponseBlock struct {
+	BlockId *types.BlockID `protobuf:"bytes,1,opt,name=block_id,json=blockId" json:"block_id,omitempty"`
+	Block   *Block         `protobuf:"bytes,2,opt,name=block" json:"block,omitempty"`
+}
+
+func (m *ResponseBlock) Reset()                    { *m = ResponseBlock{} }
+func (m *ResponseBlock) String() string            { return proto.CompactTextString(m) }
+func (*ResponseBlock) ProtoMessage()               {}
+func (*ResponseBlock) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *ResponseBlock) GetBlockId() *types.BlockID {
+	if m != nil {
+		return m.BlockId
+	}
+	return nil
+}
+
+func (m *ResponseBlock) GetBlock() *Block {
+	if m != nil {
+		return m.Block
+	}
+	return nil
+}
+
+type ResponseTx struct {
+	Hash     []byte                   `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	Height   int64                    `protobuf:"varint,2,opt,name=height" json:"height,omitempty"`
+	Index    uint32                   `protobuf:"varint,3,opt,name=index" json:"index,omitempty"`
+	TxResult *types.ResponseDeliverTx `protobuf:"bytes,4,opt,name=tx_result,json=txResult" json:"tx_result,omitempty"`
+	Tx       []byte                   `protobuf:"bytes,5,opt,name=tx,proto3" json:"tx,omitempty"`
+	Proof    *TxProof                 `protobuf:"bytes,6,opt,name=proof" json:"proof,omitempty"`
+}
+
+func (m *ResponseTx) Reset()                    { *m = ResponseTx{} }
+func (m *ResponseTx) String() string            { return proto.CompactTextString(m) }
+func (*ResponseTx) ProtoMessage()               {}
+func (*ResponseTx) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+func (m *ResponseTx) GetHash() []byte {
+	if m != nil {
+		return m.Hash
+	}
+	return nil
+}
+
+func (m *ResponseTx) GetHeight() int64 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *ResponseTx) GetIndex() uint32 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *ResponseTx) GetTxResult() *types.ResponseDeliverTx {
+	if m != nil {
+		return m.TxResult
+	}
+	return nil
+}
+
+func (m *ResponseTx) GetTx() []byte {
+	if m != nil {
+		return m.Tx
+	}
+	return nil
+}
+
+func (m *ResponseTx) GetProof() *TxProof {
+	if m != nil {
+		return m.Proof
+	}
+	return nil
+}
+
+type ResponseABCIQuery struct {
+	Response *types.ResponseQuery `protobuf:"bytes,1,opt,name=response" json:"response,omitempty"`
+}
+
+func (m *ResponseABCIQuery) Reset()                    { *m = ResponseABCIQuery{} }
+func (m *ResponseABCIQuery) String() string            { return proto.CompactTextString(m) }
+func (*ResponseABCIQuery) ProtoMessage()               {}
+func (*ResponseABCIQuery) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+func (m *ResponseABCIQuery) GetResponse() *types.ResponseQuery {
+	if m != nil {
+		return m.Response
+	}
+	return nil
+}
+
+type ResponseEvent struct {
+	Query  string      `protobuf:"bytes,1,opt,name=query" json:"query,omitempty"`
+	Type   string      `protobuf:"bytes,2,opt,name=type" json:"type,omitempty"`
+	Block  *Block      `protobuf:"bytes,3,opt,name=block" json:"block,omitempty"`
+	Header *Header     `protobuf:"bytes,4,opt,name=header" json:"header,omitempty"`
+	Tx     *ResponseTx `protobuf:"bytes,5,opt,name=tx" json:"tx,omitempty"`
+	Data   []byte      `protobuf:"bytes,6,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (m *ResponseEvent) Reset()                    { *m = ResponseEvent{} }
+func (m *ResponseEvent) String() string            { return proto.CompactTextString(m) }
+func (*ResponseEvent) ProtoMessage()               {}
+func (*ResponseEvent) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{19} }
+
+func (m *ResponseEvent) GetQuery() string {
+	if m != nil {
+		return m.Query
+	}
+	return ""
+}
+
+func (m *ResponseEvent) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *ResponseEvent) GetBlock() *Block {
+	if m != nil {
+		return m.Block
+	}
+	return nil
+}
+
+func (m *ResponseEvent) GetHeader() *Header {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *ResponseEvent) GetTx() *ResponseTx {
+	if m != nil {
+		return m.Tx
+	}
+	return nil
+}
+
+func (m *ResponseEvent) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
 func init() {
+	proto.RegisterType((*NodeInfo)(nil), "core_grpc.NodeInfo")
+	proto.RegisterType((*Header)(nil), "core_grpc.Header")
+	proto.RegisterType((*Vote)(nil), "core_grpc.Vote")
+	proto.RegisterType((*Commit)(nil), "core_grpc.Commit")
+	proto.RegisterType((*Block)(nil), "core_grpc.Block")
+	proto.RegisterType((*TxProof)(nil), "core_grpc.TxProof")
 	proto.RegisterType((*RequestPing)(nil), "core_grpc.RequestPing")
 	proto.RegisterType((*RequestBroadcastTx)(nil), "core_grpc.RequestBroadcastTx")
+	proto.RegisterType((*RequestStatus)(nil), "core_grpc.RequestStatus")
+	proto.RegisterType((*RequestBlock)(nil), "core_grpc.RequestBlock")
+	proto.RegisterType((*RequestTx)(nil), "core_grpc.RequestTx")
+	proto.RegisterType((*RequestABCIQuery)(nil), "core_grpc.RequestABCIQuery")
+	proto.RegisterType((*RequestSubscribe)(nil), "core_grpc.RequestSubscribe")
 	proto.RegisterType((*ResponsePing)(nil), "core_grpc.ResponsePing")
 	proto.RegisterType((*ResponseBroadcastTx)(nil), "core_grpc.ResponseBroadcastTx")
+	proto.RegisterType((*ResponseStatus)(nil), "core_grpc.ResponseStatus")
+	proto.RegisterType((*ResponseBlock)(nil), "core_grpc.ResponseBlock")
+	proto.RegisterType((*ResponseTx)(nil), "core_grpc.ResponseTx")
+	proto.RegisterType((*ResponseABCIQuery)(nil), "core_grpc.ResponseABCIQuery")
+	proto.RegisterType((*ResponseEvent)(nil), "core_grpc.ResponseEvent")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -206,25 +918,315 @@ var _BroadcastAPI_serviceDesc = grpc.ServiceDesc{
 	Metadata: "types.proto",
 }
 
+// Client API for CoreAPI service
+
+type CoreAPIClient interface {
+	Status(ctx context.Context, in *RequestStatus, opts ...grpc.CallOption) (*ResponseStatus, error)
+	Block(ctx context.Context, in *RequestBlock, opts ...grpc.CallOption) (*ResponseBlock, error)
+	Tx(ctx context.Context, in *RequestTx, opts ...grpc.CallOption) (*ResponseTx, error)
+	ABCIQuery(ctx context.Context, in *RequestABCIQuery, opts ...grpc.CallOption) (*ResponseABCIQuery, error)
+	Subscribe(ctx context.Context, in *RequestSubscribe, opts ...grpc.CallOption) (CoreAPI_SubscribeClient, error)
+}
+
+type coreAPIClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewCoreAPIClient(cc *grpc.ClientConn) CoreAPIClient {
+	return &coreAPIClient{cc}
+}
+
+func (c *coreAPIClient) Status(ctx context.Context, in *RequestStatus, opts ...grpc.CallOption) (*ResponseStatus, error) {
+	out := new(ResponseStatus)
+	err := grpc.Invoke(ctx, "/core_grpc.CoreAPI/Status", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *coreAPIClient) Block(ctx context.Context, in *RequestBlock, opts ...grpc.CallOption) (*ResponseBlock, error) {
+	out := new(ResponseBlock)
+	err := grpc.Invoke(ctx, "/core_grpc.CoreAPI/Block", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *coreAPIClient) Tx(ctx context.Context, in *RequestTx, opts ...grpc.CallOption) (*ResponseTx, error) {
+	out := new(ResponseTx)
+	err := grpc.Invoke(ctx, "/core_grpc.CoreAPI/Tx", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *coreAPIClient) ABCIQuery(ctx context.Context, in *RequestABCIQuery, opts ...grpc.CallOption) (*ResponseABCIQuery, error) {
+	out := new(ResponseABCIQuery)
+	err := grpc.Invoke(ctx, "/core_grpc.CoreAPI/ABCIQuery", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *coreAPIClient) Subscribe(ctx context.Context, in *RequestSubscribe, opts ...grpc.CallOption) (CoreAPI_SubscribeClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_CoreAPI_serviceDesc.Streams[0], c.cc, "/core_grpc.CoreAPI/Subscribe", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &coreAPISubscribeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CoreAPI_SubscribeClient interface {
+	Recv() (*ResponseEvent, error)
+	grpc.ClientStream
+}
+
+type coreAPISubscribeClient struct {
+	grpc.ClientStream
+}
+
+func (x *coreAPISubscribeClient) Recv() (*ResponseEvent, error) {
+	m := new(ResponseEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Server API for CoreAPI service
+
+type CoreAPIServer interface {
+	Status(context.Context, *RequestStatus) (*ResponseStatus, error)
+	Block(context.Context, *RequestBlock) (*ResponseBlock, error)
+	Tx(context.Context, *RequestTx) (*ResponseTx, error)
+	ABCIQuery(context.Context, *RequestABCIQuery) (*ResponseABCIQuery, error)
+	Subscribe(*RequestSubscribe, CoreAPI_SubscribeServer) error
+}
+
+func RegisterCoreAPIServer(s *grpc.Server, srv CoreAPIServer) {
+	s.RegisterService(&_CoreAPI_serviceDesc, srv)
+}
+
+func _CoreAPI_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestStatus)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoreAPIServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/core_grpc.CoreAPI/Status",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoreAPIServer).Status(ctx, req.(*RequestStatus))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CoreAPI_Block_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestBlock)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoreAPIServer).Block(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/core_grpc.CoreAPI/Block",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoreAPIServer).Block(ctx, req.(*RequestBlock))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CoreAPI_Tx_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestTx)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoreAPIServer).Tx(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/core_grpc.CoreAPI/Tx",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoreAPIServer).Tx(ctx, req.(*RequestTx))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CoreAPI_ABCIQuery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestABCIQuery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoreAPIServer).ABCIQuery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/core_grpc.CoreAPI/ABCIQuery",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoreAPIServer).ABCIQuery(ctx, req.(*RequestABCIQuery))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CoreAPI_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RequestSubscribe)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CoreAPIServer).Subscribe(m, &coreAPISubscribeServer{stream})
+}
+
+type CoreAPI_SubscribeServer interface {
+	Send(*ResponseEvent) error
+	grpc.ServerStream
+}
+
+type coreAPISubscribeServer struct {
+	grpc.ServerStream
+}
+
+func (x *coreAPISubscribeServer) Send(m *ResponseEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _CoreAPI_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "core_grpc.CoreAPI",
+	HandlerType: (*CoreAPIServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Status",
+			Handler:    _CoreAPI_Status_Handler,
+		},
+		{
+			MethodName: "Block",
+			Handler:    _CoreAPI_Block_Handler,
+		},
+		{
+			MethodName: "Tx",
+			Handler:    _CoreAPI_Tx_Handler,
+		},
+		{
+			MethodName: "ABCIQuery",
+			Handler:    _CoreAPI_ABCIQuery_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Subscribe",
+			Handler:       _CoreAPI_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "types.proto",
+}
+
 func init() { proto.RegisterFile("types.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 264 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0xe2, 0x2e, 0xa9, 0x2c, 0x48,
-	0x2d, 0xd6, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x4c, 0xce, 0x2f, 0x4a, 0x8d, 0x4f, 0x2f,
-	0x2a, 0x48, 0x96, 0xd2, 0x49, 0xcf, 0x2c, 0xc9, 0x28, 0x4d, 0xd2, 0x4b, 0xce, 0xcf, 0xd5, 0x2f,
-	0x49, 0xcd, 0x4b, 0x49, 0x2d, 0xca, 0xcd, 0xcc, 0x2b, 0xd1, 0x4f, 0x4c, 0x4a, 0xce, 0xd4, 0x07,
-	0x6b, 0xd1, 0x47, 0xd2, 0xa8, 0xc4, 0xcb, 0xc5, 0x1d, 0x94, 0x5a, 0x58, 0x9a, 0x5a, 0x5c, 0x12,
-	0x90, 0x99, 0x97, 0xae, 0xa4, 0xc2, 0x25, 0x04, 0xe5, 0x3a, 0x15, 0xe5, 0x27, 0xa6, 0x24, 0x27,
-	0x16, 0x97, 0x84, 0x54, 0x08, 0xf1, 0x71, 0x31, 0x95, 0x54, 0x48, 0x30, 0x2a, 0x30, 0x6a, 0xf0,
-	0x04, 0x31, 0x95, 0x54, 0x28, 0xf1, 0x71, 0xf1, 0x04, 0xa5, 0x16, 0x17, 0xe4, 0xe7, 0x15, 0xa7,
-	0x82, 0x75, 0x35, 0x32, 0x72, 0x09, 0xc3, 0x04, 0x90, 0xf5, 0x19, 0x72, 0x71, 0x24, 0x67, 0xa4,
-	0x26, 0x67, 0xc7, 0x43, 0x75, 0x73, 0x1b, 0x89, 0xe9, 0x41, 0x2c, 0x87, 0xa9, 0x76, 0x06, 0x49,
-	0x87, 0x54, 0x04, 0xb1, 0x27, 0x43, 0x18, 0x42, 0xe6, 0x5c, 0x5c, 0x29, 0xa9, 0x39, 0x99, 0x65,
-	0xa9, 0x45, 0x20, 0x4d, 0x4c, 0x60, 0x4d, 0x12, 0x68, 0x9a, 0x5c, 0x20, 0x0a, 0x42, 0x2a, 0x82,
-	0x38, 0x53, 0x60, 0x4c, 0xa3, 0xa9, 0x8c, 0x5c, 0x3c, 0x70, 0xbb, 0x1d, 0x03, 0x3c, 0x85, 0xcc,
-	0xb9, 0x58, 0x40, 0x8e, 0x13, 0x12, 0xd3, 0x83, 0x87, 0x8d, 0x1e, 0x92, 0x57, 0xa5, 0xc4, 0x51,
-	0xc4, 0x11, 0xbe, 0x11, 0xf2, 0xe1, 0xe2, 0x46, 0xf6, 0x84, 0x2c, 0xa6, 0x7e, 0x24, 0x69, 0x29,
-	0x39, 0x2c, 0xc6, 0x20, 0xc9, 0x27, 0xb1, 0x81, 0xc3, 0xd9, 0x18, 0x10, 0x00, 0x00, 0xff, 0xff,
-	0x92, 0x29, 0xd9, 0x42, 0xaf, 0x01, 0x00, 0x00,
+	// 1325 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x57, 0x4b, 0x8f, 0xdc, 0xc4,
+	0x13, 0x97, 0xe7, 0x69, 0xd7, 0xcc, 0xec, 0xa3, 0x77, 0xff, 0x89, 0x33, 0xc9, 0x1f, 0x56, 0x06,
+	0xc2, 0x04, 0xd0, 0x6e, 0x32, 0x28, 0x8a, 0x84, 0xc4, 0x61, 0x77, 0x13, 0x94, 0x15, 0x08, 0x85,
+	0xce, 0x88, 0xab, 0xe5, 0xb1, 0x7b, 0x67, 0xac, 0x9d, 0x71, 0x3b, 0xed, 0xf6, 0xe2, 0x95, 0xb8,
+	0x70, 0xe0, 0x1b, 0x80, 0x38, 0x71, 0xe4, 0x73, 0x70, 0x42, 0x88, 0x6f, 0x85, 0xba, 0xba, 0xfd,
+	0xd8, 0xec, 0x24, 0xe1, 0xd6, 0x55, 0xf5, 0xab, 0xea, 0xea, 0x7a, 0xda, 0x30, 0x90, 0x57, 0x29,
+	0xcb, 0x0e, 0x53, 0xc1, 0x25, 0x27, 0x4e, 0xc8, 0x05, 0xf3, 0x17, 0x22, 0x0d, 0xc7, 0x9f, 0x2d,
+	0x62, 0xb9, 0xcc, 0xe7, 0x87, 0x21, 0x5f, 0x1f, 0x49, 0x96, 0x44, 0x4c, 0xac, 0xe3, 0x44, 0x1e,
+	0x05, 0xf3, 0x30, 0x3e, 0x42, 0x95, 0xa3, 0x86, 0xa2, 0xf7, 0x8f, 0x05, 0xf6, 0xb7, 0x3c, 0x62,
+	0x67, 0xc9, 0x39, 0x27, 0xb7, 0xa1, 0x9f, 0xe6, 0x73, 0xff, 0x82, 0x5d, 0xb9, 0xd6, 0x81, 0x35,
+	0x19, 0xd2, 0x5e, 0x9a, 0xcf, 0xbf, 0x66, 0x57, 0xc4, 0x85, 0xfe, 0x9a, 0x27, 0xf1, 0x05, 0x13,
+	0x6e, 0xeb, 0xc0, 0x9a, 0x38, 0xb4, 0x24, 0x95, 0x24, 0x61, 0xf2, 0x07, 0x2e, 0x2e, 0xdc, 0xb6,
+	0x96, 0x18, 0x92, 0xbc, 0x0f, 0x03, 0xc1, 0xd6, 0x5c, 0x32, 0x3f, 0x88, 0x22, 0xe1, 0x76, 0x50,
+	0x0a, 0x9a, 0x75, 0x1c, 0x45, 0x42, 0x01, 0x56, 0x71, 0x26, 0x59, 0xa2, 0x01, 0x5d, 0x0d, 0xd0,
+	0x2c, 0x04, 0xb8, 0xd0, 0xbf, 0x64, 0x22, 0x8b, 0x79, 0xe2, 0xf6, 0xb4, 0x6d, 0x43, 0x92, 0x7d,
+	0xe8, 0x72, 0xb9, 0x64, 0xc2, 0xed, 0x1f, 0xb4, 0x27, 0x0e, 0xd5, 0x84, 0xf7, 0x7b, 0x1b, 0x7a,
+	0xcf, 0x59, 0x10, 0x31, 0x41, 0xee, 0x80, 0x1d, 0x2e, 0x83, 0x38, 0xf1, 0xe3, 0x08, 0x9f, 0xe2,
+	0xd0, 0x3e, 0xd2, 0x67, 0x11, 0xb9, 0x05, 0xbd, 0x25, 0x8b, 0x17, 0x4b, 0x89, 0x4f, 0x69, 0x53,
+	0x43, 0x11, 0x02, 0x1d, 0x19, 0xaf, 0x19, 0x3e, 0xa3, 0x4d, 0xf1, 0xac, 0x02, 0x92, 0xe4, 0x6b,
+	0x5f, 0x16, 0x19, 0xfa, 0xdf, 0xa6, 0xbd, 0x24, 0x5f, 0xcf, 0x8a, 0x8c, 0x4c, 0x61, 0xb4, 0x0a,
+	0x32, 0xe9, 0xcf, 0x57, 0x3c, 0xbc, 0x50, 0x97, 0x28, 0xef, 0x07, 0xd3, 0xad, 0x43, 0x1d, 0xdb,
+	0x13, 0xc5, 0x3e, 0x7b, 0x4a, 0x07, 0x0a, 0xa4, 0x89, 0x88, 0x4c, 0x60, 0x07, 0x75, 0x42, 0xbe,
+	0x5e, 0xc7, 0xd2, 0x5f, 0x06, 0xd9, 0x12, 0xdf, 0x35, 0xa4, 0x5b, 0x8a, 0x7f, 0x8a, 0xec, 0xe7,
+	0x41, 0xb6, 0x24, 0x77, 0xc1, 0x89, 0x02, 0x19, 0x68, 0x48, 0x1f, 0x21, 0xb6, 0x62, 0xa0, 0xf0,
+	0x63, 0xd8, 0xbe, 0x0c, 0x56, 0x71, 0x14, 0x48, 0x2e, 0x32, 0x0d, 0xb1, 0xb5, 0x95, 0x9a, 0x8d,
+	0xc0, 0x3b, 0x60, 0x07, 0x69, 0xaa, 0x11, 0x0e, 0x22, 0xfa, 0x41, 0x9a, 0xa2, 0xe8, 0x13, 0xd8,
+	0x45, 0x57, 0x04, 0xcb, 0xf2, 0x95, 0x34, 0x56, 0x00, 0x31, 0xdb, 0x4a, 0x40, 0x35, 0x1f, 0xb1,
+	0x1f, 0xc0, 0x48, 0x04, 0x49, 0xc4, 0xd7, 0xfe, 0x9c, 0x05, 0x21, 0x4f, 0xdc, 0x01, 0xe2, 0x86,
+	0x9a, 0x79, 0x82, 0x3c, 0x05, 0x62, 0x97, 0x71, 0xc4, 0x92, 0x90, 0x69, 0x63, 0x43, 0x0d, 0x2a,
+	0x99, 0xca, 0x92, 0xf7, 0x73, 0x0b, 0x3a, 0xdf, 0x73, 0xc9, 0xc8, 0xa7, 0xb0, 0x5b, 0xf9, 0x8a,
+	0xc9, 0x67, 0x59, 0x66, 0x2a, 0x6e, 0xa7, 0x12, 0x1c, 0x6b, 0xfe, 0xb5, 0xf7, 0xfa, 0x71, 0x12,
+	0xb1, 0x02, 0x13, 0xd7, 0x6d, 0xbc, 0xf7, 0x4c, 0x71, 0x1b, 0x89, 0x6d, 0x5f, 0x4b, 0xec, 0x3e,
+	0x74, 0x05, 0xcf, 0x93, 0x08, 0x53, 0xd8, 0xa5, 0x9a, 0xc0, 0x74, 0x5f, 0xa5, 0x0c, 0x13, 0xd7,
+	0xa5, 0x78, 0x26, 0x0f, 0xc0, 0xae, 0x12, 0xda, 0xdb, 0x98, 0xd0, 0xfe, 0xdc, 0x24, 0xf3, 0x1e,
+	0x38, 0xaa, 0x42, 0x32, 0x19, 0xac, 0x53, 0x4c, 0x51, 0x9b, 0xd6, 0x0c, 0x25, 0xcd, 0xe2, 0x45,
+	0x12, 0xc8, 0x5c, 0x30, 0x93, 0x9d, 0x9a, 0xe1, 0xfd, 0x66, 0x41, 0x4f, 0x67, 0xfb, 0xda, 0x8d,
+	0xd6, 0xdb, 0x6f, 0x3c, 0x02, 0x48, 0x05, 0xd3, 0xc5, 0x93, 0xb9, 0xad, 0x83, 0xf6, 0x64, 0x30,
+	0xdd, 0x3e, 0xac, 0xfa, 0xfe, 0x50, 0x45, 0x96, 0x36, 0x20, 0xe4, 0x11, 0xec, 0x07, 0x8b, 0x85,
+	0x60, 0x8b, 0x40, 0xb2, 0xc8, 0xaf, 0xfd, 0x69, 0xa3, 0x3f, 0x7b, 0xb5, 0xec, 0x65, 0xe5, 0xd9,
+	0x2f, 0x16, 0x74, 0xf1, 0x62, 0xf2, 0x40, 0x05, 0x53, 0xb5, 0x92, 0x71, 0x6b, 0xb7, 0x71, 0x93,
+	0xee, 0x31, 0x6a, 0x00, 0x64, 0x07, 0xda, 0xb2, 0xd0, 0x1e, 0x0d, 0xa9, 0x3a, 0x92, 0x31, 0xd8,
+	0x65, 0xe2, 0xdd, 0x36, 0xb2, 0x2b, 0x9a, 0x4c, 0x61, 0xd0, 0xe8, 0x02, 0xb7, 0x73, 0xc3, 0xba,
+	0x8e, 0x0c, 0x85, 0xba, 0x27, 0xbc, 0x1f, 0xa1, 0x3f, 0x2b, 0x5e, 0x08, 0xce, 0xcf, 0x55, 0x32,
+	0x75, 0x0d, 0x58, 0x3a, 0x99, 0x48, 0x28, 0xae, 0xe4, 0x32, 0x58, 0x99, 0xca, 0xd0, 0x84, 0x6a,
+	0x23, 0xc1, 0xb9, 0xe9, 0x34, 0xfd, 0x6a, 0x5b, 0x31, 0xb0, 0xac, 0x09, 0x74, 0x54, 0x4b, 0xa1,
+	0x03, 0x43, 0x8a, 0x67, 0x65, 0x26, 0xc8, 0x13, 0x99, 0xb9, 0x5d, 0x74, 0x5a, 0x13, 0xde, 0x08,
+	0x06, 0x94, 0xbd, 0xca, 0x59, 0x26, 0x5f, 0xc4, 0xc9, 0xc2, 0xfb, 0x10, 0x88, 0x21, 0x4f, 0x04,
+	0x0f, 0xa2, 0x30, 0xc8, 0xe4, 0xac, 0x20, 0x5b, 0xd0, 0x92, 0x85, 0xa9, 0xe1, 0x96, 0x2c, 0xbc,
+	0x6d, 0x18, 0x19, 0xd4, 0x4b, 0x19, 0xc8, 0x3c, 0xf3, 0xee, 0xc3, 0xb0, 0x54, 0xc3, 0x00, 0xd7,
+	0xd5, 0x6a, 0x35, 0xab, 0xd5, 0x7b, 0x0c, 0x8e, 0xc1, 0xcd, 0x0a, 0xe5, 0x24, 0x3a, 0xaf, 0xed,
+	0xe2, 0x59, 0x39, 0x99, 0x0a, 0x7e, 0xc9, 0xf0, 0xad, 0x36, 0xd5, 0x84, 0xb7, 0x82, 0x1d, 0xa3,
+	0x76, 0x7c, 0x72, 0x7a, 0xf6, 0x5d, 0xce, 0xc4, 0x95, 0xd2, 0x4e, 0x03, 0xb9, 0x34, 0x03, 0x10,
+	0xcf, 0xd5, 0xb3, 0x5b, 0x8d, 0x67, 0xbf, 0xa9, 0x71, 0x5c, 0xe8, 0x4b, 0x91, 0x67, 0x92, 0xe9,
+	0xd6, 0xb1, 0x69, 0x49, 0x7a, 0x93, 0xea, 0xb6, 0x97, 0xf9, 0x3c, 0x0b, 0x45, 0x3c, 0x67, 0xca,
+	0xaf, 0x57, 0xea, 0x5a, 0x73, 0x9d, 0x26, 0xbc, 0x2d, 0xf5, 0xec, 0x2c, 0xe5, 0x49, 0xc6, 0x30,
+	0x7a, 0x3f, 0x59, 0xb0, 0x57, 0x32, 0x9a, 0xf1, 0x7b, 0xa4, 0x06, 0x36, 0x0b, 0x2f, 0x7c, 0x13,
+	0xc5, 0xc1, 0xf4, 0x96, 0x69, 0x84, 0x12, 0x7d, 0xaa, 0xc4, 0xb3, 0x42, 0x0d, 0x72, 0x3c, 0x90,
+	0x27, 0x00, 0x11, 0x5b, 0xc5, 0x97, 0x4c, 0x28, 0xa5, 0x16, 0x2a, 0xb9, 0xaf, 0x29, 0x3d, 0xd5,
+	0x80, 0x59, 0x41, 0x9d, 0xa8, 0x3c, 0x7a, 0x7f, 0xb4, 0x60, 0xab, 0x04, 0xe8, 0xec, 0x90, 0x87,
+	0xe0, 0x24, 0x3c, 0x62, 0x7e, 0x9c, 0x9c, 0x73, 0x73, 0xff, 0x5e, 0xa3, 0x26, 0xcb, 0x0d, 0x49,
+	0xed, 0x64, 0xc3, 0xae, 0x6c, 0x5d, 0xdb, 0x95, 0x38, 0x5b, 0x25, 0xab, 0x96, 0x43, 0xa3, 0xfa,
+	0xb6, 0xb5, 0x00, 0x0b, 0x00, 0x8b, 0xf0, 0x3e, 0x18, 0x96, 0x5f, 0x4d, 0x6a, 0x5d, 0x8f, 0x23,
+	0xcd, 0x3e, 0x36, 0xf3, 0xfa, 0x10, 0xf6, 0xae, 0xdb, 0xd4, 0xe9, 0xea, 0x62, 0xba, 0x76, 0x9b,
+	0x56, 0x75, 0xe6, 0x5e, 0xf7, 0x01, 0x17, 0x5b, 0x0f, 0xd1, 0x4d, 0x1f, 0x66, 0x6a, 0xc7, 0xb9,
+	0xd0, 0xcf, 0xae, 0x92, 0x30, 0x4e, 0x16, 0x38, 0xc7, 0x6c, 0x5a, 0x92, 0xde, 0x1c, 0x46, 0x65,
+	0x98, 0xca, 0xa1, 0xf0, 0x9f, 0xa7, 0xd5, 0x7d, 0xe8, 0xe2, 0xd1, 0xe4, 0x65, 0xa7, 0x11, 0x4c,
+	0xc4, 0x52, 0x2d, 0xf6, 0xfe, 0xb4, 0x00, 0xca, 0x4b, 0xde, 0x50, 0xf0, 0x6f, 0x5a, 0xd8, 0xd5,
+	0x28, 0x50, 0xc1, 0x1d, 0x95, 0xa3, 0xe0, 0x31, 0x38, 0xb2, 0x30, 0x8b, 0xcd, 0xed, 0xbc, 0xa3,
+	0x28, 0x6c, 0x59, 0xe8, 0x55, 0x67, 0xfa, 0xb7, 0x5b, 0xf6, 0x2f, 0x99, 0x60, 0x97, 0xf1, 0x73,
+	0xb3, 0x07, 0x48, 0xc3, 0x7f, 0x33, 0x8a, 0xa8, 0x06, 0x78, 0xcf, 0x60, 0xb7, 0x34, 0x5c, 0xb7,
+	0xde, 0x43, 0xb0, 0x85, 0x61, 0x9a, 0x48, 0xed, 0xbf, 0xe6, 0x04, 0xe2, 0x68, 0x85, 0xf2, 0xfe,
+	0xb6, 0xea, 0x68, 0x3f, 0xbb, 0x64, 0x89, 0xdc, 0xdc, 0x50, 0xd5, 0xde, 0xd2, 0xdf, 0x61, 0x78,
+	0xae, 0x83, 0xdd, 0x7e, 0x6b, 0xb0, 0x1b, 0x43, 0xbd, 0xf3, 0xae, 0xa1, 0xfe, 0x51, 0x15, 0x8f,
+	0xc1, 0xf4, 0x7f, 0x0d, 0x58, 0x9d, 0x2b, 0x0c, 0x53, 0x39, 0x4e, 0x7a, 0xf5, 0x38, 0x99, 0xfe,
+	0x6a, 0xc1, 0xb0, 0x6a, 0xed, 0xe3, 0x17, 0x67, 0xe4, 0x09, 0x74, 0x54, 0xef, 0x93, 0x5b, 0xd7,
+	0xec, 0x54, 0x13, 0x75, 0x7c, 0x7b, 0x83, 0x7d, 0x54, 0xf8, 0x06, 0x06, 0xcd, 0x19, 0xf1, 0xff,
+	0x9b, 0xfa, 0x0d, 0xf1, 0xf8, 0xbd, 0x0d, 0x66, 0x1a, 0xf2, 0xe9, 0x5f, 0x2d, 0xe8, 0x9f, 0x72,
+	0xc1, 0x94, 0x4b, 0x5f, 0x42, 0xcf, 0x74, 0xbe, 0x7b, 0xd3, 0xa8, 0x96, 0x8c, 0xef, 0x6c, 0xb0,
+	0x67, 0x94, 0xbe, 0x28, 0xd7, 0xe4, 0xed, 0x0d, 0x2e, 0x29, 0xc1, 0xd8, 0xdd, 0xe4, 0x0c, 0xaa,
+	0x3c, 0x82, 0xd6, 0xac, 0x20, 0xfb, 0x37, 0x15, 0x67, 0xc5, 0x78, 0x73, 0xa4, 0xc9, 0x57, 0xe0,
+	0xd4, 0xa5, 0x75, 0xf7, 0xa6, 0x66, 0x25, 0x1c, 0xdf, 0xdb, 0x60, 0xa0, 0x56, 0x7d, 0x0a, 0x4e,
+	0x3d, 0xaf, 0x37, 0xd8, 0xa9, 0x84, 0x1b, 0xdd, 0xc7, 0xaa, 0x7c, 0x68, 0xcd, 0x7b, 0xf8, 0xe7,
+	0xf0, 0xf9, 0xbf, 0x03, 0x00, 0xc7, 0x04, 0x38, 0xe3, 0x81, 0x0c, 0x00, 0x00,
 }
@@ -2,6 +2,8 @@ package rpcclient
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -10,6 +12,7 @@ import (
 	"net/url"
 	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 
@@ -37,8 +40,9 @@ func makeHTTPDialer(remoteAddr string) (string, func(string, string) (net.Conn,
 			return nil, errors.New(msg)
 		}
 	}
-	// accept http as an alias for tcp
-	if protocol == "http" {
+	// accept http, and https, ws and wss, as aliases for tcp
+	switch protocol {
+	case "http", "https", "ws", "wss":
 		protocol = "tcp"
 	}
 
@@ -49,17 +53,86 @@ func makeHTTPDialer(remoteAddr string) (string, func(string, string) (net.Conn,
 	}
 }
 
+// isTLSAddr returns whether the remoteAddr is https:// or wss://.
+func isTLSAddr(remoteAddr string) bool {
+	return strings.HasPrefix(remoteAddr, "https://") || strings.HasPrefix(remoteAddr, "wss://")
+}
+
 // We overwrite the http.Client.Dial so we can do http over tcp or unix.
 // remoteAddr should be fully featured (eg. with tcp:// or unix://)
-func makeHTTPClient(remoteAddr string) (string, *http.Client) {
+// The client uses TLS if remoteAddr is https:// or wss://, or the tlsConfig is
+// not nil, with the system CAs if it is.
+func makeHTTPClient(remoteAddr string, tlsConfig *tls.Config) (string, *http.Client) {
 	address, dialer := makeHTTPDialer(remoteAddr)
-	return "http://" + address, &http.Client{
+	scheme := "http://"
+	if tlsConfig != nil || isTLSAddr(remoteAddr) {
+		scheme = "https://"
+	}
+	return scheme + address, &http.Client{
 		Transport: &http.Transport{
-			Dial: dialer,
+			Dial:            dialer,
+			TLSClientConfig: tlsConfig,
 		},
 	}
 }
 
+// authTransport sets the bearer token of the requests.
+type authTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+func (t *authTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	// a RoundTripper must not modify the request
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.Header = make(http.Header, len(r.Header)+1)
+	for k, v := range r.Header {
+		r2.Header[k] = v
+	}
+	r2.Header.Set("Authorization", "Bearer "+t.token)
+	return t.base.RoundTrip(r2)
+}
+
+// withAuthToken returns the client sending the token, see SetAuthToken.
+func withAuthToken(client *http.Client, token string) *http.Client {
+	base := client.Transport
+	if t, ok := base.(*authTransport); ok {
+		base = t.base
+	}
+	if token != "" {
+		client.Transport = &authTransport{token: token, base: base}
+	} else {
+		client.Transport = base
+	}
+	return client
+}
+
+// NewClientTLSConfig returns the TLS config of a client trusting the CAs of
+// the PEM caFile, or the system CAs if empty, and presenting the certificate
+// and the key of the PEM files, if not empty, to a server with mutual TLS.
+func NewClientTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error reading the CAs")
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("No certificate in %v", caFile)
+		}
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error loading the client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
 //------------------------------------------------------------------------------------
 
 // JSONRPCClient takes params as a slice
@@ -70,13 +143,27 @@ type JSONRPCClient struct {
 
 // NewJSONRPCClient returns a JSONRPCClient pointed at the given address.
 func NewJSONRPCClient(remote string) *JSONRPCClient {
-	address, client := makeHTTPClient(remote)
+	return NewJSONRPCClientWithTLS(remote, nil)
+}
+
+// NewJSONRPCClientWithTLS returns a JSONRPCClient pointed at the given address,
+// over TLS with the tlsConfig, eg. from NewClientTLSConfig.
+func NewJSONRPCClientWithTLS(remote string, tlsConfig *tls.Config) *JSONRPCClient {
+	address, client := makeHTTPClient(remote, tlsConfig)
 	return &JSONRPCClient{
 		address: address,
 		client:  client,
 	}
 }
 
+// SetAuthToken makes the client send the token, as
+// "Authorization: Bearer <token>", for the unsafe methods of a server with
+// rpc.unsafe_auth_token_file. Empty for none.
+// It should only be called before using the client - not Goroutine-safe.
+func (c *JSONRPCClient) SetAuthToken(token string) {
+	c.client = withAuthToken(c.client, token)
+}
+
 func (c *JSONRPCClient) Call(method string, params map[string]interface{}, result interface{}) (interface{}, error) {
 	request, err := types.MapToRequest("jsonrpc-client", method, params)
 	if err != nil {
@@ -103,6 +190,87 @@ func (c *JSONRPCClient) Call(method string, params map[string]interface{}, resul
 	return unmarshalResponseBytes(responseBytes, result)
 }
 
+// NewRequestBatch returns a batch of requests to send with the client in one
+// HTTP request.
+func (c *JSONRPCClient) NewRequestBatch() *JSONRPCRequestBatch {
+	return &JSONRPCRequestBatch{client: c}
+}
+
+// JSONRPCRequestBatch queues JSON-RPC requests, to send them in one HTTP
+// request, as a batch, with Send.
+type JSONRPCRequestBatch struct {
+	client *JSONRPCClient
+
+	mtx      sync.Mutex
+	requests []types.RPCRequest
+	results  []interface{}
+}
+
+// Call queues the request, and returns the result it is to be unmarshalled
+// into by Send. It implements HTTPClient, so the batch can be used in place of
+// the client.
+func (b *JSONRPCRequestBatch) Call(method string, params map[string]interface{}, result interface{}) (interface{}, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	request, err := types.MapToRequest(fmt.Sprintf("jsonrpc-client-batch-%d", len(b.requests)), method, params)
+	if err != nil {
+		return nil, err
+	}
+	b.requests = append(b.requests, request)
+	b.results = append(b.results, result)
+	return result, nil
+}
+
+// Count returns the number of requests queued.
+func (b *JSONRPCRequestBatch) Count() int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return len(b.requests)
+}
+
+// Clear removes the requests queued, and returns how many there were.
+func (b *JSONRPCRequestBatch) Clear() int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.clear()
+}
+
+func (b *JSONRPCRequestBatch) clear() int {
+	count := len(b.requests)
+	b.requests, b.results = nil, nil
+	return count
+}
+
+// Send sends the requests queued in one HTTP request, unmarshals their results,
+// and returns them in the order of the requests. The batch is cleared. The
+// error is the one of the first request which failed, if any, the results of
+// the others being unmarshalled anyway.
+func (b *JSONRPCRequestBatch) Send() ([]interface{}, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	requests, results := b.requests, b.results
+	b.clear()
+	if len(requests) == 0 {
+		return []interface{}{}, nil
+	}
+
+	requestBytes, err := json.Marshal(requests)
+	if err != nil {
+		return nil, err
+	}
+	httpResponse, err := b.client.client.Post(b.client.address, "text/json", bytes.NewBuffer(requestBytes))
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close() // nolint: errcheck
+
+	responseBytes, err := ioutil.ReadAll(httpResponse.Body)
+	if err != nil {
+		return nil, err
+	}
+	return unmarshalResponseBytesArray(responseBytes, requests, results)
+}
+
 //-------------------------------------------------------------
 
 // URI takes params as a map
@@ -112,13 +280,25 @@ type URIClient struct {
 }
 
 func NewURIClient(remote string) *URIClient {
-	address, client := makeHTTPClient(remote)
+	return NewURIClientWithTLS(remote, nil)
+}
+
+// NewURIClientWithTLS returns a URIClient pointed at the given address, over
+// TLS with the tlsConfig, eg. from NewClientTLSConfig.
+func NewURIClientWithTLS(remote string, tlsConfig *tls.Config) *URIClient {
+	address, client := makeHTTPClient(remote, tlsConfig)
 	return &URIClient{
 		address: address,
 		client:  client,
 	}
 }
 
+// SetAuthToken makes the client send the token, see JSONRPCClient.SetAuthToken.
+// It should only be called before using the client - not Goroutine-safe.
+func (c *URIClient) SetAuthToken(token string) {
+	c.client = withAuthToken(c.client, token)
+}
+
 func (c *URIClient) Call(method string, params map[string]interface{}, result interface{}) (interface{}, error) {
 	values, err := argsToURLValues(params)
 	if err != nil {
@@ -162,6 +342,44 @@ func unmarshalResponseBytes(responseBytes []byte, result interface{}) (interface
 	return result, nil
 }
 
+// unmarshalResponseBytesArray unmarshals the responses of a batch of requests
+// into their results, matching them by id.
+func unmarshalResponseBytesArray(responseBytes []byte, requests []types.RPCRequest, results []interface{}) ([]interface{}, error) {
+	var responses []types.RPCResponse
+	if err := json.Unmarshal(responseBytes, &responses); err != nil {
+		// the whole batch failed
+		response := &types.RPCResponse{}
+		if err := json.Unmarshal(responseBytes, response); err == nil && response.Error != nil {
+			return nil, errors.Errorf("Response error: %v", response.Error)
+		}
+		return nil, errors.Errorf("Error unmarshalling rpc responses: %v", err)
+	}
+	byID := make(map[string]types.RPCResponse, len(responses))
+	for _, response := range responses {
+		byID[response.ID] = response
+	}
+
+	var firstErr error
+	for i, request := range requests {
+		response, ok := byID[request.ID]
+		var err error
+		switch {
+		case !ok:
+			err = errors.Errorf("No response to request %d (%s)", i, request.Method)
+		case response.Error != nil:
+			err = errors.Errorf("Response error to request %d (%s): %v", i, request.Method, response.Error)
+		default:
+			if err = json.Unmarshal(response.Result, results[i]); err != nil {
+				err = errors.Errorf("Error unmarshalling rpc response result to request %d (%s): %v", i, request.Method, err)
+			}
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return results, firstErr
+}
+
 func argsToURLValues(args map[string]interface{}) (url.Values, error) {
 	values := make(url.Values)
 	if len(args) == 0 {
@@ -2,6 +2,7 @@ package rpcclient
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"math/rand"
@@ -69,6 +70,13 @@ type WSClient struct {
 
 	// Send pings to server with this period. Must be less than readWait. If 0, no pings will be sent.
 	pingPeriod time.Duration
+
+	// Whether to connect over wss://, with the tlsConfig, or the system CAs if nil.
+	useTLS    bool
+	tlsConfig *tls.Config
+
+	// Sent as "Authorization: Bearer <authToken>" on connecting, if not empty.
+	authToken string
 }
 
 // NewWSClient returns a new client. See the commentary on the func(*WSClient)
@@ -86,6 +94,7 @@ func NewWSClient(remoteAddr, endpoint string, options ...func(*WSClient)) *WSCli
 		readWait:             defaultReadWait,
 		writeWait:            defaultWriteWait,
 		pingPeriod:           defaultPingPeriod,
+		useTLS:               isTLSAddr(remoteAddr),
 	}
 	c.BaseService = *cmn.NewBaseService(nil, "WSClient", c)
 	for _, option := range options {
@@ -126,6 +135,27 @@ func PingPeriod(pingPeriod time.Duration) func(*WSClient) {
 	}
 }
 
+// TLSConfig makes the client connect over wss://, with the tlsConfig, eg. from
+// NewClientTLSConfig. A client of a wss:// address connects over wss:// anyway,
+// with the system CAs.
+// It should only be used in the constructor and is not Goroutine-safe.
+func TLSConfig(tlsConfig *tls.Config) func(*WSClient) {
+	return func(c *WSClient) {
+		c.useTLS = true
+		c.tlsConfig = tlsConfig
+	}
+}
+
+// AuthToken makes the client send the token on connecting, as
+// "Authorization: Bearer <token>", for the unsafe methods of a server with
+// rpc.unsafe_auth_token_file.
+// It should only be used in the constructor and is not Goroutine-safe.
+func AuthToken(token string) func(*WSClient) {
+	return func(c *WSClient) {
+		c.authToken = token
+	}
+}
+
 // OnReconnect sets the callback, which will be called every time after
 // successful reconnect.
 func OnReconnect(cb func()) func(*WSClient) {
@@ -228,11 +258,19 @@ func (c *WSClient) CallWithArrayParams(ctx context.Context, method string, param
 
 func (c *WSClient) dial() error {
 	dialer := &websocket.Dialer{
-		NetDial: c.Dialer,
-		Proxy:   http.ProxyFromEnvironment,
+		NetDial:         c.Dialer,
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: c.tlsConfig,
+	}
+	scheme := "ws://"
+	if c.useTLS {
+		scheme = "wss://"
 	}
 	rHeader := http.Header{}
-	conn, _, err := dialer.Dial("ws://"+c.Address+c.Endpoint, rHeader)
+	if c.authToken != "" {
+		rHeader.Set("Authorization", "Bearer "+c.authToken)
+	}
+	conn, _, err := dialer.Dial(scheme+c.Address+c.Endpoint, rHeader)
 	if err != nil {
 		return err
 	}
@@ -471,6 +509,13 @@ func (c *WSClient) Subscribe(ctx context.Context, query string) error {
 	return c.Call(ctx, "subscribe", params)
 }
 
+// SubscribeFrom subscribes to a query, replaying first its events from the
+// height. Note the server must have a "subscribe" route with a "from_height".
+func (c *WSClient) SubscribeFrom(ctx context.Context, query string, height int64) error {
+	params := map[string]interface{}{"query": query, "from_height": height}
+	return c.Call(ctx, "subscribe", params)
+}
+
 // Unsubscribe from a query. Note the server must have a "unsubscribe" route
 // defined.
 func (c *WSClient) Unsubscribe(ctx context.Context, query string) error {
@@ -286,6 +286,45 @@ func TestServersAndClientsBasic(t *testing.T) {
 	}
 }
 
+func TestJSONRPCBatch(t *testing.T) {
+	cl := client.NewJSONRPCClient(tcpAddr)
+	batch := cl.NewRequestBatch()
+
+	result1, err := batch.Call("echo", map[string]interface{}{"arg": "one"}, new(ResultEcho))
+	require.Nil(t, err)
+	result2, err := batch.Call("echo_int", map[string]interface{}{"arg": 2}, new(ResultEchoInt))
+	require.Nil(t, err)
+	assert.Equal(t, 2, batch.Count())
+
+	// the results are filled in by Send, in order
+	results, err := batch.Send()
+	require.Nil(t, err)
+	assert.Equal(t, 0, batch.Count())
+	require.Equal(t, 2, len(results))
+	assert.Equal(t, "one", results[0].(*ResultEcho).Value)
+	assert.Equal(t, 2, results[1].(*ResultEchoInt).Value)
+	assert.Equal(t, result1, results[0])
+	assert.Equal(t, result2, results[1])
+
+	// a request of the batch fails, the others still succeed
+	_, err = batch.Call("echo", map[string]interface{}{"arg": "one"}, new(ResultEcho))
+	require.Nil(t, err)
+	_, err = batch.Call("echo_ws", map[string]interface{}{"arg": "two"}, new(ResultEcho))
+	require.Nil(t, err)
+	results, err = batch.Send()
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "request 1")
+	assert.Equal(t, "one", results[0].(*ResultEcho).Value)
+
+	// works as an HTTPClient
+	_, err = echoViaHTTP(batch, "three")
+	require.Nil(t, err)
+	assert.Equal(t, 1, batch.Clear())
+	results, err = batch.Send()
+	require.Nil(t, err)
+	assert.Equal(t, 0, len(results))
+}
+
 func TestHexStringArg(t *testing.T) {
 	cl := client.NewURIClient(tcpAddr)
 	// should NOT be handled as hex
@@ -0,0 +1,238 @@
+package rpcserver
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	types "github.com/tendermint/tendermint/rpc/lib/types"
+)
+
+// redactedParam replaces the values of the redacted params in the access log.
+const redactedParam = `"<redacted>"`
+
+// maxAccessLogResponseSniff is the max size of a response read back to find
+// its JSON-RPC error. The errors are small, unlike some of the results.
+const maxAccessLogResponseSniff = 4096
+
+// AccessLogEntry is the JSON line written to the access log per request.
+type AccessLogEntry struct {
+	Time         time.Time                  `json:"time"`
+	Method       string                     `json:"method"`
+	Params       map[string]json.RawMessage `json:"params,omitempty"`
+	ParamsSize   int                        `json:"params_size"`
+	Status       int                        `json:"status"`
+	RPCError     int                        `json:"rpc_error,omitempty"`
+	ResponseSize int                        `json:"response_size"`
+	LatencyMS    float64                    `json:"latency_ms"`
+	ClientIP     string                     `json:"client_ip"`
+	ForwardedFor string                     `json:"forwarded_for,omitempty"`
+}
+
+// AccessLog writes an AccessLogEntry per RPC request served, as JSON lines,
+// for capacity planning and abuse analysis. The requests are sampled, except
+// the failed ones, which are all logged, and the values of the params named in
+// the redaction rules are left out.
+type AccessLog struct {
+	mtx        sync.Mutex
+	w          io.Writer
+	sampleRate float64
+	redact     map[string]bool
+}
+
+// NewAccessLog returns an AccessLog writing to w a fraction sampleRate, from 0
+// to 1, of the successful requests. The values of the params named in redact,
+// or of all of them for "*", are replaced by "<redacted>".
+func NewAccessLog(w io.Writer, sampleRate float64, redact []string) *AccessLog {
+	al := &AccessLog{
+		w:          w,
+		sampleRate: sampleRate,
+		redact:     make(map[string]bool, len(redact)),
+	}
+	for _, name := range redact {
+		if name = strings.TrimSpace(name); name != "" {
+			al.redact[name] = true
+		}
+	}
+	return al
+}
+
+// Log writes the entry, if it's sampled or failed.
+func (al *AccessLog) Log(entry AccessLogEntry) {
+	failed := entry.Status >= 400 || entry.RPCError != 0
+	if !failed && (al.sampleRate <= 0 || (al.sampleRate < 1 && rand.Float64() >= al.sampleRate)) {
+		return
+	}
+	for name := range entry.Params {
+		if al.redact["*"] || al.redact[name] {
+			entry.Params[name] = json.RawMessage(redactedParam)
+		}
+	}
+	// one JSON line, with the params as they were sent
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(entry); err != nil {
+		return
+	}
+
+	al.mtx.Lock()
+	defer al.mtx.Unlock()
+	al.w.Write(buf.Bytes()) // nolint: errcheck, gas
+}
+
+// AccessLogHandler wraps an RPC handler, writing the requests served, over
+// JSON-RPC or URI, to the access log. The names of the params passed by
+// position are taken from the funcMap.
+func AccessLogHandler(handler http.Handler, funcMap map[string]*RPCFunc, al *AccessLog) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		begin := time.Now()
+		entry := AccessLogEntry{
+			Time:         begin.UTC(),
+			ClientIP:     r.RemoteAddr,
+			ForwardedFor: r.Header.Get("X-Forwarded-For"),
+		}
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			entry.ClientIP = host
+		}
+
+		if r.Method == "POST" && r.Body != nil {
+			b, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				WriteRPCResponseHTTP(w, types.RPCInvalidRequestError("", err))
+				return
+			}
+			// give the body back to the handler
+			r.Body = ioutil.NopCloser(bytes.NewReader(b))
+			entry.Method, entry.Params, entry.ParamsSize = jsonRPCAccessParams(b, funcMap)
+		} else {
+			entry.Method = strings.TrimPrefix(r.URL.Path, "/")
+			entry.Params, entry.ParamsSize = uriAccessParams(r)
+		}
+
+		alw := &accessLogResponseWriter{ResponseWriter: w}
+		defer func() {
+			entry.Status = alw.status
+			if entry.Status == 0 {
+				entry.Status = http.StatusOK
+			}
+			entry.ResponseSize = alw.size
+			entry.RPCError = alw.rpcError()
+			entry.LatencyMS = float64(time.Since(begin).Nanoseconds()) / 1e6
+			al.Log(entry)
+		}()
+		handler.ServeHTTP(alw, r)
+	})
+}
+
+// jsonRPCAccessParams returns the method, the params by name and the size of
+// the params of a JSON-RPC request. A batch is logged as the method "batch",
+// with the size of the params of all its requests.
+func jsonRPCAccessParams(b []byte, funcMap map[string]*RPCFunc) (string, map[string]json.RawMessage, int) {
+	if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && trimmed[0] == '[' {
+		var requests []types.RPCRequest
+		if err := json.Unmarshal(trimmed, &requests); err != nil {
+			return "", nil, 0
+		}
+		size := 0
+		for _, request := range requests {
+			size += len(request.Params)
+		}
+		return "batch", nil, size
+	}
+
+	var request types.RPCRequest
+	if err := json.Unmarshal(b, &request); err != nil {
+		return "", nil, 0
+	}
+	params := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(request.Params, &params); err != nil {
+		var values []json.RawMessage
+		if err := json.Unmarshal(request.Params, &values); err != nil {
+			return request.Method, nil, len(request.Params)
+		}
+		var argNames []string
+		if rpcFunc := funcMap[request.Method]; rpcFunc != nil {
+			argNames = rpcFunc.argNames
+		}
+		for i, value := range values {
+			if i >= len(argNames) {
+				break
+			}
+			params[argNames[i]] = value
+		}
+	}
+	return request.Method, params, len(request.Params)
+}
+
+// uriAccessParams returns the params by name and the size of the params of a
+// URI request.
+func uriAccessParams(r *http.Request) (map[string]json.RawMessage, int) {
+	values := r.URL.Query()
+	if len(values) == 0 {
+		return nil, 0
+	}
+	params := make(map[string]json.RawMessage, len(values))
+	for name := range values {
+		// the values are quoted, as they're not all valid JSON
+		bz, err := json.Marshal(values.Get(name))
+		if err != nil {
+			continue
+		}
+		params[name] = bz
+	}
+	return params, len(r.URL.RawQuery)
+}
+
+// accessLogResponseWriter remembers the status and size of a response, and
+// its beginning, for its JSON-RPC error.
+type accessLogResponseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+	head   []byte
+}
+
+func (w *accessLogResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *accessLogResponseWriter) Write(b []byte) (int, error) {
+	if len(w.head) < maxAccessLogResponseSniff {
+		n := maxAccessLogResponseSniff - len(w.head)
+		if n > len(b) {
+			n = len(b)
+		}
+		w.head = append(w.head, b[:n]...)
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+// implements http.Hijacker, for the websockets
+func (w *accessLogResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.status = http.StatusSwitchingProtocols
+	return w.ResponseWriter.(http.Hijacker).Hijack()
+}
+
+// rpcError returns the code of the JSON-RPC error of the response, or 0.
+func (w *accessLogResponseWriter) rpcError() int {
+	if w.size > len(w.head) {
+		return 0
+	}
+	var res types.RPCResponse
+	if err := json.Unmarshal(w.head, &res); err != nil || res.Error == nil {
+		return 0
+	}
+	return res.Error.Code
+}
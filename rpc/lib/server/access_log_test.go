@@ -0,0 +1,102 @@
+package rpcserver_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	rs "github.com/tendermint/tendermint/rpc/lib/server"
+	"github.com/tendermint/tmlibs/log"
+)
+
+func accessLogMux(al *rs.AccessLog) http.Handler {
+	funcMap := map[string]*rs.RPCFunc{
+		"c": rs.NewRPCFunc(func(s string, i int) (string, error) { return "foo", nil }, "s,i"),
+	}
+	mux := http.NewServeMux()
+	rs.RegisterRPCFuncs(mux, funcMap, log.NewNopLogger())
+	return rs.AccessLogHandler(mux, funcMap, al)
+}
+
+func readAccessLog(t *testing.T, buf *bytes.Buffer) []rs.AccessLogEntry {
+	var entries []rs.AccessLogEntry
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry rs.AccessLogEntry
+		require.Nil(t, json.Unmarshal([]byte(line), &entry), line)
+		entries = append(entries, entry)
+	}
+	buf.Reset()
+	return entries
+}
+
+func TestAccessLog(t *testing.T) {
+	buf := new(bytes.Buffer)
+	mux := accessLogMux(rs.NewAccessLog(buf, 1, []string{"s"}))
+
+	// JSON-RPC, with the params by position
+	req, _ := http.NewRequest("POST", "http://localhost/", strings.NewReader(`{"method": "c", "id": "0", "params": ["secret", 10]}`))
+	req.RemoteAddr = "1.2.3.4:5678"
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	assert.Equal(t, 200, rec.Code)
+	assert.Contains(t, rec.Body.String(), "foo", "the handler must still get the body")
+
+	assert.NotContains(t, buf.String(), "secret")
+	entries := readAccessLog(t, buf)
+	require.Equal(t, 1, len(entries))
+	entry := entries[0]
+	assert.Equal(t, "c", entry.Method)
+	assert.Equal(t, len(`["secret", 10]`), entry.ParamsSize)
+	assert.Equal(t, `"<redacted>"`, string(entry.Params["s"]))
+	assert.Equal(t, `10`, string(entry.Params["i"]))
+	assert.Equal(t, 200, entry.Status)
+	assert.Equal(t, 0, entry.RPCError)
+	assert.Equal(t, rec.Body.Len(), entry.ResponseSize)
+	assert.Equal(t, "1.2.3.4", entry.ClientIP)
+
+	// URI
+	req, _ = http.NewRequest("GET", `http://localhost/c?s="a"&i=3`, nil)
+	req.Header.Set("X-Forwarded-For", "5.6.7.8")
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+	entries = readAccessLog(t, buf)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, "c", entries[0].Method)
+	assert.Equal(t, `"3"`, string(entries[0].Params["i"]))
+	assert.Equal(t, "5.6.7.8", entries[0].ForwardedFor)
+
+	// the errors
+	req, _ = http.NewRequest("POST", "http://localhost/", strings.NewReader(`{"method": "d", "id": "0"}`))
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+	entries = readAccessLog(t, buf)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, "d", entries[0].Method)
+	assert.Equal(t, -32601, entries[0].RPCError)
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	buf := new(bytes.Buffer)
+	mux := accessLogMux(rs.NewAccessLog(buf, 0, []string{"*"}))
+
+	// only the failed requests are logged
+	for i := 0; i < 10; i++ {
+		req, _ := http.NewRequest("GET", `http://localhost/c?s="a"&i=3`, nil)
+		mux.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	req, _ := http.NewRequest("GET", `http://localhost/c?s="a"&i=b`, nil)
+	mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := readAccessLog(t, buf)
+	require.Equal(t, 1, len(entries))
+	assert.NotEqual(t, 0, entries[0].RPCError)
+	assert.Equal(t, `"<redacted>"`, string(entries[0].Params["s"]))
+	assert.Equal(t, `"<redacted>"`, string(entries[0].Params["i"]))
+}
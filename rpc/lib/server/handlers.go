@@ -44,10 +44,15 @@ type RPCFunc struct {
 	returns  []reflect.Type // type of each return arg
 	argNames []string       // name of each argument
 	ws       bool           // websocket only
+	ctx      bool           // first arg is a context.Context, done when the client goes away
+
+	cacheable func(args []interface{}) bool // nil if the results are never cacheable
 }
 
 // NewRPCFunc wraps a function for introspection.
-// f is the function, args are comma separated argument names
+// f is the function, args are comma separated argument names.
+// If the first argument of f is a context.Context, it is not named in args:
+// it is the context of the request, which is done once the client disconnects.
 func NewRPCFunc(f interface{}, args string) *RPCFunc {
 	return newRPCFunc(f, args, false)
 }
@@ -62,13 +67,39 @@ func newRPCFunc(f interface{}, args string, ws bool) *RPCFunc {
 	if args != "" {
 		argNames = strings.Split(args, ",")
 	}
+	argTypes := funcArgTypes(f)
 	return &RPCFunc{
 		f:        reflect.ValueOf(f),
-		args:     funcArgTypes(f),
+		args:     argTypes,
 		returns:  funcReturnTypes(f),
 		argNames: argNames,
 		ws:       ws,
+		ctx:      !ws && len(argTypes) > 0 && argTypes[0] == contextType,
+	}
+}
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// Cacheable makes the HTTP responses of the function cacheable when cacheable
+// returns true for its args, but the context, eg. a past height whose results
+// never change: they get an ETag and a Cache-Control header, and a request with
+// the ETag in its If-None-Match gets a 304 Not Modified. It returns the RPCFunc.
+func (f *RPCFunc) Cacheable(cacheable func(args []interface{}) bool) *RPCFunc {
+	f.cacheable = cacheable
+	return f
+}
+
+// isCacheable returns whether the results of the call with the args, but the
+// context, are cacheable.
+func (f *RPCFunc) isCacheable(args []reflect.Value) bool {
+	if f.cacheable == nil {
+		return false
+	}
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg.Interface()
 	}
+	return f.cacheable(values)
 }
 
 // return a function's argument types
@@ -100,6 +131,13 @@ func funcReturnTypes(f interface{}) []reflect.Type {
 // jsonrpc calls grab the given method's function info and runs reflect.Call
 func makeJSONRPCHandler(funcMap map[string]*RPCFunc, logger log.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		// the mux routes the paths of no method here too: they are not
+		// JSON-RPC requests, whose methods are authorized and limited by the
+		// ones of their body only on "/", see requestMethods
+		if r.URL.Path != "/" {
+			WriteRPCResponseHTTPError(w, http.StatusNotFound, types.RPCMethodNotFoundError(""))
+			return
+		}
 		b, err := ioutil.ReadAll(r.Body)
 		if err != nil {
 			WriteRPCResponseHTTP(w, types.RPCInvalidRequestError("", errors.Wrap(err, "Error reading request body")))
@@ -112,48 +150,80 @@ func makeJSONRPCHandler(funcMap map[string]*RPCFunc, logger log.Logger) http.Han
 			return
 		}
 
+		// a batch of requests, answered with the array of their responses, in order
+		if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && trimmed[0] == '[' {
+			var requests []types.RPCRequest
+			if err := json.Unmarshal(trimmed, &requests); err != nil {
+				WriteRPCResponseHTTP(w, types.RPCParseError("", errors.Wrap(err, "Error unmarshalling batch request")))
+				return
+			}
+			if len(requests) == 0 {
+				WriteRPCResponseHTTP(w, types.RPCInvalidRequestError("", errors.New("Empty batch request")))
+				return
+			}
+			responses := make([]types.RPCResponse, 0, len(requests))
+			for _, request := range requests {
+				if response, ok := handleJSONRPCRequest(funcMap, logger, r, request); ok {
+					responses = append(responses, response)
+				}
+			}
+			// only notifications
+			if len(responses) == 0 {
+				return
+			}
+			WriteRPCResponseArrayHTTP(w, responses)
+			return
+		}
+
 		var request types.RPCRequest
 		err = json.Unmarshal(b, &request)
 		if err != nil {
 			WriteRPCResponseHTTP(w, types.RPCParseError("", errors.Wrap(err, "Error unmarshalling request")))
 			return
 		}
-		// A Notification is a Request object without an "id" member.
-		// The Server MUST NOT reply to a Notification, including those that are within a batch request.
-		if request.ID == "" {
-			logger.Debug("HTTPJSONRPC received a notification, skipping... (please send a non-empty ID if you want to call a method)")
-			return
-		}
-		if len(r.URL.Path) > 1 {
-			WriteRPCResponseHTTP(w, types.RPCInvalidRequestError(request.ID, errors.Errorf("Path %s is invalid", r.URL.Path)))
-			return
-		}
-		rpcFunc := funcMap[request.Method]
-		if rpcFunc == nil || rpcFunc.ws {
-			WriteRPCResponseHTTP(w, types.RPCMethodNotFoundError(request.ID))
-			return
-		}
-		var args []reflect.Value
-		if len(request.Params) > 0 {
-			args, err = jsonParamsToArgsRPC(rpcFunc, request.Params)
-			if err != nil {
-				WriteRPCResponseHTTP(w, types.RPCInvalidParamsError(request.ID, errors.Wrap(err, "Error converting json params to arguments")))
-				return
-			}
+		if response, ok := handleJSONRPCRequest(funcMap, logger, r, request); ok {
+			WriteRPCResponseHTTP(w, response)
 		}
-		returns := rpcFunc.f.Call(args)
-		logger.Info("HTTPJSONRPC", "method", request.Method, "args", args, "returns", returns)
-		result, err := unreflectResult(returns)
+	}
+}
+
+// handleJSONRPCRequest calls the function of the request, alone or in a batch,
+// and returns its response, false for a notification, which gets none.
+func handleJSONRPCRequest(funcMap map[string]*RPCFunc, logger log.Logger, r *http.Request,
+	request types.RPCRequest) (types.RPCResponse, bool) {
+	// A Notification is a Request object without an "id" member.
+	// The Server MUST NOT reply to a Notification, including those that are within a batch request.
+	if request.ID == "" {
+		logger.Debug("HTTPJSONRPC received a notification, skipping... (please send a non-empty ID if you want to call a method)")
+		return types.RPCResponse{}, false
+	}
+	if len(r.URL.Path) > 1 {
+		return types.RPCInvalidRequestError(request.ID, errors.Errorf("Path %s is invalid", r.URL.Path)), true
+	}
+	rpcFunc := funcMap[request.Method]
+	if rpcFunc == nil || rpcFunc.ws {
+		return types.RPCMethodNotFoundError(request.ID), true
+	}
+	var args []reflect.Value
+	if len(request.Params) > 0 {
+		var err error
+		args, err = jsonParamsToArgsRPC(rpcFunc, request.Params)
 		if err != nil {
-			WriteRPCResponseHTTP(w, types.RPCInternalError(request.ID, err))
-			return
+			return types.RPCInvalidParamsError(request.ID, errors.Wrap(err, "Error converting json params to arguments")), true
 		}
-		WriteRPCResponseHTTP(w, types.NewRPCSuccessResponse(request.ID, result))
 	}
+	args = withContext(rpcFunc, r.Context(), args)
+	returns := rpcFunc.f.Call(args)
+	logger.Info("HTTPJSONRPC", "method", request.Method, "args", args, "returns", returns)
+	result, err := unreflectResult(returns)
+	if err != nil {
+		return types.RPCInternalError(request.ID, err), true
+	}
+	return types.NewRPCSuccessResponse(request.ID, result), true
 }
 
 func mapParamsToArgs(rpcFunc *RPCFunc, params map[string]*json.RawMessage, argsOffset int) ([]reflect.Value, error) {
-	values := make([]reflect.Value, len(rpcFunc.argNames))
+	values := make([]reflect.Value, len(rpcFunc.args)-argsOffset)
 	for i, argName := range rpcFunc.argNames {
 		argType := rpcFunc.args[i+argsOffset]
 
@@ -172,17 +242,24 @@ func mapParamsToArgs(rpcFunc *RPCFunc, params map[string]*json.RawMessage, argsO
 	return values, nil
 }
 
+// arrayParamsToArgs accepts fewer params than the args, eg. from the callers
+// predating the params added last: the missing ones get their default, as
+// the ones missing from a map.
 func arrayParamsToArgs(rpcFunc *RPCFunc, params []*json.RawMessage, argsOffset int) ([]reflect.Value, error) {
-	if len(rpcFunc.argNames) != len(params) {
-		return nil, errors.Errorf("Expected %v parameters (%v), got %v (%v)",
+	if len(params) > len(rpcFunc.argNames) {
+		return nil, errors.Errorf("Expected at most %v parameters (%v), got %v (%v)",
 			len(rpcFunc.argNames), rpcFunc.argNames, len(params), params)
 	}
 
-	values := make([]reflect.Value, len(params))
-	for i, p := range params {
+	values := make([]reflect.Value, len(rpcFunc.argNames))
+	for i := range rpcFunc.argNames {
 		argType := rpcFunc.args[i+argsOffset]
+		if i >= len(params) || params[i] == nil {
+			values[i] = reflect.Zero(argType)
+			continue
+		}
 		val := reflect.New(argType)
-		err := json.Unmarshal(*p, val.Interface())
+		err := json.Unmarshal(*params[i], val.Interface())
 		if err != nil {
 			return nil, err
 		}
@@ -218,9 +295,21 @@ func jsonParamsToArgs(rpcFunc *RPCFunc, raw []byte, argsOffset int) ([]reflect.V
 
 // Convert a []interface{} OR a map[string]interface{} to properly typed values
 func jsonParamsToArgsRPC(rpcFunc *RPCFunc, params json.RawMessage) ([]reflect.Value, error) {
+	if rpcFunc.ctx {
+		return jsonParamsToArgs(rpcFunc, params, 1)
+	}
 	return jsonParamsToArgs(rpcFunc, params, 0)
 }
 
+// withContext prepends the context of the request to the args
+// if the function takes one.
+func withContext(rpcFunc *RPCFunc, ctx context.Context, args []reflect.Value) []reflect.Value {
+	if !rpcFunc.ctx {
+		return args
+	}
+	return append([]reflect.Value{reflect.ValueOf(ctx)}, args...)
+}
+
 // Same as above, but with the first param the websocket connection
 func jsonParamsToArgsWS(rpcFunc *RPCFunc, params json.RawMessage, wsCtx types.WSRPCContext) ([]reflect.Value, error) {
 	values, err := jsonParamsToArgs(rpcFunc, params, 1)
@@ -250,6 +339,8 @@ func makeHTTPHandler(rpcFunc *RPCFunc, logger log.Logger) func(http.ResponseWrit
 			WriteRPCResponseHTTP(w, types.RPCInvalidParamsError("", errors.Wrap(err, "Error converting http params to arguments")))
 			return
 		}
+		cacheable := rpcFunc.isCacheable(args)
+		args = withContext(rpcFunc, r.Context(), args)
 		returns := rpcFunc.f.Call(args)
 		logger.Info("HTTPRestRPC", "method", r.URL.Path, "args", args, "returns", returns)
 		result, err := unreflectResult(returns)
@@ -257,6 +348,10 @@ func makeHTTPHandler(rpcFunc *RPCFunc, logger log.Logger) func(http.ResponseWrit
 			WriteRPCResponseHTTP(w, types.RPCInternalError("", err))
 			return
 		}
+		if cacheable {
+			WriteCacheableRPCResponseHTTP(w, r, types.NewRPCSuccessResponse("", result))
+			return
+		}
 		WriteRPCResponseHTTP(w, types.NewRPCSuccessResponse("", result))
 	}
 }
@@ -264,10 +359,14 @@ func makeHTTPHandler(rpcFunc *RPCFunc, logger log.Logger) func(http.ResponseWrit
 // Covert an http query to a list of properly typed values.
 // To be properly decoded the arg must be a concrete type from tendermint (if its an interface).
 func httpParamsToArgs(rpcFunc *RPCFunc, r *http.Request) ([]reflect.Value, error) {
-	values := make([]reflect.Value, len(rpcFunc.args))
+	argsOffset := 0
+	if rpcFunc.ctx {
+		argsOffset = 1
+	}
+	values := make([]reflect.Value, len(rpcFunc.args)-argsOffset)
 
 	for i, name := range rpcFunc.argNames {
-		argType := rpcFunc.args[i]
+		argType := rpcFunc.args[i+argsOffset]
 
 		values[i] = reflect.Zero(argType) // set default for that type
 
@@ -367,6 +466,10 @@ type wsConnection struct {
 
 	funcMap map[string]*RPCFunc
 
+	// context of the calls made over the connection, cancelled when it stops
+	ctx    context.Context
+	cancel context.CancelFunc
+
 	// write channel capacity
 	writeChanCapacity int
 
@@ -381,6 +484,17 @@ type wsConnection struct {
 
 	// object that is used to subscribe / unsubscribe from events
 	eventSub types.EventSubscriber
+
+	// limits of the calls of the client, nil for none
+	rateLimiter *RateLimiter
+
+	// max size of the messages read, 0 for no limit
+	readLimit int64
+
+	// authorization of the calls to the unsafe methods, nil for none,
+	// with the token of the request the connection was opened with
+	unsafeAuth *UnsafeAuth
+	authToken  string
 }
 
 // NewWSConnection wraps websocket.Conn.
@@ -402,6 +516,7 @@ func NewWSConnection(baseConn *websocket.Conn, funcMap map[string]*RPCFunc, opti
 	for _, option := range options {
 		option(wsc)
 	}
+	wsc.ctx, wsc.cancel = context.WithCancel(context.Background())
 	wsc.BaseService = *cmn.NewBaseService(nil, "wsConnection", wsc)
 	return wsc
 }
@@ -464,6 +579,7 @@ func (wsc *wsConnection) OnStart() error {
 func (wsc *wsConnection) OnStop() {
 	// Both read and write loops close the websocket connection when they exit their loops.
 	// The writeChan is never closed, to allow WriteRPCResponse() to fail.
+	wsc.cancel()
 	if wsc.eventSub != nil {
 		wsc.eventSub.UnsubscribeAll(context.TODO(), wsc.remoteAddr)
 	}
@@ -522,6 +638,9 @@ func (wsc *wsConnection) readRoutine() {
 	wsc.baseConn.SetPongHandler(func(m string) error {
 		return wsc.baseConn.SetReadDeadline(time.Now().Add(wsc.readWait))
 	})
+	if wsc.readLimit > 0 {
+		wsc.baseConn.SetReadLimit(wsc.readLimit)
+	}
 
 	for {
 		select {
@@ -565,6 +684,10 @@ func (wsc *wsConnection) readRoutine() {
 				wsc.WriteRPCResponse(types.RPCMethodNotFoundError(request.ID))
 				continue
 			}
+			if err := wsc.unsafeAuth.Authorize(wsc.remoteAddr, wsc.authToken, request.Method); err != nil {
+				wsc.WriteRPCResponse(types.RPCUnauthorizedError(request.ID, err))
+				continue
+			}
 			var args []reflect.Value
 			if rpcFunc.ws {
 				wsCtx := types.WSRPCContext{Request: request, WSRPCConnection: wsc}
@@ -580,7 +703,14 @@ func (wsc *wsConnection) readRoutine() {
 				wsc.WriteRPCResponse(types.RPCInternalError(request.ID, errors.Wrap(err, "Error converting json params to arguments")))
 				continue
 			}
-			returns := rpcFunc.f.Call(args)
+			args = withContext(rpcFunc, wsc.ctx, args)
+			returns, err := wsc.callRateLimited(request.Method, func() []reflect.Value {
+				return rpcFunc.f.Call(args)
+			})
+			if err != nil {
+				wsc.WriteRPCResponse(types.RPCTooManyRequestsError(request.ID, err))
+				continue
+			}
 
 			// TODO: Need to encode args/returns to string if we want to log them
 			wsc.Logger.Info("WSJSONRPC", "method", request.Method)
@@ -703,6 +833,7 @@ func (wm *WebsocketManager) WebsocketHandler(w http.ResponseWriter, r *http.Requ
 
 	// register connection
 	con := NewWSConnection(wsConn, wm.funcMap, wm.wsConnOptions...)
+	con.authToken = requestAuthToken(r)
 	con.SetLogger(wm.logger.With("remote", wsConn.RemoteAddr()))
 	wm.logger.Info("New websocket connection", "remote", con.remoteAddr)
 	err = con.Start() // Blocking
@@ -2,6 +2,7 @@ package rpcserver_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
@@ -44,7 +45,7 @@ func TestRPCParams(t *testing.T) {
 		{`{"jsonrpc": "2.0", "id": "0"}`, "Method not found"},
 		{`{"jsonrpc": "2.0", "method": "y", "id": "0"}`, "Method not found"},
 		{`{"method": "c", "id": "0", "params": a}`, "invalid character"},
-		{`{"method": "c", "id": "0", "params": ["a"]}`, "got 1"},
+		{`{"method": "c", "id": "0", "params": ["a", 10, 1]}`, "got 3"},
 		{`{"method": "c", "id": "0", "params": ["a", "b"]}`, "of type int"},
 		{`{"method": "c", "id": "0", "params": [1, 1]}`, "of type string"},
 
@@ -52,6 +53,9 @@ func TestRPCParams(t *testing.T) {
 		{`{"jsonrpc": "2.0", "method": "c", "id": "0", "params": null}`, ""},
 		{`{"method": "c", "id": "0", "params": {}}`, ""},
 		{`{"method": "c", "id": "0", "params": ["a", 10]}`, ""},
+		// the missing trailing params get their default
+		{`{"method": "c", "id": "0", "params": ["a"]}`, ""},
+		{`{"method": "c", "id": "0", "params": []}`, ""},
 	}
 
 	for i, tt := range tests {
@@ -95,3 +99,150 @@ func TestRPCNotification(t *testing.T) {
 	require.Nil(t, err, "reading from the body should not give back an error")
 	require.Equal(t, len(blob), 0, "a notification SHOULD NOT be responded to by the server")
 }
+
+func TestRPCBatch(t *testing.T) {
+	mux := testMux()
+	call := func(payload string) []byte {
+		req, _ := http.NewRequest("POST", "http://localhost/", strings.NewReader(payload))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		require.True(t, statusOK(rec.Code), "should always return 2XX")
+		return rec.Body.Bytes()
+	}
+
+	// the responses are in the order of the requests, without the notifications
+	blob := call(`[
+		{"method": "c", "id": "0", "params": ["a", 10]},
+		{"method": "y", "id": "1"},
+		{"method": "c", "params": ["a", 10]},
+		{"method": "c", "id": "2", "params": ["a", "b"]},
+		{"method": "c", "id": "3", "params": {"s": "a", "i": 1}}
+	]`)
+	var responses []types.RPCResponse
+	require.Nil(t, json.Unmarshal(blob, &responses), "blob: %s", blob)
+	require.Equal(t, 4, len(responses))
+	for i, id := range []string{"0", "1", "2", "3"} {
+		assert.Equal(t, id, responses[i].ID)
+	}
+	assert.Nil(t, responses[0].Error)
+	assert.Equal(t, `"foo"`, string(responses[0].Result))
+	require.NotNil(t, responses[1].Error)
+	assert.Contains(t, responses[1].Error.Message, "Method not found")
+	require.NotNil(t, responses[2].Error)
+	assert.Contains(t, responses[2].Error.Data, "of type int")
+	assert.Nil(t, responses[3].Error)
+
+	// only notifications
+	assert.Equal(t, 0, len(call(`[{"method": "c", "params": ["a", 10]}]`)))
+
+	// an empty or invalid batch gets a single error
+	for _, payload := range []string{`[]`, ` [{"method": "c"}`} {
+		recv := new(types.RPCResponse)
+		blob = call(payload)
+		require.Nil(t, json.Unmarshal(blob, recv), "blob: %s", blob)
+		assert.NotNil(t, recv.Error, payload)
+	}
+}
+
+func TestRPCContext(t *testing.T) {
+	funcMap := map[string]*rs.RPCFunc{
+		"c": rs.NewRPCFunc(func(ctx context.Context, s string) (string, error) {
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+			return s, nil
+		}, "s"),
+	}
+	mux := http.NewServeMux()
+	rs.RegisterRPCFuncs(mux, funcMap, log.NewNopLogger())
+
+	jsonReq := func() *http.Request {
+		req, _ := http.NewRequest("POST", "http://localhost/", strings.NewReader(`{"method": "c", "id": "0", "params": ["foo"]}`))
+		return req
+	}
+	uriReq := func() *http.Request {
+		req, _ := http.NewRequest("GET", `http://localhost/c?s="foo"`, nil)
+		return req
+	}
+	call := func(ctx context.Context, req *http.Request) *types.RPCResponse {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req.WithContext(ctx))
+		recv := new(types.RPCResponse)
+		require.Nil(t, json.Unmarshal(rec.Body.Bytes(), recv), "body: %s", rec.Body)
+		return recv
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// the context is not one of the params
+	recv := call(context.Background(), jsonReq())
+	require.Nil(t, recv.Error)
+	assert.Equal(t, `"foo"`, string(recv.Result))
+
+	recv = call(context.Background(), uriReq())
+	require.Nil(t, recv.Error)
+	assert.Equal(t, `"foo"`, string(recv.Result))
+
+	// the function sees the client is gone
+	recv = call(cancelled, jsonReq())
+	require.NotNil(t, recv.Error)
+	assert.Contains(t, recv.Error.Data, context.Canceled.Error())
+
+	recv = call(cancelled, uriReq())
+	require.NotNil(t, recv.Error)
+	assert.Contains(t, recv.Error.Data, context.Canceled.Error())
+}
+
+func TestRPCCacheable(t *testing.T) {
+	funcMap := map[string]*rs.RPCFunc{
+		"c": rs.NewRPCFunc(func(ctx context.Context, height int64) (int64, error) {
+			return height, nil
+		}, "height").Cacheable(func(args []interface{}) bool {
+			return args[0].(int64) < 10
+		}),
+	}
+	mux := http.NewServeMux()
+	rs.RegisterRPCFuncs(mux, funcMap, log.NewNopLogger())
+
+	call := func(method, url, body, ifNoneMatch string) *http.Response {
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Result()
+	}
+
+	res := call("GET", "http://localhost/c?height=5", "", "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	etag := res.Header.Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Contains(t, res.Header.Get("Cache-Control"), "immutable")
+
+	// the same response, the same ETag
+	res = call("GET", "http://localhost/c?height=5", "", "")
+	assert.Equal(t, etag, res.Header.Get("ETag"))
+
+	// not modified, with no body
+	for _, ifNoneMatch := range []string{etag, `"other", ` + etag, "W/" + etag} {
+		res = call("GET", "http://localhost/c?height=5", "", ifNoneMatch)
+		assert.Equal(t, http.StatusNotModified, res.StatusCode, ifNoneMatch)
+		blob, _ := ioutil.ReadAll(res.Body)
+		assert.Empty(t, blob)
+	}
+	res = call("GET", "http://localhost/c?height=6", "", etag)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.NotEqual(t, etag, res.Header.Get("ETag"))
+
+	// not cacheable
+	res = call("GET", "http://localhost/c?height=10", "", "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Empty(t, res.Header.Get("ETag"))
+	assert.Empty(t, res.Header.Get("Cache-Control"))
+
+	// nor are the JSONRPC requests
+	res = call("POST", "http://localhost/", `{"method": "c", "id": "0", "params": [5]}`, "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Empty(t, res.Header.Get("ETag"))
+}
@@ -3,8 +3,12 @@ package rpcserver
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"runtime/debug"
@@ -18,6 +22,16 @@ import (
 )
 
 func StartHTTPServer(listenAddr string, handler http.Handler, logger log.Logger) (listener net.Listener, err error) {
+	return startHTTPServer(listenAddr, handler, nil, logger)
+}
+
+// StartHTTPAndTLSServer is like StartHTTPServer, but serves HTTPS, and the
+// websockets over wss://, with the tlsConfig, eg. from NewServerTLSConfig.
+func StartHTTPAndTLSServer(listenAddr string, handler http.Handler, tlsConfig *tls.Config, logger log.Logger) (listener net.Listener, err error) {
+	return startHTTPServer(listenAddr, handler, tlsConfig, logger)
+}
+
+func startHTTPServer(listenAddr string, handler http.Handler, tlsConfig *tls.Config, logger log.Logger) (listener net.Listener, err error) {
 	// listenAddr should be fully formed including tcp:// or unix:// prefix
 	var proto, addr string
 	parts := strings.SplitN(listenAddr, "://", 2)
@@ -32,11 +46,14 @@ func StartHTTPServer(listenAddr string, handler http.Handler, logger log.Logger)
 		proto, addr = parts[0], parts[1]
 	}
 
-	logger.Info(fmt.Sprintf("Starting RPC HTTP server on %s socket %v", proto, addr))
+	logger.Info(fmt.Sprintf("Starting RPC HTTP server on %s socket %v", proto, addr), "tls", tlsConfig != nil)
 	listener, err = net.Listen(proto, addr)
 	if err != nil {
 		return nil, errors.Errorf("Failed to listen to %v: %v", listenAddr, err)
 	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
 
 	go func() {
 		res := http.Serve(
@@ -48,6 +65,33 @@ func StartHTTPServer(listenAddr string, handler http.Handler, logger log.Logger)
 	return listener, nil
 }
 
+// NewServerTLSConfig returns the TLS config of a server with the certificate
+// and the key of the PEM files. If clientCAFile is not empty, the clients must
+// present a certificate signed by one of the CAs of the PEM file.
+func NewServerTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error loading the TLS certificate")
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if clientCAFile != "" {
+		pem, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "Error reading the client CAs")
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("No certificate in %v", clientCAFile)
+		}
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
 func WriteRPCResponseHTTPError(w http.ResponseWriter, httpCode int, res types.RPCResponse) {
 	jsonBytes, err := json.MarshalIndent(res, "", "  ")
 	if err != nil {
@@ -69,6 +113,53 @@ func WriteRPCResponseHTTP(w http.ResponseWriter, res types.RPCResponse) {
 	w.Write(jsonBytes) // nolint: errcheck, gas
 }
 
+// WriteRPCResponseArrayHTTP writes the responses of a batch request, in order.
+func WriteRPCResponseArrayHTTP(w http.ResponseWriter, res []types.RPCResponse) {
+	jsonBytes, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(jsonBytes) // nolint: errcheck, gas
+}
+
+// immutableCacheControl lets the clients and proxies cache a response for a year.
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// WriteCacheableRPCResponseHTTP writes a response which never changes with an
+// ETag, the hash of its body, and a Cache-Control header, or a 304 Not Modified
+// if the request has the ETag in its If-None-Match.
+func WriteCacheableRPCResponseHTTP(w http.ResponseWriter, r *http.Request, res types.RPCResponse) {
+	jsonBytes, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	hash := sha256.Sum256(jsonBytes)
+	etag := fmt.Sprintf(`"%X"`, hash[:16])
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", immutableCacheControl)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(200)
+	w.Write(jsonBytes) // nolint: errcheck, gas
+}
+
+// etagMatches returns whether the etag is in the If-None-Match header, which
+// compares the weak ETags too.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
 //-----------------------------------------------------------------------------
 
 // Wraps an HTTP handler, adding error logging.
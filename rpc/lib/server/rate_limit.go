@@ -0,0 +1,313 @@
+package rpcserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	types "github.com/tendermint/tendermint/rpc/lib/types"
+)
+
+// rateLimitSweepInterval is how often the clients back within their limits are
+// forgotten.
+const rateLimitSweepInterval = time.Minute
+
+// RateLimit is the max number of requests per second of a client to a method,
+// and of them being served at once. 0 means no limit.
+type RateLimit struct {
+	Rate          float64
+	MaxConcurrent int
+}
+
+func (limit RateLimit) isZero() bool {
+	return limit.Rate <= 0 && limit.MaxConcurrent <= 0
+}
+
+// burst is the max number of requests in a row, a second of them.
+func (limit RateLimit) burst() float64 {
+	return math.Max(1, limit.Rate)
+}
+
+// ParseMethodRateLimits parses comma separated limits of methods, as
+// method=requests_per_second[:max_concurrent], eg. "broadcast_tx_commit=1:1".
+func ParseMethodRateLimits(s string) (map[string]RateLimit, error) {
+	limits := make(map[string]RateLimit)
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, errors.Errorf("Invalid method rate limit %q, must be method=requests_per_second[:max_concurrent]", field)
+		}
+		values := strings.SplitN(parts[1], ":", 2)
+		var limit RateLimit
+		var err error
+		if limit.Rate, err = strconv.ParseFloat(values[0], 64); err != nil || limit.Rate < 0 {
+			return nil, errors.Errorf("Invalid requests per second in the method rate limit %q", field)
+		}
+		if len(values) == 2 {
+			if limit.MaxConcurrent, err = strconv.Atoi(values[1]); err != nil || limit.MaxConcurrent < 0 {
+				return nil, errors.Errorf("Invalid max concurrent requests in the method rate limit %q", field)
+			}
+		}
+		limits[parts[0]] = limit
+	}
+	return limits, nil
+}
+
+// RateLimiter limits the requests of each client IP to each method: with a
+// token bucket of Rate tokens per second, holding up to a second of them, and
+// the count of the requests being served.
+type RateLimiter struct {
+	mtx       sync.Mutex
+	limit     RateLimit
+	methods   map[string]RateLimit
+	clients   map[rateLimitKey]*clientRate
+	lastSweep time.Time
+
+	now func() time.Time
+}
+
+type rateLimitKey struct {
+	ip     string
+	method string
+}
+
+type clientRate struct {
+	tokens     float64
+	last       time.Time
+	concurrent int
+}
+
+// NewRateLimiter returns a RateLimiter with the limit for all the methods but
+// the ones of methods, or nil if there are no limits, which limits nothing.
+func NewRateLimiter(limit RateLimit, methods map[string]RateLimit) *RateLimiter {
+	limited := !limit.isZero()
+	for _, methodLimit := range methods {
+		limited = limited || !methodLimit.isZero()
+	}
+	if !limited {
+		return nil
+	}
+	return &RateLimiter{
+		limit:     limit,
+		methods:   methods,
+		clients:   make(map[rateLimitKey]*clientRate),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Allow returns a function to call once the request of the client to the
+// method is served, or an error if the client is over the limits of the method.
+func (rl *RateLimiter) Allow(ip, method string) (done func(), err error) {
+	if rl == nil {
+		return func() {}, nil
+	}
+	limit, ok := rl.methods[method]
+	if !ok {
+		limit = rl.limit
+	}
+	if limit.isZero() {
+		return func() {}, nil
+	}
+
+	rl.mtx.Lock()
+	defer rl.mtx.Unlock()
+	now := rl.now()
+	rl.sweep(now)
+	key := rateLimitKey{ip, method}
+	client, ok := rl.clients[key]
+	if !ok {
+		client = &clientRate{tokens: limit.burst(), last: now}
+		rl.clients[key] = client
+	}
+	if limit.Rate > 0 {
+		client.tokens = math.Min(limit.burst(), client.tokens+now.Sub(client.last).Seconds()*limit.Rate)
+		client.last = now
+		if client.tokens < 1 {
+			return nil, errors.Errorf("Over %v requests per second to %s", limit.Rate, method)
+		}
+	}
+	if limit.MaxConcurrent > 0 && client.concurrent >= limit.MaxConcurrent {
+		return nil, errors.Errorf("Over %d concurrent requests to %s", limit.MaxConcurrent, method)
+	}
+	if limit.Rate > 0 {
+		client.tokens--
+	}
+	client.concurrent++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			rl.mtx.Lock()
+			defer rl.mtx.Unlock()
+			client.concurrent--
+		})
+	}, nil
+}
+
+// sweep forgets the clients with no request being served and a full bucket,
+// as if they never came, every rateLimitSweepInterval.
+// CONTRACT: rl.mtx is held.
+func (rl *RateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	rl.lastSweep = now
+	for key, client := range rl.clients {
+		if client.concurrent > 0 {
+			continue
+		}
+		limit, ok := rl.methods[key.method]
+		if !ok {
+			limit = rl.limit
+		}
+		if limit.Rate <= 0 || client.tokens+now.Sub(client.last).Seconds()*limit.Rate >= limit.burst() {
+			delete(rl.clients, key)
+		}
+	}
+}
+
+// RateLimitHandler wraps an RPC handler, answering the requests of the clients
+// over the limits of the RateLimiter with a 429 and a JSON-RPC error. Each
+// request of a batch counts. The client is the IP the request came from: the
+// X-Forwarded-For header is set by the client, unless a proxy overwrites it,
+// and all the clients behind a proxy share its limits. The websocket
+// connections count as requests to the method "websocket" for as long as they
+// are open.
+func RateLimitHandler(handler http.Handler, rl *RateLimiter) http.Handler {
+	if rl == nil {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods, err := requestMethods(r)
+		if err != nil {
+			WriteRPCResponseHTTP(w, types.RPCInvalidRequestError("", err))
+			return
+		}
+
+		ip := clientIP(r.RemoteAddr)
+		for _, method := range methods {
+			done, err := rl.Allow(ip, method)
+			if err != nil {
+				WriteRPCResponseHTTPError(w, http.StatusTooManyRequests, types.RPCTooManyRequestsError("", err))
+				return
+			}
+			defer done()
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// MaxBytesHandler wraps an RPC handler, failing to read the bodies of the
+// requests past maxBytes, if positive, see http.MaxBytesReader.
+func MaxBytesHandler(handler http.Handler, maxBytes int64) http.Handler {
+	if maxBytes <= 0 {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// requestMethods returns the methods called by the request, leaving its body
+// to the handler: the one of the path for the URI requests, or the ones of the
+// JSON-RPC request, or batch of them, of the body of the requests to "/",
+// whatever their HTTP method, as the JSON-RPC handler serves them all. An
+// empty body, for the list of the methods, calls "".
+func requestMethods(r *http.Request) ([]string, error) {
+	if r.URL.Path != "/" {
+		return []string{strings.TrimPrefix(r.URL.Path, "/")}, nil
+	}
+	if r.Body == nil {
+		return []string{""}, nil
+	}
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error reading request body")
+	}
+	// give the body back to the handler
+	r.Body = ioutil.NopCloser(bytes.NewReader(b))
+	if len(b) == 0 {
+		return []string{""}, nil
+	}
+	return jsonRPCMethods(b)
+}
+
+// jsonRPCMethods returns the methods of a JSON-RPC request, or of a batch of
+// them, or an error if it can't be parsed, rather than let the handler guess.
+func jsonRPCMethods(b []byte) ([]string, error) {
+	if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && trimmed[0] == '[' {
+		var requests []types.RPCRequest
+		if err := json.Unmarshal(trimmed, &requests); err != nil {
+			return nil, errors.Wrap(err, "Error unmarshalling batch request")
+		}
+		if len(requests) == 0 {
+			return nil, errors.New("Empty batch request")
+		}
+		methods := make([]string, len(requests))
+		for i, request := range requests {
+			methods[i] = request.Method
+		}
+		return methods, nil
+	}
+	var request types.RPCRequest
+	if err := json.Unmarshal(b, &request); err != nil {
+		return nil, errors.Wrap(err, "Error unmarshalling request")
+	}
+	return []string{request.Method}, nil
+}
+
+// clientIP returns the IP of the remote address, or the address if it has no
+// port, eg. a unix socket.
+func clientIP(remoteAddr string) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	return remoteAddr
+}
+
+// RateLimits limits the calls over the websocket connection with the
+// RateLimiter, as the ones over HTTP from its IP.
+// It should only be used in the constructor - not Goroutine-safe.
+func RateLimits(rl *RateLimiter) func(*wsConnection) {
+	return func(wsc *wsConnection) {
+		wsc.rateLimiter = rl
+	}
+}
+
+// ReadLimit sets the max size of the messages read from the websocket
+// connection, 0 for no limit. The connection is closed past it.
+// It should only be used in the constructor - not Goroutine-safe.
+func ReadLimit(readLimit int64) func(*wsConnection) {
+	return func(wsc *wsConnection) {
+		wsc.readLimit = readLimit
+	}
+}
+
+// callRateLimited returns the returns of the call of the method, or an error
+// if the client is over its limits.
+func (wsc *wsConnection) callRateLimited(method string, call func() []reflect.Value) ([]reflect.Value, error) {
+	done, err := wsc.rateLimiter.Allow(clientIP(wsc.remoteAddr), method)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return call(), nil
+}
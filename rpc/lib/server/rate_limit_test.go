@@ -0,0 +1,127 @@
+package rpcserver_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	rs "github.com/tendermint/tendermint/rpc/lib/server"
+	"github.com/tendermint/tmlibs/log"
+)
+
+func TestParseMethodRateLimits(t *testing.T) {
+	limits, err := rs.ParseMethodRateLimits(" broadcast_tx_commit=1:1, tx_search=0.5 ,,")
+	require.Nil(t, err)
+	assert.Equal(t, map[string]rs.RateLimit{
+		"broadcast_tx_commit": {Rate: 1, MaxConcurrent: 1},
+		"tx_search":           {Rate: 0.5},
+	}, limits)
+
+	limits, err = rs.ParseMethodRateLimits("")
+	require.Nil(t, err)
+	assert.Empty(t, limits)
+
+	for _, s := range []string{"block", "=1", "block=a", "block=-1", "block=1:a", "block=1:-1"} {
+		_, err := rs.ParseMethodRateLimits(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	assert.Nil(t, rs.NewRateLimiter(rs.RateLimit{}, map[string]rs.RateLimit{"block": {}}))
+	var nilLimiter *rs.RateLimiter
+	done, err := nilLimiter.Allow("1.2.3.4", "block")
+	require.Nil(t, err)
+	done()
+
+	rl := rs.NewRateLimiter(rs.RateLimit{Rate: 0.01}, map[string]rs.RateLimit{
+		"status":    {},
+		"tx_search": {MaxConcurrent: 2},
+	})
+
+	// a request in a row
+	done, err = rl.Allow("1.2.3.4", "block")
+	require.Nil(t, err)
+	done()
+	_, err = rl.Allow("1.2.3.4", "block")
+	assert.NotNil(t, err)
+	// per method and per client
+	_, err = rl.Allow("1.2.3.4", "commit")
+	assert.Nil(t, err)
+	_, err = rl.Allow("5.6.7.8", "block")
+	assert.Nil(t, err)
+
+	// no limit
+	for i := 0; i < 10; i++ {
+		_, err = rl.Allow("1.2.3.4", "status")
+		assert.Nil(t, err)
+	}
+
+	// two at once
+	done1, err := rl.Allow("1.2.3.4", "tx_search")
+	require.Nil(t, err)
+	done2, err := rl.Allow("1.2.3.4", "tx_search")
+	require.Nil(t, err)
+	_, err = rl.Allow("1.2.3.4", "tx_search")
+	assert.NotNil(t, err)
+	done1()
+	done1() // once only
+	done3, err := rl.Allow("1.2.3.4", "tx_search")
+	require.Nil(t, err)
+	_, err = rl.Allow("1.2.3.4", "tx_search")
+	assert.NotNil(t, err)
+	done2()
+	done3()
+}
+
+func TestRateLimitHandler(t *testing.T) {
+	funcMap := map[string]*rs.RPCFunc{
+		"c": rs.NewRPCFunc(func(s string, i int) (string, error) { return "foo", nil }, "s,i"),
+		"d": rs.NewRPCFunc(func(s string, i int) (string, error) { return "bar", nil }, "s,i"),
+	}
+	mux := http.NewServeMux()
+	rs.RegisterRPCFuncs(mux, funcMap, log.NewNopLogger())
+	rl := rs.NewRateLimiter(rs.RateLimit{}, map[string]rs.RateLimit{"c": {Rate: 0.01}})
+	handler := rs.MaxBytesHandler(rs.RateLimitHandler(mux, rl), 100)
+
+	serve := func(method, url, body, remoteAddr string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// JSON-RPC
+	rec := serve("POST", "http://localhost/", `{"method": "c", "id": "0", "params": ["a", 1]}`, "1.2.3.4:5678")
+	assert.Equal(t, 200, rec.Code)
+	assert.Contains(t, rec.Body.String(), "foo", "the handler must still get the body")
+	rec = serve("POST", "http://localhost/", `{"method": "c", "id": "0", "params": ["a", 1]}`, "1.2.3.4:5679")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Contains(t, rec.Body.String(), "-32001")
+
+	// URI, from another client
+	rec = serve("GET", `http://localhost/c?s="a"&i=1`, "", "5.6.7.8:5678")
+	assert.Equal(t, 200, rec.Code)
+	rec = serve("GET", `http://localhost/c?s="a"&i=1`, "", "5.6.7.8:5678")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+	// whatever the body of a URI request claims
+	rec = serve("POST", `http://localhost/c?s="a"&i=1`, `{"method": "d", "id": "0", "params": ["a", 1]}`, "5.6.7.8:5678")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+	// each request of a batch counts
+	rec = serve("POST", "http://localhost/", `[{"method": "d", "id": "0", "params": ["a", 1]}]`, "1.2.3.4:5678")
+	assert.Equal(t, 200, rec.Code)
+	rec = serve("POST", "http://localhost/", `[{"method": "d", "id": "0", "params": ["a", 1]}, {"method": "c", "id": "1", "params": ["a", 1]}]`, "1.2.3.4:5678")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+	// the body is too big
+	rec = serve("POST", "http://localhost/", `{"method": "d", "id": "0", "params": ["`+strings.Repeat("a", 100)+`", 1]}`, "1.2.3.4:5678")
+	assert.Contains(t, rec.Body.String(), "too large")
+	assert.NotContains(t, rec.Body.String(), "bar")
+}
@@ -0,0 +1,176 @@
+package rpcserver
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	types "github.com/tendermint/tendermint/rpc/lib/types"
+)
+
+// UnsafeAuth authorizes the calls to the unsafe methods: only from the
+// loopback addresses and the unix sockets if localOnly, and only with one of
+// the tokens, if any, to the methods it allows. The other methods are not
+// gated.
+type UnsafeAuth struct {
+	methods   map[string]bool
+	localOnly bool
+	tokens    []authToken
+}
+
+type authToken struct {
+	token   []byte
+	methods map[string]bool // nil for all
+}
+
+// NewUnsafeAuth returns an UnsafeAuth of the unsafe methods, with the tokens
+// and the methods they allow, none for all, eg. from LoadAuthTokenFile, or nil
+// if neither localOnly nor any token, which authorizes everything.
+func NewUnsafeAuth(methods []string, localOnly bool, tokens map[string][]string) *UnsafeAuth {
+	if !localOnly && len(tokens) == 0 {
+		return nil
+	}
+	auth := &UnsafeAuth{
+		methods:   make(map[string]bool, len(methods)),
+		localOnly: localOnly,
+	}
+	for _, method := range methods {
+		auth.methods[method] = true
+	}
+	for token, tokenMethods := range tokens {
+		t := authToken{token: []byte(token)}
+		if len(tokenMethods) > 0 {
+			t.methods = make(map[string]bool, len(tokenMethods))
+			for _, method := range tokenMethods {
+				t.methods[method] = true
+			}
+		}
+		auth.tokens = append(auth.tokens, t)
+	}
+	return auth
+}
+
+// ParseAuthTokens parses the tokens, one per line, optionally followed by
+// the comma separated methods it allows, eg. "s3cr3t unsafe_flush_mempool".
+// The empty lines and the ones starting with # are skipped.
+func ParseAuthTokens(r io.Reader) (map[string][]string, error) {
+	tokens := make(map[string][]string)
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) > 2 {
+			return nil, errors.Errorf("Invalid auth token on line %d, must be token [method,method...]", line)
+		}
+		if _, ok := tokens[fields[0]]; ok {
+			return nil, errors.Errorf("Duplicate auth token on line %d", line)
+		}
+		var methods []string
+		if len(fields) == 2 {
+			for _, method := range strings.Split(fields[1], ",") {
+				if method != "" {
+					methods = append(methods, method)
+				}
+			}
+		}
+		tokens[fields[0]] = methods
+	}
+	return tokens, scanner.Err()
+}
+
+// LoadAuthTokenFile returns the tokens of the file, see ParseAuthTokens.
+func LoadAuthTokenFile(path string) (map[string][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error reading the auth tokens")
+	}
+	defer f.Close() // nolint: errcheck
+	return ParseAuthTokens(f)
+}
+
+// Authorize returns an error if the call of the client, with the token, to the
+// method is not authorized.
+func (auth *UnsafeAuth) Authorize(remoteAddr, token, method string) error {
+	if auth == nil || !auth.methods[method] {
+		return nil
+	}
+	if auth.localOnly && !isLocalAddr(remoteAddr) {
+		return errors.Errorf("%s is only served to the local clients", method)
+	}
+	if len(auth.tokens) == 0 {
+		return nil
+	}
+	if token == "" {
+		return errors.Errorf("%s needs an auth token", method)
+	}
+	for _, t := range auth.tokens {
+		// in constant time, not to leak how much of a token was guessed
+		if subtle.ConstantTimeCompare(t.token, []byte(token)) == 1 {
+			if t.methods != nil && !t.methods[method] {
+				return errors.Errorf("The auth token doesn't allow %s", method)
+			}
+			return nil
+		}
+	}
+	return errors.New("Invalid auth token")
+}
+
+// UnsafeAuthHandler wraps an RPC handler, answering the requests not
+// authorized by the UnsafeAuth with a 403 and a JSON-RPC error. All the
+// requests of a batch must be. The token is the one of the
+// "Authorization: Bearer <token>" header, which the websocket connections,
+// see Authorization, send once on connecting.
+func UnsafeAuthHandler(handler http.Handler, auth *UnsafeAuth) http.Handler {
+	if auth == nil {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods, err := requestMethods(r)
+		if err != nil {
+			WriteRPCResponseHTTP(w, types.RPCInvalidRequestError("", err))
+			return
+		}
+		token := requestAuthToken(r)
+		for _, method := range methods {
+			if err := auth.Authorize(r.RemoteAddr, token, method); err != nil {
+				WriteRPCResponseHTTPError(w, http.StatusForbidden, types.RPCUnauthorizedError("", err))
+				return
+			}
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// requestAuthToken returns the token of the Authorization header of the
+// request, if a bearer one.
+func requestAuthToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(header[7:])
+}
+
+// isLocalAddr returns whether the remote address is a loopback one, or not
+// an IP, eg. of a unix socket.
+func isLocalAddr(remoteAddr string) bool {
+	ip := net.ParseIP(clientIP(remoteAddr))
+	return ip == nil || ip.IsLoopback()
+}
+
+// Authorization authorizes the calls over the websocket connection with the
+// UnsafeAuth, with the token the connection was opened with.
+// It should only be used in the constructor - not Goroutine-safe.
+func Authorization(auth *UnsafeAuth) func(*wsConnection) {
+	return func(wsc *wsConnection) {
+		wsc.unsafeAuth = auth
+	}
+}
@@ -0,0 +1,123 @@
+package rpcserver_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	rs "github.com/tendermint/tendermint/rpc/lib/server"
+	"github.com/tendermint/tmlibs/log"
+)
+
+func TestParseAuthTokens(t *testing.T) {
+	tokens, err := rs.ParseAuthTokens(strings.NewReader(`
+# operators
+s3cr3t
+  flush  unsafe_flush_mempool,dial_seeds,
+`))
+	require.Nil(t, err)
+	assert.Equal(t, map[string][]string{
+		"s3cr3t": nil,
+		"flush":  {"unsafe_flush_mempool", "dial_seeds"},
+	}, tokens)
+
+	for _, s := range []string{"a b c", "a\na"} {
+		_, err := rs.ParseAuthTokens(strings.NewReader(s))
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestUnsafeAuth(t *testing.T) {
+	unsafe := []string{"unsafe_flush_mempool", "dial_seeds"}
+	assert.Nil(t, rs.NewUnsafeAuth(unsafe, false, nil))
+	var nilAuth *rs.UnsafeAuth
+	assert.Nil(t, nilAuth.Authorize("1.2.3.4:5678", "", "dial_seeds"))
+
+	// local only
+	auth := rs.NewUnsafeAuth(unsafe, true, nil)
+	assert.Nil(t, auth.Authorize("127.0.0.1:5678", "", "dial_seeds"))
+	assert.Nil(t, auth.Authorize("[::1]:5678", "", "dial_seeds"))
+	assert.Nil(t, auth.Authorize("@", "", "dial_seeds"), "unix socket")
+	assert.NotNil(t, auth.Authorize("1.2.3.4:5678", "", "dial_seeds"))
+	assert.Nil(t, auth.Authorize("1.2.3.4:5678", "", "status"), "not unsafe")
+
+	// with tokens
+	auth = rs.NewUnsafeAuth(unsafe, false, map[string][]string{
+		"s3cr3t": nil,
+		"flush":  {"unsafe_flush_mempool"},
+	})
+	assert.Nil(t, auth.Authorize("1.2.3.4:5678", "s3cr3t", "dial_seeds"))
+	assert.Nil(t, auth.Authorize("1.2.3.4:5678", "flush", "unsafe_flush_mempool"))
+	assert.NotNil(t, auth.Authorize("1.2.3.4:5678", "flush", "dial_seeds"), "not allowed by the token")
+	assert.NotNil(t, auth.Authorize("1.2.3.4:5678", "", "dial_seeds"))
+	assert.NotNil(t, auth.Authorize("1.2.3.4:5678", "s3cr3", "dial_seeds"))
+	assert.Nil(t, auth.Authorize("1.2.3.4:5678", "", "status"))
+
+	// both
+	auth = rs.NewUnsafeAuth(unsafe, true, map[string][]string{"s3cr3t": nil})
+	assert.Nil(t, auth.Authorize("127.0.0.1:5678", "s3cr3t", "dial_seeds"))
+	assert.NotNil(t, auth.Authorize("127.0.0.1:5678", "", "dial_seeds"))
+	assert.NotNil(t, auth.Authorize("1.2.3.4:5678", "s3cr3t", "dial_seeds"))
+}
+
+func TestUnsafeAuthHandler(t *testing.T) {
+	funcMap := map[string]*rs.RPCFunc{
+		"c":             rs.NewRPCFunc(func(s string, i int) (string, error) { return "foo", nil }, "s,i"),
+		"unsafe_method": rs.NewRPCFunc(func(s string, i int) (string, error) { return "bar", nil }, "s,i"),
+	}
+	mux := http.NewServeMux()
+	rs.RegisterRPCFuncs(mux, funcMap, log.NewNopLogger())
+	auth := rs.NewUnsafeAuth([]string{"unsafe_method"}, false, map[string][]string{"s3cr3t": nil})
+	handler := rs.UnsafeAuthHandler(mux, auth)
+
+	serve := func(method, url, body, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, strings.NewReader(body))
+		req.RemoteAddr = "1.2.3.4:5678"
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// JSON-RPC
+	rec := serve("POST", "http://localhost/", `{"method": "unsafe_method", "id": "0", "params": ["a", 1]}`, "s3cr3t")
+	assert.Equal(t, 200, rec.Code)
+	assert.Contains(t, rec.Body.String(), "bar", "the handler must still get the body")
+	rec = serve("POST", "http://localhost/", `{"method": "unsafe_method", "id": "0", "params": ["a", 1]}`, "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "-32002")
+	rec = serve("POST", "http://localhost/", `{"method": "c", "id": "0", "params": ["a", 1]}`, "")
+	assert.Equal(t, 200, rec.Code)
+
+	// URI
+	rec = serve("GET", `http://localhost/unsafe_method?s="a"&i=1`, "", "s3cr3t")
+	assert.Equal(t, 200, rec.Code)
+	rec = serve("GET", `http://localhost/unsafe_method?s="a"&i=1`, "", "wrong")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// the method is the one of the path, whatever the body claims
+	rec = serve("POST", "http://localhost/unsafe_method", `{"method": "c", "id": "0", "params": ["a", 1]}`, "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	// and the one of the body for "/", whatever the HTTP method
+	rec = serve("GET", "http://localhost/", `{"method": "unsafe_method", "id": "0", "params": ["a", 1]}`, "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	// the other paths are no JSON-RPC endpoint
+	rec = serve("POST", "http://localhost/other", `{"method": "unsafe_method", "id": "0", "params": ["a", 1]}`, "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "bar")
+	// nor is an unparseable body a call to no method
+	rec = serve("POST", "http://localhost/", `{"method": "unsafe_method", "id": "0", "params": ["a", 1]`, "")
+	assert.Contains(t, rec.Body.String(), "-32600")
+	assert.NotContains(t, rec.Body.String(), "bar")
+
+	// all the requests of a batch must be authorized
+	rec = serve("POST", "http://localhost/", `[{"method": "c", "id": "0", "params": ["a", 1]}, {"method": "unsafe_method", "id": "1", "params": ["a", 1]}]`, "")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "foo")
+}
@@ -0,0 +1,120 @@
+package rpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	crand "crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	client "github.com/tendermint/tendermint/rpc/lib/client"
+	server "github.com/tendermint/tendermint/rpc/lib/server"
+	"github.com/tendermint/tmlibs/log"
+)
+
+const tlsAddr = "tcp://127.0.0.1:47769"
+
+// writeCert writes the PEM files of a certificate for 127.0.0.1 signed by the
+// parent, or self-signed if nil, and of its key, and returns them.
+func writeCert(t *testing.T, dir, name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	require.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(crand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.Nil(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.Nil(t, err)
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return cert, key
+}
+
+func TestTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rpc_tls")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+	ca, caKey := writeCert(t, dir, "ca", nil, nil)
+	writeCert(t, dir, "server", ca, caKey)
+	writeCert(t, dir, "client", ca, caKey)
+	path := func(file string) string { return filepath.Join(dir, file) }
+
+	// mutual TLS
+	tlsConfig, err := server.NewServerTLSConfig(path("server.crt"), path("server.key"), path("ca.crt"))
+	require.Nil(t, err)
+	logger := log.TestingLogger()
+	mux := http.NewServeMux()
+	server.RegisterRPCFuncs(mux, Routes, logger)
+	wm := server.NewWebsocketManager(Routes)
+	wm.SetLogger(logger)
+	mux.HandleFunc(websocketEndpoint, wm.WebsocketHandler)
+	listener, err := server.StartHTTPAndTLSServer(tlsAddr, mux, tlsConfig, logger)
+	require.Nil(t, err)
+	defer listener.Close() // nolint: errcheck
+
+	clientTLS, err := client.NewClientTLSConfig(path("ca.crt"), path("client.crt"), path("client.key"))
+	require.Nil(t, err)
+	remote := "https://127.0.0.1:47769"
+
+	got, err := echoViaHTTP(client.NewJSONRPCClientWithTLS(remote, clientTLS), "json")
+	require.Nil(t, err)
+	assert.Equal(t, "json", got)
+	got, err = echoViaHTTP(client.NewURIClientWithTLS(remote, clientTLS), "uri")
+	require.Nil(t, err)
+	assert.Equal(t, "uri", got)
+
+	// wss://
+	ws := client.NewWSClient("wss://127.0.0.1:47769", websocketEndpoint, client.TLSConfig(clientTLS))
+	ws.SetLogger(logger)
+	require.Nil(t, ws.Start())
+	defer ws.Stop()
+	require.Nil(t, ws.Call(context.Background(), "echo_ws", map[string]interface{}{"arg": "ws"}))
+	msg := <-ws.ResponsesCh
+	require.Nil(t, msg.Error)
+	result := new(ResultEcho)
+	require.Nil(t, json.Unmarshal(msg.Result, result))
+	assert.Equal(t, "ws", result.Value)
+
+	// without a client certificate
+	noCert, err := client.NewClientTLSConfig(path("ca.crt"), "", "")
+	require.Nil(t, err)
+	_, err = echoViaHTTP(client.NewJSONRPCClientWithTLS(remote, noCert), "json")
+	assert.NotNil(t, err)
+
+	// nor in the clear
+	_, err = echoViaHTTP(client.NewJSONRPCClient(tlsAddr), "json")
+	assert.NotNil(t, err)
+
+	// nor trusting the server
+	_, err = echoViaHTTP(client.NewJSONRPCClient(remote), "json")
+	assert.NotNil(t, err)
+}
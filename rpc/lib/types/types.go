@@ -130,6 +130,14 @@ func RPCServerError(id string, err error) RPCResponse {
 	return NewRPCErrorResponse(id, -32000, "Server error", err.Error())
 }
 
+func RPCTooManyRequestsError(id string, err error) RPCResponse {
+	return NewRPCErrorResponse(id, -32001, "Too many requests", err.Error())
+}
+
+func RPCUnauthorizedError(id string, err error) RPCResponse {
+	return NewRPCErrorResponse(id, -32002, "Unauthorized", err.Error())
+}
+
 //----------------------------------------
 
 // *wsConnection implements this interface.
@@ -81,6 +81,7 @@ func GetConfig() *cfg.Config {
 		globalConfig.RPC.ListenAddress = rpc
 		globalConfig.RPC.GRPCListenAddress = grpc
 		globalConfig.TxIndex.IndexTags = "app.creator" // see dummy application
+		globalConfig.TxIndex.IndexBlocks = true
 	}
 	return globalConfig
 }
@@ -90,6 +91,11 @@ func GetGRPCClient() core_grpc.BroadcastAPIClient {
 	return core_grpc.StartGRPCClient(grpcAddr)
 }
 
+func GetGRPCCoreClient() core_grpc.CoreAPIClient {
+	grpcAddr := globalConfig.RPC.GRPCListenAddress
+	return core_grpc.StartGRPCCoreClient(grpcAddr)
+}
+
 // StartTendermint starts a test tendermint server in a go routine and returns when it is initialized
 func StartTendermint(app abci.Application) *nm.Node {
 	node := NewTendermint(app)
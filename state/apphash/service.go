@@ -0,0 +1,74 @@
+package apphash
+
+import (
+	"context"
+
+	cmn "github.com/tendermint/tmlibs/common"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	subscriber = "AppHashIndexer"
+)
+
+// IndexerService indexes the app hashes of the headers in the Store as the
+// blocks are committed. It catches up with the blocks committed while it
+// wasn't running.
+type IndexerService struct {
+	cmn.BaseService
+
+	store      *Store
+	blockStore types.BlockStoreRPC
+	eventBus   *types.EventBus
+}
+
+// NewIndexerService returns a service indexing the app hashes of the headers
+// of the blocks in the blockStore in the store.
+func NewIndexerService(store *Store, blockStore types.BlockStoreRPC, eventBus *types.EventBus) *IndexerService {
+	is := &IndexerService{
+		store:      store,
+		blockStore: blockStore,
+		eventBus:   eventBus,
+	}
+	is.BaseService = *cmn.NewBaseService(nil, "AppHashIndexer", is)
+	return is
+}
+
+// OnStart implements cmn.Service by subscribing to the new block headers,
+// and indexing the app hashes up to them.
+func (is *IndexerService) OnStart() error {
+	ch := make(chan interface{})
+	if err := is.eventBus.Subscribe(context.Background(), subscriber, types.EventQueryNewBlockHeader, ch); err != nil {
+		return err
+	}
+	go func() {
+		is.catchUp(is.blockStore.Height())
+		for event := range ch {
+			header := event.(types.TMEventData).Unwrap().(types.EventDataNewBlockHeader).Header
+			is.catchUp(header.Height)
+		}
+	}()
+	return nil
+}
+
+// OnStop implements cmn.Service by unsubscribing from the new block headers.
+func (is *IndexerService) OnStop() {
+	if is.eventBus.IsRunning() {
+		_ = is.eventBus.UnsubscribeAll(context.Background(), subscriber)
+	}
+}
+
+// catchUp indexes the app hashes of the headers up to the given height.
+func (is *IndexerService) catchUp(height int64) {
+	for h := is.store.LastHeight() + 1; h <= height; h++ {
+		blockMeta := is.blockStore.LoadBlockMeta(h)
+		if blockMeta == nil {
+			return
+		}
+		if err := is.store.Index(h, blockMeta.Header.AppHash); err != nil {
+			is.Logger.Error("Failed to index the app hash of a header", "height", h, "err", err)
+			return
+		}
+	}
+}
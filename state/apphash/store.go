@@ -0,0 +1,127 @@
+// Package apphash indexes the AppHash of the header of each height, in compact
+// windows of heights, so the bridges and the audit tools which only need the
+// app hashes don't load the full headers.
+package apphash
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	wire "github.com/tendermint/go-wire"
+	cmn "github.com/tendermint/tmlibs/common"
+	dbm "github.com/tendermint/tmlibs/db"
+)
+
+// WindowSize is the number of heights in each window of the index.
+const WindowSize = 1024
+
+var lastHeightKey = []byte("appHashLastHeight")
+
+// calcWindowKey returns the key of the window, the windows being sorted by height.
+func calcWindowKey(window int64) []byte {
+	return []byte(fmt.Sprintf("appHash:%016X", window))
+}
+
+// windowOf returns the window of the height, and the index of the height in it.
+func windowOf(height int64) (window int64, index int) {
+	return (height - 1) / WindowSize, int((height - 1) % WindowSize)
+}
+
+// appHashWindow is the app hashes of the heights of a window indexed so far.
+type appHashWindow struct {
+	AppHashes [][]byte
+}
+
+// Store is the index of the app hashes, in its own db.
+type Store struct {
+	db dbm.DB
+}
+
+// NewStore returns a Store on the db.
+func NewStore(db dbm.DB) *Store {
+	return &Store{db: db}
+}
+
+// LastHeight returns the last height indexed, 0 if none.
+func (s *Store) LastHeight() int64 {
+	buf := s.db.Get(lastHeightKey)
+	if len(buf) == 0 {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(buf))
+}
+
+// Index records the AppHash of the header of the height. The heights must be
+// indexed in order, the ones already indexed are skipped.
+func (s *Store) Index(height int64, appHash []byte) error {
+	lastHeight := s.LastHeight()
+	if height <= lastHeight {
+		return nil
+	}
+	if height != lastHeight+1 {
+		return fmt.Errorf("Height %d indexed after %d", height, lastHeight)
+	}
+
+	window, index := windowOf(height)
+	aw := s.loadWindow(window)
+	if aw == nil {
+		aw = &appHashWindow{}
+	}
+	aw.AppHashes = append(aw.AppHashes[:index], appHash)
+
+	heightBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(heightBytes, uint64(height))
+	batch := s.db.NewBatch()
+	batch.Set(calcWindowKey(window), wire.BinaryBytes(aw))
+	batch.Set(lastHeightKey, heightBytes)
+	batch.Write()
+	return nil
+}
+
+// AppHash returns the AppHash of the header of the height, nil if not indexed.
+func (s *Store) AppHash(height int64) []byte {
+	hashes := s.AppHashes(height, height)
+	if len(hashes) == 0 {
+		return nil
+	}
+	return hashes[0]
+}
+
+// AppHashes returns the app hashes of the headers of the heights from and to
+// included, among the heights indexed.
+func (s *Store) AppHashes(from, to int64) [][]byte {
+	if from <= 0 {
+		from = 1
+	}
+	if lastHeight := s.LastHeight(); to > lastHeight {
+		to = lastHeight
+	}
+
+	hashes := [][]byte{}
+	var aw *appHashWindow
+	loaded := int64(-1)
+	for height := from; height <= to; height++ {
+		window, index := windowOf(height)
+		if window != loaded {
+			aw, loaded = s.loadWindow(window), window
+		}
+		if aw == nil || index >= len(aw.AppHashes) {
+			break
+		}
+		hashes = append(hashes, aw.AppHashes[index])
+	}
+	return hashes
+}
+
+func (s *Store) loadWindow(window int64) *appHashWindow {
+	buf := s.db.Get(calcWindowKey(window))
+	if len(buf) == 0 {
+		return nil
+	}
+	aw := new(appHashWindow)
+	if err := wire.ReadBinaryBytes(buf, aw); err != nil {
+		// DATA HAS BEEN CORRUPTED OR THE SPEC HAS CHANGED
+		cmn.Exit(cmn.Fmt("LoadAppHashWindow: Data has been corrupted or its spec has changed: %v\n", err))
+	}
+	return aw
+}
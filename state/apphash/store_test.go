@@ -0,0 +1,52 @@
+package apphash
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	dbm "github.com/tendermint/tmlibs/db"
+)
+
+func appHashOf(height int64) []byte {
+	return []byte(fmt.Sprintf("app-hash-%d", height))
+}
+
+func TestAppHashes(t *testing.T) {
+	assert := assert.New(t)
+
+	store := NewStore(dbm.NewMemDB())
+	assert.Equal(int64(0), store.LastHeight())
+	assert.Nil(store.AppHash(1))
+
+	numHeights := int64(2*WindowSize + 10)
+	for height := int64(1); height <= numHeights; height++ {
+		assert.Nil(store.Index(height, appHashOf(height)))
+	}
+	assert.Equal(numHeights, store.LastHeight())
+
+	// the heights already indexed are skipped, the others must be in order
+	assert.Nil(store.Index(5, []byte("other")))
+	assert.Equal(appHashOf(5), store.AppHash(5))
+	assert.NotNil(store.Index(numHeights+2, []byte("gap")))
+
+	assert.Equal(appHashOf(1), store.AppHash(1))
+	assert.Equal(appHashOf(numHeights), store.AppHash(numHeights))
+	assert.Nil(store.AppHash(numHeights + 1))
+
+	// a range across two windows
+	hashes := store.AppHashes(WindowSize-1, WindowSize+1)
+	assert.Equal([][]byte{appHashOf(WindowSize - 1), appHashOf(WindowSize), appHashOf(WindowSize + 1)}, hashes)
+
+	// up to the last height indexed
+	hashes = store.AppHashes(numHeights-1, numHeights+100)
+	assert.Equal([][]byte{appHashOf(numHeights - 1), appHashOf(numHeights)}, hashes)
+	assert.Equal(0, len(store.AppHashes(numHeights+1, numHeights+100)))
+
+	// an empty app hash, eg. of the first height
+	store = NewStore(dbm.NewMemDB())
+	assert.Nil(store.Index(1, nil))
+	assert.Nil(store.Index(2, appHashOf(2)))
+	assert.Equal(0, len(store.AppHash(1)))
+	assert.Equal(appHashOf(2), store.AppHash(2))
+}
@@ -0,0 +1,35 @@
+package blockindex
+
+import (
+	abci "github.com/tendermint/abci/types"
+	"github.com/tendermint/tendermint/types/query"
+
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+)
+
+// BlockIndexer defines methods to index and search the blocks by their tags.
+type BlockIndexer interface {
+
+	// Index stores the tags of the block at the given height.
+	Index(height int64, tags []*abci.KVPair) error
+
+	// Search returns the heights of the blocks matching the query, in ascending order.
+	Search(q *query.Query) ([]int64, error)
+}
+
+// BlockTags returns the tags a block is indexed by: its height, its number of
+// txs and, if the ABCI responses of the block are given, the number of
+// validator updates returned by EndBlock, if any.
+// NOTE: the apps can't tag the blocks, as ResponseBeginBlock and
+// ResponseEndBlock have no tags in abci v0.9.0.
+func BlockTags(block *types.Block, abciResponses *sm.ABCIResponses) []*abci.KVPair {
+	tags := []*abci.KVPair{
+		abci.KVPairInt(types.BlockHeightKey, block.Height),
+		abci.KVPairInt(types.BlockNumTxsKey, int64(block.NumTxs)),
+	}
+	if abciResponses != nil && abciResponses.EndBlock != nil && len(abciResponses.EndBlock.ValidatorUpdates) > 0 {
+		tags = append(tags, abci.KVPairInt(types.BlockValidatorUpdatesKey, int64(len(abciResponses.EndBlock.ValidatorUpdates))))
+	}
+	return tags
+}
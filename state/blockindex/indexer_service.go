@@ -0,0 +1,65 @@
+package blockindex
+
+import (
+	"context"
+
+	cmn "github.com/tendermint/tmlibs/common"
+
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	subscriber = "BlockIndexer"
+)
+
+// ABCIResponsesLoader returns the ABCI responses of the block at a height,
+// eg. State.LoadABCIResponsesAt.
+type ABCIResponsesLoader func(height int64) (*sm.ABCIResponses, error)
+
+// IndexerService indexes the tags of the blocks as they are committed.
+type IndexerService struct {
+	cmn.BaseService
+
+	idr               BlockIndexer
+	loadABCIResponses ABCIResponsesLoader
+	eventBus          *types.EventBus
+}
+
+// NewIndexerService returns a service indexing the new blocks in idr, with the
+// ABCI responses loadABCIResponses returns.
+func NewIndexerService(idr BlockIndexer, loadABCIResponses ABCIResponsesLoader, eventBus *types.EventBus) *IndexerService {
+	is := &IndexerService{idr: idr, loadABCIResponses: loadABCIResponses, eventBus: eventBus}
+	is.BaseService = *cmn.NewBaseService(nil, "BlockIndexer", is)
+	return is
+}
+
+// OnStart implements cmn.Service by subscribing to the new blocks
+// and indexing them by tags.
+func (is *IndexerService) OnStart() error {
+	ch := make(chan interface{})
+	if err := is.eventBus.Subscribe(context.Background(), subscriber, types.EventQueryNewBlock, ch); err != nil {
+		return err
+	}
+	go func() {
+		for event := range ch {
+			block := event.(types.TMEventData).Unwrap().(types.EventDataNewBlock).Block
+			abciResponses, err := is.loadABCIResponses(block.Height)
+			if err != nil {
+				// still index the tags of the block itself
+				is.Logger.Error("Failed to load the ABCI responses of a block", "height", block.Height, "err", err)
+			}
+			if err := is.idr.Index(block.Height, BlockTags(block, abciResponses)); err != nil {
+				is.Logger.Error("Failed to index a block", "height", block.Height, "err", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// OnStop implements cmn.Service by unsubscribing from the new blocks.
+func (is *IndexerService) OnStop() {
+	if is.eventBus.IsRunning() {
+		_ = is.eventBus.UnsubscribeAll(context.Background(), subscriber)
+	}
+}
@@ -0,0 +1,193 @@
+package kv
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	abci "github.com/tendermint/abci/types"
+	"github.com/tendermint/tendermint/types/query"
+	db "github.com/tendermint/tmlibs/db"
+
+	"github.com/tendermint/tendermint/state/blockindex"
+)
+
+const (
+	tagKeySeparator = "/"
+)
+
+var _ blockindex.BlockIndexer = (*BlockIndex)(nil)
+
+// BlockIndex is a block indexer backed by key-value storage (levelDB),
+// storing a key "tag/value/height" for each tag of each block.
+type BlockIndex struct {
+	store db.DB
+}
+
+// NewBlockIndex creates a new KV block indexer.
+func NewBlockIndex(store db.DB) *BlockIndex {
+	return &BlockIndex{store: store}
+}
+
+// Index indexes the tags of the block at the given height.
+func (bi *BlockIndex) Index(height int64, tags []*abci.KVPair) error {
+	b := bi.store.NewBatch()
+	for _, tag := range tags {
+		key, err := keyForTag(tag, height)
+		if err != nil {
+			return err
+		}
+		b.Set(key, []byte(strconv.FormatInt(height, 10)))
+	}
+	b.Write()
+	return nil
+}
+
+// Search performs a search using the given query. Each condition is matched
+// against the values of its tag, and the heights matching all the conditions
+// of any of the conjunctions of the query are returned in ascending order.
+// NOTE: the conditions other than equality scan all the values of their tag.
+func (bi *BlockIndex) Search(q *query.Query) ([]int64, error) {
+	found := make(map[int64]bool)
+	for _, conditions := range q.Conjunctions() {
+		heights, err := bi.searchConditions(conditions)
+		if err != nil {
+			return nil, err
+		}
+		for h := range heights {
+			found[h] = true
+		}
+	}
+
+	results := make([]int64, 0, len(found))
+	for h := range found {
+		results = append(results, h)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i] < results[j] })
+	return results, nil
+}
+
+// searchConditions returns the heights of the blocks matching all the conditions.
+func (bi *BlockIndex) searchConditions(conditions []query.Condition) (map[int64]bool, error) {
+	var heights map[int64]bool
+	for _, c := range conditions {
+		matched, err := bi.match(c)
+		if err != nil {
+			return nil, err
+		}
+		if heights == nil {
+			heights = matched
+			continue
+		}
+		for h := range heights {
+			if !matched[h] {
+				delete(heights, h)
+			}
+		}
+	}
+	return heights, nil
+}
+
+// match returns the heights of the blocks whose tag matches the condition.
+func (bi *BlockIndex) match(c query.Condition) (map[int64]bool, error) {
+	prefix := c.Tag + tagKeySeparator
+	if c.Op == query.OpEqual {
+		prefix += fmt.Sprintf("%v", c.Operand) + tagKeySeparator
+	}
+
+	heights := make(map[int64]bool)
+	it := bi.store.IteratorPrefix([]byte(prefix))
+	defer it.Release()
+	for it.Next() {
+		value, height, err := parseKey(string(it.Key()), c.Tag)
+		if err != nil {
+			return nil, err
+		}
+		ok, err := matchValue(c, value)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			heights[height] = true
+		}
+	}
+	return heights, nil
+}
+
+// matchValue returns true if the indexed value of a tag satisfies the condition.
+func matchValue(c query.Condition, value string) (bool, error) {
+	if c.Op == query.OpExists {
+		return true, nil
+	}
+	switch operand := c.Operand.(type) {
+	case string:
+		switch c.Op {
+		case query.OpEqual:
+			return value == operand, nil
+		case query.OpContains:
+			return strings.Contains(value, operand), nil
+		}
+		return false, fmt.Errorf("Unsupported operator %v for the string %s", c.Op, c.Tag)
+	case int64:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false, nil // not a number
+		}
+		return compare(c.Op, float64(v), float64(operand))
+	case float64:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false, nil // not a number
+		}
+		return compare(c.Op, float64(v), operand)
+	default:
+		// XXX: passing time in a ABCI Tags is not yet implemented
+		return false, fmt.Errorf("Unsupported operand for %s: %v", c.Tag, c.Operand)
+	}
+}
+
+func compare(op query.Operator, v, operand float64) (bool, error) {
+	switch op {
+	case query.OpEqual:
+		return v == operand, nil
+	case query.OpLess:
+		return v < operand, nil
+	case query.OpLessEqual:
+		return v <= operand, nil
+	case query.OpGreater:
+		return v > operand, nil
+	case query.OpGreaterEqual:
+		return v >= operand, nil
+	}
+	return false, fmt.Errorf("Unsupported operator %v for a number", op)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Keys
+
+func keyForTag(tag *abci.KVPair, height int64) ([]byte, error) {
+	switch tag.ValueType {
+	case abci.KVPair_STRING:
+		return []byte(fmt.Sprintf("%s/%v/%d", tag.Key, tag.ValueString, height)), nil
+	case abci.KVPair_INT:
+		return []byte(fmt.Sprintf("%s/%v/%d", tag.Key, tag.ValueInt, height)), nil
+	default:
+		return nil, fmt.Errorf("Undefined value type: %v", tag.ValueType)
+	}
+}
+
+// parseKey returns the value and the height of the key of a tag, the value
+// being everything between the tag and the last separator.
+func parseKey(key, tag string) (string, int64, error) {
+	rest := strings.TrimPrefix(key, tag+tagKeySeparator)
+	i := strings.LastIndex(rest, tagKeySeparator)
+	if i < 0 {
+		return "", 0, fmt.Errorf("Malformed key %q", key)
+	}
+	height, err := strconv.ParseInt(rest[i+1:], 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("Malformed key %q: %v", key, err)
+	}
+	return rest[:i], height, nil
+}
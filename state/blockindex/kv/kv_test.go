@@ -0,0 +1,73 @@
+package kv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/abci/types"
+	"github.com/tendermint/tendermint/types/query"
+	db "github.com/tendermint/tmlibs/db"
+
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/state/blockindex"
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestBlockIndex(t *testing.T) {
+	indexer := NewBlockIndex(db.NewMemDB())
+
+	for h := int64(1); h <= 5; h++ {
+		block := &types.Block{Header: &types.Header{Height: h, NumTxs: int(h % 2)}}
+		tags := blockindex.BlockTags(block, nil)
+		if h == 4 {
+			tags = append(tags, abci.KVPairString("app.upgrade", "v2/beta"))
+		}
+		require.Nil(t, indexer.Index(h, tags))
+	}
+
+	testCases := []struct {
+		q       string
+		heights []int64
+	}{
+		{"block.height = 3", []int64{3}},
+		{"block.height > 2", []int64{3, 4, 5}},
+		{"block.height >= 2 AND block.height < 4", []int64{2, 3}},
+		{"block.num_txs = 1", []int64{1, 3, 5}},
+		{"block.num_txs = 1 AND block.height > 1", []int64{3, 5}},
+		{"block.height > 5", []int64{}},
+		{"app.upgrade = 'v2/beta'", []int64{4}},
+		{"app.upgrade CONTAINS 'v2'", []int64{4}},
+		{"app.upgrade = 'v2'", []int64{}},
+		{"app.missing = 'v2'", []int64{}},
+		{"app.upgrade EXISTS", []int64{4}},
+		{"app.missing EXISTS", []int64{}},
+		{"block.height = 1 OR app.upgrade EXISTS", []int64{1, 4}},
+		{"block.height < 3 OR block.height > 2", []int64{1, 2, 3, 4, 5}},
+		{"(block.height = 1 OR block.height = 4) AND block.num_txs = 0", []int64{4}},
+	}
+
+	for _, tc := range testCases {
+		heights, err := indexer.Search(query.MustParse(tc.q))
+		require.Nil(t, err, tc.q)
+		assert.Equal(t, tc.heights, heights, tc.q)
+	}
+
+	_, err := indexer.Search(query.MustParse("app.upgrade < DATE 2017-01-01"))
+	assert.NotNil(t, err)
+}
+
+func TestBlockTags(t *testing.T) {
+	block := &types.Block{Header: &types.Header{Height: 7, NumTxs: 1}}
+	assert.Equal(t, []*abci.KVPair{
+		abci.KVPairInt(types.BlockHeightKey, 7),
+		abci.KVPairInt(types.BlockNumTxsKey, 1),
+	}, blockindex.BlockTags(block, nil))
+
+	abciResponses := &sm.ABCIResponses{
+		EndBlock: &abci.ResponseEndBlock{ValidatorUpdates: []*abci.Validator{{PubKey: []byte("val"), Power: 10}}},
+	}
+	tags := blockindex.BlockTags(block, abciResponses)
+	require.Len(t, tags, 3)
+	assert.Equal(t, abci.KVPairInt(types.BlockValidatorUpdatesKey, 1), tags[2])
+}
@@ -37,6 +37,20 @@ type (
 	ErrNoValSetForHeight struct {
 		Height int64
 	}
+
+	ErrNoConsensusParamsForHeight struct {
+		Height int64
+	}
+
+	ErrNoABCIResponsesForHeight struct {
+		Height int64
+	}
+
+	ErrStateHashMismatch struct {
+		Height    int64
+		Persisted []byte
+		Computed  []byte
+	}
 )
 
 func (e ErrUnknownBlock) Error() string {
@@ -61,3 +75,15 @@ func (e ErrStateMismatch) Error() string {
 func (e ErrNoValSetForHeight) Error() string {
 	return cmn.Fmt("Could not find validator set for height #%d", e.Height)
 }
+
+func (e ErrNoConsensusParamsForHeight) Error() string {
+	return cmn.Fmt("Could not find consensus params for height #%d", e.Height)
+}
+
+func (e ErrNoABCIResponsesForHeight) Error() string {
+	return cmn.Fmt("Could not find ABCI responses for height #%d", e.Height)
+}
+
+func (e ErrStateHashMismatch) Error() string {
+	return cmn.Fmt("Persisted state hash (%X) does not match the one computed from the block store (%X) for height %d", e.Persisted, e.Computed, e.Height)
+}
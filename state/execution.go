@@ -1,8 +1,10 @@
 package state
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"time"
 
 	fail "github.com/ebuchman/fail-test"
 	abci "github.com/tendermint/abci/types"
@@ -19,9 +21,10 @@ import (
 // ValExecBlock executes the block, but does NOT mutate State.
 // + validates the block
 // + executes block.Txs on the proxyAppConn
-func (s *State) ValExecBlock(txEventPublisher types.TxEventPublisher, proxyAppConn proxy.AppConnConsensus, block *types.Block) (*ABCIResponses, error) {
+func (s *State) ValExecBlock(txEventPublisher types.TxEventPublisher, proxyAppConn proxy.AppConnConsensus,
+	block *types.Block, evpool types.EvidencePool) (*ABCIResponses, error) {
 	// Validate the block.
-	if err := s.validateBlock(block); err != nil {
+	if err := s.validateBlock(block, evpool); err != nil {
 		return nil, ErrInvalidBlock(err)
 	}
 
@@ -32,6 +35,8 @@ func (s *State) ValExecBlock(txEventPublisher types.TxEventPublisher, proxyAppCo
 		// TODO Report error and wait for proxyApp to be available.
 		return nil, ErrProxyAppConn(err)
 	}
+	// the params updated by the app, applied from the next height
+	abciResponses.ParamsUpdate = s.Params.UpdateFromABCI(abciResponses.EndBlock.ConsensusParamUpdates)
 
 	return abciResponses, nil
 }
@@ -78,10 +83,10 @@ func execBlockOnProxyApp(txEventPublisher types.TxEventPublisher, proxyAppConn p
 
 	// Begin block
 	_, err := proxyAppConn.BeginBlockSync(abci.RequestBeginBlock{
-		block.Hash(),
-		types.TM2PB.Header(block.Header),
-		nil,
-		nil,
+		Hash:                block.Hash(),
+		Header:              types.TM2PB.Header(block.Header),
+		AbsentValidators:    absentValidators(block),
+		ByzantineValidators: byzantineValidators(block),
 	})
 	if err != nil {
 		logger.Error("Error in proxyAppConn.BeginBlock", "err", err)
@@ -103,7 +108,7 @@ func execBlockOnProxyApp(txEventPublisher types.TxEventPublisher, proxyAppConn p
 		return nil, err
 	}
 
-	valDiff := abciResponses.EndBlock.Diffs
+	valDiff := abciResponses.EndBlock.ValidatorUpdates
 
 	logger.Info("Executed block", "height", block.Height, "validTxs", validTxs, "invalidTxs", invalidTxs)
 	if len(valDiff) > 0 {
@@ -113,6 +118,34 @@ func execBlockOnProxyApp(txEventPublisher types.TxEventPublisher, proxyAppConn p
 	return abciResponses, nil
 }
 
+// byzantineValidators returns the validators the evidence in the block is against,
+// for the app to punish them.
+func byzantineValidators(block *types.Block) []*abci.Evidence {
+	byzantineVals := make([]*abci.Evidence, len(block.Evidence.Evidence))
+	for i, ev := range block.Evidence.Evidence {
+		byzantineVals[i] = &abci.Evidence{
+			PubKey: ev.PubKey().Bytes(),
+			Height: ev.Height(),
+		}
+	}
+	return byzantineVals
+}
+
+// absentValidators returns the indexes, in the validator set of the previous block,
+// of the validators whose precommit is missing from the LastCommit of the block.
+func absentValidators(block *types.Block) []int32 {
+	absentVals := make([]int32, 0)
+	if block.LastCommit == nil {
+		return absentVals
+	}
+	for valI, vote := range block.LastCommit.Precommits {
+		if vote == nil {
+			absentVals = append(absentVals, int32(valI))
+		}
+	}
+	return absentVals
+}
+
 func updateValidators(validators *types.ValidatorSet, changedValidators []*abci.Validator) error {
 	// TODO: prevent change of 1/3+ at once
 
@@ -132,7 +165,11 @@ func updateValidators(validators *types.ValidatorSet, changedValidators []*abci.
 		_, val := validators.GetByAddress(address)
 		if val == nil {
 			// add val
-			added := validators.Add(types.NewValidator(pubkey, power))
+			newVal := types.NewValidator(pubkey, power)
+			if err := newVal.ValidateBasic(); err != nil {
+				return err
+			}
+			added := validators.Add(newVal)
 			if !added {
 				return errors.New(cmn.Fmt("Failed to add new validator %X with voting power %d", address, power))
 			}
@@ -154,6 +191,41 @@ func updateValidators(validators *types.ValidatorSet, changedValidators []*abci.
 	return nil
 }
 
+// validatorsDiff returns the updates turning prev into next, as EndBlock would return them.
+func validatorsDiff(prev, next *types.ValidatorSet) []*abci.Validator {
+	diff := []*abci.Validator{}
+	next.Iterate(func(_ int, val *types.Validator) bool {
+		_, prevVal := prev.GetByAddress(val.Address)
+		if prevVal == nil || prevVal.VotingPower != val.VotingPower {
+			diff = append(diff, &abci.Validator{PubKey: val.PubKey.Bytes(), Power: val.VotingPower})
+		}
+		return false
+	})
+	prev.Iterate(func(_ int, val *types.Validator) bool {
+		if !next.HasAddress(val.Address) {
+			diff = append(diff, &abci.Validator{PubKey: val.PubKey.Bytes(), Power: 0})
+		}
+		return false
+	})
+	return diff
+}
+
+// applyValidatorsDiff returns the set following prev, after the given number of
+// blocks without changes and one with the diff, as SetBlockAndValidators does.
+// NOTE: the accums are incremented once per block, IncrementAccum(n) doesn't
+// pick the same proposers as n calls to IncrementAccum(1).
+func applyValidatorsDiff(prev *types.ValidatorSet, blocks int64, diff []*abci.Validator) (*types.ValidatorSet, error) {
+	valSet := prev.Copy()
+	for i := int64(0); i < blocks; i++ {
+		valSet.IncrementAccum(1)
+	}
+	if err := updateValidators(valSet, diff); err != nil {
+		return nil, err
+	}
+	valSet.IncrementAccum(1)
+	return valSet, nil
+}
+
 // return a bit array of validators that signed the last commit
 // NOTE: assumes commits have already been authenticated
 /* function is currently unused
@@ -171,12 +243,13 @@ func commitBitArrayFromBlock(block *types.Block) *cmn.BitArray {
 //-----------------------------------------------------
 // Validate block
 
-// ValidateBlock validates the block against the state.
-func (s *State) ValidateBlock(block *types.Block) error {
-	return s.validateBlock(block)
+// ValidateBlock validates the block against the state, and its evidence
+// against the evidence already committed in the pool.
+func (s *State) ValidateBlock(block *types.Block, evpool types.EvidencePool) error {
+	return s.validateBlock(block, evpool)
 }
 
-func (s *State) validateBlock(block *types.Block) error {
+func (s *State) validateBlock(block *types.Block, evpool types.EvidencePool) error {
 	// Basic block validation.
 	err := block.ValidateBasic(s.ChainID, s.LastBlockHeight, s.LastBlockID, s.LastBlockTime, s.AppHash)
 	if err != nil {
@@ -198,11 +271,196 @@ func (s *State) validateBlock(block *types.Block) error {
 		if err != nil {
 			return err
 		}
+		if block.LastCommit.IsAggregated() && !s.Params.CommitParams.AggregateSignatures {
+			return errors.New("Block LastCommit signatures can't be aggregated, CommitParams.AggregateSignatures is off")
+		}
+	}
+
+	// Validate the time of the block, if it's set by the validators
+	if s.Params.BlockTimeParams.BFTTime {
+		if err := s.validateBlockTime(block); err != nil {
+			return err
+		}
+	}
+
+	// Validate the commitment to the erasure coded txs, if any
+	if err := s.validateDataAvailability(block); err != nil {
+		return err
+	}
+
+	// Validate the results of the previous block.
+	if !bytes.Equal(block.LastResultsHash, s.LastResultsHash) {
+		return errors.New(cmn.Fmt("Wrong Block.Header.LastResultsHash.  Expected %X, got %v",
+			s.LastResultsHash, block.LastResultsHash))
+	}
+
+	if maxEvidence := s.Params.EvidenceParams.MaxNum; maxEvidence > 0 && len(block.Evidence.Evidence) > maxEvidence {
+		return errors.New(cmn.Fmt("Too much evidence in the block. Expected at most %v, got %v",
+			maxEvidence, len(block.Evidence.Evidence)))
+	}
+	for _, ev := range block.Evidence.Evidence {
+		if err := s.VerifyEvidence(ev); err != nil {
+			return types.NewEvidenceInvalidErr(ev, err)
+		}
+		// a validator is only punished once for the same evidence
+		if evpool.IsCommitted(ev) {
+			return types.NewEvidenceInvalidErr(ev, errors.New("Evidence was already committed"))
+		}
+	}
+
+	return nil
+}
+
+// VerifyEvidence verifies the evidence fully by checking it is internally
+// consistent and the validator was in the validator set at its height.
+// NOTE: the validator sets are loaded from the db, so the evidence can't be
+// from a height after the next block.
+func (s *State) VerifyEvidence(evidence types.Evidence) error {
+	height := evidence.Height()
+	if height > s.LastBlockHeight+1 {
+		return fmt.Errorf("Evidence from height %d is after the next block %d", height, s.LastBlockHeight+1)
+	}
+
+	if err := evidence.Verify(s.ChainID); err != nil {
+		return err
+	}
+
+	valset, err := s.LoadValidators(height)
+	if err != nil {
+		// TODO: if err is just that we cant find it cuz we pruned, ignore.
+		// TODO: if its actually bad evidence, punish peer
+		return err
+	}
+
+	// The address must have been an active validator at the height
+	addr, idx := evidence.Address(), evidence.Index()
+	valIdx, val := valset.GetByAddress(addr)
+	if val == nil {
+		return fmt.Errorf("Address %X was not a validator at height %d", addr, height)
+	} else if idx != valIdx {
+		return fmt.Errorf("Address %X was validator %d at height %d, not %d", addr, valIdx, height, idx)
+	}
+	if !val.PubKey.Equals(evidence.PubKey()) {
+		return fmt.Errorf("Address %X had another pub key at height %d", addr, height)
+	}
+
+	// The header field must really be invalid
+	if ev, ok := evidence.(*types.LunaticValidatorEvidence); ok {
+		expected, err := s.expectedHeaderField(height, ev.InvalidHeaderField, valset)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(expected, ev.InvalidHeaderValue()) {
+			return fmt.Errorf("Header field %s at height %d is valid", ev.InvalidHeaderField, height)
+		}
+	}
+
+	// Only the proposer of the round signs proposals
+	if ev, ok := evidence.(*types.ConflictingProposalsEvidence); ok {
+		roundValset := valset.Copy()
+		roundValset.IncrementAccum(ev.Round())
+		if proposer := roundValset.GetProposer(); !bytes.Equal(proposer.Address, addr) {
+			return fmt.Errorf("Address %X was not the proposer of round %d at height %d", addr, ev.Round(), height)
+		}
+	}
+
+	return nil
+}
+
+// InvalidHeaderField returns the first field of the header for the next block
+// that all the valid blocks agree on and this one doesn't, "" if none.
+// A vote for the block is evidence of a lunatic validator.
+func (s *State) InvalidHeaderField(header *types.Header) string {
+	if header.Height != s.LastBlockHeight+1 {
+		return ""
+	}
+	if !bytes.Equal(header.ValidatorsHash, s.Validators.Hash()) {
+		return types.LunaticFieldValidatorsHash
+	}
+	if !bytes.Equal(header.AppHash, s.AppHash) {
+		return types.LunaticFieldAppHash
+	}
+	if !bytes.Equal(header.LastResultsHash, s.LastResultsHash) {
+		return types.LunaticFieldLastResultsHash
+	}
+	return ""
+}
+
+// expectedHeaderField returns the value of the field in the valid headers at the height,
+// only from the state, so that all the nodes verify the evidence alike: the validators
+// hash of any height, from the validator sets of the state db, but the app and results
+// hashes of the next block only, as the blocks a node has of the past heights depend
+// on how it synced and pruned them.
+func (s *State) expectedHeaderField(height int64, field string, valset *types.ValidatorSet) ([]byte, error) {
+	if field == types.LunaticFieldValidatorsHash {
+		return valset.Hash(), nil
+	}
+	if height != s.LastBlockHeight+1 {
+		return nil, fmt.Errorf("Header field %s can only be verified for the next block %d, not at height %d",
+			field, s.LastBlockHeight+1, height)
 	}
 
+	switch field {
+	case types.LunaticFieldAppHash:
+		return s.AppHash, nil
+	case types.LunaticFieldLastResultsHash:
+		return s.LastResultsHash, nil
+	default:
+		return nil, fmt.Errorf("Unknown header field %q", field)
+	}
+}
+
+// validateBlockTime checks the time of the block is the median time of its LastCommit,
+// or the genesis time for the first block.
+func (s *State) validateBlockTime(block *types.Block) error {
+	expected := s.BlockTime(block.Height, block.LastCommit)
+	if !block.Time.Equal(expected) {
+		return errors.New(cmn.Fmt("Wrong Block.Header.Time. Expected %v, got %v", expected, block.Time))
+	}
+	if block.Height > 1 && !block.Time.After(s.LastBlockTime) {
+		return errors.New(cmn.Fmt("Block.Header.Time %v is not after the last block time %v",
+			block.Time, s.LastBlockTime))
+	}
 	return nil
 }
 
+// validateDataAvailability checks the DataAvailabilityHeader of the block is the
+// one of the shares of its txs, or empty without DataAvailabilityParams.Scheme.
+func (s *State) validateDataAvailability(block *types.Block) error {
+	scheme := s.Params.DataAvailabilityParams.NewScheme()
+	if scheme == nil {
+		if !block.DataAvailabilityHeader.IsZero() {
+			return errors.New("Block.Header.DataAvailabilityHeader must be empty, DataAvailabilityParams.Scheme is off")
+		}
+		return nil
+	}
+	shares, err := block.MakeDataAvailabilityShares(scheme)
+	if err != nil {
+		return err
+	}
+	if !shares.HasHeader(block.DataAvailabilityHeader) {
+		return errors.New(cmn.Fmt("Wrong Block.Header.DataAvailabilityHeader. Expected %v, got %v",
+			shares.Header(), block.DataAvailabilityHeader))
+	}
+	return nil
+}
+
+// BlockTime returns the time of the block at the given height, with the given LastCommit,
+// when ConsensusParams.BlockTimeParams.BFTTime is set.
+func (s *State) BlockTime(height int64, lastCommit *types.Commit) time.Time {
+	if height == 1 {
+		// the genesis time
+		return s.LastBlockTime
+	}
+	return types.MedianTime(lastCommit, s.LastValidators)
+}
+
+//-----------------------------------------------------------------------------
+
+// BlockCommittedFunc is called once a block is committed and the state saved,
+// with a copy of the state after the block, eg. to notify the apps embedding a node.
+type BlockCommittedFunc func(block *types.Block, state *State)
+
 //-----------------------------------------------------------------------------
 // ApplyBlock validates & executes the block, updates state w/ ABCI responses,
 // then commits and updates the mempool atomically, then saves state.
@@ -211,9 +469,9 @@ func (s *State) validateBlock(block *types.Block) error {
 // commits it, and saves the block and state. It's the only function that needs to be called
 // from outside this package to process and commit an entire block.
 func (s *State) ApplyBlock(txEventPublisher types.TxEventPublisher, proxyAppConn proxy.AppConnConsensus,
-	block *types.Block, partsHeader types.PartSetHeader, mempool types.Mempool) error {
+	block *types.Block, partsHeader types.PartSetHeader, mempool types.Mempool, evpool types.EvidencePool) error {
 
-	abciResponses, err := s.ValExecBlock(txEventPublisher, proxyAppConn, block)
+	abciResponses, err := s.ValExecBlock(txEventPublisher, proxyAppConn, block, evpool)
 	if err != nil {
 		return fmt.Errorf("Exec failed for application: %v", err)
 	}
@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/tendermint/abci/example/dummy"
+	abci "github.com/tendermint/abci/types"
 	crypto "github.com/tendermint/go-crypto"
 	"github.com/tendermint/tendermint/proxy"
 	"github.com/tendermint/tendermint/types"
@@ -33,13 +34,77 @@ func TestApplyBlock(t *testing.T) {
 	// make block
 	block := makeBlock(1, state)
 
-	err = state.ApplyBlock(types.NopEventBus{}, proxyApp.Consensus(), block, block.MakePartSet(testPartSize).Header(), types.MockMempool{})
+	err = state.ApplyBlock(types.NopEventBus{}, proxyApp.Consensus(), block, block.MakePartSet(testPartSize).Header(), types.MockMempool{}, types.MockEvidencePool{})
 
 	require.Nil(t, err)
 
 	// TODO check state and mempool
 }
 
+// beginBlockApp records the BeginBlock requests
+type beginBlockApp struct {
+	abci.BaseApplication
+	requests []abci.RequestBeginBlock
+}
+
+func (app *beginBlockApp) BeginBlock(req abci.RequestBeginBlock) abci.ResponseBeginBlock {
+	app.requests = append(app.requests, req)
+	return abci.ResponseBeginBlock{}
+}
+
+func TestBeginBlockAbsentValidators(t *testing.T) {
+	app := &beginBlockApp{}
+	proxyApp := proxy.NewAppConns(proxy.NewLocalClientCreator(app), nil)
+	err := proxyApp.Start()
+	require.Nil(t, err)
+	defer proxyApp.Stop()
+
+	state := state()
+	block := makeBlock(2, state)
+	block.LastCommit = &types.Commit{Precommits: []*types.Vote{{}, nil, {}, nil}}
+
+	_, err = execBlockOnProxyApp(types.NopEventBus{}, proxyApp.Consensus(), block, log.TestingLogger())
+	require.Nil(t, err)
+	require.Len(t, app.requests, 1)
+	require.Equal(t, []int32{1, 3}, app.requests[0].AbsentValidators)
+	require.Equal(t, []byte(block.Hash()), app.requests[0].Hash)
+}
+
+// paramsApp updates the consensus params in EndBlock
+type paramsApp struct {
+	abci.BaseApplication
+	params *abci.ConsensusParams
+}
+
+func (app *paramsApp) EndBlock(req abci.RequestEndBlock) abci.ResponseEndBlock {
+	return abci.ResponseEndBlock{ConsensusParamUpdates: app.params}
+}
+
+func TestApplyBlockParamsUpdate(t *testing.T) {
+	app := &paramsApp{params: &abci.ConsensusParams{BlockSize: &abci.BlockSize{MaxTxs: 10}}}
+	proxyApp := proxy.NewAppConns(proxy.NewLocalClientCreator(app), nil)
+	err := proxyApp.Start()
+	require.Nil(t, err)
+	defer proxyApp.Stop()
+
+	state := state()
+	state.SetLogger(log.TestingLogger())
+	maxBytes := state.Params.BlockSizeParams.MaxBytes
+
+	block := makeBlock(1, state)
+	err = state.ApplyBlock(types.NopEventBus{}, proxyApp.Consensus(), block, block.MakePartSet(testPartSize).Header(), types.MockMempool{}, types.MockEvidencePool{})
+	require.Nil(t, err)
+
+	// the update applies from the next height, and is saved with the responses
+	require.Equal(t, 10, state.Params.BlockSizeParams.MaxTxs)
+	require.Equal(t, maxBytes, state.Params.BlockSizeParams.MaxBytes)
+	require.EqualValues(t, 2, state.LastHeightConsensusParamsChanged)
+	params, err := state.LoadConsensusParams(2)
+	require.Nil(t, err)
+	require.Equal(t, state.Params, params)
+	require.Equal(t, 10, state.LoadABCIResponses().ParamsUpdate.BlockSizeParams.MaxTxs)
+}
+
 //----------------------------------------------------------------------------
 
 // make some bogus txs
@@ -67,6 +132,6 @@ func makeBlock(height int64, state *State) *types.Block {
 	valHash := state.Validators.Hash()
 	prevBlockID := types.BlockID{prevHash, prevParts}
 	block, _ := types.MakeBlock(height, chainID, makeTxs(height), new(types.Commit),
-		prevBlockID, valHash, state.AppHash, testPartSize)
+		prevBlockID, valHash, state.AppHash, state.LastResultsHash, testPartSize)
 	return block
 }
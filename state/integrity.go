@@ -0,0 +1,277 @@
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	metrics "github.com/rcrowley/go-metrics"
+	cmn "github.com/tendermint/tmlibs/common"
+	dbm "github.com/tendermint/tmlibs/db"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// The records of the state db cross-checked against the blocks.
+const (
+	IntegrityFieldBlockMeta       = "BlockMeta"
+	IntegrityFieldValidatorsHash  = "ValidatorsHash"
+	IntegrityFieldLastResultsHash = "LastResultsHash"
+	IntegrityFieldConsensusParams = "ConsensusParams"
+)
+
+// maxIntegrityDiscrepancies is the max number of discrepancies kept in a report.
+const maxIntegrityDiscrepancies = 100
+
+// IntegrityDiscrepancy is a record of the state db that doesn't match
+// the block at a height, or is missing.
+type IntegrityDiscrepancy struct {
+	Height int64  `json:"height"`
+	Field  string `json:"field"`
+	Error  string `json:"error"`
+}
+
+// IntegrityReport is the result of cross-checking the blocks of a range of heights.
+// Only the first maxIntegrityDiscrepancies discrepancies are kept.
+type IntegrityReport struct {
+	From             int64                  `json:"from"`
+	To               int64                  `json:"to"`
+	CheckedHeights   int64                  `json:"checked_heights"`
+	NumDiscrepancies int                    `json:"num_discrepancies"`
+	Discrepancies    []IntegrityDiscrepancy `json:"discrepancies"`
+	Time             time.Time              `json:"time"`
+}
+
+func (r *IntegrityReport) add(discrepancy IntegrityDiscrepancy) {
+	r.NumDiscrepancies++
+	if len(r.Discrepancies) < maxIntegrityDiscrepancies {
+		r.Discrepancies = append(r.Discrepancies, discrepancy)
+	}
+}
+
+// merge adds the checks of the next range of heights to the report.
+func (r *IntegrityReport) merge(next *IntegrityReport) {
+	if r.CheckedHeights == 0 {
+		r.From = next.From
+	}
+	r.To = next.To
+	r.CheckedHeights += next.CheckedHeights
+	r.Time = next.Time
+	for _, discrepancy := range next.Discrepancies {
+		r.add(discrepancy)
+	}
+	// the ones dropped from next
+	r.NumDiscrepancies += next.NumDiscrepancies - len(next.Discrepancies)
+}
+
+// CheckIntegrity cross-checks the blocks in [from, to] of the block store against
+// the records of the state db for their heights, to catch a silent divergence
+// between them, eg. after restoring only one of them from a backup:
+//
+//  - the validator set, against the ValidatorsHash of the block
+//  - the consensus params, which must be valid, and the block cut in parts of their size
+//  - the ABCI responses of the block, against the LastResultsHash of the next one,
+//    unless they were pruned
+//
+// to is the last height of the block store if it's 0 or past it, and from its
+// base if below it, the blocks before being pruned.
+func CheckIntegrity(db dbm.DB, blockStore types.BlockStoreRPC, from, to int64) *IntegrityReport {
+	// the blocks before the base are pruned
+	if base := blockStore.Base(); from < base {
+		from = base
+	}
+	if from < 1 {
+		from = 1
+	}
+	if storeHeight := blockStore.Height(); to <= 0 || to > storeHeight {
+		to = storeHeight
+	}
+	report := &IntegrityReport{From: from, To: to, Time: time.Now()}
+
+	// only the db is used, to load the records of past heights
+	s := &State{db: db}
+	resultsBase := LoadABCIResponsesBase(db)
+	for height := from; height <= to; height++ {
+		for _, discrepancy := range s.checkIntegrityAt(blockStore, height, resultsBase) {
+			report.add(discrepancy)
+		}
+		report.CheckedHeights++
+	}
+	return report
+}
+
+// CheckIntegrity cross-checks the blocks in [from, to] against the state db,
+// see CheckIntegrity.
+func (s *State) CheckIntegrity(blockStore types.BlockStoreRPC, from, to int64) *IntegrityReport {
+	return CheckIntegrity(s.db, blockStore, from, to)
+}
+
+func (s *State) checkIntegrityAt(blockStore types.BlockStoreRPC, height, resultsBase int64) []IntegrityDiscrepancy {
+	var discrepancies []IntegrityDiscrepancy
+	addf := func(field, format string, args ...interface{}) {
+		discrepancies = append(discrepancies, IntegrityDiscrepancy{height, field, fmt.Sprintf(format, args...)})
+	}
+
+	meta := blockStore.LoadBlockMeta(height)
+	if meta == nil {
+		addf(IntegrityFieldBlockMeta, "Missing block meta")
+		return discrepancies
+	}
+	header := meta.Header
+
+	if valSet, err := s.LoadValidators(height); err != nil {
+		addf(IntegrityFieldValidatorsHash, "%v", err)
+	} else if hash := valSet.Hash(); !bytes.Equal(hash, header.ValidatorsHash) {
+		addf(IntegrityFieldValidatorsHash, "Block has %X, state db has %X", header.ValidatorsHash, hash)
+	}
+
+	params, err := loadConsensusParams(s.db, height)
+	if err != nil {
+		addf(IntegrityFieldConsensusParams, "%v", err)
+		return discrepancies
+	}
+	if err := params.Validate(); err != nil {
+		addf(IntegrityFieldConsensusParams, "Invalid consensus params: %v", err)
+	}
+	// all the parts but the last one have the part size of the params
+	if meta.BlockID.PartsHeader.Total > 1 {
+		part := blockStore.LoadBlockPart(height, 0)
+		if part != nil && len(part.Bytes) != params.BlockGossipParams.BlockPartSizeBytes {
+			addf(IntegrityFieldConsensusParams, "Block has parts of %d bytes, state db has block_part_size_bytes %d",
+				len(part.Bytes), params.BlockGossipParams.BlockPartSizeBytes)
+		}
+	}
+
+	// the results of the block are committed to by the next one
+	if height < resultsBase {
+		return discrepancies
+	}
+	nextMeta := blockStore.LoadBlockMeta(height + 1)
+	if nextMeta == nil {
+		return discrepancies
+	}
+	abciResponses, err := s.LoadABCIResponsesAt(height)
+	if err != nil {
+		// not saved by this version yet
+		return discrepancies
+	}
+	hash := abciResponses.ResultsHash(params.ResultsParams.HashVersion)
+	if !bytes.Equal(hash, nextMeta.Header.LastResultsHash) {
+		addf(IntegrityFieldLastResultsHash, "Block %d has %X, state db has %X", height+1, nextMeta.Header.LastResultsHash, hash)
+	}
+	return discrepancies
+}
+
+//-----------------------------------------------------------------------------
+
+// IntegrityMetrics exports the results of the IntegrityChecker to a go-metrics registry:
+//
+//	state.integrity.checked_height   last height cross-checked (gauge)
+//	state.integrity.discrepancies    discrepancies found since the start
+type IntegrityMetrics struct {
+	CheckedHeight metrics.Gauge
+	Discrepancies metrics.Counter
+}
+
+// NewIntegrityMetrics returns IntegrityMetrics registered into the given registry.
+// If registry is nil, metrics.DefaultRegistry is used.
+func NewIntegrityMetrics(registry metrics.Registry) *IntegrityMetrics {
+	if registry == nil {
+		registry = metrics.DefaultRegistry
+	}
+	return &IntegrityMetrics{
+		CheckedHeight: metrics.GetOrRegisterGauge("state.integrity.checked_height", registry),
+		Discrepancies: metrics.GetOrRegisterCounter("state.integrity.discrepancies", registry),
+	}
+}
+
+// IntegrityChecker cross-checks the blocks against the state db in the background
+// (see CheckIntegrity): all of them on start, and then the new ones every interval.
+// The discrepancies are logged, counted in the metrics, and kept in the Report.
+type IntegrityChecker struct {
+	cmn.BaseService
+
+	db         dbm.DB
+	blockStore types.BlockStoreRPC
+	interval   time.Duration
+	metrics    *IntegrityMetrics
+
+	mtx    sync.Mutex
+	report *IntegrityReport
+}
+
+// NewIntegrityChecker returns a checker of the blocks of the blockStore
+// against the state db, checking the new ones every interval.
+func NewIntegrityChecker(db dbm.DB, blockStore types.BlockStoreRPC, interval time.Duration) *IntegrityChecker {
+	ic := &IntegrityChecker{
+		db:         db,
+		blockStore: blockStore,
+		interval:   interval,
+		metrics:    NewIntegrityMetrics(metrics.NewRegistry()),
+		report:     &IntegrityReport{},
+	}
+	ic.BaseService = *cmn.NewBaseService(nil, "IntegrityChecker", ic)
+	return ic
+}
+
+// SetMetrics sets the metrics.
+// NOTE: not thread safe
+func (ic *IntegrityChecker) SetMetrics(metrics *IntegrityMetrics) {
+	ic.metrics = metrics
+}
+
+// OnStart implements cmn.Service by starting the checking routine.
+func (ic *IntegrityChecker) OnStart() error {
+	go ic.checkRoutine()
+	return nil
+}
+
+func (ic *IntegrityChecker) checkRoutine() {
+	ic.Check()
+	ticker := time.NewTicker(ic.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			ic.Check()
+		case <-ic.Quit:
+			return
+		}
+	}
+}
+
+// Check cross-checks the blocks committed since the last check,
+// and returns the number of discrepancies found.
+func (ic *IntegrityChecker) Check() int {
+	ic.mtx.Lock()
+	from := ic.report.To + 1
+	ic.mtx.Unlock()
+	to := ic.blockStore.Height()
+	if from > to {
+		return 0
+	}
+
+	// don't hold the lock while checking, the Report is served while it runs
+	report := CheckIntegrity(ic.db, ic.blockStore, from, to)
+	for _, discrepancy := range report.Discrepancies {
+		ic.Logger.Error("State db doesn't match the block store", "height", discrepancy.Height,
+			"field", discrepancy.Field, "err", discrepancy.Error)
+	}
+	ic.metrics.CheckedHeight.Update(report.To)
+	ic.metrics.Discrepancies.Inc(int64(report.NumDiscrepancies))
+
+	ic.mtx.Lock()
+	ic.report.merge(report)
+	ic.mtx.Unlock()
+	return report.NumDiscrepancies
+}
+
+// Report returns a copy of the report of all the checks since the start.
+func (ic *IntegrityChecker) Report() *IntegrityReport {
+	ic.mtx.Lock()
+	defer ic.mtx.Unlock()
+	report := *ic.report
+	report.Discrepancies = append([]IntegrityDiscrepancy(nil), ic.report.Discrepancies...)
+	return &report
+}
@@ -0,0 +1,63 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	abci "github.com/tendermint/abci/types"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	tearDown, stateDB, state := setupTestCase(t)
+	defer tearDown(t)
+	// nolint: vetshadow
+	assert := assert.New(t)
+
+	blockStore := make(mockBlockStore)
+	for height := int64(1); height <= 5; height++ {
+		block, parts := types.MakeBlock(height, state.ChainID, makeTxs(height), new(types.Commit),
+			state.LastBlockID, state.Validators.Hash(), state.AppHash, state.LastResultsHash, testPartSize)
+		blockStore[height] = types.NewBlockMeta(block, parts)
+
+		abciResponses := NewABCIResponses(block)
+		for i := range abciResponses.DeliverTx {
+			abciResponses.DeliverTx[i] = &abci.ResponseDeliverTx{Data: []byte{byte(i)}}
+		}
+		abciResponses.EndBlock = &abci.ResponseEndBlock{}
+		state.SaveABCIResponses(abciResponses)
+		state.SetBlockAndValidators(block.Header, parts.Header(), abciResponses)
+		state.Save()
+	}
+
+	report := CheckIntegrity(stateDB, blockStore, 0, 0)
+	assert.EqualValues(1, report.From)
+	assert.EqualValues(5, report.To)
+	assert.EqualValues(5, report.CheckedHeights)
+	assert.Empty(report.Discrepancies)
+
+	// a partial restore of the state db
+	stateDB.Delete(calcValidatorsKey(2))
+	abciResponses, err := state.LoadABCIResponsesAt(3)
+	assert.Nil(err)
+	abciResponses.DeliverTx[0] = &abci.ResponseDeliverTx{Code: 1}
+	stateDB.Set(calcABCIResponsesKey(3), abciResponses.Bytes())
+
+	checker := NewIntegrityChecker(stateDB, blockStore, time.Hour)
+	assert.Equal(2, checker.Check())
+	report = checker.Report()
+	assert.EqualValues(5, report.To)
+	if assert.Len(report.Discrepancies, 2) {
+		assert.Equal(IntegrityDiscrepancy{2, IntegrityFieldValidatorsHash, ErrNoValSetForHeight{2}.Error()},
+			report.Discrepancies[0])
+		assert.EqualValues(3, report.Discrepancies[1].Height)
+		assert.Equal(IntegrityFieldLastResultsHash, report.Discrepancies[1].Field)
+	}
+
+	// only the new blocks are checked again
+	assert.Equal(0, checker.Check())
+	assert.EqualValues(5, checker.Report().CheckedHeights)
+}
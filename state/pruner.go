@@ -0,0 +1,92 @@
+package state
+
+import (
+	"time"
+
+	wire "github.com/tendermint/go-wire"
+	cmn "github.com/tendermint/tmlibs/common"
+	dbm "github.com/tendermint/tmlibs/db"
+)
+
+// ABCIResponsesPruner deletes the ABCI responses saved for each height
+// (see SaveABCIResponses) but the ones of the last retainBlocks heights.
+// It runs in its own routine, independently of the pruning of the blocks:
+// the ABCI responses are often only needed briefly, and dominate the growth
+// of the state db.
+type ABCIResponsesPruner struct {
+	cmn.BaseService
+
+	db           dbm.DB
+	retainBlocks int64
+	interval     time.Duration
+}
+
+// NewABCIResponsesPruner returns a pruner keeping the ABCI responses of the last
+// retainBlocks heights in the state db, pruning the others every interval.
+func NewABCIResponsesPruner(db dbm.DB, retainBlocks int64, interval time.Duration) *ABCIResponsesPruner {
+	pruner := &ABCIResponsesPruner{
+		db:           db,
+		retainBlocks: retainBlocks,
+		interval:     interval,
+	}
+	pruner.BaseService = *cmn.NewBaseService(nil, "ABCIResponsesPruner", pruner)
+	return pruner
+}
+
+// OnStart implements cmn.Service by starting the pruning routine.
+func (p *ABCIResponsesPruner) OnStart() error {
+	go p.pruneRoutine()
+	return nil
+}
+
+func (p *ABCIResponsesPruner) pruneRoutine() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if pruned := p.Prune(); pruned > 0 {
+				p.Logger.Info("Pruned ABCI responses", "heights", pruned)
+			}
+		case <-p.Quit:
+			return
+		}
+	}
+}
+
+// Prune deletes the ABCI responses of the heights before the retained ones,
+// and returns the number of heights pruned.
+func (p *ABCIResponsesPruner) Prune() int64 {
+	state := LoadState(p.db)
+	if state == nil {
+		return 0
+	}
+	retainHeight := state.LastBlockHeight - p.retainBlocks + 1
+	base := LoadABCIResponsesBase(p.db)
+	if retainHeight <= base {
+		return 0
+	}
+
+	for height := base; height < retainHeight; height++ {
+		p.db.Delete(calcABCIResponsesKey(height))
+	}
+	// a crash before this only means deleting the same heights again
+	p.db.SetSync(abciResponsesBaseKey, wire.BinaryBytes(retainHeight))
+	return retainHeight - base
+}
+
+// LoadABCIResponsesBase returns the lowest height whose ABCI responses
+// were not pruned, 1 if none were.
+func LoadABCIResponsesBase(db dbm.DB) int64 {
+	buf := db.Get(abciResponsesBaseKey)
+	if len(buf) == 0 {
+		return 1
+	}
+	var base int64
+	err := wire.ReadBinaryBytes(buf, &base)
+	if err != nil {
+		// DATA HAS BEEN CORRUPTED OR THE SPEC HAS CHANGED
+		cmn.Exit(cmn.Fmt("LoadABCIResponsesBase: Data has been corrupted or its spec has changed: %v\n", err))
+	}
+	return base
+}
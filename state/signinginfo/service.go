@@ -0,0 +1,85 @@
+package signinginfo
+
+import (
+	"context"
+
+	cmn "github.com/tendermint/tmlibs/common"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	subscriber = "SigningInfoIndexer"
+)
+
+// ValidatorsLoader returns the validator set at a height, eg. State.LoadValidators.
+type ValidatorsLoader func(height int64) (*types.ValidatorSet, error)
+
+// IndexerService indexes the commits of the blocks in the Store as they are committed.
+// It catches up with the blocks committed while it wasn't running.
+type IndexerService struct {
+	cmn.BaseService
+
+	store          *Store
+	blockStore     types.BlockStoreRPC
+	loadValidators ValidatorsLoader
+	eventBus       *types.EventBus
+}
+
+// NewIndexerService returns a service indexing the commits of the blocks in the
+// blockStore, signed by the validators loadValidators returns, in the store.
+func NewIndexerService(store *Store, blockStore types.BlockStoreRPC, loadValidators ValidatorsLoader,
+	eventBus *types.EventBus) *IndexerService {
+	is := &IndexerService{
+		store:          store,
+		blockStore:     blockStore,
+		loadValidators: loadValidators,
+		eventBus:       eventBus,
+	}
+	is.BaseService = *cmn.NewBaseService(nil, "SigningInfoIndexer", is)
+	return is
+}
+
+// OnStart implements cmn.Service by subscribing to the new blocks,
+// and indexing the commits up to them.
+func (is *IndexerService) OnStart() error {
+	ch := make(chan interface{})
+	if err := is.eventBus.Subscribe(context.Background(), subscriber, types.EventQueryNewBlock, ch); err != nil {
+		return err
+	}
+	go func() {
+		is.catchUp(is.blockStore.Height())
+		for event := range ch {
+			block := event.(types.TMEventData).Unwrap().(types.EventDataNewBlock).Block
+			is.catchUp(block.Height)
+		}
+	}()
+	return nil
+}
+
+// OnStop implements cmn.Service by unsubscribing from the new blocks.
+func (is *IndexerService) OnStop() {
+	if is.eventBus.IsRunning() {
+		_ = is.eventBus.UnsubscribeAll(context.Background(), subscriber)
+	}
+}
+
+// catchUp indexes the commits of the heights before the given one,
+// the commit of a height being in the block of the next one.
+func (is *IndexerService) catchUp(height int64) {
+	for h := is.store.LastHeight() + 1; h < height; h++ {
+		commit := is.blockStore.LoadBlockCommit(h)
+		if commit == nil {
+			return
+		}
+		valSet, err := is.loadValidators(h)
+		if err != nil {
+			is.Logger.Error("Failed to load the validators of a commit", "height", h, "err", err)
+			return
+		}
+		if err := is.store.Index(h, commit, valSet); err != nil {
+			is.Logger.Error("Failed to index the signing info of a commit", "height", h, "err", err)
+			return
+		}
+	}
+}
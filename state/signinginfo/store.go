@@ -0,0 +1,158 @@
+// Package signinginfo indexes, for each validator, the heights it signed the
+// commit of, in compact bitmaps, so the apps and the clients can tell the downtime
+// of the validators without going through all the commits.
+package signinginfo
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	wire "github.com/tendermint/go-wire"
+	"github.com/tendermint/go-wire/data"
+	cmn "github.com/tendermint/tmlibs/common"
+	dbm "github.com/tendermint/tmlibs/db"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// WindowSize is the number of heights in each window of the signing bitmaps.
+const WindowSize = 1024
+
+var lastHeightKey = []byte("signingInfoLastHeight")
+
+// calcWindowKey returns the key of the window of the validator,
+// the windows of a validator being sorted by height.
+func calcWindowKey(address []byte, window int64) []byte {
+	return []byte(fmt.Sprintf("signingInfo:%X:%016X", address, window))
+}
+
+// windowOf returns the window of the height, and the index of the height in it.
+func windowOf(height int64) (window int64, index int) {
+	return (height - 1) / WindowSize, int((height - 1) % WindowSize)
+}
+
+// SigningWindow records, for the WindowSize heights of a window, the ones
+// the validator was in the validator set at, and the ones its precommit
+// is in the commit of.
+type SigningWindow struct {
+	Window int64
+	Active *cmn.BitArray
+	Signed *cmn.BitArray
+}
+
+func newSigningWindow(window int64) *SigningWindow {
+	return &SigningWindow{
+		Window: window,
+		Active: cmn.NewBitArray(WindowSize),
+		Signed: cmn.NewBitArray(WindowSize),
+	}
+}
+
+// SigningInfo is the signing record of a validator over a range of heights.
+// The commits of the heights, eg. from the /commit RPC endpoint, prove it.
+type SigningInfo struct {
+	Address data.Bytes `json:"address"`
+	From    int64      `json:"from"`
+	To      int64      `json:"to"`
+
+	// the number of heights the validator was in the validator set at,
+	// and of the ones it signed the commit of
+	Active int64 `json:"active"`
+	Signed int64 `json:"signed"`
+
+	// the heights the validator was in the validator set at, but didn't sign the commit of
+	MissedHeights []int64 `json:"missed_heights"`
+}
+
+// Store is the index of the signing bitmaps of the validators, in its own db.
+type Store struct {
+	db dbm.DB
+}
+
+// NewStore returns a Store on the db.
+func NewStore(db dbm.DB) *Store {
+	return &Store{db: db}
+}
+
+// LastHeight returns the last height indexed, 0 if none.
+func (s *Store) LastHeight() int64 {
+	buf := s.db.Get(lastHeightKey)
+	if len(buf) == 0 {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(buf))
+}
+
+// Index records which validators of the set signed the commit of the height.
+// The heights must be indexed in order, the ones already indexed are skipped.
+func (s *Store) Index(height int64, commit *types.Commit, valSet *types.ValidatorSet) error {
+	if height <= s.LastHeight() {
+		return nil
+	}
+	if len(commit.Precommits) != valSet.Size() {
+		return fmt.Errorf("Commit of height %d has %d precommits for %d validators",
+			height, len(commit.Precommits), valSet.Size())
+	}
+
+	window, index := windowOf(height)
+	batch := s.db.NewBatch()
+	for i, val := range valSet.Validators {
+		sw := s.loadWindow(val.Address, window)
+		if sw == nil {
+			sw = newSigningWindow(window)
+		}
+		sw.Active.SetIndex(index, true)
+		sw.Signed.SetIndex(index, commit.Precommits[i] != nil)
+		batch.Set(calcWindowKey(val.Address, window), wire.BinaryBytes(sw))
+	}
+
+	heightBytes := make([]byte, 8)
+	binary.BigEndian.PutUint64(heightBytes, uint64(height))
+	batch.Set(lastHeightKey, heightBytes)
+	batch.Write()
+	return nil
+}
+
+// SigningInfo returns the signing record of the validator over the heights
+// from and to included, among the heights indexed.
+func (s *Store) SigningInfo(address []byte, from, to int64) *SigningInfo {
+	if lastHeight := s.LastHeight(); to > lastHeight {
+		to = lastHeight
+	}
+	info := &SigningInfo{Address: address, From: from, To: to, MissedHeights: []int64{}}
+
+	var sw *SigningWindow
+	for height := from; height <= to; height++ {
+		window, index := windowOf(height)
+		if sw == nil || sw.Window != window {
+			sw = s.loadWindow(address, window)
+			if sw == nil {
+				// not a validator in the whole window
+				sw = newSigningWindow(window)
+			}
+		}
+		if !sw.Active.GetIndex(index) {
+			continue
+		}
+		info.Active++
+		if sw.Signed.GetIndex(index) {
+			info.Signed++
+		} else {
+			info.MissedHeights = append(info.MissedHeights, height)
+		}
+	}
+	return info
+}
+
+func (s *Store) loadWindow(address []byte, window int64) *SigningWindow {
+	buf := s.db.Get(calcWindowKey(address, window))
+	if len(buf) == 0 {
+		return nil
+	}
+	sw := new(SigningWindow)
+	if err := wire.ReadBinaryBytes(buf, sw); err != nil {
+		// DATA HAS BEEN CORRUPTED OR THE SPEC HAS CHANGED
+		cmn.Exit(cmn.Fmt("LoadSigningWindow: Data has been corrupted or its spec has changed: %v\n", err))
+	}
+	return sw
+}
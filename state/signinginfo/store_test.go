@@ -0,0 +1,66 @@
+package signinginfo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	dbm "github.com/tendermint/tmlibs/db"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// makeCommit returns a commit of the validator set, signed by the validators
+// at the given indices only.
+func makeCommit(valSet *types.ValidatorSet, signers ...int) *types.Commit {
+	precommits := make([]*types.Vote, valSet.Size())
+	for _, i := range signers {
+		precommits[i] = &types.Vote{ValidatorIndex: i, ValidatorAddress: valSet.Validators[i].Address}
+	}
+	return &types.Commit{Precommits: precommits}
+}
+
+func TestSigningInfo(t *testing.T) {
+	assert := assert.New(t)
+
+	valSet, _ := types.RandValidatorSet(3, 10)
+	store := NewStore(dbm.NewMemDB())
+	assert.Equal(int64(0), store.LastHeight())
+
+	// the first validator misses the heights multiple of 10, across windows
+	numHeights := int64(2*WindowSize + 10)
+	for height := int64(1); height <= numHeights; height++ {
+		signers := []int{1, 2}
+		if height%10 != 0 {
+			signers = append(signers, 0)
+		}
+		assert.Nil(store.Index(height, makeCommit(valSet, signers...), valSet))
+	}
+	assert.Equal(numHeights, store.LastHeight())
+
+	// the heights already indexed are skipped
+	assert.Nil(store.Index(5, makeCommit(valSet), valSet))
+
+	addr := valSet.Validators[0].Address
+	info := store.SigningInfo(addr, 1, numHeights)
+	assert.Equal(numHeights, info.Active)
+	assert.Equal(numHeights-numHeights/10, info.Signed)
+	assert.Equal(int(numHeights/10), len(info.MissedHeights))
+	assert.Equal(int64(10), info.MissedHeights[0])
+
+	// a range across two windows
+	info = store.SigningInfo(addr, WindowSize-5, WindowSize+5)
+	assert.Equal(int64(11), info.Active)
+	assert.Equal([]int64{WindowSize - 4}, info.MissedHeights)
+
+	// up to the last height indexed
+	info = store.SigningInfo(valSet.Validators[1].Address, 1, numHeights+100)
+	assert.Equal(numHeights, info.To)
+	assert.Equal(numHeights, info.Signed)
+
+	// not a validator
+	info = store.SigningInfo([]byte("nobody"), 1, numHeights)
+	assert.Equal(int64(0), info.Active)
+
+	// a commit that doesn't match the validator set
+	assert.NotNil(store.Index(numHeights+1, &types.Commit{}, valSet))
+}
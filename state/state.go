@@ -12,6 +12,7 @@ import (
 	cmn "github.com/tendermint/tmlibs/common"
 	dbm "github.com/tendermint/tmlibs/db"
 	"github.com/tendermint/tmlibs/log"
+	"github.com/tendermint/tmlibs/merkle"
 
 	wire "github.com/tendermint/go-wire"
 
@@ -19,14 +20,27 @@ import (
 )
 
 var (
-	stateKey         = []byte("stateKey")
-	abciResponsesKey = []byte("abciResponsesKey")
+	stateKey             = []byte("stateKey")
+	abciResponsesKey     = []byte("abciResponsesKey")
+	abciResponsesBaseKey = []byte("abciResponsesBaseKey")
 )
 
 func calcValidatorsKey(height int64) []byte {
 	return []byte(cmn.Fmt("validatorsKey:%v", height))
 }
 
+func calcConsensusParamsKey(height int64) []byte {
+	return []byte(cmn.Fmt("consensusParamsKey:%v", height))
+}
+
+func calcABCIResponsesKey(height int64) []byte {
+	return []byte(cmn.Fmt("abciResponsesKey:%v", height))
+}
+
+func calcStateHashKey(height int64) []byte {
+	return []byte(cmn.Fmt("stateHashKey:%v", height))
+}
+
 //-----------------------------------------------------------------------------
 
 // State represents the latest committed state of the Tendermint consensus,
@@ -41,6 +55,9 @@ type State struct {
 	ChainID string
 	// Consensus parameters used for validating blocks
 	Params types.ConsensusParams
+	// When a block returns a consensus params update via EndBlock,
+	// the update only applies to the next block, like a valset change.
+	LastHeightConsensusParamsChanged int64
 
 	// These fields are updated by SetBlockAndValidators.
 	// LastBlockHeight=0 at genesis (ie. block(H=0) does not exist)
@@ -56,10 +73,28 @@ type State struct {
 	// we set s.LastHeightValidatorsChanged = s.LastBlockHeight + 1
 	LastHeightValidatorsChanged int64
 
+	// Merkle root of the results from executing the last block,
+	// hashed as per Params.ResultsParams.
+	LastResultsHash []byte
+
 	// AppHash is updated after Commit
 	AppHash []byte
 
-	logger log.Logger
+	logger   log.Logger
+	listener StateUpdateListener
+}
+
+// StateUpdateListener is notified of the updates applied by SetBlockAndValidators,
+// so monitoring tools and reactors don't have to poll the DB for them.
+// The callbacks run synchronously, before the new state is persisted,
+// and must not block or mutate their arguments.
+type StateUpdateListener interface {
+	// OnValidatorSetUpdate is called with the validator updates returned by EndBlock
+	// at the given height, and the resulting validator set.
+	OnValidatorSetUpdate(height int64, diffs []*abci.Validator, validators *types.ValidatorSet)
+
+	// OnConsensusParamsUpdate is called when the consensus params change at the given height.
+	OnConsensusParamsUpdate(height int64, params types.ConsensusParams)
 }
 
 // GetState loads the most recent state from the database,
@@ -108,6 +143,12 @@ func (s *State) SetLogger(l log.Logger) {
 	s.logger = l
 }
 
+// SetUpdateListener sets the listener notified of validator set and consensus params updates.
+// It is carried over by Copy.
+func (s *State) SetUpdateListener(l StateUpdateListener) {
+	s.listener = l
+}
+
 // Copy makes a copy of the State for mutating.
 func (s *State) Copy() *State {
 	return &State{
@@ -118,26 +159,60 @@ func (s *State) Copy() *State {
 		Validators:                  s.Validators.Copy(),
 		LastValidators:              s.LastValidators.Copy(),
 		AppHash:                     s.AppHash,
+		LastResultsHash:             s.LastResultsHash,
 		LastHeightValidatorsChanged: s.LastHeightValidatorsChanged,
-		logger:  s.logger,
-		ChainID: s.ChainID,
-		Params:  s.Params,
+		logger:                      s.logger,
+		listener:                    s.listener,
+		ChainID:                     s.ChainID,
+		Params:                      s.Params,
+
+		LastHeightConsensusParamsChanged: s.LastHeightConsensusParamsChanged,
 	}
 }
 
-// Save persists the State to the database.
+// Save persists the State to the database, with a single sync to disk:
+// the validators and consensus params are written in a batch, synced with the state.
 func (s *State) Save() {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
-	s.saveValidatorsInfo()
+	batch := s.db.NewBatch()
+	s.saveValidatorsInfo(batch)
+	s.saveConsensusParamsInfo(batch)
+	batch.Set(calcStateHashKey(s.LastBlockHeight), s.Hash())
+	batch.Write()
 	s.db.SetSync(stateKey, s.Bytes())
 }
 
-// SaveABCIResponses persists the ABCIResponses to the database.
-// This is useful in case we crash after app.Commit and before s.Save().
+// SaveABCIResponses persists the ABCIResponses to the database,
+// as the latest ones and as the ones of their height.
+// The latest ones are useful in case we crash after app.Commit and before s.Save().
+// The ones of the heights are kept until pruned by the ABCIResponsesPruner.
 func (s *State) SaveABCIResponses(abciResponses *ABCIResponses) {
-	s.db.SetSync(abciResponsesKey, abciResponses.Bytes())
+	bz := abciResponses.Bytes()
+	s.db.Set(calcABCIResponsesKey(abciResponses.Height), bz)
+	s.db.SetSync(abciResponsesKey, bz)
+}
+
+// LoadABCIResponsesAt loads the ABCIResponses of the block at the given height.
+// It returns ErrNoABCIResponsesForHeight if they were pruned or never saved.
+func (s *State) LoadABCIResponsesAt(height int64) (*ABCIResponses, error) {
+	buf := s.db.Get(calcABCIResponsesKey(height))
+	if len(buf) == 0 {
+		return nil, ErrNoABCIResponsesForHeight{height}
+	}
+
+	abciResponses := new(ABCIResponses)
+	r, n, err := bytes.NewReader(buf), new(int), new(error)
+	wire.ReadBinaryPtr(abciResponses, r, 0, n, err)
+	if *err != nil {
+		// DATA HAS BEEN CORRUPTED OR THE SPEC HAS CHANGED
+		cmn.Exit(cmn.Fmt(`LoadABCIResponsesAt: Data has been corrupted or its spec has
+                changed: %v\n`, *err))
+	}
+	// TODO: ensure that buf is completely read.
+
+	return abciResponses, nil
 }
 
 // LoadABCIResponses loads the ABCIResponses from the database.
@@ -169,15 +244,60 @@ func (s *State) LoadValidators(height int64) (*types.ValidatorSet, error) {
 		return nil, ErrNoValSetForHeight{height}
 	}
 
-	if valInfo.ValidatorSet == nil {
-		valInfo = s.loadValidators(valInfo.LastHeightChanged)
+	if valInfo.ValidatorSet == nil && valInfo.LastHeightChanged != height {
+		lastHeightChanged := valInfo.LastHeightChanged
+		valInfo = s.loadValidators(lastHeightChanged)
 		if valInfo == nil {
 			cmn.PanicSanity(fmt.Sprintf(`Couldn't find validators at height %d as
-                        last changed from height %d`, valInfo.LastHeightChanged, height))
+                        last changed from height %d`, lastHeightChanged, height))
 		}
+		height = lastHeightChanged
 	}
 
-	return valInfo.ValidatorSet, nil
+	return s.validatorsAt(height, valInfo)
+}
+
+// validatorsAt returns the validator set persisted at height, where it changed,
+// applying its diff to the set at the previous change if it was persisted as a diff.
+func (s *State) validatorsAt(height int64, valInfo *ValidatorsInfo) (*types.ValidatorSet, error) {
+	if valInfo.ValidatorSet != nil {
+		return valInfo.ValidatorSet, nil
+	}
+
+	prevHeight := valInfo.PrevHeightChanged
+	prevInfo := s.loadValidators(prevHeight)
+	if prevInfo == nil {
+		cmn.PanicSanity(fmt.Sprintf(`Couldn't find validators at height %d as
+                        previously changed from height %d`, prevHeight, height))
+	}
+	prevValSet, err := s.validatorsAt(prevHeight, prevInfo)
+	if err != nil {
+		return nil, err
+	}
+	return applyValidatorsDiff(prevValSet, height-1-prevHeight, valInfo.Diff)
+}
+
+// ValidatorsChangeHeights returns the heights in (from, to] the validator set
+// changed at, in increasing order, only the last max of them if there are more.
+func (s *State) ValidatorsChangeHeights(from, to int64, max int) ([]int64, error) {
+	var heights []int64
+	for height := to; height > from && len(heights) < max; {
+		valInfo := s.loadValidators(height)
+		if valInfo == nil {
+			return nil, ErrNoValSetForHeight{height}
+		}
+		changed := valInfo.LastHeightChanged
+		if changed <= from {
+			break
+		}
+		heights = append(heights, changed)
+		height = changed - 1
+	}
+	// increasing
+	for i, j := 0, len(heights)-1; i < j; i, j = i+1, j-1 {
+		heights[i], heights[j] = heights[j], heights[i]
+	}
+	return heights, nil
 }
 
 func (s *State) loadValidators(height int64) *ValidatorsInfo {
@@ -200,10 +320,12 @@ func (s *State) loadValidators(height int64) *ValidatorsInfo {
 }
 
 // saveValidatorsInfo persists the validator set for the next block to disk.
-// It should be called from s.Save(), right before the state itself is persisted.
+// It should be called from s.Save(), with the batch synced with the state itself.
 // If the validator set did not change after processing the latest block,
 // only the last height for which the validators changed is persisted.
-func (s *State) saveValidatorsInfo() {
+// If it did, only the diff against the previous set is persisted,
+// with a full snapshot every valSetSnapshotInterval heights.
+func (s *State) saveValidatorsInfo(batch dbm.SetDeleter) {
 	changeHeight := s.LastHeightValidatorsChanged
 	nextHeight := s.LastBlockHeight + 1
 	valInfo := &ValidatorsInfo{
@@ -211,8 +333,103 @@ func (s *State) saveValidatorsInfo() {
 	}
 	if changeHeight == nextHeight {
 		valInfo.ValidatorSet = s.Validators
+		s.diffValidatorsInfo(valInfo, nextHeight)
+	}
+	batch.Set(calcValidatorsKey(nextHeight), valInfo.Bytes())
+}
+
+// diffValidatorsInfo replaces the validator set of valInfo with its diff against
+// the set at the previous change, unless a snapshot is due or the diff doesn't
+// reproduce the set exactly (accums included).
+func (s *State) diffValidatorsInfo(valInfo *ValidatorsInfo, nextHeight int64) {
+	if s.LastValidators == nil {
+		return
+	}
+	lastInfo := s.loadValidators(nextHeight - 1)
+	if lastInfo == nil {
+		return
+	}
+	prevHeight := lastInfo.LastHeightChanged
+	prevInfo := s.loadValidators(prevHeight)
+	if prevInfo == nil {
+		return
+	}
+	snapshotHeight := prevHeight
+	if prevInfo.ValidatorSet == nil {
+		snapshotHeight = prevInfo.LastHeightSnapshot
+	}
+	if nextHeight-snapshotHeight >= valSetSnapshotInterval {
+		return
+	}
+
+	// s.LastValidators is the set at prevHeight, incremented up to the last block
+	diff := validatorsDiff(s.LastValidators, s.Validators)
+	valSet, err := applyValidatorsDiff(s.LastValidators, 0, diff)
+	if err != nil || !bytes.Equal(wire.BinaryBytes(valSet), wire.BinaryBytes(s.Validators)) {
+		return
+	}
+	valInfo.ValidatorSet = nil
+	valInfo.Diff = diff
+	valInfo.PrevHeightChanged = prevHeight
+	valInfo.LastHeightSnapshot = snapshotHeight
+}
+
+// LoadConsensusParams loads the ConsensusParams for a given height.
+func (s *State) LoadConsensusParams(height int64) (types.ConsensusParams, error) {
+	return loadConsensusParams(s.db, height)
+}
+
+func loadConsensusParams(db dbm.DB, height int64) (types.ConsensusParams, error) {
+	paramsInfo := loadConsensusParamsInfo(db, height)
+	if paramsInfo == nil {
+		return types.ConsensusParams{}, ErrNoConsensusParamsForHeight{height}
+	}
+
+	if paramsInfo.ConsensusParams == nil {
+		lastHeightChanged := paramsInfo.LastHeightChanged
+		paramsInfo = loadConsensusParamsInfo(db, lastHeightChanged)
+		if paramsInfo == nil || paramsInfo.ConsensusParams == nil {
+			cmn.PanicSanity(fmt.Sprintf(`Couldn't find consensus params at height %d as
+                        last changed from height %d`, lastHeightChanged, height))
+		}
+	}
+	return *paramsInfo.ConsensusParams, nil
+}
+
+func loadConsensusParamsInfo(db dbm.DB, height int64) *ConsensusParamsInfo {
+	buf := db.Get(calcConsensusParamsKey(height))
+	if len(buf) == 0 {
+		return nil
+	}
+
+	paramsInfo := new(ConsensusParamsInfo)
+	r, n, err := bytes.NewReader(buf), new(int), new(error)
+	wire.ReadBinaryPtr(paramsInfo, r, 0, n, err)
+	if *err != nil {
+		// DATA HAS BEEN CORRUPTED OR THE SPEC HAS CHANGED
+		cmn.Exit(cmn.Fmt(`LoadConsensusParams: Data has been corrupted or its spec has changed:
+                %v\n`, *err))
+	}
+	// TODO: ensure that buf is completely read.
+
+	return paramsInfo
+}
+
+// saveConsensusParamsInfo persists the consensus params for the next block to disk.
+// It should be called from s.Save(), with the batch synced with the state itself.
+// If the params did not change after processing the latest block,
+// only the last height for which they changed is persisted.
+func (s *State) saveConsensusParamsInfo(batch dbm.SetDeleter) {
+	changeHeight := s.LastHeightConsensusParamsChanged
+	nextHeight := s.LastBlockHeight + 1
+	paramsInfo := &ConsensusParamsInfo{
+		LastHeightChanged: changeHeight,
+	}
+	if changeHeight == nextHeight {
+		params := s.Params
+		paramsInfo.ConsensusParams = &params
 	}
-	s.db.SetSync(calcValidatorsKey(nextHeight), valInfo.Bytes())
+	batch.Set(calcConsensusParamsKey(nextHeight), paramsInfo.Bytes())
 }
 
 // Equals returns true if the States are identical.
@@ -225,6 +442,84 @@ func (s *State) Bytes() []byte {
 	return wire.BinaryBytes(s)
 }
 
+// Hash returns the merkle root of the parts of the State which must be identical
+// on all nodes after the same block: the last block, the validators, the consensus params,
+// the results and the app hash.
+// Unlike Bytes, it doesn't depend on the serialization of the State.
+// The hash of the State at every height is persisted by Save.
+func (s *State) Hash() []byte {
+	return stateHash(s.ChainID, s.LastBlockHeight, s.LastBlockID,
+		s.LastValidators.Hash(), s.Validators.Hash(), s.Params, s.LastResultsHash, s.AppHash)
+}
+
+func stateHash(chainID string, height int64, blockID types.BlockID, lastValidatorsHash,
+	validatorsHash []byte, params types.ConsensusParams, resultsHash, appHash []byte) []byte {
+
+	return merkle.SimpleHashFromMap(map[string]interface{}{
+		"ChainID":        chainID,
+		"Height":         height,
+		"BlockID":        blockID,
+		"LastValidators": lastValidatorsHash,
+		"Validators":     validatorsHash,
+		"Params":         params,
+		"Results":        resultsHash,
+		"App":            appHash,
+	})
+}
+
+// LoadStateHash returns the hash of the State persisted after the block at the given height,
+// or nil if there is none.
+func LoadStateHash(db dbm.DB, height int64) []byte {
+	return db.Get(calcStateHashKey(height))
+}
+
+// VerifyStateHashes recomputes the hash of the State after each block in [from, to)
+// from the headers in the block store, and checks it against the persisted one,
+// to detect a corrupted state DB or nondeterminism between nodes.
+// Heights without a persisted hash are skipped. It returns the number of verified heights.
+// The consensus params persisted for each height are used, or the given ones
+// for the heights without any (ie. persisted before they could change).
+func VerifyStateHashes(db dbm.DB, blockStore types.BlockStoreRPC, params types.ConsensusParams,
+	from, to int64) (int, error) {
+
+	// the blocks before the base are pruned
+	if base := blockStore.Base(); from < base {
+		from = base
+	}
+	if from < 1 {
+		from = 1
+	}
+	if to > blockStore.Height() {
+		// the results and app hash of a block are in the header of the next one
+		to = blockStore.Height()
+	}
+
+	verified := 0
+	for height := from; height < to; height++ {
+		persisted := LoadStateHash(db, height)
+		if persisted == nil {
+			continue
+		}
+		meta, nextMeta := blockStore.LoadBlockMeta(height), blockStore.LoadBlockMeta(height+1)
+		if meta == nil || nextMeta == nil {
+			return verified, fmt.Errorf("Missing block meta at height %d", height)
+		}
+		header, next := meta.Header, nextMeta.Header
+		// the State after the block holds the params for the next one
+		nextParams, err := loadConsensusParams(db, height+1)
+		if err != nil {
+			nextParams = params
+		}
+		computed := stateHash(header.ChainID, height, meta.BlockID,
+			header.ValidatorsHash, next.ValidatorsHash, nextParams, next.LastResultsHash, next.AppHash)
+		if !bytes.Equal(persisted, computed) {
+			return verified, ErrStateHashMismatch{Height: height, Persisted: persisted, Computed: computed}
+		}
+		verified++
+	}
+	return verified, nil
+}
+
 // SetBlockAndValidators mutates State variables
 // to update block and validators after running EndBlock.
 func (s *State) SetBlockAndValidators(header *types.Header, blockPartsHeader types.PartSetHeader,
@@ -234,13 +529,17 @@ func (s *State) SetBlockAndValidators(header *types.Header, blockPartsHeader typ
 	// and update s.LastValidators and s.Validators
 	prevValSet := s.Validators.Copy()
 	nextValSet := prevValSet.Copy()
+	prevParams := s.Params
 
 	// update the validator set with the latest abciResponses
-	if len(abciResponses.EndBlock.Diffs) > 0 {
-		err := updateValidators(nextValSet, abciResponses.EndBlock.Diffs)
+	validatorsUpdated := false
+	if len(abciResponses.EndBlock.ValidatorUpdates) > 0 {
+		err := updateValidators(nextValSet, abciResponses.EndBlock.ValidatorUpdates)
 		if err != nil {
 			s.logger.Error("Error changing validator set", "err", err)
 			// TODO: err or carry on?
+		} else {
+			validatorsUpdated = true
 		}
 		// change results from this height but only applies to the next height
 		s.LastHeightValidatorsChanged = header.Height + 1
@@ -254,6 +553,27 @@ func (s *State) SetBlockAndValidators(header *types.Header, blockPartsHeader typ
 		header.Time,
 		prevValSet, nextValSet)
 
+	s.LastResultsHash = abciResponses.ResultsHash(s.Params.ResultsParams.HashVersion)
+
+	// update the consensus params, which also only apply to the next height
+	if abciResponses.ParamsUpdate != nil {
+		nextParams, err := s.Params.Update(abciResponses.ParamsUpdate)
+		if err != nil {
+			s.logger.Error("Error updating consensus params", "err", err)
+		} else {
+			s.Params = nextParams
+			s.LastHeightConsensusParamsChanged = header.Height + 1
+		}
+	}
+
+	if s.listener != nil {
+		if validatorsUpdated {
+			s.listener.OnValidatorSetUpdate(header.Height, abciResponses.EndBlock.ValidatorUpdates, s.Validators)
+		}
+		if !bytes.Equal(wire.BinaryBytes(prevParams), wire.BinaryBytes(s.Params)) {
+			s.listener.OnConsensusParamsUpdate(header.Height, s.Params)
+		}
+	}
 }
 
 func (s *State) setBlockAndValidators(height int64, blockID types.BlockID, blockTime time.Time,
@@ -281,6 +601,10 @@ type ABCIResponses struct {
 	DeliverTx []*abci.ResponseDeliverTx
 	EndBlock  *abci.ResponseEndBlock
 
+	// ParamsUpdate is the consensus params update returned by EndBlock, if any,
+	// see ConsensusParams.UpdateFromABCI.
+	ParamsUpdate *types.ConsensusParamsUpdate
+
 	txs types.Txs // reference for indexing results by hash
 }
 
@@ -298,12 +622,38 @@ func (a *ABCIResponses) Bytes() []byte {
 	return wire.BinaryBytes(*a)
 }
 
+// ResultsHash returns the merkle root of the DeliverTx results,
+// encoded with the given version, or from ResultsHashBlockV3 on,
+// the hash of the BlockResults.
+func (a *ABCIResponses) ResultsHash(version int) []byte {
+	if version >= types.ResultsHashBlockV3 {
+		return a.BlockResults().Hash()
+	}
+	results := types.NewResults(a.DeliverTx)
+	return results.Hash(version)
+}
+
+// BlockResults returns the results of the block committed to from ResultsHashBlockV3 on.
+func (a *ABCIResponses) BlockResults() *types.BlockResults {
+	return types.NewBlockResults(a.DeliverTx, a.EndBlock, a.ParamsUpdate)
+}
+
 //-----------------------------------------------------------------------------
 
-// ValidatorsInfo represents the latest validator set, or the last height it changed
+// valSetSnapshotInterval is the max number of heights between two full snapshots
+// of the validator set, and so the max length of a chain of diffs to apply on load.
+var valSetSnapshotInterval int64 = 100
+
+// ValidatorsInfo represents the latest validator set, or the last height it changed.
+// At a height it changed, the set is either persisted in full, or as the Diff
+// against the set at PrevHeightChanged.
 type ValidatorsInfo struct {
 	ValidatorSet      *types.ValidatorSet
 	LastHeightChanged int64
+
+	Diff               []*abci.Validator
+	PrevHeightChanged  int64
+	LastHeightSnapshot int64 // last height the full set was persisted at
 }
 
 // Bytes serializes the ValidatorsInfo using go-wire
@@ -311,6 +661,17 @@ func (valInfo *ValidatorsInfo) Bytes() []byte {
 	return wire.BinaryBytes(*valInfo)
 }
 
+// ConsensusParamsInfo represents the latest consensus params, or the last height they changed.
+type ConsensusParamsInfo struct {
+	ConsensusParams   *types.ConsensusParams
+	LastHeightChanged int64
+}
+
+// Bytes serializes the ConsensusParamsInfo using go-wire
+func (paramsInfo *ConsensusParamsInfo) Bytes() []byte {
+	return wire.BinaryBytes(*paramsInfo)
+}
+
 //------------------------------------------------------------------------
 // Genesis
 
@@ -373,5 +734,7 @@ func MakeGenesisState(db dbm.DB, genDoc *types.GenesisDoc) (*State, error) {
 		LastValidators:              types.NewValidatorSet(nil),
 		AppHash:                     genDoc.AppHash,
 		LastHeightValidatorsChanged: 1,
+
+		LastHeightConsensusParamsChanged: 1,
 	}, nil
 }
@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	abci "github.com/tendermint/abci/types"
 
 	crypto "github.com/tendermint/go-crypto"
+	wire "github.com/tendermint/go-wire"
 
 	cmn "github.com/tendermint/tmlibs/common"
 	dbm "github.com/tendermint/tmlibs/db"
@@ -66,6 +68,42 @@ func TestStateSaveLoad(t *testing.T) {
 			loadedState, state))
 }
 
+// syncCountingDB counts the writes synced to disk.
+type syncCountingDB struct {
+	dbm.DB
+	syncs int
+}
+
+func (db *syncCountingDB) SetSync(key, value []byte) {
+	db.syncs++
+	db.DB.SetSync(key, value)
+}
+
+// TestStateSaveSyncs tests a height costs one sync for the ABCIResponses and one for the State.
+func TestStateSaveSyncs(t *testing.T) {
+	tearDown, stateDB, state := setupTestCase(t)
+	defer tearDown(t)
+	// nolint: vetshadow
+	assert := assert.New(t)
+
+	db := &syncCountingDB{DB: stateDB}
+	state.db = db
+
+	// the validators change, so the new set is saved too
+	header, parts, responses := makeHeaderPartsResponses(state, 1, crypto.GenPrivKeyEd25519().PubKey())
+	state.SaveABCIResponses(responses)
+	assert.Equal(1, db.syncs)
+	state.SetBlockAndValidators(header, parts, responses)
+	state.Save()
+	assert.Equal(2, db.syncs)
+
+	loadedState := LoadState(stateDB)
+	assert.True(state.Equals(loadedState))
+	v, err := loadedState.LoadValidators(2)
+	assert.Nil(err)
+	assert.Equal(state.Validators.Hash(), v.Hash())
+}
+
 // TestABCIResponsesSaveLoad tests saving and loading ABCIResponses.
 func TestABCIResponsesSaveLoad(t *testing.T) {
 	tearDown, _, state := setupTestCase(t)
@@ -80,7 +118,7 @@ func TestABCIResponsesSaveLoad(t *testing.T) {
 	abciResponses := NewABCIResponses(block)
 	abciResponses.DeliverTx[0] = &abci.ResponseDeliverTx{Data: []byte("foo"), Tags: []*abci.KVPair{}}
 	abciResponses.DeliverTx[1] = &abci.ResponseDeliverTx{Data: []byte("bar"), Log: "ok", Tags: []*abci.KVPair{}}
-	abciResponses.EndBlock = &abci.ResponseEndBlock{Diffs: []*abci.Validator{
+	abciResponses.EndBlock = &abci.ResponseEndBlock{ValidatorUpdates: []*abci.Validator{
 		{
 			PubKey: crypto.GenPrivKeyEd25519().PubKey().Bytes(),
 			Power:  10,
@@ -95,6 +133,77 @@ func TestABCIResponsesSaveLoad(t *testing.T) {
 			abciResponses))
 }
 
+// TestABCIResponsesPruner tests that the ABCI responses of each height are saved,
+// and pruned but the retained ones.
+func TestABCIResponsesPruner(t *testing.T) {
+	tearDown, stateDB, state := setupTestCase(t)
+	defer tearDown(t)
+	// nolint: vetshadow
+	assert := assert.New(t)
+
+	for height := int64(1); height <= 10; height++ {
+		abciResponses := NewABCIResponses(makeBlock(height, state))
+		abciResponses.txs = nil
+		state.SaveABCIResponses(abciResponses)
+	}
+	state.LastBlockHeight = 10
+	state.Save()
+
+	loaded, err := state.LoadABCIResponsesAt(5)
+	assert.Nil(err)
+	assert.Equal(int64(5), loaded.Height)
+
+	pruner := NewABCIResponsesPruner(stateDB, 3, time.Hour)
+	assert.Equal(int64(7), pruner.Prune())
+	assert.Equal(int64(8), LoadABCIResponsesBase(stateDB))
+	_, err = state.LoadABCIResponsesAt(7)
+	assert.Equal(ErrNoABCIResponsesForHeight{7}, err)
+	for height := int64(8); height <= 10; height++ {
+		_, err = state.LoadABCIResponsesAt(height)
+		assert.Nil(err)
+	}
+	// the latest ones are kept for the crash recovery
+	assert.Equal(int64(10), state.LoadABCIResponses().Height)
+
+	// nothing more to prune until the next block
+	assert.Equal(int64(0), pruner.Prune())
+}
+
+// TestLastResultsHash tests that the results of a block are committed to in the state.
+func TestLastResultsHash(t *testing.T) {
+	tearDown, _, state := setupTestCase(t)
+	defer tearDown(t)
+	// nolint: vetshadow
+	assert := assert.New(t)
+
+	block := makeBlock(1, state)
+	abciResponses := NewABCIResponses(block)
+	for i := range abciResponses.DeliverTx {
+		abciResponses.DeliverTx[i] = &abci.ResponseDeliverTx{Code: uint32(i), Data: []byte("foo")}
+	}
+	abciResponses.EndBlock = &abci.ResponseEndBlock{}
+
+	version := state.Params.ResultsParams.HashVersion
+	assert.NotEqual(types.ResultsHashNone, version)
+	expected := types.NewResults(abciResponses.DeliverTx).Hash(version)
+
+	state.SetBlockAndValidators(block.Header, types.PartSetHeader{}, abciResponses)
+	assert.NotEmpty(state.LastResultsHash)
+	assert.Equal(expected, state.LastResultsHash)
+
+	// a chain that opted in commits to the block results
+	state.Params.ResultsParams.HashVersion = types.ResultsHashBlockV3
+	state.SetBlockAndValidators(block.Header, types.PartSetHeader{}, abciResponses)
+	blockResults := types.NewBlockResults(abciResponses.DeliverTx, abciResponses.EndBlock, nil)
+	assert.Equal(blockResults.Hash(), state.LastResultsHash)
+	assert.NotEqual(expected, state.LastResultsHash)
+
+	// a chain that opted out doesn't commit to the results
+	state.Params.ResultsParams.HashVersion = types.ResultsHashNone
+	state.SetBlockAndValidators(block.Header, types.PartSetHeader{}, abciResponses)
+	assert.Nil(state.LastResultsHash)
+}
+
 // TestValidatorSimpleSaveLoad tests saving and loading validators.
 func TestValidatorSimpleSaveLoad(t *testing.T) {
 	tearDown, _, state := setupTestCase(t)
@@ -113,14 +222,14 @@ func TestValidatorSimpleSaveLoad(t *testing.T) {
 
 	// increment height, save; should be able to load for next height
 	state.LastBlockHeight++
-	state.saveValidatorsInfo()
+	state.saveValidatorsInfo(state.db)
 	v, err = state.LoadValidators(state.LastBlockHeight + 1)
 	assert.Nil(err, "expected no err")
 	assert.Equal(v.Hash(), state.Validators.Hash(), "expected validator hashes to match")
 
 	// increment height, save; should be able to load for next height
 	state.LastBlockHeight += 10
-	state.saveValidatorsInfo()
+	state.saveValidatorsInfo(state.db)
 	v, err = state.LoadValidators(state.LastBlockHeight + 1)
 	assert.Nil(err, "expected no err")
 	assert.Equal(v.Hash(), state.Validators.Hash(), "expected validator hashes to match")
@@ -164,7 +273,7 @@ func TestValidatorChangesSaveLoad(t *testing.T) {
 		}
 		header, parts, responses := makeHeaderPartsResponses(state, i, pubkey)
 		state.SetBlockAndValidators(header, parts, responses)
-		state.saveValidatorsInfo()
+		state.saveValidatorsInfo(state.db)
 	}
 
 	// make all the test cases by using the same validator until after the change
@@ -190,6 +299,62 @@ func TestValidatorChangesSaveLoad(t *testing.T) {
 		assert.Equal(addr, testCase.vals.Address(), fmt.Sprintf(`unexpected pubkey at
                 height %d`, testCase.height))
 	}
+
+	// the changes apply from the next height
+	heights, err := state.ValidatorsChangeHeights(1, highestHeight, 100)
+	assert.Nil(err)
+	assert.Equal([]int64{2, 3, 5, 6, 11, 16, 17, 18, 21}, heights)
+	heights, err = state.ValidatorsChangeHeights(5, 17, 2)
+	assert.Nil(err)
+	assert.Equal([]int64{16, 17}, heights)
+}
+
+// TestValidatorDiffsSaveLoad tests that the validator sets persisted as diffs
+// load exactly as they were saved.
+func TestValidatorDiffsSaveLoad(t *testing.T) {
+	tearDown, _, state := setupTestCase(t)
+	defer tearDown(t)
+	// nolint: vetshadow
+	assert := assert.New(t)
+
+	defer func(interval int64) { valSetSnapshotInterval = interval }(valSetSnapshotInterval)
+	valSetSnapshotInterval = 5
+
+	_, val := state.Validators.GetByIndex(0)
+	expected := map[int64]*types.ValidatorSet{}
+	snapshots := 0
+	for height := int64(1); height < 20; height++ {
+		// add a validator at odd heights, change the power of the first one at even heights,
+		// and leave the set unchanged every third height
+		var diffs []*abci.Validator
+		switch {
+		case height%3 == 0:
+		case height%2 == 1:
+			diffs = []*abci.Validator{{crypto.GenPrivKeyEd25519().PubKey().Bytes(), height}}
+		default:
+			diffs = []*abci.Validator{{val.PubKey.Bytes(), height}}
+		}
+		block := makeBlock(height, state)
+		responses := &ABCIResponses{Height: height, EndBlock: &abci.ResponseEndBlock{ValidatorUpdates: diffs}}
+		state.SetBlockAndValidators(block.Header, types.PartSetHeader{}, responses)
+		state.Save()
+
+		expected[height+1] = state.Validators.Copy()
+		if valInfo := state.loadValidators(height + 1); valInfo.ValidatorSet != nil {
+			snapshots++
+		}
+	}
+	assert.True(snapshots > 0, "a full snapshot is due every 5 heights")
+	assert.True(snapshots < 10, "most changes are persisted as diffs, got %d snapshots", snapshots)
+
+	for height := int64(2); height <= 20; height++ {
+		valSet, err := state.LoadValidators(height)
+		if assert.Nil(err, "height %d", height) && height%3 != 1 {
+			// the accums are only those of the height the set last changed
+			assert.Equal(wire.BinaryBytes(expected[height]), wire.BinaryBytes(valSet), "height %d", height)
+		}
+		assert.Equal(expected[height].Hash(), valSet.Hash(), "height %d", height)
+	}
 }
 
 func makeHeaderPartsResponses(state *State, height int64,
@@ -199,13 +364,13 @@ func makeHeaderPartsResponses(state *State, height int64,
 	_, val := state.Validators.GetByIndex(0)
 	abciResponses := &ABCIResponses{
 		Height:   height,
-		EndBlock: &abci.ResponseEndBlock{Diffs: []*abci.Validator{}},
+		EndBlock: &abci.ResponseEndBlock{ValidatorUpdates: []*abci.Validator{}},
 	}
 
 	// if the pubkey is new, remove the old and add the new
 	if !bytes.Equal(pubkey.Bytes(), val.PubKey.Bytes()) {
 		abciResponses.EndBlock = &abci.ResponseEndBlock{
-			Diffs: []*abci.Validator{
+			ValidatorUpdates: []*abci.Validator{
 				{val.PubKey.Bytes(), 0},
 				{pubkey.Bytes(), 10},
 			},
@@ -219,3 +384,151 @@ type valChangeTestCase struct {
 	height int64
 	vals   crypto.PubKey
 }
+
+type testUpdateListener struct {
+	heights    []int64
+	validators []*types.ValidatorSet
+
+	paramsHeights []int64
+	params        []types.ConsensusParams
+}
+
+func (l *testUpdateListener) OnValidatorSetUpdate(height int64, diffs []*abci.Validator, validators *types.ValidatorSet) {
+	l.heights = append(l.heights, height)
+	l.validators = append(l.validators, validators)
+}
+
+func (l *testUpdateListener) OnConsensusParamsUpdate(height int64, params types.ConsensusParams) {
+	l.paramsHeights = append(l.paramsHeights, height)
+	l.params = append(l.params, params)
+}
+
+// TestStateUpdateListener tests that the listener is notified of validator set changes.
+func TestStateUpdateListener(t *testing.T) {
+	tearDown, _, state := setupTestCase(t)
+	defer tearDown(t)
+	// nolint: vetshadow
+	assert := assert.New(t)
+
+	listener := &testUpdateListener{}
+	state.SetUpdateListener(listener)
+
+	// the listener is carried over by Copy
+	state = state.Copy()
+
+	_, val := state.Validators.GetByIndex(0)
+	header, parts, responses := makeHeaderPartsResponses(state, 1, val.PubKey)
+	state.SetBlockAndValidators(header, parts, responses)
+	assert.Empty(listener.heights)
+
+	pubkey := crypto.GenPrivKeyEd25519().PubKey()
+	header, parts, responses = makeHeaderPartsResponses(state, 2, pubkey)
+	state.SetBlockAndValidators(header, parts, responses)
+	if assert.Equal([]int64{2}, listener.heights) {
+		assert.Equal(state.Validators, listener.validators[0])
+		_, val = listener.validators[0].GetByIndex(0)
+		assert.Equal(pubkey, val.PubKey)
+	}
+	assert.Empty(listener.paramsHeights)
+}
+
+// TestConsensusParamsChangesSaveLoad tests that the consensus params updates
+// apply from the next height, and are persisted for each height.
+func TestConsensusParamsChangesSaveLoad(t *testing.T) {
+	tearDown, _, state := setupTestCase(t)
+	defer tearDown(t)
+	// nolint: vetshadow
+	assert := assert.New(t)
+
+	listener := &testUpdateListener{}
+	state.SetUpdateListener(listener)
+	genesisParams := state.Params
+	state.saveConsensusParamsInfo(state.db)
+
+	// the timeouts change at height 3 and 6, an invalid update at height 8 is ignored
+	updates := map[int64]*types.TimeoutParams{
+		3: {Propose: 1000},
+		6: {Propose: 2000, Commit: 500},
+		8: {Commit: -1},
+	}
+	_, val := state.Validators.GetByIndex(0)
+	for height := int64(1); height < 10; height++ {
+		header, parts, responses := makeHeaderPartsResponses(state, height, val.PubKey)
+		if timeouts, ok := updates[height]; ok {
+			responses.ParamsUpdate = &types.ConsensusParamsUpdate{TimeoutParams: timeouts}
+		}
+		state.SetBlockAndValidators(header, parts, responses)
+		state.saveConsensusParamsInfo(state.db)
+	}
+	assert.Equal([]int64{3, 6}, listener.paramsHeights)
+	assert.EqualValues(7, state.LastHeightConsensusParamsChanged)
+
+	for height := int64(1); height <= 10; height++ {
+		params, err := state.LoadConsensusParams(height)
+		if !assert.Nil(err, "expected no err at height %d", height) {
+			continue
+		}
+		expected := genesisParams
+		switch {
+		case height > 6:
+			expected.TimeoutParams = *updates[6]
+		case height > 3:
+			expected.TimeoutParams = *updates[3]
+		}
+		assert.Equal(expected, params, "unexpected params at height %d", height)
+	}
+
+	_, err := state.LoadConsensusParams(11)
+	assert.IsType(ErrNoConsensusParamsForHeight{}, err, "expected err at unknown height")
+}
+
+// TestVerifyStateHashes tests that the persisted state hashes can be recomputed from the blocks.
+func TestVerifyStateHashes(t *testing.T) {
+	tearDown, stateDB, state := setupTestCase(t)
+	defer tearDown(t)
+	// nolint: vetshadow
+	assert := assert.New(t)
+
+	blockStore := make(mockBlockStore)
+	for height := int64(1); height <= 5; height++ {
+		block, parts := types.MakeBlock(height, state.ChainID, makeTxs(height), new(types.Commit),
+			state.LastBlockID, state.Validators.Hash(), state.AppHash, state.LastResultsHash, testPartSize)
+		blockStore[height] = types.NewBlockMeta(block, parts)
+
+		abciResponses := NewABCIResponses(block)
+		for i := range abciResponses.DeliverTx {
+			abciResponses.DeliverTx[i] = &abci.ResponseDeliverTx{Data: []byte{byte(i)}}
+		}
+		abciResponses.EndBlock = &abci.ResponseEndBlock{}
+		state.SetBlockAndValidators(block.Header, parts.Header(), abciResponses)
+		state.AppHash = []byte(cmn.Fmt("app_hash_%d", height))
+		state.Save()
+		assert.Equal(state.Hash(), LoadStateHash(stateDB, height))
+	}
+
+	// the state after the last block can't be recomputed yet
+	verified, err := VerifyStateHashes(stateDB, blockStore, state.Params, 1, 5)
+	assert.Nil(err)
+	assert.Equal(4, verified)
+
+	// a different app hash is detected
+	stateDB.Set(calcStateHashKey(3), stateHash(state.ChainID, 3, blockStore[3].BlockID,
+		blockStore[3].Header.ValidatorsHash, blockStore[4].Header.ValidatorsHash, state.Params,
+		blockStore[4].Header.LastResultsHash, []byte("bad_app_hash")))
+	verified, err = VerifyStateHashes(stateDB, blockStore, state.Params, 1, 5)
+	if assert.IsType(ErrStateHashMismatch{}, err) {
+		assert.EqualValues(3, err.(ErrStateHashMismatch).Height)
+	}
+	assert.Equal(2, verified)
+}
+
+// mockBlockStore only stores block metas
+type mockBlockStore map[int64]*types.BlockMeta
+
+func (bs mockBlockStore) Height() int64                                     { return int64(len(bs)) }
+func (bs mockBlockStore) Base() int64                                       { return cmn.MinInt64(1, bs.Height()) }
+func (bs mockBlockStore) LoadBlockMeta(height int64) *types.BlockMeta       { return bs[height] }
+func (bs mockBlockStore) LoadBlock(height int64) *types.Block               { return nil }
+func (bs mockBlockStore) LoadBlockPart(height int64, index int) *types.Part { return nil }
+func (bs mockBlockStore) LoadBlockCommit(height int64) *types.Commit        { return nil }
+func (bs mockBlockStore) LoadSeenCommit(height int64) *types.Commit         { return nil }
@@ -14,9 +14,9 @@ import (
 	wire "github.com/tendermint/go-wire"
 	"github.com/tendermint/tendermint/state/txindex"
 	"github.com/tendermint/tendermint/types"
+	"github.com/tendermint/tendermint/types/query"
 	cmn "github.com/tendermint/tmlibs/common"
 	db "github.com/tendermint/tmlibs/db"
-	"github.com/tendermint/tmlibs/pubsub/query"
 )
 
 const (
@@ -123,29 +123,59 @@ func (txi *TxIndex) Index(result *types.TxResult) error {
 }
 
 // Search performs a search using the given query. It breaks the query into
-// conditions (like "tx.height > 5"). For each condition, it queries the DB
-// index. One special use cases here: (1) if "tx.hash" is found, it returns tx
-// result for it (2) for range queries it is better for the client to provide
-// both lower and upper bounds, so we are not performing a full scan. Results
-// from querying indexes are then intersected and returned to the caller.
+// conjunctions of conditions (like "tx.height > 5"), searched separately, the
+// results of which are merged. See searchConditions.
 func (txi *TxIndex) Search(q *query.Query) ([]*types.TxResult, error) {
+	results := make([]*types.TxResult, 0)
+	found := make(map[string]bool)
+	for _, conditions := range q.Conjunctions() {
+		hashes, err := txi.searchConditions(conditions)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range hashes {
+			if found[string(h)] {
+				continue
+			}
+			found[string(h)] = true
+
+			res, err := txi.Get(h)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to get Tx{%X}", h)
+			}
+			if res != nil {
+				results = append(results, res)
+			}
+		}
+	}
+	return results, nil
+}
+
+// searchConditions returns the hashes of the txs matching all the conditions.
+// For each condition, it queries the DB index. One special use cases here: (1)
+// if "tx.hash" is found, it returns the hash (2) for range queries it is better
+// for the client to provide both lower and upper bounds, so we are not
+// performing a full scan. Results from querying indexes are then intersected.
+func (txi *TxIndex) searchConditions(conditions []query.Condition) ([][]byte, error) {
 	var hashes [][]byte
 	var hashesInitialized bool
 
-	// get a list of conditions (like "tx.height > 5")
-	conditions := q.Conditions()
+	// every tx has a hash and a height
+	filtered := make([]query.Condition, 0, len(conditions))
+	for _, c := range conditions {
+		if c.Op == query.OpExists && (c.Tag == types.TxHashKey || c.Tag == types.TxHeightKey) {
+			continue
+		}
+		filtered = append(filtered, c)
+	}
+	conditions = filtered
 
 	// if there is a hash condition, return the result immediately
 	hash, err, ok := lookForHash(conditions)
 	if err != nil {
 		return nil, errors.Wrap(err, "error during searching for a hash in the query")
 	} else if ok {
-		res, err := txi.Get(hash)
-		if res == nil {
-			return []*types.TxResult{}, nil
-		} else {
-			return []*types.TxResult{res}, errors.Wrap(err, "error while retrieving the result")
-		}
+		return [][]byte{hash}, nil
 	}
 
 	// conditions to skip because they're handled before "everything else"
@@ -180,25 +210,21 @@ func (txi *TxIndex) Search(q *query.Query) ([]*types.TxResult, error) {
 			continue
 		}
 
+		var matched [][]byte
+		if c.Op == query.OpExists {
+			matched = txi.matchExists(c.Tag, height)
+		} else {
+			matched = txi.match(c, startKey(c, height))
+		}
 		if !hashesInitialized {
-			hashes = txi.match(c, startKey(c, height))
+			hashes = matched
 			hashesInitialized = true
 		} else {
-			hashes = intersect(hashes, txi.match(c, startKey(c, height)))
+			hashes = intersect(hashes, matched)
 		}
 	}
 
-	results := make([]*types.TxResult, len(hashes))
-	i := 0
-	for _, h := range hashes {
-		results[i], err = txi.Get(h)
-		if err != nil {
-			return nil, errors.Wrapf(err, "failed to get Tx{%X}", h)
-		}
-		i++
-	}
-
-	return results, nil
+	return hashes, nil
 }
 
 func lookForHash(conditions []query.Condition) (hash []byte, err error, ok bool) {
@@ -295,6 +321,22 @@ func (txi *TxIndex) match(c query.Condition, startKey []byte) (hashes [][]byte)
 	return
 }
 
+// matchExists returns the hashes of the txs with the tag, at the height if not 0.
+func (txi *TxIndex) matchExists(tag string, height int64) (hashes [][]byte) {
+	it := txi.store.IteratorPrefix([]byte(tag + tagKeySeparator))
+	defer it.Release()
+	for it.Next() {
+		if !isTagKey(it.Key()) {
+			continue
+		}
+		if height > 0 && extractHeightFromKey(it.Key()) != strconv.FormatInt(height, 10) {
+			continue
+		}
+		hashes = append(hashes, it.Value())
+	}
+	return
+}
+
 func (txi *TxIndex) matchRange(r queryRange, startKey []byte) (hashes [][]byte) {
 	it := txi.store.IteratorPrefix(startKey)
 	defer it.Release()
@@ -376,6 +418,11 @@ func extractValueFromKey(key []byte) string {
 	return parts[1]
 }
 
+func extractHeightFromKey(key []byte) string {
+	parts := strings.SplitN(string(key), tagKeySeparator, 4)
+	return parts[2]
+}
+
 func keyForTag(tag *abci.KVPair, result *types.TxResult) []byte {
 	switch tag.ValueType {
 	case abci.KVPair_STRING:
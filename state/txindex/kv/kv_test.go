@@ -11,8 +11,8 @@ import (
 	abci "github.com/tendermint/abci/types"
 	"github.com/tendermint/tendermint/state/txindex"
 	"github.com/tendermint/tendermint/types"
+	"github.com/tendermint/tendermint/types/query"
 	db "github.com/tendermint/tmlibs/db"
-	"github.com/tendermint/tmlibs/pubsub/query"
 )
 
 func TestTxIndex(t *testing.T) {
@@ -87,6 +87,18 @@ func TestTxSearch(t *testing.T) {
 		{"account.owner CONTAINS 'an'", 1},
 		// search using CONTAINS
 		{"account.owner CONTAINS 'Vlad'", 0},
+		// search using EXISTS
+		{"account.owner EXISTS", 1},
+		// search using EXISTS on a not existing tag
+		{"account.date EXISTS", 0},
+		// search using OR
+		{"account.owner = 'Vlad' OR account.number = 1", 1},
+		// search using OR (no match)
+		{"account.owner = 'Vlad' OR account.number = 2", 0},
+		// search using OR and parentheses
+		{"(account.number = 1 OR account.number = 2) AND account.owner = 'Ivan'", 1},
+		// search using OR, both sides matching
+		{"account.number = 1 OR account.owner = 'Ivan'", 1},
 	}
 
 	for _, tc := range testCases {
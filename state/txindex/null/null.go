@@ -5,7 +5,7 @@ import (
 
 	"github.com/tendermint/tendermint/state/txindex"
 	"github.com/tendermint/tendermint/types"
-	"github.com/tendermint/tmlibs/pubsub/query"
+	"github.com/tendermint/tendermint/types/query"
 )
 
 var _ txindex.TxIndexer = (*TxIndex)(nil)
@@ -15,7 +15,7 @@ type TxIndex struct{}
 
 // Get on a TxIndex is disabled and panics when invoked.
 func (txi *TxIndex) Get(hash []byte) (*types.TxResult, error) {
-	return nil, errors.New(`Indexing is disabled (set 'tx_index = "kv"' in config)`)
+	return nil, errors.New(`Indexing is disabled (set 'tx_index.indexer = "kv"' or "psql" in config)`)
 }
 
 // AddBatch is a noop and always returns nil.
@@ -0,0 +1,332 @@
+package psql
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	// registers the "postgres" driver of database/sql
+	_ "github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	abci "github.com/tendermint/abci/types"
+	wire "github.com/tendermint/go-wire"
+	"github.com/tendermint/tendermint/state/txindex"
+	"github.com/tendermint/tendermint/types"
+	"github.com/tendermint/tendermint/types/query"
+	cmn "github.com/tendermint/tmlibs/common"
+)
+
+// DriverName is the name of the database/sql driver to open the database with.
+const DriverName = "postgres"
+
+// Schema is the schema of the tables the txs are indexed into, created by
+// NewTxIndex if they don't exist. Explorers can query them with SQL, eg.
+//
+//	SELECT r.height, r."index", r.hash FROM tx_results r
+//	JOIN tx_tags t ON t.tx_rowid = r.rowid
+//	WHERE t.key = 'account.owner' AND t.value_string = 'Ivan';
+//
+// The hash is the upper-case hex of the hash of the tx, as in the RPC.
+const Schema = `
+CREATE TABLE IF NOT EXISTS tx_results (
+	rowid     BIGSERIAL PRIMARY KEY,
+	height    BIGINT NOT NULL,
+	"index"   INTEGER NOT NULL,
+	hash      VARCHAR NOT NULL,
+	code      BIGINT NOT NULL,
+	data      BYTEA,
+	log       TEXT,
+	tx        BYTEA NOT NULL,
+	tx_result BYTEA NOT NULL, -- go-wire encoded types.TxResult
+	UNIQUE (height, "index")
+);
+CREATE INDEX IF NOT EXISTS tx_results_hash ON tx_results (hash);
+
+CREATE TABLE IF NOT EXISTS tx_tags (
+	tx_rowid     BIGINT NOT NULL REFERENCES tx_results (rowid) ON DELETE CASCADE,
+	key          VARCHAR NOT NULL,
+	value_string VARCHAR,
+	value_int    BIGINT
+);
+CREATE INDEX IF NOT EXISTS tx_tags_string ON tx_tags (key, value_string);
+CREATE INDEX IF NOT EXISTS tx_tags_int ON tx_tags (key, value_int);
+CREATE INDEX IF NOT EXISTS tx_tags_tx ON tx_tags (tx_rowid);
+`
+
+var _ txindex.TxIndexer = (*TxIndex)(nil)
+
+// TxIndex is an indexer backed by PostgreSQL, writing the results of the txs
+// and their tags into the tables of the Schema.
+type TxIndex struct {
+	db           *sql.DB
+	tagsToIndex  []string
+	indexAllTags bool
+}
+
+// NewTxIndex creates a PostgreSQL indexer writing into db, opened with the
+// DriverName, and creates the tables of the Schema if they don't exist.
+func NewTxIndex(db *sql.DB, options ...func(*TxIndex)) (*TxIndex, error) {
+	txi := &TxIndex{db: db, tagsToIndex: make([]string, 0), indexAllTags: false}
+	for _, o := range options {
+		o(txi)
+	}
+	if _, err := db.Exec(Schema); err != nil {
+		return nil, errors.Wrap(err, "failed to create the schema")
+	}
+	return txi, nil
+}
+
+// IndexTags is an option for setting which tags to index.
+func IndexTags(tags []string) func(*TxIndex) {
+	return func(txi *TxIndex) {
+		txi.tagsToIndex = tags
+	}
+}
+
+// IndexAllTags is an option for indexing all tags.
+func IndexAllTags() func(*TxIndex) {
+	return func(txi *TxIndex) {
+		txi.indexAllTags = true
+	}
+}
+
+// Get gets transaction from the database and returns it or nil if the
+// transaction is not found.
+func (txi *TxIndex) Get(hash []byte) (*types.TxResult, error) {
+	if len(hash) == 0 {
+		return nil, txindex.ErrorEmptyHash
+	}
+
+	var rawBytes []byte
+	err := txi.db.QueryRow(`SELECT tx_result FROM tx_results WHERE hash = $1 ORDER BY height DESC LIMIT 1`,
+		fmt.Sprintf("%X", hash)).Scan(&rawBytes)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "failed to get Tx{%X}", hash)
+	}
+	return decodeTxResult(rawBytes)
+}
+
+// AddBatch indexes a batch of transactions, in a single database transaction.
+func (txi *TxIndex) AddBatch(b *txindex.Batch) error {
+	return txi.inTx(func(dbTx *sql.Tx) error {
+		for _, result := range b.Ops {
+			if err := txi.insert(dbTx, result); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Index indexes a single transaction.
+func (txi *TxIndex) Index(result *types.TxResult) error {
+	return txi.inTx(func(dbTx *sql.Tx) error {
+		return txi.insert(dbTx, result)
+	})
+}
+
+func (txi *TxIndex) inTx(f func(*sql.Tx) error) error {
+	dbTx, err := txi.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "failed to begin a database transaction")
+	}
+	if err := f(dbTx); err != nil {
+		dbTx.Rollback() // nolint: errcheck
+		return err
+	}
+	return dbTx.Commit()
+}
+
+// insert indexes the result, replacing the one at the same height and index, if any.
+func (txi *TxIndex) insert(dbTx *sql.Tx, result *types.TxResult) error {
+	_, err := dbTx.Exec(`DELETE FROM tx_results WHERE height = $1 AND "index" = $2`,
+		result.Height, result.Index)
+	if err != nil {
+		return errors.Wrap(err, "failed to replace the indexed tx")
+	}
+
+	var rowid int64
+	err = dbTx.QueryRow(`INSERT INTO tx_results (height, "index", hash, code, data, log, tx, tx_result)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING rowid`,
+		result.Height, result.Index, fmt.Sprintf("%X", result.Tx.Hash()), result.Result.Code,
+		result.Result.Data, result.Result.Log, []byte(result.Tx), wire.BinaryBytes(result),
+	).Scan(&rowid)
+	if err != nil {
+		return errors.Wrap(err, "failed to index the tx")
+	}
+
+	for _, tag := range result.Result.Tags {
+		if !txi.indexAllTags && !cmn.StringInSlice(tag.Key, txi.tagsToIndex) {
+			continue
+		}
+		var valueString, valueInt interface{}
+		switch tag.ValueType {
+		case abci.KVPair_STRING:
+			valueString = tag.ValueString
+		case abci.KVPair_INT:
+			valueInt = tag.ValueInt
+		default:
+			return fmt.Errorf("Undefined value type: %v", tag.ValueType)
+		}
+		_, err := dbTx.Exec(`INSERT INTO tx_tags (tx_rowid, key, value_string, value_int) VALUES ($1, $2, $3, $4)`,
+			rowid, tag.Key, valueString, valueInt)
+		if err != nil {
+			return errors.Wrapf(err, "failed to index the tag %s", tag.Key)
+		}
+	}
+	return nil
+}
+
+// Search performs a search using the given query, translated into SQL: the
+// conditions on "tx.hash" and "tx.height" apply to the txs, the other ones to
+// their tags. The results are ordered by height and index.
+func (txi *TxIndex) Search(q *query.Query) ([]*types.TxResult, error) {
+	stmt, args, err := searchSQL(q.Conjunctions())
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := txi.db.Query(stmt, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to search the txs")
+	}
+	defer rows.Close() // nolint: errcheck
+
+	results := make([]*types.TxResult, 0)
+	for rows.Next() {
+		var rawBytes []byte
+		if err := rows.Scan(&rawBytes); err != nil {
+			return nil, errors.Wrap(err, "failed to read a tx")
+		}
+		result, err := decodeTxResult(rawBytes)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// searchSQL returns the statement, and its args, selecting the txs matching all
+// the conditions of any of the conjunctions.
+func searchSQL(conjunctions [][]query.Condition) (string, []interface{}, error) {
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	var or []string
+	for _, conditions := range conjunctions {
+		var where []string
+		for _, c := range conditions {
+			cond, err := conditionSQL(c, arg)
+			if err != nil {
+				return "", nil, err
+			}
+			where = append(where, cond)
+		}
+		if len(where) > 0 {
+			or = append(or, "("+strings.Join(where, " AND ")+")")
+		}
+	}
+
+	stmt := "SELECT r.tx_result FROM tx_results r"
+	if len(or) > 0 {
+		stmt += " WHERE " + strings.Join(or, " OR ")
+	}
+	stmt += ` ORDER BY r.height, r."index"`
+	return stmt, args, nil
+}
+
+// conditionSQL returns the SQL of the condition, adding its args with arg.
+func conditionSQL(c query.Condition, arg func(v interface{}) string) (string, error) {
+	switch c.Tag {
+	case types.TxHashKey, types.TxHeightKey:
+		if c.Op == query.OpExists {
+			// every tx has a hash and a height
+			return "TRUE", nil
+		}
+		if c.Tag == types.TxHashKey {
+			hash, ok := c.Operand.(string)
+			if !ok || c.Op != query.OpEqual {
+				return "", fmt.Errorf("%s must be equal to a hex string", types.TxHashKey)
+			}
+			return "r.hash = " + arg(strings.ToUpper(hash)), nil
+		}
+		height, ok := c.Operand.(int64)
+		if !ok {
+			return "", fmt.Errorf("%s must be compared to a number", types.TxHeightKey)
+		}
+		op, err := sqlOperator(c.Op)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("r.height %s %s", op, arg(height)), nil
+	}
+
+	tagSQL := "EXISTS (SELECT 1 FROM tx_tags t WHERE t.tx_rowid = r.rowid AND t.key = " + arg(c.Tag)
+	if c.Op == query.OpExists {
+		return tagSQL + ")", nil
+	}
+
+	var column, cond string
+	switch operand := c.Operand.(type) {
+	case string:
+		column = "value_string"
+		if c.Op == query.OpContains {
+			cond = fmt.Sprintf("t.%s LIKE '%%' || %s || '%%'", column, arg(escapeLike(operand)))
+		}
+	case int64:
+		column = "value_int"
+	case float64:
+		column = "value_int"
+	default:
+		// XXX: passing time in a ABCI Tags is not yet implemented
+		return "", fmt.Errorf("Unsupported operand for %s: %v", c.Tag, c.Operand)
+	}
+	if cond == "" {
+		op, err := sqlOperator(c.Op)
+		if err != nil {
+			return "", err
+		}
+		cond = fmt.Sprintf("t.%s %s %s", column, op, arg(c.Operand))
+	}
+	return tagSQL + " AND " + cond + ")", nil
+}
+
+func sqlOperator(op query.Operator) (string, error) {
+	switch op {
+	case query.OpEqual:
+		return "=", nil
+	case query.OpLess:
+		return "<", nil
+	case query.OpLessEqual:
+		return "<=", nil
+	case query.OpGreater:
+		return ">", nil
+	case query.OpGreaterEqual:
+		return ">=", nil
+	}
+	return "", fmt.Errorf("Unsupported operator %v", op)
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func decodeTxResult(rawBytes []byte) (*types.TxResult, error) {
+	r := bytes.NewReader(rawBytes)
+	var n int
+	var err error
+	txResult := wire.ReadBinary(&types.TxResult{}, r, 0, &n, &err).(*types.TxResult)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading TxResult: %v", err)
+	}
+	return txResult, nil
+}
@@ -0,0 +1,163 @@
+package statesync
+
+import (
+	"errors"
+	"fmt"
+
+	abci "github.com/tendermint/abci/types"
+	wire "github.com/tendermint/go-wire"
+	"github.com/tendermint/go-wire/data"
+
+	"github.com/tendermint/tendermint/proxy"
+)
+
+var (
+	// ErrRejectFormat is returned by OfferSnapshot if the app doesn't restore
+	// the format of the snapshot.
+	ErrRejectFormat = errors.New("Snapshot format rejected")
+	// ErrRejectChunk is returned by ApplySnapshotChunk if the chunk is invalid,
+	// to get it from another peer.
+	ErrRejectChunk = errors.New("Chunk rejected")
+)
+
+// App serves the snapshots of its state to the peers, and restores one of
+// theirs on a new node.
+type App interface {
+	// ListSnapshots returns the snapshots of the app, eg. of the last heights.
+	ListSnapshots() ([]*Snapshot, error)
+
+	// LoadSnapshotChunk returns the chunk at the index of the snapshot.
+	LoadSnapshotChunk(height int64, format uint32, index uint32) ([]byte, error)
+
+	// OfferSnapshot offers the snapshot to restore, which must restore the app
+	// hash. It returns ErrRejectFormat if the app doesn't restore the format,
+	// or another error to reject the snapshot.
+	OfferSnapshot(snapshot *Snapshot, appHash []byte) error
+
+	// ApplySnapshotChunk applies the chunks of the snapshot offered, in order.
+	// It returns ErrRejectChunk to get the chunk from another peer, or another
+	// error to give up on the snapshot.
+	ApplySnapshotChunk(index uint32, chunk []byte) error
+}
+
+//-----------------------------------------------------------------------------
+
+// The query paths of the snapshot handshake, see NewQueryApp.
+const (
+	QueryPathListSnapshots = "/snapshots/list"
+	QueryPathLoadChunk     = "/snapshots/load_chunk"
+	QueryPathOffer         = "/snapshots/offer"
+	QueryPathApplyChunk    = "/snapshots/apply_chunk"
+)
+
+// The codes of the responses to the queries of QueryPathOffer and
+// QueryPathApplyChunk for ErrRejectFormat and ErrRejectChunk. Any other code
+// but OK rejects the snapshot.
+const (
+	CodeRejectFormat = uint32(2)
+	CodeRejectChunk  = uint32(3)
+)
+
+// LoadChunkRequest is the Data of the QueryPathLoadChunk queries.
+type LoadChunkRequest struct {
+	Height int64
+	Format uint32
+	Index  uint32
+}
+
+// OfferRequest is the Data of the QueryPathOffer queries.
+type OfferRequest struct {
+	Snapshot *Snapshot
+	AppHash  data.Bytes
+}
+
+// ApplyChunkRequest is the Data of the QueryPathApplyChunk queries.
+type ApplyChunkRequest struct {
+	Index uint32
+	Chunk data.Bytes
+}
+
+type queryApp struct {
+	conn proxy.AppConnQuery
+}
+
+// NewQueryApp returns the App of the snapshot handshake over the queries of
+// the app, as abci has no methods for it yet. The app answers the queries of
+// the paths:
+//
+//   - QueryPathListSnapshots with the []*Snapshot in the Value.
+//   - QueryPathLoadChunk of a LoadChunkRequest with the chunk in the Value.
+//   - QueryPathOffer of an OfferRequest with the OK code to accept the
+//     snapshot, and QueryPathApplyChunk of an ApplyChunkRequest with the OK
+//     code once the chunk is applied, or CodeRejectFormat and CodeRejectChunk.
+//
+// The Data of the requests and the Value of the responses are go-wire
+// encoded. A node serves the snapshots only if the app lists some.
+func NewQueryApp(conn proxy.AppConnQuery) App {
+	return queryApp{conn}
+}
+
+func (app queryApp) query(path string, req interface{}) (*abci.ResponseQuery, error) {
+	var bz []byte
+	if req != nil {
+		bz = wire.BinaryBytes(req)
+	}
+	return app.conn.QuerySync(abci.RequestQuery{Path: path, Data: bz})
+}
+
+func (app queryApp) ListSnapshots() ([]*Snapshot, error) {
+	res, err := app.query(QueryPathListSnapshots, nil)
+	if err != nil {
+		return nil, err
+	}
+	if res.IsErr() || len(res.Value) == 0 {
+		// the app doesn't take snapshots
+		return nil, nil
+	}
+	var snapshots []*Snapshot
+	if err := wire.ReadBinaryBytes(res.Value, &snapshots); err != nil {
+		return nil, fmt.Errorf("Error decoding the snapshots: %v", err)
+	}
+	return snapshots, nil
+}
+
+func (app queryApp) LoadSnapshotChunk(height int64, format uint32, index uint32) ([]byte, error) {
+	res, err := app.query(QueryPathLoadChunk, LoadChunkRequest{height, format, index})
+	if err != nil {
+		return nil, err
+	}
+	if res.IsErr() {
+		return nil, fmt.Errorf("Error loading the chunk: %v", res.Log)
+	}
+	return res.Value, nil
+}
+
+func (app queryApp) OfferSnapshot(snapshot *Snapshot, appHash []byte) error {
+	res, err := app.query(QueryPathOffer, OfferRequest{snapshot, appHash})
+	if err != nil {
+		return err
+	}
+	switch {
+	case !res.IsErr():
+		return nil
+	case res.Code == CodeRejectFormat:
+		return ErrRejectFormat
+	default:
+		return fmt.Errorf("Snapshot rejected: %v", res.Log)
+	}
+}
+
+func (app queryApp) ApplySnapshotChunk(index uint32, chunk []byte) error {
+	res, err := app.query(QueryPathApplyChunk, ApplyChunkRequest{index, chunk})
+	if err != nil {
+		return err
+	}
+	switch {
+	case !res.IsErr():
+		return nil
+	case res.Code == CodeRejectChunk:
+		return ErrRejectChunk
+	default:
+		return fmt.Errorf("Error applying chunk %d: %v", index, res.Log)
+	}
+}
@@ -0,0 +1,153 @@
+package statesync
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	errDone    = errors.New("All the chunks are allocated")
+	errNoChunk = errors.New("No chunk to allocate")
+)
+
+// chunkQueue holds the chunks of the snapshot being restored, as they are
+// fetched from the peers, until they are applied in order.
+type chunkQueue struct {
+	mtx       sync.Mutex
+	snapshot  *Snapshot
+	chunks    map[uint32][]byte
+	senders   map[uint32]string
+	allocated map[uint32]bool
+	received  map[uint32]chan struct{} // closed once the chunk is received
+	applied   uint32                   // the chunks before are applied
+	closed    bool
+}
+
+func newChunkQueue(snapshot *Snapshot) *chunkQueue {
+	return &chunkQueue{
+		snapshot:  snapshot,
+		chunks:    make(map[uint32][]byte),
+		senders:   make(map[uint32]string),
+		allocated: make(map[uint32]bool),
+		received:  make(map[uint32]chan struct{}),
+	}
+}
+
+// Allocate returns the index of the next chunk to fetch, which is allocated to
+// the caller until received or Deallocate. It returns errNoChunk if all the
+// chunks left are allocated, and errDone once the queue is closed.
+func (q *chunkQueue) Allocate() (uint32, error) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if q.closed {
+		return 0, errDone
+	}
+	for index := q.applied; index < q.snapshot.Chunks; index++ {
+		if _, ok := q.chunks[index]; !ok && !q.allocated[index] {
+			q.allocated[index] = true
+			return index, nil
+		}
+	}
+	return 0, errNoChunk
+}
+
+// Deallocate gives up on fetching the chunk, for another fetcher to try.
+func (q *chunkQueue) Deallocate(index uint32) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	delete(q.allocated, index)
+}
+
+// Add adds the chunk sent by the peer. It returns false if the chunk is not
+// of the snapshot, already received or applied.
+func (q *chunkQueue) Add(index uint32, chunk []byte, peerKey string) bool {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if q.closed || index >= q.snapshot.Chunks || index < q.applied {
+		return false
+	}
+	if _, ok := q.chunks[index]; ok {
+		return false
+	}
+	q.chunks[index] = chunk
+	q.senders[index] = peerKey
+	delete(q.allocated, index)
+	close(q.receivedCh(index))
+	return true
+}
+
+// Received returns a channel closed once the chunk is received, or the queue
+// closed.
+func (q *chunkQueue) Received(index uint32) <-chan struct{} {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	return q.receivedCh(index)
+}
+
+// CONTRACT: q.mtx is held.
+func (q *chunkQueue) receivedCh(index uint32) chan struct{} {
+	ch, ok := q.received[index]
+	if !ok {
+		ch = make(chan struct{})
+		if q.closed {
+			close(ch)
+		}
+		q.received[index] = ch
+	}
+	return ch
+}
+
+// Get returns the chunk, if received, and the key of the peer which sent it.
+func (q *chunkQueue) Get(index uint32) (chunk []byte, peerKey string, ok bool) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	chunk, ok = q.chunks[index]
+	return chunk, q.senders[index], ok
+}
+
+// Discard discards the chunk, eg. rejected by the app, to fetch it again.
+func (q *chunkQueue) Discard(index uint32) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if _, ok := q.chunks[index]; !ok {
+		return
+	}
+	delete(q.chunks, index)
+	delete(q.senders, index)
+	delete(q.received, index)
+}
+
+// MarkApplied frees the chunk, applied by the app. The chunks must be applied
+// in order.
+func (q *chunkQueue) MarkApplied(index uint32) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	delete(q.chunks, index)
+	delete(q.senders, index)
+	delete(q.received, index)
+	q.applied = index + 1
+}
+
+// Close stops the fetchers, and wakes up the ones waiting for a chunk.
+func (q *chunkQueue) Close() {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if q.closed {
+		return
+	}
+	q.closed = true
+	for index, ch := range q.received {
+		if _, ok := q.chunks[index]; !ok {
+			close(ch)
+		}
+	}
+	q.chunks = nil
+}
@@ -0,0 +1,120 @@
+package statesync
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	wire "github.com/tendermint/go-wire"
+	"github.com/tendermint/go-wire/data"
+	cmn "github.com/tendermint/tmlibs/common"
+)
+
+const (
+	// SnapshotChannel is a channel for the snapshots offered by the peers.
+	SnapshotChannel = byte(0x60)
+	// ChunkChannel is a channel for the chunks of the snapshots.
+	ChunkChannel = byte(0x61)
+
+	// the max number of snapshots offered to a peer
+	recentSnapshots = 10
+	// the max size of a chunk
+	maxChunkSize = 16 * 1024 * 1024
+	// the max size of a message of the SnapshotChannel
+	maxSnapshotMsgSize = 4 * 1024 * 1024
+)
+
+//-----------------------------------------------------------------------------
+// Messages
+
+const (
+	msgTypeSnapshotsRequest  = byte(0x01)
+	msgTypeSnapshotsResponse = byte(0x02)
+	msgTypeChunkRequest      = byte(0x10)
+	msgTypeChunkResponse     = byte(0x11)
+)
+
+// StateSyncMessage is a generic message for this reactor.
+type StateSyncMessage interface{}
+
+var _ = wire.RegisterInterface(
+	struct{ StateSyncMessage }{},
+	wire.ConcreteType{&snapshotsRequestMessage{}, msgTypeSnapshotsRequest},
+	wire.ConcreteType{&snapshotsResponseMessage{}, msgTypeSnapshotsResponse},
+	wire.ConcreteType{&chunkRequestMessage{}, msgTypeChunkRequest},
+	wire.ConcreteType{&chunkResponseMessage{}, msgTypeChunkResponse},
+)
+
+// DecodeMessage decodes StateSyncMessage.
+func DecodeMessage(bz []byte, maxSize int) (msgType byte, msg StateSyncMessage, err error) {
+	if len(bz) == 0 {
+		return 0, nil, errors.New("DecodeMessage() got an empty message")
+	}
+	msgType = bz[0]
+	n := int(0)
+	r := bytes.NewReader(bz)
+	msg = wire.ReadBinary(struct{ StateSyncMessage }{}, r, maxSize, &n, &err).(struct{ StateSyncMessage }).StateSyncMessage
+	if err == nil && n != len(bz) {
+		err = errors.New("DecodeMessage() had bytes left over")
+	}
+	return
+}
+
+//-------------------------------------
+
+// snapshotsRequestMessage asks a peer for its snapshots.
+type snapshotsRequestMessage struct{}
+
+func (m *snapshotsRequestMessage) String() string {
+	return "[snapshotsRequestMessage]"
+}
+
+// snapshotsResponseMessage offers a snapshot, one per message.
+type snapshotsResponseMessage struct {
+	Snapshot *Snapshot
+}
+
+func (m *snapshotsResponseMessage) String() string {
+	return cmn.Fmt("[snapshotsResponseMessage %v]", m.Snapshot)
+}
+
+// chunkRequestMessage asks a peer for the chunk of a snapshot.
+type chunkRequestMessage struct {
+	Height int64
+	Format uint32
+	Index  uint32
+}
+
+func (m *chunkRequestMessage) String() string {
+	return cmn.Fmt("[chunkRequestMessage %v/%v/%v]", m.Height, m.Format, m.Index)
+}
+
+// chunkResponseMessage answers a chunkRequestMessage, Missing if the peer
+// doesn't have the chunk.
+type chunkResponseMessage struct {
+	Height  int64
+	Format  uint32
+	Index   uint32
+	Chunk   data.Bytes
+	Missing bool
+}
+
+func (m *chunkResponseMessage) String() string {
+	return cmn.Fmt("[chunkResponseMessage %v/%v/%v %v bytes missing:%v]", m.Height, m.Format, m.Index, len(m.Chunk), m.Missing)
+}
+
+// validateSnapshot returns an error if the snapshot offered by a peer is
+// invalid.
+func validateSnapshot(s *Snapshot) error {
+	switch {
+	case s == nil:
+		return errors.New("Missing snapshot")
+	case s.Height <= 0:
+		return fmt.Errorf("Invalid snapshot height %d", s.Height)
+	case s.Chunks == 0:
+		return errors.New("Snapshot has no chunks")
+	case len(s.Hash) == 0:
+		return errors.New("Snapshot has no hash")
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package statesync
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	cfg "github.com/tendermint/tendermint/config"
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/proxy"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+)
+
+// Reactor serves the snapshots of the app and their chunks to the peers and,
+// on a new node, restores one of theirs with Sync.
+type Reactor struct {
+	p2p.BaseReactor
+
+	config   *cfg.StateSyncConfig
+	app      App
+	appQuery proxy.AppConnQuery
+
+	mtx    sync.RWMutex
+	syncer *syncer // while syncing
+	pool   *snapshotPool
+}
+
+// NewReactor returns a new Reactor serving the snapshots of the app.
+func NewReactor(config *cfg.StateSyncConfig, app App, appQuery proxy.AppConnQuery) *Reactor {
+	r := &Reactor{
+		config:   config,
+		app:      app,
+		appQuery: appQuery,
+	}
+	r.BaseReactor = *p2p.NewBaseReactor("StateSyncReactor", r)
+	return r
+}
+
+// GetChannels implements Reactor.
+func (r *Reactor) GetChannels() []*p2p.ChannelDescriptor {
+	return []*p2p.ChannelDescriptor{
+		{
+			ID:                  SnapshotChannel,
+			Priority:            3,
+			SendQueueCapacity:   10,
+			RecvMessageCapacity: maxSnapshotMsgSize,
+		},
+		{
+			ID:                  ChunkChannel,
+			Priority:            1,
+			SendQueueCapacity:   4,
+			RecvMessageCapacity: maxChunkSize + 1024,
+		},
+	}
+}
+
+// AddPeer implements Reactor by asking the peer for its snapshots, while
+// syncing.
+func (r *Reactor) AddPeer(peer p2p.Peer) {
+	if r.syncing() {
+		peer.TrySend(SnapshotChannel, struct{ StateSyncMessage }{&snapshotsRequestMessage{}})
+	}
+}
+
+// RemovePeer implements Reactor by forgetting the snapshots of the peer.
+func (r *Reactor) RemovePeer(peer p2p.Peer, reason interface{}) {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	if r.pool != nil {
+		r.pool.RemovePeer(peer.Key())
+	}
+}
+
+// Receive implements Reactor.
+func (r *Reactor) Receive(chID byte, src p2p.Peer, msgBytes []byte) {
+	maxSize := maxSnapshotMsgSize
+	if chID == ChunkChannel {
+		maxSize = maxChunkSize + 1024
+	}
+	_, msg, err := DecodeMessage(msgBytes, maxSize)
+	if err != nil {
+		r.Logger.Error("Error decoding message", "src", src, "chId", chID, "msg", msg, "err", err, "bytes", msgBytes)
+		r.Switch.ReportMisbehavior(src, p2p.SeverityMedium, err)
+		return
+	}
+	r.Logger.Debug("Receive", "src", src, "chID", chID, "msg", msg)
+
+	switch msg := msg.(type) {
+	case *snapshotsRequestMessage:
+		r.respondSnapshots(src)
+
+	case *snapshotsResponseMessage:
+		if err := validateSnapshot(msg.Snapshot); err != nil {
+			r.Switch.ReportMisbehavior(src, p2p.SeverityMedium, fmt.Errorf("Invalid snapshot: %v", err))
+			return
+		}
+		r.mtx.RLock()
+		pool := r.pool
+		r.mtx.RUnlock()
+		if pool != nil && pool.Add(src, msg.Snapshot) {
+			r.Logger.Info("Discovered snapshot", "snapshot", msg.Snapshot, "peer", src.Key())
+		}
+
+	case *chunkRequestMessage:
+		chunk, err := r.app.LoadSnapshotChunk(msg.Height, msg.Format, msg.Index)
+		if err != nil {
+			r.Logger.Debug("Error loading chunk", "msg", msg, "err", err)
+		}
+		src.TrySend(ChunkChannel, struct{ StateSyncMessage }{&chunkResponseMessage{
+			Height:  msg.Height,
+			Format:  msg.Format,
+			Index:   msg.Index,
+			Chunk:   chunk,
+			Missing: err != nil || len(chunk) == 0,
+		}})
+
+	case *chunkResponseMessage:
+		r.mtx.RLock()
+		syncer := r.syncer
+		r.mtx.RUnlock()
+		if syncer != nil {
+			syncer.AddChunk(src, msg)
+		}
+
+	default:
+		r.Logger.Error(fmt.Sprintf("Unknown message type %v", reflect.TypeOf(msg)))
+	}
+}
+
+// respondSnapshots offers the recent snapshots of the app to the peer, the
+// highest first.
+func (r *Reactor) respondSnapshots(peer p2p.Peer) {
+	snapshots, err := r.app.ListSnapshots()
+	if err != nil {
+		r.Logger.Error("Error listing the snapshots", "err", err)
+		return
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Height > snapshots[j].Height
+	})
+	if len(snapshots) > recentSnapshots {
+		snapshots = snapshots[:recentSnapshots]
+	}
+	for _, snapshot := range snapshots {
+		peer.TrySend(SnapshotChannel, struct{ StateSyncMessage }{&snapshotsResponseMessage{snapshot}})
+	}
+}
+
+func (r *Reactor) syncing() bool {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return r.syncer != nil
+}
+
+// requestChunk asks the peer for the chunk of the snapshot.
+func (r *Reactor) requestChunk(peer p2p.Peer, snapshot *Snapshot, index uint32) bool {
+	msg := &chunkRequestMessage{Height: snapshot.Height, Format: snapshot.Format, Index: index}
+	return peer.TrySend(ChunkChannel, struct{ StateSyncMessage }{msg})
+}
+
+// Sync restores the app from a snapshot of the peers, discovered for
+// discoveryTime first, verified with the StateProvider. It returns the state
+// and the signed header of the height of the snapshot, to bootstrap the node
+// from, or ErrAborted if the reactor is stopped.
+func (r *Reactor) Sync(stateProvider StateProvider, discoveryTime time.Duration) (*sm.State, types.SignedHeader, error) {
+	r.mtx.Lock()
+	if r.syncer != nil {
+		r.mtx.Unlock()
+		return nil, types.SignedHeader{}, errors.New("A state sync is already running")
+	}
+	r.pool = newSnapshotPool()
+	r.syncer = newSyncer(r.Logger, r.app, r.appQuery, stateProvider, r.pool,
+		r.config.ChunkFetchers, r.config.ChunkTimeout(), r.Quit)
+	r.syncer.requestChunk = r.requestChunk
+	syncer := r.syncer
+	r.mtx.Unlock()
+
+	defer func() {
+		r.mtx.Lock()
+		r.syncer = nil
+		r.pool = nil
+		r.mtx.Unlock()
+	}()
+
+	discover := func() {
+		r.Switch.Broadcast(SnapshotChannel, struct{ StateSyncMessage }{&snapshotsRequestMessage{}})
+	}
+	return syncer.SyncAny(discoveryTime, discover)
+}
@@ -0,0 +1,155 @@
+/*
+Package rpcprovider provides the states to state sync from, verified against
+RPC servers with a light client. It's apart from the statesync package, which
+the node imports, as the RPC client imports the node.
+*/
+package rpcprovider
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/pkg/errors"
+	wire "github.com/tendermint/go-wire"
+
+	"github.com/tendermint/tendermint/lite"
+	liteclient "github.com/tendermint/tendermint/lite/client"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/statesync"
+	"github.com/tendermint/tendermint/types"
+)
+
+type lightStateProvider struct {
+	verifier *lite.Verifier
+	clients  []liteclient.SignStatusClient
+	initial  *sm.State
+}
+
+// New returns a StateProvider verifying the headers of the servers with a
+// light client, from the trusted header at trustHeight of trustHash. The
+// consensus params, which the headers don't commit to, must be the same on
+// all the servers. The states are built upon the initial state, of the
+// genesis.
+func New(initial *sm.State, servers []string, trustHeight int64, trustHash []byte) (statesync.StateProvider, error) {
+	if len(servers) == 0 {
+		return nil, errors.New("No RPC servers to state sync from")
+	}
+	clients := make([]liteclient.SignStatusClient, len(servers))
+	for i, server := range servers {
+		if !strings.Contains(server, "://") {
+			server = "tcp://" + server
+		}
+		clients[i] = rpcclient.NewHTTP(server, "/websocket")
+	}
+	return newLightStateProvider(initial, clients, trustHeight, trustHash)
+}
+
+func newLightStateProvider(initial *sm.State, clients []liteclient.SignStatusClient, trustHeight int64,
+	trustHash []byte) (*lightStateProvider, error) {
+
+	source := liteclient.NewProvider(clients[0])
+	root, err := source.GetByHeight(trustHeight)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error getting the trusted header %d", trustHeight)
+	}
+	if root.Height() != trustHeight || !bytes.Equal(root.Header.Hash(), trustHash) {
+		return nil, errors.Errorf("Header %d is not the trusted one of hash %X", trustHeight, trustHash)
+	}
+	verifier, err := lite.InitVerifier(initial.ChainID, root, lite.NewMemStoreProvider(), source)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error verifying the trusted header")
+	}
+	return &lightStateProvider{
+		verifier: verifier,
+		clients:  clients,
+		initial:  initial,
+	}, nil
+}
+
+// verify returns the verified FullCommit at height, the same on all the
+// servers.
+func (p *lightStateProvider) verify(height int64) (lite.FullCommit, error) {
+	fc, err := p.verifier.VerifyHeight(height)
+	if err != nil {
+		return fc, errors.Wrapf(err, "Error verifying header %d", height)
+	}
+	for _, client := range p.clients[1:] {
+		commit, err := client.Commit(&height)
+		if err != nil {
+			return fc, errors.Wrapf(err, "Error getting header %d", height)
+		}
+		if !bytes.Equal(commit.Header.Hash(), fc.Header.Hash()) {
+			return fc, errors.Errorf("The RPC servers disagree on header %d", height)
+		}
+	}
+	return fc, nil
+}
+
+// AppHash implements statesync.StateProvider. The app hash after the block at
+// height is the one of the header of the next block.
+func (p *lightStateProvider) AppHash(height int64) ([]byte, error) {
+	fc, err := p.verify(height + 1)
+	if err != nil {
+		return nil, err
+	}
+	return fc.Header.AppHash, nil
+}
+
+// SignedHeader implements statesync.StateProvider.
+func (p *lightStateProvider) SignedHeader(height int64) (types.SignedHeader, error) {
+	fc, err := p.verify(height)
+	if err != nil {
+		return types.SignedHeader{}, err
+	}
+	return types.SignedHeader(fc.Commit), nil
+}
+
+// State implements statesync.StateProvider.
+func (p *lightStateProvider) State(height int64) (*sm.State, error) {
+	last, err := p.verify(height)
+	if err != nil {
+		return nil, err
+	}
+	next, err := p.verify(height + 1)
+	if err != nil {
+		return nil, err
+	}
+	params, err := p.consensusParams(height + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	state := p.initial.Copy()
+	state.LastBlockHeight = height
+	state.LastBlockID = next.Header.LastBlockID
+	state.LastBlockTime = last.Header.Time
+	state.LastValidators = last.Validators
+	state.Validators = next.Validators
+	state.LastHeightValidatorsChanged = height + 1
+	state.AppHash = next.Header.AppHash
+	state.LastResultsHash = next.Header.LastResultsHash
+	state.Params = params
+	state.LastHeightConsensusParamsChanged = height + 1
+	return state, nil
+}
+
+// consensusParams returns the consensus params of the block at height, the
+// same on all the servers.
+func (p *lightStateProvider) consensusParams(height int64) (types.ConsensusParams, error) {
+	var params types.ConsensusParams
+	for i, client := range p.clients {
+		res, err := client.ConsensusParams(&height)
+		if err != nil {
+			return params, errors.Wrapf(err, "Error getting the consensus params %d", height)
+		}
+		if i > 0 && !bytes.Equal(wire.BinaryBytes(params), wire.BinaryBytes(res.ConsensusParams)) {
+			return params, errors.Errorf("The RPC servers disagree on the consensus params %d", height)
+		}
+		params = res.ConsensusParams
+	}
+	if err := params.Validate(); err != nil {
+		return params, errors.Wrapf(err, "Invalid consensus params %d", height)
+	}
+	return params, nil
+}
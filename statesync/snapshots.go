@@ -0,0 +1,180 @@
+package statesync
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/tendermint/go-wire/data"
+
+	"github.com/tendermint/tendermint/p2p"
+	cmn "github.com/tendermint/tmlibs/common"
+)
+
+// Snapshot is a snapshot of the app state after the block at Height, in
+// Chunks chunks of the app specific Format. The app identifies it with the
+// Hash, and can add Metadata, eg. the hashes of the chunks.
+type Snapshot struct {
+	Height   int64      `json:"height"`
+	Format   uint32     `json:"format"`
+	Chunks   uint32     `json:"chunks"`
+	Hash     data.Bytes `json:"hash"`
+	Metadata data.Bytes `json:"metadata"`
+}
+
+// snapshotKey identifies a snapshot, by all of its fields but the metadata.
+type snapshotKey string
+
+func (s *Snapshot) key() snapshotKey {
+	return snapshotKey(fmt.Sprintf("%d/%d/%d/%X", s.Height, s.Format, s.Chunks, s.Hash))
+}
+
+// String returns a string representation of the snapshot.
+func (s *Snapshot) String() string {
+	return fmt.Sprintf("Snapshot{H:%d F:%d C:%d %X}", s.Height, s.Format, s.Chunks, cmn.Fingerprint(s.Hash))
+}
+
+//-----------------------------------------------------------------------------
+
+// snapshotPool holds the snapshots offered by the peers, and which peers
+// offer each one. The rejected snapshots, formats and peers are ignored.
+type snapshotPool struct {
+	mtx             sync.Mutex
+	snapshots       map[snapshotKey]*Snapshot
+	snapshotPeers   map[snapshotKey]map[string]p2p.Peer
+	rejected        map[snapshotKey]bool
+	rejectedFormats map[uint32]bool
+	rejectedPeers   map[string]bool
+}
+
+func newSnapshotPool() *snapshotPool {
+	return &snapshotPool{
+		snapshots:       make(map[snapshotKey]*Snapshot),
+		snapshotPeers:   make(map[snapshotKey]map[string]p2p.Peer),
+		rejected:        make(map[snapshotKey]bool),
+		rejectedFormats: make(map[uint32]bool),
+		rejectedPeers:   make(map[string]bool),
+	}
+}
+
+// Add adds the snapshot offered by the peer. It returns true if the snapshot
+// is new, false if it's known or rejected.
+func (p *snapshotPool) Add(peer p2p.Peer, snapshot *Snapshot) bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	key := snapshot.key()
+	if p.rejected[key] || p.rejectedFormats[snapshot.Format] || p.rejectedPeers[peer.Key()] {
+		return false
+	}
+	if p.snapshotPeers[key] == nil {
+		p.snapshotPeers[key] = make(map[string]p2p.Peer)
+	}
+	p.snapshotPeers[key][peer.Key()] = peer
+	if _, ok := p.snapshots[key]; ok {
+		return false
+	}
+	p.snapshots[key] = snapshot
+	return true
+}
+
+// Best returns the snapshot to restore, or nil if there is none: the highest
+// one, then the one of the highest format, then the one of the most peers.
+func (p *snapshotPool) Best() *Snapshot {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	candidates := make([]*Snapshot, 0, len(p.snapshots))
+	for _, snapshot := range p.snapshots {
+		candidates = append(candidates, snapshot)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		switch {
+		case a.Height != b.Height:
+			return a.Height > b.Height
+		case a.Format != b.Format:
+			return a.Format > b.Format
+		default:
+			return len(p.snapshotPeers[a.key()]) > len(p.snapshotPeers[b.key()])
+		}
+	})
+	return candidates[0]
+}
+
+// Peers returns the peers offering the snapshot, in random order.
+func (p *snapshotPool) Peers(snapshot *Snapshot) []p2p.Peer {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	peers := make([]p2p.Peer, 0, len(p.snapshotPeers[snapshot.key()]))
+	for _, peer := range p.snapshotPeers[snapshot.key()] {
+		peers = append(peers, peer)
+	}
+	shuffled := make([]p2p.Peer, len(peers))
+	for i, j := range cmn.RandPerm(len(peers)) {
+		shuffled[i] = peers[j]
+	}
+	return shuffled
+}
+
+// Reject rejects the snapshot, eg. refused by the app.
+func (p *snapshotPool) Reject(snapshot *Snapshot) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	key := snapshot.key()
+	p.rejected[key] = true
+	p.removeSnapshot(key)
+}
+
+// RejectFormat rejects the snapshots of the format, which the app doesn't
+// restore.
+func (p *snapshotPool) RejectFormat(format uint32) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.rejectedFormats[format] = true
+	for key, snapshot := range p.snapshots {
+		if snapshot.Format == format {
+			p.removeSnapshot(key)
+		}
+	}
+}
+
+// RejectPeer rejects the peer, eg. which sent a chunk the app refused, and
+// forgets its snapshots.
+func (p *snapshotPool) RejectPeer(peerKey string) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.rejectedPeers[peerKey] = true
+	p.removePeer(peerKey)
+}
+
+// RemovePeer forgets the snapshots of the peer, eg. disconnected.
+func (p *snapshotPool) RemovePeer(peerKey string) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	p.removePeer(peerKey)
+}
+
+// CONTRACT: p.mtx is held.
+func (p *snapshotPool) removePeer(peerKey string) {
+	for key, peers := range p.snapshotPeers {
+		delete(peers, peerKey)
+		if len(peers) == 0 {
+			p.removeSnapshot(key)
+		}
+	}
+}
+
+// CONTRACT: p.mtx is held.
+func (p *snapshotPool) removeSnapshot(key snapshotKey) {
+	delete(p.snapshots, key)
+	delete(p.snapshotPeers, key)
+}
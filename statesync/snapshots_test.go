@@ -0,0 +1,64 @@
+package statesync
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/tendermint/tendermint/p2p"
+)
+
+// fakePeer is a peer of a key, which only answers the chunk requests with the
+// respond func, if any.
+type fakePeer struct {
+	p2p.Peer
+	key     string
+	respond func(msg *chunkRequestMessage)
+}
+
+func (p *fakePeer) Key() string {
+	return p.key
+}
+
+func (p *fakePeer) TrySend(chID byte, msg interface{}) bool {
+	if req, ok := msg.(struct{ StateSyncMessage }).StateSyncMessage.(*chunkRequestMessage); ok && p.respond != nil {
+		go p.respond(req)
+	}
+	return true
+}
+
+func TestSnapshotPool(t *testing.T) {
+	assert := assert.New(t)
+
+	p1, p2 := &fakePeer{key: "p1"}, &fakePeer{key: "p2"}
+	s10 := &Snapshot{Height: 10, Format: 1, Chunks: 2, Hash: []byte{1}}
+	s20 := &Snapshot{Height: 20, Format: 1, Chunks: 2, Hash: []byte{2}}
+	s20f2 := &Snapshot{Height: 20, Format: 2, Chunks: 3, Hash: []byte{3}}
+
+	pool := newSnapshotPool()
+	assert.Nil(pool.Best())
+	assert.True(pool.Add(p1, s10))
+	assert.True(pool.Add(p1, s20))
+	assert.False(pool.Add(p2, s20), "known snapshot")
+	assert.True(pool.Add(p2, s20f2))
+	assert.Equal(s20f2, pool.Best(), "the highest format of the highest height")
+	assert.Len(pool.Peers(s20), 2)
+
+	pool.RejectFormat(2)
+	assert.Equal(s20, pool.Best())
+	assert.False(pool.Add(p1, s20f2), "rejected format")
+
+	pool.Reject(s20)
+	assert.Equal(s10, pool.Best())
+	assert.False(pool.Add(p2, s20), "rejected snapshot")
+
+	// the snapshots of no peer are forgotten
+	pool.RemovePeer("p1")
+	assert.Nil(pool.Best())
+	assert.True(pool.Add(p2, s10))
+	pool.RejectPeer("p2")
+	assert.Nil(pool.Best())
+	assert.False(pool.Add(p2, s10), "rejected peer")
+	assert.True(pool.Add(p1, s10))
+	assert.Equal([]p2p.Peer{p1}, pool.Peers(s10))
+}
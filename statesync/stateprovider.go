@@ -0,0 +1,19 @@
+package statesync
+
+import (
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+)
+
+// StateProvider provides the verified state of the chain at the height of a
+// snapshot, to check the snapshot against and to bootstrap the node from.
+// See the rpcprovider package for one verifying the headers of RPC servers
+// with a light client.
+type StateProvider interface {
+	// AppHash returns the app hash after the block at height.
+	AppHash(height int64) ([]byte, error)
+	// SignedHeader returns the header and the commit of the block at height.
+	SignedHeader(height int64) (types.SignedHeader, error)
+	// State returns the state after the block at height.
+	State(height int64) (*sm.State, error)
+}
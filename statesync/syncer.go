@@ -0,0 +1,261 @@
+package statesync
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	abci "github.com/tendermint/abci/types"
+	"github.com/tendermint/tmlibs/log"
+
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/proxy"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	// how often the fetchers look for a chunk to fetch, once they are all
+	// allocated
+	chunkAllocateInterval = 100 * time.Millisecond
+)
+
+var (
+	// ErrAborted is returned by Sync if the reactor is stopped.
+	ErrAborted = errors.New("State sync aborted")
+
+	// errNoSnapshotPeers is returned if all the peers of the snapshot being
+	// restored are gone.
+	errNoSnapshotPeers = errors.New("No peers left for the snapshot")
+
+	// errAppMismatch is returned if the app restored a snapshot of another
+	// state than the one verified, and can't be trusted.
+	errAppMismatch = errors.New("The app restored a snapshot of another state")
+)
+
+// syncer restores a snapshot offered by the peers: the best one, verified
+// against the app hash of the StateProvider, is offered to the app, which
+// applies its chunks, fetched in parallel from the peers, in order. The
+// snapshots rejected by the app are skipped for the next best one.
+type syncer struct {
+	logger        log.Logger
+	app           App
+	appQuery      proxy.AppConnQuery
+	stateProvider StateProvider
+	pool          *snapshotPool
+	chunkFetchers int
+	chunkTimeout  time.Duration
+	quit          <-chan struct{}
+
+	// sends the chunk request to the peer
+	requestChunk func(peer p2p.Peer, snapshot *Snapshot, index uint32) bool
+
+	mtx    sync.Mutex
+	chunks *chunkQueue // of the snapshot being restored
+}
+
+func newSyncer(logger log.Logger, app App, appQuery proxy.AppConnQuery, stateProvider StateProvider,
+	pool *snapshotPool, chunkFetchers int, chunkTimeout time.Duration, quit <-chan struct{}) *syncer {
+	return &syncer{
+		logger:        logger,
+		app:           app,
+		appQuery:      appQuery,
+		stateProvider: stateProvider,
+		pool:          pool,
+		chunkFetchers: chunkFetchers,
+		chunkTimeout:  chunkTimeout,
+		quit:          quit,
+	}
+}
+
+// AddChunk adds the chunk sent by the peer, if of the snapshot being restored.
+func (s *syncer) AddChunk(peer p2p.Peer, msg *chunkResponseMessage) bool {
+	s.mtx.Lock()
+	chunks := s.chunks
+	s.mtx.Unlock()
+
+	if chunks == nil || msg.Missing || msg.Height != chunks.snapshot.Height || msg.Format != chunks.snapshot.Format {
+		return false
+	}
+	return chunks.Add(msg.Index, msg.Chunk, peer.Key())
+}
+
+// SyncAny restores the best snapshot of the pool, or the next best ones if
+// rejected, until one is restored. It returns the state and the signed header
+// of the height of the snapshot, to bootstrap the node from. The pool is
+// expected to be filled by the reactor; while it is empty, SyncAny calls
+// discover and waits for the discovery time before looking again.
+func (s *syncer) SyncAny(discoveryTime time.Duration, discover func()) (*sm.State, types.SignedHeader, error) {
+	res, err := s.appQuery.InfoSync(abci.RequestInfo{})
+	if err != nil {
+		return nil, types.SignedHeader{}, errors.Wrap(err, "Error getting the app info")
+	}
+	if res.LastBlockHeight != 0 {
+		return nil, types.SignedHeader{}, errors.Errorf("Can only state sync an empty app, got one at height %d",
+			res.LastBlockHeight)
+	}
+
+	for {
+		snapshot := s.pool.Best()
+		if snapshot == nil {
+			s.logger.Info("Discovering snapshots", "for", discoveryTime)
+			discover()
+			select {
+			case <-time.After(discoveryTime):
+				continue
+			case <-s.quit:
+				return nil, types.SignedHeader{}, ErrAborted
+			}
+		}
+
+		err := s.Sync(snapshot)
+		switch {
+		case err == nil:
+			state, err := s.stateProvider.State(snapshot.Height)
+			if err != nil {
+				return nil, types.SignedHeader{}, errors.Wrap(err, "Error getting the state")
+			}
+			signedHeader, err := s.stateProvider.SignedHeader(snapshot.Height)
+			if err != nil {
+				return nil, types.SignedHeader{}, errors.Wrap(err, "Error getting the signed header")
+			}
+			return state, signedHeader, nil
+		case err == ErrAborted:
+			return nil, types.SignedHeader{}, err
+		case err == ErrRejectFormat:
+			s.logger.Info("Snapshot format rejected", "format", snapshot.Format)
+			s.pool.RejectFormat(snapshot.Format)
+		case errors.Cause(err) == errAppMismatch:
+			return nil, types.SignedHeader{}, err
+		default:
+			s.logger.Info("Snapshot rejected", "snapshot", snapshot, "err", err)
+			s.pool.Reject(snapshot)
+		}
+	}
+}
+
+// Sync restores the snapshot.
+func (s *syncer) Sync(snapshot *Snapshot) error {
+	appHash, err := s.stateProvider.AppHash(snapshot.Height)
+	if err != nil {
+		return errors.Wrap(err, "Error verifying the app hash")
+	}
+	s.logger.Info("Offering snapshot", "snapshot", snapshot, "appHash", appHash)
+	if err := s.app.OfferSnapshot(snapshot, appHash); err != nil {
+		return err
+	}
+
+	chunks := newChunkQueue(snapshot)
+	s.mtx.Lock()
+	s.chunks = chunks
+	s.mtx.Unlock()
+	defer func() {
+		chunks.Close()
+		s.mtx.Lock()
+		s.chunks = nil
+		s.mtx.Unlock()
+	}()
+
+	for i := 0; i < s.chunkFetchers; i++ {
+		go s.fetchChunks(snapshot, chunks)
+	}
+	if err := s.applyChunks(snapshot, chunks); err != nil {
+		return err
+	}
+
+	// the app must now be at the height and app hash of the snapshot
+	res, err := s.appQuery.InfoSync(abci.RequestInfo{})
+	if err != nil {
+		return errors.Wrap(err, "Error getting the app info")
+	}
+	if res.LastBlockHeight != snapshot.Height || !bytes.Equal(res.LastBlockAppHash, appHash) {
+		return errors.Wrapf(errAppMismatch, "expected height %d and app hash %X, got %d and %X",
+			snapshot.Height, appHash, res.LastBlockHeight, res.LastBlockAppHash)
+	}
+	s.logger.Info("Snapshot restored", "snapshot", snapshot)
+	return nil
+}
+
+// applyChunks applies the chunks in order, as they are received.
+func (s *syncer) applyChunks(snapshot *Snapshot, chunks *chunkQueue) error {
+	ticker := time.NewTicker(s.chunkTimeout)
+	defer ticker.Stop()
+
+	for index := uint32(0); index < snapshot.Chunks; {
+		select {
+		case <-chunks.Received(index):
+		case <-ticker.C:
+			if len(s.pool.Peers(snapshot)) == 0 {
+				return errNoSnapshotPeers
+			}
+			continue
+		case <-s.quit:
+			return ErrAborted
+		}
+
+		chunk, peerKey, ok := chunks.Get(index)
+		if !ok {
+			continue
+		}
+		err := s.app.ApplySnapshotChunk(index, chunk)
+		switch {
+		case err == ErrRejectChunk:
+			s.logger.Info("Chunk rejected", "index", index, "peer", peerKey)
+			chunks.Discard(index)
+			s.pool.RejectPeer(peerKey)
+		case err != nil:
+			return err
+		default:
+			chunks.MarkApplied(index)
+			index++
+		}
+	}
+	return nil
+}
+
+// fetchChunks fetches the chunks of the queue until it is closed, one at a
+// time, asking another peer of the snapshot each time one times out.
+func (s *syncer) fetchChunks(snapshot *Snapshot, chunks *chunkQueue) {
+	for {
+		index, err := chunks.Allocate()
+		switch err {
+		case errDone:
+			return
+		case errNoChunk:
+			select {
+			case <-time.After(chunkAllocateInterval):
+				continue
+			case <-s.quit:
+				return
+			}
+		}
+
+		received := false
+	PEERS:
+		for _, peer := range s.pool.Peers(snapshot) {
+			if !s.requestChunk(peer, snapshot, index) {
+				continue
+			}
+			select {
+			case <-chunks.Received(index):
+				received = true
+				break PEERS
+			case <-time.After(s.chunkTimeout):
+				s.logger.Debug("Chunk request timed out", "index", index, "peer", peer.Key())
+			case <-s.quit:
+				return
+			}
+		}
+		if !received {
+			chunks.Deallocate(index)
+			// don't spin while the peers are gone
+			select {
+			case <-time.After(chunkAllocateInterval):
+			case <-s.quit:
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package statesync
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/abci/types"
+	"github.com/tendermint/tmlibs/log"
+
+	"github.com/tendermint/tendermint/p2p"
+	sm "github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
+)
+
+// restoringApp restores the snapshots of format 1, of any chunks but "bad",
+// which it rejects.
+type restoringApp struct {
+	mtx      sync.Mutex
+	snapshot *Snapshot
+	appHash  []byte
+	chunks   [][]byte
+	restored bool
+
+	wrongHash bool // reports another app hash once restored
+}
+
+func (app *restoringApp) ListSnapshots() ([]*Snapshot, error) { return nil, nil }
+
+func (app *restoringApp) LoadSnapshotChunk(height int64, format uint32, index uint32) ([]byte, error) {
+	return nil, errors.New("no snapshots")
+}
+
+func (app *restoringApp) OfferSnapshot(snapshot *Snapshot, appHash []byte) error {
+	app.mtx.Lock()
+	defer app.mtx.Unlock()
+	if snapshot.Format != 1 {
+		return ErrRejectFormat
+	}
+	app.snapshot, app.appHash, app.chunks = snapshot, appHash, nil
+	return nil
+}
+
+func (app *restoringApp) ApplySnapshotChunk(index uint32, chunk []byte) error {
+	app.mtx.Lock()
+	defer app.mtx.Unlock()
+	if string(chunk) == "bad" {
+		return ErrRejectChunk
+	}
+	if int(index) != len(app.chunks) {
+		return errors.New("chunk out of order")
+	}
+	app.chunks = append(app.chunks, chunk)
+	app.restored = len(app.chunks) == int(app.snapshot.Chunks)
+	return nil
+}
+
+// the proxy.AppConnQuery of the app, for its info
+
+func (app *restoringApp) Error() error { return nil }
+
+func (app *restoringApp) EchoSync(msg string) (*abci.ResponseEcho, error) {
+	return &abci.ResponseEcho{Message: msg}, nil
+}
+
+func (app *restoringApp) InfoSync(abci.RequestInfo) (*abci.ResponseInfo, error) {
+	app.mtx.Lock()
+	defer app.mtx.Unlock()
+	if !app.restored {
+		return &abci.ResponseInfo{}, nil
+	}
+	appHash := app.appHash
+	if app.wrongHash {
+		appHash = []byte("wrong")
+	}
+	return &abci.ResponseInfo{LastBlockHeight: app.snapshot.Height, LastBlockAppHash: appHash}, nil
+}
+
+func (app *restoringApp) QuerySync(abci.RequestQuery) (*abci.ResponseQuery, error) {
+	return &abci.ResponseQuery{}, nil
+}
+
+// fakeStateProvider provides the states of any height, of the app hash.
+type fakeStateProvider struct {
+	appHash []byte
+}
+
+func (p fakeStateProvider) AppHash(height int64) ([]byte, error) {
+	return p.appHash, nil
+}
+
+func (p fakeStateProvider) SignedHeader(height int64) (types.SignedHeader, error) {
+	return types.SignedHeader{Header: &types.Header{Height: height}, Commit: &types.Commit{}}, nil
+}
+
+func (p fakeStateProvider) State(height int64) (*sm.State, error) {
+	return &sm.State{LastBlockHeight: height, AppHash: p.appHash}, nil
+}
+
+func TestSyncerSyncAny(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	app := &restoringApp{}
+	pool := newSnapshotPool()
+	quit := make(chan struct{})
+	defer close(quit)
+	s := newSyncer(log.TestingLogger(), app, app, fakeStateProvider{[]byte("apphash")}, pool,
+		2, 50*time.Millisecond, quit)
+	s.requestChunk = func(peer p2p.Peer, snapshot *Snapshot, index uint32) bool {
+		return peer.TrySend(ChunkChannel, struct{ StateSyncMessage }{
+			&chunkRequestMessage{Height: snapshot.Height, Format: snapshot.Format, Index: index},
+		})
+	}
+
+	var requestsMtx sync.Mutex
+	requests := make(map[string]int)
+	respond := func(peer *fakePeer, chunk func(index uint32) []byte) func(*chunkRequestMessage) {
+		return func(msg *chunkRequestMessage) {
+			requestsMtx.Lock()
+			requests[peer.key]++
+			requestsMtx.Unlock()
+			if bz := chunk(msg.Index); bz != nil {
+				s.AddChunk(peer, &chunkResponseMessage{Height: msg.Height, Format: msg.Format, Index: msg.Index, Chunk: bz})
+			}
+		}
+	}
+	good, silent, bad := &fakePeer{key: "good"}, &fakePeer{key: "silent"}, &fakePeer{key: "bad"}
+	good.respond = respond(good, func(index uint32) []byte { return []byte{'c', byte('0' + index)} })
+	silent.respond = respond(silent, func(uint32) []byte { return nil })
+	bad.respond = respond(bad, func(uint32) []byte { return []byte("bad") })
+
+	// the highest snapshot is of a format the app doesn't restore
+	snapshot := &Snapshot{Height: 10, Format: 1, Chunks: 4, Hash: []byte{1}}
+	pool.Add(good, snapshot)
+	pool.Add(silent, snapshot)
+	pool.Add(bad, snapshot)
+	pool.Add(good, &Snapshot{Height: 20, Format: 2, Chunks: 1, Hash: []byte{2}})
+
+	state, signedHeader, err := s.SyncAny(time.Millisecond, func() {})
+	require.Nil(err)
+	assert.EqualValues(10, state.LastBlockHeight)
+	assert.EqualValues(10, signedHeader.Header.Height)
+	assert.Equal([][]byte{[]byte("c0"), []byte("c1"), []byte("c2"), []byte("c3")}, app.chunks)
+
+	// the peer of the chunk the app rejected is rejected
+	assert.False(pool.Add(bad, &Snapshot{Height: 30, Format: 1, Chunks: 1, Hash: []byte{3}}))
+	assert.Equal(snapshot, pool.Best(), "the format 2 is rejected")
+	requestsMtx.Lock()
+	assert.NotZero(requests["good"])
+	requestsMtx.Unlock()
+}
+
+func TestSyncerAppMismatch(t *testing.T) {
+	// the app restores another state than the one of the app hash verified
+	app := &restoringApp{wrongHash: true}
+	pool := newSnapshotPool()
+	quit := make(chan struct{})
+	defer close(quit)
+	s := newSyncer(log.TestingLogger(), app, app, fakeStateProvider{[]byte("apphash")}, pool,
+		1, 50*time.Millisecond, quit)
+	s.requestChunk = func(peer p2p.Peer, snapshot *Snapshot, index uint32) bool {
+		return peer.TrySend(ChunkChannel, struct{ StateSyncMessage }{
+			&chunkRequestMessage{Height: snapshot.Height, Format: snapshot.Format, Index: index},
+		})
+	}
+	peer := &fakePeer{key: "peer"}
+	peer.respond = func(msg *chunkRequestMessage) {
+		s.AddChunk(peer, &chunkResponseMessage{Height: msg.Height, Format: msg.Format, Index: msg.Index, Chunk: []byte("c")})
+	}
+	pool.Add(peer, &Snapshot{Height: 10, Format: 1, Chunks: 1, Hash: []byte{1}})
+
+	_, _, err := s.SyncAny(time.Millisecond, func() {})
+	assert.Equal(t, errAppMismatch, errors.Cause(err))
+}
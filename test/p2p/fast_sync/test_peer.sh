@@ -23,9 +23,9 @@ docker rm -vf local_testnet_$ID
 set -e
 
 # restart peer - should have an empty blockchain
-SEEDS="$(test/p2p/ip.sh 1):46656"
+SEEDS="$(test/p2p/id.sh 1)@$(test/p2p/ip.sh 1):46656"
 for j in `seq 2 $N`; do
-	SEEDS="$SEEDS,$(test/p2p/ip.sh $j):46656"
+	SEEDS="$SEEDS,$(test/p2p/id.sh $j)@$(test/p2p/ip.sh $j):46656"
 done
 bash test/p2p/peer.sh $DOCKER_IMAGE $NETWORK_NAME $ID $PROXY_APP "--p2p.seeds $SEEDS --p2p.pex --rpc.unsafe"
 
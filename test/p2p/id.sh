@@ -0,0 +1,7 @@
+#! /bin/bash
+set -eu
+
+ID=$1
+# the node_key.json of the test data holds the key of the priv_validator.json,
+# so the node ID is the lowercase address of the validator
+grep -o '"address": "[0-9A-F]*"' "test/p2p/data/mach$ID/core/priv_validator.json" | cut -d'"' -f4 | tr 'A-F' 'a-f'
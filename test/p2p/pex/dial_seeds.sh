@@ -20,9 +20,9 @@ done
 
 set -e
 # seeds need quotes
-seeds="\"$(test/p2p/ip.sh 1):46656\""
+seeds="\"$(test/p2p/id.sh 1)@$(test/p2p/ip.sh 1):46656\""
 for i in `seq 2 $N`; do
-	seeds="$seeds,\"$(test/p2p/ip.sh $i):46656\""
+	seeds="$seeds,\"$(test/p2p/id.sh $i)@$(test/p2p/ip.sh $i):46656\""
 done
 echo $seeds
 
@@ -0,0 +1,240 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	wire "github.com/tendermint/go-wire"
+	cmn "github.com/tendermint/tmlibs/common"
+)
+
+// EXPERIMENTAL: data availability sampling.
+//
+// With DataAvailabilityParams.Scheme, the txs of a block are erasure coded
+// into shares, the parts of a PartSet whose header is committed to in the
+// Header.DataAvailabilityHeader, which the validators sign. A light node which
+// only has the header samples shares at random indices from its peers, and
+// verifies them against it: if enough of the shares to recover the txs were
+// withheld, each sample misses with a fixed probability (1/2 with the
+// ReedSolomonScheme), so the node is confident the txs are available after a
+// few samples, without downloading the block.
+//
+// The shares are committed to by the simple merkle tree of the PartSet: a
+// namespaced merkle tree, to sample the shares of one app only, would need its
+// own proofs.
+
+const (
+	// DataAvailabilityReedSolomon is the name of the ReedSolomonScheme.
+	DataAvailabilityReedSolomon = "reed_solomon"
+)
+
+// DataAvailabilityScheme erasure codes the data of the blocks into shares.
+type DataAvailabilityScheme interface {
+	// Encode returns the shares of the data, as the parts of a PartSet,
+	// or an error if the data is too big for the scheme.
+	Encode(data []byte) (*PartSet, error)
+}
+
+// dataAvailabilitySchemes are the schemes by name, see
+// RegisterDataAvailabilityScheme.
+var dataAvailabilitySchemes = map[string]func(shareSize int) DataAvailabilityScheme{
+	DataAvailabilityReedSolomon: func(shareSize int) DataAvailabilityScheme {
+		return NewReedSolomonScheme(shareSize)
+	},
+}
+
+// RegisterDataAvailabilityScheme makes the scheme returned by newScheme, for
+// the DataAvailabilityParams.ShareSizeBytes, available to the chains with the
+// name as DataAvailabilityParams.Scheme.
+// NOTE: not thread safe - call it from an init function.
+func RegisterDataAvailabilityScheme(name string, newScheme func(shareSize int) DataAvailabilityScheme) {
+	if _, ok := dataAvailabilitySchemes[name]; ok {
+		cmn.PanicSanity(fmt.Sprintf("Data availability scheme %q already registered", name))
+	}
+	dataAvailabilitySchemes[name] = newScheme
+}
+
+// MakeDataAvailabilityShares returns the shares of the txs of the block,
+// with the scheme.
+func (b *Block) MakeDataAvailabilityShares(scheme DataAvailabilityScheme) (*PartSet, error) {
+	return scheme.Encode(wire.BinaryBytes(b.Data.Txs))
+}
+
+// VerifyDataAvailabilityShare returns an error if the share is not the one at
+// its index of the shares with the header.
+func VerifyDataAvailabilityShare(header PartSetHeader, share *Part) error {
+	if share == nil {
+		return errors.New("No share")
+	}
+	if share.Index < 0 || share.Index >= header.Total {
+		return errors.Errorf("Share index %d out of the %d shares", share.Index, header.Total)
+	}
+	if !share.Proof.Verify(share.Index, header.Total, share.Hash(), header.Hash) {
+		return errors.Errorf("Invalid proof of share %d", share.Index)
+	}
+	return nil
+}
+
+// SampleDataAvailability fetches the shares at samples random indices of the
+// ones with the header, and returns an error if one of them can't be fetched,
+// or is not the one committed to. All the shares are fetched if there are
+// fewer than samples.
+func SampleDataAvailability(header PartSetHeader, samples int, fetch func(index int) (*Part, error)) error {
+	if header.IsZero() {
+		return errors.New("No shares to sample")
+	}
+	indices := cmn.RandPerm(header.Total)
+	if samples < len(indices) {
+		indices = indices[:samples]
+	}
+	for _, index := range indices {
+		share, err := fetch(index)
+		if err != nil {
+			return errors.Wrapf(err, "Share %d unavailable", index)
+		}
+		if share == nil || share.Index != index {
+			return errors.Errorf("Share %d unavailable", index)
+		}
+		if err := VerifyDataAvailabilityShare(header, share); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// maxReedSolomonShares is the max number of data shares of the
+// ReedSolomonScheme, as many parity shares are evaluated in GF(2^8).
+const maxReedSolomonShares = 128
+
+// ReedSolomonScheme is a systematic Reed-Solomon code of rate 1/2: the data is
+// cut into k shares, the values at 0..k-1 of the polynomial of degree k-1 over
+// GF(2^8), byte by byte, followed by its values at k..2k-1. Any k of the 2k
+// shares recover the data, see Decode.
+type ReedSolomonScheme struct {
+	shareSize int
+}
+
+var _ DataAvailabilityScheme = ReedSolomonScheme{}
+
+// NewReedSolomonScheme returns a ReedSolomonScheme with shares of shareSize
+// bytes, or more for the data of more than 128 shares of it.
+func NewReedSolomonScheme(shareSize int) ReedSolomonScheme {
+	return ReedSolomonScheme{shareSize: shareSize}
+}
+
+// Encode implements DataAvailabilityScheme. The data is padded with zeros to
+// the k shares.
+func (rs ReedSolomonScheme) Encode(data []byte) (*PartSet, error) {
+	if rs.shareSize <= 0 {
+		return nil, errors.Errorf("Share size must be positive. Got %d", rs.shareSize)
+	}
+	shareSize := rs.shareSize
+	if len(data) > maxReedSolomonShares*shareSize {
+		shareSize = (len(data) + maxReedSolomonShares - 1) / maxReedSolomonShares
+	}
+	k := cmn.MaxInt(1, (len(data)+shareSize-1)/shareSize)
+
+	extended := make([]byte, 2*k*shareSize)
+	copy(extended, data)
+	xs := make([]byte, k)
+	ys := make([][]byte, k)
+	for i := range xs {
+		xs[i] = byte(i)
+		ys[i] = extended[i*shareSize : (i+1)*shareSize]
+	}
+	for i := k; i < 2*k; i++ {
+		gfInterpolate(xs, ys, byte(i), extended[i*shareSize:(i+1)*shareSize])
+	}
+	return NewPartSetFromData(extended, shareSize), nil
+}
+
+// Decode returns the data, padded, from at least half of the shares.
+func (rs ReedSolomonScheme) Decode(shares *PartSet) ([]byte, error) {
+	total := shares.Total()
+	if total == 0 || total%2 != 0 || total > 2*maxReedSolomonShares {
+		return nil, errors.Errorf("Not the shares of a Reed-Solomon code: %d shares", total)
+	}
+	k := total / 2
+	xs := make([]byte, 0, k)
+	ys := make([][]byte, 0, k)
+	shareSize := -1
+	for i := 0; i < total && len(xs) < k; i++ {
+		share := shares.GetPart(i)
+		if share == nil {
+			continue
+		}
+		if shareSize < 0 {
+			shareSize = len(share.Bytes)
+		} else if len(share.Bytes) != shareSize {
+			return nil, errors.Errorf("Share %d of %d bytes, not %d", i, len(share.Bytes), shareSize)
+		}
+		xs = append(xs, byte(i))
+		ys = append(ys, share.Bytes)
+	}
+	if len(xs) < k {
+		return nil, errors.Errorf("Only %d of the %d shares needed", len(xs), k)
+	}
+
+	data := make([]byte, k*shareSize)
+	for i := 0; i < k; i++ {
+		gfInterpolate(xs, ys, byte(i), data[i*shareSize:(i+1)*shareSize])
+	}
+	return data, nil
+}
+
+// gfExp and gfLog are the powers of the generator 2 of GF(2^8), with the
+// polynomial x^8+x^4+x^3+x^2+1, and their logarithms.
+var gfExp, gfLog = func() (exp [510]byte, log [256]byte) {
+	x := 1
+	for i := 0; i < 255; i++ {
+		exp[i], exp[i+255] = byte(x), byte(x)
+		log[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+	return
+}()
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+// CONTRACT: b != 0
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+// gfInterpolate writes to out the values at x of the polynomials of degree
+// len(xs)-1 whose values at the distinct xs are the bytes of the ys, by
+// Lagrange interpolation.
+func gfInterpolate(xs []byte, ys [][]byte, x byte, out []byte) {
+	for i := range out {
+		out[i] = 0
+	}
+	for i, xi := range xs {
+		coef := byte(1)
+		for m, xm := range xs {
+			if m != i {
+				// in GF(2^8), subtracting is xoring
+				coef = gfMul(coef, gfDiv(x^xm, xi^xm))
+			}
+		}
+		if coef == 0 {
+			continue
+		}
+		for j, y := range ys[i] {
+			out[j] ^= gfMul(coef, y)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	cmn "github.com/tendermint/tmlibs/common"
+)
+
+func TestReedSolomonScheme(t *testing.T) {
+	scheme := NewReedSolomonScheme(16)
+	for _, size := range []int{0, 1, 16, 100, 2048, 128*16 + 1} {
+		data := cmn.RandBytes(size)
+		shares, err := scheme.Encode(data)
+		require.Nil(t, err)
+		total := shares.Total()
+		require.True(t, total%2 == 0 && total <= 2*maxReedSolomonShares, "%d shares", total)
+		for i := 0; i < total; i++ {
+			assert.Nil(t, VerifyDataAvailabilityShare(shares.Header(), shares.GetPart(i)))
+		}
+
+		// any half of the shares recover the data
+		for trial := 0; trial < 3; trial++ {
+			half := NewPartSetFromHeader(shares.Header())
+			for _, i := range cmn.RandPerm(total)[:total/2] {
+				_, err := half.AddPart(shares.GetPart(i), true)
+				require.Nil(t, err)
+			}
+			decoded, err := scheme.Decode(half)
+			require.Nil(t, err)
+			assert.Equal(t, data, decoded[:size])
+			assert.Equal(t, make([]byte, len(decoded)-size), decoded[size:], "padding")
+		}
+
+		missing := NewPartSetFromHeader(shares.Header())
+		for i := 0; i < total/2-1; i++ {
+			_, err := missing.AddPart(shares.GetPart(i), true)
+			require.Nil(t, err)
+		}
+		_, err = scheme.Decode(missing)
+		assert.NotNil(t, err, "less than half of the shares")
+	}
+
+	_, err := NewReedSolomonScheme(0).Encode([]byte("data"))
+	assert.NotNil(t, err)
+}
+
+func TestSampleDataAvailability(t *testing.T) {
+	shares, err := NewReedSolomonScheme(8).Encode(cmn.RandBytes(100))
+	require.Nil(t, err)
+	header := shares.Header()
+
+	fetched := make(map[int]bool)
+	err = SampleDataAvailability(header, 5, func(index int) (*Part, error) {
+		fetched[index] = true
+		return shares.GetPart(index), nil
+	})
+	assert.Nil(t, err)
+	assert.Len(t, fetched, 5)
+
+	// more samples than shares
+	fetched = make(map[int]bool)
+	err = SampleDataAvailability(header, 100, func(index int) (*Part, error) {
+		fetched[index] = true
+		return shares.GetPart(index), nil
+	})
+	assert.Nil(t, err)
+	assert.Len(t, fetched, header.Total)
+
+	// withheld
+	err = SampleDataAvailability(header, header.Total, func(index int) (*Part, error) {
+		if index == 3 {
+			return nil, errors.New("withheld")
+		}
+		return shares.GetPart(index), nil
+	})
+	assert.NotNil(t, err)
+
+	// not the one committed to
+	err = SampleDataAvailability(header, header.Total, func(index int) (*Part, error) {
+		share := *shares.GetPart(index)
+		if index == 3 {
+			share.Bytes = bytes.Repeat([]byte{1}, len(share.Bytes))
+			share.hash = nil
+		}
+		return &share, nil
+	})
+	assert.NotNil(t, err)
+
+	// another index
+	err = SampleDataAvailability(header, header.Total, func(index int) (*Part, error) {
+		return shares.GetPart(0), nil
+	})
+	assert.NotNil(t, err)
+
+	assert.NotNil(t, SampleDataAvailability(PartSetHeader{}, 1, nil))
+}
+
+func TestDataAvailabilityParams(t *testing.T) {
+	params := newConsensusParams(1, 1)
+	assert.Nil(t, params.DataAvailabilityParams.NewScheme())
+	assert.NoError(t, params.Validate())
+
+	params.DataAvailabilityParams = DataAvailabilityParams{Scheme: DataAvailabilityReedSolomon, ShareSizeBytes: 512}
+	assert.NoError(t, params.Validate())
+	assert.Equal(t, NewReedSolomonScheme(512), params.DataAvailabilityParams.NewScheme())
+
+	params.DataAvailabilityParams.ShareSizeBytes = 0
+	assert.Error(t, params.Validate())
+	params.DataAvailabilityParams = DataAvailabilityParams{Scheme: "fountain", ShareSizeBytes: 512}
+	assert.Error(t, params.Validate())
+
+	RegisterDataAvailabilityScheme("test_fountain", func(shareSize int) DataAvailabilityScheme {
+		return NewReedSolomonScheme(2 * shareSize)
+	})
+	params.DataAvailabilityParams.Scheme = "test_fountain"
+	assert.NoError(t, params.Validate())
+	assert.Equal(t, NewReedSolomonScheme(1024), params.DataAvailabilityParams.NewScheme())
+	assert.Panics(t, func() {
+		RegisterDataAvailabilityScheme(DataAvailabilityReedSolomon, nil)
+	})
+}
+
+func TestHeaderHashDataAvailability(t *testing.T) {
+	block, _ := MakeBlock(1, "test_chain", []Tx{Tx("tx")}, &Commit{}, BlockID{},
+		[]byte("vals"), nil, nil, 1024)
+	hash := block.Hash()
+
+	shares, err := block.MakeDataAvailabilityShares(NewReedSolomonScheme(16))
+	require.Nil(t, err)
+	block.DataAvailabilityHeader = shares.Header()
+	assert.NotEqual(t, hash, block.Hash(), "the shares are committed to")
+
+	block.DataAvailabilityHeader = PartSetHeader{}
+	assert.Equal(t, hash, block.Hash(), "no shares hash as before")
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	crypto "github.com/tendermint/go-crypto"
 	wire "github.com/tendermint/go-wire"
 	"github.com/tendermint/go-wire/data"
 	cmn "github.com/tendermint/tmlibs/common"
@@ -18,22 +19,24 @@ import (
 type Block struct {
 	*Header    `json:"header"`
 	*Data      `json:"data"`
-	LastCommit *Commit `json:"last_commit"`
+	Evidence   EvidenceData `json:"evidence"`
+	LastCommit *Commit      `json:"last_commit"`
 }
 
 // MakeBlock returns a new block and corresponding partset from the given information.
 // TODO: Add version information to the Block struct.
 func MakeBlock(height int64, chainID string, txs []Tx, commit *Commit,
-	prevBlockID BlockID, valHash, appHash []byte, partSize int) (*Block, *PartSet) {
+	prevBlockID BlockID, valHash, appHash, resultsHash []byte, partSize int) (*Block, *PartSet) {
 	block := &Block{
 		Header: &Header{
-			ChainID:        chainID,
-			Height:         height,
-			Time:           time.Now(),
-			NumTxs:         len(txs),
-			LastBlockID:    prevBlockID,
-			ValidatorsHash: valHash,
-			AppHash:        appHash, // state merkle root of txs from the previous block.
+			ChainID:         chainID,
+			Height:          height,
+			Time:            time.Now(),
+			NumTxs:          len(txs),
+			LastBlockID:     prevBlockID,
+			ValidatorsHash:  valHash,
+			AppHash:         appHash, // state merkle root of txs from the previous block.
+			LastResultsHash: resultsHash,
 		},
 		LastCommit: commit,
 		Data: &Data{
@@ -44,6 +47,14 @@ func MakeBlock(height int64, chainID string, txs []Tx, commit *Commit,
 	return block, block.MakePartSet(partSize)
 }
 
+// AddEvidence appends the given evidence to the block, and fills in the EvidenceHash.
+// NOTE: the block's PartSet must be made after.
+func (b *Block) AddEvidence(evidence []Evidence) {
+	b.Evidence.Evidence = append(b.Evidence.Evidence, evidence...)
+	b.Evidence.hash = nil
+	b.EvidenceHash = b.Evidence.Hash()
+}
+
 // ValidateBasic performs basic validation that doesn't involve state data.
 func (b *Block) ValidateBasic(chainID string, lastBlockHeight int64, lastBlockID BlockID,
 	lastBlockTime time.Time, appHash []byte) error {
@@ -77,6 +88,12 @@ func (b *Block) ValidateBasic(chainID string, lastBlockHeight int64, lastBlockID
 	if !bytes.Equal(b.DataHash, b.Data.Hash()) {
 		return errors.New(cmn.Fmt("Wrong Block.Header.DataHash.  Expected %v, got %v", b.DataHash, b.Data.Hash()))
 	}
+	if !bytes.Equal(b.EvidenceHash, b.Evidence.Hash()) {
+		return errors.New(cmn.Fmt("Wrong Block.Header.EvidenceHash.  Expected %v, got %v", b.EvidenceHash, b.Evidence.Hash()))
+	}
+	if !bytes.Equal(b.RandomBeacon, b.LastCommit.RandomBeacon()) {
+		return errors.New(cmn.Fmt("Wrong Block.Header.RandomBeacon.  Expected %v, got %v", b.LastCommit.RandomBeacon(), b.RandomBeacon))
+	}
 	if !bytes.Equal(b.AppHash, appHash) {
 		return errors.New(cmn.Fmt("Wrong Block.Header.AppHash.  Expected %X, got %v", appHash, b.AppHash))
 	}
@@ -92,6 +109,12 @@ func (b *Block) FillHeader() {
 	if b.DataHash == nil {
 		b.DataHash = b.Data.Hash()
 	}
+	if b.RandomBeacon == nil {
+		b.RandomBeacon = b.LastCommit.RandomBeacon()
+	}
+	if b.EvidenceHash == nil {
+		b.EvidenceHash = b.Evidence.Hash()
+	}
 }
 
 // Hash computes and returns the block hash.
@@ -137,9 +160,11 @@ func (b *Block) StringIndented(indent string) string {
 %s  %v
 %s  %v
 %s  %v
+%s  %v
 %s}#%v`,
 		indent, b.Header.StringIndented(indent+"  "),
 		indent, b.Data.StringIndented(indent+"  "),
+		indent, b.Evidence.StringIndented(indent+"  "),
 		indent, b.LastCommit.StringIndented(indent+"  "),
 		indent, b.Hash())
 }
@@ -157,24 +182,32 @@ func (b *Block) StringShort() string {
 
 // Header defines the structure of a Tendermint block header
 type Header struct {
-	ChainID        string     `json:"chain_id"`
-	Height         int64      `json:"height"`
-	Time           time.Time  `json:"time"`
-	NumTxs         int        `json:"num_txs"` // XXX: Can we get rid of this?
-	LastBlockID    BlockID    `json:"last_block_id"`
-	LastCommitHash data.Bytes `json:"last_commit_hash"` // commit from validators from the last block
-	DataHash       data.Bytes `json:"data_hash"`        // transactions
-	ValidatorsHash data.Bytes `json:"validators_hash"`  // validators for the current block
-	AppHash        data.Bytes `json:"app_hash"`         // state after txs from the previous block
+	ChainID         string     `json:"chain_id"`
+	Height          int64      `json:"height"`
+	Time            time.Time  `json:"time"`
+	NumTxs          int        `json:"num_txs"` // XXX: Can we get rid of this?
+	LastBlockID     BlockID    `json:"last_block_id"`
+	LastCommitHash  data.Bytes `json:"last_commit_hash"`  // commit from validators from the last block
+	DataHash        data.Bytes `json:"data_hash"`         // transactions
+	ValidatorsHash  data.Bytes `json:"validators_hash"`   // validators for the current block
+	AppHash         data.Bytes `json:"app_hash"`          // state after txs from the previous block
+	LastResultsHash data.Bytes `json:"last_results_hash"` // root hash of all results from the txs from the previous block
+	RandomBeacon    data.Bytes `json:"random_beacon"`     // pseudo-random value derived from the last commit
+	EvidenceHash    data.Bytes `json:"evidence_hash"`     // evidence included in the block
+
+	// shares of the erasure coded txs, with DataAvailabilityParams.Scheme
+	DataAvailabilityHeader PartSetHeader `json:"data_availability_header"`
 }
 
 // Hash returns the hash of the header.
 // Returns nil if ValidatorHash is missing.
+// The DataAvailabilityHeader is hashed only if set, so the blocks of the chains
+// without data availability sampling hash as before.
 func (h *Header) Hash() data.Bytes {
 	if len(h.ValidatorsHash) == 0 {
 		return nil
 	}
-	return merkle.SimpleHashFromMap(map[string]interface{}{
+	fields := map[string]interface{}{
 		"ChainID":     h.ChainID,
 		"Height":      h.Height,
 		"Time":        h.Time,
@@ -184,7 +217,14 @@ func (h *Header) Hash() data.Bytes {
 		"Data":        h.DataHash,
 		"Validators":  h.ValidatorsHash,
 		"App":         h.AppHash,
-	})
+		"Results":     h.LastResultsHash,
+		"Beacon":      h.RandomBeacon,
+		"Evidence":    h.EvidenceHash,
+	}
+	if !h.DataAvailabilityHeader.IsZero() {
+		fields["DataAvailability"] = h.DataAvailabilityHeader
+	}
+	return merkle.SimpleHashFromMap(fields)
 }
 
 // StringIndented returns a string representation of the header
@@ -202,6 +242,10 @@ func (h *Header) StringIndented(indent string) string {
 %s  Data:           %v
 %s  Validators:     %v
 %s  App:            %v
+%s  Results:        %v
+%s  RandomBeacon:   %v
+%s  Evidence:       %v
+%s  Availability:   %v
 %s}#%v`,
 		indent, h.ChainID,
 		indent, h.Height,
@@ -212,6 +256,10 @@ func (h *Header) StringIndented(indent string) string {
 		indent, h.DataHash,
 		indent, h.ValidatorsHash,
 		indent, h.AppHash,
+		indent, h.LastResultsHash,
+		indent, h.RandomBeacon,
+		indent, h.EvidenceHash,
+		indent, h.DataAvailabilityHeader,
 		indent, h.Hash())
 }
 
@@ -226,6 +274,10 @@ type Commit struct {
 	BlockID    BlockID `json:"blockID"`
 	Precommits []*Vote `json:"precommits"`
 
+	// With ConsensusParams.CommitParams.AggregateSignatures, the BLS signatures
+	// of the precommits, which are then left empty, aggregated into one.
+	AggregatedSignature crypto.Signature `json:"aggregated_signature"`
+
 	// Volatile
 	firstPrecommit *Vote
 	hash           data.Bytes
@@ -301,6 +353,41 @@ func (commit *Commit) IsCommit() bool {
 	return len(commit.Precommits) != 0
 }
 
+// IsAggregated returns true if the signatures of the precommits are
+// aggregated into the AggregatedSignature.
+func (commit *Commit) IsAggregated() bool {
+	return !commit.AggregatedSignature.Empty()
+}
+
+// Aggregate returns a copy of the commit with the signatures of the precommits
+// aggregated into the AggregatedSignature, and removed from the precommits,
+// so the commit holds one signature instead of one per validator.
+// All the precommits must be signed with BLS12-381 keys.
+func (commit *Commit) Aggregate() (*Commit, error) {
+	if commit.IsAggregated() {
+		return commit, nil
+	}
+	precommits := make([]*Vote, len(commit.Precommits))
+	sigs := make([]crypto.Signature, 0, len(commit.Precommits))
+	for i, precommit := range commit.Precommits {
+		if precommit == nil {
+			continue
+		}
+		sigs = append(sigs, precommit.Signature)
+		precommits[i] = precommit.Copy()
+		precommits[i].Signature = crypto.Signature{}
+	}
+	aggregatedSignature, err := AggregateSignaturesBLS12381(sigs)
+	if err != nil {
+		return nil, err
+	}
+	return &Commit{
+		BlockID:             commit.BlockID,
+		Precommits:          precommits,
+		AggregatedSignature: aggregatedSignature,
+	}, nil
+}
+
 // ValidateBasic performs basic validation that doesn't involve state data.
 func (commit *Commit) ValidateBasic() error {
 	if commit.BlockID.IsZero() {
@@ -332,6 +419,11 @@ func (commit *Commit) ValidateBasic() error {
 			return fmt.Errorf("Invalid commit precommit round. Expected %v, got %v",
 				round, precommit.Round)
 		}
+		// Ensure that all signatures are in the aggregated one, if any
+		if commit.IsAggregated() && !precommit.Signature.Empty() {
+			return fmt.Errorf("Invalid commit precommit signature. Expected it aggregated, got %v",
+				precommit.Signature)
+		}
 	}
 	return nil
 }
@@ -343,11 +435,37 @@ func (commit *Commit) Hash() data.Bytes {
 		for i, precommit := range commit.Precommits {
 			bs[i] = precommit
 		}
+		if commit.IsAggregated() {
+			bs = append(bs, commit.AggregatedSignature)
+		}
 		commit.hash = merkle.SimpleHashFromBinaries(bs)
 	}
 	return commit.hash
 }
 
+// RandomBeacon returns a pseudo-random value derived from the signatures
+// of the precommits in the commit, in validator order. Missing precommits are skipped.
+// Since signatures are deterministic, every node derives the same value from the same commit.
+// NOTE: the proposer chooses which precommits beyond +2/3 end up in the commit,
+// so it has a limited ability to bias the result.
+// For an aggregated commit, it's derived from the AggregatedSignature, which is as deterministic.
+// Returns nil if the commit has no precommits (ie. for the first block).
+func (commit *Commit) RandomBeacon() data.Bytes {
+	if commit.IsAggregated() {
+		return merkle.SimpleHashFromBinaries([]interface{}{commit.AggregatedSignature})
+	}
+	sigs := make([]interface{}, 0, len(commit.Precommits))
+	for _, precommit := range commit.Precommits {
+		if precommit != nil {
+			sigs = append(sigs, precommit.Signature)
+		}
+	}
+	if len(sigs) == 0 {
+		return nil
+	}
+	return merkle.SimpleHashFromBinaries(sigs)
+}
+
 // StringIndented returns a string representation of the commit
 func (commit *Commit) StringIndented(indent string) string {
 	if commit == nil {
@@ -360,9 +478,11 @@ func (commit *Commit) StringIndented(indent string) string {
 	return fmt.Sprintf(`Commit{
 %s  BlockID:    %v
 %s  Precommits: %v
+%s  Aggregated: %v
 %s}#%v`,
 		indent, commit.BlockID,
 		indent, strings.Join(precommitStrings, "\n"+indent+"  "),
+		indent, commit.AggregatedSignature,
 		indent, commit.hash)
 }
 
@@ -416,6 +536,44 @@ func (data *Data) StringIndented(indent string) string {
 		indent, data.hash)
 }
 
+//-----------------------------------------------------------------------------
+
+// EvidenceData contains any evidence of malicious wrong-doing by validators
+type EvidenceData struct {
+	Evidence EvidenceList `json:"evidence"`
+
+	// Volatile
+	hash data.Bytes
+}
+
+// Hash returns the hash of the data.
+func (data *EvidenceData) Hash() data.Bytes {
+	if data.hash == nil {
+		data.hash = data.Evidence.Hash()
+	}
+	return data.hash
+}
+
+// StringIndented returns a string representation of the evidence.
+func (data *EvidenceData) StringIndented(indent string) string {
+	if data == nil {
+		return "nil-Evidence"
+	}
+	evStrings := make([]string, cmn.MinInt(len(data.Evidence), 21))
+	for i, ev := range data.Evidence {
+		if i == 20 {
+			evStrings[i] = fmt.Sprintf("... (%v total)", len(data.Evidence))
+			break
+		}
+		evStrings[i] = fmt.Sprintf("Evidence:%v", ev)
+	}
+	return fmt.Sprintf(`EvidenceData{
+%s  %v
+%s}#%v`,
+		indent, strings.Join(evStrings, "\n"+indent+"  "),
+		indent, data.hash)
+}
+
 //--------------------------------------------------------------------------------
 
 // BlockID defines the unique ID of a block as its Hash and its PartSetHeader
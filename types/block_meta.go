@@ -1,15 +1,19 @@
 package types
 
-// BlockMeta contains meta information about a block - namely, it's ID and Header.
+// BlockMeta contains meta information about a block - namely, it's ID, Header
+// and size. It's stored apart from the block, so it can be served without
+// loading and decoding the block, eg. to list blocks.
 type BlockMeta struct {
-	BlockID BlockID `json:"block_id"` // the block hash and partsethash
-	Header  *Header `json:"header"`   // The block's Header
+	BlockID   BlockID `json:"block_id"`   // the block hash and partsethash
+	Header    *Header `json:"header"`     // The block's Header, with the number of txs
+	BlockSize int     `json:"block_size"` // The size of the encoded block, in bytes
 }
 
 // NewBlockMeta returns a new BlockMeta from the block and its blockParts.
 func NewBlockMeta(block *Block, blockParts *PartSet) *BlockMeta {
 	return &BlockMeta{
-		BlockID: BlockID{block.Hash(), blockParts.Header()},
-		Header:  block.Header,
+		BlockID:   BlockID{block.Hash(), blockParts.Header()},
+		Header:    block.Header,
+		BlockSize: blockParts.ByteSize(),
 	}
 }
@@ -0,0 +1,65 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	crypto "github.com/tendermint/go-crypto"
+)
+
+func makeCommit(t *testing.T, height int64, numValidators int) *Commit {
+	voteSet, _, privValidators := randVoteSet(height, 0, VoteTypePrecommit, numValidators, 1)
+	blockID := BlockID{crypto.CRandBytes(32), PartSetHeader{123, crypto.CRandBytes(32)}}
+
+	voteProto := &Vote{
+		ValidatorIndex: -1,
+		Height:         height,
+		Round:          0,
+		Type:           VoteTypePrecommit,
+		BlockID:        blockID,
+	}
+	for i := 0; i < numValidators; i++ {
+		vote := withValidator(voteProto, privValidators[i].GetAddress(), i)
+		_, err := signAddVote(privValidators[i], vote, voteSet)
+		require.Nil(t, err)
+	}
+	return voteSet.MakeCommit()
+}
+
+func TestCommitRandomBeacon(t *testing.T) {
+	assert := assert.New(t)
+
+	// the first block has no last commit, and so no beacon
+	assert.Nil((&Commit{}).RandomBeacon())
+
+	commit := makeCommit(t, 1, 4)
+	beacon := commit.RandomBeacon()
+	assert.NotEmpty(beacon)
+	assert.Equal(beacon, commit.RandomBeacon(), "beacon must be deterministic")
+
+	// dropping a precommit changes the beacon
+	precommits := make([]*Vote, len(commit.Precommits))
+	copy(precommits, commit.Precommits)
+	precommits[3] = nil
+	partial := &Commit{BlockID: commit.BlockID, Precommits: precommits}
+	assert.NotEqual(beacon, partial.RandomBeacon())
+
+	// a different set of signatures gives a different beacon
+	assert.NotEqual(beacon, makeCommit(t, 1, 4).RandomBeacon())
+}
+
+func TestBlockValidateRandomBeacon(t *testing.T) {
+	commit := makeCommit(t, 1, 4)
+	block, _ := MakeBlock(2, "test_chain_id", []Tx{Tx("hello")}, commit,
+		commit.BlockID, []byte("valhash"), nil, nil, 1024)
+	assert.Equal(t, commit.RandomBeacon(), block.RandomBeacon)
+
+	err := block.ValidateBasic("test_chain_id", 1, commit.BlockID, block.Time, nil)
+	assert.Nil(t, err)
+
+	block.RandomBeacon = crypto.CRandBytes(20)
+	err = block.ValidateBasic("test_chain_id", 1, commit.BlockID, block.Time, nil)
+	assert.NotNil(t, err)
+}
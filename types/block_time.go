@@ -0,0 +1,61 @@
+package types
+
+import (
+	"sort"
+	"time"
+)
+
+// With ConsensusParams.BlockTimeParams.BFTTime, the time of a block is not the clock
+// of its proposer, but the median of the times of the precommits for the previous block,
+// weighted by the voting power of their validators. It can't be skewed by validators
+// with less than 1/3 of the voting power, and it increases as long as the honest
+// validators sign their precommits after the time of the previous block.
+
+type weightedTime struct {
+	time  time.Time
+	power int64
+}
+
+// MedianTime returns the weighted median of the times of the precommits of the commit
+// for its block, signed by the given validators. The precommits for nil or another block
+// don't count. It returns the zero time if there are no such precommits.
+// NOTE: the times are truncated to the ms, as they are signed with that precision.
+func MedianTime(commit *Commit, validators *ValidatorSet) time.Time {
+	times := make([]weightedTime, 0, len(commit.Precommits))
+	totalPower := int64(0)
+	for _, precommit := range commit.Precommits {
+		if precommit == nil || !precommit.BlockID.Equals(commit.BlockID) {
+			continue
+		}
+		_, val := validators.GetByIndex(precommit.ValidatorIndex)
+		if val == nil {
+			continue
+		}
+		times = append(times, weightedTime{precommit.Timestamp.Truncate(time.Millisecond), val.VotingPower})
+		totalPower += val.VotingPower
+	}
+	if len(times) == 0 {
+		return time.Time{}
+	}
+
+	sort.Slice(times, func(i, j int) bool { return times[i].time.Before(times[j].time) })
+	median := totalPower / 2
+	for _, t := range times {
+		if median < t.power {
+			return t.time
+		}
+		median -= t.power
+	}
+	return times[len(times)-1].time
+}
+
+// VoteTime returns the time to sign a vote at: now, with the precision of the
+// canonical json, or just after the given time, eg. of the block voted for, if
+// the clock is behind.
+func VoteTime(now, after time.Time) time.Time {
+	now = now.UTC().Truncate(time.Millisecond)
+	if min := after.UTC().Truncate(time.Millisecond).Add(time.Millisecond); now.Before(min) {
+		return min
+	}
+	return now
+}
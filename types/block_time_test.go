@@ -0,0 +1,75 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	crypto "github.com/tendermint/go-crypto"
+)
+
+func TestMedianTime(t *testing.T) {
+	assert := assert.New(t)
+
+	t0 := time.Date(2017, 12, 1, 10, 0, 0, 0, time.UTC)
+	vals := make([]*Validator, 4)
+	for i := range vals {
+		vals[i] = NewValidator(crypto.GenPrivKeyEd25519().PubKey(), int64(10*(i+1)))
+	}
+	valSet := NewValidatorSet(vals)
+
+	blockID := BlockID{Hash: []byte("hash")}
+	// each validator precommits at t0 + its power in seconds, unless its time is given
+	commit := func(times map[int64]time.Time) *Commit {
+		precommits := make([]*Vote, valSet.Size())
+		for i, val := range valSet.Validators {
+			timestamp, ok := times[val.VotingPower]
+			if !ok {
+				timestamp = t0.Add(time.Duration(val.VotingPower) * time.Second)
+			} else if timestamp.IsZero() {
+				continue // absent
+			}
+			precommits[i] = &Vote{ValidatorIndex: i, Timestamp: timestamp, BlockID: blockID}
+		}
+		return &Commit{BlockID: blockID, Precommits: precommits}
+	}
+	at := func(seconds int) time.Time { return t0.Add(time.Duration(seconds) * time.Second) }
+
+	assert.Equal(at(30), MedianTime(commit(nil), valSet))
+
+	// without the validator with a power of 40
+	assert.Equal(at(30), MedianTime(commit(map[int64]time.Time{40: {}}), valSet))
+
+	// validators with less than 1/3 of the power can't move it out of the honest times
+	farFuture := t0.Add(24 * time.Hour)
+	assert.Equal(at(40), MedianTime(commit(map[int64]time.Time{10: farFuture, 20: farFuture}), valSet))
+
+	// the time is weighted by the voting power
+	assert.Equal(at(20), MedianTime(commit(map[int64]time.Time{40: t0}), valSet))
+
+	// only the precommits for the block count
+	nilPrecommit := commit(map[int64]time.Time{40: t0})
+	for _, precommit := range nilPrecommit.Precommits {
+		if precommit.Timestamp.Equal(t0) {
+			precommit.BlockID = BlockID{}
+		}
+	}
+	assert.Equal(at(30), MedianTime(nilPrecommit, valSet))
+
+	// the times are signed with a ms precision
+	assert.Equal(at(30), MedianTime(commit(map[int64]time.Time{30: at(30).Add(999 * time.Microsecond)}), valSet))
+
+	// no precommits
+	assert.True(MedianTime(&Commit{}, valSet).IsZero())
+}
+
+func TestVoteTime(t *testing.T) {
+	assert := assert.New(t)
+
+	lastBlockTime := time.Date(2017, 12, 1, 10, 0, 0, 0, time.UTC)
+	now := lastBlockTime.Add(time.Second + 1500*time.Microsecond)
+	assert.Equal(lastBlockTime.Add(time.Second+time.Millisecond), VoteTime(now, lastBlockTime))
+
+	// a clock behind the last block
+	assert.Equal(lastBlockTime.Add(time.Millisecond), VoteTime(lastBlockTime.Add(-time.Hour), lastBlockTime))
+}
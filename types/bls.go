@@ -0,0 +1,326 @@
+package types
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	bls12381 "github.com/kilic/bls12-381"
+	crypto "github.com/tendermint/go-crypto"
+	wire "github.com/tendermint/go-wire"
+	data "github.com/tendermint/go-wire/data"
+	cmn "github.com/tendermint/tmlibs/common"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// BLS12-381 validator keys sign with the public keys in G1 (48 bytes) and the
+// signatures in G2 (96 bytes). Unlike the ed25519 signatures, the signatures
+// of several messages by several keys add up into one of the same size, which
+// verifies them all at once: with CommitParams.AggregateSignatures, a commit of
+// validators all using BLS keys holds one signature instead of one per precommit.
+// The sign bytes of the precommits don't include the validator, so two of
+// them with the same timestamp are the same message. The keys sign their
+// public key followed by the message instead, the message augmentation scheme
+// of the IETF BLS signatures draft, so the messages of different keys are
+// always different and a rogue key, made up from the others, can't cancel
+// them out in an aggregate: no proof of possession of the keys is needed.
+
+const (
+	NameBLS12381 = "bls12381"
+	TypeBLS12381 = byte(0x11)
+
+	// blsDomain is the domain separation tag of the hash to G2 of the messages,
+	// the one of the message augmentation scheme of the IETF BLS signatures draft.
+	blsDomain = "BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_AUG_"
+)
+
+func init() {
+	crypto.PrivKeyMapper.RegisterImplementation(PrivKeyBLS12381{}, NameBLS12381, TypeBLS12381)
+	crypto.PubKeyMapper.RegisterImplementation(PubKeyBLS12381{}, NameBLS12381, TypeBLS12381)
+	crypto.SignatureMapper.RegisterImplementation(SignatureBLS12381{}, NameBLS12381, TypeBLS12381)
+}
+
+// hashToG2 hashes the message augmented with the public key of its signer.
+func hashToG2(pubKey PubKeyBLS12381, msg []byte) (*bls12381.PointG2, error) {
+	augmented := make([]byte, 0, len(pubKey)+len(msg))
+	augmented = append(append(augmented, pubKey[:]...), msg...)
+	return bls12381.NewG2().HashToCurve(augmented, []byte(blsDomain))
+}
+
+//-------------------------------------
+
+// PrivKeyBLS12381 is a BLS12-381 secret scalar, big-endian.
+type PrivKeyBLS12381 [32]byte
+
+// GenPrivKeyBLS12381 generates a random BLS12-381 private key.
+func GenPrivKeyBLS12381() PrivKeyBLS12381 {
+	for {
+		fr, err := bls12381.NewFr().Rand(rand.Reader)
+		if err != nil {
+			panic(err)
+		}
+		if !fr.IsZero() {
+			var privKey PrivKeyBLS12381
+			copy(privKey[:], fr.ToBytes())
+			return privKey
+		}
+	}
+}
+
+func (privKey PrivKeyBLS12381) scalar() *bls12381.Fr {
+	return bls12381.NewFr().FromBytes(privKey[:])
+}
+
+// AssertIsPrivKeyInner implements crypto.PrivKeyInner.
+func (privKey PrivKeyBLS12381) AssertIsPrivKeyInner() {}
+
+// Bytes implements crypto.PrivKeyInner.
+func (privKey PrivKeyBLS12381) Bytes() []byte {
+	return wire.BinaryBytes(privKey.Wrap())
+}
+
+// Sign implements crypto.PrivKeyInner.
+func (privKey PrivKeyBLS12381) Sign(msg []byte) crypto.Signature {
+	g2 := bls12381.NewG2()
+	point, err := hashToG2(privKey.PubKey().Unwrap().(PubKeyBLS12381), msg)
+	if err != nil {
+		panic(err) // only fails for a domain over 255 bytes
+	}
+	var sig SignatureBLS12381
+	copy(sig[:], g2.ToCompressed(g2.MulScalar(g2.New(), point, privKey.scalar())))
+	return sig.Wrap()
+}
+
+// PubKey implements crypto.PrivKeyInner.
+func (privKey PrivKeyBLS12381) PubKey() crypto.PubKey {
+	g1 := bls12381.NewG1()
+	var pubKey PubKeyBLS12381
+	copy(pubKey[:], g1.ToCompressed(g1.MulScalar(g1.New(), g1.One(), privKey.scalar())))
+	return pubKey.Wrap()
+}
+
+// Equals implements crypto.PrivKeyInner.
+func (privKey PrivKeyBLS12381) Equals(other crypto.PrivKey) bool {
+	otherPrivKey, ok := other.Unwrap().(PrivKeyBLS12381)
+	return ok && privKey == otherPrivKey
+}
+
+// Wrap implements crypto.PrivKeyInner.
+func (privKey PrivKeyBLS12381) Wrap() crypto.PrivKey {
+	return crypto.PrivKey{privKey}
+}
+
+func (privKey PrivKeyBLS12381) MarshalJSON() ([]byte, error) {
+	return data.Encoder.Marshal(privKey[:])
+}
+
+func (privKey *PrivKeyBLS12381) UnmarshalJSON(enc []byte) error {
+	var ref []byte
+	err := data.Encoder.Unmarshal(&ref, enc)
+	copy(privKey[:], ref)
+	return err
+}
+
+//-------------------------------------
+
+// PubKeyBLS12381 is a compressed point of G1.
+type PubKeyBLS12381 [48]byte
+
+func (pubKey PubKeyBLS12381) point() (*bls12381.PointG1, error) {
+	g1 := bls12381.NewG1()
+	point, err := g1.FromCompressed(pubKey[:])
+	if err != nil {
+		return nil, err
+	}
+	if g1.IsZero(point) {
+		return nil, errors.New("Public key is the point at infinity")
+	}
+	if !g1.InCorrectSubgroup(point) {
+		return nil, errors.New("Public key is not in the G1 subgroup")
+	}
+	return point, nil
+}
+
+// Validate returns an error unless the key is a point of G1 which can verify signatures.
+func (pubKey PubKeyBLS12381) Validate() error {
+	_, err := pubKey.point()
+	return err
+}
+
+// AssertIsPubKeyInner implements crypto.PubKeyInner.
+func (pubKey PubKeyBLS12381) AssertIsPubKeyInner() {}
+
+// Address implements crypto.PubKeyInner: the RIPEMD160 of the key, like for the other keys.
+func (pubKey PubKeyBLS12381) Address() []byte {
+	hasher := ripemd160.New()
+	hasher.Write(wire.BinaryBytes(pubKey)) // does not error
+	return hasher.Sum(nil)
+}
+
+// Bytes implements crypto.PubKeyInner.
+func (pubKey PubKeyBLS12381) Bytes() []byte {
+	return wire.BinaryBytes(pubKey.Wrap())
+}
+
+// KeyString implements crypto.PubKeyInner.
+func (pubKey PubKeyBLS12381) KeyString() string {
+	return fmt.Sprintf("%X", pubKey[:])
+}
+
+// VerifyBytes implements crypto.PubKeyInner.
+func (pubKey PubKeyBLS12381) VerifyBytes(msg []byte, sig crypto.Signature) bool {
+	return VerifyAggregateBLS12381([]PubKeyBLS12381{pubKey}, [][]byte{msg}, sig)
+}
+
+// Equals implements crypto.PubKeyInner.
+func (pubKey PubKeyBLS12381) Equals(other crypto.PubKey) bool {
+	otherPubKey, ok := other.Unwrap().(PubKeyBLS12381)
+	return ok && pubKey == otherPubKey
+}
+
+// Wrap implements crypto.PubKeyInner.
+func (pubKey PubKeyBLS12381) Wrap() crypto.PubKey {
+	return crypto.PubKey{pubKey}
+}
+
+func (pubKey PubKeyBLS12381) String() string {
+	return fmt.Sprintf("PubKeyBLS12381{%X}", pubKey[:])
+}
+
+func (pubKey PubKeyBLS12381) MarshalJSON() ([]byte, error) {
+	return data.Encoder.Marshal(pubKey[:])
+}
+
+func (pubKey *PubKeyBLS12381) UnmarshalJSON(enc []byte) error {
+	var ref []byte
+	err := data.Encoder.Unmarshal(&ref, enc)
+	copy(pubKey[:], ref)
+	return err
+}
+
+//-------------------------------------
+
+// SignatureBLS12381 is a compressed point of G2, the signature of one message
+// or the aggregate of the signatures of several messages.
+type SignatureBLS12381 [96]byte
+
+func (sig SignatureBLS12381) point() (*bls12381.PointG2, error) {
+	g2 := bls12381.NewG2()
+	point, err := g2.FromCompressed(sig[:])
+	if err != nil {
+		return nil, err
+	}
+	if !g2.InCorrectSubgroup(point) {
+		return nil, errors.New("Signature is not in the G2 subgroup")
+	}
+	return point, nil
+}
+
+// AssertIsSignatureInner implements crypto.SignatureInner.
+func (sig SignatureBLS12381) AssertIsSignatureInner() {}
+
+// Bytes implements crypto.SignatureInner.
+func (sig SignatureBLS12381) Bytes() []byte {
+	return wire.BinaryBytes(sig.Wrap())
+}
+
+// IsZero implements crypto.SignatureInner.
+func (sig SignatureBLS12381) IsZero() bool {
+	return sig == SignatureBLS12381{}
+}
+
+// String implements crypto.SignatureInner.
+func (sig SignatureBLS12381) String() string {
+	return fmt.Sprintf("/%X.../", cmn.Fingerprint(sig[:]))
+}
+
+// Equals implements crypto.SignatureInner.
+func (sig SignatureBLS12381) Equals(other crypto.Signature) bool {
+	otherSig, ok := other.Unwrap().(SignatureBLS12381)
+	return ok && bytes.Equal(sig[:], otherSig[:])
+}
+
+// Wrap implements crypto.SignatureInner.
+func (sig SignatureBLS12381) Wrap() crypto.Signature {
+	return crypto.Signature{sig}
+}
+
+func (sig SignatureBLS12381) MarshalJSON() ([]byte, error) {
+	return data.Encoder.Marshal(sig[:])
+}
+
+func (sig *SignatureBLS12381) UnmarshalJSON(enc []byte) error {
+	var ref []byte
+	err := data.Encoder.Unmarshal(&ref, enc)
+	copy(sig[:], ref)
+	return err
+}
+
+//-------------------------------------
+
+// AggregateSignaturesBLS12381 adds up the BLS signatures into one,
+// which VerifyAggregateBLS12381 verifies with the keys and messages of all of them.
+func AggregateSignaturesBLS12381(sigs []crypto.Signature) (crypto.Signature, error) {
+	if len(sigs) == 0 {
+		return crypto.Signature{}, errors.New("No signatures to aggregate")
+	}
+	g2 := bls12381.NewG2()
+	aggregate := g2.Zero()
+	for i, sig := range sigs {
+		blsSig, ok := sig.Unwrap().(SignatureBLS12381)
+		if !ok {
+			return crypto.Signature{}, fmt.Errorf("Signature %d is not a BLS12-381 signature", i)
+		}
+		point, err := blsSig.point()
+		if err != nil {
+			return crypto.Signature{}, fmt.Errorf("Invalid signature %d: %v", i, err)
+		}
+		g2.Add(aggregate, aggregate, point)
+	}
+	var res SignatureBLS12381
+	copy(res[:], g2.ToCompressed(aggregate))
+	return res.Wrap(), nil
+}
+
+// VerifyAggregateBLS12381 verifies that sig is the aggregate of the signatures
+// of msgs[i] by pubKeys[i], checking
+// e(g1, sig) == e(pubKeys[0], H(pubKeys[0] || msgs[0])) * ...
+// The augmented messages must all be different, ie. a key can't sign the same
+// message twice in an aggregate.
+func VerifyAggregateBLS12381(pubKeys []PubKeyBLS12381, msgs [][]byte, sig crypto.Signature) bool {
+	if len(pubKeys) == 0 || len(pubKeys) != len(msgs) {
+		return false
+	}
+	seen := make(map[string]struct{}, len(msgs))
+	for i, pubKey := range pubKeys {
+		augmented := string(pubKey[:]) + string(msgs[i])
+		if _, ok := seen[augmented]; ok {
+			return false
+		}
+		seen[augmented] = struct{}{}
+	}
+	blsSig, ok := sig.Unwrap().(SignatureBLS12381)
+	if !ok {
+		return false
+	}
+	sigPoint, err := blsSig.point()
+	if err != nil {
+		return false
+	}
+
+	engine := bls12381.NewEngine()
+	engine.AddPairInv(engine.G1.One(), sigPoint)
+	for i, pubKey := range pubKeys {
+		pubKeyPoint, err := pubKey.point()
+		if err != nil {
+			return false
+		}
+		msgPoint, err := hashToG2(pubKey, msgs[i])
+		if err != nil {
+			return false
+		}
+		engine.AddPair(pubKeyPoint, msgPoint)
+	}
+	return engine.Check()
+}
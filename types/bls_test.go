@@ -0,0 +1,188 @@
+package types
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+
+	bls12381 "github.com/kilic/bls12-381"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	crypto "github.com/tendermint/go-crypto"
+	wire "github.com/tendermint/go-wire"
+	cmn "github.com/tendermint/tmlibs/common"
+)
+
+func TestBLS12381Signatures(t *testing.T) {
+	privKey := GenPrivKeyBLS12381().Wrap()
+	pubKey := privKey.PubKey()
+	require.NoError(t, ValidateValidatorPubKey(pubKey))
+
+	msg := []byte("sign bytes")
+	sig := privKey.Sign(msg)
+	assert.True(t, pubKey.VerifyBytes(msg, sig))
+	assert.False(t, pubKey.VerifyBytes([]byte("other sign bytes"), sig))
+	assert.False(t, GenPrivKeyBLS12381().PubKey().VerifyBytes(msg, sig))
+	assert.Equal(t, sig, privKey.Sign(msg), "signatures must be deterministic")
+
+	// the keys and signatures are encoded like the other ones
+	var pubKey2 crypto.PubKey
+	require.NoError(t, wire.ReadBinaryBytes(pubKey.Bytes(), &pubKey2))
+	assert.True(t, pubKey.Equals(pubKey2))
+	jsonBytes, err := json.Marshal(sig)
+	require.NoError(t, err)
+	var sig2 crypto.Signature
+	require.NoError(t, json.Unmarshal(jsonBytes, &sig2))
+	assert.True(t, sig.Equals(sig2))
+
+	// aggregated, the signatures of several messages verify at once
+	privKeys := []crypto.PrivKey{privKey, GenPrivKeyBLS12381().Wrap(), GenPrivKeyBLS12381().Wrap()}
+	pubKeys := make([]PubKeyBLS12381, len(privKeys))
+	msgs := make([][]byte, len(privKeys))
+	sigs := make([]crypto.Signature, len(privKeys))
+	for i, pk := range privKeys {
+		pubKeys[i] = pk.PubKey().Unwrap().(PubKeyBLS12381)
+		msgs[i] = []byte{byte(i)}
+		sigs[i] = pk.Sign(msgs[i])
+	}
+	aggregated, err := AggregateSignaturesBLS12381(sigs)
+	require.NoError(t, err)
+	assert.True(t, VerifyAggregateBLS12381(pubKeys, msgs, aggregated))
+	assert.False(t, VerifyAggregateBLS12381(pubKeys[:2], msgs[:2], aggregated))
+	msgs[2] = []byte("other")
+	assert.False(t, VerifyAggregateBLS12381(pubKeys, msgs, aggregated))
+
+	_, err = AggregateSignaturesBLS12381([]crypto.Signature{sig, crypto.GenPrivKeyEd25519().Sign(msg)})
+	assert.Error(t, err)
+
+	// a key can't sign the same message twice in an aggregate
+	twice, err := AggregateSignaturesBLS12381([]crypto.Signature{sig, sig})
+	require.NoError(t, err)
+	blsPubKey := pubKey.Unwrap().(PubKeyBLS12381)
+	assert.False(t, VerifyAggregateBLS12381([]PubKeyBLS12381{blsPubKey, blsPubKey}, [][]byte{msg, msg}, twice))
+}
+
+func TestBLS12381RogueKey(t *testing.T) {
+	victim := GenPrivKeyBLS12381().PubKey().Unwrap().(PubKeyBLS12381)
+	victimPoint, err := victim.point()
+	require.NoError(t, err)
+
+	// the rogue key g1^r - victim, for which the attacker alone can sign the
+	// message as if both signed it, were the messages not augmented
+	r := GenPrivKeyBLS12381()
+	g1, g2 := bls12381.NewG1(), bls12381.NewG2()
+	roguePoint := g1.MulScalar(g1.New(), g1.One(), r.scalar())
+	g1.Sub(roguePoint, roguePoint, victimPoint)
+	var rogue PubKeyBLS12381
+	copy(rogue[:], g1.ToCompressed(roguePoint))
+
+	msg := []byte("sign bytes")
+	for _, pubKey := range []PubKeyBLS12381{rogue, victim} {
+		msgPoint, err := hashToG2(pubKey, msg)
+		require.NoError(t, err)
+		var forged SignatureBLS12381
+		copy(forged[:], g2.ToCompressed(g2.MulScalar(g2.New(), msgPoint, r.scalar())))
+		assert.False(t, VerifyAggregateBLS12381([]PubKeyBLS12381{rogue, victim}, [][]byte{msg, msg}, forged.Wrap()))
+	}
+}
+
+func makeBLSCommit(t *testing.T, chainID string, height int64, numValidators int) (*Commit, *ValidatorSet) {
+	vals := make([]*Validator, numValidators)
+	privVals := make([]*PrivValidatorFS, numValidators)
+	for i := range vals {
+		_, tempFilePath := cmn.Tempfile("priv_validator_")
+		privVals[i] = NewPrivValidatorFS(GenPrivKeyBLS12381().Wrap(), tempFilePath)
+		vals[i] = NewValidator(privVals[i].GetPubKey(), 1)
+	}
+	valSet := NewValidatorSet(vals)
+	sort.Sort(PrivValidatorsByAddress(privVals))
+
+	voteSet := NewVoteSet(chainID, height, 0, VoteTypePrecommit, valSet)
+	blockID := BlockID{crypto.CRandBytes(32), PartSetHeader{123, crypto.CRandBytes(32)}}
+	voteProto := &Vote{
+		ValidatorIndex: -1,
+		Height:         height,
+		Round:          0,
+		Type:           VoteTypePrecommit,
+		BlockID:        blockID,
+	}
+	// the last validator is missing
+	for i := 0; i < numValidators-1; i++ {
+		vote := withValidator(voteProto, privVals[i].GetAddress(), i)
+		_, err := signAddVote(privVals[i], vote, voteSet)
+		require.Nil(t, err)
+	}
+	return voteSet.MakeCommit(), valSet
+}
+
+func TestCommitAggregate(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	chainID := "test_chain_id"
+	commit, valSet := makeBLSCommit(t, chainID, 1, 4)
+	require.NoError(valSet.VerifyCommit(chainID, commit.BlockID, 1, commit))
+
+	aggregated, err := commit.Aggregate()
+	require.NoError(err)
+	assert.True(aggregated.IsAggregated())
+	assert.False(commit.IsAggregated(), "the commit is left as it is")
+	for _, precommit := range aggregated.Precommits[:3] {
+		assert.True(precommit.Signature.Empty())
+	}
+	assert.Nil(aggregated.Precommits[3])
+	assert.NoError(aggregated.ValidateBasic())
+	assert.NoError(valSet.VerifyCommit(chainID, commit.BlockID, 1, aggregated))
+	assert.NoError(valSet.VerifyCommitAny(valSet, chainID, commit.BlockID, 1, aggregated))
+	assert.NotEqual(commit.Hash(), aggregated.Hash())
+	assert.NotEqual(commit.RandomBeacon(), aggregated.RandomBeacon())
+
+	// it doesn't verify for other sign bytes
+	assert.Error(valSet.VerifyCommit("other_chain_id", commit.BlockID, 1, aggregated))
+
+	// nor without one of the precommits it aggregates
+	partial := &Commit{BlockID: aggregated.BlockID, AggregatedSignature: aggregated.AggregatedSignature}
+	partial.Precommits = append([]*Vote{}, aggregated.Precommits...)
+	partial.Precommits[2] = nil
+	assert.Error(valSet.VerifyCommit(chainID, commit.BlockID, 1, partial))
+
+	// nor can it keep any signature in the precommits
+	mixed := &Commit{BlockID: aggregated.BlockID, AggregatedSignature: aggregated.AggregatedSignature}
+	mixed.Precommits = append([]*Vote{}, aggregated.Precommits...)
+	mixed.Precommits[0] = commit.Precommits[0]
+	assert.Error(mixed.ValidateBasic())
+	assert.Error(valSet.VerifyCommit(chainID, commit.BlockID, 1, mixed))
+
+	// the ed25519 signatures can't be aggregated
+	_, err = makeCommit(t, 1, 4).Aggregate()
+	assert.Error(err)
+}
+
+func TestVoteSetAddAggregatedCommit(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	chainID := "test_chain_id"
+	commit, valSet := makeBLSCommit(t, chainID, 1, 4)
+	aggregated, err := commit.Aggregate()
+	require.NoError(err)
+
+	voteSet := NewVoteSet(chainID, 1, 0, VoteTypePrecommit, valSet)
+	assert.Error(voteSet.AddAggregatedCommit(commit), "the commit must be aggregated")
+	partial := &Commit{BlockID: aggregated.BlockID, AggregatedSignature: aggregated.AggregatedSignature}
+	partial.Precommits = append([]*Vote{}, aggregated.Precommits...)
+	partial.Precommits[2] = nil
+	assert.Error(voteSet.AddAggregatedCommit(partial))
+	assert.False(voteSet.HasTwoThirdsMajority())
+
+	// the precommits are verified at once, and made into the same commit
+	require.NoError(voteSet.AddAggregatedCommit(aggregated))
+	blockID, ok := voteSet.TwoThirdsMajority()
+	assert.True(ok)
+	assert.Equal(aggregated.BlockID, blockID)
+	assert.Equal(aggregated, voteSet.AggregatedCommit())
+	assert.Equal(aggregated.Hash(), voteSet.MakeCommit().Hash())
+
+	// the signed precommits are no news
+	added, err := voteSet.AddVote(commit.Precommits[0])
+	assert.False(added)
+	assert.NoError(err)
+}
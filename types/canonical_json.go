@@ -1,9 +1,15 @@
 package types
 
 import (
+	"time"
+
 	"github.com/tendermint/go-wire/data"
 )
 
+// TimeFormat is the format of the times in canonical json,
+// with a ms precision so they are the same on all platforms.
+const TimeFormat = "2006-01-02T15:04:05.000Z"
+
 // canonical json is go-wire's json for structs with fields in alphabetical order
 
 type CanonicalJSONBlockID struct {
@@ -25,10 +31,11 @@ type CanonicalJSONProposal struct {
 }
 
 type CanonicalJSONVote struct {
-	BlockID CanonicalJSONBlockID `json:"block_id"`
-	Height  int64                `json:"height"`
-	Round   int                  `json:"round"`
-	Type    byte                 `json:"type"`
+	BlockID   CanonicalJSONBlockID `json:"block_id"`
+	Height    int64                `json:"height"`
+	Round     int                  `json:"round"`
+	Timestamp string               `json:"timestamp"`
+	Type      byte                 `json:"type"`
 }
 
 type CanonicalJSONHeartbeat struct {
@@ -89,6 +96,7 @@ func CanonicalVote(vote *Vote) CanonicalJSONVote {
 		CanonicalBlockID(vote.BlockID),
 		vote.Height,
 		vote.Round,
+		CanonicalTime(vote.Timestamp),
 		vote.Type,
 	}
 }
@@ -102,3 +110,8 @@ func CanonicalHeartbeat(heartbeat *Heartbeat) CanonicalJSONHeartbeat {
 		heartbeat.ValidatorIndex,
 	}
 }
+
+// CanonicalTime formats the time in UTC with TimeFormat.
+func CanonicalTime(t time.Time) string {
+	return t.UTC().Format(TimeFormat)
+}
@@ -0,0 +1,117 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// The reasons a commit fails to verify, in a CommitFailure.
+const (
+	CommitFailureMissing                    = "missing"
+	CommitFailureValidatorsHashMismatch     = "validators_hash_mismatch"
+	CommitFailureBlockIDMismatch            = "block_id_mismatch"
+	CommitFailureWrongSetSize               = "wrong_set_size"
+	CommitFailureWrongHeight                = "wrong_height"
+	CommitFailureWrongRound                 = "wrong_round"
+	CommitFailureNotPrecommit               = "not_precommit"
+	CommitFailureWrongValidator             = "wrong_validator"
+	CommitFailureInvalidSignature           = "invalid_signature"
+	CommitFailureInvalidAggregatedSignature = "invalid_aggregated_signature"
+	CommitFailureInsufficientVotingPower    = "insufficient_voting_power"
+)
+
+// CommitFailure is a reason a commit fails to verify.
+type CommitFailure struct {
+	Reason string `json:"reason"`
+	// Index is the index of the precommit at fault, or -1 for the commit.
+	Index   int    `json:"index"`
+	Message string `json:"message"`
+}
+
+// CommitVerification is the outcome of the verification of a commit for a
+// header by a validator set, with all the reasons it fails rather than the
+// first one, for the relayers and bridges which verify the headers of a chain.
+type CommitVerification struct {
+	Valid    bool            `json:"valid"`
+	Failures []CommitFailure `json:"failures"`
+
+	// the voting power of the valid precommits for the block, and the power
+	// needed
+	SignedVotingPower int64 `json:"signed_voting_power"`
+	NeededVotingPower int64 `json:"needed_voting_power"`
+	TotalVotingPower  int64 `json:"total_voting_power"`
+}
+
+func (cv *CommitVerification) fail(reason string, index int, format string, args ...interface{}) {
+	cv.Failures = append(cv.Failures, CommitFailure{reason, index, fmt.Sprintf(format, args...)})
+}
+
+// VerifyCommitForHeader checks, like ValidatorSet.VerifyCommit, that the commit
+// is for the header, made by the validator set of the header, and signed by
+// more than 2/3 of its voting power, and returns why not, if not.
+func VerifyCommitForHeader(header *Header, commit *Commit, valSet *ValidatorSet) *CommitVerification {
+	cv := &CommitVerification{Failures: []CommitFailure{}}
+	if header == nil || commit == nil || valSet == nil || valSet.Size() == 0 {
+		cv.fail(CommitFailureMissing, -1, "The header, the commit and the validators are needed")
+		return cv
+	}
+	cv.TotalVotingPower = valSet.TotalVotingPower()
+	cv.NeededVotingPower = cv.TotalVotingPower*2/3 + 1
+
+	if !bytes.Equal(valSet.Hash(), header.ValidatorsHash) {
+		cv.fail(CommitFailureValidatorsHashMismatch, -1, "The validators hash %X, not %X of the header", valSet.Hash(), header.ValidatorsHash)
+	}
+	if !bytes.Equal(commit.BlockID.Hash, header.Hash()) {
+		cv.fail(CommitFailureBlockIDMismatch, -1, "The commit is for the block %X, not %X of the header", commit.BlockID.Hash, header.Hash())
+	}
+	if valSet.Size() != len(commit.Precommits) {
+		cv.fail(CommitFailureWrongSetSize, -1, "%d precommits for %d validators", len(commit.Precommits), valSet.Size())
+		return cv
+	}
+
+	aggregated := commit.IsAggregated()
+	if aggregated {
+		if err := valSet.verifyAggregatedSignature(header.ChainID, commit); err != nil {
+			cv.fail(CommitFailureInvalidAggregatedSignature, -1, "%v", err)
+		}
+	}
+
+	round := commit.Round()
+	for idx, precommit := range commit.Precommits {
+		// may be nil if validator skipped.
+		if precommit == nil {
+			continue
+		}
+		valid := true
+		if precommit.Height != header.Height {
+			cv.fail(CommitFailureWrongHeight, idx, "Height %d, not %d", precommit.Height, header.Height)
+			valid = false
+		}
+		if precommit.Round != round {
+			cv.fail(CommitFailureWrongRound, idx, "Round %d, not %d", precommit.Round, round)
+			valid = false
+		}
+		if precommit.Type != VoteTypePrecommit {
+			cv.fail(CommitFailureNotPrecommit, idx, "Vote type %X", precommit.Type)
+			valid = false
+		}
+		_, val := valSet.GetByIndex(idx)
+		if precommit.ValidatorIndex != idx || !bytes.Equal(precommit.ValidatorAddress, val.Address) {
+			cv.fail(CommitFailureWrongValidator, idx, "From the validator %X at %d, not %X", precommit.ValidatorAddress, precommit.ValidatorIndex, val.Address)
+			valid = false
+		}
+		if !aggregated && !val.PubKey.VerifyBytes(SignBytes(header.ChainID, precommit), precommit.Signature) {
+			cv.fail(CommitFailureInvalidSignature, idx, "Invalid signature of %X", val.Address)
+			valid = false
+		}
+		if valid && commit.BlockID.Equals(precommit.BlockID) {
+			cv.SignedVotingPower += val.VotingPower
+		}
+	}
+
+	if cv.SignedVotingPower < cv.NeededVotingPower {
+		cv.fail(CommitFailureInsufficientVotingPower, -1, "Got %d of the voting power, needed %d", cv.SignedVotingPower, cv.NeededVotingPower)
+	}
+	cv.Valid = len(cv.Failures) == 0
+	return cv
+}
@@ -0,0 +1,101 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	crypto "github.com/tendermint/go-crypto"
+)
+
+// makeHeaderCommit returns a header of the valSet at the height, and its commit
+// signed by the first signers of the privValidators.
+func makeHeaderCommit(t *testing.T, valSet *ValidatorSet, privValidators []*PrivValidatorFS, height int64, signers int) (*Header, *Commit) {
+	header := &Header{
+		ChainID:        "test_chain_id",
+		Height:         height,
+		Time:           time.Now(),
+		ValidatorsHash: valSet.Hash(),
+		AppHash:        crypto.CRandBytes(20),
+	}
+	blockID := BlockID{header.Hash(), PartSetHeader{1, crypto.CRandBytes(20)}}
+	voteSet := NewVoteSet(header.ChainID, header.Height, 0, VoteTypePrecommit, valSet)
+	voteProto := &Vote{
+		ValidatorIndex: -1,
+		Height:         header.Height,
+		Round:          0,
+		Type:           VoteTypePrecommit,
+		BlockID:        blockID,
+	}
+	for i := 0; i < signers; i++ {
+		addr := privValidators[i].GetAddress()
+		idx, _ := valSet.GetByAddress(addr)
+		_, err := signAddVote(privValidators[i], withValidator(voteProto, addr, idx), voteSet)
+		require.Nil(t, err)
+	}
+	return header, voteSet.MakeCommit()
+}
+
+func reasons(cv *CommitVerification) []string {
+	var reasons []string
+	for _, f := range cv.Failures {
+		reasons = append(reasons, f.Reason)
+	}
+	return reasons
+}
+
+func TestVerifyCommitForHeader(t *testing.T) {
+	assert := assert.New(t)
+	valSet, privValidators := RandValidatorSet(4, 1)
+
+	header, commit := makeHeaderCommit(t, valSet, privValidators, 5, 4)
+	cv := VerifyCommitForHeader(header, commit, valSet)
+	assert.True(cv.Valid, "%v", cv.Failures)
+	assert.EqualValues(4, cv.SignedVotingPower)
+	assert.EqualValues(3, cv.NeededVotingPower)
+	assert.Nil(valSet.VerifyCommit(header.ChainID, commit.BlockID, header.Height, commit))
+
+	// half is not enough
+	header, commit = makeHeaderCommit(t, valSet, privValidators, 6, 2)
+	cv = VerifyCommitForHeader(header, commit, valSet)
+	assert.False(cv.Valid)
+	assert.Equal([]string{CommitFailureInsufficientVotingPower}, reasons(cv))
+
+	// another header
+	header, commit = makeHeaderCommit(t, valSet, privValidators, 7, 4)
+	other := *header
+	other.AppHash = crypto.CRandBytes(20)
+	cv = VerifyCommitForHeader(&other, commit, valSet)
+	assert.Equal([]string{CommitFailureBlockIDMismatch}, reasons(cv))
+
+	// another validator set
+	otherSet, _ := RandValidatorSet(4, 1)
+	cv = VerifyCommitForHeader(header, commit, otherSet)
+	assert.Contains(reasons(cv), CommitFailureValidatorsHashMismatch)
+	assert.Contains(reasons(cv), CommitFailureInvalidSignature)
+	assert.Contains(reasons(cv), CommitFailureInsufficientVotingPower)
+
+	// all the bad precommits are reported
+	for _, idx := range []int{1, 2} {
+		bad := commit.Precommits[idx].Copy()
+		bad.Round = 1
+		commit.Precommits[idx] = bad
+	}
+	cv = VerifyCommitForHeader(header, commit, valSet)
+	assert.False(cv.Valid)
+	var badIndexes []int
+	for _, f := range cv.Failures {
+		if f.Reason == CommitFailureWrongRound {
+			badIndexes = append(badIndexes, f.Index)
+		}
+	}
+	assert.Equal([]int{1, 2}, badIndexes)
+	assert.Contains(reasons(cv), CommitFailureInvalidSignature)
+
+	// nothing to verify
+	cv = VerifyCommitForHeader(header, nil, valSet)
+	assert.Equal([]string{CommitFailureMissing}, reasons(cv))
+	cv = VerifyCommitForHeader(header, &Commit{BlockID: commit.BlockID}, valSet)
+	assert.Equal([]string{CommitFailureWrongSetSize}, reasons(cv))
+}
@@ -92,7 +92,12 @@ func (b *EventBus) PublishEventVote(event EventDataVote) error {
 func (b *EventBus) PublishEventTx(event EventDataTx) error {
 	// no explicit deadline for publishing events
 	ctx := context.Background()
+	b.pubsub.PublishWithTags(ctx, TMEventData{event}, b.txEventTags(event))
+	return nil
+}
 
+// txEventTags returns the tags of the tx event, from its Result and predefined.
+func (b *EventBus) txEventTags(event EventDataTx) map[string]interface{} {
 	tags := make(map[string]interface{})
 
 	// validate and fill tags from tx result
@@ -120,11 +125,30 @@ func (b *EventBus) PublishEventTx(event EventDataTx) error {
 
 	logIfTagExists(TxHeightKey, tags, b.Logger)
 	tags[TxHeightKey] = event.Height
+	return tags
+}
+
+func (b *EventBus) PublishEventValidatorSetUpdates(event EventDataValidatorSetUpdates) error {
+	return b.Publish(EventValidatorSetUpdates, TMEventData{event})
+}
 
+// PublishEventMempoolTx publishes a tx accepted into the mempool, with its hash
+// in the TxHashKey tag, so a client can wait for a given tx to be accepted.
+func (b *EventBus) PublishEventMempoolTx(event EventDataMempoolTx) error {
+	// no explicit deadline for publishing events
+	ctx := context.Background()
+	tags := map[string]interface{}{
+		EventTypeKey: EventMempoolTx,
+		TxHashKey:    fmt.Sprintf("%X", []byte(event.Hash)),
+	}
 	b.pubsub.PublishWithTags(ctx, TMEventData{event}, tags)
 	return nil
 }
 
+func (b *EventBus) PublishEventValidatorExit(event EventDataValidatorExit) error {
+	return b.Publish(EventValidatorExit, TMEventData{event})
+}
+
 func (b *EventBus) PublishEventProposalHeartbeat(event EventDataProposalHeartbeat) error {
 	return b.Publish(EventProposalHeartbeat, TMEventData{event})
 }
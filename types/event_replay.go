@@ -0,0 +1,150 @@
+package types
+
+import (
+	"context"
+	"fmt"
+
+	tmpubsub "github.com/tendermint/tmlibs/pubsub"
+)
+
+// EventReplayer loads the events of the past blocks, for EventBus.SubscribeFrom.
+type EventReplayer interface {
+	// LatestHeight returns the height of the latest block whose events were
+	// published, or are being.
+	LatestHeight() int64
+
+	// BlockEvents returns the NewBlock, NewBlockHeader, Tx and
+	// ValidatorSetUpdates events of the block at the height, in the order they
+	// were published.
+	BlockEvents(height int64) ([]TMEventData, error)
+}
+
+// EventHeight returns the height of the block of the event, if it has one.
+func EventHeight(event TMEventData) (int64, bool) {
+	switch data := event.Unwrap().(type) {
+	case EventDataNewBlock:
+		return data.Block.Height, true
+	case EventDataNewBlockHeader:
+		return data.Header.Height, true
+	case EventDataTx:
+		return data.Height, true
+	case EventDataValidatorSetUpdates:
+		return data.Height, true
+	}
+	return 0, false
+}
+
+// SubscribeFrom subscribes to the query like Subscribe, but first sends out the
+// past events matching it, of the blocks from the height up to the latest one
+// of the replayer, which it returns. The live events are queued meanwhile, not
+// to block the bus, and those of the blocks replayed skipped, so that none is
+// missed or sent twice. The past events are sent at the pace out is read.
+//
+// out is closed once unsubscribed, or if a past block fails to load.
+func (b *EventBus) SubscribeFrom(ctx context.Context, subscriber string, query tmpubsub.Query,
+	height int64, replayer EventReplayer, out chan<- interface{}) (int64, error) {
+
+	if height <= 0 {
+		return 0, fmt.Errorf("Height must be greater than 0, got %d", height)
+	}
+	live := make(chan interface{})
+	if err := b.Subscribe(ctx, subscriber, query, live); err != nil {
+		return 0, err
+	}
+	// the live events from here on are either of the latest block or newer
+	latest := replayer.LatestHeight()
+
+	// fail early if the replay can't start, eg. the blocks were pruned
+	var first []TMEventData
+	if height <= latest {
+		var err error
+		if first, err = replayer.BlockEvents(height); err != nil {
+			go func() {
+				for range live {
+				}
+			}()
+			if err := b.Unsubscribe(context.Background(), subscriber, query); err != nil {
+				b.Logger.Error("Failed to unsubscribe", "subscriber", subscriber, "query", query, "err", err)
+			}
+			return 0, err
+		}
+		first = b.matchPast(query, first)
+	}
+
+	go b.replay(subscriber, query, height, latest, first, replayer, live, out)
+	return latest, nil
+}
+
+// replay sends out the past events of the heights up to latest, the first ones
+// already loaded, then the live events.
+func (b *EventBus) replay(subscriber string, query tmpubsub.Query, height, latest int64,
+	past []TMEventData, replayer EventReplayer, live <-chan interface{}, out chan<- interface{}) {
+
+	defer close(out)
+	var queued []interface{}
+	for {
+		var next interface{}
+		var send chan<- interface{}
+		switch {
+		case len(past) > 0:
+			next, send = past[0], out
+		case height < latest:
+			height++
+			events, err := replayer.BlockEvents(height)
+			if err != nil {
+				b.Logger.Error("Failed to replay the events", "subscriber", subscriber, "height", height, "err", err)
+				// closes live
+				go b.Unsubscribe(context.Background(), subscriber, query)
+				latest = height
+			}
+			past = b.matchPast(query, events)
+			continue
+		case len(queued) > 0:
+			next, send = queued[0], out
+		}
+
+		// nil send blocks, until live has an event
+		select {
+		case send <- next:
+			if len(past) > 0 {
+				past = past[1:]
+			} else {
+				queued = queued[1:]
+			}
+		case event, ok := <-live:
+			if !ok {
+				return
+			}
+			if h, ok := EventHeight(event.(TMEventData)); ok && h <= latest {
+				// replayed
+				continue
+			}
+			queued = append(queued, event)
+		}
+	}
+}
+
+// matchPast returns the past events matching the query, by the tags they were
+// published with.
+func (b *EventBus) matchPast(query tmpubsub.Query, events []TMEventData) []TMEventData {
+	var matched []TMEventData
+	for _, event := range events {
+		var tags map[string]interface{}
+		switch data := event.Unwrap().(type) {
+		case EventDataNewBlock:
+			tags = map[string]interface{}{EventTypeKey: EventNewBlock}
+		case EventDataNewBlockHeader:
+			tags = map[string]interface{}{EventTypeKey: EventNewBlockHeader}
+		case EventDataTx:
+			tags = b.txEventTags(data)
+		case EventDataValidatorSetUpdates:
+			tags = map[string]interface{}{EventTypeKey: EventValidatorSetUpdates}
+		default:
+			continue
+		}
+		if query.Matches(tags) {
+			matched = append(matched, event)
+		}
+	}
+	return matched
+}
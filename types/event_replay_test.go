@@ -0,0 +1,90 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testReplayer has a block with a tx at each height from base to latest.
+type testReplayer struct {
+	base, latest int64
+}
+
+func (r testReplayer) LatestHeight() int64 { return r.latest }
+
+func (r testReplayer) BlockEvents(height int64) ([]TMEventData, error) {
+	if height < r.base || height > r.latest {
+		return nil, fmt.Errorf("no block at height %d", height)
+	}
+	return []TMEventData{
+		{EventDataNewBlock{&Block{Header: &Header{Height: height}}}},
+		{EventDataNewBlockHeader{&Header{Height: height}}},
+		{testTxEvent(height)},
+	}, nil
+}
+
+func testTxEvent(height int64) EventDataTx {
+	return EventDataTx{TxResult{Height: height, Tx: Tx(fmt.Sprintf("tx-%d", height))}}
+}
+
+func TestEventBusSubscribeFrom(t *testing.T) {
+	eventBus := NewEventBus()
+	require.Nil(t, eventBus.Start())
+	defer eventBus.Stop()
+	ctx := context.Background()
+
+	out := make(chan interface{})
+	latest, err := eventBus.SubscribeFrom(ctx, "test", EventQueryTx, 2, testReplayer{1, 3}, out)
+	require.Nil(t, err)
+	assert.EqualValues(t, 3, latest)
+
+	// the live events don't wait for the replay, the replayed ones are skipped
+	require.Nil(t, eventBus.PublishEventTx(testTxEvent(3)))
+	require.Nil(t, eventBus.PublishEventTx(testTxEvent(4)))
+	require.Nil(t, eventBus.PublishEventNewBlock(EventDataNewBlock{&Block{Header: &Header{Height: 4}}}))
+
+	var heights []int64
+	for len(heights) < 3 {
+		select {
+		case event := <-out:
+			tx, ok := event.(TMEventData).Unwrap().(EventDataTx)
+			require.True(t, ok, "%v", event)
+			heights = append(heights, tx.Height)
+		case <-time.After(time.Second):
+			t.Fatalf("got only the txs of the heights %v", heights)
+		}
+	}
+	assert.Equal(t, []int64{2, 3, 4}, heights)
+
+	// out is closed once unsubscribed
+	require.Nil(t, eventBus.Unsubscribe(ctx, "test", EventQueryTx))
+	select {
+	case _, ok := <-out:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("out not closed")
+	}
+
+	// the heights which can't be replayed
+	_, err = eventBus.SubscribeFrom(ctx, "test", EventQueryTx, 0, testReplayer{1, 3}, make(chan interface{}))
+	assert.NotNil(t, err)
+	_, err = eventBus.SubscribeFrom(ctx, "test", EventQueryTx, 1, testReplayer{2, 3}, make(chan interface{}))
+	assert.NotNil(t, err, "pruned")
+
+	// nothing to replay yet
+	out = make(chan interface{})
+	_, err = eventBus.SubscribeFrom(ctx, "test", EventQueryTx, 5, testReplayer{1, 3}, out)
+	require.Nil(t, err)
+	require.Nil(t, eventBus.PublishEventTx(testTxEvent(4)))
+	select {
+	case event := <-out:
+		assert.EqualValues(t, 4, event.(TMEventData).Unwrap().(EventDataTx).Height)
+	case <-time.After(time.Second):
+		t.Fatal("no live event")
+	}
+}
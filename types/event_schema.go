@@ -0,0 +1,321 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	abci "github.com/tendermint/abci/types"
+)
+
+/*
+
+Versioned event schema.
+
+Websocket subscribers asking for a schema version get the events as an
+EventMessage, whose data has a fixed JSON layout for that version,
+independent of the internal types:
+
+	{"schema_version": 1, "type": "new_block", "data": {...}}
+
+Within a schema version, fields are never removed, renamed or retyped, and
+their meaning doesn't change. New fields and new event types may be added,
+so consumers must ignore what they don't know. Any other change makes a new
+version, and the previous one is still served for at least one release.
+
+Bytes are upper case hex strings, times are RFC3339 with nanoseconds.
+
+*/
+
+// EventSchemaVersion is the latest version of the event schema.
+const EventSchemaVersion = 1
+
+// Event types of the event schema
+const (
+	EventSchemaNewBlock            = "new_block"
+	EventSchemaNewBlockHeader      = "new_block_header"
+	EventSchemaTx                  = "tx"
+	EventSchemaVote                = "vote"
+	EventSchemaValidatorSetUpdates = "validator_set_updates"
+	EventSchemaMempoolTx           = "mempool_tx"
+	EventSchemaValidatorExit       = "validator_exit"
+)
+
+// ErrNoEventSchema is returned for the events that are not part of the event schema,
+// eg. the internal consensus steps.
+var ErrNoEventSchema = errors.New("Event not in the event schema")
+
+// EventMessage is an event in a version of the event schema.
+type EventMessage struct {
+	SchemaVersion int         `json:"schema_version"`
+	Type          string      `json:"type"`
+	Data          interface{} `json:"data"`
+}
+
+// NewEventMessage converts the event data to the given version of the event schema.
+func NewEventMessage(data TMEventData, version int) (*EventMessage, error) {
+	if version != 1 {
+		return nil, fmt.Errorf("Unknown event schema version %d, the latest is %d", version, EventSchemaVersion)
+	}
+	msg := &EventMessage{SchemaVersion: version}
+	switch event := data.Unwrap().(type) {
+	case EventDataNewBlock:
+		msg.Type = EventSchemaNewBlock
+		msg.Data = newBlockEventV1(event.Block)
+	case EventDataNewBlockHeader:
+		msg.Type = EventSchemaNewBlockHeader
+		msg.Data = newHeaderEventV1(event.Header)
+	case EventDataTx:
+		msg.Type = EventSchemaTx
+		msg.Data = newTxEventV1(event.TxResult)
+	case EventDataVote:
+		msg.Type = EventSchemaVote
+		msg.Data = newVoteEventV1(event.Vote)
+	case EventDataValidatorSetUpdates:
+		msg.Type = EventSchemaValidatorSetUpdates
+		msg.Data = newValidatorSetUpdatesEventV1(event)
+	case EventDataMempoolTx:
+		msg.Type = EventSchemaMempoolTx
+		msg.Data = newMempoolTxEventV1(event)
+	case EventDataValidatorExit:
+		msg.Type = EventSchemaValidatorExit
+		msg.Data = EventValidatorExitV1{
+			Address:    hexV1(event.Address),
+			StopHeight: event.StopHeight,
+			Stopped:    event.Stopped,
+		}
+	default:
+		return nil, ErrNoEventSchema
+	}
+	return msg, nil
+}
+
+// UnmarshalJSON decodes the data into the type of the event,
+// or leaves it as a json.RawMessage for unknown event types and versions.
+func (msg *EventMessage) UnmarshalJSON(bz []byte) error {
+	var raw struct {
+		SchemaVersion int             `json:"schema_version"`
+		Type          string          `json:"type"`
+		Data          json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(bz, &raw); err != nil {
+		return err
+	}
+	msg.SchemaVersion, msg.Type, msg.Data = raw.SchemaVersion, raw.Type, raw.Data
+	if raw.SchemaVersion != 1 {
+		return nil
+	}
+	var data interface{}
+	switch raw.Type {
+	case EventSchemaNewBlock:
+		data = new(EventNewBlockV1)
+	case EventSchemaNewBlockHeader:
+		data = new(EventHeaderV1)
+	case EventSchemaTx:
+		data = new(EventTxV1)
+	case EventSchemaVote:
+		data = new(EventVoteV1)
+	case EventSchemaValidatorSetUpdates:
+		data = new(EventValidatorSetUpdatesV1)
+	case EventSchemaMempoolTx:
+		data = new(EventMempoolTxV1)
+	case EventSchemaValidatorExit:
+		data = new(EventValidatorExitV1)
+	default:
+		return nil
+	}
+	if err := json.Unmarshal(raw.Data, data); err != nil {
+		return errors.Wrapf(err, "Error decoding %s event", raw.Type)
+	}
+	msg.Data = data
+	return nil
+}
+
+//--------------------------------------------------------------------------------
+// Version 1
+
+// EventHeaderV1 is a block header, and the data of the new_block_header events.
+type EventHeaderV1 struct {
+	ChainID         string    `json:"chain_id"`
+	Height          int64     `json:"height"`
+	Time            time.Time `json:"time"`
+	NumTxs          int       `json:"num_txs"`
+	Hash            string    `json:"hash"`
+	LastBlockHash   string    `json:"last_block_hash"`
+	LastCommitHash  string    `json:"last_commit_hash"`
+	DataHash        string    `json:"data_hash"`
+	ValidatorsHash  string    `json:"validators_hash"`
+	AppHash         string    `json:"app_hash"`
+	LastResultsHash string    `json:"last_results_hash"`
+}
+
+// EventNewBlockV1 is the data of the new_block events.
+type EventNewBlockV1 struct {
+	Header EventHeaderV1 `json:"header"`
+	Txs    []string      `json:"txs"`
+}
+
+// EventTagV1 is a tag of a tx, with a number or a string value.
+type EventTagV1 struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+// EventTxV1 is the data of the tx events.
+type EventTxV1 struct {
+	Height int64        `json:"height"`
+	Index  uint32       `json:"index"`
+	Hash   string       `json:"hash"`
+	Tx     string       `json:"tx"`
+	Code   uint32       `json:"code"`
+	Data   string       `json:"data"`
+	Log    string       `json:"log"`
+	Tags   []EventTagV1 `json:"tags"`
+}
+
+// EventVoteV1 is the data of the vote events.
+type EventVoteV1 struct {
+	Height           int64  `json:"height"`
+	Round            int    `json:"round"`
+	Type             string `json:"type"` // prevote or precommit
+	ValidatorAddress string `json:"validator_address"`
+	ValidatorIndex   int    `json:"validator_index"`
+	BlockHash        string `json:"block_hash"` // empty for a nil vote
+	Signature        string `json:"signature"`
+}
+
+// EventValidatorUpdateV1 is the new power of a validator, 0 if it's removed.
+type EventValidatorUpdateV1 struct {
+	PubKey string `json:"pub_key"`
+	Power  int64  `json:"power"`
+}
+
+// EventValidatorSetUpdatesV1 is the data of the validator_set_updates events,
+// the updates returned by EndBlock at the height, which apply from the next block.
+type EventValidatorSetUpdatesV1 struct {
+	Height  int64                    `json:"height"`
+	Updates []EventValidatorUpdateV1 `json:"updates"`
+}
+
+// EventMempoolTxV1 is the data of the mempool_tx events, a tx accepted into the mempool.
+type EventMempoolTxV1 struct {
+	Hash      string `json:"hash"`
+	Size      int    `json:"size"`
+	Priority  int64  `json:"priority"`
+	GasWanted int64  `json:"gas_wanted"`
+	Height    int64  `json:"height"`
+}
+
+// EventValidatorExitV1 is the data of the validator_exit events, the exit of
+// the validator of the node after the stop height, 0 if canceled, announced
+// when scheduled and once stopped.
+type EventValidatorExitV1 struct {
+	Address    string `json:"address"`
+	StopHeight int64  `json:"stop_height"`
+	Stopped    bool   `json:"stopped"`
+}
+
+func hexV1(bz []byte) string {
+	return fmt.Sprintf("%X", bz)
+}
+
+func newHeaderEventV1(header *Header) EventHeaderV1 {
+	return EventHeaderV1{
+		ChainID:         header.ChainID,
+		Height:          header.Height,
+		Time:            header.Time,
+		NumTxs:          header.NumTxs,
+		Hash:            hexV1(header.Hash()),
+		LastBlockHash:   hexV1(header.LastBlockID.Hash),
+		LastCommitHash:  hexV1(header.LastCommitHash),
+		DataHash:        hexV1(header.DataHash),
+		ValidatorsHash:  hexV1(header.ValidatorsHash),
+		AppHash:         hexV1(header.AppHash),
+		LastResultsHash: hexV1(header.LastResultsHash),
+	}
+}
+
+func newBlockEventV1(block *Block) EventNewBlockV1 {
+	txs := make([]string, len(block.Txs))
+	for i, tx := range block.Txs {
+		txs[i] = hexV1(tx)
+	}
+	return EventNewBlockV1{
+		Header: newHeaderEventV1(block.Header),
+		Txs:    txs,
+	}
+}
+
+func newTxEventV1(txResult TxResult) EventTxV1 {
+	tags := make([]EventTagV1, 0, len(txResult.Result.Tags))
+	for _, tag := range txResult.Result.Tags {
+		switch tag.ValueType {
+		case abci.KVPair_STRING:
+			tags = append(tags, EventTagV1{tag.Key, tag.ValueString})
+		case abci.KVPair_INT:
+			tags = append(tags, EventTagV1{tag.Key, tag.ValueInt})
+		}
+	}
+	return EventTxV1{
+		Height: txResult.Height,
+		Index:  txResult.Index,
+		Hash:   hexV1(txResult.Tx.Hash()),
+		Tx:     hexV1(txResult.Tx),
+		Code:   txResult.Result.Code,
+		Data:   hexV1(txResult.Result.Data),
+		Log:    txResult.Result.Log,
+		Tags:   tags,
+	}
+}
+
+func newVoteEventV1(vote *Vote) EventVoteV1 {
+	voteV1 := EventVoteV1{
+		Height:           vote.Height,
+		Round:            vote.Round,
+		Type:             voteTypeV1(vote.Type),
+		ValidatorAddress: hexV1(vote.ValidatorAddress),
+		ValidatorIndex:   vote.ValidatorIndex,
+		BlockHash:        hexV1(vote.BlockID.Hash),
+	}
+	if !vote.Signature.Empty() {
+		voteV1.Signature = hexV1(vote.Signature.Bytes())
+	}
+	return voteV1
+}
+
+func voteTypeV1(voteType byte) string {
+	switch voteType {
+	case VoteTypePrevote:
+		return "prevote"
+	case VoteTypePrecommit:
+		return "precommit"
+	default:
+		return fmt.Sprintf("unknown(%d)", voteType)
+	}
+}
+
+func newValidatorSetUpdatesEventV1(event EventDataValidatorSetUpdates) EventValidatorSetUpdatesV1 {
+	updates := make([]EventValidatorUpdateV1, len(event.Updates))
+	for i, v := range event.Updates {
+		updates[i] = EventValidatorUpdateV1{
+			PubKey: hexV1(v.PubKey),
+			Power:  v.Power,
+		}
+	}
+	return EventValidatorSetUpdatesV1{
+		Height:  event.Height,
+		Updates: updates,
+	}
+}
+
+func newMempoolTxEventV1(event EventDataMempoolTx) EventMempoolTxV1 {
+	return EventMempoolTxV1{
+		Hash:      hexV1(event.Hash),
+		Size:      event.Size,
+		Priority:  event.Priority,
+		GasWanted: event.GasWanted,
+		Height:    event.Height,
+	}
+}
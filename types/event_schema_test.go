@@ -0,0 +1,121 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/tendermint/abci/types"
+)
+
+func TestEventMessageV1(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	block, _ := MakeBlock(3, "test_chain", []Tx{Tx("foo")}, new(Commit),
+		BlockID{}, []byte("vals"), []byte("app"), nil, 1024)
+	vote := &Vote{
+		ValidatorAddress: []byte("val"),
+		ValidatorIndex:   2,
+		Height:           3,
+		Round:            1,
+		Type:             VoteTypePrecommit,
+	}
+	txResult := TxResult{
+		Height: 3,
+		Tx:     Tx("foo"),
+		Result: abci.ResponseDeliverTx{
+			Code: 1,
+			Log:  "bad",
+			Tags: []*abci.KVPair{
+				{Key: "account", ValueType: abci.KVPair_STRING, ValueString: "alice"},
+				{Key: "amount", ValueType: abci.KVPair_INT, ValueInt: 10},
+			},
+		},
+	}
+	header := newHeaderEventV1(block.Header)
+	assert.Equal("test_chain", header.ChainID)
+	assert.EqualValues(3, header.Height)
+	assert.Equal(1, header.NumTxs)
+	assert.Equal(hexV1(block.Hash()), header.Hash)
+	assert.Equal("76616C73", header.ValidatorsHash)
+	assert.Equal("617070", header.AppHash)
+	assert.Equal("", header.LastResultsHash)
+
+	valUpdates := EventDataValidatorSetUpdates{3, []*abci.Validator{{PubKey: []byte{0xAB}, Power: 0}}}
+	mempoolTx := EventDataMempoolTx{Hash: Tx("foo").Hash(), Size: 3, Priority: 10, GasWanted: 5, Height: 2}
+
+	cases := []struct {
+		data      TMEventDataInner
+		eventType string
+		expected  interface{}
+	}{
+		{EventDataNewBlock{block}, EventSchemaNewBlock, &EventNewBlockV1{
+			Header: header,
+			Txs:    []string{"666F6F"},
+		}},
+		{EventDataNewBlockHeader{block.Header}, EventSchemaNewBlockHeader, &header},
+		{EventDataTx{txResult}, EventSchemaTx, &EventTxV1{
+			Height: 3,
+			Hash:   hexV1(Tx("foo").Hash()),
+			Tx:     "666F6F",
+			Code:   1,
+			Log:    "bad",
+			Tags:   []EventTagV1{{"account", "alice"}, {"amount", float64(10)}},
+		}},
+		{EventDataVote{vote}, EventSchemaVote, &EventVoteV1{
+			Height:           3,
+			Round:            1,
+			Type:             "precommit",
+			ValidatorAddress: "76616C",
+			ValidatorIndex:   2,
+		}},
+		{valUpdates, EventSchemaValidatorSetUpdates, &EventValidatorSetUpdatesV1{
+			Height:  3,
+			Updates: []EventValidatorUpdateV1{{"AB", 0}},
+		}},
+		{mempoolTx, EventSchemaMempoolTx, &EventMempoolTxV1{
+			Hash:      hexV1(Tx("foo").Hash()),
+			Size:      3,
+			Priority:  10,
+			GasWanted: 5,
+			Height:    2,
+		}},
+		{EventDataValidatorExit{Address: []byte("val"), StopHeight: 10}, EventSchemaValidatorExit, &EventValidatorExitV1{
+			Address:    "76616C",
+			StopHeight: 10,
+		}},
+	}
+	for _, c := range cases {
+		msg, err := NewEventMessage(TMEventData{c.data}, 1)
+		require.Nil(err, c.eventType)
+		assert.Equal(c.eventType, msg.Type)
+
+		bz, err := json.Marshal(msg)
+		require.Nil(err, c.eventType)
+		decoded := new(EventMessage)
+		require.Nil(json.Unmarshal(bz, decoded), c.eventType)
+		assert.Equal(1, decoded.SchemaVersion)
+		assert.Equal(c.eventType, decoded.Type)
+		if header, ok := decoded.Data.(*EventHeaderV1); ok {
+			// no monotonic clock reading after decoding
+			header.Time = block.Time
+		}
+		if b, ok := decoded.Data.(*EventNewBlockV1); ok {
+			b.Header.Time = block.Time
+		}
+		assert.Equal(c.expected, decoded.Data, c.eventType)
+	}
+
+	// the other events are not in the schema
+	_, err := NewEventMessage(TMEventData{EventDataRoundState{Height: 3}}, 1)
+	assert.Equal(ErrNoEventSchema, err)
+	_, err = NewEventMessage(TMEventData{EventDataVote{vote}}, 2)
+	assert.NotNil(err)
+
+	// unknown types are left undecoded
+	decoded := new(EventMessage)
+	require.Nil(json.Unmarshal([]byte(`{"schema_version":1,"type":"new_thing","data":{"a":1}}`), decoded))
+	assert.Equal(json.RawMessage(`{"a":1}`), decoded.Data)
+}
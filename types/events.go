@@ -3,9 +3,10 @@ package types
 import (
 	"fmt"
 
+	abci "github.com/tendermint/abci/types"
 	"github.com/tendermint/go-wire/data"
+	tmquery "github.com/tendermint/tendermint/types/query"
 	tmpubsub "github.com/tendermint/tmlibs/pubsub"
-	tmquery "github.com/tendermint/tmlibs/pubsub/query"
 )
 
 // Reserved event types
@@ -29,6 +30,10 @@ const (
 	EventUnlock            = "Unlock"
 	EventVote              = "Vote"
 	EventProposalHeartbeat = "ProposalHeartbeat"
+
+	EventValidatorSetUpdates = "ValidatorSetUpdates"
+	EventMempoolTx           = "MempoolTx"
+	EventValidatorExit       = "ValidatorExit"
 )
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -42,6 +47,10 @@ var (
 	EventDataNameRoundState        = "round_state"
 	EventDataNameVote              = "vote"
 	EventDataNameProposalHeartbeat = "proposal_heartbeat"
+
+	EventDataNameValidatorSetUpdates = "validator_set_updates"
+	EventDataNameMempoolTx           = "mempool_tx"
+	EventDataNameValidatorExit       = "validator_exit"
 )
 
 // implements events.EventData
@@ -82,6 +91,9 @@ const (
 	EventDataTypeFork              = byte(0x02)
 	EventDataTypeTx                = byte(0x03)
 	EventDataTypeNewBlockHeader    = byte(0x04)
+	EventDataTypeValSetUpdates     = byte(0x05)
+	EventDataTypeMempoolTx         = byte(0x06)
+	EventDataTypeValidatorExit     = byte(0x07)
 	EventDataTypeRoundState        = byte(0x11)
 	EventDataTypeVote              = byte(0x12)
 	EventDataTypeProposalHeartbeat = byte(0x20)
@@ -93,7 +105,10 @@ var tmEventDataMapper = data.NewMapper(TMEventData{}).
 	RegisterImplementation(EventDataTx{}, EventDataNameTx, EventDataTypeTx).
 	RegisterImplementation(EventDataRoundState{}, EventDataNameRoundState, EventDataTypeRoundState).
 	RegisterImplementation(EventDataVote{}, EventDataNameVote, EventDataTypeVote).
-	RegisterImplementation(EventDataProposalHeartbeat{}, EventDataNameProposalHeartbeat, EventDataTypeProposalHeartbeat)
+	RegisterImplementation(EventDataProposalHeartbeat{}, EventDataNameProposalHeartbeat, EventDataTypeProposalHeartbeat).
+	RegisterImplementation(EventDataValidatorSetUpdates{}, EventDataNameValidatorSetUpdates, EventDataTypeValSetUpdates).
+	RegisterImplementation(EventDataMempoolTx{}, EventDataNameMempoolTx, EventDataTypeMempoolTx).
+	RegisterImplementation(EventDataValidatorExit{}, EventDataNameValidatorExit, EventDataTypeValidatorExit)
 
 // Most event messages are basic types (a block, a transaction)
 // but some (an input to a call tx or a receive) are more exotic
@@ -130,6 +145,31 @@ type EventDataVote struct {
 	Vote *Vote
 }
 
+// The validator updates returned by EndBlock, which apply from the next block
+type EventDataValidatorSetUpdates struct {
+	Height  int64             `json:"height"`
+	Updates []*abci.Validator `json:"updates"`
+}
+
+// A tx accepted into the local mempool by CheckTx, from a client or a peer.
+// The tx itself is not included, only what's needed to follow the pending flow.
+type EventDataMempoolTx struct {
+	Hash      data.Bytes `json:"hash"`
+	Size      int        `json:"size"`
+	Priority  int64      `json:"priority"`   // the Fee of the CheckTx response
+	GasWanted int64      `json:"gas_wanted"` // the Gas of the CheckTx response
+	Height    int64      `json:"height"`     // the last block height when it was checked
+}
+
+// The exit of the validator of the node: it signs no votes nor proposals of
+// the heights after StopHeight. Announced when scheduled, or canceled with a
+// StopHeight of 0, and once it stopped signing.
+type EventDataValidatorExit struct {
+	Address    data.Bytes `json:"address"`
+	StopHeight int64      `json:"stop_height"`
+	Stopped    bool       `json:"stopped"`
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // PUBSUB
 ///////////////////////////////////////////////////////////////////////////////
@@ -143,6 +183,15 @@ const (
 	// TxHeightKey is a reserved key, used to specify transaction block's height.
 	// see EventBus#PublishEventTx
 	TxHeightKey = "tx.height"
+
+	// BlockHeightKey is a reserved key, used to specify the height of a block
+	// indexed by the block indexer. see state/blockindex
+	BlockHeightKey = "block.height"
+	// BlockNumTxsKey is a reserved key, used to specify the number of txs of a block.
+	BlockNumTxsKey = "block.num_txs"
+	// BlockValidatorUpdatesKey is a reserved key, used to specify the number of
+	// validator updates returned by the EndBlock of a block, if any.
+	BlockValidatorUpdatesKey = "block.validator_updates"
 )
 
 var (
@@ -165,6 +214,10 @@ var (
 	EventQueryVote              = QueryForEvent(EventVote)
 	EventQueryProposalHeartbeat = QueryForEvent(EventProposalHeartbeat)
 	EventQueryTx                = QueryForEvent(EventTx)
+
+	EventQueryValidatorSetUpdates = QueryForEvent(EventValidatorSetUpdates)
+	EventQueryMempoolTx           = QueryForEvent(EventMempoolTx)
+	EventQueryValidatorExit       = QueryForEvent(EventValidatorExit)
 )
 
 func EventQueryTxFor(tx Tx) tmpubsub.Query {
@@ -178,3 +231,7 @@ func QueryForEvent(eventType string) tmpubsub.Query {
 type TxEventPublisher interface {
 	PublishEventTx(EventDataTx) error
 }
+
+type MempoolEventPublisher interface {
+	PublishEventMempoolTx(EventDataMempoolTx) error
+}
@@ -0,0 +1,503 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/tendermint/go-crypto"
+	wire "github.com/tendermint/go-wire"
+	"github.com/tendermint/tmlibs/merkle"
+)
+
+// ErrEvidenceInvalid wraps a piece of evidence and the error denoting how or why it is invalid.
+type ErrEvidenceInvalid struct {
+	Evidence   Evidence
+	ErrorValue error
+}
+
+// NewEvidenceInvalidErr returns a new ErrEvidenceInvalid with the given err.
+func NewEvidenceInvalidErr(ev Evidence, err error) *ErrEvidenceInvalid {
+	return &ErrEvidenceInvalid{ev, err}
+}
+
+// Error returns a string representation of the error.
+func (err *ErrEvidenceInvalid) Error() string {
+	return fmt.Sprintf("Invalid evidence: %v. Evidence: %v", err.ErrorValue, err.Evidence)
+}
+
+//-------------------------------------------
+
+// Evidence represents any provable malicious activity by a validator
+type Evidence interface {
+	Height() int64               // height of the equivocation
+	Address() []byte             // address of the equivocating validator
+	PubKey() crypto.PubKey       // public key of the equivocating validator
+	Index() int                  // index of the validator in the validator set
+	Hash() []byte                // hash of the evidence
+	Verify(chainID string) error // verify the evidence
+	Equal(Evidence) bool         // check equality of evidence
+
+	String() string
+}
+
+const (
+	evidenceTypeDuplicateVote        = byte(0x01)
+	evidenceTypeAmnesia              = byte(0x02)
+	evidenceTypeLunatic              = byte(0x03)
+	evidenceTypeConflictingProposals = byte(0x04)
+)
+
+var _ = wire.RegisterInterface(
+	struct{ Evidence }{},
+	wire.ConcreteType{&DuplicateVoteEvidence{}, evidenceTypeDuplicateVote},
+	wire.ConcreteType{&AmnesiaEvidence{}, evidenceTypeAmnesia},
+	wire.ConcreteType{&LunaticValidatorEvidence{}, evidenceTypeLunatic},
+	wire.ConcreteType{&ConflictingProposalsEvidence{}, evidenceTypeConflictingProposals},
+)
+
+//-------------------------------------------
+
+// EvidenceList is a list of Evidence. Evidences is not a word.
+type EvidenceList []Evidence
+
+// Hash returns the simple merkle root hash of the EvidenceList.
+func (evl EvidenceList) Hash() []byte {
+	// Recursive impl.
+	// Copied from tmlibs/merkle to avoid allocations
+	switch len(evl) {
+	case 0:
+		return nil
+	case 1:
+		return evl[0].Hash()
+	default:
+		left := EvidenceList(evl[:(len(evl)+1)/2]).Hash()
+		right := EvidenceList(evl[(len(evl)+1)/2:]).Hash()
+		return merkle.SimpleHashFromTwoHashes(left, right)
+	}
+}
+
+func (evl EvidenceList) String() string {
+	s := ""
+	for _, e := range evl {
+		s += fmt.Sprintf("%s\t\t", e)
+	}
+	return s
+}
+
+// Has returns true if the evidence is in the EvidenceList.
+func (evl EvidenceList) Has(evidence Evidence) bool {
+	for _, ev := range evl {
+		if ev.Equal(evidence) {
+			return true
+		}
+	}
+	return false
+}
+
+//-------------------------------------------
+
+// DuplicateVoteEvidence contains evidence a validator signed two conflicting votes.
+type DuplicateVoteEvidence struct {
+	ValidatorPubKey crypto.PubKey `json:"pub_key"`
+
+	// TODO: Should these be in a BlockID ordered, lexicographically?
+	VoteA *Vote `json:"vote_a"`
+	VoteB *Vote `json:"vote_b"`
+}
+
+// NewDuplicateVoteEvidence returns the evidence that the validator signed
+// the two conflicting votes.
+func NewDuplicateVoteEvidence(pubKey crypto.PubKey, voteA, voteB *Vote) *DuplicateVoteEvidence {
+	return &DuplicateVoteEvidence{
+		ValidatorPubKey: pubKey,
+		VoteA:           voteA,
+		VoteB:           voteB,
+	}
+}
+
+// String returns a string representation of the evidence.
+func (dve *DuplicateVoteEvidence) String() string {
+	return fmt.Sprintf("VoteA: %v; VoteB: %v", dve.VoteA, dve.VoteB)
+}
+
+// Height returns the height this evidence refers to.
+func (dve *DuplicateVoteEvidence) Height() int64 {
+	return dve.VoteA.Height
+}
+
+// Address returns the address of the validator.
+func (dve *DuplicateVoteEvidence) Address() []byte {
+	return dve.ValidatorPubKey.Address()
+}
+
+// PubKey returns the public key of the validator.
+func (dve *DuplicateVoteEvidence) PubKey() crypto.PubKey {
+	return dve.ValidatorPubKey
+}
+
+// Index returns the index of the validator.
+func (dve *DuplicateVoteEvidence) Index() int {
+	return dve.VoteA.ValidatorIndex
+}
+
+// Hash returns the hash of the evidence.
+func (dve *DuplicateVoteEvidence) Hash() []byte {
+	return merkle.SimpleHashFromBinary(dve)
+}
+
+// Verify returns an error if the two votes aren't conflicting.
+// To be conflicting, they must be from the same validator, for the same H/R/S, but for different blocks.
+func (dve *DuplicateVoteEvidence) Verify(chainID string) error {
+	if dve.VoteA == nil || dve.VoteB == nil {
+		return fmt.Errorf("DuplicateVoteEvidence Error: Missing vote")
+	}
+
+	// H/R/S must be the same
+	if dve.VoteA.Height != dve.VoteB.Height ||
+		dve.VoteA.Round != dve.VoteB.Round ||
+		dve.VoteA.Type != dve.VoteB.Type {
+		return fmt.Errorf("DuplicateVoteEvidence Error: H/R/S does not match. Got %v and %v", dve.VoteA, dve.VoteB)
+	}
+
+	// BlockIDs must be different
+	if dve.VoteA.BlockID.Equals(dve.VoteB.BlockID) {
+		return fmt.Errorf("DuplicateVoteEvidence Error: BlockIDs are the same (%v) - not a real duplicate vote!", dve.VoteA.BlockID)
+	}
+
+	if err := verifyVotes(chainID, dve.ValidatorPubKey, dve.VoteA, dve.VoteB); err != nil {
+		return fmt.Errorf("DuplicateVoteEvidence Error: %v", err)
+	}
+	return nil
+}
+
+// Equal checks if two pieces of evidence are equal.
+func (dve *DuplicateVoteEvidence) Equal(ev Evidence) bool {
+	if _, ok := ev.(*DuplicateVoteEvidence); !ok {
+		return false
+	}
+
+	// just check their hashes
+	return bytes.Equal(merkle.SimpleHashFromBinary(dve), merkle.SimpleHashFromBinary(ev))
+}
+
+//-------------------------------------------
+
+// AmnesiaEvidence contains evidence a validator signed two votes at a height
+// for the same block, but with conflicting part set headers. The parts of a
+// block are deterministic, so at least one of the votes is for parts that
+// don't make the block: the validator forgot the block it voted for.
+type AmnesiaEvidence struct {
+	ValidatorPubKey crypto.PubKey `json:"pub_key"`
+
+	VoteA *Vote `json:"vote_a"`
+	VoteB *Vote `json:"vote_b"`
+}
+
+// NewAmnesiaEvidence returns the evidence that the validator signed
+// the two votes for conflicting parts of a block.
+func NewAmnesiaEvidence(pubKey crypto.PubKey, voteA, voteB *Vote) *AmnesiaEvidence {
+	return &AmnesiaEvidence{
+		ValidatorPubKey: pubKey,
+		VoteA:           voteA,
+		VoteB:           voteB,
+	}
+}
+
+// String returns a string representation of the evidence.
+func (ae *AmnesiaEvidence) String() string {
+	return fmt.Sprintf("Amnesia VoteA: %v; VoteB: %v", ae.VoteA, ae.VoteB)
+}
+
+// Height returns the height this evidence refers to.
+func (ae *AmnesiaEvidence) Height() int64 {
+	return ae.VoteA.Height
+}
+
+// Address returns the address of the validator.
+func (ae *AmnesiaEvidence) Address() []byte {
+	return ae.ValidatorPubKey.Address()
+}
+
+// PubKey returns the public key of the validator.
+func (ae *AmnesiaEvidence) PubKey() crypto.PubKey {
+	return ae.ValidatorPubKey
+}
+
+// Index returns the index of the validator.
+func (ae *AmnesiaEvidence) Index() int {
+	return ae.VoteA.ValidatorIndex
+}
+
+// Hash returns the hash of the evidence.
+func (ae *AmnesiaEvidence) Hash() []byte {
+	return merkle.SimpleHashFromBinary(ae)
+}
+
+// Verify returns an error if the two votes aren't for conflicting parts of a block.
+// They must be from the same validator at the same height, in any round and of any type,
+// for the same block hash but different part set headers.
+func (ae *AmnesiaEvidence) Verify(chainID string) error {
+	if ae.VoteA == nil || ae.VoteB == nil {
+		return fmt.Errorf("AmnesiaEvidence Error: Missing vote")
+	}
+
+	if ae.VoteA.Height != ae.VoteB.Height {
+		return fmt.Errorf("AmnesiaEvidence Error: Heights do not match. Got %d and %d", ae.VoteA.Height, ae.VoteB.Height)
+	}
+
+	// same block, different parts
+	if len(ae.VoteA.BlockID.Hash) == 0 || !bytes.Equal(ae.VoteA.BlockID.Hash, ae.VoteB.BlockID.Hash) {
+		return fmt.Errorf("AmnesiaEvidence Error: Block hashes are not the same. Got %X and %X", ae.VoteA.BlockID.Hash, ae.VoteB.BlockID.Hash)
+	}
+	if ae.VoteA.BlockID.PartsHeader.Equals(ae.VoteB.BlockID.PartsHeader) {
+		return fmt.Errorf("AmnesiaEvidence Error: Part set headers are the same (%v)", ae.VoteA.BlockID.PartsHeader)
+	}
+
+	if err := verifyVotes(chainID, ae.ValidatorPubKey, ae.VoteA, ae.VoteB); err != nil {
+		return fmt.Errorf("AmnesiaEvidence Error: %v", err)
+	}
+	return nil
+}
+
+// Equal checks if two pieces of evidence are equal.
+func (ae *AmnesiaEvidence) Equal(ev Evidence) bool {
+	if _, ok := ev.(*AmnesiaEvidence); !ok {
+		return false
+	}
+
+	// just check their hashes
+	return bytes.Equal(merkle.SimpleHashFromBinary(ae), merkle.SimpleHashFromBinary(ev))
+}
+
+//-------------------------------------------
+
+// The header fields a LunaticValidatorEvidence can prove invalid:
+// all the valid blocks at a height agree on them.
+const (
+	LunaticFieldValidatorsHash  = "ValidatorsHash"
+	LunaticFieldAppHash         = "AppHash"
+	LunaticFieldLastResultsHash = "LastResultsHash"
+)
+
+// LunaticValidatorEvidence contains evidence a validator signed a vote for
+// a block whose header has an invalid InvalidHeaderField, eg. an app hash
+// that isn't the one of the app state at the height.
+// Only the state can tell the field is invalid, see state.VerifyEvidence: the
+// app hash and results hash only until the block at the height is committed.
+type LunaticValidatorEvidence struct {
+	ValidatorPubKey crypto.PubKey `json:"pub_key"`
+
+	Header             *Header `json:"header"`
+	Vote               *Vote   `json:"vote"`
+	InvalidHeaderField string  `json:"invalid_header_field"`
+}
+
+// NewLunaticValidatorEvidence returns the evidence that the validator signed
+// the vote for the header, whose field is invalid.
+func NewLunaticValidatorEvidence(pubKey crypto.PubKey, header *Header, vote *Vote, invalidHeaderField string) *LunaticValidatorEvidence {
+	return &LunaticValidatorEvidence{
+		ValidatorPubKey:    pubKey,
+		Header:             header,
+		Vote:               vote,
+		InvalidHeaderField: invalidHeaderField,
+	}
+}
+
+// String returns a string representation of the evidence.
+func (lve *LunaticValidatorEvidence) String() string {
+	return fmt.Sprintf("Lunatic Vote: %v; invalid %s: %X", lve.Vote, lve.InvalidHeaderField, lve.InvalidHeaderValue())
+}
+
+// Height returns the height this evidence refers to.
+func (lve *LunaticValidatorEvidence) Height() int64 {
+	return lve.Vote.Height
+}
+
+// Address returns the address of the validator.
+func (lve *LunaticValidatorEvidence) Address() []byte {
+	return lve.ValidatorPubKey.Address()
+}
+
+// PubKey returns the public key of the validator.
+func (lve *LunaticValidatorEvidence) PubKey() crypto.PubKey {
+	return lve.ValidatorPubKey
+}
+
+// Index returns the index of the validator.
+func (lve *LunaticValidatorEvidence) Index() int {
+	return lve.Vote.ValidatorIndex
+}
+
+// Hash returns the hash of the evidence.
+func (lve *LunaticValidatorEvidence) Hash() []byte {
+	return merkle.SimpleHashFromBinary(lve)
+}
+
+// InvalidHeaderValue returns the value of the invalid field in the header,
+// nil if the field can't be proven invalid.
+func (lve *LunaticValidatorEvidence) InvalidHeaderValue() []byte {
+	if lve.Header == nil {
+		return nil
+	}
+	switch lve.InvalidHeaderField {
+	case LunaticFieldValidatorsHash:
+		return lve.Header.ValidatorsHash
+	case LunaticFieldAppHash:
+		return lve.Header.AppHash
+	case LunaticFieldLastResultsHash:
+		return lve.Header.LastResultsHash
+	default:
+		return nil
+	}
+}
+
+// Verify returns an error if the validator didn't sign a vote for the header.
+// It doesn't check the field is invalid, which takes the state at the height.
+func (lve *LunaticValidatorEvidence) Verify(chainID string) error {
+	if lve.Vote == nil || lve.Header == nil {
+		return fmt.Errorf("LunaticValidatorEvidence Error: Missing vote or header")
+	}
+
+	switch lve.InvalidHeaderField {
+	case LunaticFieldValidatorsHash, LunaticFieldAppHash, LunaticFieldLastResultsHash:
+	default:
+		return fmt.Errorf("LunaticValidatorEvidence Error: Unknown header field %q", lve.InvalidHeaderField)
+	}
+
+	if lve.Header.Height != lve.Vote.Height {
+		return fmt.Errorf("LunaticValidatorEvidence Error: Header height %d does not match the vote height %d", lve.Header.Height, lve.Vote.Height)
+	}
+	if !bytes.Equal(lve.Header.Hash(), lve.Vote.BlockID.Hash) {
+		return fmt.Errorf("LunaticValidatorEvidence Error: Header hash %X does not match the voted block %X", lve.Header.Hash(), lve.Vote.BlockID.Hash)
+	}
+
+	if err := verifyVotes(chainID, lve.ValidatorPubKey, lve.Vote); err != nil {
+		return fmt.Errorf("LunaticValidatorEvidence Error: %v", err)
+	}
+	return nil
+}
+
+// Equal checks if two pieces of evidence are equal.
+func (lve *LunaticValidatorEvidence) Equal(ev Evidence) bool {
+	if _, ok := ev.(*LunaticValidatorEvidence); !ok {
+		return false
+	}
+
+	// just check their hashes
+	return bytes.Equal(merkle.SimpleHashFromBinary(lve), merkle.SimpleHashFromBinary(ev))
+}
+
+//-------------------------------------------
+
+// ConflictingProposalsEvidence contains evidence a proposer signed two different
+// proposals for the same height and round, eg. to split the votes of the validators.
+// The proposals don't carry the index of the validator, so the evidence does,
+// and the state checks it was the proposer of the round, see state.VerifyEvidence.
+type ConflictingProposalsEvidence struct {
+	ProposerPubKey crypto.PubKey `json:"pub_key"`
+	ProposerIndex  int           `json:"proposer_index"`
+
+	ProposalA *Proposal `json:"proposal_a"`
+	ProposalB *Proposal `json:"proposal_b"`
+}
+
+// NewConflictingProposalsEvidence returns the evidence that the proposer,
+// validator proposerIndex, signed the two conflicting proposals.
+func NewConflictingProposalsEvidence(pubKey crypto.PubKey, proposerIndex int, proposalA, proposalB *Proposal) *ConflictingProposalsEvidence {
+	return &ConflictingProposalsEvidence{
+		ProposerPubKey: pubKey,
+		ProposerIndex:  proposerIndex,
+		ProposalA:      proposalA,
+		ProposalB:      proposalB,
+	}
+}
+
+// String returns a string representation of the evidence.
+func (cpe *ConflictingProposalsEvidence) String() string {
+	return fmt.Sprintf("ProposalA: %v; ProposalB: %v", cpe.ProposalA, cpe.ProposalB)
+}
+
+// Height returns the height this evidence refers to.
+func (cpe *ConflictingProposalsEvidence) Height() int64 {
+	return cpe.ProposalA.Height
+}
+
+// Round returns the round of the proposals.
+func (cpe *ConflictingProposalsEvidence) Round() int {
+	return cpe.ProposalA.Round
+}
+
+// Address returns the address of the proposer.
+func (cpe *ConflictingProposalsEvidence) Address() []byte {
+	return cpe.ProposerPubKey.Address()
+}
+
+// PubKey returns the public key of the proposer.
+func (cpe *ConflictingProposalsEvidence) PubKey() crypto.PubKey {
+	return cpe.ProposerPubKey
+}
+
+// Index returns the index of the proposer.
+func (cpe *ConflictingProposalsEvidence) Index() int {
+	return cpe.ProposerIndex
+}
+
+// Hash returns the hash of the evidence.
+func (cpe *ConflictingProposalsEvidence) Hash() []byte {
+	return merkle.SimpleHashFromBinary(cpe)
+}
+
+// Verify returns an error if the two proposals aren't conflicting.
+// To be conflicting, they must be signed by the proposer, for the same height
+// and round, but differ in anything else than their signature.
+func (cpe *ConflictingProposalsEvidence) Verify(chainID string) error {
+	if cpe.ProposalA == nil || cpe.ProposalB == nil {
+		return fmt.Errorf("ConflictingProposalsEvidence Error: Missing proposal")
+	}
+
+	if cpe.ProposalA.Height != cpe.ProposalB.Height || cpe.ProposalA.Round != cpe.ProposalB.Round {
+		return fmt.Errorf("ConflictingProposalsEvidence Error: H/R does not match. Got %v and %v", cpe.ProposalA, cpe.ProposalB)
+	}
+
+	signBytesA, signBytesB := SignBytes(chainID, cpe.ProposalA), SignBytes(chainID, cpe.ProposalB)
+	if bytes.Equal(signBytesA, signBytesB) {
+		return fmt.Errorf("ConflictingProposalsEvidence Error: Proposals are the same (%v)", cpe.ProposalA)
+	}
+
+	if !cpe.ProposerPubKey.VerifyBytes(signBytesA, cpe.ProposalA.Signature) ||
+		!cpe.ProposerPubKey.VerifyBytes(signBytesB, cpe.ProposalB.Signature) {
+		return fmt.Errorf("ConflictingProposalsEvidence Error: %v", ErrVoteInvalidSignature)
+	}
+	return nil
+}
+
+// Equal checks if two pieces of evidence are equal.
+func (cpe *ConflictingProposalsEvidence) Equal(ev Evidence) bool {
+	if _, ok := ev.(*ConflictingProposalsEvidence); !ok {
+		return false
+	}
+
+	// just check their hashes
+	return bytes.Equal(merkle.SimpleHashFromBinary(cpe), merkle.SimpleHashFromBinary(ev))
+}
+
+//-------------------------------------------
+
+// verifyVotes returns an error if the votes are not all signed by the validator
+// with the pub key, at the same index.
+func verifyVotes(chainID string, pubKey crypto.PubKey, votes ...*Vote) error {
+	address := pubKey.Address()
+	for i, vote := range votes {
+		if !bytes.Equal(vote.ValidatorAddress, address) {
+			return fmt.Errorf("Validator address %X does not match the pub key", vote.ValidatorAddress)
+		}
+		// XXX: Should we enforce index is the same ?
+		if vote.ValidatorIndex != votes[0].ValidatorIndex {
+			return fmt.Errorf("Validator indices do not match. Got %d and %d", votes[0].ValidatorIndex, vote.ValidatorIndex)
+		}
+		if !pubKey.VerifyBytes(SignBytes(chainID, vote), vote.Signature) {
+			return fmt.Errorf("Error verifying vote %d: %v", i, ErrVoteInvalidSignature)
+		}
+	}
+	return nil
+}
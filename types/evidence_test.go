@@ -0,0 +1,189 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	crypto "github.com/tendermint/go-crypto"
+	wire "github.com/tendermint/go-wire"
+)
+
+func makeSignedVote(chainID string, privKey crypto.PrivKey, blockID BlockID) *Vote {
+	vote := &Vote{
+		ValidatorAddress: privKey.PubKey().Address(),
+		ValidatorIndex:   1,
+		Height:           10,
+		Round:            2,
+		Type:             VoteTypePrevote,
+		BlockID:          blockID,
+	}
+	vote.Signature = privKey.Sign(SignBytes(chainID, vote))
+	return vote
+}
+
+func TestDuplicateVoteEvidence(t *testing.T) {
+	assert := assert.New(t)
+	chainID := "mychain"
+	privKey := crypto.GenPrivKeyEd25519().Wrap()
+	pubKey := privKey.PubKey()
+
+	blockID := BlockID{[]byte("blockhash"), PartSetHeader{1000, []byte("partshash")}}
+	blockID2 := BlockID{[]byte("blockhash2"), PartSetHeader{1000, []byte("partshash")}}
+
+	voteA := makeSignedVote(chainID, privKey, blockID)
+	voteB := makeSignedVote(chainID, privKey, blockID2)
+	ev := NewDuplicateVoteEvidence(pubKey, voteA, voteB)
+	assert.Nil(ev.Verify(chainID))
+	assert.Equal(int64(10), ev.Height())
+	assert.Equal(1, ev.Index())
+	assert.Equal(pubKey.Address(), ev.Address())
+
+	// the evidence survives a round trip through go-wire
+	bz := wire.BinaryBytes(struct{ Evidence }{ev})
+	var ev2 struct{ Evidence }
+	assert.Nil(wire.ReadBinaryBytes(bz, &ev2))
+	assert.True(ev.Equal(ev2.Evidence))
+	assert.True(EvidenceList{ev}.Has(ev2.Evidence))
+	assert.Equal(ev.Hash(), []byte((&EvidenceData{Evidence: EvidenceList{ev}}).Hash()))
+
+	// the same block twice is not a duplicate vote
+	assert.NotNil(NewDuplicateVoteEvidence(pubKey, voteA, voteA).Verify(chainID))
+
+	// signed for another chain
+	assert.NotNil(ev.Verify("otherchain"))
+
+	// another round
+	voteC := makeSignedVote(chainID, privKey, blockID2)
+	voteC.Round = 3
+	voteC.Signature = privKey.Sign(SignBytes(chainID, voteC))
+	assert.NotNil(NewDuplicateVoteEvidence(pubKey, voteA, voteC).Verify(chainID))
+
+	// signed by another validator
+	otherKey := crypto.GenPrivKeyEd25519().Wrap()
+	assert.NotNil(NewDuplicateVoteEvidence(otherKey.PubKey(), voteA, voteB).Verify(chainID))
+	voteD := makeSignedVote(chainID, otherKey, blockID2)
+	assert.NotNil(NewDuplicateVoteEvidence(pubKey, voteA, voteD).Verify(chainID))
+
+	// a forged signature
+	voteE := makeSignedVote(chainID, privKey, blockID2)
+	voteE.Signature = otherKey.Sign(SignBytes(chainID, voteE))
+	assert.NotNil(NewDuplicateVoteEvidence(pubKey, voteA, voteE).Verify(chainID))
+}
+
+func TestAmnesiaEvidence(t *testing.T) {
+	assert := assert.New(t)
+	chainID := "mychain"
+	privKey := crypto.GenPrivKeyEd25519().Wrap()
+	pubKey := privKey.PubKey()
+
+	blockID := BlockID{[]byte("blockhash"), PartSetHeader{1000, []byte("partshash")}}
+	blockID2 := BlockID{[]byte("blockhash"), PartSetHeader{1000, []byte("partshash2")}}
+
+	voteA := makeSignedVote(chainID, privKey, blockID)
+	voteB := makeSignedVote(chainID, privKey, blockID2)
+	voteB.Round = 3
+	voteB.Type = VoteTypePrecommit
+	voteB.Signature = privKey.Sign(SignBytes(chainID, voteB))
+	ev := NewAmnesiaEvidence(pubKey, voteA, voteB)
+	assert.Nil(ev.Verify(chainID))
+
+	// the evidence survives a round trip through go-wire
+	bz := wire.BinaryBytes(struct{ Evidence }{ev})
+	var ev2 struct{ Evidence }
+	assert.Nil(wire.ReadBinaryBytes(bz, &ev2))
+	assert.True(ev.Equal(ev2.Evidence))
+
+	// the same parts
+	assert.NotNil(NewAmnesiaEvidence(pubKey, voteA, voteA).Verify(chainID))
+
+	// other blocks
+	voteC := makeSignedVote(chainID, privKey, BlockID{[]byte("blockhash2"), PartSetHeader{1000, []byte("partshash2")}})
+	assert.NotNil(NewAmnesiaEvidence(pubKey, voteA, voteC).Verify(chainID))
+
+	// nil votes
+	voteD := makeSignedVote(chainID, privKey, BlockID{nil, PartSetHeader{1000, []byte("partshash2")}})
+	voteE := makeSignedVote(chainID, privKey, BlockID{})
+	assert.NotNil(NewAmnesiaEvidence(pubKey, voteD, voteE).Verify(chainID))
+
+	// another height
+	voteF := makeSignedVote(chainID, privKey, blockID2)
+	voteF.Height = 11
+	voteF.Signature = privKey.Sign(SignBytes(chainID, voteF))
+	assert.NotNil(NewAmnesiaEvidence(pubKey, voteA, voteF).Verify(chainID))
+}
+
+func TestLunaticValidatorEvidence(t *testing.T) {
+	assert := assert.New(t)
+	chainID := "mychain"
+	privKey := crypto.GenPrivKeyEd25519().Wrap()
+	pubKey := privKey.PubKey()
+
+	header := &Header{
+		ChainID:        chainID,
+		Height:         10,
+		ValidatorsHash: []byte("validatorshash"),
+		AppHash:        []byte("invalidapphash"),
+	}
+	vote := makeSignedVote(chainID, privKey, BlockID{header.Hash(), PartSetHeader{1, []byte("partshash")}})
+	ev := NewLunaticValidatorEvidence(pubKey, header, vote, LunaticFieldAppHash)
+	assert.Nil(ev.Verify(chainID))
+	assert.Equal([]byte("invalidapphash"), ev.InvalidHeaderValue())
+
+	// the evidence survives a round trip through go-wire
+	bz := wire.BinaryBytes(struct{ Evidence }{ev})
+	var ev2 struct{ Evidence }
+	assert.Nil(wire.ReadBinaryBytes(bz, &ev2))
+	assert.True(ev.Equal(ev2.Evidence))
+
+	// an unknown field
+	assert.NotNil(NewLunaticValidatorEvidence(pubKey, header, vote, "Time").Verify(chainID))
+
+	// a vote for another header
+	header2 := *header
+	header2.AppHash = []byte("apphash")
+	assert.NotNil(NewLunaticValidatorEvidence(pubKey, &header2, vote, LunaticFieldAppHash).Verify(chainID))
+
+	// signed by another validator
+	otherKey := crypto.GenPrivKeyEd25519().Wrap()
+	assert.NotNil(NewLunaticValidatorEvidence(otherKey.PubKey(), header, vote, LunaticFieldAppHash).Verify(chainID))
+}
+
+func TestConflictingProposalsEvidence(t *testing.T) {
+	assert := assert.New(t)
+	chainID := "mychain"
+	privKey := crypto.GenPrivKeyEd25519().Wrap()
+	pubKey := privKey.PubKey()
+
+	makeSignedProposal := func(partsHash string) *Proposal {
+		proposal := NewProposal(10, 2, PartSetHeader{1, []byte(partsHash)}, -1, BlockID{})
+		proposal.Signature = privKey.Sign(SignBytes(chainID, proposal))
+		return proposal
+	}
+	proposalA := makeSignedProposal("partshash")
+	proposalB := makeSignedProposal("partshash2")
+	ev := NewConflictingProposalsEvidence(pubKey, 1, proposalA, proposalB)
+	assert.Nil(ev.Verify(chainID))
+	assert.Equal(int64(10), ev.Height())
+	assert.Equal(2, ev.Round())
+	assert.Equal(1, ev.Index())
+	assert.Equal(pubKey.Address(), ev.Address())
+
+	// the evidence survives a round trip through go-wire
+	bz := wire.BinaryBytes(struct{ Evidence }{ev})
+	var ev2 struct{ Evidence }
+	assert.Nil(wire.ReadBinaryBytes(bz, &ev2))
+	assert.True(ev.Equal(ev2.Evidence))
+
+	// the same proposal twice
+	assert.NotNil(NewConflictingProposalsEvidence(pubKey, 1, proposalA, proposalA).Verify(chainID))
+
+	// another round
+	proposalC := makeSignedProposal("partshash2")
+	proposalC.Round = 3
+	proposalC.Signature = privKey.Sign(SignBytes(chainID, proposalC))
+	assert.NotNil(NewConflictingProposalsEvidence(pubKey, 1, proposalA, proposalC).Verify(chainID))
+
+	// signed by another validator
+	otherKey := crypto.GenPrivKeyEd25519().Wrap()
+	assert.NotNil(NewConflictingProposalsEvidence(otherKey.PubKey(), 1, proposalA, proposalB).Verify(chainID))
+}
@@ -1,6 +1,7 @@
 package types
 
 import (
+	"crypto/sha256"
 	"encoding/json"
 	"io/ioutil"
 	"time"
@@ -51,6 +52,18 @@ func (genDoc *GenesisDoc) ValidatorHash() []byte {
 	return vset.Hash()
 }
 
+// Hash returns the SHA256 hash of the JSON encoding of the GenesisDoc,
+// to tell apart the genesis files of the nodes of a chain.
+// NOTE: call it after ValidateAndComplete, which fills in the defaults
+func (genDoc *GenesisDoc) Hash() []byte {
+	genDocBytes, err := json.Marshal(genDoc)
+	if err != nil {
+		cmn.PanicSanity(cmn.Fmt("Error encoding GenesisDoc: %v", err))
+	}
+	hash := sha256.Sum256(genDocBytes)
+	return hash[:]
+}
+
 // ValidateAndComplete checks that all necessary fields are present
 // and fills in defaults for optional fields left empty
 func (genDoc *GenesisDoc) ValidateAndComplete() error {
@@ -71,10 +84,13 @@ func (genDoc *GenesisDoc) ValidateAndComplete() error {
 		return errors.Errorf("The genesis file must have at least one validator")
 	}
 
-	for _, v := range genDoc.Validators {
+	for i, v := range genDoc.Validators {
 		if v.Power == 0 {
 			return errors.Errorf("The genesis file cannot contain validators with no voting power: %v", v)
 		}
+		if err := ValidateValidatorPubKey(v.PubKey); err != nil {
+			return errors.Wrapf(err, "Invalid validator %d in the genesis file", i)
+		}
 	}
 
 	if genDoc.GenesisTime.IsZero() {
@@ -1,6 +1,7 @@
 package types
 
 import (
+	"bytes"
 	"encoding/json"
 	"testing"
 
@@ -60,3 +61,46 @@ func TestGenesis(t *testing.T) {
 	genDoc, err = GenesisDocFromJSON(genDocBytes)
 	assert.Error(t, err, "expected error for genDoc json with block size of 0")
 }
+
+func TestGenesisValidatorKeyTypes(t *testing.T) {
+	for _, keyType := range KeyTypes() {
+		privKey, err := GenPrivKey(keyType)
+		assert.NoError(t, err)
+		genDoc := &GenesisDoc{
+			ChainID:    "abc",
+			Validators: []GenesisValidator{{privKey.PubKey(), 10, keyType}},
+		}
+		genDocBytes, err := json.Marshal(genDoc)
+		assert.NoError(t, err)
+		_, err = GenesisDocFromJSON(genDocBytes)
+		assert.NoError(t, err, "expected no error for a %v validator", keyType)
+	}
+
+	// keys which can't sign
+	badKeys := []string{
+		`{"type":"ed25519","data":"0000000000000000000000000000000000000000000000000000000000000000"}`,
+		`{"type":"secp256k1","data":"050000000000000000000000000000000000000000000000000000000000000000"}`,
+	}
+	for _, badKey := range badKeys {
+		genDocBytes := []byte(`{"chain_id":"abc","validators":[{"pub_key":` + badKey + `,"power":10,"name":""}]}`)
+		_, err := GenesisDocFromJSON(genDocBytes)
+		assert.Error(t, err, "expected error for validator key %v", badKey)
+	}
+}
+
+func TestGenesisHash(t *testing.T) {
+	genDocBytes := []byte(`{"genesis_time":"2017-12-01T00:00:00Z","chain_id":"test-chain","validators":[{"pub_key":{"type":"ed25519","data":"961EAB8752E51A03618502F55C2B6E09C38C65635C64CCF3173ED452CF86C957"},"power":10,"name":""}],"app_hash":""}`)
+	genDoc, err := GenesisDocFromJSON(genDocBytes)
+	assert.NoError(t, err)
+	assert.Len(t, genDoc.Hash(), 32)
+
+	// the same doc from a file formatted differently has the same hash
+	var indented bytes.Buffer
+	assert.NoError(t, json.Indent(&indented, genDocBytes, "", "  "))
+	genDoc2, err := GenesisDocFromJSON(indented.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, genDoc.Hash(), genDoc2.Hash())
+
+	genDoc2.Validators[0].Power = 11
+	assert.NotEqual(t, genDoc.Hash(), genDoc2.Hash())
+}
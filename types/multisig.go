@@ -0,0 +1,132 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+
+	crypto "github.com/tendermint/go-crypto"
+	wire "github.com/tendermint/go-wire"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// A multisig validator signs with M of the N keys of its co-signers, so no
+// single compromised key can sign for it. Its public key lists the N keys,
+// and its signatures hold the M signatures of the sign bytes by the co-signers.
+// Both are registered into go-crypto, and the consensus verifies them like any other.
+
+const (
+	NameMultisig = "multisig"
+	TypeMultisig = byte(0x10)
+)
+
+func init() {
+	crypto.PubKeyMapper.RegisterImplementation(MultisigPubKey{}, NameMultisig, TypeMultisig)
+	crypto.SignatureMapper.RegisterImplementation(MultiSignature{}, NameMultisig, TypeMultisig)
+}
+
+// MultisigPubKey is the public key of a validator signing with Threshold of the PubKeys.
+type MultisigPubKey struct {
+	Threshold int             `json:"threshold"`
+	PubKeys   []crypto.PubKey `json:"pub_keys"`
+}
+
+// NewMultisigPubKey returns the public key of a validator signing with threshold
+// of the pubKeys, in order.
+func NewMultisigPubKey(threshold int, pubKeys []crypto.PubKey) (crypto.PubKey, error) {
+	pubKey := MultisigPubKey{threshold, pubKeys}.Wrap()
+	if err := ValidateValidatorPubKey(pubKey); err != nil {
+		return crypto.PubKey{}, err
+	}
+	return pubKey, nil
+}
+
+// AssertIsPubKeyInner implements crypto.PubKeyInner.
+func (pk MultisigPubKey) AssertIsPubKeyInner() {}
+
+// Address implements crypto.PubKeyInner: the RIPEMD160 of the key, like for the other keys.
+func (pk MultisigPubKey) Address() []byte {
+	hasher := ripemd160.New()
+	hasher.Write(wire.BinaryBytes(pk)) // does not error
+	return hasher.Sum(nil)
+}
+
+// Bytes implements crypto.PubKeyInner.
+func (pk MultisigPubKey) Bytes() []byte {
+	return wire.BinaryBytes(pk.Wrap())
+}
+
+// KeyString implements crypto.PubKeyInner.
+func (pk MultisigPubKey) KeyString() string {
+	return fmt.Sprintf("%X", pk.Bytes())
+}
+
+// VerifyBytes implements crypto.PubKeyInner. The signature must be a MultiSignature
+// holding valid signatures of the msg by at least Threshold distinct keys.
+func (pk MultisigPubKey) VerifyBytes(msg []byte, sig crypto.Signature) bool {
+	multiSig, ok := sig.Unwrap().(MultiSignature)
+	if !ok || len(multiSig.Indexes) != len(multiSig.Sigs) || len(multiSig.Sigs) < pk.Threshold {
+		return false
+	}
+	for i, index := range multiSig.Indexes {
+		// in increasing order, so no key counts twice
+		if index < 0 || index >= len(pk.PubKeys) || (i > 0 && index <= multiSig.Indexes[i-1]) {
+			return false
+		}
+		if !pk.PubKeys[index].VerifyBytes(msg, multiSig.Sigs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Equals implements crypto.PubKeyInner.
+func (pk MultisigPubKey) Equals(other crypto.PubKey) bool {
+	otherPk, ok := other.Unwrap().(MultisigPubKey)
+	return ok && bytes.Equal(pk.Bytes(), otherPk.Bytes())
+}
+
+// Wrap implements crypto.PubKeyInner.
+func (pk MultisigPubKey) Wrap() crypto.PubKey {
+	return crypto.PubKey{pk}
+}
+
+// String returns a string representation of the key.
+func (pk MultisigPubKey) String() string {
+	return fmt.Sprintf("MultisigPubKey{%d of %v}", pk.Threshold, pk.PubKeys)
+}
+
+// MultiSignature holds the signatures of the keys of a MultisigPubKey
+// at the Indexes, in increasing order.
+type MultiSignature struct {
+	Indexes []int              `json:"indexes"`
+	Sigs    []crypto.Signature `json:"sigs"`
+}
+
+// AssertIsSignatureInner implements crypto.SignatureInner.
+func (sig MultiSignature) AssertIsSignatureInner() {}
+
+// Bytes implements crypto.SignatureInner.
+func (sig MultiSignature) Bytes() []byte {
+	return wire.BinaryBytes(sig.Wrap())
+}
+
+// IsZero implements crypto.SignatureInner.
+func (sig MultiSignature) IsZero() bool {
+	return len(sig.Sigs) == 0
+}
+
+// String implements crypto.SignatureInner.
+func (sig MultiSignature) String() string {
+	return fmt.Sprintf("MultiSignature{%v %v}", sig.Indexes, sig.Sigs)
+}
+
+// Equals implements crypto.SignatureInner.
+func (sig MultiSignature) Equals(other crypto.Signature) bool {
+	otherSig, ok := other.Unwrap().(MultiSignature)
+	return ok && bytes.Equal(sig.Bytes(), otherSig.Bytes())
+}
+
+// Wrap implements crypto.SignatureInner.
+func (sig MultiSignature) Wrap() crypto.Signature {
+	return crypto.Signature{sig}
+}
@@ -0,0 +1,66 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	crypto "github.com/tendermint/go-crypto"
+	wire "github.com/tendermint/go-wire"
+)
+
+func TestMultisigPubKey(t *testing.T) {
+	msg := []byte("sign bytes")
+	privKeys := make([]crypto.PrivKey, 3)
+	pubKeys := make([]crypto.PubKey, 3)
+	sigs := make([]crypto.Signature, 3)
+	for i := range privKeys {
+		privKeys[i] = crypto.GenPrivKeyEd25519().Wrap()
+		pubKeys[i] = privKeys[i].PubKey()
+		sigs[i] = privKeys[i].Sign(msg)
+	}
+
+	_, err := NewMultisigPubKey(0, pubKeys)
+	assert.NotNil(t, err)
+	_, err = NewMultisigPubKey(4, pubKeys)
+	assert.NotNil(t, err)
+	pubKey, err := NewMultisigPubKey(2, pubKeys)
+	require.Nil(t, err)
+
+	cases := []struct {
+		sig   MultiSignature
+		valid bool
+	}{
+		{MultiSignature{[]int{0, 2}, []crypto.Signature{sigs[0], sigs[2]}}, true},
+		{MultiSignature{[]int{0, 1, 2}, sigs}, true},
+		{MultiSignature{[]int{1}, []crypto.Signature{sigs[1]}}, false},                // under the threshold
+		{MultiSignature{[]int{1, 1}, []crypto.Signature{sigs[1], sigs[1]}}, false},    // the same key twice
+		{MultiSignature{[]int{2, 0}, []crypto.Signature{sigs[2], sigs[0]}}, false},    // not in order
+		{MultiSignature{[]int{0, 1}, []crypto.Signature{sigs[0], sigs[2]}}, false},    // the signature of another key
+		{MultiSignature{[]int{0, 3}, []crypto.Signature{sigs[0], sigs[1]}}, false},    // unknown key
+		{MultiSignature{[]int{0, 1, 2}, []crypto.Signature{sigs[0], sigs[1]}}, false}, // missing signature
+	}
+	for i, c := range cases {
+		assert.Equal(t, c.valid, pubKey.VerifyBytes(msg, c.sig.Wrap()), "case %d", i)
+	}
+	assert.False(t, pubKey.VerifyBytes(msg, sigs[0]))
+	assert.False(t, pubKeys[0].VerifyBytes(msg, cases[0].sig.Wrap()))
+
+	// the keys and signatures survive a round trip through go-wire and JSON, eg. in votes
+	sig := cases[0].sig.Wrap()
+	var pubKey2 crypto.PubKey
+	require.Nil(t, wire.ReadBinaryBytes(wire.BinaryBytes(pubKey), &pubKey2))
+	assert.True(t, pubKey.Equals(pubKey2))
+	assert.Equal(t, pubKey.Address(), pubKey2.Address())
+	var sig2 crypto.Signature
+	require.Nil(t, wire.ReadBinaryBytes(wire.BinaryBytes(sig), &sig2))
+	assert.True(t, sig.Equals(sig2))
+
+	jsonBytes, err := json.Marshal(pubKey)
+	require.Nil(t, err)
+	var pubKey3 crypto.PubKey
+	require.Nil(t, json.Unmarshal(jsonBytes, &pubKey3))
+	assert.True(t, pubKey.Equals(pubKey3))
+	assert.True(t, pubKey3.VerifyBytes(msg, sig2))
+}
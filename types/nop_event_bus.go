@@ -38,6 +38,18 @@ func (NopEventBus) PublishEventTx(tx EventDataTx) error {
 	return nil
 }
 
+func (NopEventBus) PublishEventValidatorSetUpdates(updates EventDataValidatorSetUpdates) error {
+	return nil
+}
+
+func (NopEventBus) PublishEventMempoolTx(tx EventDataMempoolTx) error {
+	return nil
+}
+
+func (NopEventBus) PublishEventValidatorExit(exit EventDataValidatorExit) error {
+	return nil
+}
+
 //--- EventDataRoundState events
 
 func (NopEventBus) PublishEventNewRoundStep(rs EventDataRoundState) error {
@@ -2,6 +2,10 @@ package types
 
 import (
 	"github.com/pkg/errors"
+
+	abci "github.com/tendermint/abci/types"
+
+	cfg "github.com/tendermint/tendermint/config"
 )
 
 const (
@@ -14,6 +18,13 @@ type ConsensusParams struct {
 	BlockSizeParams   `json:"block_size_params"`
 	TxSizeParams      `json:"tx_size_params"`
 	BlockGossipParams `json:"block_gossip_params"`
+	ResultsParams     `json:"results_params"`
+	TimeoutParams     `json:"timeout_params"`
+	BlockTimeParams   `json:"block_time_params"`
+	CommitParams      `json:"commit_params"`
+	EvidenceParams    `json:"evidence_params"`
+
+	DataAvailabilityParams `json:"data_availability_params"`
 }
 
 // BlockSizeParams contain limits on the block size.
@@ -34,12 +45,72 @@ type BlockGossipParams struct {
 	BlockPartSizeBytes int `json:"block_part_size_bytes"` // NOTE: must not be 0
 }
 
+// ResultsParams determine how the results of the txs in a block are committed to.
+type ResultsParams struct {
+	HashVersion int `json:"hash_version"` // NOTE: ResultsHashNone (0) leaves LastResultsHash empty
+}
+
+// TimeoutParams determine the timeouts of the consensus, in ms,
+// so all the validators of a chain wait the same time for the same steps.
+// A timeout of 0 is left to the config of the node (ConsensusConfig).
+// The deltas are added to the timeouts every round, as set by the Escalation
+// ("linear" or "exponential", "" for the config of the node), up to Max.
+type TimeoutParams struct {
+	Propose        int    `json:"propose"`
+	ProposeDelta   int    `json:"propose_delta"`
+	Prevote        int    `json:"prevote"`
+	PrevoteDelta   int    `json:"prevote_delta"`
+	Precommit      int    `json:"precommit"`
+	PrecommitDelta int    `json:"precommit_delta"`
+	Commit         int    `json:"commit"`
+	Escalation     string `json:"escalation"`
+	Max            int    `json:"max"`
+}
+
+// BlockTimeParams determine how the time of a block is set.
+type BlockTimeParams struct {
+	BFTTime bool `json:"bft_time"` // median of the precommit times instead of the proposer's clock, see MedianTime
+}
+
+// CommitParams determine how the precommits are committed to in the blocks.
+type CommitParams struct {
+	AggregateSignatures bool `json:"aggregate_signatures"` // one BLS signature for all the precommits, see Commit.Aggregate
+}
+
+// EvidenceParams contain limits on the evidence committed in a block.
+type EvidenceParams struct {
+	MaxNum int `json:"max_num"` // NOTE: 0 for no limit
+}
+
+// DataAvailabilityParams determine how the txs of the blocks are erasure coded
+// for data availability sampling (EXPERIMENTAL), see DataAvailabilityScheme.
+type DataAvailabilityParams struct {
+	Scheme         string `json:"scheme"`           // NOTE: "" for none, the DataAvailabilityHeader is left empty
+	ShareSizeBytes int    `json:"share_size_bytes"` // NOTE: must not be 0 with a scheme
+}
+
+// ConsensusParamsUpdate holds the sections of the ConsensusParams
+// changed by the app, nil for the ones left as they are.
+type ConsensusParamsUpdate struct {
+	BlockSizeParams   *BlockSizeParams   `json:"block_size_params"`
+	TxSizeParams      *TxSizeParams      `json:"tx_size_params"`
+	BlockGossipParams *BlockGossipParams `json:"block_gossip_params"`
+	TimeoutParams     *TimeoutParams     `json:"timeout_params"`
+	CommitParams      *CommitParams      `json:"commit_params"`
+}
+
 // DefaultConsensusParams returns a default ConsensusParams.
 func DefaultConsensusParams() *ConsensusParams {
 	return &ConsensusParams{
 		DefaultBlockSizeParams(),
 		DefaultTxSizeParams(),
 		DefaultBlockGossipParams(),
+		DefaultResultsParams(),
+		DefaultTimeoutParams(),
+		DefaultBlockTimeParams(),
+		DefaultCommitParams(),
+		DefaultEvidenceParams(),
+		DefaultDataAvailabilityParams(),
 	}
 }
 
@@ -67,6 +138,59 @@ func DefaultBlockGossipParams() BlockGossipParams {
 	}
 }
 
+// DefaultResultsParams returns a default ResultsParams.
+// NOTE: chains opt in to ResultsHashBlockV3 in their genesis.
+func DefaultResultsParams() ResultsParams {
+	return ResultsParams{
+		HashVersion: ResultsHashBinaryV2,
+	}
+}
+
+// DefaultTimeoutParams returns a default TimeoutParams,
+// which leaves all the timeouts to the config of the nodes.
+func DefaultTimeoutParams() TimeoutParams {
+	return TimeoutParams{}
+}
+
+// DefaultBlockTimeParams returns a default BlockTimeParams.
+func DefaultBlockTimeParams() BlockTimeParams {
+	return BlockTimeParams{
+		BFTTime: false,
+	}
+}
+
+// DefaultCommitParams returns a default CommitParams.
+func DefaultCommitParams() CommitParams {
+	return CommitParams{
+		AggregateSignatures: false,
+	}
+}
+
+// DefaultEvidenceParams returns a default EvidenceParams.
+func DefaultEvidenceParams() EvidenceParams {
+	return EvidenceParams{
+		MaxNum: 100,
+	}
+}
+
+// DefaultDataAvailabilityParams returns a default DataAvailabilityParams,
+// without data availability sampling.
+func DefaultDataAvailabilityParams() DataAvailabilityParams {
+	return DataAvailabilityParams{
+		Scheme:         "",
+		ShareSizeBytes: 4096, // 4kB
+	}
+}
+
+// NewScheme returns the DataAvailabilityScheme of the params, or nil if none.
+func (params DataAvailabilityParams) NewScheme() DataAvailabilityScheme {
+	newScheme, ok := dataAvailabilitySchemes[params.Scheme]
+	if params.Scheme == "" || !ok {
+		return nil
+	}
+	return newScheme(params.ShareSizeBytes)
+}
+
 // Validate validates the ConsensusParams to ensure all values
 // are within their allowed limits, and returns an error if they are not.
 func (params *ConsensusParams) Validate() error {
@@ -83,5 +207,121 @@ func (params *ConsensusParams) Validate() error {
 		return errors.Errorf("BlockSizeParams.MaxBytes is too big. %d > %d",
 			params.BlockSizeParams.MaxBytes, maxBlockSizeBytes)
 	}
+
+	// ensure we know how to hash the results
+	if params.ResultsParams.HashVersion < 0 || params.ResultsParams.HashVersion > latestResultsHashVersion {
+		return errors.Errorf("ResultsParams.HashVersion must be between 0 and %d. Got %d",
+			latestResultsHashVersion, params.ResultsParams.HashVersion)
+	}
+
+	// ensure the timeouts aren't negative
+	timeouts := params.TimeoutParams
+	for _, t := range []struct {
+		name  string
+		value int
+	}{
+		{"Propose", timeouts.Propose},
+		{"ProposeDelta", timeouts.ProposeDelta},
+		{"Prevote", timeouts.Prevote},
+		{"PrevoteDelta", timeouts.PrevoteDelta},
+		{"Precommit", timeouts.Precommit},
+		{"PrecommitDelta", timeouts.PrecommitDelta},
+		{"Commit", timeouts.Commit},
+		{"Max", timeouts.Max},
+	} {
+		if t.value < 0 {
+			return errors.Errorf("TimeoutParams.%s must not be negative. Got %d", t.name, t.value)
+		}
+	}
+	switch timeouts.Escalation {
+	case "", cfg.TimeoutEscalationLinear, cfg.TimeoutEscalationExponential:
+	default:
+		return errors.Errorf("TimeoutParams.Escalation must be empty, %s or %s. Got %q",
+			cfg.TimeoutEscalationLinear, cfg.TimeoutEscalationExponential, timeouts.Escalation)
+	}
+
+	if params.EvidenceParams.MaxNum < 0 {
+		return errors.Errorf("EvidenceParams.MaxNum must not be negative. Got %d", params.EvidenceParams.MaxNum)
+	}
+
+	// ensure we know how to erasure code the txs
+	if availability := params.DataAvailabilityParams; availability.Scheme != "" {
+		if _, ok := dataAvailabilitySchemes[availability.Scheme]; !ok {
+			return errors.Errorf("DataAvailabilityParams.Scheme %q is unknown", availability.Scheme)
+		}
+		if availability.ShareSizeBytes <= 0 {
+			return errors.Errorf("DataAvailabilityParams.ShareSizeBytes must be greater than 0. Got %d", availability.ShareSizeBytes)
+		}
+	}
 	return nil
 }
+
+// Update returns a copy of the params with the update applied,
+// or an error if the result is not valid.
+func (params ConsensusParams) Update(update *ConsensusParamsUpdate) (ConsensusParams, error) {
+	res := params // explicit copy
+	if update == nil {
+		return res, nil
+	}
+	if update.BlockSizeParams != nil {
+		res.BlockSizeParams = *update.BlockSizeParams
+	}
+	if update.TxSizeParams != nil {
+		res.TxSizeParams = *update.TxSizeParams
+	}
+	if update.BlockGossipParams != nil {
+		res.BlockGossipParams = *update.BlockGossipParams
+	}
+	if update.TimeoutParams != nil {
+		res.TimeoutParams = *update.TimeoutParams
+	}
+	if update.CommitParams != nil {
+		res.CommitParams = *update.CommitParams
+	}
+	if err := res.Validate(); err != nil {
+		return params, err
+	}
+	return res, nil
+}
+
+// UpdateFromABCI returns the update of the params by the ConsensusParamUpdates
+// returned by EndBlock, whose zero fields leave the params as they are, or nil
+// if there's none.
+// NOTE: ABCI only carries the block size, tx size and block gossip params.
+func (params ConsensusParams) UpdateFromABCI(abciParams *abci.ConsensusParams) *ConsensusParamsUpdate {
+	if abciParams == nil {
+		return nil
+	}
+	update := &ConsensusParamsUpdate{}
+	if abciBlockSize := abciParams.BlockSize; abciBlockSize != nil {
+		blockSize := params.BlockSizeParams
+		if abciBlockSize.MaxBytes > 0 {
+			blockSize.MaxBytes = int(abciBlockSize.MaxBytes)
+		}
+		if abciBlockSize.MaxTxs > 0 {
+			blockSize.MaxTxs = int(abciBlockSize.MaxTxs)
+		}
+		if abciBlockSize.MaxGas > 0 {
+			blockSize.MaxGas = int(abciBlockSize.MaxGas)
+		}
+		update.BlockSizeParams = &blockSize
+	}
+	if abciTxSize := abciParams.TxSize; abciTxSize != nil {
+		txSize := params.TxSizeParams
+		if abciTxSize.MaxBytes > 0 {
+			txSize.MaxBytes = int(abciTxSize.MaxBytes)
+		}
+		if abciTxSize.MaxGas > 0 {
+			txSize.MaxGas = int(abciTxSize.MaxGas)
+		}
+		update.TxSizeParams = &txSize
+	}
+	if abciBlockGossip := abciParams.BlockGossip; abciBlockGossip != nil {
+		blockGossip := params.BlockGossipParams
+		if abciBlockGossip.BlockPartSizeBytes > 0 {
+			blockGossip.BlockPartSizeBytes = int(abciBlockGossip.BlockPartSizeBytes)
+		}
+		update.BlockGossipParams = &blockGossip
+	}
+	return update
+}
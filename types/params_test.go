@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	abci "github.com/tendermint/abci/types"
 )
 
 func newConsensusParams(blockSize, partSize int) ConsensusParams {
@@ -38,3 +40,83 @@ func TestConsensusParamsValidation(t *testing.T) {
 		}
 	}
 }
+
+func TestConsensusParamsResultsHashVersion(t *testing.T) {
+	testCases := []struct {
+		version int
+		valid   bool
+	}{
+		{ResultsHashNone, true},
+		{ResultsHashBinaryV1, true},
+		{ResultsHashBinaryV2, true},
+		{-1, false},
+		{latestResultsHashVersion + 1, false},
+	}
+	for _, testCase := range testCases {
+		params := newConsensusParams(1, 1)
+		params.ResultsParams.HashVersion = testCase.version
+		if testCase.valid {
+			assert.NoError(t, params.Validate(), "expected no error for version %d", testCase.version)
+		} else {
+			assert.Error(t, params.Validate(), "expected error for version %d", testCase.version)
+		}
+	}
+}
+
+func TestConsensusParamsEvidence(t *testing.T) {
+	params := newConsensusParams(1, 1)
+	assert.NoError(t, params.Validate())
+	params.EvidenceParams.MaxNum = 10
+	assert.NoError(t, params.Validate())
+	params.EvidenceParams.MaxNum = -1
+	assert.Error(t, params.Validate())
+}
+
+func TestConsensusParamsUpdate(t *testing.T) {
+	assert := assert.New(t)
+
+	params := newConsensusParams(1, 1)
+	res, err := params.Update(nil)
+	assert.NoError(err)
+	assert.Equal(params, res)
+
+	timeouts := TimeoutParams{Propose: 2000, Commit: 500}
+	res, err = params.Update(&ConsensusParamsUpdate{TimeoutParams: &timeouts})
+	assert.NoError(err)
+	assert.Equal(timeouts, res.TimeoutParams)
+	assert.Equal(params.BlockSizeParams, res.BlockSizeParams)
+	// params is left as it is
+	assert.Equal(TimeoutParams{}, params.TimeoutParams)
+
+	_, err = params.Update(&ConsensusParamsUpdate{TimeoutParams: &TimeoutParams{PrevoteDelta: -1}})
+	assert.Error(err)
+
+	// the escalation of the timeouts must be known
+	_, err = params.Update(&ConsensusParamsUpdate{TimeoutParams: &TimeoutParams{Escalation: "exponential", Max: 60000}})
+	assert.NoError(err)
+	_, err = params.Update(&ConsensusParamsUpdate{TimeoutParams: &TimeoutParams{Escalation: "quadratic"}})
+	assert.Error(err)
+	_, err = params.Update(&ConsensusParamsUpdate{TimeoutParams: &TimeoutParams{Max: -1}})
+	assert.Error(err)
+}
+
+func TestConsensusParamsUpdateFromABCI(t *testing.T) {
+	assert := assert.New(t)
+
+	params := *DefaultConsensusParams()
+	assert.Nil(params.UpdateFromABCI(nil))
+
+	// the zero fields are left as they are
+	update := params.UpdateFromABCI(&abci.ConsensusParams{
+		BlockSize: &abci.BlockSize{MaxTxs: 10},
+		TxSize:    &abci.TxSize{MaxBytes: 1024},
+	})
+	res, err := params.Update(update)
+	assert.NoError(err)
+	assert.Equal(10, res.BlockSizeParams.MaxTxs)
+	assert.Equal(params.BlockSizeParams.MaxBytes, res.BlockSizeParams.MaxBytes)
+	assert.Equal(1024, res.TxSizeParams.MaxBytes)
+	assert.Equal(params.TxSizeParams.MaxGas, res.TxSizeParams.MaxGas)
+	assert.Nil(update.BlockGossipParams)
+	assert.Equal(params.BlockGossipParams, res.BlockGossipParams)
+}
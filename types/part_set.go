@@ -88,6 +88,14 @@ type PartSet struct {
 	parts         []*Part
 	partsBitArray *cmn.BitArray
 	count         int
+
+	// Parts are reassembled into one preallocated buffer, so a complete PartSet
+	// can be read without concatenating many small byte slices.
+	// buf is allocated once the part size is known,
+	// and dropped (see noBuf) if the parts don't fit a contiguous layout.
+	buf      []byte
+	partSize int
+	noBuf    bool
 }
 
 // Returns an immutable, full PartSet from the data bytes.
@@ -118,6 +126,8 @@ func NewPartSetFromData(data []byte, partSize int) *PartSet {
 		parts:         parts,
 		partsBitArray: partsBitArray,
 		count:         total,
+		buf:           data,
+		partSize:      partSize,
 	}
 }
 
@@ -185,6 +195,23 @@ func (ps *PartSet) Total() int {
 	return ps.total
 }
 
+// ByteSize returns the number of bytes of the parts added so far,
+// the size of the encoded block once the PartSet is complete.
+func (ps *PartSet) ByteSize() int {
+	if ps == nil {
+		return 0
+	}
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
+	size := 0
+	for _, part := range ps.parts {
+		if part != nil {
+			size += len(part.Bytes)
+		}
+	}
+	return size
+}
+
 func (ps *PartSet) AddPart(part *Part, verify bool) (bool, error) {
 	ps.mtx.Lock()
 	defer ps.mtx.Unlock()
@@ -207,12 +234,59 @@ func (ps *PartSet) AddPart(part *Part, verify bool) (bool, error) {
 	}
 
 	// Add part
-	ps.parts[part.Index] = part
+	ps.parts[part.Index] = ps.bufferPart(part)
 	ps.partsBitArray.SetIndex(part.Index, true)
 	ps.count++
 	return true, nil
 }
 
+// bufferPart copies the part into the reassembly buffer, allocating it
+// as soon as the part size is known, and returns the part to store.
+// Parts added before that are moved into the buffer when it is allocated.
+// CONTRACT: ps.mtx is held.
+func (ps *PartSet) bufferPart(part *Part) *Part {
+	if ps.noBuf {
+		return part
+	}
+	if ps.buf == nil {
+		// all parts but the last have the same size
+		if part.Index == ps.total-1 && ps.total > 1 {
+			return part
+		}
+		ps.partSize = len(part.Bytes)
+		if ps.partSize == 0 || ps.total*ps.partSize > maxBlockSizeBytes {
+			// not a valid block, don't allocate for it
+			ps.noBuf = true
+			return part
+		}
+		ps.buf = make([]byte, ps.total*ps.partSize)
+		for i, p := range ps.parts {
+			if p != nil {
+				ps.parts[i] = ps.bufferPart(p)
+			}
+		}
+		if ps.noBuf {
+			return part
+		}
+	}
+
+	size := len(part.Bytes)
+	if size > ps.partSize || (size < ps.partSize && part.Index != ps.total-1) {
+		// the data can't be contiguous, parts already in buf keep it alive
+		ps.noBuf = true
+		ps.buf = nil
+		return part
+	}
+	start := part.Index * ps.partSize
+	copy(ps.buf[start:start+size], part.Bytes)
+	return &Part{
+		Index: part.Index,
+		Bytes: ps.buf[start : start+size],
+		Proof: part.Proof,
+		hash:  part.hash,
+	}
+}
+
 func (ps *PartSet) GetPart(index int) *Part {
 	ps.mtx.Lock()
 	defer ps.mtx.Unlock()
@@ -227,6 +301,10 @@ func (ps *PartSet) GetReader() io.Reader {
 	if !ps.IsComplete() {
 		cmn.PanicSanity("Cannot GetReader() on incomplete PartSet")
 	}
+	if ps.buf != nil {
+		last := ps.parts[ps.total-1]
+		return bytes.NewReader(ps.buf[:(ps.total-1)*ps.partSize+len(last.Bytes)])
+	}
 	return NewPartSetReader(ps.parts)
 }
 
@@ -49,6 +49,9 @@ func TestBasicPartSet(t *testing.T) {
 	if !partSet2.IsComplete() {
 		t.Errorf("Reconstructed PartSet should be complete")
 	}
+	if partSet2.ByteSize() != len(data) {
+		t.Errorf("Expected to get %v bytes, but got %v", len(data), partSet2.ByteSize())
+	}
 
 	// Reconstruct data, assert that they are equal.
 	data2Reader := partSet2.GetReader()
@@ -88,3 +91,53 @@ func TestWrongProof(t *testing.T) {
 	}
 
 }
+
+func TestPartSetReassembly(t *testing.T) {
+
+	// The last part is shorter than the others
+	data := cmn.RandBytes(testPartSize*10 + 123)
+	partSet := NewPartSetFromData(data, testPartSize)
+
+	// Add the parts in reverse order, so the part size is only known from the second one
+	partSet2 := NewPartSetFromHeader(partSet.Header())
+	for i := partSet.Total() - 1; i >= 0; i-- {
+		added, err := partSet2.AddPart(partSet.GetPart(i), true)
+		if !added || err != nil {
+			t.Errorf("Failed to add part %v, error: %v", i, err)
+		}
+	}
+	if partSet2.buf == nil {
+		t.Fatal("Expected the parts to be reassembled in a buffer")
+	}
+	data2, err := ioutil.ReadAll(partSet2.GetReader())
+	if err != nil {
+		t.Errorf("Error reading data2Reader: %v", err)
+	}
+	if !bytes.Equal(data, data2) {
+		t.Errorf("Got wrong data.")
+	}
+
+	// Parts which don't have the same size are still reassembled, without the buffer
+	parts := []*Part{
+		{Index: 0, Bytes: cmn.RandBytes(10)},
+		{Index: 1, Bytes: cmn.RandBytes(20)},
+		{Index: 2, Bytes: cmn.RandBytes(5)},
+	}
+	partSet3 := NewPartSetFromHeader(PartSetHeader{Total: 3})
+	for _, part := range parts {
+		if added, err := partSet3.AddPart(part, false); !added || err != nil {
+			t.Errorf("Failed to add part %v, error: %v", part.Index, err)
+		}
+	}
+	if partSet3.buf != nil {
+		t.Error("Expected the buffer to be dropped")
+	}
+	data3, err := ioutil.ReadAll(partSet3.GetReader())
+	if err != nil {
+		t.Errorf("Error reading data3Reader: %v", err)
+	}
+	expected := append(append(append([]byte{}, parts[0].Bytes...), parts[1].Bytes...), parts[2].Bytes...)
+	if !bytes.Equal(expected, data3) {
+		t.Errorf("Got wrong data.")
+	}
+}
@@ -0,0 +1,66 @@
+package types
+
+import (
+	"sort"
+)
+
+// PowerDistribution describes how the voting power is spread among the validators
+// of a set, eg. for decentralization dashboards.
+type PowerDistribution struct {
+	Height           int64 `json:"height"` // the set is the one of this height
+	NumValidators    int   `json:"num_validators"`
+	TotalVotingPower int64 `json:"total_voting_power"`
+	MaxVotingPower   int64 `json:"max_voting_power"`
+
+	// Gini coefficient of the voting powers: 0 when they are all equal,
+	// and up to 1 - 1/NumValidators when one validator has all of it.
+	Gini float64 `json:"gini"`
+	// NakamotoCoefficient is the fewest validators with more than 1/3 of the
+	// voting power, who can halt the chain together.
+	NakamotoCoefficient int `json:"nakamoto_coefficient"`
+	// SupermajorityCoefficient is the fewest validators with more than 2/3 of
+	// the voting power, who can commit any block together.
+	SupermajorityCoefficient int `json:"supermajority_coefficient"`
+}
+
+// NewPowerDistribution returns the distribution of the voting power of the validators
+// of the set of the given height.
+func NewPowerDistribution(height int64, valSet *ValidatorSet) PowerDistribution {
+	powers := make([]int64, 0, valSet.Size())
+	for _, val := range valSet.Validators {
+		powers = append(powers, val.VotingPower)
+	}
+	// decreasing
+	sort.Slice(powers, func(i, j int) bool { return powers[i] > powers[j] })
+
+	dist := PowerDistribution{
+		Height:        height,
+		NumValidators: len(powers),
+	}
+	for _, power := range powers {
+		dist.TotalVotingPower += power
+	}
+	if dist.TotalVotingPower == 0 {
+		return dist
+	}
+	dist.MaxVotingPower = powers[0]
+
+	// G = sum((2i - n - 1) * x_i) / (n * sum(x_i)), with x_i increasing and i from 1,
+	// so (n + 1 - 2i) with them decreasing
+	n := int64(len(powers))
+	weighted := 0.0
+	var cumulative int64
+	for i, power := range powers {
+		weighted += float64(n+1-2*int64(i+1)) * float64(power)
+
+		cumulative += power
+		if dist.NakamotoCoefficient == 0 && cumulative*3 > dist.TotalVotingPower {
+			dist.NakamotoCoefficient = i + 1
+		}
+		if dist.SupermajorityCoefficient == 0 && cumulative*3 > dist.TotalVotingPower*2 {
+			dist.SupermajorityCoefficient = i + 1
+		}
+	}
+	dist.Gini = weighted / (float64(n) * float64(dist.TotalVotingPower))
+	return dist
+}
@@ -0,0 +1,55 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	crypto "github.com/tendermint/go-crypto"
+)
+
+func TestPowerDistribution(t *testing.T) {
+	assert := assert.New(t)
+
+	newValSet := func(powers ...int64) *ValidatorSet {
+		vals := make([]*Validator, len(powers))
+		for i, power := range powers {
+			vals[i] = NewValidator(crypto.GenPrivKeyEd25519().PubKey(), power)
+		}
+		return NewValidatorSet(vals)
+	}
+
+	// equal powers
+	dist := NewPowerDistribution(5, newValSet(10, 10, 10, 10))
+	assert.Equal(PowerDistribution{
+		Height:                   5,
+		NumValidators:            4,
+		TotalVotingPower:         40,
+		MaxVotingPower:           10,
+		Gini:                     0,
+		NakamotoCoefficient:      2,
+		SupermajorityCoefficient: 3,
+	}, dist)
+
+	// one big validator
+	dist = NewPowerDistribution(5, newValSet(10, 60, 10, 10, 10))
+	assert.EqualValues(100, dist.TotalVotingPower)
+	assert.EqualValues(60, dist.MaxVotingPower)
+	assert.InDelta(0.4, dist.Gini, 1e-9)
+	assert.Equal(1, dist.NakamotoCoefficient)
+	assert.Equal(2, dist.SupermajorityCoefficient)
+
+	// exactly 1/3 is not more than 1/3
+	dist = NewPowerDistribution(5, newValSet(1, 1, 1))
+	assert.Equal(2, dist.NakamotoCoefficient)
+	assert.Equal(3, dist.SupermajorityCoefficient)
+
+	// a single validator has it all
+	dist = NewPowerDistribution(5, newValSet(7))
+	assert.Equal(0.0, dist.Gini)
+	assert.Equal(1, dist.NakamotoCoefficient)
+	assert.Equal(1, dist.SupermajorityCoefficient)
+
+	// nothing to distribute
+	dist = NewPowerDistribution(5, NewValidatorSet(nil))
+	assert.Equal(PowerDistribution{Height: 5}, dist)
+}
@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sync"
+	"time"
 
 	crypto "github.com/tendermint/go-crypto"
 	data "github.com/tendermint/go-wire/data"
@@ -108,7 +110,12 @@ func (pv *PrivValidatorFS) GetPubKey() crypto.PubKey {
 // GenPrivValidatorFS generates a new validator with randomly generated private key
 // and sets the filePath, but does not call Save().
 func GenPrivValidatorFS(filePath string) *PrivValidatorFS {
-	privKey := crypto.GenPrivKeyEd25519().Wrap()
+	return NewPrivValidatorFS(crypto.GenPrivKeyEd25519().Wrap(), filePath)
+}
+
+// NewPrivValidatorFS returns a validator with the private key, eg. an imported one,
+// and no last signed state, and sets the filePath, but does not call Save().
+func NewPrivValidatorFS(privKey crypto.PrivKey, filePath string) *PrivValidatorFS {
 	return &PrivValidatorFS{
 		Address:  privKey.PubKey().Address(),
 		PubKey:   privKey.PubKey(),
@@ -159,6 +166,32 @@ func LoadPrivValidatorFSWithSigner(filePath string, signerFunc func(PrivValidato
 	return privVal
 }
 
+// LoadOrGenPrivValidatorFSWithSigner loads a PrivValidatorFS from the filePath
+// with the signer, eg. a hardware signer holding the private key of the pubKey,
+// or else creates one without private key and saves it to the filePath.
+// It returns an error if the filePath is for another key.
+func LoadOrGenPrivValidatorFSWithSigner(filePath string, signer Signer, pubKey crypto.PubKey) (*PrivValidatorFS, error) {
+	if _, err := os.Stat(filePath); err != nil {
+		privVal := &PrivValidatorFS{
+			Address:  pubKey.Address(),
+			PubKey:   pubKey,
+			LastStep: stepNone,
+			Signer:   signer,
+			filePath: filePath,
+		}
+		privVal.Save()
+		return privVal, nil
+	}
+
+	privVal := LoadPrivValidatorFSWithSigner(filePath, func(PrivValidator) Signer {
+		return signer
+	})
+	if !privVal.PubKey.Equals(pubKey) {
+		return nil, fmt.Errorf("%s is for the validator %X, not for the validator %X of the signer", filePath, privVal.Address, pubKey.Address())
+	}
+	return privVal, nil
+}
+
 // Save persists the PrivValidatorFS to disk.
 func (privVal *PrivValidatorFS) Save() {
 	privVal.mtx.Lock()
@@ -167,19 +200,58 @@ func (privVal *PrivValidatorFS) Save() {
 }
 
 func (privVal *PrivValidatorFS) save() {
+	if err := privVal.persist(); err != nil {
+		// `@; BOOM!!!
+		cmn.PanicCrisis(err)
+	}
+}
+
+// persist writes the PrivValidatorFS to disk, and syncs it before returning,
+// so the last signed height/round/step survive a crash of the machine.
+func (privVal *PrivValidatorFS) persist() error {
 	if privVal.filePath == "" {
 		cmn.PanicSanity("Cannot save PrivValidator: filePath not set")
 	}
 	jsonBytes, err := json.Marshal(privVal)
 	if err != nil {
-		// `@; BOOM!!!
-		cmn.PanicCrisis(err)
+		return err
 	}
-	err = cmn.WriteFileAtomic(privVal.filePath, jsonBytes, 0600)
+	return writeFileSync(privVal.filePath, jsonBytes, 0600)
+}
+
+// writeFileSync atomically replaces the file with the data, by renaming a
+// temporary file over it, and syncs the file and its directory to disk.
+func writeFileSync(filePath string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(filePath)
+	f, err := ioutil.TempFile(dir, filepath.Base(filePath)+".tmp")
 	if err != nil {
-		// `@; BOOM!!!
-		cmn.PanicCrisis(err)
+		return err
+	}
+	tmpPath := f.Name()
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
 	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, perm)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, filePath)
+	}
+	if err != nil {
+		os.Remove(tmpPath) // nolint: errcheck
+		return err
+	}
+
+	// sync the directory, for the rename to be durable
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close() // nolint: errcheck
+	return d.Sync()
 }
 
 // Reset resets all fields in the PrivValidatorFS.
@@ -198,7 +270,21 @@ func (privVal *PrivValidatorFS) Reset() {
 func (privVal *PrivValidatorFS) SignVote(chainID string, vote *Vote) error {
 	privVal.mtx.Lock()
 	defer privVal.mtx.Unlock()
-	signature, err := privVal.signBytesHRS(vote.Height, vote.Round, voteToStep(vote), SignBytes(chainID, vote))
+	// the timestamp is signed: a vote without one gets the current time
+	if vote.Timestamp.IsZero() {
+		vote.Timestamp = VoteTime(time.Now(), time.Time{})
+	}
+	step := voteToStep(vote)
+	signBytes := SignBytes(chainID, vote)
+	// the vote may be signed again, eg. on WAL replay, with a new timestamp:
+	// give it the timestamp it was signed with, not to sign a conflicting vote
+	if privVal.LastHeight == vote.Height && privVal.LastRound == vote.Round && privVal.LastStep == step {
+		if timestamp, ok := onlyTimestampDiffers(privVal.LastSignBytes, signBytes); ok {
+			vote.Timestamp = timestamp
+			signBytes = SignBytes(chainID, vote)
+		}
+	}
+	signature, err := privVal.signBytesHRS(vote.Height, vote.Round, step, signBytes)
 	if err != nil {
 		return errors.New(cmn.Fmt("Error signing vote: %v", err))
 	}
@@ -206,6 +292,32 @@ func (privVal *PrivValidatorFS) SignVote(chainID string, vote *Vote) error {
 	return nil
 }
 
+// onlyTimestampDiffers returns the timestamp of the vote signed as lastSignBytes,
+// and true if it's the vote of signBytes with a different timestamp.
+func onlyTimestampDiffers(lastSignBytes, signBytes []byte) (time.Time, bool) {
+	var lastVote, vote CanonicalJSONOnceVote
+	if err := json.Unmarshal(lastSignBytes, &lastVote); err != nil {
+		return time.Time{}, false
+	}
+	if err := json.Unmarshal(signBytes, &vote); err != nil {
+		return time.Time{}, false
+	}
+	timestamp, err := time.Parse(TimeFormat, lastVote.Vote.Timestamp)
+	if err != nil {
+		return time.Time{}, false
+	}
+	lastVote.Vote.Timestamp = vote.Vote.Timestamp
+	lastBytes, err := json.Marshal(lastVote)
+	if err != nil {
+		return time.Time{}, false
+	}
+	bz, err := json.Marshal(vote)
+	if err != nil || !bytes.Equal(lastBytes, bz) {
+		return time.Time{}, false
+	}
+	return timestamp, true
+}
+
 // SignProposal signs a canonical representation of the proposal, along with the chainID.
 // Implements PrivValidator.
 func (privVal *PrivValidatorFS) SignProposal(chainID string, proposal *Proposal) error {
@@ -222,6 +334,8 @@ func (privVal *PrivValidatorFS) SignProposal(chainID string, proposal *Proposal)
 // signBytesHRS signs the given signBytes if the height/round/step (HRS)
 // are greater than the latest state. If the HRS are equal,
 // it returns the privValidator.LastSignature.
+// The new HRS is synced to disk before the signature is returned, so a
+// crash can't make us sign at the same or a lower HRS again.
 func (privVal *PrivValidatorFS) signBytesHRS(height int64, round int, step int8, signBytes []byte) (crypto.Signature, error) {
 
 	sig := crypto.Signature{}
@@ -264,12 +378,19 @@ func (privVal *PrivValidatorFS) signBytesHRS(height int64, round int, step int8,
 	}
 
 	// Persist height/round/step
+	lastHeight, lastRound, lastStep := privVal.LastHeight, privVal.LastRound, privVal.LastStep
+	lastSignature, lastSignBytes := privVal.LastSignature, privVal.LastSignBytes
 	privVal.LastHeight = height
 	privVal.LastRound = round
 	privVal.LastStep = step
 	privVal.LastSignature = sig
 	privVal.LastSignBytes = signBytes
-	privVal.save()
+	if err := privVal.persist(); err != nil {
+		// the signature must not be used if it's not persisted
+		privVal.LastHeight, privVal.LastRound, privVal.LastStep = lastHeight, lastRound, lastStep
+		privVal.LastSignature, privVal.LastSignBytes = lastSignature, lastSignBytes
+		return crypto.Signature{}, fmt.Errorf("Error persisting the last signed state: %v", err)
+	}
 
 	return sig, nil
 }
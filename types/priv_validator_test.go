@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -43,6 +44,33 @@ func TestLoadOrGenValidator(t *testing.T) {
 	assert.Equal(addr, privVal.GetAddress(), "expected privval addr to be the same")
 }
 
+func TestLoadOrGenValidatorWithSigner(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+
+	_, tempFilePath := cmn.Tempfile("priv_validator_")
+	require.Nil(os.Remove(tempFilePath))
+
+	// eg. a hardware signer
+	privKey := crypto.GenPrivKeyEd25519().Wrap()
+	signer := NewDefaultSigner(privKey)
+	privVal, err := LoadOrGenPrivValidatorFSWithSigner(tempFilePath, signer, privKey.PubKey())
+	require.Nil(err)
+	vote := newVote(privVal.Address, 0, 10, 1, VoteTypePrevote, BlockID{[]byte{1, 2, 3}, PartSetHeader{}})
+	require.Nil(privVal.SignVote("mychainid", vote))
+	assert.True(privKey.PubKey().VerifyBytes(SignBytes("mychainid", vote), vote.Signature))
+
+	// the private key is not saved, the last signed state is
+	privVal = LoadPrivValidatorFS(tempFilePath)
+	assert.True(privVal.PrivKey.Empty())
+	privVal, err = LoadOrGenPrivValidatorFSWithSigner(tempFilePath, signer, privKey.PubKey())
+	require.Nil(err)
+	assert.Equal(int64(10), privVal.LastHeight)
+
+	// the file of another key
+	_, err = LoadOrGenPrivValidatorFSWithSigner(tempFilePath, signer, crypto.GenPrivKeyEd25519().PubKey())
+	assert.NotNil(err)
+}
+
 func TestUnmarshalValidator(t *testing.T) {
 	assert, require := assert.New(t), require.New(t)
 
@@ -125,6 +153,81 @@ func TestSignVote(t *testing.T) {
 	}
 }
 
+func TestSignVoteTimestamp(t *testing.T) {
+	assert := assert.New(t)
+
+	_, tempFilePath := cmn.Tempfile("priv_validator_")
+	privVal := GenPrivValidatorFS(tempFilePath)
+
+	block1 := BlockID{[]byte{1, 2, 3}, PartSetHeader{}}
+	timestamp := time.Date(2017, 12, 1, 10, 0, 0, 0, time.UTC)
+	vote := newVote(privVal.Address, 0, 10, 1, VoteTypePrecommit, block1)
+	vote.Timestamp = timestamp
+	assert.NoError(privVal.SignVote("mychainid", vote))
+
+	// signing the vote again at another time gives the same vote
+	again := newVote(privVal.Address, 0, 10, 1, VoteTypePrecommit, block1)
+	again.Timestamp = timestamp.Add(time.Second)
+	assert.NoError(privVal.SignVote("mychainid", again))
+	assert.True(timestamp.Equal(again.Timestamp))
+	assert.Equal(vote.Signature, again.Signature)
+
+	// but it's still a conflicting vote for another block
+	block2 := BlockID{[]byte{3, 2, 1}, PartSetHeader{}}
+	conflicting := newVote(privVal.Address, 0, 10, 1, VoteTypePrecommit, block2)
+	conflicting.Timestamp = timestamp.Add(time.Second)
+	assert.Error(privVal.SignVote("mychainid", conflicting))
+
+	// a vote without a timestamp is signed with the current time
+	next := newVote(privVal.Address, 0, 11, 0, VoteTypePrevote, block1)
+	assert.NoError(privVal.SignVote("mychainid", next))
+	assert.WithinDuration(time.Now(), next.Timestamp, time.Second)
+}
+
+func TestSignVoteAfterCrash(t *testing.T) {
+	assert := assert.New(t)
+
+	_, tempFilePath := cmn.Tempfile("priv_validator_")
+	privVal := GenPrivValidatorFS(tempFilePath)
+
+	block1 := BlockID{[]byte{1, 2, 3}, PartSetHeader{}}
+	block2 := BlockID{[]byte{3, 2, 1}, PartSetHeader{}}
+	vote := newVote(privVal.Address, 0, 10, 1, VoteTypePrecommit, block1)
+	assert.NoError(privVal.SignVote("mychainid", vote))
+
+	// the last signed state is on disk as soon as the vote is signed
+	privVal = LoadPrivValidatorFS(tempFilePath)
+	assert.Error(privVal.SignVote("mychainid", newVote(privVal.Address, 0, 10, 1, VoteTypePrecommit, block2)))
+	assert.Error(privVal.SignVote("mychainid", newVote(privVal.Address, 0, 10, 1, VoteTypePrevote, block1)))
+	assert.Error(privVal.SignProposal("mychainid", newProposal(10, 1, PartSetHeader{5, []byte{1, 2, 3}})))
+
+	// the vote isn't signed if it can't be persisted
+	privVal.filePath = tempFilePath + "_missing/priv_validator.json"
+	assert.Error(privVal.SignVote("mychainid", newVote(privVal.Address, 0, 11, 0, VoteTypePrevote, block1)))
+	assert.Equal(int64(10), privVal.LastHeight)
+}
+
+func TestSignVoteSecp256k1(t *testing.T) {
+	_, tempFilePath := cmn.Tempfile("priv_validator_")
+	privKey, err := GenPrivKey(KeyTypeSecp256k1)
+	require.NoError(t, err)
+	privVal := NewPrivValidatorFS(privKey, tempFilePath)
+	privVal.Save()
+
+	// the key type is kept on disk
+	privVal = LoadPrivValidatorFS(tempFilePath)
+	_, ok := privVal.GetPubKey().Unwrap().(crypto.PubKeySecp256k1)
+	require.True(t, ok)
+	require.NoError(t, NewValidator(privVal.GetPubKey(), 10).ValidateBasic())
+
+	vote := newVote(privVal.Address, 0, 10, 1, VoteTypePrecommit, BlockID{[]byte{1, 2, 3}, PartSetHeader{}})
+	require.NoError(t, privVal.SignVote("mychainid", vote))
+	assert.True(t, privVal.GetPubKey().VerifyBytes(SignBytes("mychainid", vote), vote.Signature))
+
+	_, err = GenPrivKey("rsa")
+	assert.Error(t, err)
+}
+
 func TestSignProposal(t *testing.T) {
 	assert := assert.New(t)
 
@@ -0,0 +1,144 @@
+package privval
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tendermint/ed25519"
+	crypto "github.com/tendermint/go-crypto"
+)
+
+// The validator keys are exported and imported either as PEM, the PKCS#8
+// encoding of RFC 8410 understood by OpenSSL and most HSM provisioning tools,
+// optionally encrypted with a passphrase, or as the raw hex of the 32 bytes
+// seed, or of the 64 bytes private key as in the priv_validator.json.
+
+const (
+	// KeyFormatPEM is the PKCS#8 PEM format.
+	KeyFormatPEM = "pem"
+	// KeyFormatHex is the raw hex format.
+	KeyFormatHex = "hex"
+
+	pemTypePrivateKey = "PRIVATE KEY"
+)
+
+var oidEd25519 = asn1.ObjectIdentifier{1, 3, 101, 112}
+
+// ErrHexKeyEncrypted is returned when a passphrase is given for a hex key.
+var ErrHexKeyEncrypted = errors.New("Hex keys can't be encrypted, use the pem format")
+
+// pkcs8 is the PrivateKeyInfo of RFC 5208.
+type pkcs8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+// ExportPrivKey encodes the private key in the format, the PEM encrypted
+// with the passphrase unless it's empty.
+func ExportPrivKey(privKey crypto.PrivKeyEd25519, format string, passphrase []byte) ([]byte, error) {
+	switch format {
+	case KeyFormatPEM:
+		// the private key is the seed, in an OCTET STRING
+		seed, err := asn1.Marshal(privKey[:32])
+		if err != nil {
+			return nil, err
+		}
+		der, err := asn1.Marshal(pkcs8{
+			Algo:       pkix.AlgorithmIdentifier{Algorithm: oidEd25519},
+			PrivateKey: seed,
+		})
+		if err != nil {
+			return nil, err
+		}
+		block := &pem.Block{Type: pemTypePrivateKey, Bytes: der}
+		if len(passphrase) > 0 {
+			block, err = x509.EncryptPEMBlock(rand.Reader, pemTypePrivateKey, der, passphrase, x509.PEMCipherAES256)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return pem.EncodeToMemory(block), nil
+	case KeyFormatHex:
+		if len(passphrase) > 0 {
+			return nil, ErrHexKeyEncrypted
+		}
+		return []byte(fmt.Sprintf("%X\n", privKey[:])), nil
+	default:
+		return nil, fmt.Errorf("Unknown key format %q, expected %q or %q", format, KeyFormatPEM, KeyFormatHex)
+	}
+}
+
+// ImportPrivKey decodes a private key exported in any of the formats,
+// the PEM decrypted with the passphrase if it's encrypted.
+func ImportPrivKey(bz []byte, passphrase []byte) (crypto.PrivKeyEd25519, error) {
+	bz = bytes.TrimSpace(bz)
+	if bytes.HasPrefix(bz, []byte("-----BEGIN")) {
+		return importPEM(bz, passphrase)
+	}
+	if len(passphrase) > 0 {
+		return crypto.PrivKeyEd25519{}, ErrHexKeyEncrypted
+	}
+	return importHex(string(bz))
+}
+
+func importPEM(bz []byte, passphrase []byte) (crypto.PrivKeyEd25519, error) {
+	block, _ := pem.Decode(bz)
+	if block == nil || block.Type != pemTypePrivateKey {
+		return crypto.PrivKeyEd25519{}, fmt.Errorf("No %s PEM block found", pemTypePrivateKey)
+	}
+	der := block.Bytes
+	if x509.IsEncryptedPEMBlock(block) {
+		if len(passphrase) == 0 {
+			return crypto.PrivKeyEd25519{}, errors.New("The key is encrypted, a passphrase is required")
+		}
+		var err error
+		if der, err = x509.DecryptPEMBlock(block, passphrase); err != nil {
+			return crypto.PrivKeyEd25519{}, err
+		}
+	}
+
+	var info pkcs8
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return crypto.PrivKeyEd25519{}, fmt.Errorf("Invalid PKCS#8 key: %v", err)
+	}
+	if !info.Algo.Algorithm.Equal(oidEd25519) {
+		return crypto.PrivKeyEd25519{}, fmt.Errorf("Unsupported key algorithm %v, only ed25519 is supported", info.Algo.Algorithm)
+	}
+	var seed []byte
+	if _, err := asn1.Unmarshal(info.PrivateKey, &seed); err != nil {
+		return crypto.PrivKeyEd25519{}, fmt.Errorf("Invalid ed25519 private key: %v", err)
+	}
+	return privKeyFromBytes(seed)
+}
+
+func importHex(str string) (crypto.PrivKeyEd25519, error) {
+	bz, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(str), "0x"))
+	if err != nil {
+		return crypto.PrivKeyEd25519{}, fmt.Errorf("Invalid hex key: %v", err)
+	}
+	return privKeyFromBytes(bz)
+}
+
+// privKeyFromBytes returns the private key of the 32 bytes seed,
+// or of the 64 bytes seed and public key, which is checked.
+func privKeyFromBytes(bz []byte) (crypto.PrivKeyEd25519, error) {
+	if len(bz) != 32 && len(bz) != 64 {
+		return crypto.PrivKeyEd25519{}, fmt.Errorf("Invalid ed25519 private key length %d, expected 32 or 64", len(bz))
+	}
+	var privKeyBytes [64]byte
+	copy(privKeyBytes[:32], bz[:32])
+	ed25519.MakePublicKey(&privKeyBytes)
+	if len(bz) == 64 && !bytes.Equal(privKeyBytes[32:], bz[32:]) {
+		return crypto.PrivKeyEd25519{}, errors.New("The public key doesn't match the private key")
+	}
+	return crypto.PrivKeyEd25519(privKeyBytes), nil
+}
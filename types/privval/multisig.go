@@ -0,0 +1,172 @@
+package privval
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	crypto "github.com/tendermint/go-crypto"
+	data "github.com/tendermint/go-wire/data"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// PrivValidatorMultisig implements types.PrivValidator by collecting the signatures
+// of threshold of its co-signers, eg. PrivValidatorSocketClients of remote signers
+// on separate machines, into a types.MultiSignature. Its public key is the
+// types.MultisigPubKey of the co-signers' keys, so M-of-N keys must be compromised
+// to sign for the validator, and each co-signer keeps its own double sign protection.
+type PrivValidatorMultisig struct {
+	threshold int
+	signers   []types.PrivValidator
+	pubKey    crypto.PubKey
+
+	// the last signature, returned again for the same sign bytes, eg. on WAL replay,
+	// where other co-signers may answer first
+	mtx           sync.Mutex
+	lastSignBytes []byte
+	lastSignature crypto.Signature
+}
+
+var _ types.PrivValidator = (*PrivValidatorMultisig)(nil)
+
+// NewPrivValidatorMultisig returns a PrivValidatorMultisig signing with threshold of the signers,
+// which must be ready to return their public key.
+func NewPrivValidatorMultisig(threshold int, signers []types.PrivValidator) (*PrivValidatorMultisig, error) {
+	pubKeys := make([]crypto.PubKey, len(signers))
+	for i, signer := range signers {
+		pubKeys[i] = signer.GetPubKey()
+	}
+	pubKey, err := types.NewMultisigPubKey(threshold, pubKeys)
+	if err != nil {
+		return nil, err
+	}
+	return &PrivValidatorMultisig{
+		threshold: threshold,
+		signers:   signers,
+		pubKey:    pubKey,
+	}, nil
+}
+
+// GetAddress implements types.PrivValidator.
+func (pv *PrivValidatorMultisig) GetAddress() data.Bytes {
+	return pv.pubKey.Address()
+}
+
+// GetPubKey implements types.PrivValidator.
+func (pv *PrivValidatorMultisig) GetPubKey() crypto.PubKey {
+	return pv.pubKey
+}
+
+// SignVote implements types.PrivValidator.
+func (pv *PrivValidatorMultisig) SignVote(chainID string, vote *types.Vote) error {
+	// all the signers must sign the same timestamp
+	if vote.Timestamp.IsZero() {
+		vote.Timestamp = types.VoteTime(time.Now(), time.Time{})
+	}
+	sig, err := pv.sign(types.SignBytes(chainID, vote), func(signer types.PrivValidator) ([]byte, crypto.Signature, error) {
+		partial := *vote
+		err := signer.SignVote(chainID, &partial)
+		return types.SignBytes(chainID, &partial), partial.Signature, err
+	})
+	if err != nil {
+		return fmt.Errorf("Error signing vote: %v", err)
+	}
+	vote.Signature = sig
+	return nil
+}
+
+// SignProposal implements types.PrivValidator.
+func (pv *PrivValidatorMultisig) SignProposal(chainID string, proposal *types.Proposal) error {
+	sig, err := pv.sign(types.SignBytes(chainID, proposal), func(signer types.PrivValidator) ([]byte, crypto.Signature, error) {
+		partial := *proposal
+		err := signer.SignProposal(chainID, &partial)
+		return types.SignBytes(chainID, &partial), partial.Signature, err
+	})
+	if err != nil {
+		return fmt.Errorf("Error signing proposal: %v", err)
+	}
+	proposal.Signature = sig
+	return nil
+}
+
+// SignHeartbeat implements types.PrivValidator.
+func (pv *PrivValidatorMultisig) SignHeartbeat(chainID string, heartbeat *types.Heartbeat) error {
+	sig, err := pv.sign(types.SignBytes(chainID, heartbeat), func(signer types.PrivValidator) ([]byte, crypto.Signature, error) {
+		partial := *heartbeat
+		err := signer.SignHeartbeat(chainID, &partial)
+		return types.SignBytes(chainID, &partial), partial.Signature, err
+	})
+	if err != nil {
+		return fmt.Errorf("Error signing heartbeat: %v", err)
+	}
+	heartbeat.Signature = sig
+	return nil
+}
+
+type partialSignature struct {
+	index int
+	sig   crypto.Signature
+	err   error
+}
+
+// sign requests the signature of the signBytes from all the co-signers at once,
+// and returns the MultiSignature of the first threshold valid ones. A co-signer
+// signing other sign bytes, eg. a vote with the timestamp it signed before, is ignored.
+func (pv *PrivValidatorMultisig) sign(signBytes []byte, signFn func(types.PrivValidator) ([]byte, crypto.Signature, error)) (crypto.Signature, error) {
+	pv.mtx.Lock()
+	defer pv.mtx.Unlock()
+	if bytes.Equal(signBytes, pv.lastSignBytes) {
+		return pv.lastSignature, nil
+	}
+
+	results := make(chan partialSignature, len(pv.signers))
+	for i, signer := range pv.signers {
+		go func(i int, signer types.PrivValidator) {
+			signed, sig, err := signFn(signer)
+			if err == nil && !bytes.Equal(signed, signBytes) {
+				err = errors.New("Signed other sign bytes")
+			}
+			if err == nil && !signer.GetPubKey().VerifyBytes(signBytes, sig) {
+				err = errors.New("Invalid signature")
+			}
+			results <- partialSignature{i, sig, err}
+		}(i, signer)
+	}
+
+	sigs := make(map[int]crypto.Signature, pv.threshold)
+	var errs []string
+	for range pv.signers {
+		res := <-results
+		if res.err != nil {
+			errs = append(errs, fmt.Sprintf("co-signer %d: %v", res.index, res.err))
+			continue
+		}
+		sigs[res.index] = res.sig
+		if len(sigs) == pv.threshold {
+			break
+		}
+	}
+	if len(sigs) < pv.threshold {
+		return crypto.Signature{}, fmt.Errorf("Got %d of the %d signatures needed (%s)",
+			len(sigs), pv.threshold, strings.Join(errs, "; "))
+	}
+
+	var multiSig types.MultiSignature
+	for i := range pv.signers {
+		if sig, ok := sigs[i]; ok {
+			multiSig.Indexes = append(multiSig.Indexes, i)
+			multiSig.Sigs = append(multiSig.Sigs, sig)
+		}
+	}
+	pv.lastSignBytes, pv.lastSignature = signBytes, multiSig.Wrap()
+	return pv.lastSignature, nil
+}
+
+// String returns a string representation of the PrivValidatorMultisig.
+func (pv *PrivValidatorMultisig) String() string {
+	return fmt.Sprintf("PrivValidatorMultisig{%v %d of %d}", pv.GetAddress(), pv.threshold, len(pv.signers))
+}
@@ -0,0 +1,59 @@
+package privval
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestPrivValidatorMultisig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "privval_multisig")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	coSigners := make([]*types.PrivValidatorFS, 3)
+	signers := make([]types.PrivValidator, 3)
+	for i := range coSigners {
+		coSigners[i] = types.GenPrivValidatorFS(filepath.Join(dir, fmt.Sprintf("priv_validator_%d.json", i)))
+		signers[i] = coSigners[i]
+	}
+	_, err = NewPrivValidatorMultisig(4, signers)
+	assert.NotNil(t, err)
+	pv, err := NewPrivValidatorMultisig(2, signers)
+	require.Nil(t, err)
+
+	blockID := types.BlockID{[]byte{1, 2, 3}, types.PartSetHeader{}}
+	vote := &types.Vote{ValidatorAddress: pv.GetAddress(), Height: 10, Round: 1, Type: types.VoteTypePrevote, BlockID: blockID}
+	require.Nil(t, pv.SignVote(chainID, vote))
+	assert.True(t, pv.GetPubKey().VerifyBytes(types.SignBytes(chainID, vote), vote.Signature))
+
+	// the same vote gets the same signature
+	again := *vote
+	require.Nil(t, pv.SignVote(chainID, &again))
+	assert.True(t, vote.Signature.Equals(again.Signature))
+
+	// a co-signer refusing to sign, eg. not to double sign, is ignored
+	precommit := &types.Vote{ValidatorAddress: pv.GetAddress(), Height: 11, Round: 0, Type: types.VoteTypePrecommit, BlockID: blockID}
+	conflicting := *precommit
+	conflicting.BlockID = types.BlockID{[]byte{3, 2, 1}, types.PartSetHeader{}}
+	require.Nil(t, coSigners[2].SignVote(chainID, &conflicting))
+	require.Nil(t, pv.SignVote(chainID, precommit))
+	multiSig := precommit.Signature.Unwrap().(types.MultiSignature)
+	assert.Equal(t, []int{0, 1}, multiSig.Indexes)
+
+	// but not two of them
+	proposal := &types.Proposal{Height: 12, Round: 0, BlockPartsHeader: types.PartSetHeader{5, []byte{1, 2, 3}}, POLRound: -1}
+	for _, coSigner := range coSigners[1:] {
+		other := *proposal
+		other.BlockPartsHeader = types.PartSetHeader{5, []byte{3, 2, 1}}
+		require.Nil(t, coSigner.SignProposal(chainID, &other))
+	}
+	assert.NotNil(t, pv.SignProposal(chainID, proposal))
+}
@@ -0,0 +1,47 @@
+package privval
+
+import (
+	"encoding/asn1"
+	"errors"
+	"fmt"
+
+	crypto "github.com/tendermint/go-crypto"
+)
+
+// The PKCS11Signer signs with an ed25519 key held by a hardware security
+// module, eg. a YubiHSM or a CloudHSM, through its PKCS#11 module. It needs
+// cgo and the github.com/miekg/pkcs11 bindings, so it's only built with the
+// pkcs11 build tag:
+//
+//    go build -tags pkcs11 ./cmd/tendermint
+//
+// Without it, NewPKCS11Signer returns ErrPKCS11Disabled.
+
+const (
+	// Mechanism and key type of the ed25519 keys, from PKCS#11 v3.0
+	ckmEdDSA     = 0x1057 // CKM_EDDSA
+	ckkECEdwards = 0x40   // CKK_EC_EDWARDS
+)
+
+// ErrPKCS11Disabled is returned when the node was built without the pkcs11 build tag.
+var ErrPKCS11Disabled = errors.New("PKCS#11 support is disabled, build with the pkcs11 tag to sign with an HSM")
+
+// ed25519PubKeyFromPoint returns the public key of the CKA_EC_POINT of an ed25519
+// public key object, the DER encoding of the 32 bytes point in an OCTET STRING,
+// or the raw point for the modules that don't encode it.
+func ed25519PubKeyFromPoint(point []byte) (crypto.PubKey, error) {
+	if len(point) != 32 {
+		var raw []byte
+		rest, err := asn1.Unmarshal(point, &raw)
+		if err != nil || len(rest) != 0 {
+			return crypto.PubKey{}, fmt.Errorf("Invalid CKA_EC_POINT %X", point)
+		}
+		point = raw
+	}
+	if len(point) != 32 {
+		return crypto.PubKey{}, fmt.Errorf("Invalid ed25519 public key length %d, expected 32", len(point))
+	}
+	var pubKey crypto.PubKeyEd25519
+	copy(pubKey[:], point)
+	return pubKey.Wrap(), nil
+}
@@ -0,0 +1,30 @@
+// +build !pkcs11
+
+package privval
+
+import (
+	crypto "github.com/tendermint/go-crypto"
+)
+
+// PKCS11Signer is disabled, see ErrPKCS11Disabled.
+type PKCS11Signer struct{}
+
+// NewPKCS11Signer returns ErrPKCS11Disabled.
+func NewPKCS11Signer(module, tokenLabel, keyLabel, pin string) (*PKCS11Signer, error) {
+	return nil, ErrPKCS11Disabled
+}
+
+// PubKey returns an empty key.
+func (ps *PKCS11Signer) PubKey() crypto.PubKey {
+	return crypto.PubKey{}
+}
+
+// Sign returns ErrPKCS11Disabled.
+func (ps *PKCS11Signer) Sign(msg []byte) (crypto.Signature, error) {
+	return crypto.Signature{}, ErrPKCS11Disabled
+}
+
+// Close does nothing.
+func (ps *PKCS11Signer) Close() error {
+	return nil
+}
@@ -0,0 +1,172 @@
+// +build pkcs11
+
+package privval
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/miekg/pkcs11"
+	crypto "github.com/tendermint/go-crypto"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// PKCS11Signer implements types.Signer with an ed25519 key held by an HSM.
+// The private key never leaves the HSM: use it as the Signer of a PrivValidatorFS,
+// see types.LoadOrGenPrivValidatorFSWithSigner, which prevents double signing.
+type PKCS11Signer struct {
+	mtx     sync.Mutex // a session signs one message at a time
+	ctx     *pkcs11.Ctx
+	session pkcs11.SessionHandle
+	privKey pkcs11.ObjectHandle
+	pubKey  crypto.PubKey
+
+	hasSession bool
+}
+
+var _ types.Signer = (*PKCS11Signer)(nil)
+
+// NewPKCS11Signer loads the PKCS#11 module, logs in to the token with the pin,
+// and returns a PKCS11Signer signing with the key of the keyLabel on it.
+// The private and public key objects must both have the keyLabel.
+func NewPKCS11Signer(module, tokenLabel, keyLabel, pin string) (*PKCS11Signer, error) {
+	ctx := pkcs11.New(module)
+	if ctx == nil {
+		return nil, fmt.Errorf("Error loading the PKCS#11 module %s", module)
+	}
+	if err := ctx.Initialize(); err != nil {
+		ctx.Destroy()
+		return nil, fmt.Errorf("Error initializing the PKCS#11 module %s: %v", module, err)
+	}
+
+	ps := &PKCS11Signer{ctx: ctx}
+	if err := ps.open(tokenLabel, keyLabel, pin); err != nil {
+		ps.Close() // nolint: errcheck
+		return nil, err
+	}
+	return ps, nil
+}
+
+func (ps *PKCS11Signer) open(tokenLabel, keyLabel, pin string) error {
+	slot, err := ps.findSlot(tokenLabel)
+	if err != nil {
+		return err
+	}
+	ps.session, err = ps.ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION)
+	if err != nil {
+		return fmt.Errorf("Error opening a session on the token %q: %v", tokenLabel, err)
+	}
+	ps.hasSession = true
+	if err := ps.ctx.Login(ps.session, pkcs11.CKU_USER, pin); err != nil {
+		if perr, ok := err.(pkcs11.Error); !ok || perr != pkcs11.CKR_USER_ALREADY_LOGGED_IN {
+			return fmt.Errorf("Error logging in to the token %q: %v", tokenLabel, err)
+		}
+	}
+
+	if ps.privKey, err = ps.findKey(pkcs11.CKO_PRIVATE_KEY, keyLabel); err != nil {
+		return err
+	}
+	pubKeyObj, err := ps.findKey(pkcs11.CKO_PUBLIC_KEY, keyLabel)
+	if err != nil {
+		return err
+	}
+	attrs, err := ps.ctx.GetAttributeValue(ps.session, pubKeyObj, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_EC_POINT, nil),
+	})
+	if err != nil {
+		return fmt.Errorf("Error reading the public key %q: %v", keyLabel, err)
+	}
+	ps.pubKey, err = ed25519PubKeyFromPoint(attrs[0].Value)
+	return err
+}
+
+// findSlot returns the slot of the token with the label.
+func (ps *PKCS11Signer) findSlot(tokenLabel string) (uint, error) {
+	slots, err := ps.ctx.GetSlotList(true)
+	if err != nil {
+		return 0, err
+	}
+	for _, slot := range slots {
+		info, err := ps.ctx.GetTokenInfo(slot)
+		if err != nil {
+			return 0, err
+		}
+		// the labels are padded with spaces
+		if strings.TrimRight(info.Label, " \x00") == tokenLabel {
+			return slot, nil
+		}
+	}
+	return 0, fmt.Errorf("No token with the label %q", tokenLabel)
+}
+
+// findKey returns the ed25519 key object of the class with the label,
+// which must be unique.
+func (ps *PKCS11Signer) findKey(class uint, keyLabel string) (pkcs11.ObjectHandle, error) {
+	if err := ps.ctx.FindObjectsInit(ps.session, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+		pkcs11.NewAttribute(pkcs11.CKA_KEY_TYPE, ckkECEdwards),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, keyLabel),
+	}); err != nil {
+		return 0, err
+	}
+	objs, _, err := ps.ctx.FindObjects(ps.session, 2)
+	if finalErr := ps.ctx.FindObjectsFinal(ps.session); err == nil {
+		err = finalErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(objs) != 1 {
+		return 0, fmt.Errorf("Found %d ed25519 keys with the label %q, expected 1", len(objs), keyLabel)
+	}
+	return objs[0], nil
+}
+
+// PubKey returns the public key of the validator.
+func (ps *PKCS11Signer) PubKey() crypto.PubKey {
+	return ps.pubKey
+}
+
+// Sign implements types.Signer. The signature is checked with the public key,
+// not to send a bad signature if the HSM misbehaves.
+func (ps *PKCS11Signer) Sign(msg []byte) (crypto.Signature, error) {
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
+
+	mechanism := []*pkcs11.Mechanism{pkcs11.NewMechanism(ckmEdDSA, nil)}
+	if err := ps.ctx.SignInit(ps.session, mechanism, ps.privKey); err != nil {
+		return crypto.Signature{}, err
+	}
+	sigBytes, err := ps.ctx.Sign(ps.session, msg)
+	if err != nil {
+		return crypto.Signature{}, err
+	}
+	if len(sigBytes) != 64 {
+		return crypto.Signature{}, fmt.Errorf("Invalid ed25519 signature length %d, expected 64", len(sigBytes))
+	}
+	var sig crypto.SignatureEd25519
+	copy(sig[:], sigBytes)
+	if !ps.pubKey.VerifyBytes(msg, sig.Wrap()) {
+		return crypto.Signature{}, fmt.Errorf("The HSM signature doesn't verify with the public key")
+	}
+	return sig.Wrap(), nil
+}
+
+// Close logs out of the token and unloads the PKCS#11 module.
+func (ps *PKCS11Signer) Close() error {
+	ps.mtx.Lock()
+	defer ps.mtx.Unlock()
+	var err error
+	if ps.hasSession {
+		ps.ctx.Logout(ps.session) // nolint: errcheck
+		err = ps.ctx.CloseSession(ps.session)
+		ps.hasSession = false
+	}
+	if finalizeErr := ps.ctx.Finalize(); err == nil {
+		err = finalizeErr
+	}
+	ps.ctx.Destroy()
+	return err
+}
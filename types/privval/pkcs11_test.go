@@ -0,0 +1,34 @@
+package privval
+
+import (
+	"encoding/asn1"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	crypto "github.com/tendermint/go-crypto"
+)
+
+func TestED25519PubKeyFromPoint(t *testing.T) {
+	pubKey := crypto.GenPrivKeyEd25519().PubKey().Unwrap().(crypto.PubKeyEd25519)
+
+	// the DER encoded point
+	der, err := asn1.Marshal(pubKey[:])
+	require.Nil(t, err)
+	key, err := ed25519PubKeyFromPoint(der)
+	require.Nil(t, err)
+	assert.Equal(t, pubKey.Wrap(), key)
+
+	// the raw point
+	key, err = ed25519PubKeyFromPoint(pubKey[:])
+	require.Nil(t, err)
+	assert.Equal(t, pubKey.Wrap(), key)
+
+	// a point of another curve
+	der, err = asn1.Marshal(make([]byte, 65))
+	require.Nil(t, err)
+	_, err = ed25519PubKeyFromPoint(der)
+	assert.NotNil(t, err)
+	_, err = ed25519PubKeyFromPoint([]byte("garbage"))
+	assert.NotNil(t, err)
+}
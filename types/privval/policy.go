@@ -0,0 +1,185 @@
+package privval
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	cmn "github.com/tendermint/tmlibs/common"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+// The types of the messages a validator signs, as named in a SigningPolicy.
+const (
+	MsgTypePrevote   = "prevote"
+	MsgTypePrecommit = "precommit"
+	MsgTypeProposal  = "proposal"
+	MsgTypeHeartbeat = "heartbeat"
+)
+
+// the steps of the messages, increasing within a round, as in types.PrivValidatorFS
+const (
+	stepPropose   = 1
+	stepPrevote   = 2
+	stepPrecommit = 3
+)
+
+// SigningPolicy is what a signer, eg. an HSM, accepts to sign for a validator.
+// Its zero value accepts everything. It's written as JSON, eg.
+//
+//	{"chain_ids": ["test-chain"], "max_round": 20, "max_height_jump": 1000}
+type SigningPolicy struct {
+	// ChainIDs are the chains signed for, all if empty.
+	ChainIDs []string `json:"chain_ids"`
+	// MsgTypes are the types of the messages signed, all if empty.
+	MsgTypes []string `json:"msg_types"`
+	// MinHeight and MaxHeight bound the heights signed at, if not 0.
+	MinHeight int64 `json:"min_height"`
+	MaxHeight int64 `json:"max_height"`
+	// MaxRound is the highest round signed at, if not 0.
+	MaxRound int `json:"max_round"`
+	// MaxHeightJump is the max increase of the height since the previous
+	// message signed, if not 0, not to sign far in the future.
+	MaxHeightJump int64 `json:"max_height_jump"`
+}
+
+// LoadSigningPolicy reads the JSON SigningPolicy of the file.
+func LoadSigningPolicy(file string) (*SigningPolicy, error) {
+	bz, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	policy := new(SigningPolicy)
+	if err := json.Unmarshal(bz, policy); err != nil {
+		return nil, fmt.Errorf("Error reading the signing policy %s: %v", file, err)
+	}
+	if err := policy.ValidateBasic(); err != nil {
+		return nil, fmt.Errorf("Invalid signing policy %s: %v", file, err)
+	}
+	return policy, nil
+}
+
+// ValidateBasic returns an error if the policy has an unknown message type,
+// or negative limits.
+func (p *SigningPolicy) ValidateBasic() error {
+	for _, msgType := range p.MsgTypes {
+		switch msgType {
+		case MsgTypePrevote, MsgTypePrecommit, MsgTypeProposal, MsgTypeHeartbeat:
+		default:
+			return fmt.Errorf("Unknown message type %q", msgType)
+		}
+	}
+	if p.MinHeight < 0 || p.MaxHeight < 0 || p.MaxRound < 0 || p.MaxHeightJump < 0 {
+		return fmt.Errorf("The limits can't be negative")
+	}
+	return nil
+}
+
+// Check returns an error if the policy refuses to sign a message of the type,
+// height and round for the chain, lastHeight being the height of the previous
+// message signed, or 0.
+func (p *SigningPolicy) Check(chainID, msgType string, height int64, round int, lastHeight int64) error {
+	if len(p.ChainIDs) > 0 && !cmn.StringInSlice(chainID, p.ChainIDs) {
+		return fmt.Errorf("The chain %s is not allowed", chainID)
+	}
+	if len(p.MsgTypes) > 0 && !cmn.StringInSlice(msgType, p.MsgTypes) {
+		return fmt.Errorf("The %s messages are not allowed", msgType)
+	}
+	if height < p.MinHeight {
+		return fmt.Errorf("The height %d is below the min height %d", height, p.MinHeight)
+	}
+	if p.MaxHeight > 0 && height > p.MaxHeight {
+		return fmt.Errorf("The height %d is above the max height %d", height, p.MaxHeight)
+	}
+	if p.MaxRound > 0 && round > p.MaxRound {
+		return fmt.Errorf("The round %d is above the max round %d", round, p.MaxRound)
+	}
+	if p.MaxHeightJump > 0 && lastHeight > 0 && height > lastHeight+p.MaxHeightJump {
+		return fmt.Errorf("The height %d jumps more than %d from the last height %d", height, p.MaxHeightJump, lastHeight)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// PolicySimulator runs the messages a validator is asked to sign through a
+// SigningPolicy in dry-run mode, without signing them, so the policy of a
+// signer can be validated on the messages of a node before the node is pointed
+// at it. Like a signer, it also refuses the height, round and step regressions,
+// and the conflicting messages, of the votes and proposals.
+type PolicySimulator struct {
+	policy  *SigningPolicy
+	chainID string
+
+	lastHeight    int64
+	lastRound     int
+	lastStep      int8
+	lastSignBytes []byte
+}
+
+// NewPolicySimulator returns a PolicySimulator of the messages of the chain.
+func NewPolicySimulator(policy *SigningPolicy, chainID string) *PolicySimulator {
+	return &PolicySimulator{policy: policy, chainID: chainID}
+}
+
+// Check returns the sign bytes of the vote, proposal or heartbeat, as the signer
+// would see them, and an error if it would refuse to sign them. The messages
+// must be checked in the order they are signed.
+func (ps *PolicySimulator) Check(msg types.Signable) ([]byte, error) {
+	signBytes := types.SignBytes(ps.chainID, msg)
+
+	msgType, height, round, step, err := describeMsg(msg)
+	if err != nil {
+		return signBytes, err
+	}
+	if err := ps.policy.Check(ps.chainID, msgType, height, round, ps.lastHeight); err != nil {
+		return signBytes, err
+	}
+	if msgType == MsgTypeHeartbeat {
+		return signBytes, nil
+	}
+
+	if ps.lastHeight > height ||
+		(ps.lastHeight == height && ps.lastRound > round) ||
+		(ps.lastHeight == height && ps.lastRound == round && ps.lastStep > step) {
+		return signBytes, fmt.Errorf("Regression from %d/%d/%d", ps.lastHeight, ps.lastRound, ps.lastStep)
+	}
+	if ps.lastHeight == height && ps.lastRound == round && ps.lastStep == step {
+		if !bytes.Equal(ps.lastSignBytes, signBytes) {
+			return signBytes, fmt.Errorf("Conflicts with the %s signed at %d/%d", msgType, height, round)
+		}
+		return signBytes, nil
+	}
+	ps.lastHeight, ps.lastRound, ps.lastStep, ps.lastSignBytes = height, round, step, signBytes
+	return signBytes, nil
+}
+
+// describeMsg returns the type, height, round and step of a message to sign.
+func describeMsg(msg types.Signable) (msgType string, height int64, round int, step int8, err error) {
+	switch m := msg.(type) {
+	case *types.Vote:
+		switch m.Type {
+		case types.VoteTypePrevote:
+			return MsgTypePrevote, m.Height, m.Round, stepPrevote, nil
+		case types.VoteTypePrecommit:
+			return MsgTypePrecommit, m.Height, m.Round, stepPrecommit, nil
+		}
+		return "", 0, 0, 0, fmt.Errorf("Unknown vote type %X", m.Type)
+	case *types.Proposal:
+		return MsgTypeProposal, m.Height, m.Round, stepPropose, nil
+	case *types.Heartbeat:
+		return MsgTypeHeartbeat, m.Height, m.Round, 0, nil
+	}
+	return "", 0, 0, 0, fmt.Errorf("Unknown message %T", msg)
+}
+
+// DescribeMsg returns a short description of a message to sign, eg. "prevote 5/0".
+func DescribeMsg(msg types.Signable) string {
+	msgType, height, round, _, err := describeMsg(msg)
+	if err != nil {
+		return fmt.Sprintf("%T", msg)
+	}
+	return fmt.Sprintf("%s %d/%d", msgType, height, round)
+}
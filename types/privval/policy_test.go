@@ -0,0 +1,92 @@
+package privval
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+func TestLoadSigningPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "privval_policy")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	file := filepath.Join(dir, "policy.json")
+	require.Nil(t, ioutil.WriteFile(file, []byte(`{"chain_ids": ["test-chain"], "msg_types": ["prevote", "precommit"], "max_round": 3}`), 0600))
+	policy, err := LoadSigningPolicy(file)
+	require.Nil(t, err)
+	assert.Equal(t, &SigningPolicy{
+		ChainIDs: []string{"test-chain"},
+		MsgTypes: []string{MsgTypePrevote, MsgTypePrecommit},
+		MaxRound: 3,
+	}, policy)
+
+	require.Nil(t, ioutil.WriteFile(file, []byte(`{"msg_types": ["vote"]}`), 0600))
+	_, err = LoadSigningPolicy(file)
+	assert.NotNil(t, err)
+}
+
+func TestSigningPolicyCheck(t *testing.T) {
+	policy := &SigningPolicy{
+		ChainIDs:      []string{chainID},
+		MsgTypes:      []string{MsgTypePrevote, MsgTypePrecommit},
+		MinHeight:     2,
+		MaxHeight:     100,
+		MaxRound:      3,
+		MaxHeightJump: 10,
+	}
+	assert.Nil(t, policy.Check(chainID, MsgTypePrevote, 5, 0, 4))
+	assert.NotNil(t, policy.Check("other-chain", MsgTypePrevote, 5, 0, 4))
+	assert.NotNil(t, policy.Check(chainID, MsgTypeProposal, 5, 0, 4))
+	assert.NotNil(t, policy.Check(chainID, MsgTypePrevote, 1, 0, 0))
+	assert.NotNil(t, policy.Check(chainID, MsgTypePrevote, 101, 0, 100))
+	assert.NotNil(t, policy.Check(chainID, MsgTypePrevote, 5, 4, 4))
+	assert.NotNil(t, policy.Check(chainID, MsgTypePrevote, 15, 0, 4))
+	// no jump from no previous message
+	assert.Nil(t, policy.Check(chainID, MsgTypePrevote, 50, 0, 0))
+
+	// the zero policy accepts everything
+	assert.Nil(t, new(SigningPolicy).Check("other-chain", MsgTypeHeartbeat, 1000, 1000, 1))
+}
+
+func TestPolicySimulator(t *testing.T) {
+	ps := NewPolicySimulator(&SigningPolicy{MaxRound: 1}, chainID)
+
+	blockID := types.BlockID{[]byte{1, 2, 3}, types.PartSetHeader{}}
+	proposal := &types.Proposal{Height: 5, Round: 0, BlockPartsHeader: types.PartSetHeader{5, []byte{1, 2, 3}}, POLRound: -1}
+	prevote := &types.Vote{Height: 5, Round: 0, Type: types.VoteTypePrevote, BlockID: blockID}
+	precommit := &types.Vote{Height: 5, Round: 0, Type: types.VoteTypePrecommit, BlockID: blockID}
+
+	signBytes, err := ps.Check(proposal)
+	assert.Nil(t, err)
+	assert.Equal(t, types.SignBytes(chainID, proposal), signBytes)
+	_, err = ps.Check(prevote)
+	assert.Nil(t, err)
+	_, err = ps.Check(precommit)
+	assert.Nil(t, err)
+
+	// the same vote again, eg. on WAL replay, but not a conflicting one
+	_, err = ps.Check(precommit)
+	assert.Nil(t, err)
+	conflicting := *precommit
+	conflicting.BlockID = types.BlockID{[]byte{3, 2, 1}, types.PartSetHeader{}}
+	_, err = ps.Check(&conflicting)
+	assert.NotNil(t, err)
+
+	// regressions
+	_, err = ps.Check(prevote)
+	assert.NotNil(t, err)
+
+	// the policy
+	late := &types.Vote{Height: 6, Round: 2, Type: types.VoteTypePrevote, BlockID: blockID}
+	_, err = ps.Check(late)
+	assert.NotNil(t, err)
+
+	assert.Equal(t, "precommit 5/0", DescribeMsg(precommit))
+}
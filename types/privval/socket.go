@@ -0,0 +1,408 @@
+// Package privval implements a PrivValidator requesting the signatures from
+// a remote signer over a socket, so the validator's key can live on a separate,
+// hardened machine, or behind an HSM front-end.
+//
+// The node listens on the priv_validator_laddr, and the signer dials it: the
+// signer's machine needs no open port. The connection is a p2p SecretConnection,
+// encrypted and authenticated with the ed25519 keys of both ends.
+//
+// It also imports and exports the validator keys in standard formats (see ExportPrivKey),
+// signs with the keys held by an HSM (see PKCS11Signer), and with M of N remote
+// co-signers as a multisig validator (see PrivValidatorMultisig).
+package privval
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	crypto "github.com/tendermint/go-crypto"
+	wire "github.com/tendermint/go-wire"
+	data "github.com/tendermint/go-wire/data"
+	cmn "github.com/tendermint/tmlibs/common"
+
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/types"
+)
+
+const (
+	defaultConnWaitSeconds = 60
+	defaultRequestSeconds  = 3
+
+	maxPrivValMsgSize = 1024 * 1024 // 1MB
+)
+
+var (
+	// ErrNoSigner is returned when no remote signer is connected.
+	ErrNoSigner = errors.New("No remote signer connected")
+	// ErrUnexpectedResponse is returned when the signer answers with another message than expected.
+	ErrUnexpectedResponse = errors.New("Unexpected response from the remote signer")
+	// ErrInvalidSignature is returned when the signature of the signer doesn't verify.
+	ErrInvalidSignature = errors.New("Invalid signature from the remote signer")
+	// ErrNoAuthorizedKeys is returned when starting a connection to a remote
+	// signer, or to a node, with no key to authenticate the other end with.
+	ErrNoAuthorizedKeys = errors.New("No authorized key for the other end of the remote signer connection")
+)
+
+// PrivValidatorSocketClient implements types.PrivValidator by requesting the
+// signatures from a remote signer, eg. a PrivValidatorSocketServer. A signer
+// connecting replaces the previous one, so a restarted signer is picked up.
+type PrivValidatorSocketClient struct {
+	cmn.BaseService
+
+	addr     string
+	privKey  crypto.PrivKeyEd25519
+	listener net.Listener
+
+	connWaitTimeout time.Duration
+	requestTimeout  time.Duration
+	authorizedKeys  []crypto.PubKeyEd25519
+
+	mtx    sync.Mutex // one request at a time
+	conn   net.Conn
+	connCh chan struct{} // closed when the first signer connects
+
+	pubKey crypto.PubKey
+}
+
+var _ types.PrivValidator = (*PrivValidatorSocketClient)(nil)
+
+// NewPrivValidatorSocketClient returns a PrivValidatorSocketClient listening
+// for the remote signer on the addr, with the privKey for the connection.
+func NewPrivValidatorSocketClient(addr string, privKey crypto.PrivKeyEd25519) *PrivValidatorSocketClient {
+	sc := &PrivValidatorSocketClient{
+		addr:            addr,
+		privKey:         privKey,
+		connWaitTimeout: defaultConnWaitSeconds * time.Second,
+		requestTimeout:  defaultRequestSeconds * time.Second,
+		connCh:          make(chan struct{}),
+	}
+	sc.BaseService = *cmn.NewBaseService(nil, "PrivValidatorSocketClient", sc)
+	return sc
+}
+
+// SetConnWaitTimeout sets how long OnStart waits for the signer to connect.
+// NOTE: not thread safe
+func (sc *PrivValidatorSocketClient) SetConnWaitTimeout(timeout time.Duration) {
+	sc.connWaitTimeout = timeout
+}
+
+// SetRequestTimeout sets the timeout of each signing request.
+// NOTE: not thread safe
+func (sc *PrivValidatorSocketClient) SetRequestTimeout(timeout time.Duration) {
+	sc.requestTimeout = timeout
+}
+
+// SetAuthorizedKeys restricts the signers to the ones authenticating with one of the keys.
+// It's required: OnStart fails if none is set.
+// NOTE: not thread safe
+func (sc *PrivValidatorSocketClient) SetAuthorizedKeys(keys []crypto.PubKeyEd25519) {
+	sc.authorizedKeys = keys
+}
+
+// OnStart implements cmn.Service. It waits for the signer to connect,
+// and fetches the public key of the validator from it.
+func (sc *PrivValidatorSocketClient) OnStart() error {
+	if len(sc.authorizedKeys) == 0 {
+		return ErrNoAuthorizedKeys
+	}
+	protocol, address := cmn.ProtocolAndAddress(sc.addr)
+	listener, err := net.Listen(protocol, address)
+	if err != nil {
+		return err
+	}
+	sc.listener = listener
+	sc.Logger.Info("Waiting for the remote signer", "addr", sc.addr)
+	go sc.acceptRoutine()
+
+	select {
+	case <-sc.connCh:
+	case <-time.After(sc.connWaitTimeout):
+		listener.Close() // nolint: errcheck
+		return fmt.Errorf("No remote signer connected to %s in %v", sc.addr, sc.connWaitTimeout)
+	}
+
+	res, err := sc.request(&PubKeyMsg{})
+	if err != nil {
+		listener.Close() // nolint: errcheck
+		return err
+	}
+	msg, ok := res.(*PubKeyMsg)
+	if !ok {
+		listener.Close() // nolint: errcheck
+		return ErrUnexpectedResponse
+	}
+	sc.pubKey = msg.PubKey
+	sc.Logger.Info("Remote signer connected", "address", fmt.Sprintf("%X", sc.pubKey.Address()))
+	return nil
+}
+
+// OnStop implements cmn.Service.
+func (sc *PrivValidatorSocketClient) OnStop() {
+	sc.listener.Close() // nolint: errcheck
+
+	sc.mtx.Lock()
+	defer sc.mtx.Unlock()
+	if sc.conn != nil {
+		sc.conn.Close() // nolint: errcheck
+		sc.conn = nil
+	}
+}
+
+func (sc *PrivValidatorSocketClient) acceptRoutine() {
+	first := true
+	for {
+		conn, err := sc.listener.Accept()
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				sc.Logger.Error("Error accepting the remote signer", "err", err)
+				continue
+			}
+			// the listener is closed
+			return
+		}
+
+		// the handshake must not hang, eg. on a port scan
+		if err := conn.SetDeadline(time.Now().Add(sc.requestTimeout)); err != nil {
+			conn.Close() // nolint: errcheck
+			continue
+		}
+		sconn, err := p2p.MakeSecretConnection(conn, sc.privKey)
+		if err != nil {
+			sc.Logger.Error("Error authenticating the remote signer", "err", err)
+			conn.Close() // nolint: errcheck
+			continue
+		}
+		if !sc.isAuthorized(sconn.RemotePubKey()) {
+			sc.Logger.Error("Unauthorized remote signer", "key", sconn.RemotePubKey().KeyString())
+			sconn.Close() // nolint: errcheck
+			continue
+		}
+
+		sc.mtx.Lock()
+		if sc.conn != nil {
+			sc.conn.Close() // nolint: errcheck
+		}
+		sc.conn = sconn
+		sc.mtx.Unlock()
+
+		if first {
+			close(sc.connCh)
+			first = false
+		} else {
+			sc.Logger.Info("Remote signer reconnected")
+		}
+	}
+}
+
+func (sc *PrivValidatorSocketClient) isAuthorized(key crypto.PubKeyEd25519) bool {
+	for _, authorized := range sc.authorizedKeys {
+		if authorized == key {
+			return true
+		}
+	}
+	return false
+}
+
+// request sends the msg to the signer and returns its response.
+func (sc *PrivValidatorSocketClient) request(msg PrivValMsg) (PrivValMsg, error) {
+	sc.mtx.Lock()
+	defer sc.mtx.Unlock()
+	if sc.conn == nil {
+		return nil, ErrNoSigner
+	}
+
+	if err := sc.conn.SetDeadline(time.Now().Add(sc.requestTimeout)); err != nil {
+		return nil, err
+	}
+	res, err := writeReadMsg(sc.conn, msg)
+	if err != nil {
+		// the signer reconnects if it's still there
+		sc.conn.Close() // nolint: errcheck
+		sc.conn = nil
+		return nil, err
+	}
+	if errMsg, ok := res.(*ErrorMsg); ok {
+		return nil, errors.New(errMsg.Error)
+	}
+	return res, nil
+}
+
+// GetAddress implements types.PrivValidator.
+func (sc *PrivValidatorSocketClient) GetAddress() data.Bytes {
+	return sc.pubKey.Address()
+}
+
+// GetPubKey implements types.PrivValidator.
+func (sc *PrivValidatorSocketClient) GetPubKey() crypto.PubKey {
+	return sc.pubKey
+}
+
+// SignVote implements types.PrivValidator.
+func (sc *PrivValidatorSocketClient) SignVote(chainID string, vote *types.Vote) error {
+	res, err := sc.request(&SignVoteMsg{ChainID: chainID, Vote: vote})
+	if err != nil {
+		return err
+	}
+	msg, ok := res.(*SignVoteMsg)
+	if !ok || msg.Vote == nil {
+		return ErrUnexpectedResponse
+	}
+	// the signer may give the vote the timestamp it signed it with before,
+	// but nothing else of it may change
+	signed := vote.Copy()
+	signed.Timestamp = msg.Vote.Timestamp
+	signBytes := types.SignBytes(chainID, signed)
+	if !bytes.Equal(signBytes, types.SignBytes(chainID, msg.Vote)) {
+		return ErrUnexpectedResponse
+	}
+	if !sc.pubKey.VerifyBytes(signBytes, msg.Vote.Signature) {
+		return ErrInvalidSignature
+	}
+	vote.Timestamp = msg.Vote.Timestamp
+	vote.Signature = msg.Vote.Signature
+	return nil
+}
+
+// SignProposal implements types.PrivValidator.
+func (sc *PrivValidatorSocketClient) SignProposal(chainID string, proposal *types.Proposal) error {
+	res, err := sc.request(&SignProposalMsg{ChainID: chainID, Proposal: proposal})
+	if err != nil {
+		return err
+	}
+	msg, ok := res.(*SignProposalMsg)
+	if !ok || msg.Proposal == nil {
+		return ErrUnexpectedResponse
+	}
+	if !sc.pubKey.VerifyBytes(types.SignBytes(chainID, proposal), msg.Proposal.Signature) {
+		return ErrInvalidSignature
+	}
+	proposal.Signature = msg.Proposal.Signature
+	return nil
+}
+
+// SignHeartbeat implements types.PrivValidator.
+func (sc *PrivValidatorSocketClient) SignHeartbeat(chainID string, heartbeat *types.Heartbeat) error {
+	res, err := sc.request(&SignHeartbeatMsg{ChainID: chainID, Heartbeat: heartbeat})
+	if err != nil {
+		return err
+	}
+	msg, ok := res.(*SignHeartbeatMsg)
+	if !ok || msg.Heartbeat == nil {
+		return ErrUnexpectedResponse
+	}
+	if !sc.pubKey.VerifyBytes(types.SignBytes(chainID, heartbeat), msg.Heartbeat.Signature) {
+		return ErrInvalidSignature
+	}
+	heartbeat.Signature = msg.Heartbeat.Signature
+	return nil
+}
+
+// String returns a string representation of the PrivValidatorSocketClient.
+func (sc *PrivValidatorSocketClient) String() string {
+	return fmt.Sprintf("PrivValidatorSocketClient{%v %v}", sc.addr, sc.GetAddress())
+}
+
+//-----------------------------------------------------------------------------
+// Messages
+
+const (
+	msgTypePubKey        = byte(0x01)
+	msgTypeSignVote      = byte(0x10)
+	msgTypeSignProposal  = byte(0x11)
+	msgTypeSignHeartbeat = byte(0x12)
+	msgTypeError         = byte(0x20)
+)
+
+// PrivValMsg is a message exchanged with the remote signer.
+// The requests and their responses are the same messages, the responses
+// with the signatures filled in, or an ErrorMsg.
+type PrivValMsg interface{}
+
+var _ = wire.RegisterInterface(
+	struct{ PrivValMsg }{},
+	wire.ConcreteType{&PubKeyMsg{}, msgTypePubKey},
+	wire.ConcreteType{&SignVoteMsg{}, msgTypeSignVote},
+	wire.ConcreteType{&SignProposalMsg{}, msgTypeSignProposal},
+	wire.ConcreteType{&SignHeartbeatMsg{}, msgTypeSignHeartbeat},
+	wire.ConcreteType{&ErrorMsg{}, msgTypeError},
+)
+
+// PubKeyMsg requests the public key of the validator.
+type PubKeyMsg struct {
+	PubKey crypto.PubKey
+}
+
+// SignVoteMsg requests the signature of a vote.
+type SignVoteMsg struct {
+	ChainID string
+	Vote    *types.Vote
+}
+
+// SignProposalMsg requests the signature of a proposal.
+type SignProposalMsg struct {
+	ChainID  string
+	Proposal *types.Proposal
+}
+
+// SignHeartbeatMsg requests the signature of a heartbeat.
+type SignHeartbeatMsg struct {
+	ChainID   string
+	Heartbeat *types.Heartbeat
+}
+
+// ErrorMsg is the response of the signer when it refuses to sign,
+// eg. not to double sign.
+type ErrorMsg struct {
+	Error string
+}
+
+func writeMsg(conn net.Conn, msg PrivValMsg) error {
+	var n int
+	var err error
+	wire.WriteBinary(struct{ PrivValMsg }{msg}, conn, &n, &err)
+	return err
+}
+
+func readMsg(conn net.Conn) (PrivValMsg, error) {
+	var n int
+	var err error
+	msg := wire.ReadBinary(struct{ PrivValMsg }{}, conn, maxPrivValMsgSize, &n, &err)
+	if err != nil {
+		return nil, err
+	}
+	return msg.(struct{ PrivValMsg }).PrivValMsg, nil
+}
+
+func writeReadMsg(conn net.Conn, msg PrivValMsg) (PrivValMsg, error) {
+	if err := writeMsg(conn, msg); err != nil {
+		return nil, err
+	}
+	return readMsg(conn)
+}
+
+// ParseConnKeys parses the comma separated hex of the ed25519 public keys
+// authenticating the ends of the remote signer connections, as printed by
+// their KeyString.
+func ParseConnKeys(s string) ([]crypto.PubKeyEd25519, error) {
+	var keys []crypto.PubKeyEd25519
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		bz, err := hex.DecodeString(field)
+		if err != nil || len(bz) != len(crypto.PubKeyEd25519{}) {
+			return nil, fmt.Errorf("Invalid connection key %q, must be the hex of an ed25519 public key", field)
+		}
+		var key crypto.PubKeyEd25519
+		copy(key[:], bz)
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
@@ -0,0 +1,164 @@
+package privval
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	crypto "github.com/tendermint/go-crypto"
+	cmn "github.com/tendermint/tmlibs/common"
+
+	"github.com/tendermint/tendermint/p2p"
+	"github.com/tendermint/tendermint/types"
+)
+
+const defaultRedialSeconds = 1
+
+// PrivValidatorSocketServer is the remote signer: it dials the node's
+// PrivValidatorSocketClient and answers its requests with the PrivValidator,
+// eg. a PrivValidatorFS, which still refuses to double sign.
+// It dials again whenever the connection is lost.
+type PrivValidatorSocketServer struct {
+	cmn.BaseService
+
+	addr          string
+	privKey       crypto.PrivKeyEd25519
+	privVal       types.PrivValidator
+	redialTimeout time.Duration
+	nodeKeys      []crypto.PubKeyEd25519
+
+	mtx  sync.Mutex
+	conn net.Conn // closed by OnStop to unblock the serveRoutine
+}
+
+// NewPrivValidatorSocketServer returns a PrivValidatorSocketServer signing
+// with the privVal for the node at addr, with the privKey for the connection.
+func NewPrivValidatorSocketServer(addr string, privKey crypto.PrivKeyEd25519, privVal types.PrivValidator) *PrivValidatorSocketServer {
+	ss := &PrivValidatorSocketServer{
+		addr:          addr,
+		privKey:       privKey,
+		privVal:       privVal,
+		redialTimeout: defaultRedialSeconds * time.Second,
+	}
+	ss.BaseService = *cmn.NewBaseService(nil, "PrivValidatorSocketServer", ss)
+	return ss
+}
+
+// SetNodeKeys restricts the nodes to sign for to the ones authenticating
+// with one of the keys. It's required: OnStart fails if none is set.
+// NOTE: not thread safe
+func (ss *PrivValidatorSocketServer) SetNodeKeys(keys []crypto.PubKeyEd25519) {
+	ss.nodeKeys = keys
+}
+
+// OnStart implements cmn.Service.
+func (ss *PrivValidatorSocketServer) OnStart() error {
+	if len(ss.nodeKeys) == 0 {
+		return ErrNoAuthorizedKeys
+	}
+	go ss.serveRoutine()
+	return nil
+}
+
+// OnStop implements cmn.Service.
+func (ss *PrivValidatorSocketServer) OnStop() {
+	ss.mtx.Lock()
+	defer ss.mtx.Unlock()
+	if ss.conn != nil {
+		ss.conn.Close() // nolint: errcheck
+	}
+}
+
+func (ss *PrivValidatorSocketServer) serveRoutine() {
+	for {
+		conn, err := ss.connect()
+		if err != nil {
+			ss.Logger.Error("Error connecting to the node", "addr", ss.addr, "err", err)
+			select {
+			case <-time.After(ss.redialTimeout):
+				continue
+			case <-ss.Quit:
+				return
+			}
+		}
+		ss.mtx.Lock()
+		ss.conn = conn
+		ss.mtx.Unlock()
+		ss.Logger.Info("Connected to the node", "addr", ss.addr)
+
+		err = ss.serve(conn)
+		conn.Close() // nolint: errcheck
+		if !ss.IsRunning() {
+			return
+		}
+		if err != io.EOF {
+			ss.Logger.Error("Connection to the node lost", "err", err)
+		}
+	}
+}
+
+func (ss *PrivValidatorSocketServer) connect() (net.Conn, error) {
+	protocol, address := cmn.ProtocolAndAddress(ss.addr)
+	conn, err := net.DialTimeout(protocol, address, ss.redialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	sconn, err := p2p.MakeSecretConnection(conn, ss.privKey)
+	if err != nil {
+		conn.Close() // nolint: errcheck
+		return nil, err
+	}
+	if !ss.isNodeKey(sconn.RemotePubKey()) {
+		sconn.Close() // nolint: errcheck
+		return nil, fmt.Errorf("Unauthorized node key %v", sconn.RemotePubKey().KeyString())
+	}
+	return sconn, nil
+}
+
+func (ss *PrivValidatorSocketServer) isNodeKey(key crypto.PubKeyEd25519) bool {
+	for _, nodeKey := range ss.nodeKeys {
+		if nodeKey == key {
+			return true
+		}
+	}
+	return false
+}
+
+// serve answers the requests of the node until the connection fails.
+func (ss *PrivValidatorSocketServer) serve(conn net.Conn) error {
+	for {
+		req, err := readMsg(conn)
+		if err != nil {
+			return err
+		}
+		if err := writeMsg(conn, ss.handleRequest(req)); err != nil {
+			return err
+		}
+	}
+}
+
+func (ss *PrivValidatorSocketServer) handleRequest(req PrivValMsg) PrivValMsg {
+	var err error
+	switch req := req.(type) {
+	case *PubKeyMsg:
+		return &PubKeyMsg{ss.privVal.GetPubKey()}
+	case *SignVoteMsg:
+		if err = ss.privVal.SignVote(req.ChainID, req.Vote); err == nil {
+			return req
+		}
+	case *SignProposalMsg:
+		if err = ss.privVal.SignProposal(req.ChainID, req.Proposal); err == nil {
+			return req
+		}
+	case *SignHeartbeatMsg:
+		if err = ss.privVal.SignHeartbeat(req.ChainID, req.Heartbeat); err == nil {
+			return req
+		}
+	default:
+		err = fmt.Errorf("Unknown request %T", req)
+	}
+	ss.Logger.Error("Refused to sign", "req", req, "err", err)
+	return &ErrorMsg{err.Error()}
+}
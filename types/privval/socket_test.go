@@ -0,0 +1,175 @@
+package privval
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	crypto "github.com/tendermint/go-crypto"
+	"github.com/tendermint/tmlibs/log"
+
+	"github.com/tendermint/tendermint/types"
+)
+
+const chainID = "privval_test"
+
+func connPubKey(privKey crypto.PrivKeyEd25519) crypto.PubKeyEd25519 {
+	return privKey.PubKey().Unwrap().(crypto.PubKeyEd25519)
+}
+
+// startSocketPair returns a client signing with a new PrivValidatorFS through a server.
+func startSocketPair(t *testing.T, dir string) (*PrivValidatorSocketClient, *PrivValidatorSocketServer, *types.PrivValidatorFS) {
+	addr := "unix://" + filepath.Join(dir, "privval.sock")
+	privVal := types.GenPrivValidatorFS(filepath.Join(dir, "priv_validator.json"))
+
+	clientKey, serverKey := crypto.GenPrivKeyEd25519(), crypto.GenPrivKeyEd25519()
+	client := NewPrivValidatorSocketClient(addr, clientKey)
+	client.SetLogger(log.TestingLogger())
+	client.SetAuthorizedKeys([]crypto.PubKeyEd25519{connPubKey(serverKey)})
+	server := NewPrivValidatorSocketServer(addr, serverKey, privVal)
+	server.SetLogger(log.TestingLogger())
+	server.SetNodeKeys([]crypto.PubKeyEd25519{connPubKey(clientKey)})
+
+	errCh := make(chan error)
+	go func() { errCh <- client.Start() }()
+	require.Nil(t, server.Start())
+	require.Nil(t, <-errCh)
+	return client, server, privVal
+}
+
+func TestSocketSignVote(t *testing.T) {
+	dir, err := ioutil.TempDir("", "privval")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	client, server, privVal := startSocketPair(t, dir)
+	defer server.Stop()
+	defer client.Stop()
+
+	assert.Equal(t, privVal.GetPubKey(), client.GetPubKey())
+	assert.Equal(t, privVal.GetAddress(), client.GetAddress())
+
+	blockID := types.BlockID{[]byte{1, 2, 3}, types.PartSetHeader{}}
+	vote := &types.Vote{
+		ValidatorAddress: client.GetAddress(),
+		Height:           1,
+		Type:             types.VoteTypePrevote,
+		BlockID:          blockID,
+	}
+	require.Nil(t, client.SignVote(chainID, vote))
+	assert.True(t, client.GetPubKey().VerifyBytes(types.SignBytes(chainID, vote), vote.Signature))
+
+	proposal := &types.Proposal{Height: 1, Round: 1, BlockPartsHeader: types.PartSetHeader{5, []byte{1, 2, 3}}}
+	require.Nil(t, client.SignProposal(chainID, proposal))
+	assert.True(t, client.GetPubKey().VerifyBytes(types.SignBytes(chainID, proposal), proposal.Signature))
+
+	// the remote PrivValidatorFS still refuses to double sign
+	conflicting := &types.Vote{
+		ValidatorAddress: client.GetAddress(),
+		Height:           1,
+		Type:             types.VoteTypePrevote,
+		BlockID:          types.BlockID{[]byte{3, 2, 1}, types.PartSetHeader{}},
+	}
+	assert.NotNil(t, client.SignVote(chainID, conflicting))
+}
+
+// tamperingPrivValidator signs the votes, then changes them.
+type tamperingPrivValidator struct {
+	*types.PrivValidatorFS
+}
+
+func (pv tamperingPrivValidator) SignVote(chainID string, vote *types.Vote) error {
+	if err := pv.PrivValidatorFS.SignVote(chainID, vote); err != nil {
+		return err
+	}
+	vote.BlockID = types.BlockID{}
+	return nil
+}
+
+func TestSocketSignVoteTampered(t *testing.T) {
+	dir, err := ioutil.TempDir("", "privval")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	client, server, privVal := startSocketPair(t, dir)
+	defer client.Stop()
+	server.Stop()
+
+	// the signer signs another vote than requested
+	server = NewPrivValidatorSocketServer(server.addr, server.privKey, tamperingPrivValidator{privVal})
+	server.SetLogger(log.TestingLogger())
+	server.SetNodeKeys([]crypto.PubKeyEd25519{connPubKey(client.privKey)})
+	require.Nil(t, server.Start())
+	defer server.Stop()
+
+	blockID := types.BlockID{[]byte{1, 2, 3}, types.PartSetHeader{}}
+	vote := &types.Vote{
+		ValidatorAddress: client.GetAddress(),
+		Height:           1,
+		Type:             types.VoteTypePrevote,
+		BlockID:          blockID,
+	}
+	// once it's connected in place of the first one
+	for i := 0; i < 50; i++ {
+		if err = client.SignVote(chainID, vote); err == nil || err == ErrUnexpectedResponse {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	assert.Equal(t, ErrUnexpectedResponse, err)
+	assert.Equal(t, blockID, vote.BlockID)
+	assert.Empty(t, vote.Signature)
+}
+
+func TestSocketUnauthorizedSigner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "privval")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	addr := "unix://" + filepath.Join(dir, "privval.sock")
+	clientKey := crypto.GenPrivKeyEd25519()
+	client := NewPrivValidatorSocketClient(addr, clientKey)
+	client.SetLogger(log.TestingLogger())
+	client.SetAuthorizedKeys([]crypto.PubKeyEd25519{connPubKey(crypto.GenPrivKeyEd25519())})
+	client.SetConnWaitTimeout(2 * time.Second)
+
+	privVal := types.GenPrivValidatorFS(filepath.Join(dir, "priv_validator.json"))
+	server := NewPrivValidatorSocketServer(addr, crypto.GenPrivKeyEd25519(), privVal)
+	server.SetLogger(log.TestingLogger())
+	server.SetNodeKeys([]crypto.PubKeyEd25519{connPubKey(clientKey)})
+
+	errCh := make(chan error)
+	go func() { errCh <- client.Start() }()
+	require.Nil(t, server.Start())
+	defer server.Stop()
+	assert.NotNil(t, <-errCh)
+}
+
+func TestSocketNoAuthorizedKeys(t *testing.T) {
+	privVal := types.GenPrivValidatorFS("")
+	client := NewPrivValidatorSocketClient("unix:///nonexistent.sock", crypto.GenPrivKeyEd25519())
+	assert.Equal(t, ErrNoAuthorizedKeys, client.Start())
+	server := NewPrivValidatorSocketServer("unix:///nonexistent.sock", crypto.GenPrivKeyEd25519(), privVal)
+	assert.Equal(t, ErrNoAuthorizedKeys, server.Start())
+}
+
+func TestParseConnKeys(t *testing.T) {
+	key1, key2 := connPubKey(crypto.GenPrivKeyEd25519()), connPubKey(crypto.GenPrivKeyEd25519())
+	keys, err := ParseConnKeys(key1.KeyString() + ", " + key2.KeyString() + ",")
+	require.Nil(t, err)
+	assert.Equal(t, []crypto.PubKeyEd25519{key1, key2}, keys)
+
+	keys, err = ParseConnKeys("")
+	require.Nil(t, err)
+	assert.Empty(t, keys)
+
+	for _, s := range []string{"zz", key1.KeyString()[2:]} {
+		_, err = ParseConnKeys(s)
+		assert.NotNil(t, err, s)
+	}
+}
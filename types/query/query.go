@@ -0,0 +1,517 @@
+// Package query implements the query language of the event subscriptions and
+// of the searches of the indexers, eg.
+//
+//	tm.event = 'Tx' AND (account.owner = 'Ivan' OR account.owner = 'Igor') AND tx.height > 5
+//
+// A condition compares a tag with an operand, which is a 'string', a number
+// (an integer, or a float with a '.'), a time (TIME 2013-05-03T14:45:00Z) or a
+// date (DATE 2013-05-03), with =, <, <=, >, >=, or CONTAINS for a substring of
+// a string; or checks the tag is set with EXISTS. The conditions are combined
+// with AND and OR, AND taking precedence, and grouped with parentheses.
+//
+// It extends the language of the tmlibs pubsub/query package with OR, EXISTS
+// and parentheses: a Query is a pubsub.Query, for the EventBus subscriptions.
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Operator is an operator of a condition.
+type Operator uint8
+
+const (
+	// "<="
+	OpLessEqual Operator = iota
+	// ">="
+	OpGreaterEqual
+	// "<"
+	OpLess
+	// ">"
+	OpGreater
+	// "="
+	OpEqual
+	// "CONTAINS"; used to check if a string contains a certain sub string.
+	OpContains
+	// "EXISTS"; used to check if a tag is set, whatever its value.
+	OpExists
+)
+
+var operators = map[string]Operator{
+	"<=":       OpLessEqual,
+	">=":       OpGreaterEqual,
+	"<":        OpLess,
+	">":        OpGreater,
+	"=":        OpEqual,
+	"CONTAINS": OpContains,
+	"EXISTS":   OpExists,
+}
+
+// String returns the operator as written in a query.
+func (op Operator) String() string {
+	for s, o := range operators {
+		if o == op {
+			return s
+		}
+	}
+	return fmt.Sprintf("Operator(%d)", uint8(op))
+}
+
+const (
+	// TimeLayout is the layout of the times of a query, after TIME.
+	TimeLayout = time.RFC3339
+	// DateLayout is the layout of the dates of a query, after DATE.
+	DateLayout = "2006-01-02"
+)
+
+// Condition represents a single condition within a query and consists of a tag
+// (e.g. "tx.gas"), an operator (e.g. "=") and an operand (e.g. "7"): a string,
+// an int64, a float64 or a time.Time, or nil for OpExists.
+type Condition struct {
+	Tag     string
+	Op      Operator
+	Operand interface{}
+}
+
+// Query holds the query string and the expression parsed from it.
+type Query struct {
+	str  string
+	expr expr
+}
+
+// New parses the given string and returns a query or error if the string is
+// invalid.
+func New(s string) (*Query, error) {
+	p := &parser{tokens: tokenize(s)}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("Unexpected %q at position %d", tok.text, tok.pos)
+	}
+	return &Query{str: strings.TrimSpace(s), expr: e}, nil
+}
+
+// MustParse turns the given string into a query or panics; for tests or others
+// cases where you know the string is valid.
+func MustParse(s string) *Query {
+	q, err := New(s)
+	if err != nil {
+		panic(fmt.Sprintf("failed to parse %s: %v", s, err))
+	}
+	return q
+}
+
+// String returns the original string.
+func (q *Query) String() string {
+	return q.str
+}
+
+// Matches returns true if the query matches the given set of tags, false otherwise.
+//
+// For example, query "name=John" matches tags = {"name": "John"}. More
+// examples could be found in query_test.go.
+func (q *Query) Matches(tags map[string]interface{}) bool {
+	return q.expr.matches(tags)
+}
+
+// Conjunctions returns the query in disjunctive normal form, for the indexers:
+// the query matches if all the conditions of one of the conjunctions match.
+// A query without OR has a single conjunction.
+func (q *Query) Conjunctions() [][]Condition {
+	return q.expr.conjunctions()
+}
+
+//------------------------------------------------------------------------------
+// Expressions
+
+type expr interface {
+	matches(tags map[string]interface{}) bool
+	conjunctions() [][]Condition
+}
+
+type andExpr []expr
+
+func (e andExpr) matches(tags map[string]interface{}) bool {
+	for _, sub := range e {
+		if !sub.matches(tags) {
+			return false
+		}
+	}
+	return true
+}
+
+// conjunctions distributes the AND over the ORs of the sub expressions.
+func (e andExpr) conjunctions() [][]Condition {
+	result := [][]Condition{{}}
+	for _, sub := range e {
+		var product [][]Condition
+		for _, left := range result {
+			for _, right := range sub.conjunctions() {
+				conj := make([]Condition, 0, len(left)+len(right))
+				conj = append(append(conj, left...), right...)
+				product = append(product, conj)
+			}
+		}
+		result = product
+	}
+	return result
+}
+
+type orExpr []expr
+
+func (e orExpr) matches(tags map[string]interface{}) bool {
+	for _, sub := range e {
+		if sub.matches(tags) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e orExpr) conjunctions() [][]Condition {
+	var result [][]Condition
+	for _, sub := range e {
+		result = append(result, sub.conjunctions()...)
+	}
+	return result
+}
+
+func (c Condition) conjunctions() [][]Condition {
+	return [][]Condition{{c}}
+}
+
+// matches returns true if the value of the tag satisfies the condition. The
+// numbers are compared whatever their type, the int64 ones exactly.
+func (c Condition) matches(tags map[string]interface{}) bool {
+	value, ok := tags[c.Tag]
+	if !ok {
+		return false
+	}
+	switch operand := c.Operand.(type) {
+	case nil:
+		return c.Op == OpExists
+	case string:
+		v, ok := value.(string)
+		if !ok {
+			return false
+		}
+		switch c.Op {
+		case OpEqual:
+			return v == operand
+		case OpContains:
+			return strings.Contains(v, operand)
+		}
+	case int64:
+		if v, ok := toInt64(value); ok {
+			return compare(c.Op, cmpInt64(v, operand))
+		}
+		if v, ok := toFloat64(value); ok {
+			return compare(c.Op, cmpFloat64(v, float64(operand)))
+		}
+	case float64:
+		if v, ok := toFloat64(value); ok {
+			return compare(c.Op, cmpFloat64(v, operand))
+		}
+	case time.Time:
+		v, ok := value.(time.Time)
+		if !ok {
+			return false
+		}
+		switch {
+		case v.Before(operand):
+			return compare(c.Op, -1)
+		case v.After(operand):
+			return compare(c.Op, 1)
+		}
+		return compare(c.Op, 0)
+	}
+	return false
+}
+
+// compare returns true if the result of a comparison, -1, 0 or 1, satisfies the operator.
+func compare(op Operator, cmp int) bool {
+	switch op {
+	case OpLessEqual:
+		return cmp <= 0
+	case OpGreaterEqual:
+		return cmp >= 0
+	case OpLess:
+		return cmp < 0
+	case OpGreater:
+		return cmp > 0
+	case OpEqual:
+		return cmp == 0
+	}
+	return false
+}
+
+func cmpInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func cmpFloat64(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func toInt64(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+func toFloat64(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case uint:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	if v, ok := toInt64(value); ok {
+		return float64(v), true
+	}
+	return 0, false
+}
+
+//------------------------------------------------------------------------------
+// Parsing
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+	tokenInvalid
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// tokenize splits the query into words (tags, keywords, numbers, times and
+// dates), 'strings', operators and parentheses.
+func tokenize(s string) []token {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokenLeftParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenRightParen, ")", i})
+			i++
+		case c == '\'':
+			end := strings.IndexByte(s[i+1:], '\'')
+			if end < 0 {
+				return append(tokens, token{tokenInvalid, s[i:], i})
+			}
+			tokens = append(tokens, token{tokenString, s[i+1 : i+1+end], i})
+			i += end + 2
+		case c == '<' || c == '>' || c == '=':
+			j := i + 1
+			if c != '=' && j < len(s) && s[j] == '=' {
+				j++
+			}
+			tokens = append(tokens, token{tokenOperator, s[i:j], i})
+			i = j
+		case c == '"' || c == '\\':
+			return append(tokens, token{tokenInvalid, s[i : i+1], i})
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\n\r()'\"\\<>=", rune(s[j])) {
+				j++
+			}
+			tokens = append(tokens, token{tokenWord, s[i:j], i})
+			i = j
+		}
+	}
+	return append(tokens, token{tokenEOF, "end of query", len(s)})
+}
+
+type parser struct {
+	tokens []token
+	next   int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) pop() token {
+	tok := p.tokens[p.next]
+	if tok.kind != tokenEOF {
+		p.next++
+	}
+	return tok
+}
+
+func (p *parser) isKeyword(keyword string) bool {
+	tok := p.peek()
+	return tok.kind == tokenWord && tok.text == keyword
+}
+
+// parseOr parses: and ("OR" and)*
+func (p *parser) parseOr() (expr, error) {
+	var or orExpr
+	for {
+		e, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, e)
+		if !p.isKeyword("OR") {
+			break
+		}
+		p.pop()
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+// parseAnd parses: term ("AND" term)*
+func (p *parser) parseAnd() (expr, error) {
+	var and andExpr
+	for {
+		e, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, e)
+		if !p.isKeyword("AND") {
+			break
+		}
+		p.pop()
+	}
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+// parseTerm parses: "(" or ")" | tag "EXISTS" | tag "CONTAINS" string | tag operator operand
+func (p *parser) parseTerm() (expr, error) {
+	tok := p.pop()
+	if tok.kind == tokenLeftParen {
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.pop(); tok.kind != tokenRightParen {
+			return nil, fmt.Errorf("Expected ')' at position %d, got %q", tok.pos, tok.text)
+		}
+		return e, nil
+	}
+	if tok.kind != tokenWord || isKeyword(tok.text) {
+		return nil, fmt.Errorf("Expected a tag at position %d, got %q", tok.pos, tok.text)
+	}
+	tag := tok.text
+
+	tok = p.pop()
+	op, ok := operators[tok.text]
+	if !ok || (tok.kind != tokenOperator && tok.kind != tokenWord) {
+		return nil, fmt.Errorf("Expected an operator after %s at position %d, got %q", tag, tok.pos, tok.text)
+	}
+	if op == OpExists {
+		return Condition{Tag: tag, Op: op}, nil
+	}
+
+	operand, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	// strings can only be equal or contain others, and only strings contain others
+	if _, isString := operand.(string); isString && op != OpEqual && op != OpContains ||
+		!isString && op == OpContains {
+		return nil, fmt.Errorf("Operator %v of %s can't be applied to %v", op, tag, operand)
+	}
+	return Condition{Tag: tag, Op: op, Operand: operand}, nil
+}
+
+// parseOperand parses: string | number | "TIME" time | "DATE" date
+func (p *parser) parseOperand() (interface{}, error) {
+	tok := p.pop()
+	switch {
+	case tok.kind == tokenString:
+		return tok.text, nil
+	case tok.kind != tokenWord:
+		return nil, fmt.Errorf("Expected an operand at position %d, got %q", tok.pos, tok.text)
+	case tok.text == "TIME" || tok.text == "DATE":
+		layout := TimeLayout
+		if tok.text == "DATE" {
+			layout = DateLayout
+		}
+		value := p.pop()
+		t, err := time.Parse(layout, value.text)
+		if err != nil || value.kind != tokenWord {
+			return nil, fmt.Errorf("Expected a %s at position %d, got %q", strings.ToLower(tok.text), value.pos, value.text)
+		}
+		return t, nil
+	case strings.Contains(tok.text, "."):
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid number %q at position %d", tok.text, tok.pos)
+		}
+		return f, nil
+	default:
+		n, err := strconv.ParseInt(tok.text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid number %q at position %d", tok.text, tok.pos)
+		}
+		return n, nil
+	}
+}
+
+func isKeyword(word string) bool {
+	switch word {
+	case "AND", "OR", "CONTAINS", "EXISTS", "TIME", "DATE":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package query_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/tendermint/tendermint/types/query"
+)
+
+func TestParse(t *testing.T) {
+	valid := []string{
+		"tm.event = 'NewBlock'",
+		"tm.event='Tx' AND tx.hash='2B8EC32BA2579B3B8606E42C06DE2F7AFA2556EF'",
+		"tx.height > 5 AND tx.height <= 10",
+		"account.balance >= -1.5",
+		"account.owner CONTAINS 'Iv'",
+		"account.owner EXISTS",
+		"tx.time >= TIME 2013-05-03T14:45:00Z",
+		"tx.date < DATE 2013-05-03",
+		"account.owner = 'Ivan' OR account.owner = 'Igor'",
+		"tm.event = 'Tx' AND (account.owner = 'Ivan' OR (tx.height > 5 AND account.owner EXISTS))",
+	}
+	for _, s := range valid {
+		q, err := query.New(s)
+		if assert.Nil(t, err, s) {
+			assert.Equal(t, s, q.String())
+		}
+	}
+
+	invalid := []string{
+		"",
+		"tm.event",
+		"tm.event =",
+		"tm.event = NewBlock",
+		"tm.event = 'NewBlock",
+		"tm.event = \"NewBlock\"",
+		"tx.height > 5 AND",
+		"tx.height > 5 OR OR tx.height < 3",
+		"(tx.height > 5",
+		"tx.height > 5)",
+		"tx.hash > 'ab12'",
+		"tx.height CONTAINS 1",
+		"tx.time > TIME 2013-05-03",
+		"tx.height EXISTS 5",
+		"AND = 'x'",
+	}
+	for _, s := range invalid {
+		_, err := query.New(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	txTime, _ := time.Parse(query.TimeLayout, "2018-05-03T14:45:00Z")
+	tags := map[string]interface{}{
+		"tm.event":        "Tx",
+		"tx.height":       int64(12),
+		"tx.time":         txTime,
+		"account.owner":   "Ivan",
+		"account.balance": 100,
+		"account.ratio":   0.25,
+	}
+
+	testCases := []struct {
+		s       string
+		matches bool
+	}{
+		{"tm.event = 'Tx'", true},
+		{"tm.event = 'NewBlock'", false},
+		{"tx.height = 12", true},
+		{"tx.height > 5 AND tx.height <= 12", true},
+		{"tx.height > 12", false},
+		{"tx.height >= 11.5", true},
+		{"account.balance = 100", true},
+		{"account.ratio < 0.5", true},
+		{"account.ratio > 0", true},
+		{"account.owner CONTAINS 'va'", true},
+		{"account.owner CONTAINS 'x'", false},
+		{"account.owner EXISTS", true},
+		{"account.name EXISTS", false},
+		{"tx.height = 'Ivan'", false},
+		{"account.owner > 5", false},
+		{"tx.time > TIME 2018-01-01T00:00:00Z", true},
+		{"tx.time < DATE 2018-05-03", false},
+		{"tx.time >= DATE 2018-05-03 AND tx.time < DATE 2018-05-04", true},
+		{"account.owner = 'Igor' OR account.owner = 'Ivan'", true},
+		{"account.owner = 'Igor' OR tx.height < 10", false},
+		{"tm.event = 'Tx' AND (account.owner = 'Igor' OR tx.height > 10)", true},
+		{"tm.event = 'NewBlock' AND account.owner = 'Igor' OR tx.height > 10", true},
+		{"tm.event = 'NewBlock' AND (account.owner = 'Igor' OR tx.height > 10)", false},
+	}
+	for _, tc := range testCases {
+		q, err := query.New(tc.s)
+		require.Nil(t, err, tc.s)
+		assert.Equal(t, tc.matches, q.Matches(tags), tc.s)
+	}
+}
+
+func TestConjunctions(t *testing.T) {
+	q := query.MustParse("tm.event = 'Tx' AND (owner = 'Ivan' OR owner EXISTS) AND (tx.height < 3 OR tx.height > 10)")
+	event := query.Condition{"tm.event", query.OpEqual, "Tx"}
+	ivan := query.Condition{"owner", query.OpEqual, "Ivan"}
+	exists := query.Condition{"owner", query.OpExists, nil}
+	low := query.Condition{"tx.height", query.OpLess, int64(3)}
+	high := query.Condition{"tx.height", query.OpGreater, int64(10)}
+	assert.Equal(t, [][]query.Condition{
+		{event, ivan, low},
+		{event, ivan, high},
+		{event, exists, low},
+		{event, exists, high},
+	}, q.Conjunctions())
+
+	q = query.MustParse("tx.height = 3")
+	assert.Equal(t, [][]query.Condition{{{"tx.height", query.OpEqual, int64(3)}}}, q.Conjunctions())
+}
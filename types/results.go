@@ -0,0 +1,322 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+
+	abci "github.com/tendermint/abci/types"
+	wire "github.com/tendermint/go-wire"
+	"github.com/tendermint/go-wire/data"
+	cmn "github.com/tendermint/tmlibs/common"
+	"github.com/tendermint/tmlibs/merkle"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// Versions of the encoding used to hash ABCIResults.
+// The version in effect is a consensus param (see ResultsParams),
+// so chains can switch encodings without breaking the hashes of existing blocks.
+const (
+	// ResultsHashNone means the results are not committed to in the header.
+	ResultsHashNone = 0
+
+	// ResultsHashBinaryV1 hashes the canonical binary encoding of (Code, Data).
+	ResultsHashBinaryV1 = 1
+
+	// ResultsHashBinaryV2 also includes the TagsHash, so tags can be proven.
+	ResultsHashBinaryV2 = 2
+
+	// ResultsHashBlockV3 commits to the BlockResults, ie. also to the number of txs
+	// and the EndBlock updates, with the results encoded as in v2.
+	ResultsHashBlockV3 = 3
+
+	latestResultsHashVersion = ResultsHashBlockV3
+)
+
+//-----------------------------------------------------------------------------
+
+// ABCIResult is the deterministic component of a ResponseDeliverTx.
+// Its canonical form, as returned by NewResults, has a nil Data and TagsHash
+// when they are empty. A nil and an empty slice are encoded the same, so they
+// hash the same, but only the canonical form compares equal.
+// NOTE: it has no gas used, which ResponseDeliverTx doesn't report.
+type ABCIResult struct {
+	Code     uint32     `json:"code"`
+	Data     data.Bytes `json:"data"`
+	TagsHash data.Bytes `json:"tags_hash"` // only committed to from ResultsHashBinaryV2
+}
+
+// Bytes returns the canonical binary encoding of the result for the given version.
+// It is the version byte followed by the go-wire encodings of Code and Data,
+// and from ResultsHashBinaryV2 on, the TagsHash.
+// It does not depend on any JSON formatting or escaping of Data,
+// nor on whether an empty Data or TagsHash is nil.
+func (a ABCIResult) Bytes(version int) []byte {
+	buf, n, err := new(bytes.Buffer), new(int), new(error)
+	wire.WriteByte(byte(version), buf, n, err)
+	wire.WriteUint32(a.Code, buf, n, err)
+	wire.WriteByteSlice(a.Data, buf, n, err)
+	if version >= ResultsHashBinaryV2 {
+		wire.WriteByteSlice(a.TagsHash, buf, n, err)
+	}
+	if *err != nil {
+		cmn.PanicSanity(cmn.Fmt("Error encoding ABCIResult: %v", *err))
+	}
+	return buf.Bytes()
+}
+
+// Hash returns the RIPEMD160 hash of the canonical encoding of the result.
+func (a ABCIResult) Hash(version int) []byte {
+	hasher := ripemd160.New()
+	hasher.Write(a.Bytes(version)) // nolint: errcheck, gas
+	return hasher.Sum(nil)
+}
+
+// ABCIResults wraps the deliver tx results to return a proof
+type ABCIResults []ABCIResult
+
+// NewResults creates ABCIResults from ResponseDeliverTx, in their canonical form.
+func NewResults(del []*abci.ResponseDeliverTx) ABCIResults {
+	res := make(ABCIResults, len(del))
+	for i, d := range del {
+		res[i] = ABCIResult{
+			Code:     d.Code,
+			Data:     d.Data,
+			TagsHash: TagsHash(d.Tags),
+		}.Normalize()
+	}
+	return res
+}
+
+// Normalize returns the canonical form of the result, with a nil Data and
+// TagsHash if they are empty, eg. once decoded from JSON.
+func (a ABCIResult) Normalize() ABCIResult {
+	if len(a.Data) == 0 {
+		a.Data = nil
+	}
+	if len(a.TagsHash) == 0 {
+		a.TagsHash = nil
+	}
+	return a
+}
+
+// Bytes serializes the ABCIResults using go-wire
+func (a ABCIResults) Bytes() []byte {
+	return wire.BinaryBytes(a)
+}
+
+// Hash returns the merkle root of the results, encoded with the given version.
+// Returns nil if the version is ResultsHashNone.
+// NOTE: from ResultsHashBlockV3 on, it's only the root of the results,
+// which the LastResultsHash commits to through BlockResults.
+func (a ABCIResults) Hash(version int) []byte {
+	if version == ResultsHashNone {
+		return nil
+	}
+	return merkle.SimpleHashFromHashables(a.toHashables(version))
+}
+
+// ProveResult returns a merkle proof of one result from the set
+//
+// Panics if i < 0 or i >= len(a)
+func (a ABCIResults) ProveResult(i int, version int) ResultProof {
+	root, proofs := merkle.SimpleProofsFromHashables(a.toHashables(version))
+	return ResultProof{
+		Index:    i,
+		Total:    len(a),
+		Version:  version,
+		RootHash: root,
+		Result:   a[i],
+		Proof:    *proofs[i],
+	}
+}
+
+func (a ABCIResults) toHashables(version int) []merkle.Hashable {
+	l := len(a)
+	hashables := make([]merkle.Hashable, l)
+	for i := 0; i < l; i++ {
+		hashables[i] = versionedResult{a[i], version}
+	}
+	return hashables
+}
+
+// versionedResult implements merkle.Hashable for a result and a hash version
+type versionedResult struct {
+	ABCIResult
+	version int
+}
+
+func (r versionedResult) Hash() []byte {
+	return r.ABCIResult.Hash(r.version)
+}
+
+//-----------------------------------------------------------------------------
+
+// ResultProof proves that a result is part of the results of a block,
+// ie. that it is committed to by the LastResultsHash of the next header.
+// From ResultsHashBlockV3 on, RootHash is the root of the results,
+// and BlockProof proves it's part of the BlockResults.
+type ResultProof struct {
+	Index, Total int
+	Version      int
+	RootHash     data.Bytes
+	Result       ABCIResult
+	Proof        merkle.SimpleProof
+	BlockProof   *merkle.SimpleProof
+}
+
+// LeafHash returns the hash of the proven result.
+func (rp ResultProof) LeafHash() []byte {
+	return rp.Result.Hash(rp.Version)
+}
+
+// Validate returns nil if it matches the resultsHash, and is internally consistent
+// otherwise, returns a sensible error
+func (rp ResultProof) Validate(resultsHash []byte) error {
+	if rp.Version >= ResultsHashBlockV3 {
+		if rp.BlockProof == nil || !rp.BlockProof.Verify(blockResultsIndexResults,
+			blockResultsLeaves, rp.RootHash, resultsHash) {
+			return errors.New("Proof matches different results hash")
+		}
+	} else if !bytes.Equal(resultsHash, rp.RootHash) {
+		return errors.New("Proof matches different results hash")
+	}
+
+	valid := rp.Proof.Verify(rp.Index, rp.Total, rp.LeafHash(), rp.RootHash)
+	if !valid {
+		return errors.New("Proof is not internally consistent")
+	}
+	return nil
+}
+
+// ValidateTags returns nil if the given tags are the ones committed to by the proven result.
+// NOTE: tags are only committed to from ResultsHashBinaryV2 on.
+func (rp ResultProof) ValidateTags(tags []*abci.KVPair) error {
+	if rp.Version < ResultsHashBinaryV2 {
+		return errors.New("Tags are not committed to by this results hash version")
+	}
+	if !bytes.Equal(rp.Result.TagsHash, TagsHash(tags)) {
+		return errors.New("Tags don't match the result")
+	}
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// the leaves of the BlockResults merkle tree
+const (
+	blockResultsIndexResults = iota
+	blockResultsIndexSummary
+	blockResultsIndexValidatorUpdates
+	blockResultsIndexParamsUpdate
+	blockResultsLeaves
+)
+
+// BlockResults are the outcomes of executing a block: the results of its txs,
+// and the validator and consensus params updates of EndBlock. The gas used is
+// not recorded, as ResponseDeliverTx doesn't report it.
+// From ResultsHashBlockV3 on, the LastResultsHash of the next header is their Hash,
+// so light clients can verify all of them, not only the results of the txs.
+type BlockResults struct {
+	Results          ABCIResults            `json:"results"`
+	ValidatorUpdates []*abci.Validator      `json:"validator_updates"`
+	ParamsUpdate     *ConsensusParamsUpdate `json:"consensus_params_update"`
+}
+
+// NewBlockResults creates BlockResults from the responses of DeliverTx and EndBlock.
+// endBlock may be nil. No validator updates are a nil ValidatorUpdates.
+func NewBlockResults(del []*abci.ResponseDeliverTx, endBlock *abci.ResponseEndBlock,
+	paramsUpdate *ConsensusParamsUpdate) *BlockResults {
+
+	var validatorUpdates []*abci.Validator
+	if endBlock != nil && len(endBlock.ValidatorUpdates) > 0 {
+		validatorUpdates = endBlock.ValidatorUpdates
+	}
+	return &BlockResults{
+		Results:          NewResults(del),
+		ValidatorUpdates: validatorUpdates,
+		ParamsUpdate:     paramsUpdate,
+	}
+}
+
+// Hash returns the merkle root of the BlockResults, whose leaves are
+// the root of the results, the hash of the number of txs,
+// the hash of the validator updates and the hash of the consensus params update.
+func (br *BlockResults) Hash() []byte {
+	return merkle.SimpleHashFromHashables(br.toHashables())
+}
+
+// ProveResult returns a merkle proof of one result from the block,
+// against its BlockResults hash.
+//
+// Panics if i < 0 or i >= len(br.Results)
+func (br *BlockResults) ProveResult(i int) ResultProof {
+	proof := br.Results.ProveResult(i, ResultsHashBlockV3)
+	_, proofs := merkle.SimpleProofsFromHashables(br.toHashables())
+	proof.BlockProof = proofs[blockResultsIndexResults]
+	return proof
+}
+
+// Validate returns nil if the BlockResults match the resultsHash of a block
+// committing to them with ResultsHashBlockV3.
+func (br *BlockResults) Validate(resultsHash []byte) error {
+	if !bytes.Equal(resultsHash, br.Hash()) {
+		return errors.New("Block results don't match the results hash")
+	}
+	return nil
+}
+
+func (br *BlockResults) toHashables() []merkle.Hashable {
+	buf, n, err := new(bytes.Buffer), new(int), new(error)
+	wire.WriteInt64(int64(len(br.Results)), buf, n, err)
+	if *err != nil {
+		cmn.PanicSanity(cmn.Fmt("Error encoding BlockResults: %v", *err))
+	}
+	hashables := make([]merkle.Hashable, blockResultsLeaves)
+	hashables[blockResultsIndexResults] = rawHash(br.Results.Hash(ResultsHashBlockV3))
+	hashables[blockResultsIndexSummary] = rawHash(ripemd160Hash(buf.Bytes()))
+	hashables[blockResultsIndexValidatorUpdates] = rawHash(ripemd160Hash(wire.BinaryBytes(br.ValidatorUpdates)))
+	hashables[blockResultsIndexParamsUpdate] = rawHash(ripemd160Hash(wire.BinaryBytes(br.ParamsUpdate)))
+	return hashables
+}
+
+// rawHash implements merkle.Hashable for an already computed hash
+type rawHash []byte
+
+func (h rawHash) Hash() []byte {
+	return h
+}
+
+func ripemd160Hash(bz []byte) []byte {
+	hasher := ripemd160.New()
+	hasher.Write(bz) // nolint: errcheck, gas
+	return hasher.Sum(nil)
+}
+
+//-----------------------------------------------------------------------------
+
+// TagsHash returns the merkle root of the canonical binary encodings of the tags.
+// Returns nil if there are no tags.
+func TagsHash(tags []*abci.KVPair) []byte {
+	if len(tags) == 0 {
+		return nil
+	}
+	hashes := make([][]byte, len(tags))
+	for i, tag := range tags {
+		hasher := ripemd160.New()
+		hasher.Write(tagBytes(tag)) // nolint: errcheck, gas
+		hashes[i] = hasher.Sum(nil)
+	}
+	return merkle.SimpleHashFromHashes(hashes)
+}
+
+func tagBytes(tag *abci.KVPair) []byte {
+	buf, n, err := new(bytes.Buffer), new(int), new(error)
+	wire.WriteString(tag.Key, buf, n, err)
+	wire.WriteInt32(int32(tag.ValueType), buf, n, err)
+	wire.WriteString(tag.ValueString, buf, n, err)
+	wire.WriteInt64(tag.ValueInt, buf, n, err)
+	if *err != nil {
+		cmn.PanicSanity(cmn.Fmt("Error encoding tag: %v", *err))
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,224 @@
+package types
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	abci "github.com/tendermint/abci/types"
+)
+
+func TestABCIResults(t *testing.T) {
+	a := ABCIResult{Code: 0, Data: nil}
+	b := ABCIResult{Code: 0, Data: []byte{}}
+	c := ABCIResult{Code: 0, Data: []byte("one")}
+	d := ABCIResult{Code: 14, Data: nil}
+	e := ABCIResult{Code: 14, Data: []byte("foo")}
+	f := ABCIResult{Code: 14, Data: []byte("bar")}
+
+	// nil and []byte{} should produce same hash, whatever the version
+	for _, version := range []int{ResultsHashBinaryV1, ResultsHashBinaryV2, ResultsHashBlockV3} {
+		assert.Equal(t, a.Bytes(version), b.Bytes(version), "%d", version)
+		assert.Equal(t, a.Hash(version), b.Hash(version), "%d", version)
+	}
+	assert.Equal(t, a, b.Normalize())
+
+	// a and b should be the same, don't go in results
+	results := ABCIResults{a, c, d, e, f}
+
+	// make sure each result hashes properly
+	var last []byte
+	for i, res := range results {
+		h := res.Hash(ResultsHashBinaryV1)
+		assert.NotEqual(t, last, h, "%d", i)
+		last = h
+	}
+
+	// make sure that we can get a root hash from results
+	// and verify proofs
+	root := results.Hash(ResultsHashBinaryV1)
+	assert.NotEmpty(t, root)
+
+	for i, res := range results {
+		proof := results.ProveResult(i, ResultsHashBinaryV1)
+		valid := proof.Proof.Verify(i, len(results), res.Hash(ResultsHashBinaryV1), root)
+		assert.True(t, valid, "%d", i)
+		assert.Nil(t, proof.Validate(root), "%d", i)
+		assert.NotNil(t, proof.Validate(res.Hash(ResultsHashBinaryV1)), "%d", i)
+	}
+
+	// nothing is committed to if results hashing is off
+	assert.Nil(t, results.Hash(ResultsHashNone))
+}
+
+func TestABCIResultsBytes(t *testing.T) {
+	// data that would need escaping in json must hash differently
+	quoted := ABCIResult{Code: 1, Data: []byte(`"},{"code":2`)}
+	plain := ABCIResult{Code: 1, Data: []byte(`},{code:2`)}
+	assert.NotEqual(t, quoted.Hash(ResultsHashBinaryV1), plain.Hash(ResultsHashBinaryV1))
+
+	// the encoding is prefixed with its version
+	bz := plain.Bytes(ResultsHashBinaryV1)
+	assert.Equal(t, byte(ResultsHashBinaryV1), bz[0])
+}
+
+func TestNewResults(t *testing.T) {
+	del := []*abci.ResponseDeliverTx{
+		{Code: 0, Data: []byte("foo"), Log: "ignored"},
+		{Code: 5, Data: nil, Log: "not deterministic"},
+		{Code: 383, Data: []byte{}, Tags: []*abci.KVPair{}},
+	}
+	results := NewResults(del)
+	assert.Equal(t, ABCIResults{{0, []byte("foo"), nil}, {5, nil, nil}, {383, nil, nil}}, results)
+}
+
+// The encodings and hashes of the results must not change, as they are committed
+// to by the LastResultsHash, and other implementations must reproduce them.
+func TestABCIResultsVectors(t *testing.T) {
+	tagsHash := mustDecodeHex("0102030405060708090A0B0C0D0E0F1011121314")
+	results := ABCIResults{
+		{Code: 0},
+		{Code: 0, Data: []byte("one")},
+		{Code: 14, Data: []byte("foo"), TagsHash: tagsHash},
+	}
+
+	cases := []struct {
+		version int
+		bytes   []string
+		hashes  []string
+		root    string
+	}{
+		{
+			ResultsHashBinaryV1,
+			[]string{"010000000000", "010000000001036F6E65", "010000000E0103666F6F"},
+			[]string{
+				"E988C6107C0491256D34244FD5974E9B76043064",
+				"105EB00E7ADBE06B76CDBEF81B4429A506D1239B",
+				"E828D469BECEF7BA2FA45F2240B800B3BB949AA6",
+			},
+			"3BE2EF62CF904F4FF5439E00F7DBA596CB09F3B2",
+		},
+		{
+			ResultsHashBinaryV2,
+			[]string{
+				"02000000000000",
+				"020000000001036F6E6500",
+				"020000000E0103666F6F01140102030405060708090A0B0C0D0E0F1011121314",
+			},
+			[]string{
+				"C70F1CA7FE09B745BC10E0E0C5A36D5E4ACE52BD",
+				"B73B90A6824973993C70A821ED1639933AB0E300",
+				"DC3B0874ED18FBB7DEBCB2ADE7E4088D2CFE7FD6",
+			},
+			"E88692FDAAF3A5663DF885833844FC0FA18AC737",
+		},
+		{
+			ResultsHashBlockV3,
+			[]string{
+				"03000000000000",
+				"030000000001036F6E6500",
+				"030000000E0103666F6F01140102030405060708090A0B0C0D0E0F1011121314",
+			},
+			[]string{
+				"A53C63DF9D7688F116D5E9C1DA002E3FDB6F9E0C",
+				"F8DC25FEDFD26D483C267B67039A22C1512FB4F5",
+				"0FB0AAC41FDDFD789780815BC9CDA3B612B1D914",
+			},
+			"E347227B0DA4B044C9B1F07923DB1A25D6C5BD64",
+		},
+	}
+	for _, tc := range cases {
+		for i, res := range results {
+			assert.Equal(t, tc.bytes[i], fmt.Sprintf("%X", res.Bytes(tc.version)), "v%d #%d", tc.version, i)
+			assert.Equal(t, tc.hashes[i], fmt.Sprintf("%X", res.Hash(tc.version)), "v%d #%d", tc.version, i)
+		}
+		assert.Equal(t, tc.root, fmt.Sprintf("%X", results.Hash(tc.version)), "v%d", tc.version)
+
+		// the empty slices of a decoded result give the same root
+		decoded := ABCIResults{{Code: 0, Data: []byte{}, TagsHash: []byte{}}, results[1], results[2]}
+		assert.Equal(t, tc.root, fmt.Sprintf("%X", decoded.Hash(tc.version)), "v%d", tc.version)
+	}
+}
+
+func mustDecodeHex(s string) []byte {
+	bz, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return bz
+}
+
+func TestABCIResultsTags(t *testing.T) {
+	tags := []*abci.KVPair{
+		abci.KVPairString("account.name", "igor"),
+		abci.KVPairInt("account.balance", 100),
+	}
+	del := []*abci.ResponseDeliverTx{
+		{Code: 0, Data: []byte("foo"), Tags: tags},
+		{Code: 0, Data: []byte("foo")},
+	}
+	results := NewResults(del)
+	assert.NotEmpty(t, results[0].TagsHash)
+	assert.Nil(t, results[1].TagsHash)
+
+	// tags only make a difference from v2 on
+	assert.Equal(t, results[0].Hash(ResultsHashBinaryV1), results[1].Hash(ResultsHashBinaryV1))
+	assert.NotEqual(t, results[0].Hash(ResultsHashBinaryV2), results[1].Hash(ResultsHashBinaryV2))
+
+	// the order of the tags matters
+	reversed := []*abci.KVPair{tags[1], tags[0]}
+	assert.NotEqual(t, TagsHash(tags), TagsHash(reversed))
+
+	// we can prove the tags emitted by a tx
+	root := results.Hash(ResultsHashBinaryV2)
+	proof := results.ProveResult(0, ResultsHashBinaryV2)
+	assert.Nil(t, proof.Validate(root))
+	assert.Nil(t, proof.ValidateTags(tags))
+	assert.NotNil(t, proof.ValidateTags(reversed))
+	assert.NotNil(t, proof.ValidateTags(nil))
+
+	// but not if the chain doesn't commit to them
+	proof = results.ProveResult(0, ResultsHashBinaryV1)
+	assert.NotNil(t, proof.ValidateTags(tags))
+}
+
+func TestBlockResults(t *testing.T) {
+	del := []*abci.ResponseDeliverTx{
+		{Code: 0, Data: []byte("foo"), Tags: []*abci.KVPair{abci.KVPairString("account.name", "igor")}},
+		{Code: 5, Data: nil},
+		{Code: 0, Data: []byte("bar")},
+	}
+	endBlock := &abci.ResponseEndBlock{ValidatorUpdates: []*abci.Validator{{PubKey: []byte("val"), Power: 10}}}
+	blockResults := NewBlockResults(del, endBlock, nil)
+	root := blockResults.Hash()
+	assert.Nil(t, NewBlockResults(del, &abci.ResponseEndBlock{ValidatorUpdates: []*abci.Validator{}}, nil).ValidatorUpdates)
+	assert.Nil(t, blockResults.Validate(root))
+
+	// every outcome of the block is committed to
+	changes := []func(*BlockResults){
+		func(br *BlockResults) { br.Results = br.Results[:2] },
+		func(br *BlockResults) { br.ValidatorUpdates = nil },
+		func(br *BlockResults) {
+			br.ParamsUpdate = &ConsensusParamsUpdate{TimeoutParams: &TimeoutParams{Commit: 1}}
+		},
+	}
+	for i, change := range changes {
+		changed := NewBlockResults(del, endBlock, nil)
+		change(changed)
+		assert.NotEqual(t, root, changed.Hash(), "%d", i)
+		assert.NotNil(t, changed.Validate(root), "%d", i)
+	}
+
+	// the results of the txs can still be proven one by one
+	for i := range del {
+		proof := blockResults.ProveResult(i)
+		assert.Nil(t, proof.Validate(root), "%d", i)
+		assert.NotNil(t, proof.Validate(blockResults.Results.Hash(ResultsHashBlockV3)), "%d", i)
+	}
+	proof := blockResults.ProveResult(0)
+	assert.Nil(t, proof.ValidateTags(del[0].Tags))
+	proof.BlockProof = nil
+	assert.NotNil(t, proof.Validate(root))
+}
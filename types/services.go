@@ -24,12 +24,26 @@ type Mempool interface {
 
 	Size() int
 	CheckTx(Tx, func(*abci.Response)) error
+	CheckTxWithExpiry(Tx, int64, func(*abci.Response)) error
 	Reap(int) Txs
+	Txs() []MempoolTx
 	Update(height int64, txs Txs) error
 	Flush()
 
 	TxsAvailable() <-chan int64
 	EnableTxsAvailable()
+
+	Pause()
+	Resume()
+}
+
+// MempoolTx is a tx in the mempool, with what the last CheckTx said about it.
+// UNSTABLE
+type MempoolTx struct {
+	Tx       Tx
+	Height   int64 // height the tx was added at
+	Priority int64
+	Gas      int64
 }
 
 // MockMempool is an empty implementation of a Mempool, useful for testing.
@@ -37,15 +51,19 @@ type Mempool interface {
 type MockMempool struct {
 }
 
-func (m MockMempool) Lock()                                        {}
-func (m MockMempool) Unlock()                                      {}
-func (m MockMempool) Size() int                                    { return 0 }
-func (m MockMempool) CheckTx(tx Tx, cb func(*abci.Response)) error { return nil }
-func (m MockMempool) Reap(n int) Txs                               { return Txs{} }
-func (m MockMempool) Update(height int64, txs Txs) error           { return nil }
-func (m MockMempool) Flush()                                       {}
-func (m MockMempool) TxsAvailable() <-chan int64                   { return make(chan int64) }
-func (m MockMempool) EnableTxsAvailable()                          {}
+func (m MockMempool) Lock()                                                           {}
+func (m MockMempool) Unlock()                                                         {}
+func (m MockMempool) Size() int                                                       { return 0 }
+func (m MockMempool) CheckTx(tx Tx, cb func(*abci.Response)) error                    { return nil }
+func (m MockMempool) CheckTxWithExpiry(tx Tx, h int64, cb func(*abci.Response)) error { return nil }
+func (m MockMempool) Reap(n int) Txs                                                  { return Txs{} }
+func (m MockMempool) Txs() []MempoolTx                                                { return nil }
+func (m MockMempool) Update(height int64, txs Txs) error                              { return nil }
+func (m MockMempool) Flush()                                                          {}
+func (m MockMempool) TxsAvailable() <-chan int64                                      { return make(chan int64) }
+func (m MockMempool) EnableTxsAvailable()                                             {}
+func (m MockMempool) Pause()                                                          {}
+func (m MockMempool) Resume()                                                         {}
 
 //------------------------------------------------------
 // blockstore
@@ -54,6 +72,7 @@ func (m MockMempool) EnableTxsAvailable()                          {}
 // UNSTABLE
 type BlockStoreRPC interface {
 	Height() int64
+	Base() int64
 
 	LoadBlockMeta(height int64) *BlockMeta
 	LoadBlock(height int64) *Block
@@ -69,3 +88,25 @@ type BlockStore interface {
 	BlockStoreRPC
 	SaveBlock(block *Block, blockParts *PartSet, seenCommit *Commit)
 }
+
+//------------------------------------------------------
+// evidence pool
+
+// EvidencePool defines the EvidencePool interface used by the ConsensusState.
+// UNSTABLE
+type EvidencePool interface {
+	PendingEvidence() []Evidence
+	AddEvidence(Evidence) error
+	MarkEvidenceAsCommitted([]Evidence)
+	IsCommitted(Evidence) bool
+}
+
+// MockEvidencePool is an empty implementation of an EvidencePool, useful for testing.
+// UNSTABLE
+type MockEvidencePool struct {
+}
+
+func (m MockEvidencePool) PendingEvidence() []Evidence        { return nil }
+func (m MockEvidencePool) AddEvidence(Evidence) error         { return nil }
+func (m MockEvidencePool) MarkEvidenceAsCommitted([]Evidence) {}
+func (m MockEvidencePool) IsCommitted(Evidence) bool          { return false }
@@ -87,19 +87,24 @@ func (txs Txs) Proof(i int) TxProof {
 	}
 }
 
+// TxProof proves a tx is the one at Index of the Total txs of a block,
+// ie. that it's committed to by the DataHash of its header.
 type TxProof struct {
-	Index, Total int
-	RootHash     data.Bytes
-	Data         Tx
-	Proof        merkle.SimpleProof
+	Index    int                `json:"index"`
+	Total    int                `json:"total"`
+	RootHash data.Bytes         `json:"root_hash"`
+	Data     Tx                 `json:"data"`
+	Proof    merkle.SimpleProof `json:"proof"`
 }
 
+// LeafHash returns the hash of the proven tx.
 func (tp TxProof) LeafHash() []byte {
 	return tp.Data.Hash()
 }
 
 // Validate returns nil if it matches the dataHash, and is internally consistent
-// otherwise, returns a sensible error
+// otherwise, returns a sensible error.
+// NOTE: it proves Data is in the block, the caller must check it's the tx it expects.
 func (tp TxProof) Validate(dataHash []byte) error {
 	if !bytes.Equal(dataHash, tp.RootHash) {
 		return errors.New("Proof matches different data hash")
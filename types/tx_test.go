@@ -2,6 +2,7 @@ package types
 
 import (
 	"bytes"
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -74,6 +75,15 @@ func TestValidTxProof(t *testing.T) {
 			if assert.Nil(err, "%d: %d: %+v", h, i, err) {
 				assert.Nil(p2.Validate(root), "%d: %d", h, i)
 			}
+
+			// and so must json, as returned by the rpc
+			var p3 TxProof
+			bz, err := json.Marshal(proof)
+			if assert.Nil(err, "%d: %d: %+v", h, i, err) {
+				assert.Contains(string(bz), `"root_hash"`, "%d: %d", h, i)
+				assert.Nil(json.Unmarshal(bz, &p3), "%d: %d", h, i)
+				assert.Nil(p3.Validate(root), "%d: %d", h, i)
+			}
 		}
 	}
 }
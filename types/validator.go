@@ -2,6 +2,7 @@ package types
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 
@@ -31,6 +32,21 @@ func NewValidator(pubKey crypto.PubKey, votingPower int64) *Validator {
 	}
 }
 
+// ValidateBasic performs basic validation: the public key must be a
+// validator key (see ValidateValidatorPubKey) with the address.
+func (v *Validator) ValidateBasic() error {
+	if err := ValidateValidatorPubKey(v.PubKey); err != nil {
+		return err
+	}
+	if !bytes.Equal(v.Address, v.PubKey.Address()) {
+		return fmt.Errorf("Validator address %X doesn't match its public key", v.Address)
+	}
+	if v.VotingPower < 0 {
+		return fmt.Errorf("Validator %X has negative voting power %d", v.Address, v.VotingPower)
+	}
+	return nil
+}
+
 // Creates a new copy of the validator so we can mutate accum.
 // Panics if the validator is nil.
 func (v *Validator) Copy() *Validator {
@@ -86,6 +102,72 @@ func (v *Validator) Hash() []byte {
 
 //-------------------------------------
 
+// The key types of the validators.
+const (
+	KeyTypeEd25519   = crypto.NameEd25519
+	KeyTypeSecp256k1 = crypto.NameSecp256k1
+	KeyTypeBLS12381  = NameBLS12381
+)
+
+// KeyTypes returns the key types the validators can be generated with.
+func KeyTypes() []string {
+	return []string{KeyTypeEd25519, KeyTypeSecp256k1, KeyTypeBLS12381}
+}
+
+// GenPrivKey generates a validator private key of the keyType.
+func GenPrivKey(keyType string) (crypto.PrivKey, error) {
+	switch keyType {
+	case KeyTypeEd25519:
+		return crypto.GenPrivKeyEd25519().Wrap(), nil
+	case KeyTypeSecp256k1:
+		return crypto.GenPrivKeySecp256k1().Wrap(), nil
+	case KeyTypeBLS12381:
+		return GenPrivKeyBLS12381().Wrap(), nil
+	default:
+		return crypto.PrivKey{}, fmt.Errorf("Unknown validator key type %q, expected %q, %q or %q",
+			keyType, KeyTypeEd25519, KeyTypeSecp256k1, KeyTypeBLS12381)
+	}
+}
+
+// ValidateValidatorPubKey returns an error unless the pubKey can sign for a validator:
+// an ed25519, a secp256k1 or a BLS12-381 key, or a multisig of them.
+func ValidateValidatorPubKey(pubKey crypto.PubKey) error {
+	switch pk := pubKey.Unwrap().(type) {
+	case nil:
+		return errors.New("Missing validator public key")
+	case crypto.PubKeyEd25519:
+		if pk == (crypto.PubKeyEd25519{}) {
+			return errors.New("Empty ed25519 validator public key")
+		}
+	case crypto.PubKeySecp256k1:
+		// compressed, so it starts with 0x02 or 0x03
+		if pk[0] != 0x02 && pk[0] != 0x03 {
+			return fmt.Errorf("Invalid secp256k1 validator public key %X", pk[:])
+		}
+	case PubKeyBLS12381:
+		if err := pk.Validate(); err != nil {
+			return fmt.Errorf("Invalid BLS12-381 validator public key %X: %v", pk[:], err)
+		}
+	case MultisigPubKey:
+		if pk.Threshold < 1 || pk.Threshold > len(pk.PubKeys) {
+			return fmt.Errorf("Invalid multisig threshold %d of %d keys", pk.Threshold, len(pk.PubKeys))
+		}
+		for i, coSignerKey := range pk.PubKeys {
+			if _, ok := coSignerKey.Unwrap().(MultisigPubKey); ok {
+				return fmt.Errorf("Multisig key %d is a multisig key itself", i)
+			}
+			if err := ValidateValidatorPubKey(coSignerKey); err != nil {
+				return fmt.Errorf("Multisig key %d: %v", i, err)
+			}
+		}
+	default:
+		return fmt.Errorf("Unsupported validator key type %T", pk)
+	}
+	return nil
+}
+
+//-------------------------------------
+
 var ValidatorCodec = validatorCodec{}
 
 type validatorCodec struct{}
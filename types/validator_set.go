@@ -231,6 +231,13 @@ func (valSet *ValidatorSet) VerifyCommit(chainID string, blockID BlockID, height
 		return fmt.Errorf("Invalid commit -- wrong height: %v vs %v", height, commit.Height())
 	}
 
+	aggregated := commit.IsAggregated()
+	if aggregated {
+		if err := valSet.verifyAggregatedSignature(chainID, commit); err != nil {
+			return err
+		}
+	}
+
 	talliedVotingPower := int64(0)
 	round := commit.Round()
 
@@ -249,9 +256,9 @@ func (valSet *ValidatorSet) VerifyCommit(chainID string, blockID BlockID, height
 			return fmt.Errorf("Invalid commit -- not precommit @ index %v", idx)
 		}
 		_, val := valSet.GetByIndex(idx)
-		// Validate signature
+		// Validate signature, unless it's in the aggregated one
 		precommitSignBytes := SignBytes(chainID, precommit)
-		if !val.PubKey.VerifyBytes(precommitSignBytes, precommit.Signature) {
+		if !aggregated && !val.PubKey.VerifyBytes(precommitSignBytes, precommit.Signature) {
 			return fmt.Errorf("Invalid commit -- invalid signature: %v", precommit)
 		}
 		if !blockID.Equals(precommit.BlockID) {
@@ -285,15 +292,62 @@ func (valSet *ValidatorSet) VerifyCommit(chainID string, blockID BlockID, height
 func (valSet *ValidatorSet) VerifyCommitAny(newSet *ValidatorSet, chainID string,
 	blockID BlockID, height int64, commit *Commit) error {
 
+	oldVotingPower, newVotingPower, err := valSet.tallyCommitAny(newSet, chainID, blockID, height, commit)
+	if err != nil {
+		return err
+	}
+	if oldVotingPower <= valSet.TotalVotingPower()*2/3 {
+		return errors.Errorf("Invalid commit -- insufficient old voting power: got %v, needed %v",
+			oldVotingPower, (valSet.TotalVotingPower()*2/3 + 1))
+	} else if newVotingPower <= newSet.TotalVotingPower()*2/3 {
+		return errors.Errorf("Invalid commit -- insufficient cur voting power: got %v, needed %v",
+			newVotingPower, (newSet.TotalVotingPower()*2/3 + 1))
+	}
+	return nil
+}
+
+// VerifyCommitTrusting checks the commit, made by newSet, is signed by more than
+// trustNum/trustDenom of the voting power of valSet, the set a light client trusts.
+// Unlike VerifyCommitAny, the signers of newSet don't have to be in valSet:
+// with more than 1/3 of valSet, at least one honest validator vouches for newSet,
+// whatever its change. The commit must also be verified with newSet.VerifyCommit.
+func (valSet *ValidatorSet) VerifyCommitTrusting(newSet *ValidatorSet, chainID string,
+	blockID BlockID, height int64, commit *Commit, trustNum, trustDenom int64) error {
+
+	if trustNum <= 0 || trustDenom <= 0 || trustNum > trustDenom {
+		return errors.Errorf("Invalid trust level %d/%d", trustNum, trustDenom)
+	}
+	oldVotingPower, _, err := valSet.tallyCommitAny(newSet, chainID, blockID, height, commit)
+	if err != nil {
+		return err
+	}
+	if needed := valSet.TotalVotingPower() * trustNum / trustDenom; oldVotingPower <= needed {
+		return errors.Errorf("Invalid commit -- insufficient trusted voting power: got %v, needed %v",
+			oldVotingPower, needed+1)
+	}
+	return nil
+}
+
+// tallyCommitAny returns the voting power of valSet which signed the commit of newSet,
+// and the voting power of newSet which signed it with the same keys as in valSet.
+func (valSet *ValidatorSet) tallyCommitAny(newSet *ValidatorSet, chainID string,
+	blockID BlockID, height int64, commit *Commit) (oldVotingPower, newVotingPower int64, err error) {
+
 	if newSet.Size() != len(commit.Precommits) {
-		return errors.Errorf("Invalid commit -- wrong set size: %v vs %v", newSet.Size(), len(commit.Precommits))
+		return 0, 0, errors.Errorf("Invalid commit -- wrong set size: %v vs %v", newSet.Size(), len(commit.Precommits))
 	}
 	if height != commit.Height() {
-		return errors.Errorf("Invalid commit -- wrong height: %v vs %v", height, commit.Height())
+		return 0, 0, errors.Errorf("Invalid commit -- wrong height: %v vs %v", height, commit.Height())
+	}
+
+	// the commit is signed by the new set, by index
+	aggregated := commit.IsAggregated()
+	if aggregated {
+		if err := newSet.verifyAggregatedSignature(chainID, commit); err != nil {
+			return 0, 0, err
+		}
 	}
 
-	oldVotingPower := int64(0)
-	newVotingPower := int64(0)
 	seen := map[int]bool{}
 	round := commit.Round()
 
@@ -304,13 +358,13 @@ func (valSet *ValidatorSet) VerifyCommitAny(newSet *ValidatorSet, chainID string
 		}
 		if precommit.Height != height {
 			// return certerr.ErrHeightMismatch(height, precommit.Height)
-			return errors.Errorf("Blocks don't match - %d vs %d", round, precommit.Round)
+			return 0, 0, errors.Errorf("Blocks don't match - %d vs %d", round, precommit.Round)
 		}
 		if precommit.Round != round {
-			return errors.Errorf("Invalid commit -- wrong round: %v vs %v", round, precommit.Round)
+			return 0, 0, errors.Errorf("Invalid commit -- wrong round: %v vs %v", round, precommit.Round)
 		}
 		if precommit.Type != VoteTypePrecommit {
-			return errors.Errorf("Invalid commit -- not precommit @ index %v", idx)
+			return 0, 0, errors.Errorf("Invalid commit -- not precommit @ index %v", idx)
 		}
 		if !blockID.Equals(precommit.BlockID) {
 			continue // Not an error, but doesn't count
@@ -325,8 +379,13 @@ func (valSet *ValidatorSet) VerifyCommitAny(newSet *ValidatorSet, chainID string
 
 		// Validate signature old school
 		precommitSignBytes := SignBytes(chainID, precommit)
-		if !ov.PubKey.VerifyBytes(precommitSignBytes, precommit.Signature) {
-			return errors.Errorf("Invalid commit -- invalid signature: %v", precommit)
+		if aggregated {
+			// signed by the key of the new set, which must be the same
+			if _, cv := newSet.GetByIndex(idx); !cv.PubKey.Equals(ov.PubKey) {
+				continue
+			}
+		} else if !ov.PubKey.VerifyBytes(precommitSignBytes, precommit.Signature) {
+			return 0, 0, errors.Errorf("Invalid commit -- invalid signature: %v", precommit)
 		}
 		// Good precommit!
 		oldVotingPower += ov.VotingPower
@@ -339,12 +398,32 @@ func (valSet *ValidatorSet) VerifyCommitAny(newSet *ValidatorSet, chainID string
 		}
 	}
 
-	if oldVotingPower <= valSet.TotalVotingPower()*2/3 {
-		return errors.Errorf("Invalid commit -- insufficient old voting power: got %v, needed %v",
-			oldVotingPower, (valSet.TotalVotingPower()*2/3 + 1))
-	} else if newVotingPower <= newSet.TotalVotingPower()*2/3 {
-		return errors.Errorf("Invalid commit -- insufficient cur voting power: got %v, needed %v",
-			newVotingPower, (newSet.TotalVotingPower()*2/3 + 1))
+	return oldVotingPower, newVotingPower, nil
+}
+
+// verifyAggregatedSignature verifies the AggregatedSignature of the commit
+// is the aggregate of the signatures of all its precommits by the validators
+// at their index, who must all have BLS12-381 keys.
+func (valSet *ValidatorSet) verifyAggregatedSignature(chainID string, commit *Commit) error {
+	var pubKeys []PubKeyBLS12381
+	var msgs [][]byte
+	for idx, precommit := range commit.Precommits {
+		if precommit == nil {
+			continue
+		}
+		if !precommit.Signature.Empty() {
+			return fmt.Errorf("Invalid commit -- signature not aggregated @ index %v", idx)
+		}
+		_, val := valSet.GetByIndex(idx)
+		pubKey, ok := val.PubKey.Unwrap().(PubKeyBLS12381)
+		if !ok {
+			return fmt.Errorf("Invalid commit -- aggregated signature of a non BLS12-381 validator @ index %v", idx)
+		}
+		pubKeys = append(pubKeys, pubKey)
+		msgs = append(msgs, SignBytes(chainID, precommit))
+	}
+	if !VerifyAggregateBLS12381(pubKeys, msgs, commit.AggregatedSignature) {
+		return fmt.Errorf("Invalid commit -- invalid aggregated signature")
 	}
 	return nil
 }
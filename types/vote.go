@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/tendermint/go-crypto"
 	"github.com/tendermint/go-wire"
@@ -17,18 +18,26 @@ var (
 	ErrVoteInvalidValidatorAddress = errors.New("Invalid validator address")
 	ErrVoteInvalidSignature        = errors.New("Invalid signature")
 	ErrVoteInvalidBlockHash        = errors.New("Invalid block hash")
+	ErrVoteInvalidTimestamp        = errors.New("Invalid timestamp")
 	ErrVoteNil                     = errors.New("Nil vote")
 )
 
 type ErrVoteConflictingVotes struct {
-	VoteA *Vote
-	VoteB *Vote
+	*DuplicateVoteEvidence
 }
 
 func (err *ErrVoteConflictingVotes) Error() string {
 	return "Conflicting votes"
 }
 
+// NewConflictingVoteError returns the error for the conflicting votes of the validator,
+// with the evidence of its misbehaviour.
+func NewConflictingVoteError(val *Validator, voteA, voteB *Vote) *ErrVoteConflictingVotes {
+	return &ErrVoteConflictingVotes{
+		NewDuplicateVoteEvidence(val.PubKey, voteA, voteB),
+	}
+}
+
 // Types of votes
 // TODO Make a new type "VoteType"
 const (
@@ -54,7 +63,8 @@ type Vote struct {
 	Height           int64            `json:"height"`
 	Round            int              `json:"round"`
 	Type             byte             `json:"type"`
-	BlockID          BlockID          `json:"block_id"` // zero if vote is nil.
+	BlockID          BlockID          `json:"block_id"`  // zero if vote is nil.
+	Timestamp        time.Time        `json:"timestamp"` // signed with a ms precision, see CanonicalTime
 	Signature        crypto.Signature `json:"signature"`
 }
 
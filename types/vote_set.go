@@ -57,6 +57,8 @@ type VoteSet struct {
 	maj23         *BlockID               // First 2/3 majority seen
 	votesByBlock  map[string]*blockVotes // string(blockHash|blockParts) -> blockVotes
 	peerMaj23s    map[string]BlockID     // Maj23 for each peer
+
+	aggregatedCommit *Commit // See AddAggregatedCommit
 }
 
 // Constructs a new VoteSet struct used to accumulate votes for given height/round.
@@ -157,6 +159,11 @@ func (voteSet *VoteSet) addVote(vote *Vote) (added bool, err error) {
 		return false, ErrVoteUnexpectedStep
 	}
 
+	// Ensure that the vote was signed with a timestamp.
+	if vote.Timestamp.IsZero() {
+		return false, ErrVoteInvalidTimestamp
+	}
+
 	// Ensure that signer is a validator.
 	lookupAddr, val := voteSet.valSet.GetByIndex(valIndex)
 	if val == nil {
@@ -170,7 +177,9 @@ func (voteSet *VoteSet) addVote(vote *Vote) (added bool, err error) {
 
 	// If we already know of this vote, return false.
 	if existing, ok := voteSet.getVote(valIndex, blockKey); ok {
-		if existing.Signature.Equals(vote.Signature) {
+		if existing.Signature.Empty() {
+			return false, nil // verified in an aggregated commit
+		} else if existing.Signature.Equals(vote.Signature) {
 			return false, nil // duplicate
 		} else {
 			return false, ErrVoteInvalidSignature // NOTE: assumes deterministic signatures
@@ -186,10 +195,7 @@ func (voteSet *VoteSet) addVote(vote *Vote) (added bool, err error) {
 	// Add vote and get conflicting vote if any
 	added, conflicting := voteSet.addVerifiedVote(vote, blockKey, val.VotingPower)
 	if conflicting != nil {
-		return added, &ErrVoteConflictingVotes{
-			VoteA: conflicting,
-			VoteB: vote,
-		}
+		return added, NewConflictingVoteError(val, conflicting, vote)
 	} else {
 		if !added {
 			cmn.PanicSanity("Expected to add non-conflicting vote")
@@ -322,6 +328,63 @@ func (voteSet *VoteSet) SetPeerMaj23(peerID string, blockID BlockID) {
 	}
 }
 
+// AddAggregatedCommit adds the precommits of a commit whose signatures are
+// aggregated, eg. the LastCommit of a block fetched by fast sync, verifying
+// them at once with the AggregatedSignature. MakeCommit then returns the
+// aggregated commit, as its precommits have no signature of their own.
+// NOTE: VoteSet must not be nil
+func (voteSet *VoteSet) AddAggregatedCommit(commit *Commit) error {
+	if voteSet == nil {
+		cmn.PanicSanity("AddAggregatedCommit() on nil VoteSet")
+	}
+	if !commit.IsAggregated() {
+		return fmt.Errorf("Commit signatures are not aggregated")
+	}
+	voteSet.mtx.Lock()
+	defer voteSet.mtx.Unlock()
+
+	if voteSet.aggregatedCommit != nil {
+		return nil // Nothing to do
+	}
+	if voteSet.type_ != VoteTypePrecommit || commit.Round() != voteSet.round {
+		return ErrVoteUnexpectedStep
+	}
+	if err := voteSet.valSet.VerifyCommit(voteSet.chainID, commit.BlockID, voteSet.height, commit); err != nil {
+		return err
+	}
+
+	// Track the block like for a peer claiming a 2/3 majority,
+	// so the precommits are added even if some validators conflict.
+	blockKey := commit.BlockID.Key()
+	if votesByBlock, ok := voteSet.votesByBlock[blockKey]; ok {
+		votesByBlock.peerMaj23 = true
+	} else {
+		voteSet.votesByBlock[blockKey] = newBlockVotes(true, voteSet.valSet.Size())
+	}
+	for idx, precommit := range commit.Precommits {
+		if precommit == nil {
+			continue
+		}
+		if _, ok := voteSet.getVote(idx, blockKey); ok {
+			continue
+		}
+		_, val := voteSet.valSet.GetByIndex(idx)
+		voteSet.addVerifiedVote(precommit, blockKey, val.VotingPower)
+	}
+	voteSet.aggregatedCommit = commit
+	return nil
+}
+
+// AggregatedCommit returns the commit added with AddAggregatedCommit, if any.
+func (voteSet *VoteSet) AggregatedCommit() *Commit {
+	if voteSet == nil {
+		return nil
+	}
+	voteSet.mtx.Lock()
+	defer voteSet.mtx.Unlock()
+	return voteSet.aggregatedCommit
+}
+
 func (voteSet *VoteSet) BitArray() *cmn.BitArray {
 	if voteSet == nil {
 		return nil
@@ -470,6 +533,17 @@ func (voteSet *VoteSet) MakeCommit() *Commit {
 		cmn.PanicSanity("Cannot MakeCommit() unless a blockhash has +2/3")
 	}
 
+	// The precommits of an aggregated commit can only be sent along with it
+	if commit := voteSet.aggregatedCommit; commit != nil && commit.BlockID.Equals(*voteSet.maj23) {
+		precommitsCopy := make([]*Vote, len(commit.Precommits))
+		copy(precommitsCopy, commit.Precommits)
+		return &Commit{
+			BlockID:             commit.BlockID,
+			Precommits:          precommitsCopy,
+			AggregatedSignature: commit.AggregatedSignature,
+		}
+	}
+
 	// For every validator, get the precommit
 	votesCopy := make([]*Vote, len(voteSet.votes))
 	copy(votesCopy, voteSet.votes)
@@ -3,6 +3,7 @@ package types
 import (
 	"bytes"
 	"testing"
+	"time"
 
 	crypto "github.com/tendermint/go-crypto"
 	cmn "github.com/tendermint/tmlibs/common"
@@ -58,7 +59,11 @@ func withBlockPartsHeader(vote *Vote, blockPartsHeader PartSetHeader) *Vote {
 	return vote
 }
 
+// NOTE: the votes without a timestamp get the same one, for deterministic signatures
 func signAddVote(privVal *PrivValidatorFS, vote *Vote, voteSet *VoteSet) (bool, error) {
+	if vote.Timestamp.IsZero() {
+		vote.Timestamp = time.Date(2017, 12, 1, 10, 0, 0, 0, time.UTC)
+	}
 	var err error
 	vote.Signature, err = privVal.Signer.Sign(SignBytes(voteSet.ChainID(), vote))
 	if err != nil {
@@ -109,6 +114,18 @@ func TestAddVote(t *testing.T) {
 	if ok || !blockID.IsZero() {
 		t.Errorf("There should be no 2/3 majority")
 	}
+
+	// a vote without a timestamp
+	val1 := privValidators[1]
+	vote = withValidator(vote, val1.GetAddress(), 1)
+	vote.Timestamp = time.Time{}
+	vote.Signature, err = val1.Signer.Sign(SignBytes(voteSet.ChainID(), vote))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := voteSet.AddVote(vote); err != ErrVoteInvalidTimestamp {
+		t.Errorf("Expected ErrVoteInvalidTimestamp, got %v", err)
+	}
 }
 
 func Test2_3Majority(t *testing.T) {
@@ -2,6 +2,7 @@ package types
 
 import (
 	"testing"
+	"time"
 )
 
 func TestVoteSignable(t *testing.T) {
@@ -11,6 +12,7 @@ func TestVoteSignable(t *testing.T) {
 		Height:           12345,
 		Round:            23456,
 		Type:             byte(2),
+		Timestamp:        time.Date(2017, 12, 1, 10, 0, 0, 0, time.UTC),
 		BlockID: BlockID{
 			Hash: []byte("hash"),
 			PartsHeader: PartSetHeader{
@@ -22,7 +24,7 @@ func TestVoteSignable(t *testing.T) {
 	signBytes := SignBytes("test_chain_id", vote)
 	signStr := string(signBytes)
 
-	expected := `{"chain_id":"test_chain_id","vote":{"block_id":{"hash":"68617368","parts":{"hash":"70617274735F68617368","total":1000000}},"height":12345,"round":23456,"type":2}}`
+	expected := `{"chain_id":"test_chain_id","vote":{"block_id":{"hash":"68617368","parts":{"hash":"70617274735F68617368","total":1000000}},"height":12345,"round":23456,"timestamp":"2017-12-01T10:00:00.000Z","type":2}}`
 	if signStr != expected {
 		// NOTE: when this fails, you probably want to fix up consensus/replay_test too
 		t.Errorf("Got unexpected sign string for Vote. Expected:\n%v\nGot:\n%v", expected, signStr)